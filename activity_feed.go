@@ -0,0 +1,205 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// ActivityEvent is an entry of the activity feed
+type ActivityEvent struct {
+	ID         int                    `json:"id"`
+	Event      string                 `json:"event"`
+	DocumentID *int                   `json:"document_id,omitempty"`
+	Title      string                 `json:"title"` // The document's current title, or the name of the view
+	Data       map[string]interface{} `json:"data"`
+	Created    *string                `json:"created,omitempty"`
+}
+
+// ActivityFeedResponse is a page of the activity feed, newest first. NextCursor continues
+// with older events; it is empty on the last page.
+type ActivityFeedResponse struct {
+	Results    []ActivityEvent `json:"results"`
+	NextCursor *string         `json:"next_cursor,omitempty"`
+	Next       *string         `json:"next,omitempty"`
+}
+
+// ActivityFeedQuery selects a page of the activity feed. Before is the cursor of the page:
+// only events older than it are listed. Since lists only events newer than an event seen
+// before, to poll for what's new.
+type ActivityFeedQuery struct {
+	Events []string
+	Before int
+	Since  int
+	Limit  int
+}
+
+// GetActivityFeed returns a page of the events a user can see: their own, those of global
+// views, and those of documents outside the trash the viewer can see
+func (s *Service) GetActivityFeed(ctx context.Context, userID int, viewerID int, query ActivityFeedQuery) (*ActivityFeedResponse, error) {
+	conditions := []string{"(e.user_id IS NULL OR e.user_id = ?)"}
+	args := []interface{}{userID}
+
+	documentCondition := trashedCondition(false)
+	visibility, err := s.documentVisibilityCondition(ctx, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	if visibility != "" {
+		documentCondition += " AND " + visibility
+	}
+	conditions = append(conditions, fmt.Sprintf("(e.document_id IS NULL OR (d.id IS NOT NULL AND %s))", documentCondition))
+
+	if len(query.Events) > 0 {
+		placeholders := make([]string, len(query.Events))
+		for i, event := range query.Events {
+			placeholders[i] = "?"
+			args = append(args, event)
+		}
+		conditions = append(conditions, fmt.Sprintf("e.event IN (%s)", strings.Join(placeholders, ", ")))
+	}
+	if query.Before > 0 {
+		conditions = append(conditions, "e.id < ?")
+		args = append(args, query.Before)
+	}
+	if query.Since > 0 {
+		conditions = append(conditions, "e.id > ?")
+		args = append(args, query.Since)
+	}
+
+	// One more event than the page tells whether there is a next page
+	rows, err := s.db.QueryContext(ctx, s.rebind(fmt.Sprintf(`
+		SELECT e.id, e.event, e.document_id, e.title, d.title, e.data, e.created
+		FROM activity_events e
+		LEFT JOIN documents_document d ON d.id = e.document_id
+		WHERE %s
+		ORDER BY e.id DESC
+		LIMIT %d
+	`, strings.Join(conditions, " AND "), query.Limit+1)), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query activity feed: %w", err)
+	}
+	defer rows.Close()
+
+	response := &ActivityFeedResponse{Results: []ActivityEvent{}}
+	for rows.Next() {
+		var event ActivityEvent
+		var documentID sql.NullInt64
+		var title, documentTitle, dataJSON sql.NullString
+		var created dbTimestamp
+		if err := rows.Scan(&event.ID, &event.Event, &documentID, &title, &documentTitle, &dataJSON, &created); err != nil {
+			return nil, fmt.Errorf("failed to scan activity event: %w", err)
+		}
+		if documentID.Valid {
+			id := int(documentID.Int64)
+			event.DocumentID = &id
+		}
+		event.Title = title.String
+		if documentTitle.Valid {
+			event.Title = documentTitle.String
+		}
+		event.Data = map[string]interface{}{}
+		if dataJSON.Valid {
+			json.Unmarshal([]byte(dataJSON.String), &event.Data)
+		}
+		event.Created = s.formatTimestamp(created)
+		response.Results = append(response.Results, event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read activity feed: %w", err)
+	}
+
+	if len(response.Results) > query.Limit {
+		response.Results = response.Results[:query.Limit]
+		cursor := strconv.Itoa(response.Results[query.Limit-1].ID)
+		response.NextCursor = &cursor
+	}
+	return response, nil
+}
+
+// parseActivityFeedQuery reads the event, cursor, since and limit query parameters
+func parseActivityFeedQuery(params url.Values) (ActivityFeedQuery, error) {
+	query := ActivityFeedQuery{Limit: defaultPageSize}
+	if value := strings.TrimSpace(params.Get("event")); value != "" {
+		for _, event := range strings.Split(value, ",") {
+			event = strings.TrimSpace(event)
+			if !activityEvents[event] {
+				return query, fmt.Errorf("Invalid event %q", event)
+			}
+			query.Events = append(query.Events, event)
+		}
+	}
+	for _, param := range []struct {
+		name   string
+		target *int
+	}{{"cursor", &query.Before}, {"since", &query.Since}} {
+		if value := params.Get(param.name); value != "" {
+			parsed, err := strconv.Atoi(value)
+			if err != nil || parsed < 1 {
+				return query, fmt.Errorf("Invalid %s", param.name)
+			}
+			*param.target = parsed
+		}
+	}
+	if value := params.Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > maxPageSize {
+			return query, fmt.Errorf("Invalid limit, expected 1 to %d", maxPageSize)
+		}
+		query.Limit = parsed
+	}
+	return query, nil
+}
+
+// HTTP Handler for the activity feed
+func (s *Service) handleGetActivityFeed(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	log.Printf("[Activity] GET /api/activity/feed/ - Request from %s", r.RemoteAddr)
+
+	viewerID, err := viewerFromRequest(r)
+	if err != nil {
+		respondError(w, viewerErrorStatus(err), err.Error())
+		return
+	}
+
+	query, err := parseActivityFeedQuery(r.URL.Query())
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	userID, err := getUserIDFromRequest(r)
+	if err != nil {
+		respondError(w, viewerErrorStatus(err), err.Error())
+		return
+	}
+
+	feed, err := s.GetActivityFeed(ctx, *userID, viewerID, query)
+	if err != nil {
+		log.Printf("[Activity] Error reading activity feed: %v", err)
+		respondError(w, queryErrorStatus(err), err.Error())
+		return
+	}
+	if feed.NextCursor != nil {
+		scheme := "http"
+		if r.TLS != nil {
+			scheme = "https"
+		}
+		if proto := r.Header.Get("X-Forwarded-Proto"); proto != "" {
+			scheme = proto
+		}
+		params := r.URL.Query()
+		params.Set("cursor", *feed.NextCursor)
+		next := (&url.URL{Scheme: scheme, Host: r.Host, Path: r.URL.Path, RawQuery: params.Encode()}).String()
+		feed.Next = &next
+	}
+
+	respondJSON(w, http.StatusOK, feed)
+}
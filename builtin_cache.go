@@ -0,0 +1,271 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// documentChangeChannel is the PostgreSQL notification channel signalling changes to the
+// tables builtin filter values are computed from
+const documentChangeChannel = "paperless_link_document_changes"
+
+// documentChangeTables are the tables whose changes invalidate builtin filter values
+var documentChangeTables = []string{
+	"documents_document",
+	"documents_document_tags",
+	"documents_correspondent",
+	"documents_documenttype",
+	"documents_storagepath",
+	"documents_tag",
+}
+
+// tagGroupFacetCacheKeyPrefix prefixes the cached grouped and folded tag values, which also
+// depend on the tag groups and tag aliases managed by this service
+const tagGroupFacetCacheKeyPrefix = "builtin:tag-groups|"
+
+// installDocumentChangeTriggers creates statement-level triggers on the document tables
+// that NOTIFY documentChangeChannel. It reports whether notifications are available;
+// other engines and failures (e.g. missing privileges) fall back to expiry only.
+func (s *Service) installDocumentChangeTriggers() bool {
+	if s.config.DBEngine != "postgresql" && s.config.DBEngine != "postgres" {
+		return false
+	}
+	if !s.config.BuiltinCacheNotify {
+		return false
+	}
+
+	statements := []string{fmt.Sprintf(`
+		CREATE OR REPLACE FUNCTION paperless_link_notify_document_changes() RETURNS trigger AS $$
+		BEGIN
+			PERFORM pg_notify('%s', TG_TABLE_NAME);
+			RETURN NULL;
+		END;
+		$$ LANGUAGE plpgsql;
+	`, documentChangeChannel)}
+	for _, table := range documentChangeTables {
+		statements = append(statements, fmt.Sprintf(`
+			DROP TRIGGER IF EXISTS paperless_link_notify_changes ON %s;
+			CREATE TRIGGER paperless_link_notify_changes
+				AFTER INSERT OR UPDATE OR DELETE OR TRUNCATE ON %s
+				FOR EACH STATEMENT EXECUTE PROCEDURE paperless_link_notify_document_changes();
+		`, table, table))
+	}
+
+	for _, statement := range statements {
+		if _, err := s.db.Exec(statement); err != nil {
+			log.Printf("[BuiltinCache] Could not install document change triggers, falling back to expiry: %v", err)
+			return false
+		}
+	}
+	log.Printf("[BuiltinCache] Document change triggers installed on %d tables", len(documentChangeTables))
+	return true
+}
+
+// runDocumentChangeListener clears the builtin filter value cache whenever the document
+// tables change. Notifications missed while reconnecting clear the cache as well.
+func (s *Service) runDocumentChangeListener(ctx context.Context) {
+	listener := pq.NewListener(postgresDSN(s.config), 10*time.Second, time.Minute, func(event pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Printf("[BuiltinCache] Listener event %d: %v", event, err)
+		}
+	})
+	defer listener.Close()
+
+	if err := listener.Listen(documentChangeChannel); err != nil {
+		log.Printf("[BuiltinCache] Failed to listen on %s, cached values now only expire: %v", documentChangeChannel, err)
+		return
+	}
+	log.Printf("[BuiltinCache] Listening for document changes on %s", documentChangeChannel)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case notification := <-listener.Notify:
+			s.documentGeneration.Add(1)
+			removed := s.builtinCache.DeletePrefix("")
+			if notification == nil {
+				log.Printf("[BuiltinCache] Listener reconnected, invalidated %d entries", removed)
+			} else if removed > 0 {
+				log.Printf("[BuiltinCache] %s changed, invalidated %d entries", notification.Extra, removed)
+			}
+		case <-time.After(90 * time.Second):
+			// Detect dead connections between notifications
+			go listener.Ping()
+		}
+	}
+}
+
+// getBuiltinFilterValuesCached wraps GetBuiltinFilterValues with the builtin filter value
+// cache; concurrent misses for the same key share one query
+func (s *Service) getBuiltinFilterValuesCached(ctx context.Context, filterType string, filterRulesJSON string, includeTrashed bool, includeEmpty bool, asn ASNBucketing, viewerID int, bypass bool) ([]BuiltinFilterValueOption, bool, error) {
+	key := fmt.Sprintf("builtin:%s|%s|%t|%t|%d|%t|%d|%d|%d", filterType, hashFilterRules(filterRulesJSON), includeTrashed, includeEmpty,
+		asn.BucketSize, asn.DrillDown, asn.From, asn.To, viewerID)
+	if !bypass {
+		var cached []BuiltinFilterValueOption
+		if s.builtinCache.Get(key, &cached) {
+			return cached, true, nil
+		}
+	}
+
+	result, err := s.shareQuery(ctx, key, func(ctx context.Context) (interface{}, error) {
+		values, err := s.GetBuiltinFilterValues(ctx, filterType, filterRulesJSON, includeTrashed, includeEmpty, asn, viewerID)
+		if err != nil {
+			return nil, err
+		}
+		s.builtinCache.Set(key, values)
+		return values, nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return result.([]BuiltinFilterValueOption), false, nil
+}
+
+// getGroupedTagValuesCached wraps GetGroupedTagValues with the builtin filter value cache
+func (s *Service) getGroupedTagValuesCached(ctx context.Context, filterRulesJSON string, includeTrashed bool, includeEmpty bool, viewerID int, bypass bool) ([]TagGroupFacet, bool, error) {
+	key := fmt.Sprintf("%s%s|%t|%t|%d", tagGroupFacetCacheKeyPrefix, hashFilterRules(filterRulesJSON), includeTrashed, includeEmpty, viewerID)
+	if !bypass {
+		var cached []TagGroupFacet
+		if s.builtinCache.Get(key, &cached) {
+			return cached, true, nil
+		}
+	}
+
+	result, err := s.shareQuery(ctx, key, func(ctx context.Context) (interface{}, error) {
+		groups, err := s.GetGroupedTagValues(ctx, filterRulesJSON, includeTrashed, includeEmpty, viewerID)
+		if err != nil {
+			return nil, err
+		}
+		s.builtinCache.Set(key, groups)
+		return groups, nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return result.([]TagGroupFacet), false, nil
+}
+
+// getFoldedTagValuesCached wraps GetFoldedTagValues with the builtin filter value cache
+func (s *Service) getFoldedTagValuesCached(ctx context.Context, filterRulesJSON string, includeTrashed bool, includeEmpty bool, viewerID int, bypass bool) ([]BuiltinFilterValueOption, bool, error) {
+	key := fmt.Sprintf("%saliases|%s|%t|%t|%d", tagGroupFacetCacheKeyPrefix, hashFilterRules(filterRulesJSON), includeTrashed, includeEmpty, viewerID)
+	if !bypass {
+		var cached []BuiltinFilterValueOption
+		if s.builtinCache.Get(key, &cached) {
+			return cached, true, nil
+		}
+	}
+
+	result, err := s.shareQuery(ctx, key, func(ctx context.Context) (interface{}, error) {
+		values, err := s.GetFoldedTagValues(ctx, filterRulesJSON, includeTrashed, includeEmpty, viewerID)
+		if err != nil {
+			return nil, err
+		}
+		s.builtinCache.Set(key, values)
+		return values, nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return result.([]BuiltinFilterValueOption), false, nil
+}
+
+// getTagGroupCountsCached wraps GetTagGroupCounts with the builtin filter value cache
+func (s *Service) getTagGroupCountsCached(ctx context.Context, filterRulesJSON string, includeTrashed bool, viewerID int, bypass bool) ([]TagGroupCount, bool, error) {
+	key := fmt.Sprintf("%scounts|%s|%t|%d", tagGroupFacetCacheKeyPrefix, hashFilterRules(filterRulesJSON), includeTrashed, viewerID)
+	if !bypass {
+		var cached []TagGroupCount
+		if s.builtinCache.Get(key, &cached) {
+			return cached, true, nil
+		}
+	}
+
+	result, err := s.shareQuery(ctx, key, func(ctx context.Context) (interface{}, error) {
+		counts, err := s.GetTagGroupCounts(ctx, filterRulesJSON, includeTrashed, viewerID)
+		if err != nil {
+			return nil, err
+		}
+		s.builtinCache.Set(key, counts)
+		return counts, nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return result.([]TagGroupCount), false, nil
+}
+
+// getTagAnalyticsCached wraps GetTagAnalytics with the builtin filter value cache. The
+// months of the period follow the current date, so entries only live for the cache TTL.
+func (s *Service) getTagAnalyticsCached(ctx context.Context, months int, top int, includeTrashed bool, viewerID int, bypass bool) (*TagAnalyticsResponse, bool, error) {
+	key := fmt.Sprintf("%sanalytics|%d|%d|%t|%d", tagGroupFacetCacheKeyPrefix, months, top, includeTrashed, viewerID)
+	if !bypass {
+		var cached *TagAnalyticsResponse
+		if s.builtinCache.Get(key, &cached) {
+			return cached, true, nil
+		}
+	}
+
+	result, err := s.shareQuery(ctx, key, func(ctx context.Context) (interface{}, error) {
+		analytics, err := s.GetTagAnalytics(ctx, months, top, includeTrashed, viewerID, time.Now())
+		if err != nil {
+			return nil, err
+		}
+		s.builtinCache.Set(key, analytics)
+		return analytics, nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return result.(*TagAnalyticsResponse), false, nil
+}
+
+// getDuplicateReportCached returns the duplicate document report from the cache, computing
+// it on a miss; the cached report holds all groups and is paginated by the caller
+func (s *Service) getDuplicateReportCached(ctx context.Context, reasons []string, includeTrashed bool, viewerID int, bypass bool) (*DuplicateReportResponse, bool, error) {
+	key := fmt.Sprintf("builtin:duplicates|%s|%t|%d", strings.Join(reasons, ","), includeTrashed, viewerID)
+	if !bypass {
+		var cached *DuplicateReportResponse
+		if s.builtinCache.Get(key, &cached) {
+			return cached, true, nil
+		}
+	}
+
+	result, err := s.shareQuery(ctx, key, func(ctx context.Context) (interface{}, error) {
+		report, err := s.GetDuplicateReport(ctx, reasons, includeTrashed, viewerID)
+		if err != nil {
+			return nil, err
+		}
+		s.builtinCache.Set(key, report)
+		return report, nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return result.(*DuplicateReportResponse), false, nil
+}
+
+// invalidateTagGroupFacets drops cached grouped and folded tag values after tag group or
+// tag alias changes
+func (s *Service) invalidateTagGroupFacets() {
+	s.documentGeneration.Add(1)
+	s.builtinCache.DeletePrefix(tagGroupFacetCacheKeyPrefix)
+}
+
+// HTTP Handler for builtin filter value cache invalidation
+func (s *Service) handleInvalidateBuiltinCache(w http.ResponseWriter, r *http.Request) {
+	prefix := ""
+	if filterType := strings.TrimSpace(r.URL.Query().Get("filter_type")); filterType != "" {
+		prefix = "builtin:" + filterType + "|"
+	}
+
+	removed := s.builtinCache.DeletePrefix(prefix)
+	log.Printf("[Cache] Invalidated %d builtin filter value cache entries (prefix=%q)", removed, prefix)
+
+	respondJSON(w, http.StatusOK, map[string]int{"invalidated": removed})
+}
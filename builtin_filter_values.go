@@ -1,9 +1,13 @@
 package main
 
 import (
+	"context"
+	"database/sql"
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/gorilla/mux"
@@ -11,309 +15,289 @@ import (
 
 // BuiltinFilterValueOption represents a filter option with count
 type BuiltinFilterValueOption struct {
-	ID    interface{} `json:"id"` // Can be int (for IDs) or string (for ASN, owner)
-	Label string      `json:"label"`
-	Count int         `json:"count"`
-}
+	ID        interface{} `json:"id"` // Can be int (for IDs), string (for ASN, date buckets) or null ("(No owner)")
+	Label     string      `json:"label"`
+	Count     int         `json:"count"`
+	Username  string      `json:"username,omitempty"` // Owner options only
+	FirstName string      `json:"first_name,omitempty"`
+	LastName  string      `json:"last_name,omitempty"`
+
+	Color             string `json:"color,omitempty"`        // Tag options only
+	IsInboxTag        *bool  `json:"is_inbox_tag,omitempty"` // Tag options only
+	Aliases           []int  `json:"aliases,omitempty"`      // Tags folded into a tag option with fold_aliases
+	Path              string `json:"path,omitempty"`         // Storage path template
+	Match             string `json:"match,omitempty"`        // Correspondent matching rule
+	MatchingAlgorithm *int   `json:"matching_algorithm,omitempty"`
+	IsInsensitive     *bool  `json:"is_insensitive,omitempty"`
 
-// GetBuiltinFilterValues retrieves filter values with counts for built-in fields
-// filterType: "correspondent", "document_type", "tag", "storage_path", "owner", "asn"
-func (s *Service) GetBuiltinFilterValues(filterType string, filterRulesJSON string) ([]BuiltinFilterValueOption, error) {
-	// Map filter type to rule type for exclusion
-	const (
-		FILTER_CORRESPONDENT = 1
-		FILTER_DOCUMENT_TYPE = 2
-		FILTER_HAS_TAGS_ANY  = 3
-		FILTER_STORAGE_PATH  = 4
-		FILTER_OWNER_ANY     = 5
-		FILTER_ASN           = 8
-	)
+	LastDocumentCreated *string `json:"last_document_created,omitempty"` // Correspondent and document type options only
 
-	var excludeRuleType int
-	switch filterType {
-	case "correspondent":
-		excludeRuleType = FILTER_CORRESPONDENT
-	case "document_type":
-		excludeRuleType = FILTER_DOCUMENT_TYPE
-	case "tag":
-		excludeRuleType = FILTER_HAS_TAGS_ANY
-	case "storage_path":
-		excludeRuleType = FILTER_STORAGE_PATH
-	case "owner":
-		excludeRuleType = FILTER_OWNER_ANY
-	case "asn":
-		excludeRuleType = FILTER_ASN
-	default:
-		excludeRuleType = 0
+	Profile *CorrespondentProfile `json:"profile,omitempty"` // Correspondent options with expand=profile
+}
+
+// GetBuiltinFilterValues retrieves filter values with counts for built-in fields from
+// the FacetProvider registered for filterType: "correspondent", "document_type", "tag",
+// "storage_path", "owner", "asn", "pages", or one of the date histograms "created_year",
+// "created_month", "added_month"
+// Trashed documents are only counted when includeTrashed is set. With includeEmpty,
+// correspondents, document types, tags and storage paths without matching documents are
+// returned with a zero count. A viewerID other than 0 only counts documents visible to
+// that user.
+func (s *Service) GetBuiltinFilterValues(ctx context.Context, filterType string, filterRulesJSON string, includeTrashed bool, includeEmpty bool, asn ASNBucketing, viewerID int) ([]BuiltinFilterValueOption, error) {
+	if filterType == "size" {
+		// Paperless only knows file sizes from the media files, not from the database
+		return nil, fmt.Errorf("unsupported filter type: size (file sizes are not stored in the Paperless database)")
+	}
+	provider, ok := lookupFacetProvider(filterType)
+	if !ok {
+		return nil, fmt.Errorf("unsupported filter type: %s (supported: %s)", filterType, strings.Join(registeredFacetTypes(), ", "))
 	}
 
+	// Rules of the requested dimension do not restrict its own values
+	excludeRuleType := builtinFilterRuleTypes[filterType]
+
 	// Build document filter query, excluding the current filter type
-	docFilterWhere, docFilterArgs, err := s.buildDocumentFilterQuery(filterRulesJSON, 0, excludeRuleType)
+	docFilterWhere, docFilterArgs, err := s.buildDocumentFilterQuery(ctx, filterRulesJSON, 0, excludeRuleType)
 	if err != nil {
 		return nil, fmt.Errorf("failed to build filter query: %w", err)
 	}
 
-	var query string
-	var args []interface{}
-	usePostgres := s.config.DBEngine == "postgresql" || s.config.DBEngine == "postgres"
-
-	switch filterType {
-	case "correspondent":
-		// Query correspondents with document counts
-		if docFilterWhere != "" {
-			query = fmt.Sprintf(`
-				SELECT c.id, c.name, COUNT(DISTINCT d.id) as doc_count
-				FROM documents_correspondent c
-				INNER JOIN documents_document d ON d.correspondent_id = c.id AND d.deleted_at IS NULL
-				WHERE %s
-				GROUP BY c.id, c.name
-				ORDER BY doc_count DESC, c.name ASC
-			`, strings.Replace(docFilterWhere, "WHERE ", "", 1))
-			args = docFilterArgs
-		} else {
-			if usePostgres {
-				query = `
-					SELECT c.id, c.name, COUNT(DISTINCT d.id) as doc_count
-					FROM documents_correspondent c
-					LEFT JOIN documents_document d ON d.correspondent_id = c.id AND d.deleted_at IS NULL
-					GROUP BY c.id, c.name
-					HAVING COUNT(DISTINCT d.id) > 0
-					ORDER BY doc_count DESC, c.name ASC
-				`
-			} else {
-				query = `
-					SELECT c.id, c.name, COUNT(DISTINCT d.id) as doc_count
-					FROM documents_correspondent c
-					LEFT JOIN documents_document d ON d.correspondent_id = c.id AND d.deleted_at IS NULL
-					GROUP BY c.id, c.name
-					HAVING COUNT(DISTINCT d.id) > 0
-					ORDER BY doc_count DESC, c.name ASC
-				`
-			}
-			args = []interface{}{}
-		}
+	// Documents hidden from the viewer are treated like trashed ones. The viewer is bound
+	// rather than inlined so the unfiltered queries are prepared once for all viewers; on
+	// PostgreSQL its placeholder follows those of the filter rules.
+	documentCondition := trashedCondition(includeTrashed)
+	visibility, visibilityArgs, err := s.boundVisibilityCondition(ctx, viewerID, len(docFilterArgs)+1)
+	if err != nil {
+		return nil, err
+	}
+	if visibility != "" {
+		documentCondition = fmt.Sprintf("(%s AND %s)", documentCondition, visibility)
+	}
 
-	case "document_type":
-		// Query document types with document counts
-		if docFilterWhere != "" {
-			query = fmt.Sprintf(`
-				SELECT dt.id, dt.name, COUNT(DISTINCT d.id) as doc_count
-				FROM documents_documenttype dt
-				INNER JOIN documents_document d ON d.document_type_id = dt.id AND d.deleted_at IS NULL
-				WHERE %s
-				GROUP BY dt.id, dt.name
-				ORDER BY doc_count DESC, dt.name ASC
-			`, strings.Replace(docFilterWhere, "WHERE ", "", 1))
-			args = docFilterArgs
-		} else {
-			if usePostgres {
-				query = `
-					SELECT dt.id, dt.name, COUNT(DISTINCT d.id) as doc_count
-					FROM documents_documenttype dt
-					LEFT JOIN documents_document d ON d.document_type_id = dt.id AND d.deleted_at IS NULL
-					GROUP BY dt.id, dt.name
-					HAVING COUNT(DISTINCT d.id) > 0
-					ORDER BY doc_count DESC, dt.name ASC
-				`
-			} else {
-				query = `
-					SELECT dt.id, dt.name, COUNT(DISTINCT d.id) as doc_count
-					FROM documents_documenttype dt
-					LEFT JOIN documents_document d ON d.document_type_id = dt.id AND d.deleted_at IS NULL
-					GROUP BY dt.id, dt.name
-					HAVING COUNT(DISTINCT d.id) > 0
-					ORDER BY doc_count DESC, dt.name ASC
-				`
-			}
-			args = []interface{}{}
-		}
+	q := FacetQuery{
+		FilterType:        filterType,
+		DBEngine:          s.config.DBEngine,
+		DocumentCondition: documentCondition,
+		Visibility:        "1 = 1",
+		FilterCondition:   "1 = 1",
+		FilterArgs:        []interface{}{},
+		IncludeEmpty:      includeEmpty,
+		ASN:               asn,
+	}
+	if visibility != "" {
+		q.Visibility = visibility
+	}
+	if docFilterWhere != "" {
+		q.FilterCondition = strings.Replace(docFilterWhere, "WHERE ", "", 1)
+		q.Filtered = true
+	}
+	if q.usePostgres() {
+		q.FilterArgs = append(append(q.FilterArgs, docFilterArgs...), visibilityArgs...)
+	} else {
+		q.FilterArgs = append(append(q.FilterArgs, visibilityArgs...), docFilterArgs...)
+	}
 
-	case "tag":
-		// Query tags with document counts
-		if docFilterWhere != "" {
-			query = fmt.Sprintf(`
-				SELECT t.id, t.name, COUNT(DISTINCT d.id) as doc_count
-				FROM documents_tag t
-				INNER JOIN documents_document_tags dt ON dt.tag_id = t.id
-				INNER JOIN documents_document d ON d.id = dt.document_id AND d.deleted_at IS NULL
-				WHERE %s
-				GROUP BY t.id, t.name
-				ORDER BY doc_count DESC, t.name ASC
-			`, strings.Replace(docFilterWhere, "WHERE ", "", 1))
-			args = docFilterArgs
-		} else {
-			if usePostgres {
-				query = `
-					SELECT t.id, t.name, COUNT(DISTINCT d.id) as doc_count
-					FROM documents_tag t
-					INNER JOIN documents_document_tags dt ON dt.tag_id = t.id
-					INNER JOIN documents_document d ON d.id = dt.document_id AND d.deleted_at IS NULL
-					GROUP BY t.id, t.name
-					ORDER BY doc_count DESC, t.name ASC
-				`
-			} else {
-				query = `
-					SELECT t.id, t.name, COUNT(DISTINCT d.id) as doc_count
-					FROM documents_tag t
-					INNER JOIN documents_document_tags dt ON dt.tag_id = t.id
-					INNER JOIN documents_document d ON d.id = dt.document_id AND d.deleted_at IS NULL
-					GROUP BY t.id, t.name
-					ORDER BY doc_count DESC, t.name ASC
-				`
-			}
-			args = []interface{}{}
-		}
+	query, args, err := provider.BuildQuery(q)
+	if err != nil {
+		return nil, err
+	}
 
-	case "storage_path":
-		// Query storage paths with document counts
-		if docFilterWhere != "" {
-			query = fmt.Sprintf(`
-				SELECT sp.id, sp.name, COUNT(DISTINCT d.id) as doc_count
-				FROM documents_storagepath sp
-				INNER JOIN documents_document d ON d.storage_path_id = sp.id AND d.deleted_at IS NULL
-				WHERE %s
-				GROUP BY sp.id, sp.name
-				ORDER BY doc_count DESC, sp.name ASC
-			`, strings.Replace(docFilterWhere, "WHERE ", "", 1))
-			args = docFilterArgs
-		} else {
-			if usePostgres {
-				query = `
-					SELECT sp.id, sp.name, COUNT(DISTINCT d.id) as doc_count
-					FROM documents_storagepath sp
-					LEFT JOIN documents_document d ON d.storage_path_id = sp.id AND d.deleted_at IS NULL
-					GROUP BY sp.id, sp.name
-					HAVING COUNT(DISTINCT d.id) > 0
-					ORDER BY doc_count DESC, sp.name ASC
-				`
-			} else {
-				query = `
-					SELECT sp.id, sp.name, COUNT(DISTINCT d.id) as doc_count
-					FROM documents_storagepath sp
-					LEFT JOIN documents_document d ON d.storage_path_id = sp.id AND d.deleted_at IS NULL
-					GROUP BY sp.id, sp.name
-					HAVING COUNT(DISTINCT d.id) > 0
-					ORDER BY doc_count DESC, sp.name ASC
-				`
-			}
-			args = []interface{}{}
-		}
+	// Unfiltered facet queries have a fixed shape per type and are kept prepared
+	var rows *sql.Rows
+	if q.Filtered {
+		rows, err = s.db.QueryContext(ctx, query, args...)
+	} else {
+		rows, err = s.queryPrepared(ctx, query, args...)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query %s values: %w", filterType, err)
+	}
+	values, err := provider.Scan(rows, q)
+	rows.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s values: %w", filterType, err)
+	}
 
-	case "owner":
-		// Query owners (usernames) with document counts
-		if docFilterWhere != "" {
-			query = fmt.Sprintf(`
-				SELECT d.owner_id as username, COUNT(DISTINCT d.id) as doc_count
-				FROM documents_document d
-				WHERE d.deleted_at IS NULL AND d.owner_id IS NOT NULL AND d.owner_id != '' AND %s
-				GROUP BY d.owner_id
-				ORDER BY doc_count DESC, d.owner_id ASC
-			`, strings.Replace(docFilterWhere, "WHERE ", "", 1))
-			args = docFilterArgs
-		} else {
-			if usePostgres {
-				query = `
-					SELECT d.owner_id as username, COUNT(DISTINCT d.id) as doc_count
-					FROM documents_document d
-					WHERE d.deleted_at IS NULL AND d.owner_id IS NOT NULL AND d.owner_id != ''
-					GROUP BY d.owner_id
-					ORDER BY doc_count DESC, d.owner_id ASC
-				`
-			} else {
-				query = `
-					SELECT d.owner_id as username, COUNT(DISTINCT d.id) as doc_count
-					FROM documents_document d
-					WHERE d.deleted_at IS NULL AND d.owner_id IS NOT NULL AND d.owner_id != ''
-					GROUP BY d.owner_id
-					ORDER BY doc_count DESC, d.owner_id ASC
-				`
-			}
-			args = []interface{}{}
+	if decorator, ok := provider.(FacetDecorator); ok {
+		if err := decorator.Decorate(ctx, s.db, q, values); err != nil {
+			return nil, err
 		}
+	}
 
-	case "asn":
-		// Query ASN values with document counts
-		if docFilterWhere != "" {
-			query = fmt.Sprintf(`
-				SELECT d.archive_serial_number as asn, COUNT(DISTINCT d.id) as doc_count
-				FROM documents_document d
-				WHERE d.deleted_at IS NULL AND d.archive_serial_number IS NOT NULL AND %s
-				GROUP BY d.archive_serial_number
-				ORDER BY doc_count DESC, d.archive_serial_number ASC
-			`, strings.Replace(docFilterWhere, "WHERE ", "", 1))
-			args = docFilterArgs
-		} else {
-			if usePostgres {
-				query = `
-					SELECT d.archive_serial_number as asn, COUNT(DISTINCT d.id) as doc_count
-					FROM documents_document d
-					WHERE d.deleted_at IS NULL AND d.archive_serial_number IS NOT NULL
-					GROUP BY d.archive_serial_number
-					ORDER BY doc_count DESC, d.archive_serial_number ASC
-				`
-			} else {
-				query = `
-					SELECT d.archive_serial_number as asn, COUNT(DISTINCT d.id) as doc_count
-					FROM documents_document d
-					WHERE d.deleted_at IS NULL AND d.archive_serial_number IS NOT NULL
-					GROUP BY d.archive_serial_number
-					ORDER BY doc_count DESC, d.archive_serial_number ASC
-				`
-			}
-			args = []interface{}{}
-		}
+	return values, nil
+}
 
-	default:
-		return nil, fmt.Errorf("unsupported filter type: %s", filterType)
+// builtinOptionKey normalizes option IDs as scanned by the different drivers
+func builtinOptionKey(id interface{}) string {
+	if b, ok := id.([]byte); ok {
+		return string(b)
 	}
+	return fmt.Sprintf("%v", id)
+}
+
+func (s *Service) handleGetBuiltinFilterValues(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
 
-	rows, err := s.db.Query(query, args...)
+	viewerID, err := viewerFromRequest(r)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query %s values: %w", filterType, err)
+		respondError(w, viewerErrorStatus(err), err.Error())
+		return
 	}
-	defer rows.Close()
 
-	var values []BuiltinFilterValueOption
-	for rows.Next() {
-		var id interface{}
-		var label string
-		var count int
+	vars := mux.Vars(r)
+	filterType := vars["filterType"]
 
-		if err := rows.Scan(&id, &label, &count); err != nil {
-			continue
+	// Parse filter rules from request body (POST) or query parameters (GET)
+	var body map[string]interface{}
+	if r.Method == http.MethodGet {
+		body, err = builtinFilterBodyFromQuery(r)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, err.Error())
+			return
 		}
+	} else if r.Body != nil {
+		json.NewDecoder(r.Body).Decode(&body)
+	}
+	body, err = s.applyFilterPreset(ctx, r, body)
+	if err != nil {
+		respondError(w, filterPresetErrorStatus(err), err.Error())
+		return
+	}
+	filterRulesJSON := filterRulesFromBody(body)
 
-		values = append(values, BuiltinFilterValueOption{
-			ID:    id,
-			Label: label,
-			Count: count,
-		})
+	asn, err := parseASNBucketing(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	expand := r.URL.Query().Get("expand")
+	if err := checkFacetExpand(filterType, expand); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
 	}
 
-	return values, nil
-}
+	// Conditional GET: clients revalidate with If-None-Match and get 304 while the data is unchanged
+	if r.Method == http.MethodGet {
+		etag, err := s.builtinFilterValuesETag(ctx, r, filterType, filterRulesJSON, viewerID)
+		if err != nil {
+			respondError(w, queryErrorStatus(err), err.Error())
+			return
+		}
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Cache-Control", "private, no-cache")
+		if etagMatches(r.Header.Get("If-None-Match"), etag) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+	}
 
-func (s *Service) handleGetBuiltinFilterValues(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	filterType := vars["filterType"]
+	// Tags rolled up by tag group
+	if grouped := r.URL.Query().Get("grouped"); filterType == "tag" && (grouped == "true" || grouped == "1") {
+		groups, hit, err := s.getGroupedTagValuesCached(ctx, filterRulesJSON, wantsTrashed(r), wantsEmpty(r), viewerID, bypassCache(r))
+		if err != nil {
+			respondError(w, queryErrorStatus(err), err.Error())
+			return
+		}
+		setCacheHeader(w, hit)
+		respondJSON(w, http.StatusOK, groups)
+		return
+	}
 
-	// Parse filter rules from request body if present
-	var filterRulesJSON string
-	if r.Body != nil {
-		var body map[string]interface{}
-		if err := json.NewDecoder(r.Body).Decode(&body); err == nil {
-			if rules, ok := body["filter_rules"].([]interface{}); ok {
-				rulesBytes, _ := json.Marshal(rules)
-				filterRulesJSON = string(rulesBytes)
-			}
+	// Tags with the documents of their aliases counted under the canonical tag
+	if fold := r.URL.Query().Get("fold_aliases"); filterType == "tag" && (fold == "true" || fold == "1") {
+		values, hit, err := s.getFoldedTagValuesCached(ctx, filterRulesJSON, wantsTrashed(r), wantsEmpty(r), viewerID, bypassCache(r))
+		if err != nil {
+			respondError(w, queryErrorStatus(err), err.Error())
+			return
 		}
+		setCacheHeader(w, hit)
+		respondOptions(w, r, http.StatusOK, values)
+		return
 	}
 
-	values, err := s.GetBuiltinFilterValues(filterType, filterRulesJSON)
+	values, hit, err := s.getBuiltinFilterValuesCached(ctx, filterType, filterRulesJSON, wantsTrashed(r), wantsEmpty(r), asn, viewerID, bypassCache(r))
 	if err != nil {
-		respondError(w, http.StatusInternalServerError, err.Error())
+		if strings.Contains(err.Error(), "unsupported filter type") {
+			respondError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		respondError(w, queryErrorStatus(err), err.Error())
 		return
 	}
+	setCacheHeader(w, hit)
+	if r.URL.Query().Get("sort") == "recent" {
+		values = sortBuiltinValuesByRecency(values)
+	}
+	if expand == "profile" {
+		if values, err = s.expandCorrespondentProfiles(ctx, values); err != nil {
+			respondError(w, queryErrorStatus(err), err.Error())
+			return
+		}
+	}
+
+	respondOptions(w, r, http.StatusOK, values)
+}
+
+// builtinFilterBodyFromQuery reads the request body fields of the POST variant from query
+// parameters: filter_rules (URL-encoded JSON array), query and search_content
+func builtinFilterBodyFromQuery(r *http.Request) (map[string]interface{}, error) {
+	params := r.URL.Query()
+	body := map[string]interface{}{
+		"query":          params.Get("query"),
+		"search_content": params.Get("search_content") == "true" || params.Get("search_content") == "1",
+	}
+	if value := params.Get("filter_rules"); value != "" {
+		var rules []interface{}
+		if err := json.Unmarshal([]byte(value), &rules); err != nil {
+			return nil, fmt.Errorf("Invalid filter_rules: %v", err)
+		}
+		body["filter_rules"] = rules
+	}
+	return body, nil
+}
+
+// parseASNBucketing reads the asn_bucket_size and asn_range ("1000-1999") query parameters
+func parseASNBucketing(r *http.Request) (ASNBucketing, error) {
+	var asn ASNBucketing
+	if value := r.URL.Query().Get("asn_bucket_size"); value != "" {
+		size, err := strconv.Atoi(value)
+		if err != nil || size <= 0 {
+			return asn, fmt.Errorf("Invalid asn_bucket_size")
+		}
+		asn.BucketSize = size
+	}
+	if value := r.URL.Query().Get("asn_range"); value != "" {
+		from, to, found := strings.Cut(value, "-")
+		fromNumber, fromErr := strconv.Atoi(strings.TrimSpace(from))
+		toNumber, toErr := strconv.Atoi(strings.TrimSpace(to))
+		if !found || fromErr != nil || toErr != nil || fromNumber > toNumber {
+			return asn, fmt.Errorf("Invalid asn_range, expected FROM-TO")
+		}
+		asn.DrillDown = true
+		asn.From = fromNumber
+		asn.To = toNumber
+	}
+	return asn, nil
+}
+
+// sortBuiltinValuesByRecency returns a copy of values ordered by their latest document,
+// most recently active first; options without documents go last
+func sortBuiltinValuesByRecency(values []BuiltinFilterValueOption) []BuiltinFilterValueOption {
+	sorted := make([]BuiltinFilterValueOption, len(values))
+	copy(sorted, values)
+	sort.SliceStable(sorted, func(a, b int) bool {
+		lastA, lastB := sorted[a].LastDocumentCreated, sorted[b].LastDocumentCreated
+		if lastA == nil || lastB == nil {
+			return lastA != nil
+		}
+		return *lastA > *lastB
+	})
+	return sorted
+}
 
-	respondJSON(w, http.StatusOK, values)
+// wantsEmpty reports whether the request asked for options without matching documents
+func wantsEmpty(r *http.Request) bool {
+	value := r.URL.Query().Get("include_empty")
+	return value == "true" || value == "1"
 }
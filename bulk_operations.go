@@ -0,0 +1,529 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// bulkJobColumns are the columns read by scanBulkJob
+const bulkJobColumns = "id, user_id, operation, status, total, processed, failed, error, created, modified, finished"
+
+// Statuses of a bulk job
+const (
+	BulkJobRunning   = "running"
+	BulkJobCompleted = "completed"
+	BulkJobFailed    = "failed"
+)
+
+// bulkEditMethods are the Paperless bulk edit methods of the bulk operations
+var bulkEditMethods = map[string]string{
+	"add_tag":          "add_tag",
+	"set_custom_field": "modify_custom_fields",
+	"set_storage_path": "set_storage_path",
+}
+
+// checkBulkOperation validates a bulk operation and that the tag, custom field or storage
+// path it refers to exists in Paperless. It returns the data type of the custom field of a
+// set_custom_field operation.
+func (s *Service) checkBulkOperation(ctx context.Context, operation *BulkOperation) (string, error) {
+	if operation.FilterRules == nil {
+		operation.FilterRules = []map[string]interface{}{}
+	}
+	filterRulesJSON, err := json.Marshal(operation.FilterRules)
+	if err != nil {
+		return "", fmt.Errorf("invalid filter_rules: %v", err)
+	}
+	if _, _, err := s.buildDocumentFilterQuery(ctx, string(filterRulesJSON), 0, 0); err != nil {
+		return "", err
+	}
+
+	var table, name string
+	var id *int
+	switch operation.Operation {
+	case "add_tag":
+		table, name, id = "documents_tag", "tag", operation.TagID
+		if id == nil {
+			return "", fmt.Errorf("tag_id is required")
+		}
+	case "set_storage_path":
+		table, name, id = "documents_storagepath", "storage path", operation.StoragePathID
+		if id == nil {
+			return "", fmt.Errorf("storage_path_id is required")
+		}
+	case "set_custom_field":
+		if operation.FieldID == nil {
+			return "", fmt.Errorf("field_id is required")
+		}
+		var dataType string
+		err := s.db.QueryRowContext(ctx, s.rebind("SELECT data_type FROM documents_customfield WHERE id = ?"), *operation.FieldID).Scan(&dataType)
+		if err == sql.ErrNoRows {
+			return "", fmt.Errorf("custom field with id %d not found", *operation.FieldID)
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to query custom field: %w", err)
+		}
+		return dataType, nil
+	case "":
+		return "", fmt.Errorf("operation is required")
+	default:
+		return "", fmt.Errorf("invalid operation %q: expected add_tag, set_custom_field or set_storage_path", operation.Operation)
+	}
+
+	var count int
+	if err := s.db.QueryRowContext(ctx, s.rebind("SELECT COUNT(*) FROM "+table+" WHERE id = ?"), *id).Scan(&count); err != nil {
+		return "", fmt.Errorf("failed to query %s: %w", name, err)
+	}
+	if count == 0 {
+		return "", fmt.Errorf("%s with id %d not found", name, *id)
+	}
+	return "", nil
+}
+
+// bulkUnchangedCondition returns the condition on d of the documents a bulk operation
+// leaves unchanged, with its arguments appended to those of the conditions before it
+func (s *Service) bulkUnchangedCondition(operation *BulkOperation, dataType string, args []interface{}) (string, []interface{}) {
+	args = append([]interface{}{}, args...)
+	nextArg := func(value interface{}) string {
+		args = append(args, value)
+		if s.config.DBEngine == "postgresql" || s.config.DBEngine == "postgres" {
+			return fmt.Sprintf("$%d", len(args))
+		}
+		return "?"
+	}
+
+	switch operation.Operation {
+	case "add_tag":
+		return fmt.Sprintf("EXISTS (SELECT 1 FROM documents_document_tags dt WHERE dt.document_id = d.id AND dt.tag_id = %s)", nextArg(*operation.TagID)), args
+	case "set_storage_path":
+		return "d.storage_path_id = " + nextArg(*operation.StoragePathID), args
+	}
+	if dataType == "documentlink" {
+		// Document link lists are not compared; every document is updated
+		return "1 = 0", args
+	}
+	instance := fmt.Sprintf("cfi.document_id = d.id AND cfi.field_id = %s AND cfi.deleted_at IS NULL", nextArg(*operation.FieldID))
+	column := getValueColumnName(dataType)
+	if operation.Value == nil {
+		return fmt.Sprintf("EXISTS (SELECT 1 FROM documents_customfieldinstance cfi WHERE %s AND cfi.%s IS NULL)", instance, column), args
+	}
+	return fmt.Sprintf("EXISTS (SELECT 1 FROM documents_customfieldinstance cfi WHERE %s AND cfi.%s = %s)", instance, column, nextArg(operation.Value)), args
+}
+
+// bulkDocumentCondition returns the condition on d of the documents matching the filter
+// rules of a bulk operation that the user can see and change in Paperless
+func (s *Service) bulkDocumentCondition(ctx context.Context, operation *BulkOperation, userID int) (string, []interface{}, error) {
+	where, args, err := s.viewDocumentCondition(ctx, &CustomView{FilterRules: operation.FilterRules}, userID)
+	if err != nil {
+		return "", nil, err
+	}
+	changeable, err := s.documentPermissionCondition(ctx, userID, "change_document")
+	if err != nil {
+		return "", nil, err
+	}
+	if changeable != "" {
+		where += " AND " + changeable
+	}
+	return where, args, nil
+}
+
+// checkBulkPermission verifies that the user may change documents in Paperless at all
+func (s *Service) checkBulkPermission(ctx context.Context, userID int) error {
+	allowed, err := s.hasModelPermission(ctx, userID, "change_document")
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		return fmt.Errorf("permission denied: user %d may not change documents", userID)
+	}
+	return nil
+}
+
+// PreviewBulkOperation counts the documents a bulk operation would change for the user
+// without changing them
+func (s *Service) PreviewBulkOperation(ctx context.Context, operation BulkOperation, userID int) (*BulkPreview, error) {
+	if err := s.checkBulkPermission(ctx, userID); err != nil {
+		return nil, err
+	}
+	dataType, err := s.checkBulkOperation(ctx, &operation)
+	if err != nil {
+		return nil, err
+	}
+	where, args, err := s.bulkDocumentCondition(ctx, &operation, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	preview := &BulkPreview{Operation: operation.Operation}
+	if preview.Matched, err = s.countViewDocuments(ctx, where, args); err != nil {
+		return nil, err
+	}
+	unchanged, unchangedArgs := s.bulkUnchangedCondition(&operation, dataType, args)
+	if preview.Unchanged, err = s.countViewDocuments(ctx, where+" AND "+unchanged, unchangedArgs); err != nil {
+		return nil, err
+	}
+	preview.Affected = preview.Matched - preview.Unchanged
+	return preview, nil
+}
+
+// bulkDocumentIDs returns the IDs of the documents a bulk operation changes for the user
+func (s *Service) bulkDocumentIDs(ctx context.Context, operation *BulkOperation, dataType string, userID int) ([]int, error) {
+	where, args, err := s.bulkDocumentCondition(ctx, operation, userID)
+	if err != nil {
+		return nil, err
+	}
+	unchanged, args := s.bulkUnchangedCondition(operation, dataType, args)
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf("SELECT d.id FROM documents_document d WHERE %s AND NOT (%s) ORDER BY d.id ASC", where, unchanged), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query bulk operation documents: %w", err)
+	}
+	defer rows.Close()
+
+	ids := []int{}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan bulk operation document: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// scanBulkJob scans a bulk job from a database row or rows
+func (s *Service) scanBulkJob(scanner interface{ Scan(...interface{}) error }) (*BulkJob, error) {
+	var job BulkJob
+	var userID sql.NullInt64
+	var operationJSON, jobError sql.NullString
+	var created, modified, finished dbTimestamp
+	if err := scanner.Scan(&job.ID, &userID, &operationJSON, &job.Status, &job.Total, &job.Processed, &job.Failed, &jobError,
+		&created, &modified, &finished); err != nil {
+		return nil, err
+	}
+	if userID.Valid {
+		id := int(userID.Int64)
+		job.UserID = &id
+	}
+	if operationJSON.Valid {
+		json.Unmarshal([]byte(operationJSON.String), &job.Operation)
+	}
+	if jobError.Valid {
+		job.Error = &jobError.String
+	}
+	job.Created = s.formatTimestamp(created)
+	job.Modified = s.formatTimestamp(modified)
+	job.Finished = s.formatTimestamp(finished)
+	return &job, nil
+}
+
+// ListBulkJobs retrieves the bulk jobs of a user, newest first
+func (s *Service) ListBulkJobs(ctx context.Context, userID int) ([]BulkJob, error) {
+	rows, err := s.db.QueryContext(ctx, s.rebind("SELECT "+bulkJobColumns+" FROM bulk_jobs WHERE user_id = ? ORDER BY id DESC"), userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query bulk jobs: %w", err)
+	}
+	defer rows.Close()
+
+	jobs := []BulkJob{}
+	for rows.Next() {
+		job, err := s.scanBulkJob(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan bulk job: %w", err)
+		}
+		jobs = append(jobs, *job)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read bulk jobs: %w", err)
+	}
+	return jobs, nil
+}
+
+// GetBulkJob retrieves a bulk job of the user; other users' jobs are not found
+func (s *Service) GetBulkJob(ctx context.Context, id int, userID int) (*BulkJob, error) {
+	job, err := s.scanBulkJob(s.db.QueryRowContext(ctx, s.rebind("SELECT "+bulkJobColumns+" FROM bulk_jobs WHERE id = ? AND user_id = ?"), id, userID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("bulk job with id %d not found", id)
+		}
+		return nil, fmt.Errorf("failed to query bulk job: %w", err)
+	}
+	return job, nil
+}
+
+// ApplyBulkOperation starts a bulk job changing the documents the operation affects for the
+// user through the Paperless bulk edit API. Paperless applies the edits with the permissions
+// of PAPERLESS_TOKEN, so only documents the user may change themselves are chosen. The
+// documents are chosen when the job starts; their batches are sent in the background until
+// the service shuts down and the job records the progress.
+func (s *Service) ApplyBulkOperation(ctx context.Context, operation BulkOperation, userID int) (*BulkJob, error) {
+	log.Printf("[Bulk] ApplyBulkOperation - Operation: %s, UserID: %d", operation.Operation, userID)
+	if s.config.PaperlessURL == "" || s.config.PaperlessToken == "" {
+		return nil, fmt.Errorf("bulk operations are disabled: PAPERLESS_URL and PAPERLESS_TOKEN are not set")
+	}
+	if err := s.checkBulkPermission(ctx, userID); err != nil {
+		return nil, err
+	}
+	dataType, err := s.checkBulkOperation(ctx, &operation)
+	if err != nil {
+		return nil, err
+	}
+	documentIDs, err := s.bulkDocumentIDs(ctx, &operation, dataType, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	status := BulkJobRunning
+	if len(documentIDs) == 0 {
+		status = BulkJobCompleted
+	}
+	operationJSON, _ := json.Marshal(operation)
+	var id int64
+	if s.config.DBEngine == "postgresql" || s.config.DBEngine == "postgres" {
+		err := s.db.QueryRowContext(ctx, `
+			INSERT INTO bulk_jobs (user_id, operation, status, total)
+			VALUES ($1, $2::jsonb, $3, $4)
+			RETURNING id
+		`, userID, string(operationJSON), status, len(documentIDs)).Scan(&id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create bulk job: %w", err)
+		}
+	} else {
+		result, err := s.db.ExecContext(ctx, `
+			INSERT INTO bulk_jobs (user_id, operation, status, total)
+			VALUES (?, ?, ?, ?)
+		`, userID, string(operationJSON), status, len(documentIDs))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create bulk job: %w", err)
+		}
+		if id, err = result.LastInsertId(); err != nil {
+			return nil, fmt.Errorf("failed to get bulk job ID: %w", err)
+		}
+	}
+	if status == BulkJobCompleted {
+		s.db.ExecContext(ctx, s.rebind("UPDATE bulk_jobs SET finished = CURRENT_TIMESTAMP WHERE id = ?"), id)
+	} else {
+		// The job outlives the request but not the service
+		go s.runBulkJob(s.lifecycle, int(id), operation, documentIDs)
+	}
+
+	return s.GetBulkJob(ctx, int(id), userID)
+}
+
+// runBulkJob sends the documents of a bulk job to Paperless batch by batch, recording the
+// progress after each. A rejected batch is counted as failed and the job goes on; the job
+// fails if no batch succeeds. A job stopped by shutdown is left running and marked failed
+// on the next start.
+func (s *Service) runBulkJob(ctx context.Context, id int, operation BulkOperation, documentIDs []int) {
+	batchSize := s.config.BulkBatchSize
+	if batchSize <= 0 {
+		batchSize = 100
+	}
+	processed, failed := 0, 0
+	var lastError *string
+	for start := 0; start < len(documentIDs); start += batchSize {
+		if ctx.Err() != nil {
+			log.Printf("[Bulk] Bulk job %d stopped by shutdown after %d documents", id, processed+failed)
+			return
+		}
+		batch := documentIDs[start:min(start+batchSize, len(documentIDs))]
+		if err := s.postBulkEdit(ctx, operation, batch); err != nil {
+			log.Printf("[Bulk] Batch of bulk job %d failed: %v", id, err)
+			message := err.Error()
+			lastError = &message
+			failed += len(batch)
+		} else {
+			processed += len(batch)
+		}
+		_, err := s.db.ExecContext(ctx, s.rebind("UPDATE bulk_jobs SET processed = ?, failed = ?, error = ?, modified = CURRENT_TIMESTAMP WHERE id = ?"),
+			processed, failed, lastError, id)
+		if err != nil {
+			log.Printf("[Bulk] Failed to record progress of bulk job %d: %v", id, err)
+		}
+	}
+
+	status := BulkJobCompleted
+	if processed == 0 {
+		status = BulkJobFailed
+	}
+	_, err := s.db.ExecContext(ctx, s.rebind("UPDATE bulk_jobs SET status = ?, modified = CURRENT_TIMESTAMP, finished = CURRENT_TIMESTAMP WHERE id = ?"), status, id)
+	if err != nil {
+		log.Printf("[Bulk] Failed to finish bulk job %d: %v", id, err)
+	}
+	log.Printf("[Bulk] Bulk job %d %s: %d documents changed, %d failed", id, status, processed, failed)
+
+	// Paperless' change notifications may not reach this service, so cached values are
+	// dropped here as well
+	if processed > 0 {
+		s.documentGeneration.Add(1)
+		s.builtinCache.DeletePrefix("")
+		s.valueCache.DeletePrefix("")
+	}
+}
+
+// postBulkEdit applies an operation to a batch of documents with the Paperless bulk edit API
+func (s *Service) postBulkEdit(ctx context.Context, operation BulkOperation, documentIDs []int) error {
+	parameters := map[string]interface{}{}
+	switch operation.Operation {
+	case "add_tag":
+		parameters["tag"] = *operation.TagID
+	case "set_storage_path":
+		parameters["storage_path"] = *operation.StoragePathID
+	case "set_custom_field":
+		parameters["add_custom_fields"] = map[string]interface{}{strconv.Itoa(*operation.FieldID): operation.Value}
+		parameters["remove_custom_fields"] = []int{}
+	}
+	payload, err := json.Marshal(map[string]interface{}{
+		"documents":  documentIDs,
+		"method":     bulkEditMethods[operation.Operation],
+		"parameters": parameters,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimRight(s.config.PaperlessURL, "/")+"/api/documents/bulk_edit/", bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Authorization", "Token "+s.config.PaperlessToken)
+
+	resp, err := s.paperlessClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("Paperless responded with status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+// bulkErrorStatus maps bulk operation errors to HTTP status codes
+func bulkErrorStatus(err error) int {
+	switch {
+	case strings.Contains(err.Error(), "disabled") || strings.HasPrefix(err.Error(), "permission denied"):
+		return http.StatusForbidden
+	case strings.HasPrefix(err.Error(), "invalid") || strings.Contains(err.Error(), "required"):
+		return http.StatusBadRequest
+	case strings.Contains(err.Error(), "not found"):
+		return http.StatusNotFound
+	}
+	return queryErrorStatus(err)
+}
+
+// HTTP Handlers for bulk operations
+func (s *Service) handlePreviewBulkOperation(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	log.Printf("[Bulk] POST /api/bulk/preview/ - Request from %s", r.RemoteAddr)
+
+	userID, err := viewerFromRequest(r)
+	if err != nil {
+		respondError(w, viewerErrorStatus(err), err.Error())
+		return
+	}
+	var operation BulkOperation
+	if err := json.NewDecoder(r.Body).Decode(&operation); err != nil {
+		respondError(w, http.StatusBadRequest, fmt.Sprintf("Invalid request body: %v", err))
+		return
+	}
+
+	preview, err := s.PreviewBulkOperation(ctx, operation, userID)
+	if err != nil {
+		log.Printf("[Bulk] Error previewing bulk operation: %v", err)
+		respondError(w, bulkErrorStatus(err), err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, preview)
+}
+
+func (s *Service) handleApplyBulkOperation(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	log.Printf("[Bulk] POST /api/bulk/apply/ - Request from %s", r.RemoteAddr)
+
+	// Bulk edits run with the permissions of PAPERLESS_TOKEN, so the user must be known
+	userID, err := viewerFromRequest(r)
+	if err != nil {
+		respondError(w, viewerErrorStatus(err), err.Error())
+		return
+	}
+	var operation BulkOperation
+	if err := json.NewDecoder(r.Body).Decode(&operation); err != nil {
+		respondError(w, http.StatusBadRequest, fmt.Sprintf("Invalid request body: %v", err))
+		return
+	}
+
+	job, err := s.ApplyBulkOperation(ctx, operation, userID)
+	if err != nil {
+		log.Printf("[Bulk] Error applying bulk operation: %v", err)
+		respondError(w, bulkErrorStatus(err), err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusAccepted, job)
+}
+
+func (s *Service) handleListBulkJobs(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	log.Printf("[Bulk] GET /api/bulk/jobs/ - Request from %s", r.RemoteAddr)
+
+	userID, err := getUserIDFromRequest(r)
+	if err != nil {
+		respondError(w, viewerErrorStatus(err), err.Error())
+		return
+	}
+
+	jobs, err := s.ListBulkJobs(ctx, *userID)
+	if err != nil {
+		log.Printf("[Bulk] Error listing bulk jobs: %v", err)
+		respondError(w, queryErrorStatus(err), err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, BulkJobListResponse{Count: len(jobs), Results: jobs})
+}
+
+func (s *Service) handleGetBulkJob(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	idStr := mux.Vars(r)["id"]
+	log.Printf("[Bulk] GET /api/bulk/jobs/%s/ - Request from %s", idStr, r.RemoteAddr)
+
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid bulk job ID")
+		return
+	}
+	userID, err := getUserIDFromRequest(r)
+	if err != nil {
+		respondError(w, viewerErrorStatus(err), err.Error())
+		return
+	}
+
+	job, err := s.GetBulkJob(ctx, id, *userID)
+	if err != nil {
+		respondError(w, bulkErrorStatus(err), err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, job)
+}
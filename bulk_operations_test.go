@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newBulkTestService creates a service whose Paperless bulk edit API is a test server
+// recording the documents of every batch it receives
+func newBulkTestService(t *testing.T) (*Service, func() [][]int) {
+	t.Helper()
+	var mu sync.Mutex
+	var batches [][]int
+	paperless := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Documents []int `json:"documents"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		mu.Lock()
+		batches = append(batches, body.Documents)
+		mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(paperless.Close)
+
+	s := newTestService(t, func(config *Config) {
+		config.PaperlessURL = paperless.URL
+		config.PaperlessToken = "token"
+	})
+	mustExec(t, s,
+		// 1 is a superuser, 2 may change documents through group 10, 3 may only view them
+		`INSERT INTO auth_user (id, username, is_superuser) VALUES (1, 'admin', 1), (2, 'editor', 0), (3, 'reader', 0), (4, 'owner', 0)`,
+		`INSERT INTO auth_user_groups (user_id, group_id) VALUES (2, 10)`,
+		`INSERT INTO auth_group_permissions (group_id, permission_id) VALUES (10, 2)`,
+		`INSERT INTO auth_user_user_permissions (user_id, permission_id) VALUES (3, 1)`,
+		`INSERT INTO documents_document (id, title, owner_id) VALUES (1, 'unowned', NULL), (2, 'own', 2), (3, 'shared to view', 4),
+			(4, 'shared to change', 4), (5, 'private', 4)`,
+		`INSERT INTO guardian_userobjectpermission (user_id, permission_id, object_pk) VALUES (2, 1, '3')`,
+		`INSERT INTO guardian_groupobjectpermission (group_id, permission_id, object_pk) VALUES (10, 1, '4'), (10, 2, '4')`,
+		`INSERT INTO documents_tag (id, name) VALUES (7, 'reviewed')`,
+	)
+	return s, func() [][]int {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([][]int{}, batches...)
+	}
+}
+
+func addTagOperation() BulkOperation {
+	tagID := 7
+	return BulkOperation{Operation: "add_tag", TagID: &tagID}
+}
+
+func TestPreviewBulkOperationOnlyCountsChangeableDocuments(t *testing.T) {
+	s, _ := newBulkTestService(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		userID  int
+		matched int
+	}{
+		{1, 5}, // Superusers change everything
+		{2, 3}, // Unowned, own and shared with change permission; not shared to view only
+	}
+	for _, tt := range tests {
+		preview, err := s.PreviewBulkOperation(ctx, addTagOperation(), tt.userID)
+		if err != nil {
+			t.Fatalf("user %d: %v", tt.userID, err)
+		}
+		if preview.Matched != tt.matched || preview.Affected != tt.matched {
+			t.Errorf("user %d: matched %d, affected %d, want %d", tt.userID, preview.Matched, preview.Affected, tt.matched)
+		}
+	}
+}
+
+func TestBulkOperationRequiresChangePermission(t *testing.T) {
+	s, batches := newBulkTestService(t)
+	ctx := context.Background()
+
+	for _, userID := range []int{3, 99} {
+		if _, err := s.PreviewBulkOperation(ctx, addTagOperation(), userID); err == nil || bulkErrorStatus(err) != http.StatusForbidden {
+			t.Errorf("preview of user %d: got %v, want permission denied", userID, err)
+		}
+		if _, err := s.ApplyBulkOperation(ctx, addTagOperation(), userID); err == nil || bulkErrorStatus(err) != http.StatusForbidden {
+			t.Errorf("apply of user %d: got %v, want permission denied", userID, err)
+		}
+	}
+	if sent := batches(); len(sent) != 0 {
+		t.Errorf("batches sent to Paperless: %v", sent)
+	}
+}
+
+func TestApplyBulkOperationSendsOnlyChangeableDocuments(t *testing.T) {
+	s, batches := newBulkTestService(t)
+	ctx := context.Background()
+
+	job, err := s.ApplyBulkOperation(ctx, addTagOperation(), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for job.Status == BulkJobRunning && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+		if job, err = s.GetBulkJob(ctx, job.ID, 2); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if job.Status != BulkJobCompleted || job.Processed != 3 {
+		t.Fatalf("job %s with %d processed, want completed with 3", job.Status, job.Processed)
+	}
+	if sent := batches(); !reflect.DeepEqual(sent, [][]int{{1, 2, 4}}) {
+		t.Errorf("sent batches %v, want [[1 2 4]]", sent)
+	}
+}
+
+func TestBulkHandlersRejectUnidentifiedUsers(t *testing.T) {
+	s, batches := newBulkTestService(t)
+
+	handlers := map[string]http.HandlerFunc{
+		"/api/bulk/preview/": s.handlePreviewBulkOperation,
+		"/api/bulk/apply/":   s.handleApplyBulkOperation,
+	}
+	for path, handler := range handlers {
+		for userHeader, status := range map[string]int{"": http.StatusUnauthorized, "abc": http.StatusBadRequest, "0": http.StatusBadRequest} {
+			req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(`{"operation": "add_tag", "tag_id": 7}`))
+			if userHeader != "" {
+				req.Header.Set("X-User-ID", userHeader)
+			}
+			rec := httptest.NewRecorder()
+			handler(rec, req)
+			if rec.Code != status {
+				t.Errorf("%s with X-User-ID %q: status %d, want %d", path, userHeader, rec.Code, status)
+			}
+		}
+	}
+	if sent := batches(); len(sent) != 0 {
+		t.Errorf("batches sent to Paperless: %v", sent)
+	}
+}
+
+func TestRunBulkJobStopsOnShutdown(t *testing.T) {
+	s, batches := newBulkTestService(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	s.runBulkJob(ctx, 1, addTagOperation(), []int{1, 2, 4})
+	if sent := batches(); len(sent) != 0 {
+		t.Errorf("batches sent after shutdown: %v", sent)
+	}
+}
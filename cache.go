@@ -0,0 +1,309 @@
+package main
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Cache holds aggregation results for a limited time. Get decodes a cached value into
+// target, a pointer to a variable of the type that was stored, and reports whether it did.
+// DeletePrefix removes the entries whose key starts with prefix (all for "") and returns
+// how many it removed.
+type Cache interface {
+	Get(key string, target interface{}) bool
+	Set(key string, value interface{})
+	DeletePrefix(prefix string) int
+}
+
+// newCache creates a cache on the configured backend: in memory, or in Redis shared by all
+// replicas of the service under the key prefix of name
+func newCache(config *Config, name string, ttl time.Duration, maxSize int) (Cache, error) {
+	if config.CacheBackend == "redis" {
+		return newRedisCache(config, name, ttl, maxSize)
+	}
+	return encodedCache{newTTLCache(ttl, maxSize)}, nil
+}
+
+// encodedCache is the in-memory backend of newCache. Values are stored as JSON and decoded
+// like those in Redis, so both backends return the same types (json.Number for numbers in
+// interface{} fields) and callers cannot change cached values through shared slices.
+type encodedCache struct {
+	*ttlCache
+}
+
+// Get decodes the value stored under key into target
+func (c encodedCache) Get(key string, target interface{}) bool {
+	var data []byte
+	if !c.ttlCache.Get(key, &data) {
+		return false
+	}
+	return decodeCachedValue(key, data, target)
+}
+
+// Set stores the JSON encoding of value under key
+func (c encodedCache) Set(key string, value interface{}) {
+	if !c.enabled() {
+		return
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		log.Printf("[Cache] Failed to encode %s: %v", key, err)
+		return
+	}
+	c.ttlCache.Set(key, data)
+}
+
+// decodeCachedValue decodes a value of a cache backend into target. Numbers in interface{}
+// fields (the IDs of builtin filter values) are decoded as json.Number, which prints like
+// the integer.
+func decodeCachedValue(key string, data []byte, target interface{}) bool {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(target); err != nil {
+		log.Printf("[Cache] Failed to decode cached %s: %v", key, err)
+		return false
+	}
+	return true
+}
+
+// ttlCache is a concurrency-safe LRU cache whose entries expire after a fixed TTL
+type ttlCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	maxSize int
+	entries map[string]*list.Element
+	order   *list.List // Front = most recently used
+}
+
+// ttlCacheEntry is a single cached value
+type ttlCacheEntry struct {
+	key     string
+	value   interface{}
+	expires time.Time
+}
+
+// newTTLCache creates a cache holding at most maxSize entries for ttl each.
+// A cache with a non-positive size or TTL never stores anything.
+func newTTLCache(ttl time.Duration, maxSize int) *ttlCache {
+	return &ttlCache{
+		ttl:     ttl,
+		maxSize: maxSize,
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+	}
+}
+
+// enabled reports whether the cache stores entries at all
+func (c *ttlCache) enabled() bool {
+	return c != nil && c.ttl > 0 && c.maxSize > 0
+}
+
+// Get sets target to the cached value for key if present and not expired
+func (c *ttlCache) Get(key string, target interface{}) bool {
+	value, ok := c.get(key)
+	if !ok || value == nil {
+		return false
+	}
+	destination := reflect.ValueOf(target).Elem()
+	if !reflect.TypeOf(value).AssignableTo(destination.Type()) {
+		return false
+	}
+	destination.Set(reflect.ValueOf(value))
+	return true
+}
+
+// get returns the cached value for key if present and not expired
+func (c *ttlCache) get(key string) (interface{}, bool) {
+	if !c.enabled() {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := elem.Value.(*ttlCacheEntry)
+	if time.Now().After(entry.expires) {
+		c.order.Remove(elem)
+		delete(c.entries, key)
+		return nil, false
+	}
+	c.order.MoveToFront(elem)
+	return entry.value, true
+}
+
+// Set stores value under key, evicting the least recently used entry when full
+func (c *ttlCache) Set(key string, value interface{}) {
+	if !c.enabled() {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expires := time.Now().Add(c.ttl)
+	if elem, ok := c.entries[key]; ok {
+		entry := elem.Value.(*ttlCacheEntry)
+		entry.value = value
+		entry.expires = expires
+		c.order.MoveToFront(elem)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(&ttlCacheEntry{key: key, value: value, expires: expires})
+	for c.order.Len() > c.maxSize {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*ttlCacheEntry).key)
+	}
+}
+
+// DeletePrefix removes all entries whose key starts with prefix and returns how many were removed.
+// An empty prefix clears the cache.
+func (c *ttlCache) DeletePrefix(prefix string) int {
+	if c == nil {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	removed := 0
+	for key, elem := range c.entries {
+		if strings.HasPrefix(key, prefix) {
+			c.order.Remove(elem)
+			delete(c.entries, key)
+			removed++
+		}
+	}
+	return removed
+}
+
+// Len returns the number of entries currently held (including expired ones not yet evicted)
+func (c *ttlCache) Len() int {
+	if c == nil {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// hashFilterRules returns a short stable hash of a filter_rules JSON payload
+func hashFilterRules(filterRulesJSON string) string {
+	if filterRulesJSON == "" {
+		return "none"
+	}
+	sum := sha256.Sum256([]byte(filterRulesJSON))
+	return hex.EncodeToString(sum[:8])
+}
+
+// fieldCacheKeyPrefix returns the key prefix shared by all cached entries of a field
+func fieldCacheKeyPrefix(fieldID int) string {
+	return fmt.Sprintf("field:%d|", fieldID)
+}
+
+// valueCacheKey builds the cache key for a value list request
+func valueCacheKey(kind string, fieldID int, filterRulesJSON string, sortBy string, sortOrder string, ignoreCase bool, includeTrashed bool) string {
+	return fmt.Sprintf("%s%s|%s|%s|%s|%t|%t", fieldCacheKeyPrefix(fieldID), kind, hashFilterRules(filterRulesJSON),
+		strings.ToLower(sortBy), strings.ToLower(sortOrder), ignoreCase, includeTrashed)
+}
+
+// bypassCache reports whether the request asked to skip the value cache
+func bypassCache(r *http.Request) bool {
+	noCache := r.URL.Query().Get("no_cache")
+	return noCache == "true" || noCache == "1"
+}
+
+// getFieldValuesCached wraps GetFieldValues with the per-field value cache; concurrent
+// misses for the same key share one query
+func (s *Service) getFieldValuesCached(ctx context.Context, fieldID int, sortBy string, sortOrder string, ignoreCase bool, includeTrashed bool, bypass bool) (*CustomFieldValuesResponse, bool, error) {
+	key := valueCacheKey("values", fieldID, "", sortBy, sortOrder, ignoreCase, includeTrashed)
+	if !bypass {
+		var cached *CustomFieldValuesResponse
+		if s.valueCache.Get(key, &cached) {
+			return cached, true, nil
+		}
+	}
+
+	result, err := s.shareQuery(ctx, key, func(ctx context.Context) (interface{}, error) {
+		response, err := s.GetFieldValues(ctx, fieldID, sortBy, sortOrder, ignoreCase, includeTrashed)
+		if err != nil {
+			return nil, err
+		}
+		s.valueCache.Set(key, response)
+		return response, nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return result.(*CustomFieldValuesResponse), false, nil
+}
+
+// getValueCountsCached wraps GetValueCounts with the per-field value cache; concurrent
+// misses for the same key share one query
+func (s *Service) getValueCountsCached(ctx context.Context, fieldID int, filterRulesJSON string, sortBy string, sortOrder string, ignoreCase bool, includeTrashed bool, bypass bool) ([]CustomFieldValueOption, bool, error) {
+	key := valueCacheKey("counts", fieldID, filterRulesJSON, sortBy, sortOrder, ignoreCase, includeTrashed)
+	if !bypass {
+		var cached []CustomFieldValueOption
+		if s.valueCache.Get(key, &cached) {
+			return cached, true, nil
+		}
+	}
+
+	result, err := s.shareQuery(ctx, key, func(ctx context.Context) (interface{}, error) {
+		values, err := s.GetValueCounts(ctx, fieldID, filterRulesJSON, sortBy, sortOrder, ignoreCase, includeTrashed)
+		if err != nil {
+			return nil, err
+		}
+		s.valueCache.Set(key, values)
+		return values, nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return result.([]CustomFieldValueOption), false, nil
+}
+
+// setCacheHeader reports cache usage to the client
+func setCacheHeader(w http.ResponseWriter, hit bool) {
+	if hit {
+		w.Header().Set("X-Cache", "HIT")
+	} else {
+		w.Header().Set("X-Cache", "MISS")
+	}
+}
+
+// HTTP Handler for value cache invalidation
+func (s *Service) handleInvalidateValueCache(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	prefix := ""
+	if fieldIDStr, ok := vars["fieldId"]; ok {
+		fieldID, err := strconv.Atoi(fieldIDStr)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "Invalid field ID")
+			return
+		}
+		prefix = fieldCacheKeyPrefix(fieldID)
+	}
+
+	removed := s.valueCache.DeletePrefix(prefix)
+	s.fieldMeta.DeletePrefix(prefix)
+	log.Printf("[Cache] Invalidated %d value cache entries (prefix=%q)", removed, prefix)
+
+	respondJSON(w, http.StatusOK, map[string]int{"invalidated": removed})
+}
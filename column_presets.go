@@ -0,0 +1,478 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+const columnPresetColumns = "id, name, description, column_order, column_sizing, column_visibility, column_display_types, is_global, owner_id, username, created, modified"
+
+// ValidateColumnPreset checks the name and the column references of a preset. Column
+// settings left unset are not checked, so partial updates can be validated too.
+func (s *Service) ValidateColumnPreset(ctx context.Context, preset ColumnPreset) ([]ValidationError, error) {
+	fieldIDs, err := s.customFieldIDs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	v := &customViewValidator{fieldIDs: fieldIDs}
+	if len(preset.Name) > maxCustomViewNameLength {
+		v.add("name", "name must be at most %d characters", maxCustomViewNameLength)
+	}
+	v.columns(preset.ColumnOrder, preset.ColumnSizing, preset.ColumnVisibility, preset.ColumnDisplayTypes)
+	return v.problems, nil
+}
+
+// ListColumnPresets retrieves the presets of a user together with the global presets
+func (s *Service) ListColumnPresets(ctx context.Context, userID int) ([]ColumnPreset, error) {
+	var query string
+	switch s.config.DBEngine {
+	case "postgresql", "postgres":
+		query = `SELECT ` + columnPresetColumns + ` FROM column_presets
+			WHERE owner_id = $1 OR is_global = true
+			ORDER BY name ASC`
+	case "mysql", "mariadb", "sqlite", "sqlite3":
+		query = `SELECT ` + columnPresetColumns + ` FROM column_presets
+			WHERE owner_id = ? OR is_global = 1
+			ORDER BY name ASC`
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query column presets: %w", err)
+	}
+	defer rows.Close()
+
+	presets := []ColumnPreset{}
+	for rows.Next() {
+		preset, err := s.scanColumnPreset(rows)
+		if err != nil {
+			continue
+		}
+		presets = append(presets, preset)
+	}
+
+	return presets, nil
+}
+
+// GetColumnPreset retrieves a preset visible to the user: their own or a global one.
+// Other users' presets are reported as not found.
+func (s *Service) GetColumnPreset(ctx context.Context, id int, userID int) (*ColumnPreset, error) {
+	preset, err := s.scanColumnPreset(s.db.QueryRowContext(ctx, s.rebind(`SELECT `+columnPresetColumns+` FROM column_presets WHERE id = ?`), id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("column preset with id %d not found", id)
+		}
+		return nil, fmt.Errorf("failed to query column preset: %w", err)
+	}
+
+	isGlobal := preset.IsGlobal != nil && *preset.IsGlobal
+	if !isGlobal && preset.OwnerID != nil && *preset.OwnerID != userID {
+		return nil, fmt.Errorf("column preset with id %d not found", id)
+	}
+
+	return &preset, nil
+}
+
+// CreateColumnPreset creates a new column preset owned by the user
+func (s *Service) CreateColumnPreset(ctx context.Context, preset ColumnPreset, userID int, username string) (*ColumnPreset, error) {
+	log.Printf("[ColumnPresets] CreateColumnPreset - Name: %s, UserID: %d", preset.Name, userID)
+	if preset.ColumnOrder == nil {
+		preset.ColumnOrder = []interface{}{}
+	}
+	if preset.ColumnSizing == nil {
+		preset.ColumnSizing = map[string]int{}
+	}
+	if preset.ColumnVisibility == nil {
+		preset.ColumnVisibility = map[string]bool{}
+	}
+	if preset.ColumnDisplayTypes == nil {
+		preset.ColumnDisplayTypes = map[string]string{}
+	}
+	columnOrderJSON, _ := json.Marshal(preset.ColumnOrder)
+	columnSizingJSON, _ := json.Marshal(preset.ColumnSizing)
+	columnVisibilityJSON, _ := json.Marshal(preset.ColumnVisibility)
+	columnDisplayTypesJSON, _ := json.Marshal(preset.ColumnDisplayTypes)
+	isGlobal := preset.IsGlobal != nil && *preset.IsGlobal
+
+	var newID int
+	if s.config.DBEngine == "postgresql" || s.config.DBEngine == "postgres" {
+		err := s.db.QueryRowContext(ctx, `
+			INSERT INTO column_presets (name, description, column_order, column_sizing, column_visibility,
+				column_display_types, is_global, owner_id, username)
+			VALUES ($1, $2, $3::jsonb, $4::jsonb, $5::jsonb, $6::jsonb, $7, $8, $9)
+			RETURNING id
+		`, preset.Name, preset.Description, string(columnOrderJSON), string(columnSizingJSON),
+			string(columnVisibilityJSON), string(columnDisplayTypesJSON), isGlobal, userID, username).Scan(&newID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create column preset: %w", err)
+		}
+	} else {
+		result, err := s.db.ExecContext(ctx, `
+			INSERT INTO column_presets (name, description, column_order, column_sizing, column_visibility,
+				column_display_types, is_global, owner_id, username)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`, preset.Name, preset.Description, string(columnOrderJSON), string(columnSizingJSON),
+			string(columnVisibilityJSON), string(columnDisplayTypesJSON), isGlobal, userID, username)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create column preset: %w", err)
+		}
+		lastID, err := result.LastInsertId()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get last insert ID: %w", err)
+		}
+		newID = int(lastID)
+	}
+
+	return s.GetColumnPreset(ctx, newID, userID)
+}
+
+// UpdateColumnPreset updates the provided fields of a preset owned by the user
+func (s *Service) UpdateColumnPreset(ctx context.Context, id int, updates ColumnPreset, userID int) (*ColumnPreset, error) {
+	log.Printf("[ColumnPresets] UpdateColumnPreset - ID: %d, UserID: %d", id, userID)
+	existing, err := s.GetColumnPreset(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if existing.OwnerID != nil && *existing.OwnerID != userID {
+		return nil, fmt.Errorf("permission denied: preset belongs to another user")
+	}
+
+	usePostgres := s.config.DBEngine == "postgresql" || s.config.DBEngine == "postgres"
+	setParts := []string{}
+	args := []interface{}{}
+	addSet := func(column string, value interface{}, cast string) {
+		args = append(args, value)
+		if usePostgres {
+			setParts = append(setParts, fmt.Sprintf("%s = $%d%s", column, len(args), cast))
+		} else {
+			setParts = append(setParts, column+" = ?")
+		}
+	}
+	addJSON := func(column string, value interface{}) {
+		encoded, _ := json.Marshal(value)
+		addSet(column, string(encoded), "::jsonb")
+	}
+
+	if updates.Name != "" {
+		addSet("name", updates.Name, "")
+	}
+	if updates.Description != nil {
+		addSet("description", *updates.Description, "")
+	}
+	if updates.ColumnOrder != nil {
+		addJSON("column_order", updates.ColumnOrder)
+	}
+	if updates.ColumnSizing != nil {
+		addJSON("column_sizing", updates.ColumnSizing)
+	}
+	if updates.ColumnVisibility != nil {
+		addJSON("column_visibility", updates.ColumnVisibility)
+	}
+	if updates.ColumnDisplayTypes != nil {
+		addJSON("column_display_types", updates.ColumnDisplayTypes)
+	}
+	if updates.IsGlobal != nil {
+		addSet("is_global", *updates.IsGlobal, "")
+	}
+	setParts = append(setParts, "modified = CURRENT_TIMESTAMP")
+
+	args = append(args, id)
+	updateQuery := fmt.Sprintf("UPDATE column_presets SET %s WHERE id = ?", strings.Join(setParts, ", "))
+	if usePostgres {
+		updateQuery = fmt.Sprintf("UPDATE column_presets SET %s WHERE id = $%d", strings.Join(setParts, ", "), len(args))
+	}
+
+	if _, err := s.db.ExecContext(ctx, updateQuery, args...); err != nil {
+		return nil, fmt.Errorf("failed to update column preset: %w", err)
+	}
+
+	return s.GetColumnPreset(ctx, id, userID)
+}
+
+// DeleteColumnPreset deletes a preset owned by the user. Views created from the preset
+// keep their columns; presets are expanded, not referenced.
+func (s *Service) DeleteColumnPreset(ctx context.Context, id int, userID int) error {
+	log.Printf("[ColumnPresets] DeleteColumnPreset - ID: %d, UserID: %d", id, userID)
+	existing, err := s.GetColumnPreset(ctx, id, userID)
+	if err != nil {
+		return err
+	}
+	if existing.OwnerID != nil && *existing.OwnerID != userID {
+		return fmt.Errorf("permission denied: preset belongs to another user")
+	}
+
+	if _, err := s.db.ExecContext(ctx, s.rebind("DELETE FROM column_presets WHERE id = ?"), id); err != nil {
+		return fmt.Errorf("failed to delete column preset: %w", err)
+	}
+
+	return nil
+}
+
+// scanColumnPreset scans a ColumnPreset from a database row or rows
+func (s *Service) scanColumnPreset(scanner interface{ Scan(...interface{}) error }) (ColumnPreset, error) {
+	var preset ColumnPreset
+	var id int
+	var ownerID sql.NullInt64
+	var description, username sql.NullString
+	var created, modified dbTimestamp
+	var columnOrderJSON, columnSizingJSON, columnVisibilityJSON, columnDisplayTypesJSON sql.NullString
+	var isGlobal sql.NullBool
+
+	if err := scanner.Scan(&id, &preset.Name, &description, &columnOrderJSON, &columnSizingJSON,
+		&columnVisibilityJSON, &columnDisplayTypesJSON, &isGlobal, &ownerID, &username, &created, &modified); err != nil {
+		return preset, err
+	}
+
+	preset.ID = &id
+	if description.Valid {
+		preset.Description = &description.String
+	}
+	preset.ColumnOrder = []interface{}{}
+	if columnOrderJSON.Valid {
+		json.Unmarshal([]byte(columnOrderJSON.String), &preset.ColumnOrder)
+	}
+	preset.ColumnSizing = map[string]int{}
+	if columnSizingJSON.Valid {
+		json.Unmarshal([]byte(columnSizingJSON.String), &preset.ColumnSizing)
+	}
+	preset.ColumnVisibility = map[string]bool{}
+	if columnVisibilityJSON.Valid {
+		json.Unmarshal([]byte(columnVisibilityJSON.String), &preset.ColumnVisibility)
+	}
+	preset.ColumnDisplayTypes = map[string]string{}
+	if columnDisplayTypesJSON.Valid {
+		json.Unmarshal([]byte(columnDisplayTypesJSON.String), &preset.ColumnDisplayTypes)
+	}
+	if isGlobal.Valid {
+		preset.IsGlobal = &isGlobal.Bool
+	}
+	if ownerID.Valid {
+		owner := int(ownerID.Int64)
+		preset.OwnerID = &owner
+	}
+	if username.Valid {
+		preset.Username = &username.String
+	}
+	preset.Created = s.formatTimestamp(created)
+	preset.Modified = s.formatTimestamp(modified)
+
+	return preset, nil
+}
+
+// expandColumnPreset replaces the column order, sizing, visibility and display types of a
+// view with those of the preset named by its column_preset_id. The reference itself is
+// not stored: later changes to the preset do not affect the view.
+func (s *Service) expandColumnPreset(ctx context.Context, view *CustomView, userID int) error {
+	if view.ColumnPresetID == nil {
+		return nil
+	}
+	presetID := *view.ColumnPresetID
+	view.ColumnPresetID = nil
+
+	preset, err := s.GetColumnPreset(ctx, presetID, userID)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			return &ValidationFailedError{Problems: []ValidationError{{
+				Field:   "column_preset_id",
+				Message: fmt.Sprintf("column preset %d does not exist", presetID),
+			}}}
+		}
+		return err
+	}
+
+	view.ColumnOrder = preset.ColumnOrder
+	view.ColumnSizing = preset.ColumnSizing
+	view.ColumnVisibility = preset.ColumnVisibility
+	view.ColumnDisplayTypes = preset.ColumnDisplayTypes
+	return nil
+}
+
+// columnPresetErrorStatus maps column preset errors to HTTP status codes
+func columnPresetErrorStatus(err error) int {
+	if strings.Contains(err.Error(), "not found") {
+		return http.StatusNotFound
+	}
+	if strings.Contains(err.Error(), "permission denied") {
+		return http.StatusForbidden
+	}
+	return queryErrorStatus(err)
+}
+
+// HTTP Handlers for Column Presets
+func (s *Service) handleListColumnPresets(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	log.Printf("[ColumnPresets] GET /api/column_presets/ - Request from %s", r.RemoteAddr)
+
+	userID, err := getUserIDFromRequest(r)
+	if err != nil {
+		respondError(w, viewerErrorStatus(err), err.Error())
+		return
+	}
+
+	presets, err := s.ListColumnPresets(ctx, *userID)
+	if err != nil {
+		log.Printf("[ColumnPresets] Error listing presets: %v", err)
+		respondError(w, queryErrorStatus(err), err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, ColumnPresetListResponse{Count: len(presets), Results: presets})
+}
+
+func (s *Service) handleGetColumnPreset(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid preset ID")
+		return
+	}
+
+	userID, err := getUserIDFromRequest(r)
+	if err != nil {
+		respondError(w, viewerErrorStatus(err), err.Error())
+		return
+	}
+
+	preset, err := s.GetColumnPreset(ctx, id, *userID)
+	if err != nil {
+		respondError(w, columnPresetErrorStatus(err), err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, preset)
+}
+
+func (s *Service) handleCreateColumnPreset(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	log.Printf("[ColumnPresets] POST /api/column_presets/ - Request from %s", r.RemoteAddr)
+
+	var preset ColumnPreset
+	if err := json.NewDecoder(r.Body).Decode(&preset); err != nil {
+		respondError(w, http.StatusBadRequest, fmt.Sprintf("Invalid request body: %v", err))
+		return
+	}
+	if strings.TrimSpace(preset.Name) == "" {
+		respondError(w, http.StatusBadRequest, "Name is required")
+		return
+	}
+
+	problems, err := s.ValidateColumnPreset(ctx, preset)
+	if err != nil {
+		respondError(w, queryErrorStatus(err), err.Error())
+		return
+	}
+	if len(problems) > 0 {
+		respondValidationErrors(w, "Invalid column preset", problems)
+		return
+	}
+
+	userID, err := getUserIDFromRequest(r)
+	if err != nil {
+		respondError(w, viewerErrorStatus(err), err.Error())
+		return
+	}
+	username := getUsernameFromRequest(r)
+
+	created, err := s.CreateColumnPreset(ctx, preset, *userID, *username)
+	if err != nil {
+		log.Printf("[ColumnPresets] Error creating preset: %v", err)
+		respondError(w, queryErrorStatus(err), err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, created)
+}
+
+func (s *Service) handleUpdateColumnPreset(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid preset ID")
+		return
+	}
+	log.Printf("[ColumnPresets] %s /api/column_presets/%d/ - Request from %s", r.Method, id, r.RemoteAddr)
+
+	var updates ColumnPreset
+	if err := json.NewDecoder(r.Body).Decode(&updates); err != nil {
+		respondError(w, http.StatusBadRequest, fmt.Sprintf("Invalid request body: %v", err))
+		return
+	}
+
+	problems, err := s.ValidateColumnPreset(ctx, updates)
+	if err != nil {
+		respondError(w, queryErrorStatus(err), err.Error())
+		return
+	}
+	if len(problems) > 0 {
+		respondValidationErrors(w, "Invalid column preset", problems)
+		return
+	}
+
+	userID, err := getUserIDFromRequest(r)
+	if err != nil {
+		respondError(w, viewerErrorStatus(err), err.Error())
+		return
+	}
+
+	updated, err := s.UpdateColumnPreset(ctx, id, updates, *userID)
+	if err != nil {
+		log.Printf("[ColumnPresets] Error updating preset %d: %v", id, err)
+		respondError(w, columnPresetErrorStatus(err), err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, updated)
+}
+
+func (s *Service) handleDeleteColumnPreset(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid preset ID")
+		return
+	}
+	log.Printf("[ColumnPresets] DELETE /api/column_presets/%d/ - Request from %s", id, r.RemoteAddr)
+
+	userID, err := getUserIDFromRequest(r)
+	if err != nil {
+		respondError(w, viewerErrorStatus(err), err.Error())
+		return
+	}
+
+	if err := s.DeleteColumnPreset(ctx, id, *userID); err != nil {
+		log.Printf("[ColumnPresets] Error deleting preset %d: %v", id, err)
+		respondError(w, columnPresetErrorStatus(err), err.Error())
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// respondViewExpansionError answers a failed column preset expansion of a view request
+func respondViewExpansionError(w http.ResponseWriter, err error) {
+	var invalid *ValidationFailedError
+	if errors.As(err, &invalid) {
+		respondValidationErrors(w, "Invalid custom view", invalid.Problems)
+		return
+	}
+	respondError(w, queryErrorStatus(err), err.Error())
+}
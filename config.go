@@ -1,7 +1,9 @@
 package main
 
 import (
+	"log"
 	"os"
+	"strconv"
 	"time"
 
 	"github.com/joho/godotenv"
@@ -20,6 +22,144 @@ type Config struct {
 	DBSSLMode    string
 	ReadTimeout  time.Duration
 	WriteTimeout time.Duration
+	QueryTimeout time.Duration // Upper bound for the database work of a single request (0 = none)
+	Compression  bool          // Compress responses with gzip or deflate when the client accepts it
+
+	// Independent queries of a request run in parallel, at most QueryConcurrency at a time
+	// across all requests
+	QueryConcurrency int
+
+	// Value aggregation: "exact" or "approximate" document counts per value, and the memory
+	// budget of one aggregation in MB (0 = unlimited)
+	ValueCountMode      string
+	AggregationMemoryMB int
+
+	// Log how many documents the filter of a counts request matches (costs an extra query)
+	DebugFilterCounts bool
+
+	// Upper bound for a single database statement (0 = none) and the duration above which
+	// statements are logged as slow (0 = never)
+	StatementTimeout   time.Duration
+	SlowQueryThreshold time.Duration
+
+	// Preload the caches for the custom fields of global views after startup
+	WarmUp bool
+
+	// Supporting indexes at startup: "report" logs the missing ones, "create" creates them,
+	// "off" skips the check
+	IndexAdvisor string
+
+	// Value cache for custom field value lists and counts
+	ValueCacheTTL  time.Duration
+	ValueCacheSize int // Maximum number of cached entries (0 disables the cache)
+
+	// Cache for the name, data type and extra data of custom fields (0 disables the cache)
+	FieldMetaCacheTTL time.Duration
+
+	// Cache for builtin filter values, invalidated by document change notifications on
+	// PostgreSQL (BuiltinCacheNotifyTTL applies) and by expiry only elsewhere
+	BuiltinCacheTTL       time.Duration
+	BuiltinCacheNotifyTTL time.Duration
+	BuiltinCacheSize      int  // Maximum number of cached entries (0 disables the cache)
+	BuiltinCacheNotify    bool // Install change triggers and LISTEN for notifications (PostgreSQL)
+
+	// How often the data version behind builtin filter value ETags is read without change
+	// notifications; a changed version also invalidates the builtin filter value cache
+	DataVersionInterval time.Duration
+
+	// Backend of the value and builtin filter value caches: "memory" (per process) or
+	// "redis" (shared by all replicas)
+	CacheBackend string
+	RedisURL     string // redis://[:password@]host:port/db, or rediss:// for TLS
+	RedisPrefix  string // Prefix of the keys of the caches
+	RedisTimeout time.Duration
+	RedisPool    int // Idle connections kept open
+
+	// Custom view created, modified and deleted_at are RFC3339 in UTC unless legacy
+	// timestamps are enabled, which return them as the database driver formats them
+	LegacyTimestamps bool
+
+	// Views a user may own, not counting the trash (0 = unlimited)
+	MaxViewsPerUser int
+
+	// Custom view history
+	ViewRevisionLimit  int           // Revisions kept per custom view (0 disables the history)
+	ViewTrashRetention time.Duration // Deleted views are purged after this long (0 keeps them)
+
+	// Earlier versions kept per tag, correspondent, document type or storage path
+	// description (0 disables the history)
+	DescriptionRevisionLimit int
+
+	// Documents returned at most by the document link graph
+	LinkGraphMaxNodes int
+
+	// Recently viewed documents kept per user (0 keeps all)
+	RecentDocumentsLimit int
+
+	// Suggested links between documents sharing metadata
+	LinkSuggestionInterval time.Duration // How often new and modified documents are analyzed (0 analyzes on request)
+	LinkSuggestionDays     int           // Days apart documents of a correspondent may be to be suggested
+
+	// Checks of views referring to deleted custom fields
+	ViewFieldCheckInterval time.Duration // How often views are checked (0 disables the check)
+	ViewFieldPrune         bool          // Remove the references instead of only logging them
+
+	// Scheduled snapshots of the documents matching a view
+	ViewSnapshotCheckInterval time.Duration // How often due snapshot schedules are run (0 disables them)
+	ViewSnapshotLimit         int           // Snapshots kept per view (0 keeps all)
+
+	// Outgoing webhook notifications
+	WebhookTimeout      time.Duration // Timeout of a single delivery attempt
+	WebhookMaxAttempts  int           // Delivery attempts before a notification is dropped
+	WebhookRetryBackoff time.Duration // Wait before the first retry, doubled for each further one
+	WebhookAllowPrivate bool          // Deliver to loopback, private and link-local addresses too
+	WebhookConcurrency  int           // View event deliveries run at the same time
+
+	// Per-view notification rules, sent by webhook or by email
+	ViewRuleInterval time.Duration // How often notification rules are evaluated (0 disables them)
+	SMTPHost         string        // Email notifications are unavailable without a host
+	SMTPPort         string
+	SMTPUsername     string
+	SMTPPassword     string
+	SMTPFrom         string
+
+	// Notifications of document reminders that came due, sent like the notification rules
+	ReminderInterval time.Duration // How often due reminders are checked (0 disables notifications)
+
+	// Webhook notifications of new matches of saved searches
+	SavedSearchInterval time.Duration // How often saved searches are checked (0 disables notifications)
+
+	// Scheduled reports exported from views
+	ReportInterval     time.Duration // How often due reports are generated (0 disables the schedules)
+	ReportDirectory    string        // Directory reports are written to; the directory destination is unavailable without it
+	ReportS3Bucket     string        // The S3 destination is unavailable without a bucket
+	ReportS3Region     string
+	ReportS3Endpoint   string // S3-compatible endpoint (e.g. MinIO), addressed path-style; empty uses AWS
+	ReportS3Prefix     string // Key prefix of uploaded reports
+	ReportS3AccessKey  string
+	ReportS3SecretKey  string
+	ReportEmailAllowed string // Comma-separated addresses and @domains reports may be emailed to besides the owner's address
+
+	// Document events posted by the Paperless post-consumption script
+	EventSecret string // Shared secret of the event endpoint; events are refused without it
+
+	// Paperless REST API, which applies bulk metadata operations
+	PaperlessURL     string // Base URL of Paperless; bulk operations cannot be applied without it
+	PaperlessToken   string // API token of a Paperless user allowed to edit the documents
+	PaperlessTimeout time.Duration
+	BulkBatchSize    int // Documents per bulk edit request
+
+	// Background precomputation of value counts for hot fields
+	PrecomputeFields     []int         // Custom field IDs to precompute
+	PrecomputeViewFields bool          // Also precompute custom field columns of saved views
+	PrecomputeInterval   time.Duration // 0 disables the scheduler
+	PrecomputeMaxAge     time.Duration // Summaries older than this are not served
+
+	// Materialized value counts kept up to date incrementally, from a trigger-filled change
+	// log on PostgreSQL and from document modification times elsewhere
+	FacetSummaryFields      []int // Custom field IDs to materialize; none disables the mode
+	FacetSummaryInterval    time.Duration
+	FacetSummaryChangeLimit int // Longer change logs are dropped and the summaries rebuilt
 }
 
 // loadConfig loads configuration from environment variables
@@ -39,6 +179,104 @@ func loadConfig() *Config {
 		DBSSLMode:    getEnv("DB_SSL_MODE", "prefer"),
 		ReadTimeout:  15 * time.Second,
 		WriteTimeout: 15 * time.Second,
+		QueryTimeout: getEnvDuration("QUERY_TIMEOUT", 30*time.Second),
+		Compression:  getEnvBool("RESPONSE_COMPRESSION", true),
+
+		QueryConcurrency: getEnvInt("QUERY_CONCURRENCY", 16),
+
+		ValueCountMode:      getEnv("VALUE_COUNT_MODE", "exact"),
+		AggregationMemoryMB: getEnvInt("AGGREGATION_MEMORY_MB", 64),
+
+		DebugFilterCounts: getEnvBool("DEBUG_FILTER_COUNTS", false),
+
+		StatementTimeout:   getEnvDuration("STATEMENT_TIMEOUT", 0),
+		SlowQueryThreshold: getEnvDuration("SLOW_QUERY_THRESHOLD", time.Second),
+
+		WarmUp: getEnvBool("WARM_UP", false),
+
+		IndexAdvisor: getEnv("INDEX_ADVISOR", "report"),
+
+		ValueCacheTTL:  getEnvDuration("VALUE_CACHE_TTL", 30*time.Second),
+		ValueCacheSize: getEnvInt("VALUE_CACHE_SIZE", 1000),
+
+		FieldMetaCacheTTL: getEnvDuration("FIELD_META_CACHE_TTL", 5*time.Minute),
+
+		BuiltinCacheTTL:       getEnvDuration("BUILTIN_CACHE_TTL", 30*time.Second),
+		BuiltinCacheNotifyTTL: getEnvDuration("BUILTIN_CACHE_NOTIFY_TTL", 15*time.Minute),
+		BuiltinCacheSize:      getEnvInt("BUILTIN_CACHE_SIZE", 500),
+		BuiltinCacheNotify:    getEnvBool("BUILTIN_CACHE_NOTIFY", true),
+		DataVersionInterval:   getEnvDuration("DATA_VERSION_INTERVAL", 5*time.Second),
+
+		CacheBackend: getEnv("CACHE_BACKEND", "memory"),
+		RedisURL:     getEnv("REDIS_URL", "redis://localhost:6379/0"),
+		RedisPrefix:  getEnv("REDIS_PREFIX", "paperless-link:"),
+		RedisTimeout: getEnvDuration("REDIS_TIMEOUT", 2*time.Second),
+		RedisPool:    getEnvInt("REDIS_POOL", 10),
+
+		LegacyTimestamps: getEnvBool("LEGACY_TIMESTAMPS", false),
+
+		MaxViewsPerUser: getEnvInt("MAX_VIEWS_PER_USER", 0),
+
+		ViewRevisionLimit:  getEnvInt("VIEW_REVISION_LIMIT", 50),
+		ViewTrashRetention: getEnvDuration("VIEW_TRASH_RETENTION", 30*24*time.Hour),
+
+		DescriptionRevisionLimit: getEnvInt("DESCRIPTION_REVISION_LIMIT", 50),
+
+		LinkGraphMaxNodes: getEnvInt("LINK_GRAPH_MAX_NODES", 200),
+
+		RecentDocumentsLimit: getEnvInt("RECENT_DOCUMENTS_LIMIT", 50),
+
+		LinkSuggestionInterval: getEnvDuration("LINK_SUGGESTION_INTERVAL", time.Hour),
+		LinkSuggestionDays:     getEnvInt("LINK_SUGGESTION_DAYS", 30),
+
+		ViewFieldCheckInterval: getEnvDuration("VIEW_FIELD_CHECK_INTERVAL", time.Hour),
+		ViewFieldPrune:         getEnvBool("VIEW_FIELD_PRUNE", false),
+
+		ViewSnapshotCheckInterval: getEnvDuration("VIEW_SNAPSHOT_CHECK_INTERVAL", time.Minute),
+		ViewSnapshotLimit:         getEnvInt("VIEW_SNAPSHOT_LIMIT", 0),
+
+		WebhookTimeout:      getEnvDuration("WEBHOOK_TIMEOUT", 10*time.Second),
+		WebhookMaxAttempts:  getEnvInt("WEBHOOK_MAX_ATTEMPTS", 5),
+		WebhookRetryBackoff: getEnvDuration("WEBHOOK_RETRY_BACKOFF", 2*time.Second),
+		WebhookAllowPrivate: getEnvBool("WEBHOOK_ALLOW_PRIVATE", false),
+		WebhookConcurrency:  getEnvInt("WEBHOOK_CONCURRENCY", 4),
+
+		ViewRuleInterval: getEnvDuration("VIEW_RULE_INTERVAL", 5*time.Minute),
+		SMTPHost:         getEnv("SMTP_HOST", ""),
+		SMTPPort:         getEnv("SMTP_PORT", "587"),
+		SMTPUsername:     getEnv("SMTP_USERNAME", ""),
+		SMTPPassword:     getEnv("SMTP_PASSWORD", ""),
+		SMTPFrom:         getEnv("SMTP_FROM", ""),
+
+		ReminderInterval: getEnvDuration("REMINDER_INTERVAL", time.Minute),
+
+		SavedSearchInterval: getEnvDuration("SAVED_SEARCH_INTERVAL", 5*time.Minute),
+
+		ReportInterval:     getEnvDuration("REPORT_INTERVAL", time.Minute),
+		ReportDirectory:    getEnv("REPORT_DIR", ""),
+		ReportS3Bucket:     getEnv("REPORT_S3_BUCKET", ""),
+		ReportS3Region:     getEnv("REPORT_S3_REGION", "us-east-1"),
+		ReportS3Endpoint:   getEnv("REPORT_S3_ENDPOINT", ""),
+		ReportS3Prefix:     getEnv("REPORT_S3_PREFIX", ""),
+		ReportS3AccessKey:  getEnv("REPORT_S3_ACCESS_KEY", ""),
+		ReportS3SecretKey:  getEnv("REPORT_S3_SECRET_KEY", ""),
+		ReportEmailAllowed: getEnv("REPORT_EMAIL_ALLOWED", ""),
+
+		EventSecret: getEnv("EVENT_SECRET", ""),
+
+		PaperlessURL:     getEnv("PAPERLESS_URL", ""),
+		PaperlessToken:   getEnv("PAPERLESS_TOKEN", ""),
+		PaperlessTimeout: getEnvDuration("PAPERLESS_TIMEOUT", time.Minute),
+		BulkBatchSize:    getEnvInt("BULK_BATCH_SIZE", 100),
+
+		PrecomputeFields:     parseFieldIDList(getEnv("PRECOMPUTE_FIELDS", "")),
+		PrecomputeViewFields: getEnvBool("PRECOMPUTE_VIEW_FIELDS", false),
+		PrecomputeInterval:   getEnvDuration("PRECOMPUTE_INTERVAL", 0),
+		PrecomputeMaxAge:     getEnvDuration("PRECOMPUTE_MAX_AGE", 15*time.Minute),
+
+		FacetSummaryFields:      parseFieldIDList(getEnv("FACET_SUMMARY_FIELDS", "")),
+		FacetSummaryInterval:    getEnvDuration("FACET_SUMMARY_INTERVAL", time.Minute),
+		FacetSummaryChangeLimit: getEnvInt("FACET_SUMMARY_CHANGE_LIMIT", 100000),
 	}
 
 	return config
@@ -51,3 +289,35 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
+func getEnvInt(key string, defaultValue int) int {
+	if value := os.Getenv(key); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err == nil {
+			return parsed
+		}
+		log.Printf("[Config] Invalid integer for %s: %q, using default %d", key, value, defaultValue)
+	}
+	return defaultValue
+}
+
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err == nil {
+			return parsed
+		}
+		log.Printf("[Config] Invalid duration for %s: %q, using default %s", key, value, defaultValue)
+	}
+	return defaultValue
+}
+
+func getEnvBool(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err == nil {
+			return parsed
+		}
+		log.Printf("[Config] Invalid boolean for %s: %q, using default %t", key, value, defaultValue)
+	}
+	return defaultValue
+}
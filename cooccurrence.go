@@ -0,0 +1,366 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// CooccurrenceAxis identifies one side of a co-occurrence matrix.
+// Either FieldID (a custom field) or Dimension (a built-in field) must be set.
+type CooccurrenceAxis struct {
+	FieldID   *int   `json:"field_id,omitempty"`
+	Dimension string `json:"dimension,omitempty"` // "correspondent", "document_type", "storage_path", "tag", "owner"
+}
+
+// CooccurrenceRequest represents the request body for the co-occurrence endpoint
+type CooccurrenceRequest struct {
+	Rows           CooccurrenceAxis         `json:"rows"`
+	Columns        CooccurrenceAxis         `json:"columns"`
+	FilterRules    []map[string]interface{} `json:"filter_rules,omitempty"`
+	IncludeTrashed bool                     `json:"include_trashed,omitempty"`
+}
+
+// CooccurrenceAxisValues describes the values found on one axis of the matrix
+type CooccurrenceAxisValues struct {
+	FieldID   *int                     `json:"field_id,omitempty"`
+	Dimension string                   `json:"dimension,omitempty"`
+	Name      string                   `json:"name"`
+	Values    []CustomFieldValueOption `json:"values"` // Count is the axis total
+}
+
+// CooccurrenceResponse represents a matrix of document counts for value pairs.
+// Matrix[i][j] is the number of documents having Rows.Values[i] and Columns.Values[j].
+type CooccurrenceResponse struct {
+	Rows           CooccurrenceAxisValues `json:"rows"`
+	Columns        CooccurrenceAxisValues `json:"columns"`
+	Matrix         [][]int                `json:"matrix"`
+	TotalDocuments int                    `json:"total_documents"` // Documents having a value on both axes
+}
+
+// axisValue is a single value attached to a document on a co-occurrence axis
+type axisValue struct {
+	id    string
+	label string
+}
+
+// GetCooccurrence computes document counts for every pair of values on two axes
+func (s *Service) GetCooccurrence(ctx context.Context, req CooccurrenceRequest) (*CooccurrenceResponse, error) {
+	filterRulesJSON := ""
+	if len(req.FilterRules) > 0 {
+		rulesBytes, _ := json.Marshal(req.FilterRules)
+		filterRulesJSON = string(rulesBytes)
+	}
+
+	docFilterWhere, docFilterArgs, err := s.buildDocumentFilterQuery(ctx, filterRulesJSON, 0, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build filter query: %w", err)
+	}
+
+	rowName, rowDocs, err := s.loadCooccurrenceAxis(ctx, req.Rows, docFilterWhere, docFilterArgs, req.IncludeTrashed)
+	if err != nil {
+		return nil, err
+	}
+	columnName, columnDocs, err := s.loadCooccurrenceAxis(ctx, req.Columns, docFilterWhere, docFilterArgs, req.IncludeTrashed)
+	if err != nil {
+		return nil, err
+	}
+
+	// Count documents per value pair; each document contributes at most once per pair
+	pairCounts := make(map[string]map[string]int)
+	rowTotals := make(map[string]int)
+	columnTotals := make(map[string]int)
+	rowLabels := make(map[string]string)
+	columnLabels := make(map[string]string)
+	totalDocuments := 0
+
+	for documentID, rowValues := range rowDocs {
+		columnValues, ok := columnDocs[documentID]
+		if !ok {
+			continue
+		}
+		totalDocuments++
+		for _, rv := range rowValues {
+			rowLabels[rv.id] = rv.label
+			rowTotals[rv.id]++
+			if pairCounts[rv.id] == nil {
+				pairCounts[rv.id] = make(map[string]int)
+			}
+			for _, cv := range columnValues {
+				pairCounts[rv.id][cv.id]++
+			}
+		}
+		for _, cv := range columnValues {
+			columnLabels[cv.id] = cv.label
+			columnTotals[cv.id]++
+		}
+	}
+
+	rowOptions := cooccurrenceOptions(rowTotals, rowLabels)
+	columnOptions := cooccurrenceOptions(columnTotals, columnLabels)
+
+	matrix := make([][]int, len(rowOptions))
+	for i, row := range rowOptions {
+		matrix[i] = make([]int, len(columnOptions))
+		for j, column := range columnOptions {
+			matrix[i][j] = pairCounts[row.ID][column.ID]
+		}
+	}
+
+	return &CooccurrenceResponse{
+		Rows: CooccurrenceAxisValues{
+			FieldID:   req.Rows.FieldID,
+			Dimension: req.Rows.Dimension,
+			Name:      rowName,
+			Values:    rowOptions,
+		},
+		Columns: CooccurrenceAxisValues{
+			FieldID:   req.Columns.FieldID,
+			Dimension: req.Columns.Dimension,
+			Name:      columnName,
+			Values:    columnOptions,
+		},
+		Matrix:         matrix,
+		TotalDocuments: totalDocuments,
+	}, nil
+}
+
+// cooccurrenceOptions converts axis totals into options sorted by count (desc)
+func cooccurrenceOptions(totals map[string]int, labels map[string]string) []CustomFieldValueOption {
+	options := []CustomFieldValueOption{}
+	for id, count := range totals {
+		options = append(options, CustomFieldValueOption{
+			ID:    id,
+			Label: labels[id],
+			Count: count,
+		})
+	}
+	sort.SliceStable(options, func(i, j int) bool {
+		if options[i].Count != options[j].Count {
+			return options[i].Count > options[j].Count
+		}
+		return options[i].Label < options[j].Label
+	})
+	return options
+}
+
+// loadCooccurrenceAxis returns the axis name and a map of document ID to the values
+// the document carries on that axis, restricted to documents matching the filter
+func (s *Service) loadCooccurrenceAxis(ctx context.Context, axis CooccurrenceAxis, docFilterWhere string, docFilterArgs []interface{}, includeTrashed bool) (string, map[int][]axisValue, error) {
+	if axis.FieldID != nil && axis.Dimension != "" {
+		return "", nil, fmt.Errorf("axis must specify either field_id or dimension, not both")
+	}
+	if axis.FieldID != nil {
+		return s.loadCustomFieldAxis(ctx, *axis.FieldID, docFilterWhere, docFilterArgs, includeTrashed)
+	}
+
+	var selectFrom string
+	var name string
+	switch axis.Dimension {
+	case "correspondent":
+		name = "Correspondent"
+		selectFrom = `
+			SELECT d.id, c.id, c.name
+			FROM documents_document d
+			INNER JOIN documents_correspondent c ON c.id = d.correspondent_id`
+	case "document_type":
+		name = "Document type"
+		selectFrom = `
+			SELECT d.id, dt.id, dt.name
+			FROM documents_document d
+			INNER JOIN documents_documenttype dt ON dt.id = d.document_type_id`
+	case "storage_path":
+		name = "Storage path"
+		selectFrom = `
+			SELECT d.id, sp.id, sp.name
+			FROM documents_document d
+			INNER JOIN documents_storagepath sp ON sp.id = d.storage_path_id`
+	case "tag":
+		name = "Tag"
+		selectFrom = `
+			SELECT d.id, t.id, t.name
+			FROM documents_document d
+			INNER JOIN documents_document_tags dtags ON dtags.document_id = d.id
+			INNER JOIN documents_tag t ON t.id = dtags.tag_id`
+	case "owner":
+		name = "Owner"
+		selectFrom = `
+			SELECT d.id, d.owner_id, d.owner_id
+			FROM documents_document d`
+	case "":
+		return "", nil, fmt.Errorf("axis must specify either field_id or dimension")
+	default:
+		return "", nil, fmt.Errorf("unsupported dimension: %s", axis.Dimension)
+	}
+
+	where := "WHERE " + trashedCondition(includeTrashed)
+	if docFilterWhere != "" {
+		where = docFilterWhere + " AND " + trashedCondition(includeTrashed)
+	}
+	if axis.Dimension == "owner" {
+		where += " AND d.owner_id IS NOT NULL"
+	}
+	query := selectFrom + "\n\t\t\t" + where
+
+	rows, err := s.db.QueryContext(ctx, query, docFilterArgs...)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to query %s values: %w", axis.Dimension, err)
+	}
+	defer rows.Close()
+
+	documents := make(map[int][]axisValue)
+	for rows.Next() {
+		var documentID int
+		var valueID int
+		var label string
+		if err := rows.Scan(&documentID, &valueID, &label); err != nil {
+			continue
+		}
+		documents[documentID] = append(documents[documentID], axisValue{
+			id:    strconv.Itoa(valueID),
+			label: label,
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return "", nil, fmt.Errorf("failed to read axis values: %w", err)
+	}
+
+	return name, documents, nil
+}
+
+// loadCustomFieldAxis loads the values of a custom field per document.
+// Values are split into individual entries the same way GetValueCounts does.
+func (s *Service) loadCustomFieldAxis(ctx context.Context, fieldID int, docFilterWhere string, docFilterArgs []interface{}, includeTrashed bool) (string, map[int][]axisValue, error) {
+	usePostgres := s.config.DBEngine == "postgresql" || s.config.DBEngine == "postgres"
+
+	meta, err := s.getFieldMeta(ctx, fieldID)
+	if err != nil {
+		return "", nil, fmt.Errorf("custom field with id %d not found: %w", fieldID, err)
+	}
+	fieldName, dataType, extraDataJSON := meta.Name, meta.DataType, meta.ExtraData
+
+	// Map select option IDs to labels
+	selectOptionMap := make(map[string]string)
+	if dataType == "select" && len(extraDataJSON) > 0 {
+		var extraData map[string]interface{}
+		if err := json.Unmarshal(extraDataJSON, &extraData); err == nil {
+			if selectOptions, ok := extraData["select_options"].([]interface{}); ok {
+				for _, opt := range selectOptions {
+					if optMap, ok := opt.(map[string]interface{}); ok {
+						if optID, ok := optMap["id"].(string); ok {
+							if optLabel, ok := optMap["label"].(string); ok {
+								selectOptionMap[optID] = optLabel
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+
+	valueColumn := getValueColumnName(dataType)
+
+	where := "WHERE " + trashedCondition(includeTrashed)
+	if docFilterWhere != "" {
+		where = docFilterWhere + " AND " + trashedCondition(includeTrashed)
+	}
+	fieldPlaceholder := "?"
+	if usePostgres {
+		fieldPlaceholder = fmt.Sprintf("$%d", len(docFilterArgs)+1)
+	}
+
+	query := fmt.Sprintf(`
+		SELECT cfi.document_id, cfi.%s
+		FROM documents_customfieldinstance cfi
+		INNER JOIN documents_document d ON cfi.document_id = d.id
+		%s
+		AND cfi.field_id = %s
+		AND cfi.deleted_at IS NULL
+		AND cfi.%s IS NOT NULL
+		AND cfi.%s != ''
+	`, valueColumn, where, fieldPlaceholder, valueColumn, valueColumn)
+	args := append(append([]interface{}{}, docFilterArgs...), fieldID)
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to query field values: %w", err)
+	}
+	defer rows.Close()
+
+	documents := make(map[int][]axisValue)
+	seen := make(map[int]map[string]bool)
+	for rows.Next() {
+		var documentID int
+		var value string
+		if err := rows.Scan(&documentID, &value); err != nil {
+			continue
+		}
+
+		for _, part := range parseValueList(value) {
+			part = strings.TrimSpace(part)
+			if part == "" {
+				continue
+			}
+			if seen[documentID] == nil {
+				seen[documentID] = make(map[string]bool)
+			}
+			if seen[documentID][part] {
+				continue
+			}
+			seen[documentID][part] = true
+
+			v := axisValue{id: generateID(part), label: part}
+			if dataType == "select" {
+				v.id = part
+				if mappedLabel, exists := selectOptionMap[part]; exists {
+					v.label = mappedLabel
+				}
+			}
+			documents[documentID] = append(documents[documentID], v)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return "", nil, fmt.Errorf("failed to read field values: %w", err)
+	}
+
+	return fieldName, documents, nil
+}
+
+// HTTP Handler for co-occurrence matrix
+func (s *Service) handleGetCooccurrence(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	log.Printf("[Cooccurrence] POST /api/custom-field-values/cooccurrence/ - Request from %s", r.RemoteAddr)
+
+	var req CooccurrenceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, fmt.Sprintf("Invalid request body: %v", err))
+		return
+	}
+
+	if wantsTrashed(r) {
+		req.IncludeTrashed = true
+	}
+
+	response, err := s.GetCooccurrence(ctx, req)
+	if err != nil {
+		if strings.Contains(err.Error(), "axis must") || strings.Contains(err.Error(), "unsupported dimension") {
+			respondError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if strings.Contains(err.Error(), "not found") {
+			respondError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		respondError(w, queryErrorStatus(err), err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, response)
+}
@@ -0,0 +1,432 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// maxVATNumberLength caps the length of a VAT number
+const maxVATNumberLength = 50
+
+// correspondentProfileColumns are the columns read by queryCorrespondentProfiles, with the
+// correspondent's name
+const correspondentProfileColumns = "p.correspondent_id, c.name, p.address, p.email, p.website, p.vat_number, p.logo_url, p.extra, p.created, p.modified, p.modified_by"
+
+// queryCorrespondentProfiles returns the profiles selected by condition keyed by
+// correspondent ID. Profiles of deleted correspondents are left out.
+func (s *Service) queryCorrespondentProfiles(ctx context.Context, condition string, args ...interface{}) (map[int]CorrespondentProfile, error) {
+	rows, err := s.db.QueryContext(ctx, s.rebind(`
+		SELECT `+correspondentProfileColumns+`
+		FROM correspondent_profiles p
+		INNER JOIN documents_correspondent c ON c.id = p.correspondent_id
+		WHERE `+condition), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query correspondent profiles: %w", err)
+	}
+	defer rows.Close()
+
+	profiles := make(map[int]CorrespondentProfile)
+	for rows.Next() {
+		var profile CorrespondentProfile
+		var name, address, email, website, vatNumber, logoURL, extraJSON sql.NullString
+		var created, modified dbTimestamp
+		var modifiedBy sql.NullInt64
+		if err := rows.Scan(&profile.CorrespondentID, &name, &address, &email, &website, &vatNumber, &logoURL, &extraJSON,
+			&created, &modified, &modifiedBy); err != nil {
+			return nil, fmt.Errorf("failed to scan correspondent profile: %w", err)
+		}
+		profile.CorrespondentName = name.String
+		for _, field := range []struct {
+			value  sql.NullString
+			target **string
+		}{{address, &profile.Address}, {email, &profile.Email}, {website, &profile.Website}, {vatNumber, &profile.VATNumber}, {logoURL, &profile.LogoURL}} {
+			if field.value.Valid {
+				value := field.value.String
+				*field.target = &value
+			}
+		}
+		if extraJSON.Valid && extraJSON.String != "" {
+			json.Unmarshal([]byte(extraJSON.String), &profile.Extra)
+		}
+		profile.Created = s.formatTimestamp(created)
+		profile.Modified = s.formatTimestamp(modified)
+		if modifiedBy.Valid {
+			userID := int(modifiedBy.Int64)
+			profile.ModifiedBy = &userID
+		}
+		profiles[profile.CorrespondentID] = profile
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read correspondent profiles: %w", err)
+	}
+	return profiles, nil
+}
+
+// ListCorrespondentProfiles returns the profiles of the correspondents, or all profiles
+// when ids is nil, ordered by correspondent name
+func (s *Service) ListCorrespondentProfiles(ctx context.Context, ids []int) ([]CorrespondentProfile, error) {
+	profiles := make(map[int]CorrespondentProfile)
+	if ids == nil {
+		var err error
+		if profiles, err = s.queryCorrespondentProfiles(ctx, "1 = 1"); err != nil {
+			return nil, err
+		}
+	} else {
+		ids = uniqueTagIDs(ids)
+		for start := 0; start < len(ids); start += tagGroupMembershipBatchSize {
+			placeholders, args := inPlaceholders(ids[start:min(start+tagGroupMembershipBatchSize, len(ids))])
+			batch, err := s.queryCorrespondentProfiles(ctx, "p.correspondent_id IN ("+placeholders+")", args...)
+			if err != nil {
+				return nil, err
+			}
+			for id, profile := range batch {
+				profiles[id] = profile
+			}
+		}
+	}
+
+	results := make([]CorrespondentProfile, 0, len(profiles))
+	for _, profile := range profiles {
+		results = append(results, profile)
+	}
+	sort.Slice(results, func(i, j int) bool {
+		nameI, nameJ := strings.ToLower(results[i].CorrespondentName), strings.ToLower(results[j].CorrespondentName)
+		if nameI != nameJ {
+			return nameI < nameJ
+		}
+		return results[i].CorrespondentID < results[j].CorrespondentID
+	})
+	return results, nil
+}
+
+// GetCorrespondentProfile retrieves the profile of a correspondent
+func (s *Service) GetCorrespondentProfile(ctx context.Context, correspondentID int) (*CorrespondentProfile, error) {
+	profiles, err := s.queryCorrespondentProfiles(ctx, "p.correspondent_id = ?", correspondentID)
+	if err != nil {
+		return nil, err
+	}
+	profile, ok := profiles[correspondentID]
+	if !ok {
+		return nil, fmt.Errorf("profile of correspondent %d not found", correspondentID)
+	}
+	return &profile, nil
+}
+
+// checkProfileURL validates an absolute http or https URL of a profile field
+func checkProfileURL(field string, value string) error {
+	parsed, err := url.Parse(value)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("invalid %s: must be an absolute http or https URL", field)
+	}
+	return nil
+}
+
+// checkCorrespondentProfile normalizes and validates a profile before it is stored. Blank
+// fields are removed.
+func checkCorrespondentProfile(profile *CorrespondentProfile) error {
+	for _, field := range []**string{&profile.Address, &profile.Email, &profile.Website, &profile.VATNumber, &profile.LogoURL} {
+		if *field != nil {
+			trimmed := strings.TrimSpace(**field)
+			*field = &trimmed
+			if trimmed == "" {
+				*field = nil
+			}
+		}
+	}
+	if profile.Email != nil {
+		address, err := mail.ParseAddress(*profile.Email)
+		if err != nil {
+			return fmt.Errorf("invalid email: %v", err)
+		}
+		profile.Email = &address.Address
+	}
+	if profile.Website != nil {
+		if err := checkProfileURL("website", *profile.Website); err != nil {
+			return err
+		}
+	}
+	if profile.LogoURL != nil {
+		if err := checkProfileURL("logo_url", *profile.LogoURL); err != nil {
+			return err
+		}
+	}
+	if profile.VATNumber != nil {
+		vatNumber := strings.ToUpper(*profile.VATNumber)
+		if len(vatNumber) > maxVATNumberLength {
+			return fmt.Errorf("invalid vat_number: at most %d characters", maxVATNumberLength)
+		}
+		profile.VATNumber = &vatNumber
+	}
+	if len(profile.Extra) == 0 {
+		profile.Extra = nil
+	}
+	return nil
+}
+
+// SetCorrespondentProfile creates or replaces the profile of a correspondent on behalf of a
+// user (0 when unknown)
+func (s *Service) SetCorrespondentProfile(ctx context.Context, profile CorrespondentProfile, userID int) (*CorrespondentProfile, error) {
+	log.Printf("[CorrespondentProfiles] SetCorrespondentProfile - Correspondent: %d, UserID: %d", profile.CorrespondentID, userID)
+	if err := checkCorrespondentProfile(&profile); err != nil {
+		return nil, err
+	}
+	var exists int
+	err := s.db.QueryRowContext(ctx, s.rebind("SELECT COUNT(*) FROM documents_correspondent WHERE id = ?"), profile.CorrespondentID).Scan(&exists)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query correspondent: %w", err)
+	}
+	if exists == 0 {
+		return nil, fmt.Errorf("correspondent with id %d not found", profile.CorrespondentID)
+	}
+	var extraJSON *string
+	if profile.Extra != nil {
+		encoded, err := json.Marshal(profile.Extra)
+		if err != nil {
+			return nil, fmt.Errorf("invalid extra: %v", err)
+		}
+		value := string(encoded)
+		extraJSON = &value
+	}
+	var modifiedBy *int
+	if userID > 0 {
+		modifiedBy = &userID
+	}
+	cast := ""
+	if s.config.DBEngine == "postgresql" || s.config.DBEngine == "postgres" {
+		cast = "::jsonb"
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, s.rebind(`
+		UPDATE correspondent_profiles
+		SET address = ?, email = ?, website = ?, vat_number = ?, logo_url = ?, extra = ?`+cast+`, modified = CURRENT_TIMESTAMP, modified_by = ?
+		WHERE correspondent_id = ?
+	`), profile.Address, profile.Email, profile.Website, profile.VATNumber, profile.LogoURL, extraJSON, modifiedBy, profile.CorrespondentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save correspondent profile: %w", err)
+	}
+	if updated, _ := result.RowsAffected(); updated == 0 {
+		_, err := tx.ExecContext(ctx, s.rebind(`
+			INSERT INTO correspondent_profiles (correspondent_id, address, email, website, vat_number, logo_url, extra, modified_by)
+			VALUES (?, ?, ?, ?, ?, ?, ?`+cast+`, ?)
+		`), profile.CorrespondentID, profile.Address, profile.Email, profile.Website, profile.VATNumber, profile.LogoURL, extraJSON, modifiedBy)
+		if err != nil {
+			return nil, fmt.Errorf("failed to save correspondent profile: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit correspondent profile: %w", err)
+	}
+	// Expanded correspondent values are revalidated
+	s.documentGeneration.Add(1)
+
+	return s.GetCorrespondentProfile(ctx, profile.CorrespondentID)
+}
+
+// PatchCorrespondentProfile applies a JSON Merge Patch to the profile of a correspondent:
+// null removes a field, and extra is merged
+func (s *Service) PatchCorrespondentProfile(ctx context.Context, correspondentID int, patch []byte, userID int) (*CorrespondentProfile, error) {
+	existing, err := s.GetCorrespondentProfile(ctx, correspondentID)
+	if err != nil {
+		return nil, err
+	}
+	var profile CorrespondentProfile
+	if err := mergePatchJSON(existing, patch, &profile); err != nil {
+		return nil, err
+	}
+	profile.CorrespondentID = correspondentID
+	return s.SetCorrespondentProfile(ctx, profile, userID)
+}
+
+// DeleteCorrespondentProfile deletes the profile of a correspondent
+func (s *Service) DeleteCorrespondentProfile(ctx context.Context, correspondentID int) error {
+	log.Printf("[CorrespondentProfiles] DeleteCorrespondentProfile - Correspondent: %d", correspondentID)
+	result, err := s.db.ExecContext(ctx, s.rebind("DELETE FROM correspondent_profiles WHERE correspondent_id = ?"), correspondentID)
+	if err != nil {
+		return fmt.Errorf("failed to delete correspondent profile: %w", err)
+	}
+	if deleted, _ := result.RowsAffected(); deleted == 0 {
+		return fmt.Errorf("profile of correspondent %d not found", correspondentID)
+	}
+	s.documentGeneration.Add(1)
+	return nil
+}
+
+// expandCorrespondentProfiles returns a copy of correspondent options with their profiles,
+// leaving the (possibly cached) options untouched
+func (s *Service) expandCorrespondentProfiles(ctx context.Context, values []BuiltinFilterValueOption) ([]BuiltinFilterValueOption, error) {
+	expanded := make([]BuiltinFilterValueOption, len(values))
+	copy(expanded, values)
+	if len(values) == 0 {
+		return expanded, nil
+	}
+
+	profiles, err := s.queryCorrespondentProfiles(ctx, "1 = 1")
+	if err != nil {
+		return nil, err
+	}
+	for i := range expanded {
+		id, err := strconv.Atoi(builtinOptionKey(expanded[i].ID))
+		if err != nil {
+			continue
+		}
+		if profile, ok := profiles[id]; ok {
+			expanded[i].Profile = &profile
+		}
+	}
+	return expanded, nil
+}
+
+// checkFacetExpand validates the expand option of a builtin filter type
+func checkFacetExpand(filterType string, expand string) error {
+	if expand == "" {
+		return nil
+	}
+	if expand != "profile" || filterType != "correspondent" {
+		return fmt.Errorf("Invalid expand %q: only the correspondent values can be expanded with profile", expand)
+	}
+	return nil
+}
+
+// correspondentProfileErrorStatus maps correspondent profile errors to HTTP status codes
+func correspondentProfileErrorStatus(err error) int {
+	switch {
+	case strings.HasPrefix(err.Error(), "invalid"):
+		return http.StatusBadRequest
+	case strings.Contains(err.Error(), "not found"):
+		return http.StatusNotFound
+	}
+	return queryErrorStatus(err)
+}
+
+// HTTP Handlers for correspondent profiles
+func (s *Service) handleListCorrespondentProfiles(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	log.Printf("[CorrespondentProfiles] GET /api/correspondent-profiles/ - Request from %s", r.RemoteAddr)
+
+	var ids []int
+	if r.URL.Query().Has("ids") {
+		var err error
+		if ids, err = parseIDList(r.URL.Query().Get("ids"), "ids"); err != nil {
+			respondError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	profiles, err := s.ListCorrespondentProfiles(ctx, ids)
+	if err != nil {
+		log.Printf("[CorrespondentProfiles] Error listing correspondent profiles: %v", err)
+		respondError(w, queryErrorStatus(err), err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, CorrespondentProfileListResponse{Count: len(profiles), Results: profiles})
+}
+
+func (s *Service) handleGetCorrespondentProfile(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	idStr := mux.Vars(r)["correspondentId"]
+	log.Printf("[CorrespondentProfiles] GET /api/correspondent-profiles/%s/ - Request from %s", idStr, r.RemoteAddr)
+
+	correspondentID, err := strconv.Atoi(idStr)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid correspondent ID")
+		return
+	}
+
+	profile, err := s.GetCorrespondentProfile(ctx, correspondentID)
+	if err != nil {
+		respondError(w, correspondentProfileErrorStatus(err), err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, profile)
+}
+
+func (s *Service) handleSetCorrespondentProfile(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	idStr := mux.Vars(r)["correspondentId"]
+	log.Printf("[CorrespondentProfiles] %s /api/correspondent-profiles/%s/ - Request from %s", r.Method, idStr, r.RemoteAddr)
+
+	viewerID, err := viewerFromRequest(r)
+	if err != nil {
+		respondError(w, viewerErrorStatus(err), err.Error())
+		return
+	}
+
+	correspondentID, err := strconv.Atoi(idStr)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid correspondent ID")
+		return
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, fmt.Sprintf("Invalid request body: %v", err))
+		return
+	}
+
+	var saved *CorrespondentProfile
+	if r.Method == http.MethodPatch {
+		saved, err = s.PatchCorrespondentProfile(ctx, correspondentID, body, viewerID)
+	} else {
+		var profile CorrespondentProfile
+		if err := json.Unmarshal(body, &profile); err != nil {
+			respondError(w, http.StatusBadRequest, fmt.Sprintf("Invalid request body: %v", err))
+			return
+		}
+		profile.CorrespondentID = correspondentID
+		saved, err = s.SetCorrespondentProfile(ctx, profile, viewerID)
+	}
+	if err != nil {
+		log.Printf("[CorrespondentProfiles] Error saving profile of correspondent %d: %v", correspondentID, err)
+		respondError(w, correspondentProfileErrorStatus(err), err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, saved)
+}
+
+func (s *Service) handleDeleteCorrespondentProfile(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	idStr := mux.Vars(r)["correspondentId"]
+	log.Printf("[CorrespondentProfiles] DELETE /api/correspondent-profiles/%s/ - Request from %s", idStr, r.RemoteAddr)
+
+	correspondentID, err := strconv.Atoi(idStr)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid correspondent ID")
+		return
+	}
+
+	if err := s.DeleteCorrespondentProfile(ctx, correspondentID); err != nil {
+		log.Printf("[CorrespondentProfiles] Error deleting profile of correspondent %d: %v", correspondentID, err)
+		respondError(w, correspondentProfileErrorStatus(err), err.Error())
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,507 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Kinds of custom field data-quality issues
+const (
+	AuditIssueTypeMismatch   = "type_mismatch"     // Value that does not parse as the field's data type
+	AuditIssueDateOutOfRange = "date_out_of_range" // Date outside the plausible range
+	AuditIssueOrphanSelect   = "orphan_select"     // Select value that is not an option of the field
+)
+
+// auditIssueKinds are the supported issue kinds
+var auditIssueKinds = map[string]bool{
+	AuditIssueTypeMismatch:   true,
+	AuditIssueDateOutOfRange: true,
+	AuditIssueOrphanSelect:   true,
+}
+
+// Default plausible range of date values
+var (
+	defaultAuditMinDate = time.Date(1900, time.January, 1, 0, 0, 0, 0, time.UTC)
+	defaultAuditMaxDate = time.Date(2100, time.December, 31, 0, 0, 0, 0, time.UTC)
+)
+
+// CustomFieldAudit summarizes the data quality of a custom field
+type CustomFieldAudit struct {
+	FieldID            int     `json:"field_id"`
+	FieldName          string  `json:"field_name"`
+	DataType           string  `json:"data_type"`
+	TotalDocuments     int     `json:"total_documents"`
+	FilledDocuments    int     `json:"filled_documents"`
+	FillRate           float64 `json:"fill_rate"` // Percentage of documents with a non-blank value
+	TypeMismatches     int     `json:"type_mismatches"`
+	DatesOutOfRange    int     `json:"dates_out_of_range"`
+	OrphanSelectValues int     `json:"orphan_select_values"`
+	IssueCount         int     `json:"issue_count"`
+}
+
+// CustomFieldAuditIssue is a value of a document found by the audit
+type CustomFieldAuditIssue struct {
+	Kind       string `json:"kind"`
+	DocumentID int    `json:"document_id"`
+	Value      string `json:"value"`
+	Message    string `json:"message"`
+}
+
+// CustomFieldAuditResponse is the audit of all custom fields, ordered by name
+type CustomFieldAuditResponse struct {
+	MinDate    string             `json:"min_date"`
+	MaxDate    string             `json:"max_date"`
+	IssueCount int                `json:"issue_count"`
+	Fields     []CustomFieldAudit `json:"fields"`
+}
+
+// CustomFieldAuditDetailResponse is the audit of a custom field with a page of its issues
+type CustomFieldAuditDetailResponse struct {
+	Field    CustomFieldAudit        `json:"field"`
+	Count    int                     `json:"count"`
+	Next     *string                 `json:"next,omitempty"`
+	Previous *string                 `json:"previous,omitempty"`
+	Results  []CustomFieldAuditIssue `json:"results"`
+}
+
+// AuditDocument is a document listed by an audit drill-down
+type AuditDocument struct {
+	ID    int    `json:"id"`
+	Title string `json:"title"`
+}
+
+// AuditDocumentListResponse is a page of documents of an audit drill-down
+type AuditDocumentListResponse struct {
+	FieldID  int             `json:"field_id"`
+	Count    int             `json:"count"`
+	Next     *string         `json:"next,omitempty"`
+	Previous *string         `json:"previous,omitempty"`
+	Results  []AuditDocument `json:"results"`
+}
+
+// auditField is the definition of a custom field as read by the audit
+type auditField struct {
+	ID            int
+	Name          string
+	DataType      string
+	SelectOptions map[string]bool
+}
+
+// auditTextColumns are the value columns holding text, where blank strings count as empty
+var auditTextColumns = map[string]bool{
+	"value_text": true, "value_url": true, "value_monetary": true, "value_select": true, "value_long_text": true,
+}
+
+// queryAuditFields retrieves the custom fields to audit, all of them when fieldID is 0
+func (s *Service) queryAuditFields(ctx context.Context, fieldID int) ([]auditField, error) {
+	query := "SELECT id, name, data_type, extra_data FROM documents_customfield ORDER BY name ASC, id ASC"
+	var args []interface{}
+	if fieldID > 0 {
+		query = s.rebind("SELECT id, name, data_type, extra_data FROM documents_customfield WHERE id = ?")
+		args = append(args, fieldID)
+	}
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query custom fields: %w", err)
+	}
+	defer rows.Close()
+
+	fields := []auditField{}
+	for rows.Next() {
+		var field auditField
+		var name, dataType sql.NullString
+		var extraDataJSON []byte
+		if err := rows.Scan(&field.ID, &name, &dataType, &extraDataJSON); err != nil {
+			return nil, fmt.Errorf("failed to scan custom field: %w", err)
+		}
+		field.Name = name.String
+		field.DataType = dataType.String
+		field.SelectOptions = make(map[string]bool)
+		if field.DataType == "select" && len(extraDataJSON) > 0 {
+			var extraData map[string]interface{}
+			if err := json.Unmarshal(extraDataJSON, &extraData); err == nil {
+				if selectOptions, ok := extraData["select_options"].([]interface{}); ok {
+					for _, opt := range selectOptions {
+						if optMap, ok := opt.(map[string]interface{}); ok {
+							if optID, ok := optMap["id"].(string); ok {
+								field.SelectOptions[optID] = true
+							}
+						}
+					}
+				}
+			}
+		}
+		fields = append(fields, field)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read custom fields: %w", err)
+	}
+	if fieldID > 0 && len(fields) == 0 {
+		return nil, fmt.Errorf("custom field with id %d not found", fieldID)
+	}
+	return fields, nil
+}
+
+// auditValue checks a non-blank value of a field and returns the issue it has, if any
+func auditValue(field auditField, raw interface{}, minDate, maxDate time.Time) (string, string) {
+	value := strings.TrimSpace(statsString(raw))
+	switch field.DataType {
+	case "integer":
+		if _, ok := raw.(int64); ok {
+			return "", ""
+		}
+		if _, err := strconv.ParseInt(value, 10, 64); err != nil {
+			return AuditIssueTypeMismatch, fmt.Sprintf("%q is not an integer", value)
+		}
+	case "float":
+		if _, ok := raw.(float64); ok {
+			return "", ""
+		}
+		if _, err := strconv.ParseFloat(value, 64); err != nil {
+			return AuditIssueTypeMismatch, fmt.Sprintf("%q is not a number", value)
+		}
+	case "monetary":
+		if _, ok := parseStatsNumber(raw); !ok {
+			return AuditIssueTypeMismatch, fmt.Sprintf("%q is not an amount", value)
+		}
+	case "boolean":
+		switch strings.ToLower(value) {
+		case "0", "1", "true", "false", "t", "f":
+		default:
+			return AuditIssueTypeMismatch, fmt.Sprintf("%q is not a boolean", value)
+		}
+	case "date":
+		date, ok := parseStatsDate(raw)
+		if !ok {
+			return AuditIssueTypeMismatch, fmt.Sprintf("%q is not a date", value)
+		}
+		if date.Before(minDate) || date.After(maxDate) {
+			return AuditIssueDateOutOfRange, fmt.Sprintf("%s is outside %s to %s", date.Format("2006-01-02"),
+				minDate.Format("2006-01-02"), maxDate.Format("2006-01-02"))
+		}
+	case "select":
+		if !field.SelectOptions[value] {
+			return AuditIssueOrphanSelect, fmt.Sprintf("%q is not an option of the field", value)
+		}
+	}
+	return "", ""
+}
+
+// auditCustomField scans the values of a field on the documents counted in totalDocuments.
+// The issues themselves are only collected with collect, the summary counts them either way.
+func (s *Service) auditCustomField(ctx context.Context, field auditField, totalDocuments int, includeTrashed bool, minDate, maxDate time.Time, collect bool) (*CustomFieldAudit, []CustomFieldAuditIssue, error) {
+	audit := &CustomFieldAudit{
+		FieldID:        field.ID,
+		FieldName:      field.Name,
+		DataType:       field.DataType,
+		TotalDocuments: totalDocuments,
+	}
+
+	valueColumn := getValueColumnName(field.DataType)
+	rows, err := s.db.QueryContext(ctx, s.rebind(fmt.Sprintf(`
+		SELECT cfi.document_id, cfi.%s
+		FROM documents_customfieldinstance cfi
+		INNER JOIN documents_document d ON cfi.document_id = d.id
+		WHERE cfi.field_id = ?
+			AND cfi.deleted_at IS NULL
+			AND %s
+			AND cfi.%s IS NOT NULL
+		ORDER BY cfi.document_id ASC
+	`, valueColumn, trashedCondition(includeTrashed), valueColumn)), field.ID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to query values of field %d: %w", field.ID, err)
+	}
+	defer rows.Close()
+
+	filled := make(map[int]bool)
+	issues := []CustomFieldAuditIssue{}
+	for rows.Next() {
+		var documentID int
+		var raw interface{}
+		if err := rows.Scan(&documentID, &raw); err != nil {
+			return nil, nil, fmt.Errorf("failed to scan value of field %d: %w", field.ID, err)
+		}
+		if strings.TrimSpace(statsString(raw)) == "" {
+			continue
+		}
+		filled[documentID] = true
+
+		kind, message := auditValue(field, raw, minDate, maxDate)
+		switch kind {
+		case "":
+			continue
+		case AuditIssueTypeMismatch:
+			audit.TypeMismatches++
+		case AuditIssueDateOutOfRange:
+			audit.DatesOutOfRange++
+		case AuditIssueOrphanSelect:
+			audit.OrphanSelectValues++
+		}
+		audit.IssueCount++
+		if collect {
+			issues = append(issues, CustomFieldAuditIssue{Kind: kind, DocumentID: documentID, Value: statsString(raw), Message: message})
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("failed to read values of field %d: %w", field.ID, err)
+	}
+
+	audit.FilledDocuments = len(filled)
+	if audit.TotalDocuments > 0 {
+		audit.FillRate = math.Round(float64(audit.FilledDocuments)/float64(audit.TotalDocuments)*10000) / 100
+	}
+	return audit, issues, nil
+}
+
+// countAuditDocuments counts the documents the audit considers
+func (s *Service) countAuditDocuments(ctx context.Context, includeTrashed bool) (int, error) {
+	var total int
+	query := fmt.Sprintf("SELECT COUNT(*) FROM documents_document d WHERE %s", trashedCondition(includeTrashed))
+	if err := s.db.QueryRowContext(ctx, query).Scan(&total); err != nil {
+		return 0, fmt.Errorf("failed to count documents: %w", err)
+	}
+	return total, nil
+}
+
+// AuditCustomFields audits all custom fields
+func (s *Service) AuditCustomFields(ctx context.Context, includeTrashed bool, minDate, maxDate time.Time) (*CustomFieldAuditResponse, error) {
+	log.Printf("[Audit] AuditCustomFields - IncludeTrashed: %t", includeTrashed)
+	fields, err := s.queryAuditFields(ctx, 0)
+	if err != nil {
+		return nil, err
+	}
+	total, err := s.countAuditDocuments(ctx, includeTrashed)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &CustomFieldAuditResponse{
+		MinDate: minDate.Format("2006-01-02"),
+		MaxDate: maxDate.Format("2006-01-02"),
+		Fields:  []CustomFieldAudit{},
+	}
+	for _, field := range fields {
+		audit, _, err := s.auditCustomField(ctx, field, total, includeTrashed, minDate, maxDate, false)
+		if err != nil {
+			return nil, err
+		}
+		response.IssueCount += audit.IssueCount
+		response.Fields = append(response.Fields, *audit)
+	}
+	return response, nil
+}
+
+// AuditCustomField audits a custom field and returns its issues, only those of kind unless
+// it is empty
+func (s *Service) AuditCustomField(ctx context.Context, fieldID int, kind string, includeTrashed bool, minDate, maxDate time.Time) (*CustomFieldAudit, []CustomFieldAuditIssue, error) {
+	log.Printf("[Audit] AuditCustomField - FieldID: %d, Kind: %q", fieldID, kind)
+	fields, err := s.queryAuditFields(ctx, fieldID)
+	if err != nil {
+		return nil, nil, err
+	}
+	total, err := s.countAuditDocuments(ctx, includeTrashed)
+	if err != nil {
+		return nil, nil, err
+	}
+	audit, issues, err := s.auditCustomField(ctx, fields[0], total, includeTrashed, minDate, maxDate, true)
+	if err != nil {
+		return nil, nil, err
+	}
+	if kind != "" {
+		matching := []CustomFieldAuditIssue{}
+		for _, issue := range issues {
+			if issue.Kind == kind {
+				matching = append(matching, issue)
+			}
+		}
+		issues = matching
+	}
+	return audit, issues, nil
+}
+
+// GetCustomFieldMissingDocuments lists the documents without a non-blank value for a field,
+// lowest ID first
+func (s *Service) GetCustomFieldMissingDocuments(ctx context.Context, fieldID int, includeTrashed bool, pagination Pagination) (*AuditDocumentListResponse, error) {
+	fields, err := s.queryAuditFields(ctx, fieldID)
+	if err != nil {
+		return nil, err
+	}
+	valueColumn := getValueColumnName(fields[0].DataType)
+	filledCondition := fmt.Sprintf("cfi.%s IS NOT NULL", valueColumn)
+	if auditTextColumns[valueColumn] {
+		filledCondition += fmt.Sprintf(" AND TRIM(cfi.%s) <> ''", valueColumn)
+	}
+	// The field ID is an integer, so it is safe to inline
+	condition := fmt.Sprintf(`%s AND NOT EXISTS (
+			SELECT 1 FROM documents_customfieldinstance cfi
+			WHERE cfi.document_id = d.id AND cfi.field_id = %d AND cfi.deleted_at IS NULL AND %s)`,
+		trashedCondition(includeTrashed), fieldID, filledCondition)
+
+	response := &AuditDocumentListResponse{FieldID: fieldID, Results: []AuditDocument{}}
+	if err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM documents_document d WHERE "+condition).Scan(&response.Count); err != nil {
+		return nil, fmt.Errorf("failed to count documents without a value: %w", err)
+	}
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf("SELECT d.id, d.title FROM documents_document d WHERE %s ORDER BY d.id ASC %s",
+		condition, pagination.limitClause()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query documents without a value: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var document AuditDocument
+		var title sql.NullString
+		if err := rows.Scan(&document.ID, &title); err != nil {
+			return nil, fmt.Errorf("failed to scan document: %w", err)
+		}
+		document.Title = title.String
+		response.Results = append(response.Results, document)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read documents without a value: %w", err)
+	}
+	return response, nil
+}
+
+// parseAuditDateRange reads the min_date and max_date query parameters
+func parseAuditDateRange(r *http.Request) (time.Time, time.Time, error) {
+	minDate, maxDate := defaultAuditMinDate, defaultAuditMaxDate
+	if value := r.URL.Query().Get("min_date"); value != "" {
+		parsed, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			return minDate, maxDate, fmt.Errorf("Invalid min_date, expected YYYY-MM-DD")
+		}
+		minDate = parsed
+	}
+	if value := r.URL.Query().Get("max_date"); value != "" {
+		parsed, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			return minDate, maxDate, fmt.Errorf("Invalid max_date, expected YYYY-MM-DD")
+		}
+		maxDate = parsed
+	}
+	if maxDate.Before(minDate) {
+		return minDate, maxDate, fmt.Errorf("Invalid date range, max_date is before min_date")
+	}
+	return minDate, maxDate, nil
+}
+
+// HTTP Handlers for the custom field audit
+func (s *Service) handleAuditCustomFields(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	log.Printf("[Audit] GET /api/audit/custom-fields/ - Request from %s", r.RemoteAddr)
+
+	minDate, maxDate, err := parseAuditDateRange(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	response, err := s.AuditCustomFields(ctx, wantsTrashed(r), minDate, maxDate)
+	if err != nil {
+		log.Printf("[Audit] Error auditing custom fields: %v", err)
+		respondError(w, queryErrorStatus(err), err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, response)
+}
+
+func (s *Service) handleAuditCustomField(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	fieldIDStr := mux.Vars(r)["fieldId"]
+	log.Printf("[Audit] GET /api/audit/custom-fields/%s/ - Request from %s", fieldIDStr, r.RemoteAddr)
+
+	fieldID, err := strconv.Atoi(fieldIDStr)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid field ID")
+		return
+	}
+	kind := r.URL.Query().Get("issue")
+	if kind != "" && !auditIssueKinds[kind] {
+		respondError(w, http.StatusBadRequest, "Invalid issue, expected type_mismatch, date_out_of_range or orphan_select")
+		return
+	}
+	minDate, maxDate, err := parseAuditDateRange(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	pagination, err := parsePagination(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	// Issues are always paginated
+	if pagination.PageSize == 0 {
+		pagination.PageSize = defaultPageSize
+	}
+
+	audit, issues, err := s.AuditCustomField(ctx, fieldID, kind, wantsTrashed(r), minDate, maxDate)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			respondError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		log.Printf("[Audit] Error auditing custom field %d: %v", fieldID, err)
+		respondError(w, queryErrorStatus(err), err.Error())
+		return
+	}
+
+	start := min((pagination.Page-1)*pagination.PageSize, len(issues))
+	end := min(start+pagination.PageSize, len(issues))
+	response := CustomFieldAuditDetailResponse{Field: *audit, Count: len(issues), Results: issues[start:end]}
+	response.Next, response.Previous = pageLinks(r, pagination, response.Count)
+
+	respondJSON(w, http.StatusOK, response)
+}
+
+func (s *Service) handleGetCustomFieldMissingDocuments(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	fieldIDStr := mux.Vars(r)["fieldId"]
+	log.Printf("[Audit] GET /api/audit/custom-fields/%s/missing/ - Request from %s", fieldIDStr, r.RemoteAddr)
+
+	fieldID, err := strconv.Atoi(fieldIDStr)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid field ID")
+		return
+	}
+	pagination, err := parsePagination(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	// Documents are always paginated
+	if pagination.PageSize == 0 {
+		pagination.PageSize = defaultPageSize
+	}
+
+	response, err := s.GetCustomFieldMissingDocuments(ctx, fieldID, wantsTrashed(r), pagination)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			respondError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		log.Printf("[Audit] Error listing documents without field %d: %v", fieldID, err)
+		respondError(w, queryErrorStatus(err), err.Error())
+		return
+	}
+	response.Next, response.Previous = pageLinks(r, pagination, response.Count)
+
+	respondJSON(w, http.StatusOK, response)
+}
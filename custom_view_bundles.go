@@ -0,0 +1,252 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// customViewBundleVersion is the version of the custom view export format
+const customViewBundleVersion = 1
+
+// CustomViewBundle is the portable JSON form of one or more custom views. Instance
+// specific fields (IDs, owners, timestamps) are left out.
+type CustomViewBundle struct {
+	Version    int          `json:"version"`
+	ExportedAt string       `json:"exported_at,omitempty"`
+	Views      []CustomView `json:"views"`
+}
+
+// CustomViewImportResult reports what an import did with each view of a bundle
+type CustomViewImportResult struct {
+	Created []CustomView `json:"created"`
+	Updated []CustomView `json:"updated"`
+	Skipped []string     `json:"skipped"` // Names of views that already existed
+}
+
+// customViewConflictStrategies are the supported ways of importing a view whose name
+// the user already uses
+var customViewConflictStrategies = map[string]bool{"skip": true, "rename": true, "overwrite": true}
+
+// portableCustomView strips the instance specific fields of a view
+func portableCustomView(view CustomView) CustomView {
+	view.ID = nil
+	view.OwnerID = nil
+	view.Username = nil
+	view.Created = nil
+	view.Modified = nil
+	view.DeletedAt = nil
+	view.DocumentCount = nil
+	view.CountedAt = nil
+	view.DisplayName = nil
+	view.DisplayDescription = nil
+	view.Locale = nil
+	view.DanglingFieldIDs = nil
+	return view
+}
+
+// ExportCustomViews bundles views: the one with the given id, or all views listed for the
+// user when id is 0
+func (s *Service) ExportCustomViews(ctx context.Context, id int, userID int) (*CustomViewBundle, error) {
+	var views []CustomView
+	if id != 0 {
+		view, err := s.GetCustomView(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		views = []CustomView{*view}
+	} else {
+		listed, _, err := s.ListCustomViews(ctx, &userID, CustomViewListOptions{Ordering: "name"})
+		if err != nil {
+			return nil, err
+		}
+		views = listed
+	}
+
+	bundle := &CustomViewBundle{
+		Version:    customViewBundleVersion,
+		ExportedAt: time.Now().UTC().Format(time.RFC3339),
+		Views:      make([]CustomView, 0, len(views)),
+	}
+	for _, view := range views {
+		bundle.Views = append(bundle.Views, portableCustomView(view))
+	}
+	return bundle, nil
+}
+
+// findCustomViewByName returns the ID of the user's view with the given name, 0 if none
+func (s *Service) findCustomViewByName(ctx context.Context, name string, userID int) (int, error) {
+	query := "SELECT id FROM custom_views WHERE owner_id = ? AND name = ? AND deleted_at IS NULL ORDER BY id LIMIT 1"
+	if s.config.DBEngine == "postgresql" || s.config.DBEngine == "postgres" {
+		query = "SELECT id FROM custom_views WHERE owner_id = $1 AND name = $2 AND deleted_at IS NULL ORDER BY id LIMIT 1"
+	}
+	var id int
+	err := s.db.QueryRowContext(ctx, query, userID, name).Scan(&id)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to look up custom view %q: %w", name, err)
+	}
+	return id, nil
+}
+
+// ImportCustomViews creates the views of a bundle for the user. Views whose name the user
+// already uses are skipped, imported under a numbered name ("Name (2)") or overwrite the
+// existing view, depending on conflict.
+func (s *Service) ImportCustomViews(ctx context.Context, bundle CustomViewBundle, conflict string, userID int, username string) (*CustomViewImportResult, error) {
+	if bundle.Version != customViewBundleVersion {
+		return nil, fmt.Errorf("unsupported bundle version %d (expected %d)", bundle.Version, customViewBundleVersion)
+	}
+	if !customViewConflictStrategies[conflict] {
+		return nil, fmt.Errorf("invalid conflict strategy %q (expected skip, rename or overwrite)", conflict)
+	}
+	for i, view := range bundle.Views {
+		if strings.TrimSpace(view.Name) == "" {
+			return nil, fmt.Errorf("invalid bundle: view %d has no name", i+1)
+		}
+	}
+
+	result := &CustomViewImportResult{Created: []CustomView{}, Updated: []CustomView{}, Skipped: []string{}}
+	for _, view := range bundle.Views {
+		view = portableCustomView(view)
+
+		existingID, err := s.findCustomViewByName(ctx, view.Name, userID)
+		if err != nil {
+			return nil, err
+		}
+		if existingID != 0 {
+			switch conflict {
+			case "skip":
+				result.Skipped = append(result.Skipped, view.Name)
+				continue
+			case "overwrite":
+				updated, err := s.ReplaceCustomView(ctx, existingID, view, userID)
+				if err != nil {
+					return nil, err
+				}
+				result.Updated = append(result.Updated, *updated)
+				continue
+			case "rename":
+				base := view.Name
+				for n := 2; existingID != 0; n++ {
+					view.Name = fmt.Sprintf("%s (%d)", base, n)
+					if existingID, err = s.findCustomViewByName(ctx, view.Name, userID); err != nil {
+						return nil, err
+					}
+				}
+			}
+		}
+
+		created, err := s.CreateCustomView(ctx, view, userID, username)
+		if err != nil {
+			return nil, err
+		}
+		result.Created = append(result.Created, *created)
+	}
+
+	log.Printf("[CustomViews] Imported bundle: %d created, %d updated, %d skipped", len(result.Created), len(result.Updated), len(result.Skipped))
+	return result, nil
+}
+
+// HTTP Handlers for Custom View bundles
+func (s *Service) handleExportCustomViews(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	id := 0
+	if idStr, ok := mux.Vars(r)["id"]; ok {
+		var err error
+		if id, err = strconv.Atoi(idStr); err != nil {
+			respondError(w, http.StatusBadRequest, "Invalid view ID")
+			return
+		}
+	}
+	log.Printf("[CustomViews] GET %s - Request from %s", r.URL.Path, r.RemoteAddr)
+
+	userID, err := getUserIDFromRequest(r)
+	if err != nil {
+		respondError(w, viewerErrorStatus(err), err.Error())
+		return
+	}
+
+	bundle, err := s.ExportCustomViews(ctx, id, *userID)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			respondError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		respondError(w, queryErrorStatus(err), err.Error())
+		return
+	}
+
+	filename := "custom-views.json"
+	if id != 0 {
+		filename = fmt.Sprintf("custom-view-%d.json", id)
+	}
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	respondJSON(w, http.StatusOK, bundle)
+}
+
+func (s *Service) handleImportCustomViews(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	log.Printf("[CustomViews] POST /api/custom_views/import/ - Request from %s", r.RemoteAddr)
+
+	var bundle CustomViewBundle
+	if err := json.NewDecoder(r.Body).Decode(&bundle); err != nil {
+		respondError(w, http.StatusBadRequest, fmt.Sprintf("Invalid request body: %v", err))
+		return
+	}
+	conflict := r.URL.Query().Get("conflict")
+	if conflict == "" {
+		conflict = "skip"
+	}
+
+	userID, err := getUserIDFromRequest(r)
+	if err != nil {
+		respondError(w, viewerErrorStatus(err), err.Error())
+		return
+	}
+	username := getUsernameFromRequest(r)
+
+	problems, err := s.ValidateCustomViewBundle(ctx, bundle)
+	if err != nil {
+		respondError(w, queryErrorStatus(err), err.Error())
+		return
+	}
+	if len(problems) > 0 {
+		respondValidationErrors(w, "Invalid custom view bundle", problems)
+		return
+	}
+
+	result, err := s.ImportCustomViews(ctx, bundle, conflict, *userID, *username)
+	if err != nil {
+		log.Printf("[CustomViews] Error importing bundle: %v", err)
+		if respondViewQuotaExceeded(w, err) {
+			return
+		}
+		if strings.Contains(err.Error(), "unsupported bundle version") ||
+			strings.Contains(err.Error(), "invalid conflict strategy") || strings.Contains(err.Error(), "invalid bundle") {
+			respondError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if strings.Contains(err.Error(), "permission denied") {
+			respondError(w, http.StatusForbidden, err.Error())
+			return
+		}
+		respondError(w, queryErrorStatus(err), err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, result)
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+)
+
+// PreconditionFailedError is returned by conditional updates of a view that has changed
+// since the client read it; Current is the view as it is now
+type PreconditionFailedError struct {
+	Current *CustomView
+}
+
+func (e *PreconditionFailedError) Error() string {
+	return "precondition failed: custom view has been modified"
+}
+
+// PreconditionFailedResponse is the 412 response of a stale update
+type PreconditionFailedResponse struct {
+	Error   string      `json:"error"`
+	Message string      `json:"message"`
+	Current *CustomView `json:"current"`
+}
+
+// customViewETag returns a strong ETag for the current state of a view. It is derived from
+// the whole view, so updates within the same second still change it.
+func customViewETag(view *CustomView) string {
+	stored := *view
+	stored.DanglingFieldIDs = nil // Derived from the custom fields, not part of the view
+	data, _ := json.Marshal(stored)
+	sum := sha256.Sum256(data)
+	return `"` + hex.EncodeToString(sum[:12]) + `"`
+}
+
+// ValidationFailedError is returned by updates whose resulting view is invalid
+type ValidationFailedError struct {
+	Problems []ValidationError
+}
+
+func (e *ValidationFailedError) Error() string {
+	return fmt.Sprintf("invalid custom view: %d problem(s)", len(e.Problems))
+}
+
+// UpdateCustomViewIfMatch replaces a view with the configuration build derives from its
+// current state, but only if the current ETag matches ifMatch (an If-Match header value);
+// an empty ifMatch updates unconditionally. Conditional updates are serialized so two
+// clients holding the same version cannot both succeed.
+func (s *Service) UpdateCustomViewIfMatch(ctx context.Context, id int, userID int, ifMatch string, build func(current *CustomView) (CustomView, error)) (*CustomView, error) {
+	if ifMatch != "" {
+		s.viewUpdates.Lock()
+		defer s.viewUpdates.Unlock()
+	}
+
+	current, err := s.GetCustomView(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if ifMatch != "" && !etagMatches(ifMatch, customViewETag(current)) {
+		log.Printf("[CustomViews] Rejected stale update of view %d", id)
+		return nil, &PreconditionFailedError{Current: current}
+	}
+
+	view, err := build(current)
+	if err != nil {
+		return nil, err
+	}
+	return s.ReplaceCustomView(ctx, id, view, userID)
+}
+
+// respondCustomView sends a view, localized for the request, together with its ETag. The
+// ETag is computed from the stored view so it does not depend on the requested locale.
+func respondCustomView(w http.ResponseWriter, r *http.Request, status int, view *CustomView) {
+	w.Header().Set("ETag", customViewETag(view))
+	respondJSON(w, status, localizedCustomView(r, view))
+}
+
+// respondPreconditionFailed sends the 412 response of a stale update, carrying the current
+// version of the view and its ETag
+func respondPreconditionFailed(w http.ResponseWriter, err *PreconditionFailedError) {
+	w.Header().Set("ETag", customViewETag(err.Current))
+	respondJSON(w, http.StatusPreconditionFailed, PreconditionFailedResponse{
+		Error:   http.StatusText(http.StatusPreconditionFailed),
+		Message: err.Error(),
+		Current: err.Current,
+	})
+}
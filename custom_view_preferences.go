@@ -0,0 +1,308 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// accessibleCustomView returns a view the user can see: one of their own or a global view.
+// Views of other users are reported as not found.
+func (s *Service) accessibleCustomView(ctx context.Context, id int, userID int) (*CustomView, error) {
+	view, err := s.GetCustomView(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	isGlobal := view.IsGlobal != nil && *view.IsGlobal
+	if view.OwnerID != nil && *view.OwnerID != userID && !isGlobal {
+		return nil, fmt.Errorf("custom view with id %d not found", id)
+	}
+	return view, nil
+}
+
+// setViewPreference sets one column of the user's preferences for a view, creating the
+// preferences row if needed
+func (s *Service) setViewPreference(ctx context.Context, tx *sql.Tx, userID int, viewID int, column string, value interface{}) error {
+	result, err := tx.ExecContext(ctx, s.rebind("UPDATE user_view_preferences SET "+column+" = ? WHERE user_id = ? AND view_id = ?"), value, userID, viewID)
+	if err != nil {
+		return fmt.Errorf("failed to update view preferences: %w", err)
+	}
+	if updated, _ := result.RowsAffected(); updated > 0 {
+		return nil
+	}
+	if _, err := tx.ExecContext(ctx, s.rebind("INSERT INTO user_view_preferences (user_id, view_id, "+column+") VALUES (?, ?, ?)"), userID, viewID, value); err != nil {
+		return fmt.Errorf("failed to insert view preferences: %w", err)
+	}
+	return nil
+}
+
+// GetViewPreferences returns the user's favorites, display order and default view. Views
+// in the trash are left out.
+func (s *Service) GetViewPreferences(ctx context.Context, userID int) (*ViewPreferences, error) {
+	query := s.rebind(`
+		SELECT p.view_id, p.is_favorite, p.position, p.is_default
+		FROM user_view_preferences p
+		JOIN custom_views v ON v.id = p.view_id AND v.deleted_at IS NULL
+		WHERE p.user_id = ?
+		ORDER BY CASE WHEN p.position IS NULL THEN 1 ELSE 0 END, p.position, p.view_id
+	`)
+	rows, err := s.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query view preferences: %w", err)
+	}
+	defer rows.Close()
+
+	preferences := &ViewPreferences{Favorites: []int{}, Order: []int{}}
+	for rows.Next() {
+		var viewID int
+		var isFavorite, isDefault bool
+		var position sql.NullInt64
+		if err := rows.Scan(&viewID, &isFavorite, &position, &isDefault); err != nil {
+			return nil, fmt.Errorf("failed to scan view preferences: %w", err)
+		}
+		if isFavorite {
+			preferences.Favorites = append(preferences.Favorites, viewID)
+		}
+		if position.Valid {
+			preferences.Order = append(preferences.Order, viewID)
+		}
+		if isDefault {
+			id := viewID
+			preferences.DefaultViewID = &id
+		}
+	}
+	return preferences, rows.Err()
+}
+
+// SetViewFavorite marks or unmarks a view as one of the user's favorites
+func (s *Service) SetViewFavorite(ctx context.Context, userID int, viewID int, favorite bool) error {
+	if _, err := s.accessibleCustomView(ctx, viewID, userID); err != nil {
+		return err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := s.setViewPreference(ctx, tx, userID, viewID, "is_favorite", favorite); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// SetViewOrder stores the user's display order of views; views left out lose their position
+func (s *Service) SetViewOrder(ctx context.Context, userID int, viewIDs []int) error {
+	seen := make(map[int]bool, len(viewIDs))
+	for _, viewID := range viewIDs {
+		if seen[viewID] {
+			return fmt.Errorf("invalid view order: view %d is listed twice", viewID)
+		}
+		seen[viewID] = true
+		if _, err := s.accessibleCustomView(ctx, viewID, userID); err != nil {
+			return err
+		}
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, s.rebind("UPDATE user_view_preferences SET position = NULL WHERE user_id = ?"), userID); err != nil {
+		return fmt.Errorf("failed to reset view order: %w", err)
+	}
+	for position, viewID := range viewIDs {
+		if err := s.setViewPreference(ctx, tx, userID, viewID, "position", position); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// SetDefaultView makes a view the user's default view; nil clears the default
+func (s *Service) SetDefaultView(ctx context.Context, userID int, viewID *int) error {
+	if viewID != nil {
+		if _, err := s.accessibleCustomView(ctx, *viewID, userID); err != nil {
+			return err
+		}
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, s.rebind("UPDATE user_view_preferences SET is_default = ? WHERE user_id = ?"), false, userID); err != nil {
+		return fmt.Errorf("failed to reset default view: %w", err)
+	}
+	if viewID != nil {
+		if err := s.setViewPreference(ctx, tx, userID, *viewID, "is_default", true); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// GetDefaultView returns the user's default view
+func (s *Service) GetDefaultView(ctx context.Context, userID int) (*CustomView, error) {
+	preferences, err := s.GetViewPreferences(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if preferences.DefaultViewID == nil {
+		return nil, fmt.Errorf("default view not found")
+	}
+	return s.accessibleCustomView(ctx, *preferences.DefaultViewID, userID)
+}
+
+// viewPreferencesErrorStatus maps view preference errors to HTTP statuses
+func viewPreferencesErrorStatus(err error) int {
+	switch {
+	case strings.Contains(err.Error(), "not found"):
+		return http.StatusNotFound
+	case strings.Contains(err.Error(), "invalid view order"):
+		return http.StatusBadRequest
+	}
+	return queryErrorStatus(err)
+}
+
+// respondViewPreferences sends the user's current view preferences
+func (s *Service) respondViewPreferences(ctx context.Context, w http.ResponseWriter, userID int) {
+	preferences, err := s.GetViewPreferences(ctx, userID)
+	if err != nil {
+		respondError(w, queryErrorStatus(err), err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, preferences)
+}
+
+// HTTP Handlers for view preferences
+func (s *Service) handleGetViewPreferences(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	log.Printf("[CustomViews] GET /api/custom_views/preferences/ - Request from %s", r.RemoteAddr)
+
+	userID, err := getUserIDFromRequest(r)
+	if err != nil {
+		respondError(w, viewerErrorStatus(err), err.Error())
+		return
+	}
+
+	s.respondViewPreferences(ctx, w, *userID)
+}
+
+func (s *Service) handleSetViewFavorite(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	idStr := mux.Vars(r)["id"]
+	log.Printf("[CustomViews] %s /api/custom_views/%s/favorite/ - Request from %s", r.Method, idStr, r.RemoteAddr)
+
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid view ID")
+		return
+	}
+	userID, err := getUserIDFromRequest(r)
+	if err != nil {
+		respondError(w, viewerErrorStatus(err), err.Error())
+		return
+	}
+
+	if err := s.SetViewFavorite(ctx, *userID, id, r.Method != http.MethodDelete); err != nil {
+		log.Printf("[CustomViews] Error updating favorite %d: %v", id, err)
+		respondError(w, viewPreferencesErrorStatus(err), err.Error())
+		return
+	}
+
+	s.respondViewPreferences(ctx, w, *userID)
+}
+
+func (s *Service) handleSetViewOrder(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	log.Printf("[CustomViews] PUT /api/custom_views/order/ - Request from %s", r.RemoteAddr)
+
+	var body struct {
+		ViewIDs []int `json:"view_ids"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		respondError(w, http.StatusBadRequest, fmt.Sprintf("Invalid request body: %v", err))
+		return
+	}
+	userID, err := getUserIDFromRequest(r)
+	if err != nil {
+		respondError(w, viewerErrorStatus(err), err.Error())
+		return
+	}
+
+	if err := s.SetViewOrder(ctx, *userID, body.ViewIDs); err != nil {
+		log.Printf("[CustomViews] Error updating view order: %v", err)
+		respondError(w, viewPreferencesErrorStatus(err), err.Error())
+		return
+	}
+
+	s.respondViewPreferences(ctx, w, *userID)
+}
+
+func (s *Service) handleGetDefaultView(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	log.Printf("[CustomViews] GET /api/custom_views/default/ - Request from %s", r.RemoteAddr)
+
+	userID, err := getUserIDFromRequest(r)
+	if err != nil {
+		respondError(w, viewerErrorStatus(err), err.Error())
+		return
+	}
+
+	view, err := s.GetDefaultView(ctx, *userID)
+	if err != nil {
+		respondError(w, viewPreferencesErrorStatus(err), err.Error())
+		return
+	}
+
+	respondCustomView(w, r, http.StatusOK, view)
+}
+
+func (s *Service) handleSetDefaultView(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	log.Printf("[CustomViews] PUT /api/custom_views/default/ - Request from %s", r.RemoteAddr)
+
+	var body struct {
+		ViewID *int `json:"view_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		respondError(w, http.StatusBadRequest, fmt.Sprintf("Invalid request body: %v", err))
+		return
+	}
+	userID, err := getUserIDFromRequest(r)
+	if err != nil {
+		respondError(w, viewerErrorStatus(err), err.Error())
+		return
+	}
+
+	if err := s.SetDefaultView(ctx, *userID, body.ViewID); err != nil {
+		log.Printf("[CustomViews] Error setting default view: %v", err)
+		respondError(w, viewPreferencesErrorStatus(err), err.Error())
+		return
+	}
+
+	s.respondViewPreferences(ctx, w, *userID)
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// recordCustomViewRevision stores the current configuration of a view before it is
+// overwritten and drops revisions beyond the configured limit
+func (s *Service) recordCustomViewRevision(ctx context.Context, view CustomView, userID int) error {
+	if s.config.ViewRevisionLimit <= 0 || view.ID == nil {
+		return nil
+	}
+	usePostgres := s.config.DBEngine == "postgresql" || s.config.DBEngine == "postgres"
+	snapshot, err := json.Marshal(portableCustomView(view))
+	if err != nil {
+		return fmt.Errorf("failed to encode custom view revision: %w", err)
+	}
+
+	maxQuery := "SELECT COALESCE(MAX(revision), 0) FROM custom_view_revisions WHERE view_id = ?"
+	insertQuery := "INSERT INTO custom_view_revisions (view_id, revision, snapshot, user_id) VALUES (?, ?, ?, ?)"
+	pruneQuery := "DELETE FROM custom_view_revisions WHERE view_id = ? AND revision <= ?"
+	if usePostgres {
+		maxQuery = "SELECT COALESCE(MAX(revision), 0) FROM custom_view_revisions WHERE view_id = $1"
+		insertQuery = "INSERT INTO custom_view_revisions (view_id, revision, snapshot, user_id) VALUES ($1, $2, $3::jsonb, $4)"
+		pruneQuery = "DELETE FROM custom_view_revisions WHERE view_id = $1 AND revision <= $2"
+	}
+
+	var latest int
+	if err := s.db.QueryRowContext(ctx, maxQuery, *view.ID).Scan(&latest); err != nil {
+		return fmt.Errorf("failed to query custom view revisions: %w", err)
+	}
+	revision := latest + 1
+	if _, err := s.db.ExecContext(ctx, insertQuery, *view.ID, revision, string(snapshot), userID); err != nil {
+		return fmt.Errorf("failed to record custom view revision: %w", err)
+	}
+	if _, err := s.db.ExecContext(ctx, pruneQuery, *view.ID, revision-s.config.ViewRevisionLimit); err != nil {
+		return fmt.Errorf("failed to prune custom view revisions: %w", err)
+	}
+	return nil
+}
+
+// ListCustomViewRevisions retrieves the revisions of a view, latest first
+func (s *Service) ListCustomViewRevisions(ctx context.Context, viewID int) ([]CustomViewRevision, error) {
+	query := "SELECT id, view_id, revision, snapshot, user_id, created FROM custom_view_revisions WHERE view_id = ? ORDER BY revision DESC"
+	if s.config.DBEngine == "postgresql" || s.config.DBEngine == "postgres" {
+		query = "SELECT id, view_id, revision, snapshot, user_id, created FROM custom_view_revisions WHERE view_id = $1 ORDER BY revision DESC"
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, viewID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query custom view revisions: %w", err)
+	}
+	defer rows.Close()
+
+	revisions := []CustomViewRevision{}
+	for rows.Next() {
+		revision, err := s.scanCustomViewRevision(rows)
+		if err != nil {
+			continue
+		}
+		revisions = append(revisions, revision)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read custom view revisions: %w", err)
+	}
+
+	return revisions, nil
+}
+
+// RestoreCustomViewRevision puts a view back to the configuration of one of its revisions.
+// The configuration being replaced is recorded as a new revision, so restores can be undone.
+func (s *Service) RestoreCustomViewRevision(ctx context.Context, viewID int, revisionNumber int, userID int) (*CustomView, error) {
+	query := "SELECT id, view_id, revision, snapshot, user_id, created FROM custom_view_revisions WHERE view_id = ? AND revision = ?"
+	if s.config.DBEngine == "postgresql" || s.config.DBEngine == "postgres" {
+		query = "SELECT id, view_id, revision, snapshot, user_id, created FROM custom_view_revisions WHERE view_id = $1 AND revision = $2"
+	}
+
+	revision, err := s.scanCustomViewRevision(s.db.QueryRowContext(ctx, query, viewID, revisionNumber))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("revision %d of custom view %d not found", revisionNumber, viewID)
+		}
+		return nil, fmt.Errorf("failed to query custom view revision: %w", err)
+	}
+
+	return s.ReplaceCustomView(ctx, viewID, revision.View, userID)
+}
+
+// scanCustomViewRevision scans a CustomViewRevision from a database row or rows
+func (s *Service) scanCustomViewRevision(scanner interface{ Scan(...interface{}) error }) (CustomViewRevision, error) {
+	var revision CustomViewRevision
+	var snapshot string
+	var userID sql.NullInt64
+	var created dbTimestamp
+	if err := scanner.Scan(&revision.ID, &revision.ViewID, &revision.Revision, &snapshot, &userID, &created); err != nil {
+		return revision, err
+	}
+	json.Unmarshal([]byte(snapshot), &revision.View)
+	if userID.Valid {
+		id := int(userID.Int64)
+		revision.UserID = &id
+	}
+	revision.Created = s.formatTimestamp(created)
+	return revision, nil
+}
+
+// HTTP Handlers for Custom View revisions
+func (s *Service) handleListCustomViewRevisions(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid view ID")
+		return
+	}
+	log.Printf("[CustomViews] GET /api/custom_views/%d/revisions/ - Request from %s", id, r.RemoteAddr)
+
+	if _, err := s.GetCustomView(ctx, id); err != nil {
+		respondError(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	revisions, err := s.ListCustomViewRevisions(ctx, id)
+	if err != nil {
+		respondError(w, queryErrorStatus(err), err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, CustomViewRevisionListResponse{Count: len(revisions), Results: revisions})
+}
+
+func (s *Service) handleRestoreCustomViewRevision(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid view ID")
+		return
+	}
+	revision, err := strconv.Atoi(vars["rev"])
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid revision")
+		return
+	}
+	log.Printf("[CustomViews] POST /api/custom_views/%d/revisions/%d/restore/ - Request from %s", id, revision, r.RemoteAddr)
+
+	userID, err := getUserIDFromRequest(r)
+	if err != nil {
+		respondError(w, viewerErrorStatus(err), err.Error())
+		return
+	}
+
+	restored, err := s.RestoreCustomViewRevision(ctx, id, revision, *userID)
+	if err != nil {
+		log.Printf("[CustomViews] Error restoring revision %d of view %d: %v", revision, id, err)
+		if strings.Contains(err.Error(), "permission denied") {
+			respondError(w, http.StatusForbidden, err.Error())
+			return
+		}
+		if strings.Contains(err.Error(), "not found") {
+			respondError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		respondError(w, queryErrorStatus(err), err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, restored)
+}
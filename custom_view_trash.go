@@ -0,0 +1,266 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// viewTrashPurgeInterval is how often views past the trash retention are purged
+const viewTrashPurgeInterval = time.Hour
+
+// ListTrashedCustomViews retrieves the user's soft-deleted views, most recently deleted first
+func (s *Service) ListTrashedCustomViews(ctx context.Context, userID int) ([]CustomView, error) {
+	query := `
+		SELECT id, name, description, column_order, column_sizing, column_visibility,
+			column_display_types, filter_rules, filter_visibility, subrow_enabled, subrow_content,
+			column_spanning, filter_types, edit_mode_settings, column_styles, sort_field, sort_reverse, is_global, owner_id, username, created, modified, deleted_at, translations, board_settings
+		FROM custom_views
+		WHERE deleted_at IS NOT NULL AND owner_id = ?
+		ORDER BY deleted_at DESC
+	`
+	if s.config.DBEngine == "postgresql" || s.config.DBEngine == "postgres" {
+		query = strings.Replace(query, "owner_id = ?", "owner_id = $1", 1)
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query trashed custom views: %w", err)
+	}
+	defer rows.Close()
+
+	views := []CustomView{}
+	for rows.Next() {
+		view, err := s.scanCustomView(rows)
+		if err != nil {
+			continue
+		}
+		views = append(views, view)
+	}
+
+	return views, nil
+}
+
+// checkTrashedCustomView verifies that a view is in the trash and belongs to the user
+func (s *Service) checkTrashedCustomView(ctx context.Context, id int, userID int) error {
+	query := "SELECT owner_id FROM custom_views WHERE id = ? AND deleted_at IS NOT NULL"
+	if s.config.DBEngine == "postgresql" || s.config.DBEngine == "postgres" {
+		query = "SELECT owner_id FROM custom_views WHERE id = $1 AND deleted_at IS NOT NULL"
+	}
+
+	var ownerID sql.NullInt64
+	err := s.db.QueryRowContext(ctx, query, id).Scan(&ownerID)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("custom view with id %d not found in trash", id)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to query custom view: %w", err)
+	}
+	if ownerID.Valid && int(ownerID.Int64) != userID {
+		return fmt.Errorf("permission denied: view belongs to another user")
+	}
+	return nil
+}
+
+// RestoreCustomView takes a view out of the trash
+func (s *Service) RestoreCustomView(ctx context.Context, id int, userID int) (*CustomView, error) {
+	log.Printf("[CustomViews] RestoreCustomView - ID: %d, UserID: %d", id, userID)
+	if err := s.checkTrashedCustomView(ctx, id, userID); err != nil {
+		return nil, err
+	}
+	if err := s.checkViewQuota(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	query := "UPDATE custom_views SET deleted_at = NULL, modified = CURRENT_TIMESTAMP WHERE id = ?"
+	if s.config.DBEngine == "postgresql" || s.config.DBEngine == "postgres" {
+		query = "UPDATE custom_views SET deleted_at = NULL, modified = CURRENT_TIMESTAMP WHERE id = $1"
+	}
+	if _, err := s.db.ExecContext(ctx, query, id); err != nil {
+		return nil, fmt.Errorf("failed to restore custom view: %w", err)
+	}
+
+	restored, err := s.GetCustomView(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	s.emitViewEvent(EventViewRestored, restored)
+	return restored, nil
+}
+
+// PurgeCustomView permanently deletes a view in the trash together with its revisions
+func (s *Service) PurgeCustomView(ctx context.Context, id int, userID int) error {
+	log.Printf("[CustomViews] PurgeCustomView - ID: %d, UserID: %d", id, userID)
+	if err := s.checkTrashedCustomView(ctx, id, userID); err != nil {
+		return err
+	}
+	_, err := s.purgeCustomViews(ctx, "id = ?", id)
+	return err
+}
+
+// purgeCustomViews hard-deletes the trashed views matching condition (written with ?
+// placeholders) together with their revisions and returns the number of purged views
+func (s *Service) purgeCustomViews(ctx context.Context, condition string, args ...interface{}) (int64, error) {
+	if s.config.DBEngine == "postgresql" || s.config.DBEngine == "postgres" {
+		for i := range args {
+			condition = strings.Replace(condition, "?", fmt.Sprintf("$%d", i+1), 1)
+		}
+	}
+	where := "deleted_at IS NOT NULL AND " + condition
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM custom_view_revisions WHERE view_id IN (SELECT id FROM custom_views WHERE "+where+")", args...); err != nil {
+		return 0, fmt.Errorf("failed to delete custom view revisions: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM user_view_preferences WHERE view_id IN (SELECT id FROM custom_views WHERE "+where+")", args...); err != nil {
+		return 0, fmt.Errorf("failed to delete custom view preferences: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM view_snapshots WHERE view_id IN (SELECT id FROM custom_views WHERE "+where+")", args...); err != nil {
+		return 0, fmt.Errorf("failed to delete custom view snapshots: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM view_snapshot_schedules WHERE view_id IN (SELECT id FROM custom_views WHERE "+where+")", args...); err != nil {
+		return 0, fmt.Errorf("failed to delete custom view snapshot schedules: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM view_notification_rules WHERE view_id IN (SELECT id FROM custom_views WHERE "+where+")", args...); err != nil {
+		return 0, fmt.Errorf("failed to delete custom view notification rules: %w", err)
+	}
+	result, err := tx.ExecContext(ctx, "DELETE FROM custom_views WHERE "+where, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge custom views: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit purge: %w", err)
+	}
+	purged, _ := result.RowsAffected()
+	return purged, nil
+}
+
+// runViewTrashPurge periodically purges views that have been in the trash for longer than
+// the configured retention
+func (s *Service) runViewTrashPurge(ctx context.Context) {
+	log.Printf("[CustomViews] Trash purge started - Retention: %s", s.config.ViewTrashRetention)
+	purge := func() {
+		cutoff := time.Now().UTC().Add(-s.config.ViewTrashRetention).Format("2006-01-02 15:04:05")
+		purged, err := s.purgeCustomViews(ctx, "deleted_at < ?", cutoff)
+		if err != nil {
+			log.Printf("[CustomViews] Trash purge failed: %v", err)
+		} else if purged > 0 {
+			log.Printf("[CustomViews] Purged %d views deleted before %s", purged, cutoff)
+		}
+	}
+	purge()
+
+	ticker := time.NewTicker(viewTrashPurgeInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Printf("[CustomViews] Trash purge stopped")
+			return
+		case <-ticker.C:
+			purge()
+		}
+	}
+}
+
+// customViewTrashErrorStatus maps trash errors to HTTP status codes
+func customViewTrashErrorStatus(err error) int {
+	if strings.Contains(err.Error(), "permission denied") {
+		return http.StatusForbidden
+	}
+	if strings.Contains(err.Error(), "not found") {
+		return http.StatusNotFound
+	}
+	return queryErrorStatus(err)
+}
+
+// HTTP Handlers for the Custom View trash
+func (s *Service) handleListTrashedCustomViews(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	log.Printf("[CustomViews] GET /api/custom_views/trash/ - Request from %s", r.RemoteAddr)
+
+	userID, err := getUserIDFromRequest(r)
+	if err != nil {
+		respondError(w, viewerErrorStatus(err), err.Error())
+		return
+	}
+
+	views, err := s.ListTrashedCustomViews(ctx, *userID)
+	if err != nil {
+		respondError(w, queryErrorStatus(err), err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, CustomViewListResponse{Count: len(views), Results: views})
+}
+
+func (s *Service) handleRestoreCustomView(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid view ID")
+		return
+	}
+	log.Printf("[CustomViews] POST /api/custom_views/%d/restore/ - Request from %s", id, r.RemoteAddr)
+
+	userID, err := getUserIDFromRequest(r)
+	if err != nil {
+		respondError(w, viewerErrorStatus(err), err.Error())
+		return
+	}
+
+	view, err := s.RestoreCustomView(ctx, id, *userID)
+	if err != nil {
+		log.Printf("[CustomViews] Error restoring view %d: %v", id, err)
+		if respondViewQuotaExceeded(w, err) {
+			return
+		}
+		respondError(w, customViewTrashErrorStatus(err), err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, view)
+}
+
+func (s *Service) handlePurgeCustomView(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid view ID")
+		return
+	}
+	log.Printf("[CustomViews] DELETE /api/custom_views/%d/purge/ - Request from %s", id, r.RemoteAddr)
+
+	userID, err := getUserIDFromRequest(r)
+	if err != nil {
+		respondError(w, viewerErrorStatus(err), err.Error())
+		return
+	}
+
+	if err := s.PurgeCustomView(ctx, id, *userID); err != nil {
+		log.Printf("[CustomViews] Error purging view %d: %v", id, err)
+		respondError(w, customViewTrashErrorStatus(err), err.Error())
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
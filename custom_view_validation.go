@@ -0,0 +1,343 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// maxCustomViewNameLength caps the name of a custom view
+const maxCustomViewNameLength = 255
+
+// ValidationError describes one problem with a field of a request body
+type ValidationError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationErrorResponse is the 422 response listing every problem of a request body
+type ValidationErrorResponse struct {
+	Error   string            `json:"error"`
+	Message string            `json:"message"`
+	Errors  []ValidationError `json:"errors"`
+}
+
+// respondValidationErrors sends a 422 response listing the problems
+func respondValidationErrors(w http.ResponseWriter, message string, problems []ValidationError) {
+	respondJSON(w, http.StatusUnprocessableEntity, ValidationErrorResponse{
+		Error:   http.StatusText(http.StatusUnprocessableEntity),
+		Message: message,
+		Errors:  problems,
+	})
+}
+
+// builtinViewColumns are the non custom field columns a view can show
+var builtinViewColumns = map[string]bool{
+	"select": true, "thumbnail": true, "title": true, "content": true, "id": true,
+	"created": true, "added": true, "modified": true,
+	"correspondent": true, "document_type": true, "storage_path": true, "tags": true,
+	"owner": true, "shared": true, "notes": true, "num_notes": true,
+	"asn": true, "archive_serial_number": true, "page_count": true, "mime_type": true,
+	"filename": true, "original_filename": true, "checksum": true, "actions": true,
+}
+
+// customViewSubrowContents are the accepted subrow_content values
+var customViewSubrowContents = map[string]bool{"summary": true, "tags": true, "none": true}
+
+// boardGroupColumns are the builtin columns a board can group its lanes by; custom fields
+// are accepted too
+var boardGroupColumns = map[string]bool{
+	"correspondent": true, "document_type": true, "storage_path": true, "tags": true, "owner": true,
+}
+
+// Value shapes of filter rule types
+var (
+	idFilterRules = map[int]bool{
+		FILTER_CORRESPONDENT: true, FILTER_DOCUMENT_TYPE: true, FILTER_HAS_TAGS_ALL: true,
+		FILTER_DOES_NOT_HAVE_TAG: true, FILTER_HAS_TAGS_ANY: true, FILTER_STORAGE_PATH: true,
+		FILTER_HAS_CORRESPONDENT_ANY: true, FILTER_DOES_NOT_HAVE_CORRESPONDENT: true,
+		FILTER_HAS_DOCUMENT_TYPE_ANY: true, FILTER_DOES_NOT_HAVE_DOCUMENT_TYPE: true,
+		FILTER_HAS_STORAGE_PATH_ANY: true, FILTER_DOES_NOT_HAVE_STORAGE_PATH: true,
+		FILTER_OWNER: true, FILTER_OWNER_ANY: true, FILTER_OWNER_DOES_NOT_INCLUDE: true,
+		FILTER_SHARED_BY_USER: true, FILTER_HAS_CUSTOM_FIELDS_ALL: true,
+		FILTER_HAS_CUSTOM_FIELDS_ANY: true, FILTER_DOES_NOT_HAVE_CUSTOM_FIELDS: true,
+	}
+	dateFilterRules = map[int]bool{
+		FILTER_CREATED_BEFORE: true, FILTER_CREATED_AFTER: true, FILTER_ADDED_BEFORE: true,
+		FILTER_ADDED_AFTER: true, FILTER_MODIFIED_BEFORE: true, FILTER_MODIFIED_AFTER: true,
+		FILTER_CREATED_TO: true, FILTER_CREATED_FROM: true, FILTER_ADDED_TO: true, FILTER_ADDED_FROM: true,
+	}
+	integerFilterRules = map[int]bool{
+		FILTER_ASN: true, FILTER_CREATED_YEAR: true, FILTER_CREATED_MONTH: true,
+		FILTER_CREATED_DAY: true, FILTER_ASN_GT: true, FILTER_ASN_LT: true,
+	}
+	boolFilterRules = map[int]bool{
+		FILTER_IS_IN_INBOX: true, FILTER_HAS_ANY_TAG: true, FILTER_ASN_ISNULL: true,
+		FILTER_OWNER_ISNULL: true, FILTER_HAS_ANY_CUSTOM_FIELDS: true,
+	}
+)
+
+// customFieldIDs returns the IDs of the existing custom fields
+func (s *Service) customFieldIDs(ctx context.Context) (map[int]bool, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT id FROM documents_customfield")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query custom fields: %w", err)
+	}
+	defer rows.Close()
+
+	ids := make(map[int]bool)
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan custom field: %w", err)
+		}
+		ids[id] = true
+	}
+	return ids, rows.Err()
+}
+
+// customViewValidator collects the problems of a view payload
+type customViewValidator struct {
+	fieldIDs map[int]bool
+	prefix   string // Prepended to field names, e.g. "views[2]." for bundles
+	problems []ValidationError
+}
+
+func (v *customViewValidator) add(field string, format string, args ...interface{}) {
+	v.problems = append(v.problems, ValidationError{Field: v.prefix + field, Message: fmt.Sprintf(format, args...)})
+}
+
+// columnKey checks a column reference: a builtin column, or a custom field ID given as
+// "12" or "custom_field_12"
+func (v *customViewValidator) columnKey(field string, key string) {
+	if builtinViewColumns[key] {
+		return
+	}
+	fieldKey := strings.TrimPrefix(key, "custom_field_")
+	if id, err := strconv.Atoi(fieldKey); err == nil {
+		if !v.fieldIDs[id] {
+			v.add(field, "custom field %d does not exist", id)
+		}
+		return
+	}
+	v.add(field, "unknown column %q", key)
+}
+
+// columnMapKeys returns the keys of a map keyed by column in a stable order
+func columnMapKeys[T any](values map[string]T) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// filterKey checks a filter_visibility or filter_types key. Builtin filter names are left
+// to the frontend; numeric keys must name an existing custom field.
+func (v *customViewValidator) filterKey(field string, key string) {
+	fieldKey := strings.TrimPrefix(key, "custom_field_")
+	if id, err := strconv.Atoi(fieldKey); err == nil && !v.fieldIDs[id] {
+		v.add(field, "custom field %d does not exist", id)
+	}
+}
+
+// filterRule checks the rule type and the value shape of one filter rule
+func (v *customViewValidator) filterRule(field string, rule map[string]interface{}) {
+	rawType, ok := rule["rule_type"]
+	if !ok {
+		v.add(field+".rule_type", "rule_type is required")
+		return
+	}
+	ruleType, ok := rawType.(float64)
+	if !ok || ruleType != math.Trunc(ruleType) {
+		v.add(field+".rule_type", "rule_type must be an integer")
+		return
+	}
+	if ruleType < FILTER_TITLE || ruleType > FILTER_MIME_TYPE {
+		v.add(field+".rule_type", "unknown rule type %d", int(ruleType))
+		return
+	}
+	ruleTypeInt := int(ruleType)
+
+	rawValue, ok := rule["value"]
+	if !ok {
+		v.add(field+".value", "value is required")
+		return
+	}
+	var value string
+	switch typed := rawValue.(type) {
+	case nil:
+		if !idFilterRules[ruleTypeInt] {
+			v.add(field+".value", "value must not be null for rule type %d", ruleTypeInt)
+		}
+		return
+	case string:
+		value = strings.TrimSpace(typed)
+	case float64:
+		value = strconv.FormatFloat(typed, 'f', -1, 64)
+	case bool:
+		value = strconv.FormatBool(typed)
+	default:
+		v.add(field+".value", "value must be a string, number, boolean or null")
+		return
+	}
+
+	switch {
+	case idFilterRules[ruleTypeInt]:
+		if id, err := strconv.Atoi(value); err != nil || id < 1 {
+			v.add(field+".value", "value must be an object ID for rule type %d", ruleTypeInt)
+		}
+	case integerFilterRules[ruleTypeInt]:
+		if _, err := strconv.Atoi(value); err != nil {
+			v.add(field+".value", "value must be an integer for rule type %d", ruleTypeInt)
+		}
+	case boolFilterRules[ruleTypeInt]:
+		if _, ok := parseRuleBool(value); !ok {
+			v.add(field+".value", "value must be a boolean for rule type %d", ruleTypeInt)
+		}
+	case dateFilterRules[ruleTypeInt]:
+		if _, ok := shiftRuleDate(value, 0); !ok {
+			v.add(field+".value", "value must be a date (YYYY-MM-DD) for rule type %d", ruleTypeInt)
+		}
+	case ruleTypeInt == FILTER_CUSTOM_FIELDS_QUERY:
+		var query []interface{}
+		if err := json.Unmarshal([]byte(value), &query); err != nil {
+			v.add(field+".value", "value must be a JSON encoded custom field query array")
+		}
+	}
+}
+
+// validate checks the fields of a view
+func (v *customViewValidator) validate(view CustomView) {
+	if strings.TrimSpace(view.Name) == "" {
+		v.add("name", "name is required")
+	}
+	if len(view.Name) > maxCustomViewNameLength {
+		v.add("name", "name must be at most %d characters", maxCustomViewNameLength)
+	}
+
+	v.columns(view.ColumnOrder, view.ColumnSizing, view.ColumnVisibility, view.ColumnDisplayTypes)
+	for _, key := range columnMapKeys(view.ColumnStyles) {
+		v.columnKey("column_styles."+key, key)
+	}
+	for _, key := range columnMapKeys(view.ColumnSpanning) {
+		v.columnKey("column_spanning."+key, key)
+	}
+	for _, key := range columnMapKeys(view.FilterVisibility) {
+		v.filterKey("filter_visibility."+key, key)
+	}
+	for _, key := range columnMapKeys(view.FilterTypes) {
+		v.filterKey("filter_types."+key, key)
+	}
+
+	for i, rule := range view.FilterRules {
+		v.filterRule(fmt.Sprintf("filter_rules[%d]", i), rule)
+	}
+
+	if view.SubrowContent != nil && !customViewSubrowContents[*view.SubrowContent] {
+		v.add("subrow_content", "subrow_content must be summary, tags or none")
+	}
+
+	v.translations(view.Translations)
+	if view.BoardSettings != nil {
+		v.boardSettings(*view.BoardSettings)
+	}
+}
+
+// boardSettings checks the lane field, lane order and card fields of a kanban layout
+func (v *customViewValidator) boardSettings(board ViewBoardSettings) {
+	switch _, isField := customFieldKeyID(board.GroupBy); {
+	case board.GroupBy == "":
+		v.add("board_settings.group_by", "group_by is required")
+	case isField:
+		v.columnKey("board_settings.group_by", board.GroupBy)
+	case !boardGroupColumns[board.GroupBy]:
+		v.add("board_settings.group_by", "cannot group by %q; use correspondent, document_type, storage_path, tags, owner or a custom field", board.GroupBy)
+	}
+
+	seen := make(map[string]bool, len(board.SwimlaneOrder))
+	for i, lane := range board.SwimlaneOrder {
+		field := fmt.Sprintf("board_settings.swimlane_order[%d]", i)
+		var key string
+		switch typed := lane.(type) {
+		case string:
+			key = typed
+		case float64:
+			key = strconv.FormatFloat(typed, 'f', -1, 64)
+		default:
+			v.add(field, "lane must be a string or a number")
+			continue
+		}
+		if seen[key] {
+			v.add(field, "lane %s is listed more than once", key)
+		}
+		seen[key] = true
+	}
+
+	for i, column := range board.CardFields {
+		v.columnKey(fmt.Sprintf("board_settings.card_fields[%d]", i), column)
+	}
+}
+
+// columns checks the column order, sizing, visibility and display types shared by views
+// and column presets
+func (v *customViewValidator) columns(order []interface{}, sizing map[string]int, visibility map[string]bool, displayTypes map[string]string) {
+	for i, entry := range order {
+		field := fmt.Sprintf("column_order[%d]", i)
+		switch typed := entry.(type) {
+		case string:
+			v.columnKey(field, typed)
+		case float64:
+			if typed != math.Trunc(typed) || !v.fieldIDs[int(typed)] {
+				v.add(field, "custom field %v does not exist", typed)
+			}
+		default:
+			v.add(field, "column must be a column name or a custom field ID")
+		}
+	}
+	for _, key := range columnMapKeys(sizing) {
+		v.columnKey("column_sizing."+key, key)
+		if sizing[key] <= 0 {
+			v.add("column_sizing."+key, "width must be positive")
+		}
+	}
+	for _, key := range columnMapKeys(visibility) {
+		v.columnKey("column_visibility."+key, key)
+	}
+	for _, key := range columnMapKeys(displayTypes) {
+		v.columnKey("column_display_types."+key, key)
+	}
+}
+
+// ValidateCustomView checks a view payload against the known columns, custom fields and
+// filter rule types
+func (s *Service) ValidateCustomView(ctx context.Context, view CustomView) ([]ValidationError, error) {
+	fieldIDs, err := s.customFieldIDs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	v := &customViewValidator{fieldIDs: fieldIDs}
+	v.validate(view)
+	return v.problems, nil
+}
+
+// ValidateCustomViewBundle checks every view of a bundle; field names are prefixed with
+// the index of the view ("views[0].name")
+func (s *Service) ValidateCustomViewBundle(ctx context.Context, bundle CustomViewBundle) ([]ValidationError, error) {
+	fieldIDs, err := s.customFieldIDs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	v := &customViewValidator{fieldIDs: fieldIDs}
+	for i, view := range bundle.Views {
+		v.prefix = fmt.Sprintf("views[%d].", i)
+		v.validate(view)
+	}
+	return v.problems, nil
+}
@@ -1,9 +1,12 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
 	"strconv"
@@ -12,93 +15,98 @@ import (
 	"github.com/gorilla/mux"
 )
 
-// ListCustomViews retrieves a list of custom views for a user
-func (s *Service) ListCustomViews(userID *int, includeGlobal bool) ([]CustomView, error) {
-	log.Printf("[CustomViews] ListCustomViews - UserID: %v, IncludeGlobal: %v", userID, includeGlobal)
-	var query string
-	var args []interface{}
+// customViewOrderings maps the ordering query values of the custom views list to ORDER BY
+// clauses; a leading "-" sorts descending
+var customViewOrderings = map[string]string{
+	"name":      "LOWER(name) ASC, id ASC",
+	"-name":     "LOWER(name) DESC, id DESC",
+	"modified":  "modified ASC, id ASC",
+	"-modified": "modified DESC, id DESC",
+	"owner":     "username ASC, LOWER(name) ASC",
+	"-owner":    "username DESC, LOWER(name) ASC",
+	"created":   "created ASC, id ASC",
+	"-created":  "created DESC, id DESC",
+}
 
-	// ListCustomViews retrieves a list of CustomViews
-	// ... (abbreviated comments)
-	if userID != nil {
-		if includeGlobal {
-			// Get user's views OR global views
-			switch s.config.DBEngine {
-			case "postgresql", "postgres":
-				query = `
-					SELECT id, name, description, column_order, column_sizing, column_visibility,
-						column_display_types, filter_rules, filter_visibility, subrow_enabled, subrow_content,
-						column_spanning, filter_types, edit_mode_settings, column_styles, sort_field, sort_reverse, is_global, owner_id, username, created, modified, deleted_at
-					FROM custom_views
-					WHERE deleted_at IS NULL
-						AND (owner_id = $1 OR is_global = true)
-					ORDER BY created DESC
-				`
-				args = []interface{}{*userID}
-			case "mysql", "mariadb", "sqlite", "sqlite3":
-				query = `
-					SELECT id, name, description, column_order, column_sizing, column_visibility,
-						column_display_types, filter_rules, filter_visibility, subrow_enabled, subrow_content,
-						column_spanning, filter_types, edit_mode_settings, column_styles, sort_field, sort_reverse, is_global, owner_id, username, created, modified, deleted_at
-					FROM custom_views
-					WHERE deleted_at IS NULL
-						AND (owner_id = ? OR is_global = 1)
-					ORDER BY created DESC
-				`
-				args = []interface{}{*userID}
-			}
-		} else {
-			// Only user's views
-			switch s.config.DBEngine {
-			case "postgresql", "postgres":
-				query = `
-					SELECT id, name, description, column_order, column_sizing, column_visibility,
-						column_display_types, filter_rules, filter_visibility, subrow_enabled, subrow_content,
-						column_spanning, filter_types, edit_mode_settings, column_styles, sort_field, sort_reverse, is_global, owner_id, username, created, modified, deleted_at
-					FROM custom_views
-					WHERE deleted_at IS NULL AND owner_id = $1
-					ORDER BY created DESC
-				`
-				args = []interface{}{*userID}
-			case "mysql", "mariadb", "sqlite", "sqlite3":
-				query = `
-					SELECT id, name, description, column_order, column_sizing, column_visibility,
-						column_display_types, filter_rules, filter_visibility, subrow_enabled, subrow_content,
-						column_spanning, filter_types, edit_mode_settings, column_styles, sort_field, sort_reverse, is_global, owner_id, username, created, modified, deleted_at
-					FROM custom_views
-					WHERE deleted_at IS NULL AND owner_id = ?
-					ORDER BY created DESC
-				`
-				args = []interface{}{*userID}
-			}
+// Scopes of the custom views list
+const (
+	ViewScopeAll    = "all"    // The user's own views and the global views of everyone
+	ViewScopeMine   = "mine"   // The user's own views, global or not
+	ViewScopeGlobal = "global" // The global views of everyone
+)
+
+// CustomViewListOptions holds the scope, search, sorting and paging of the custom views list
+type CustomViewListOptions struct {
+	Scope      string // One of the ViewScope constants, default ViewScopeAll
+	OwnerID    *int   // Only views of this owner, including their private views unless Scope is global
+	Search     string // Case-insensitive substring of the view name
+	Ordering   string // Key of customViewOrderings, default "-created"
+	Pagination Pagination
+}
+
+// ListCustomViews retrieves a list of custom views for a user together with the number
+// of views matching before pagination
+func (s *Service) ListCustomViews(ctx context.Context, userID *int, opts CustomViewListOptions) ([]CustomView, int, error) {
+	log.Printf("[CustomViews] ListCustomViews - UserID: %v, Scope: %q, Search: %q, Ordering: %q", userID, opts.Scope, opts.Search, opts.Ordering)
+	usePostgres := s.config.DBEngine == "postgresql" || s.config.DBEngine == "postgres"
+	args := []interface{}{}
+	nextArg := func(value interface{}) string {
+		args = append(args, value)
+		if usePostgres {
+			return fmt.Sprintf("$%d", len(args))
 		}
-	} else {
-		// No user ID - return global views only
-		switch s.config.DBEngine {
-		case "postgresql", "postgres":
-			query = `
-				SELECT id, name, description, column_order, column_sizing, column_visibility,
-					column_display_types, filter_rules, filter_visibility, subrow_enabled, subrow_content,
-					column_spanning, filter_types, edit_mode_settings, column_styles, sort_field, sort_reverse, is_global, owner_id, username, created, modified, deleted_at
-				FROM custom_views
-				WHERE deleted_at IS NULL AND is_global = true
-				ORDER BY created DESC
-			`
-		case "mysql", "mariadb", "sqlite", "sqlite3":
-			query = `
-				SELECT id, name, description, column_order, column_sizing, column_visibility,
-					column_display_types, filter_rules, filter_visibility, subrow_enabled, subrow_content,
-					column_spanning, filter_types, edit_mode_settings, column_styles, sort_field, sort_reverse, is_global, owner_id, username, created, modified, deleted_at
-				FROM custom_views
-				WHERE deleted_at IS NULL AND is_global = 1
-				ORDER BY created DESC
-			`
+		return "?"
+	}
+	globalTrue := "is_global = 1"
+	if usePostgres {
+		globalTrue = "is_global = true"
+	}
+
+	conditions := []string{"deleted_at IS NULL"}
+	switch {
+	case opts.OwnerID != nil:
+		// Views of one owner; callers make sure the user may see their private views
+		conditions = append(conditions, fmt.Sprintf("owner_id = %s", nextArg(*opts.OwnerID)))
+		if opts.Scope == ViewScopeGlobal {
+			conditions = append(conditions, globalTrue)
 		}
+	case userID == nil, opts.Scope == ViewScopeGlobal:
+		// Without a user ID only global views are listed
+		conditions = append(conditions, globalTrue)
+	case opts.Scope == ViewScopeMine:
+		conditions = append(conditions, fmt.Sprintf("owner_id = %s", nextArg(*userID)))
+	default:
+		conditions = append(conditions, fmt.Sprintf("(owner_id = %s OR %s)", nextArg(*userID), globalTrue))
+	}
+	if search := strings.TrimSpace(opts.Search); search != "" {
+		pattern := "%" + escapeLikePattern(strings.ToLower(search)) + "%"
+		conditions = append(conditions, fmt.Sprintf("LOWER(name) LIKE %s ESCAPE '!'", nextArg(pattern)))
+	}
+	where := strings.Join(conditions, " AND ")
+
+	orderBy, ok := customViewOrderings[opts.Ordering]
+	if !ok {
+		orderBy = customViewOrderings["-created"]
 	}
 
-	rows, err := s.db.Query(query, args...)
+	var total int
+	if err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM custom_views WHERE "+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count custom views: %w", err)
+	}
+
+	query := fmt.Sprintf(`
+		SELECT id, name, description, column_order, column_sizing, column_visibility,
+			column_display_types, filter_rules, filter_visibility, subrow_enabled, subrow_content,
+			column_spanning, filter_types, edit_mode_settings, column_styles, sort_field, sort_reverse, is_global, owner_id, username, created, modified, deleted_at, translations, board_settings
+		FROM custom_views
+		WHERE %s
+		ORDER BY %s
+		%s
+	`, where, orderBy, opts.Pagination.limitClause())
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query custom views: %w", err)
+		return nil, 0, fmt.Errorf("failed to query custom views: %w", err)
 	}
 	defer rows.Close()
 
@@ -111,11 +119,11 @@ func (s *Service) ListCustomViews(userID *int, includeGlobal bool) ([]CustomView
 		views = append(views, view)
 	}
 
-	return views, nil
+	return views, total, nil
 }
 
 // GetCustomView retrieves a specific custom view by ID
-func (s *Service) GetCustomView(id int) (*CustomView, error) {
+func (s *Service) GetCustomView(ctx context.Context, id int) (*CustomView, error) {
 	var query string
 
 	switch s.config.DBEngine {
@@ -123,7 +131,7 @@ func (s *Service) GetCustomView(id int) (*CustomView, error) {
 		query = `
 			SELECT id, name, description, column_order, column_sizing, column_visibility,
 				column_display_types, filter_rules, filter_visibility, subrow_enabled, subrow_content,
-				column_spanning, filter_types, edit_mode_settings, column_styles, sort_field, sort_reverse, is_global, owner_id, username, created, modified, deleted_at
+				column_spanning, filter_types, edit_mode_settings, column_styles, sort_field, sort_reverse, is_global, owner_id, username, created, modified, deleted_at, translations, board_settings
 			FROM custom_views
 			WHERE id = $1 AND deleted_at IS NULL
 		`
@@ -131,13 +139,13 @@ func (s *Service) GetCustomView(id int) (*CustomView, error) {
 		query = `
 			SELECT id, name, description, column_order, column_sizing, column_visibility,
 				column_display_types, filter_rules, filter_visibility, subrow_enabled, subrow_content,
-				column_spanning, filter_types, edit_mode_settings, column_styles, sort_field, sort_reverse, is_global, owner_id, username, created, modified, deleted_at
+				column_spanning, filter_types, edit_mode_settings, column_styles, sort_field, sort_reverse, is_global, owner_id, username, created, modified, deleted_at, translations, board_settings
 			FROM custom_views
 			WHERE id = ? AND deleted_at IS NULL
 		`
 	}
 
-	row := s.db.QueryRow(query, id)
+	row := s.db.QueryRowContext(ctx, query, id)
 	view, err := s.scanCustomView(row)
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -150,8 +158,26 @@ func (s *Service) GetCustomView(id int) (*CustomView, error) {
 }
 
 // CreateCustomView creates a new custom view
-func (s *Service) CreateCustomView(view CustomView, userID int, username string) (*CustomView, error) {
+func (s *Service) CreateCustomView(ctx context.Context, view CustomView, userID int, username string) (*CustomView, error) {
 	log.Printf("[CustomViews] CreateCustomView - Name: %s, UserID: %d, Username: %s", view.Name, userID, username)
+	if err := s.checkViewQuota(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	// Set defaults
+	if view.ColumnOrder == nil {
+		view.ColumnOrder = []interface{}{}
+	}
+	if view.ColumnSizing == nil {
+		view.ColumnSizing = make(map[string]int)
+	}
+	if view.ColumnVisibility == nil {
+		view.ColumnVisibility = make(map[string]bool)
+	}
+	if view.ColumnDisplayTypes == nil {
+		view.ColumnDisplayTypes = make(map[string]string)
+	}
+
 	// Marshal JSON fields
 	columnOrderJSON, _ := json.Marshal(view.ColumnOrder)
 	columnSizingJSON, _ := json.Marshal(view.ColumnSizing)
@@ -163,6 +189,8 @@ func (s *Service) CreateCustomView(view CustomView, userID int, username string)
 	editModeSettingsJSON, _ := json.Marshal(view.EditModeSettings)
 	columnSpanningJSON, _ := json.Marshal(view.ColumnSpanning)
 	columnStylesJSON, _ := json.Marshal(view.ColumnStyles)
+	translationsJSON, _ := json.Marshal(view.Translations)
+	boardSettingsJSON, _ := json.Marshal(view.BoardSettings)
 
 	// Set defaults for new fields
 	subrowEnabled := false
@@ -193,8 +221,8 @@ func (s *Service) CreateCustomView(view CustomView, userID int, username string)
 		insertQuery = `
 			INSERT INTO custom_views (name, description, column_order, column_sizing, column_visibility,
 				column_display_types, filter_rules, filter_visibility, filter_types, edit_mode_settings,
-				subrow_enabled, subrow_content, column_spanning, column_styles, sort_field, sort_reverse, is_global, owner_id, username)
-			VALUES ($1, $2, $3::jsonb, $4::jsonb, $5::jsonb, $6::jsonb, $7::jsonb, $8::jsonb, $9::jsonb, $10::jsonb, $11, $12, $13::jsonb, $14::jsonb, $15, $16, $17, $18, $19)
+				subrow_enabled, subrow_content, column_spanning, column_styles, sort_field, sort_reverse, is_global, owner_id, username, translations, board_settings)
+			VALUES ($1, $2, $3::jsonb, $4::jsonb, $5::jsonb, $6::jsonb, $7::jsonb, $8::jsonb, $9::jsonb, $10::jsonb, $11, $12, $13::jsonb, $14::jsonb, $15, $16, $17, $18, $19, $20::jsonb, $21::jsonb)
 			RETURNING id, created, modified
 		`
 		args = []interface{}{
@@ -202,48 +230,48 @@ func (s *Service) CreateCustomView(view CustomView, userID int, username string)
 			string(columnVisibilityJSON), string(columnDisplayTypesJSON), string(filterRulesJSON),
 			string(filterVisibilityJSON), string(filterTypesJSON), string(editModeSettingsJSON),
 			subrowEnabled, subrowContent, string(columnSpanningJSON), string(columnStylesJSON),
-			view.SortField, sortReverse, isGlobal, userID, username,
+			view.SortField, sortReverse, isGlobal, userID, username, string(translationsJSON), string(boardSettingsJSON),
 		}
 	case "mysql", "mariadb":
 		insertQuery = `
 			INSERT INTO custom_views (name, description, column_order, column_sizing, column_visibility,
 				column_display_types, filter_rules, filter_visibility, filter_types, edit_mode_settings,
-				subrow_enabled, subrow_content, column_spanning, column_styles, sort_field, sort_reverse, is_global, owner_id, username)
-			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+				subrow_enabled, subrow_content, column_spanning, column_styles, sort_field, sort_reverse, is_global, owner_id, username, translations, board_settings)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 		`
 		args = []interface{}{
 			view.Name, view.Description, string(columnOrderJSON), string(columnSizingJSON),
 			string(columnVisibilityJSON), string(columnDisplayTypesJSON), string(filterRulesJSON),
 			string(filterVisibilityJSON), string(filterTypesJSON), string(editModeSettingsJSON),
 			subrowEnabled, subrowContent, string(columnSpanningJSON), string(columnStylesJSON),
-			view.SortField, sortReverse, isGlobal, userID, username,
+			view.SortField, sortReverse, isGlobal, userID, username, string(translationsJSON), string(boardSettingsJSON),
 		}
 	case "sqlite", "sqlite3":
 		insertQuery = `
 			INSERT INTO custom_views (name, description, column_order, column_sizing, column_visibility,
 				column_display_types, filter_rules, filter_visibility, filter_types, edit_mode_settings,
-				subrow_enabled, subrow_content, column_spanning, column_styles, sort_field, sort_reverse, is_global, owner_id, username)
-			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+				subrow_enabled, subrow_content, column_spanning, column_styles, sort_field, sort_reverse, is_global, owner_id, username, translations, board_settings)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 		`
 		args = []interface{}{
 			view.Name, view.Description, string(columnOrderJSON), string(columnSizingJSON),
 			string(columnVisibilityJSON), string(columnDisplayTypesJSON), string(filterRulesJSON),
 			string(filterVisibilityJSON), string(filterTypesJSON), string(editModeSettingsJSON),
 			subrowEnabled, subrowContent, string(columnSpanningJSON), string(columnStylesJSON),
-			view.SortField, sortReverse, isGlobal, userID, username,
+			view.SortField, sortReverse, isGlobal, userID, username, string(translationsJSON), string(boardSettingsJSON),
 		}
 	}
 
 	var newID int
-	var created, modified string
+	var created, modified dbTimestamp
 
 	if s.config.DBEngine == "postgresql" || s.config.DBEngine == "postgres" {
-		err := s.db.QueryRow(insertQuery, args...).Scan(&newID, &created, &modified)
+		err := s.db.QueryRowContext(ctx, insertQuery, args...).Scan(&newID, &created, &modified)
 		if err != nil {
 			return nil, fmt.Errorf("failed to create custom view: %w", err)
 		}
 	} else {
-		result, err := s.db.Exec(insertQuery, args...)
+		result, err := s.db.ExecContext(ctx, insertQuery, args...)
 		if err != nil {
 			return nil, fmt.Errorf("failed to create custom view: %w", err)
 		}
@@ -256,23 +284,26 @@ func (s *Service) CreateCustomView(view CustomView, userID int, username string)
 
 		// Fetch created/modified timestamps
 		getTimeQuery := "SELECT created, modified FROM custom_views WHERE id = ?"
-		s.db.QueryRow(getTimeQuery, newID).Scan(&created, &modified)
+		s.db.QueryRowContext(ctx, getTimeQuery, newID).Scan(&created, &modified)
 	}
 
 	view.ID = &newID
 	view.OwnerID = &userID
 	view.Username = &username
-	view.Created = &created
-	view.Modified = &modified
+	view.Created = s.formatTimestamp(created)
+	view.Modified = s.formatTimestamp(modified)
 
+	s.emitViewEvent(EventViewCreated, &view)
 	return &view, nil
 }
 
-// UpdateCustomView updates an existing custom view
-func (s *Service) UpdateCustomView(id int, updates CustomView, userID int) (*CustomView, error) {
-	log.Printf("[CustomViews] UpdateCustomView - ID: %d, UserID: %d", id, userID)
+// ReplaceCustomView replaces the configuration of an existing custom view with view. Fields
+// left unset in view are cleared (or reset to their defaults), like on creation; the ID,
+// owner and timestamps are kept.
+func (s *Service) ReplaceCustomView(ctx context.Context, id int, view CustomView, userID int) (*CustomView, error) {
+	log.Printf("[CustomViews] ReplaceCustomView - ID: %d, UserID: %d", id, userID)
 	// Get existing view
-	existing, err := s.GetCustomView(id)
+	existing, err := s.GetCustomView(ctx, id)
 	if err != nil {
 		return nil, err
 	}
@@ -285,233 +316,122 @@ func (s *Service) UpdateCustomView(id int, updates CustomView, userID int) (*Cus
 		}
 	}
 
-	// Build update query dynamically based on provided fields
-	setParts := []string{}
-	args := []interface{}{}
-	usePostgres := s.config.DBEngine == "postgresql" || s.config.DBEngine == "postgres"
-	argIndex := 1
-
-	if updates.Name != "" {
-		if usePostgres {
-			setParts = append(setParts, fmt.Sprintf("name = $%d", argIndex))
-		} else {
-			setParts = append(setParts, "name = ?")
-		}
-		args = append(args, updates.Name)
-		argIndex++
-		existing.Name = updates.Name
-	}
-	if updates.Description != nil {
-		if usePostgres {
-			setParts = append(setParts, fmt.Sprintf("description = $%d", argIndex))
-		} else {
-			setParts = append(setParts, "description = ?")
-		}
-		args = append(args, updates.Description)
-		argIndex++
-		existing.Description = updates.Description
-	}
-	if updates.ColumnOrder != nil {
-		columnOrderJSON, _ := json.Marshal(updates.ColumnOrder)
-		if usePostgres {
-			setParts = append(setParts, fmt.Sprintf("column_order = $%d::jsonb", argIndex))
-		} else {
-			setParts = append(setParts, "column_order = ?")
-		}
-		args = append(args, string(columnOrderJSON))
-		argIndex++
-		existing.ColumnOrder = updates.ColumnOrder
-	}
-	if updates.ColumnSizing != nil {
-		columnSizingJSON, _ := json.Marshal(updates.ColumnSizing)
-		if usePostgres {
-			setParts = append(setParts, fmt.Sprintf("column_sizing = $%d::jsonb", argIndex))
-		} else {
-			setParts = append(setParts, "column_sizing = ?")
-		}
-		args = append(args, string(columnSizingJSON))
-		argIndex++
-		existing.ColumnSizing = updates.ColumnSizing
-	}
-	if updates.ColumnVisibility != nil {
-		columnVisibilityJSON, _ := json.Marshal(updates.ColumnVisibility)
-		if usePostgres {
-			setParts = append(setParts, fmt.Sprintf("column_visibility = $%d::jsonb", argIndex))
-		} else {
-			setParts = append(setParts, "column_visibility = ?")
-		}
-		args = append(args, string(columnVisibilityJSON))
-		argIndex++
-		existing.ColumnVisibility = updates.ColumnVisibility
-	}
-	if updates.ColumnDisplayTypes != nil {
-		columnDisplayTypesJSON, _ := json.Marshal(updates.ColumnDisplayTypes)
-		if usePostgres {
-			setParts = append(setParts, fmt.Sprintf("column_display_types = $%d::jsonb", argIndex))
-		} else {
-			setParts = append(setParts, "column_display_types = ?")
-		}
-		args = append(args, string(columnDisplayTypesJSON))
-		argIndex++
-		existing.ColumnDisplayTypes = updates.ColumnDisplayTypes
-	}
-	if updates.FilterRules != nil {
-		filterRulesJSON, _ := json.Marshal(updates.FilterRules)
-		if usePostgres {
-			setParts = append(setParts, fmt.Sprintf("filter_rules = $%d::jsonb", argIndex))
-		} else {
-			setParts = append(setParts, "filter_rules = ?")
-		}
-		args = append(args, string(filterRulesJSON))
-		argIndex++
-		existing.FilterRules = updates.FilterRules
+	// Keep the configuration being replaced
+	if err := s.recordCustomViewRevision(ctx, *existing, userID); err != nil {
+		return nil, err
 	}
-	if updates.FilterVisibility != nil {
-		filterVisibilityJSON, _ := json.Marshal(updates.FilterVisibility)
-		if usePostgres {
-			setParts = append(setParts, fmt.Sprintf("filter_visibility = $%d::jsonb", argIndex))
-		} else {
-			setParts = append(setParts, "filter_visibility = ?")
-		}
-		args = append(args, string(filterVisibilityJSON))
-		argIndex++
-		existing.FilterVisibility = updates.FilterVisibility
+
+	// Same defaults as on creation
+	if view.ColumnOrder == nil {
+		view.ColumnOrder = []interface{}{}
 	}
-	if updates.SortField != nil {
-		if usePostgres {
-			setParts = append(setParts, fmt.Sprintf("sort_field = $%d", argIndex))
-		} else {
-			setParts = append(setParts, "sort_field = ?")
-		}
-		args = append(args, updates.SortField)
-		argIndex++
-		existing.SortField = updates.SortField
+	if view.ColumnSizing == nil {
+		view.ColumnSizing = make(map[string]int)
 	}
-	if updates.SortReverse != nil {
-		if usePostgres {
-			setParts = append(setParts, fmt.Sprintf("sort_reverse = $%d", argIndex))
-		} else {
-			setParts = append(setParts, "sort_reverse = ?")
-		}
-		args = append(args, *updates.SortReverse)
-		argIndex++
-		existing.SortReverse = updates.SortReverse
+	if view.ColumnVisibility == nil {
+		view.ColumnVisibility = make(map[string]bool)
 	}
-	if updates.IsGlobal != nil {
-		if usePostgres {
-			setParts = append(setParts, fmt.Sprintf("is_global = $%d", argIndex))
-		} else {
-			setParts = append(setParts, "is_global = ?")
-		}
-		args = append(args, *updates.IsGlobal)
-		argIndex++
-		existing.IsGlobal = updates.IsGlobal
+	if view.ColumnDisplayTypes == nil {
+		view.ColumnDisplayTypes = make(map[string]string)
 	}
-	if updates.SubrowEnabled != nil {
-		if usePostgres {
-			setParts = append(setParts, fmt.Sprintf("subrow_enabled = $%d", argIndex))
-		} else {
-			setParts = append(setParts, "subrow_enabled = ?")
-		}
-		args = append(args, *updates.SubrowEnabled)
-		argIndex++
-		existing.SubrowEnabled = updates.SubrowEnabled
+	subrowEnabled := false
+	if view.SubrowEnabled != nil {
+		subrowEnabled = *view.SubrowEnabled
 	}
-	if updates.SubrowContent != nil {
-		if usePostgres {
-			setParts = append(setParts, fmt.Sprintf("subrow_content = $%d", argIndex))
-		} else {
-			setParts = append(setParts, "subrow_content = ?")
-		}
-		args = append(args, updates.SubrowContent)
-		argIndex++
-		existing.SubrowContent = updates.SubrowContent
+	subrowContent := "summary"
+	if view.SubrowContent != nil {
+		subrowContent = *view.SubrowContent
 	}
-	if updates.ColumnSpanning != nil {
-		columnSpanningJSON, _ := json.Marshal(updates.ColumnSpanning)
-		if usePostgres {
-			setParts = append(setParts, fmt.Sprintf("column_spanning = $%d::jsonb", argIndex))
-		} else {
-			setParts = append(setParts, "column_spanning = ?")
-		}
-		args = append(args, string(columnSpanningJSON))
-		argIndex++
-		existing.ColumnSpanning = updates.ColumnSpanning
+	isGlobal := false
+	if view.IsGlobal != nil {
+		isGlobal = *view.IsGlobal
 	}
-	if updates.FilterTypes != nil {
-		filterTypesJSON, _ := json.Marshal(updates.FilterTypes)
-		if usePostgres {
-			setParts = append(setParts, fmt.Sprintf("filter_types = $%d::jsonb", argIndex))
-		} else {
-			setParts = append(setParts, "filter_types = ?")
-		}
-		args = append(args, string(filterTypesJSON))
-		argIndex++
-		existing.FilterTypes = updates.FilterTypes
+	sortReverse := false
+	if view.SortReverse != nil {
+		sortReverse = *view.SortReverse
 	}
-	if updates.EditModeSettings != nil {
-		editModeSettingsJSON, _ := json.Marshal(updates.EditModeSettings)
-		if usePostgres {
-			setParts = append(setParts, fmt.Sprintf("edit_mode_settings = $%d::jsonb", argIndex))
-		} else {
-			setParts = append(setParts, "edit_mode_settings = ?")
-		}
-		args = append(args, string(editModeSettingsJSON))
-		argIndex++
-		existing.EditModeSettings = updates.EditModeSettings
+
+	// Marshal JSON fields
+	columnOrderJSON, _ := json.Marshal(view.ColumnOrder)
+	columnSizingJSON, _ := json.Marshal(view.ColumnSizing)
+	columnVisibilityJSON, _ := json.Marshal(view.ColumnVisibility)
+	columnDisplayTypesJSON, _ := json.Marshal(view.ColumnDisplayTypes)
+	filterRulesJSON, _ := json.Marshal(view.FilterRules)
+	filterVisibilityJSON, _ := json.Marshal(view.FilterVisibility)
+	filterTypesJSON, _ := json.Marshal(view.FilterTypes)
+	editModeSettingsJSON, _ := json.Marshal(view.EditModeSettings)
+	columnSpanningJSON, _ := json.Marshal(view.ColumnSpanning)
+	columnStylesJSON, _ := json.Marshal(view.ColumnStyles)
+	translationsJSON, _ := json.Marshal(view.Translations)
+	boardSettingsJSON, _ := json.Marshal(view.BoardSettings)
+
+	columns := []struct {
+		name   string
+		value  interface{}
+		isJSON bool
+	}{
+		{"name", view.Name, false},
+		{"description", view.Description, false},
+		{"column_order", string(columnOrderJSON), true},
+		{"column_sizing", string(columnSizingJSON), true},
+		{"column_visibility", string(columnVisibilityJSON), true},
+		{"column_display_types", string(columnDisplayTypesJSON), true},
+		{"filter_rules", string(filterRulesJSON), true},
+		{"filter_visibility", string(filterVisibilityJSON), true},
+		{"filter_types", string(filterTypesJSON), true},
+		{"edit_mode_settings", string(editModeSettingsJSON), true},
+		{"subrow_enabled", subrowEnabled, false},
+		{"subrow_content", subrowContent, false},
+		{"column_spanning", string(columnSpanningJSON), true},
+		{"column_styles", string(columnStylesJSON), true},
+		{"sort_field", view.SortField, false},
+		{"sort_reverse", sortReverse, false},
+		{"is_global", isGlobal, false},
+		{"translations", string(translationsJSON), true},
+		{"board_settings", string(boardSettingsJSON), true},
 	}
 
-	if updates.ColumnStyles != nil {
-		columnStylesJSON, _ := json.Marshal(updates.ColumnStyles)
+	usePostgres := s.config.DBEngine == "postgresql" || s.config.DBEngine == "postgres"
+	setParts := make([]string, 0, len(columns)+1)
+	args := make([]interface{}, 0, len(columns)+1)
+	for i, column := range columns {
+		placeholder := "?"
 		if usePostgres {
-			setParts = append(setParts, fmt.Sprintf("column_styles = $%d::jsonb", argIndex))
-		} else {
-			setParts = append(setParts, "column_styles = ?")
+			placeholder = fmt.Sprintf("$%d", i+1)
+			if column.isJSON {
+				placeholder += "::jsonb"
+			}
 		}
-		args = append(args, string(columnStylesJSON))
-		argIndex++
-		existing.ColumnStyles = updates.ColumnStyles
-	}
-
-	if len(setParts) == 0 {
-		return existing, nil // No updates
+		setParts = append(setParts, column.name+" = "+placeholder)
+		args = append(args, column.value)
 	}
+	setParts = append(setParts, "modified = CURRENT_TIMESTAMP")
 
-	// Update modified timestamp
-	// For all databases, use CURRENT_TIMESTAMP directly in SQL (not as a parameter)
-	if s.config.DBEngine == "mysql" || s.config.DBEngine == "mariadb" {
-		setParts = append(setParts, "modified = CURRENT_TIMESTAMP")
-	} else if usePostgres {
-		// PostgreSQL: Use CURRENT_TIMESTAMP directly in SQL, not as a parameter
-		setParts = append(setParts, "modified = CURRENT_TIMESTAMP")
-	} else {
-		// SQLite
-		setParts = append(setParts, "modified = CURRENT_TIMESTAMP")
-	}
-
-	var updateQuery string
+	idPlaceholder := "?"
 	if usePostgres {
-		updateQuery = fmt.Sprintf("UPDATE custom_views SET %s WHERE id = $%d", strings.Join(setParts, ", "), argIndex)
-		args = append(args, id)
-	} else {
-		updateQuery = fmt.Sprintf("UPDATE custom_views SET %s WHERE id = ?", strings.Join(setParts, ", "))
-		args = append(args, id)
+		idPlaceholder = fmt.Sprintf("$%d", len(args)+1)
 	}
+	args = append(args, id)
+	updateQuery := fmt.Sprintf("UPDATE custom_views SET %s WHERE id = %s", strings.Join(setParts, ", "), idPlaceholder)
 
-	_, err = s.db.Exec(updateQuery, args...)
-	if err != nil {
+	if _, err := s.db.ExecContext(ctx, updateQuery, args...); err != nil {
 		return nil, fmt.Errorf("failed to update custom view: %w", err)
 	}
 
 	// Fetch updated view
-	return s.GetCustomView(id)
+	updated, err := s.GetCustomView(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	s.emitViewEvent(EventViewUpdated, updated)
+	return updated, nil
 }
 
 // DeleteCustomView soft-deletes a custom view
-func (s *Service) DeleteCustomView(id int, userID int) error {
+func (s *Service) DeleteCustomView(ctx context.Context, id int, userID int) error {
 	log.Printf("[CustomViews] DeleteCustomView - ID: %d, UserID: %d", id, userID)
 	// Get existing view to check ownership
-	existing, err := s.GetCustomView(id)
+	existing, err := s.GetCustomView(ctx, id)
 	if err != nil {
 		return err
 	}
@@ -530,11 +450,12 @@ func (s *Service) DeleteCustomView(id int, userID int) error {
 		deleteQuery = "UPDATE custom_views SET deleted_at = CURRENT_TIMESTAMP WHERE id = ?"
 	}
 
-	_, err = s.db.Exec(deleteQuery, id)
+	_, err = s.db.ExecContext(ctx, deleteQuery, id)
 	if err != nil {
 		return fmt.Errorf("failed to delete custom view: %w", err)
 	}
 
+	s.emitViewEvent(EventViewDeleted, existing)
 	return nil
 }
 
@@ -542,9 +463,11 @@ func (s *Service) DeleteCustomView(id int, userID int) error {
 func (s *Service) scanCustomView(scanner interface{}) (CustomView, error) {
 	var view CustomView
 	var id sql.NullInt64
-	var description, sortField, username, created, modified, deletedAt, subrowContent sql.NullString
+	var description, sortField, username, subrowContent sql.NullString
+	var created, modified, deletedAt dbTimestamp
 	var columnOrderJSON, columnSizingJSON, columnVisibilityJSON, columnDisplayTypesJSON sql.NullString
 	var filterRulesJSON, filterVisibilityJSON, filterTypesJSON, editModeSettingsJSON, columnSpanningJSON, columnStylesJSON sql.NullString
+	var translationsJSON, boardSettingsJSON sql.NullString
 	var isGlobal, sortReverse, subrowEnabled sql.NullBool
 
 	var scanErr error
@@ -557,7 +480,7 @@ func (s *Service) scanCustomView(scanner interface{}) (CustomView, error) {
 			&columnVisibilityJSON, &columnDisplayTypesJSON, &filterRulesJSON,
 			&filterVisibilityJSON, &subrowEnabled, &subrowContent, &columnSpanningJSON,
 			&filterTypesJSON, &editModeSettingsJSON, &columnStylesJSON,
-			&sortField, &sortReverse, &isGlobal, &view.OwnerID, &username, &created, &modified, &deletedAt,
+			&sortField, &sortReverse, &isGlobal, &view.OwnerID, &username, &created, &modified, &deletedAt, &translationsJSON, &boardSettingsJSON,
 		)
 	case *sql.Rows:
 		rows := scanner.(*sql.Rows)
@@ -566,7 +489,7 @@ func (s *Service) scanCustomView(scanner interface{}) (CustomView, error) {
 			&columnVisibilityJSON, &columnDisplayTypesJSON, &filterRulesJSON,
 			&filterVisibilityJSON, &subrowEnabled, &subrowContent, &columnSpanningJSON,
 			&filterTypesJSON, &editModeSettingsJSON, &columnStylesJSON,
-			&sortField, &sortReverse, &isGlobal, &view.OwnerID, &username, &created, &modified, &deletedAt,
+			&sortField, &sortReverse, &isGlobal, &view.OwnerID, &username, &created, &modified, &deletedAt, &translationsJSON, &boardSettingsJSON,
 		)
 	default:
 		return view, fmt.Errorf("unsupported scanner type")
@@ -589,15 +512,9 @@ func (s *Service) scanCustomView(scanner interface{}) (CustomView, error) {
 	if username.Valid {
 		view.Username = &username.String
 	}
-	if created.Valid {
-		view.Created = &created.String
-	}
-	if modified.Valid {
-		view.Modified = &modified.String
-	}
-	if deletedAt.Valid {
-		view.DeletedAt = &deletedAt.String
-	}
+	view.Created = s.formatTimestamp(created)
+	view.Modified = s.formatTimestamp(modified)
+	view.DeletedAt = s.formatTimestamp(deletedAt)
 	if isGlobal.Valid {
 		view.IsGlobal = &isGlobal.Bool
 	}
@@ -642,44 +559,133 @@ func (s *Service) scanCustomView(scanner interface{}) (CustomView, error) {
 	if columnStylesJSON.Valid {
 		json.Unmarshal([]byte(columnStylesJSON.String), &view.ColumnStyles)
 	}
+	if translationsJSON.Valid {
+		json.Unmarshal([]byte(translationsJSON.String), &view.Translations)
+	}
+	if boardSettingsJSON.Valid {
+		json.Unmarshal([]byte(boardSettingsJSON.String), &view.BoardSettings)
+	}
 
 	return view, nil
 }
 
 // HTTP Handlers for Custom Views
 func (s *Service) handleListCustomViews(w http.ResponseWriter, r *http.Request) {
-	log.Printf("[CustomViews] GET /api/custom_views/ - Request from %s", r.RemoteAddr)
+	scope := ViewScopeAll
+	if r.URL.Query().Get("global_only") == "true" {
+		// Kept for older clients: despite its name it lists only the user's own views
+		scope = ViewScopeMine
+	}
+	s.listCustomViews(w, r, scope)
+}
+
+func (s *Service) handleListMyCustomViews(w http.ResponseWriter, r *http.Request) {
+	s.listCustomViews(w, r, ViewScopeMine)
+}
+
+func (s *Service) handleListGlobalCustomViews(w http.ResponseWriter, r *http.Request) {
+	s.listCustomViews(w, r, ViewScopeGlobal)
+}
+
+// listCustomViews sends the views of a scope, narrowed to one owner by the owner parameter.
+// Superusers may name any owner and then also see private views; other users only themselves,
+// except among global views.
+func (s *Service) listCustomViews(w http.ResponseWriter, r *http.Request, scope string) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	log.Printf("[CustomViews] GET %s - Request from %s", r.URL.Path, r.RemoteAddr)
 
 	userID, err := getUserIDFromRequest(r)
 	if err != nil {
 		log.Printf("[CustomViews] Error getting user ID: %v", err)
-		respondError(w, http.StatusUnauthorized, "Unauthorized")
+		respondError(w, viewerErrorStatus(err), err.Error())
 		return
 	}
-	log.Printf("[CustomViews] User ID: %d", *userID)
+	log.Printf("[CustomViews] User ID: %d, Scope: %s", *userID, scope)
 
-	// Include global views by default
-	includeGlobal := r.URL.Query().Get("global_only") != "true"
-	log.Printf("[CustomViews] Include global views: %v", includeGlobal)
+	var ownerID *int
+	if ownerStr := r.URL.Query().Get("owner"); ownerStr != "" {
+		owner, err := strconv.Atoi(ownerStr)
+		if err != nil || owner < 1 {
+			respondError(w, http.StatusBadRequest, fmt.Sprintf("Invalid owner: %s", ownerStr))
+			return
+		}
+		if scope == ViewScopeMine {
+			respondError(w, http.StatusBadRequest, "owner cannot be combined with the user's own views")
+			return
+		}
+		if owner != *userID && scope != ViewScopeGlobal {
+			superuser, err := s.isSuperuser(ctx, *userID)
+			if err != nil {
+				respondError(w, queryErrorStatus(err), err.Error())
+				return
+			}
+			if !superuser {
+				respondError(w, http.StatusForbidden, "permission denied: only superusers can list the views of other users")
+				return
+			}
+		}
+		ownerID = &owner
+	}
 
-	views, err := s.ListCustomViews(userID, includeGlobal)
+	pagination, err := parsePagination(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	opts := CustomViewListOptions{
+		Scope:      scope,
+		OwnerID:    ownerID,
+		Search:     r.URL.Query().Get("q"),
+		Ordering:   r.URL.Query().Get("ordering"),
+		Pagination: pagination,
+	}
+	if _, ok := customViewOrderings[opts.Ordering]; opts.Ordering != "" && !ok {
+		respondError(w, http.StatusBadRequest, fmt.Sprintf("Invalid ordering: %s", opts.Ordering))
+		return
+	}
+
+	views, total, err := s.ListCustomViews(ctx, userID, opts)
 	if err != nil {
 		log.Printf("[CustomViews] Error listing views: %v", err)
-		respondError(w, http.StatusInternalServerError, err.Error())
+		respondError(w, queryErrorStatus(err), err.Error())
 		return
 	}
 
 	log.Printf("[CustomViews] Found %d views", len(views))
+	if r.URL.Query().Get("with_counts") == "true" {
+		viewerID, err := viewerFromRequest(r)
+		if err != nil {
+			respondError(w, viewerErrorStatus(err), err.Error())
+			return
+		}
+		if err := s.addViewDocumentCounts(ctx, views, viewerID, bypassCache(r)); err != nil {
+			log.Printf("[CustomViews] Error counting view documents: %v", err)
+			respondError(w, queryErrorStatus(err), err.Error())
+			return
+		}
+	}
+	if err := s.flagDanglingFieldRefs(ctx, views); err != nil {
+		log.Printf("[CustomViews] Error checking view custom fields: %v", err)
+		respondError(w, queryErrorStatus(err), err.Error())
+		return
+	}
+	localizeCustomViews(r, views)
 	response := CustomViewListResponse{
-		Count:   len(views),
+		Count:   total,
 		Results: views,
 	}
+	response.Next, response.Previous = pageLinks(r, pagination, total)
 
 	respondJSON(w, http.StatusOK, response)
 	log.Printf("[CustomViews] Successfully returned %d views", len(views))
 }
 
 func (s *Service) handleGetCustomView(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
 	vars := mux.Vars(r)
 	idStr := vars["id"]
 	log.Printf("[CustomViews] GET /api/custom_views/%s/ - Request from %s", idStr, r.RemoteAddr)
@@ -692,18 +698,28 @@ func (s *Service) handleGetCustomView(w http.ResponseWriter, r *http.Request) {
 	}
 
 	log.Printf("[CustomViews] Fetching view ID: %d", id)
-	view, err := s.GetCustomView(id)
+	view, err := s.GetCustomView(ctx, id)
 	if err != nil {
 		log.Printf("[CustomViews] Error getting view %d: %v", id, err)
 		respondError(w, http.StatusNotFound, err.Error())
 		return
 	}
 
+	views := []CustomView{*view}
+	if err := s.flagDanglingFieldRefs(ctx, views); err != nil {
+		log.Printf("[CustomViews] Error checking custom fields of view %d: %v", id, err)
+		respondError(w, queryErrorStatus(err), err.Error())
+		return
+	}
+
 	log.Printf("[CustomViews] Successfully retrieved view %d: %s", id, view.Name)
-	respondJSON(w, http.StatusOK, view)
+	respondCustomView(w, r, http.StatusOK, &views[0])
 }
 
 func (s *Service) handleCreateCustomView(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
 	log.Printf("[CustomViews] POST /api/custom_views/ - Request from %s", r.RemoteAddr)
 
 	var view CustomView
@@ -715,47 +731,49 @@ func (s *Service) handleCreateCustomView(w http.ResponseWriter, r *http.Request)
 
 	log.Printf("[CustomViews] Creating view: Name=%s, IsGlobal=%v", view.Name, view.IsGlobal)
 
-	if view.Name == "" {
-		log.Printf("[CustomViews] Validation error: Name is required")
-		respondError(w, http.StatusBadRequest, "Name is required")
+	userID, err := getUserIDFromRequest(r)
+	if err != nil {
+		log.Printf("[CustomViews] Error getting user ID: %v", err)
+		respondError(w, viewerErrorStatus(err), err.Error())
 		return
 	}
+	username := getUsernameFromRequest(r)
+	log.Printf("[CustomViews] User ID: %d, Username: %s", *userID, *username)
 
-	// Set defaults
-	if view.ColumnOrder == nil {
-		view.ColumnOrder = []interface{}{}
-	}
-	if view.ColumnSizing == nil {
-		view.ColumnSizing = make(map[string]int)
-	}
-	if view.ColumnVisibility == nil {
-		view.ColumnVisibility = make(map[string]bool)
-	}
-	if view.ColumnDisplayTypes == nil {
-		view.ColumnDisplayTypes = make(map[string]string)
+	if err := s.expandColumnPreset(ctx, &view, *userID); err != nil {
+		respondViewExpansionError(w, err)
+		return
 	}
 
-	userID, err := getUserIDFromRequest(r)
+	problems, err := s.ValidateCustomView(ctx, view)
 	if err != nil {
-		log.Printf("[CustomViews] Error getting user ID: %v", err)
-		respondError(w, http.StatusUnauthorized, "Unauthorized")
+		respondError(w, queryErrorStatus(err), err.Error())
+		return
+	}
+	if len(problems) > 0 {
+		log.Printf("[CustomViews] Validation failed with %d problem(s)", len(problems))
+		respondValidationErrors(w, "Invalid custom view", problems)
 		return
 	}
-	username := getUsernameFromRequest(r)
-	log.Printf("[CustomViews] User ID: %d, Username: %s", *userID, *username)
 
-	created, err := s.CreateCustomView(view, *userID, *username)
+	created, err := s.CreateCustomView(ctx, view, *userID, *username)
 	if err != nil {
 		log.Printf("[CustomViews] Error creating view: %v", err)
+		if respondViewQuotaExceeded(w, err) {
+			return
+		}
 		respondError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
 	log.Printf("[CustomViews] Successfully created view ID: %d, Name: %s", created.ID, created.Name)
-	respondJSON(w, http.StatusCreated, created)
+	respondCustomView(w, r, http.StatusCreated, created)
 }
 
 func (s *Service) handleUpdateCustomView(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
 	vars := mux.Vars(r)
 	idStr := vars["id"]
 	method := r.Method
@@ -768,9 +786,8 @@ func (s *Service) handleUpdateCustomView(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	var updates CustomView
-	if err := json.NewDecoder(r.Body).Decode(&updates); err != nil {
-		log.Printf("[CustomViews] Error decoding request body for view %d: %v", id, err)
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
 		respondError(w, http.StatusBadRequest, fmt.Sprintf("Invalid request body: %v", err))
 		return
 	}
@@ -779,14 +796,57 @@ func (s *Service) handleUpdateCustomView(w http.ResponseWriter, r *http.Request)
 	userID, err := getUserIDFromRequest(r)
 	if err != nil {
 		log.Printf("[CustomViews] Error getting user ID: %v", err)
-		respondError(w, http.StatusUnauthorized, "Unauthorized")
+		respondError(w, viewerErrorStatus(err), err.Error())
 		return
 	}
 	log.Printf("[CustomViews] User ID: %d", *userID)
 
-	updated, err := s.UpdateCustomView(id, updates, *userID)
+	// PUT replaces the whole view; PATCH is a JSON Merge Patch (RFC 7386) of the current
+	// view, where omitted fields are kept and null clears a field
+	build := func(current *CustomView) (CustomView, error) {
+		var view CustomView
+		if method == http.MethodPatch {
+			if err := mergePatchJSON(current, body, &view); err != nil {
+				return view, err
+			}
+		} else if err := json.Unmarshal(body, &view); err != nil {
+			return view, fmt.Errorf("invalid request body: %v", err)
+		}
+		if err := s.expandColumnPreset(ctx, &view, *userID); err != nil {
+			return view, err
+		}
+
+		problems, err := s.ValidateCustomView(ctx, view)
+		if err != nil {
+			return view, err
+		}
+		if len(problems) > 0 {
+			return view, &ValidationFailedError{Problems: problems}
+		}
+		return view, nil
+	}
+
+	updated, err := s.UpdateCustomViewIfMatch(ctx, id, *userID, r.Header.Get("If-Match"), build)
 	if err != nil {
 		log.Printf("[CustomViews] Error updating view %d: %v", id, err)
+		var stale *PreconditionFailedError
+		if errors.As(err, &stale) {
+			respondPreconditionFailed(w, stale)
+			return
+		}
+		var invalid *ValidationFailedError
+		if errors.As(err, &invalid) {
+			respondValidationErrors(w, "Invalid custom view", invalid.Problems)
+			return
+		}
+		if strings.Contains(err.Error(), "invalid merge patch") || strings.Contains(err.Error(), "invalid request body") {
+			respondError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if strings.Contains(err.Error(), "not found") {
+			respondError(w, http.StatusNotFound, err.Error())
+			return
+		}
 		if strings.Contains(err.Error(), "permission denied") {
 			respondError(w, http.StatusForbidden, err.Error())
 			return
@@ -796,10 +856,13 @@ func (s *Service) handleUpdateCustomView(w http.ResponseWriter, r *http.Request)
 	}
 
 	log.Printf("[CustomViews] Successfully updated view ID: %d", id)
-	respondJSON(w, http.StatusOK, updated)
+	respondCustomView(w, r, http.StatusOK, updated)
 }
 
 func (s *Service) handleDeleteCustomView(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
 	vars := mux.Vars(r)
 	idStr := vars["id"]
 	log.Printf("[CustomViews] DELETE /api/custom_views/%s/ - Request from %s", idStr, r.RemoteAddr)
@@ -814,12 +877,12 @@ func (s *Service) handleDeleteCustomView(w http.ResponseWriter, r *http.Request)
 	userID, err := getUserIDFromRequest(r)
 	if err != nil {
 		log.Printf("[CustomViews] Error getting user ID: %v", err)
-		respondError(w, http.StatusUnauthorized, "Unauthorized")
+		respondError(w, viewerErrorStatus(err), err.Error())
 		return
 	}
 	log.Printf("[CustomViews] Deleting view ID: %d, User ID: %d", id, *userID)
 
-	if err := s.DeleteCustomView(id, *userID); err != nil {
+	if err := s.DeleteCustomView(ctx, id, *userID); err != nil {
 		log.Printf("[CustomViews] Error deleting view %d: %v", id, err)
 		if strings.Contains(err.Error(), "permission denied") {
 			respondError(w, http.StatusForbidden, err.Error())
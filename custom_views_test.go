@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+// newCustomViewListService creates a service where user 1 is a superuser without views
+// and users 2 (alice) and 3 (bob) each own a private and a global view; alice also has a
+// deleted view
+func newCustomViewListService(t *testing.T) *Service {
+	t.Helper()
+	s := newTestService(t, nil)
+	mustExec(t, s, `INSERT INTO auth_user (id, username, is_superuser) VALUES (1, 'admin', 1), (2, 'alice', 0), (3, 'bob', 0)`)
+
+	ctx := context.Background()
+	global := true
+	views := []struct {
+		name     string
+		ownerID  int
+		username string
+		global   bool
+	}{
+		{"Alice private", 2, "alice", false},
+		{"Alice global", 2, "alice", true},
+		{"Bob private", 3, "bob", false},
+		{"Bob global", 3, "bob", true},
+		{"Alice deleted", 2, "alice", true},
+	}
+	for _, v := range views {
+		view := CustomView{Name: v.name}
+		if v.global {
+			view.IsGlobal = &global
+		}
+		created, err := s.CreateCustomView(ctx, view, v.ownerID, v.username)
+		if err != nil {
+			t.Fatalf("failed to create view %s: %v", v.name, err)
+		}
+		if v.name == "Alice deleted" {
+			if err := s.DeleteCustomView(ctx, *created.ID, v.ownerID); err != nil {
+				t.Fatalf("failed to delete view %s: %v", v.name, err)
+			}
+		}
+	}
+	return s
+}
+
+func TestListCustomViewScopes(t *testing.T) {
+	s := newCustomViewListService(t)
+	base, mine, globalViews := s.handleListCustomViews, s.handleListMyCustomViews, s.handleListGlobalCustomViews
+
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		userID  int
+		query   string
+		status  int
+		want    []string
+	}{
+		// The base list: own views and the global views of everyone
+		{"base", base, 2, "", http.StatusOK, []string{"Alice global", "Alice private", "Bob global"}},
+		{"base of superuser", base, 1, "", http.StatusOK, []string{"Alice global", "Bob global"}},
+		{"base global_only", base, 2, "global_only=true", http.StatusOK, []string{"Alice global", "Alice private"}},
+		{"base own owner", base, 2, "owner=2", http.StatusOK, []string{"Alice global", "Alice private"}},
+		{"base other owner", base, 2, "owner=3", http.StatusForbidden, nil},
+		{"base other owner of superuser", base, 1, "owner=3", http.StatusOK, []string{"Bob global", "Bob private"}},
+		{"base search", base, 2, "q=GLOBAL", http.StatusOK, []string{"Alice global", "Bob global"}},
+		{"base invalid owner", base, 2, "owner=abc", http.StatusBadRequest, nil},
+		{"base zero owner", base, 2, "owner=0", http.StatusBadRequest, nil},
+		{"mine", mine, 2, "", http.StatusOK, []string{"Alice global", "Alice private"}},
+		{"mine of user without views", mine, 1, "", http.StatusOK, []string{}},
+		{"mine with owner", mine, 2, "owner=2", http.StatusBadRequest, nil},
+		{"global", globalViews, 2, "", http.StatusOK, []string{"Alice global", "Bob global"}},
+		{"global other owner", globalViews, 2, "owner=3", http.StatusOK, []string{"Bob global"}},
+		{"global other owner of superuser", globalViews, 1, "owner=3", http.StatusOK, []string{"Bob global"}},
+	}
+	for _, tt := range tests {
+		target := "/api/custom_views/?ordering=name"
+		if tt.query != "" {
+			target += "&" + tt.query
+		}
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		req.Header.Set("X-User-ID", strconv.Itoa(tt.userID))
+		rec := httptest.NewRecorder()
+		tt.handler(rec, req)
+
+		if rec.Code != tt.status {
+			t.Errorf("%s: status %d, want %d: %s", tt.name, rec.Code, tt.status, rec.Body)
+			continue
+		}
+		if tt.status != http.StatusOK {
+			continue
+		}
+		var response CustomViewListResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		names := []string{}
+		for _, view := range response.Results {
+			names = append(names, view.Name)
+		}
+		if !reflect.DeepEqual(names, tt.want) || response.Count != len(tt.want) {
+			t.Errorf("%s: got %v (count %d), want %v", tt.name, names, response.Count, tt.want)
+		}
+	}
+}
+
+func TestListCustomViewsOrderingAndPages(t *testing.T) {
+	s := newCustomViewListService(t)
+
+	list := func(query string) (*httptest.ResponseRecorder, CustomViewListResponse) {
+		req := httptest.NewRequest(http.MethodGet, "/api/custom_views/?"+query, nil)
+		req.Header.Set("X-User-ID", "2")
+		rec := httptest.NewRecorder()
+		s.handleListCustomViews(rec, req)
+		var response CustomViewListResponse
+		json.Unmarshal(rec.Body.Bytes(), &response)
+		return rec, response
+	}
+
+	if rec, _ := list("ordering=size"); rec.Code != http.StatusBadRequest {
+		t.Errorf("unknown ordering: status %d, want 400", rec.Code)
+	}
+
+	_, response := list("ordering=-name&page=1&page_size=2")
+	if response.Count != 3 || len(response.Results) != 2 || response.Next == nil || response.Previous != nil {
+		t.Fatalf("first page: count %d, %d results, next %v, previous %v", response.Count, len(response.Results), response.Next, response.Previous)
+	}
+	if response.Results[0].Name != "Bob global" || response.Results[1].Name != "Alice private" {
+		t.Errorf("first page %s, %s", response.Results[0].Name, response.Results[1].Name)
+	}
+	_, response = list("ordering=-name&page=2&page_size=2")
+	if len(response.Results) != 1 || response.Results[0].Name != "Alice global" || response.Next != nil || response.Previous == nil {
+		t.Errorf("second page: %+v", response)
+	}
+}
@@ -0,0 +1,507 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	// maxDashboardWidgets caps the widgets of a dashboard
+	maxDashboardWidgets = 50
+	// defaultRecentDocumentsLimit is the number of documents of a recent_documents widget without a limit
+	defaultRecentDocumentsLimit = 10
+	// dashboardWidgetConcurrency is the number of widgets of a dashboard evaluated at the same time
+	dashboardWidgetConcurrency = 4
+)
+
+// recentDocumentColumns are the columns of the documents of a recent_documents widget
+var recentDocumentColumns = []interface{}{"title", "created", "added", "correspondent", "document_type", "tags"}
+
+// DashboardViewCount is the data of a view_count widget
+type DashboardViewCount struct {
+	ViewID        int    `json:"view_id"`
+	Name          string `json:"name"`
+	DocumentCount int    `json:"document_count"`
+	CountedAt     string `json:"counted_at"`
+}
+
+// queryDashboards retrieves the dashboards selected by condition, ordered by name
+func (s *Service) queryDashboards(ctx context.Context, condition string, args ...interface{}) ([]Dashboard, error) {
+	rows, err := s.db.QueryContext(ctx, s.rebind(`
+		SELECT id, name, description, widgets, owner_id, created, modified
+		FROM dashboards
+		WHERE `+condition+`
+		ORDER BY name ASC, id ASC
+	`), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query dashboards: %w", err)
+	}
+	defer rows.Close()
+
+	dashboards := []Dashboard{}
+	for rows.Next() {
+		var dashboard Dashboard
+		var id int
+		var description, widgetsJSON sql.NullString
+		var ownerID sql.NullInt64
+		var created, modified dbTimestamp
+		if err := rows.Scan(&id, &dashboard.Name, &description, &widgetsJSON, &ownerID, &created, &modified); err != nil {
+			return nil, fmt.Errorf("failed to scan dashboard: %w", err)
+		}
+		dashboard.ID = &id
+		if description.Valid {
+			dashboard.Description = &description.String
+		}
+		dashboard.Widgets = []DashboardWidget{}
+		if widgetsJSON.Valid {
+			json.Unmarshal([]byte(widgetsJSON.String), &dashboard.Widgets)
+		}
+		if ownerID.Valid {
+			owner := int(ownerID.Int64)
+			dashboard.OwnerID = &owner
+		}
+		dashboard.Created = s.formatTimestamp(created)
+		dashboard.Modified = s.formatTimestamp(modified)
+		dashboards = append(dashboards, dashboard)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read dashboards: %w", err)
+	}
+	return dashboards, nil
+}
+
+// ListDashboards retrieves the dashboards of a user
+func (s *Service) ListDashboards(ctx context.Context, userID int) ([]Dashboard, error) {
+	return s.queryDashboards(ctx, "owner_id = ?", userID)
+}
+
+// GetDashboard retrieves a dashboard of the user; other users' dashboards are reported as
+// not found
+func (s *Service) GetDashboard(ctx context.Context, id int, userID int) (*Dashboard, error) {
+	dashboards, err := s.queryDashboards(ctx, "id = ? AND owner_id = ?", id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(dashboards) == 0 {
+		return nil, fmt.Errorf("dashboard with id %d not found", id)
+	}
+	return &dashboards[0], nil
+}
+
+// checkDashboard normalizes and validates a dashboard of the user before it is stored. Widgets
+// without an ID are numbered; the fields and views they refer to must exist.
+func (s *Service) checkDashboard(ctx context.Context, dashboard *Dashboard, userID int) error {
+	dashboard.Name = strings.TrimSpace(dashboard.Name)
+	if dashboard.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if dashboard.Widgets == nil {
+		dashboard.Widgets = []DashboardWidget{}
+	}
+	if len(dashboard.Widgets) > maxDashboardWidgets {
+		return fmt.Errorf("invalid widgets: at most %d widgets", maxDashboardWidgets)
+	}
+
+	ids := make(map[string]bool)
+	for i := range dashboard.Widgets {
+		widget := &dashboard.Widgets[i]
+		widget.ID = strings.TrimSpace(widget.ID)
+		if widget.ID == "" {
+			widget.ID = fmt.Sprintf("widget-%d", i+1)
+		}
+		if ids[widget.ID] {
+			return fmt.Errorf("invalid widget %q: duplicate ID", widget.ID)
+		}
+		ids[widget.ID] = true
+		if widget.Limit < 0 {
+			return fmt.Errorf("invalid widget %q: limit must not be negative", widget.ID)
+		}
+
+		switch widget.Type {
+		case "value_counts":
+			if widget.FieldID <= 0 {
+				return fmt.Errorf("invalid widget %q: field_id is required", widget.ID)
+			}
+			var exists int
+			err := s.db.QueryRowContext(ctx, s.rebind("SELECT 1 FROM documents_customfield WHERE id = ?"), widget.FieldID).Scan(&exists)
+			if err == sql.ErrNoRows {
+				return fmt.Errorf("invalid widget %q: custom field %d does not exist", widget.ID, widget.FieldID)
+			} else if err != nil {
+				return fmt.Errorf("failed to check custom field: %w", err)
+			}
+		case "view_count":
+			if widget.ViewID <= 0 {
+				return fmt.Errorf("invalid widget %q: view_id is required", widget.ID)
+			}
+			if _, err := s.accessibleCustomView(ctx, widget.ViewID, userID); err != nil {
+				if strings.Contains(err.Error(), "not found") {
+					return fmt.Errorf("invalid widget %q: custom view %d does not exist", widget.ID, widget.ViewID)
+				}
+				return err
+			}
+		case "recent_documents":
+			if widget.Limit > maxPageSize {
+				return fmt.Errorf("invalid widget %q: limit must be at most %d", widget.ID, maxPageSize)
+			}
+		default:
+			return fmt.Errorf("invalid widget %q: unknown type %q, expected value_counts, view_count or recent_documents", widget.ID, widget.Type)
+		}
+	}
+	return nil
+}
+
+// CreateDashboard creates a new dashboard owned by the user
+func (s *Service) CreateDashboard(ctx context.Context, dashboard Dashboard, userID int) (*Dashboard, error) {
+	log.Printf("[Dashboards] CreateDashboard - Name: %s, UserID: %d", dashboard.Name, userID)
+	if err := s.checkDashboard(ctx, &dashboard, userID); err != nil {
+		return nil, err
+	}
+	widgetsJSON, err := json.Marshal(dashboard.Widgets)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode widgets: %w", err)
+	}
+
+	var id int64
+	if s.config.DBEngine == "postgresql" || s.config.DBEngine == "postgres" {
+		err := s.db.QueryRowContext(ctx, `
+			INSERT INTO dashboards (name, description, widgets, owner_id)
+			VALUES ($1, $2, $3::jsonb, $4)
+			RETURNING id
+		`, dashboard.Name, dashboard.Description, string(widgetsJSON), userID).Scan(&id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create dashboard: %w", err)
+		}
+	} else {
+		result, err := s.db.ExecContext(ctx, `
+			INSERT INTO dashboards (name, description, widgets, owner_id)
+			VALUES (?, ?, ?, ?)
+		`, dashboard.Name, dashboard.Description, string(widgetsJSON), userID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create dashboard: %w", err)
+		}
+		if id, err = result.LastInsertId(); err != nil {
+			return nil, fmt.Errorf("failed to get dashboard ID: %w", err)
+		}
+	}
+
+	return s.GetDashboard(ctx, int(id), userID)
+}
+
+// UpdateDashboard replaces the name, description and widgets of a dashboard of the user
+func (s *Service) UpdateDashboard(ctx context.Context, id int, dashboard Dashboard, userID int) (*Dashboard, error) {
+	log.Printf("[Dashboards] UpdateDashboard - ID: %d, UserID: %d", id, userID)
+	if _, err := s.GetDashboard(ctx, id, userID); err != nil {
+		return nil, err
+	}
+	if err := s.checkDashboard(ctx, &dashboard, userID); err != nil {
+		return nil, err
+	}
+	widgetsJSON, err := json.Marshal(dashboard.Widgets)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode widgets: %w", err)
+	}
+
+	query := "UPDATE dashboards SET name = ?, description = ?, widgets = ?, modified = CURRENT_TIMESTAMP WHERE id = ?"
+	if s.config.DBEngine == "postgresql" || s.config.DBEngine == "postgres" {
+		query = "UPDATE dashboards SET name = $1, description = $2, widgets = $3::jsonb, modified = CURRENT_TIMESTAMP WHERE id = $4"
+	}
+	if _, err := s.db.ExecContext(ctx, query, dashboard.Name, dashboard.Description, string(widgetsJSON), id); err != nil {
+		return nil, fmt.Errorf("failed to update dashboard: %w", err)
+	}
+
+	return s.GetDashboard(ctx, id, userID)
+}
+
+// DeleteDashboard deletes a dashboard of the user
+func (s *Service) DeleteDashboard(ctx context.Context, id int, userID int) error {
+	log.Printf("[Dashboards] DeleteDashboard - ID: %d, UserID: %d", id, userID)
+	if _, err := s.GetDashboard(ctx, id, userID); err != nil {
+		return err
+	}
+	if _, err := s.db.ExecContext(ctx, s.rebind("DELETE FROM dashboards WHERE id = ?"), id); err != nil {
+		return fmt.Errorf("failed to delete dashboard: %w", err)
+	}
+	return nil
+}
+
+// GetDashboardData evaluates all widgets of a dashboard of the user, several at a time.
+// Documents are scoped to what viewerID can see. A widget that fails is reported with its
+// error without failing the others.
+func (s *Service) GetDashboardData(ctx context.Context, id int, userID int, viewerID int, bypass bool) (*DashboardDataResponse, error) {
+	dashboard, err := s.GetDashboard(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &DashboardDataResponse{DashboardID: id, Widgets: make([]DashboardWidgetData, len(dashboard.Widgets))}
+	slots := make(chan struct{}, dashboardWidgetConcurrency)
+	var wg sync.WaitGroup
+	for i, widget := range dashboard.Widgets {
+		wg.Add(1)
+		go func(i int, widget DashboardWidget) {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+
+			result := DashboardWidgetData{ID: widget.ID, Type: widget.Type, Title: widget.Title}
+			data, err := s.evaluateDashboardWidget(ctx, widget, userID, viewerID, bypass)
+			if err != nil {
+				log.Printf("[Dashboards] Error evaluating widget %q of dashboard %d: %v", widget.ID, id, err)
+				message := err.Error()
+				result.Error = &message
+			} else {
+				result.Data = data
+			}
+			response.Widgets[i] = result
+		}(i, widget)
+	}
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("failed to evaluate dashboard: %w", err)
+	}
+	return response, nil
+}
+
+// evaluateDashboardWidget computes the data of a widget
+func (s *Service) evaluateDashboardWidget(ctx context.Context, widget DashboardWidget, userID int, viewerID int, bypass bool) (interface{}, error) {
+	filterRulesJSON := ""
+	if len(widget.FilterRules) > 0 {
+		data, err := json.Marshal(widget.FilterRules)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode filter rules: %w", err)
+		}
+		filterRulesJSON = string(data)
+	}
+
+	switch widget.Type {
+	case "value_counts":
+		values, _, err := s.getValueCountsCached(ctx, widget.FieldID, filterRulesJSON, "count", "desc", false, false, bypass)
+		if err != nil {
+			return nil, err
+		}
+		if widget.Limit > 0 && len(values) > widget.Limit {
+			values = values[:widget.Limit]
+		}
+		return values, nil
+
+	case "view_count":
+		view, err := s.accessibleCustomView(ctx, widget.ViewID, userID)
+		if err != nil {
+			return nil, err
+		}
+		views := []CustomView{*view}
+		if err := s.addViewDocumentCounts(ctx, views, viewerID, bypass); err != nil {
+			return nil, err
+		}
+		return DashboardViewCount{ViewID: widget.ViewID, Name: view.Name, DocumentCount: *views[0].DocumentCount, CountedAt: *views[0].CountedAt}, nil
+
+	case "recent_documents":
+		limit := widget.Limit
+		if limit == 0 {
+			limit = defaultRecentDocumentsLimit
+		}
+		sortField := "added"
+		view := &CustomView{ColumnOrder: recentDocumentColumns, FilterRules: widget.FilterRules, SortField: &sortField}
+		return s.GetViewDocuments(ctx, view, viewerID, Pagination{Page: 1, PageSize: limit})
+	}
+	return nil, fmt.Errorf("invalid widget %q: unknown type %q", widget.ID, widget.Type)
+}
+
+// dashboardErrorStatus maps dashboard errors to HTTP status codes
+func dashboardErrorStatus(err error) int {
+	switch {
+	case strings.HasPrefix(err.Error(), "invalid"), strings.Contains(err.Error(), "required"):
+		return http.StatusBadRequest
+	case strings.Contains(err.Error(), "not found"):
+		return http.StatusNotFound
+	}
+	return queryErrorStatus(err)
+}
+
+// HTTP Handlers for dashboards
+
+func (s *Service) handleListDashboards(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	log.Printf("[Dashboards] GET /api/dashboards/ - Request from %s", r.RemoteAddr)
+
+	userID, err := getUserIDFromRequest(r)
+	if err != nil {
+		respondError(w, viewerErrorStatus(err), err.Error())
+		return
+	}
+
+	dashboards, err := s.ListDashboards(ctx, *userID)
+	if err != nil {
+		log.Printf("[Dashboards] Error listing dashboards: %v", err)
+		respondError(w, queryErrorStatus(err), err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, DashboardListResponse{Count: len(dashboards), Results: dashboards})
+}
+
+func (s *Service) handleGetDashboard(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	idStr := mux.Vars(r)["id"]
+	log.Printf("[Dashboards] GET /api/dashboards/%s/ - Request from %s", idStr, r.RemoteAddr)
+
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid dashboard ID")
+		return
+	}
+	userID, err := getUserIDFromRequest(r)
+	if err != nil {
+		respondError(w, viewerErrorStatus(err), err.Error())
+		return
+	}
+
+	dashboard, err := s.GetDashboard(ctx, id, *userID)
+	if err != nil {
+		respondError(w, dashboardErrorStatus(err), err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, dashboard)
+}
+
+func (s *Service) handleCreateDashboard(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	log.Printf("[Dashboards] POST /api/dashboards/ - Request from %s", r.RemoteAddr)
+
+	var dashboard Dashboard
+	if err := json.NewDecoder(r.Body).Decode(&dashboard); err != nil {
+		log.Printf("[Dashboards] Error decoding request body: %v", err)
+		respondError(w, http.StatusBadRequest, fmt.Sprintf("Invalid request body: %v", err))
+		return
+	}
+	userID, err := getUserIDFromRequest(r)
+	if err != nil {
+		respondError(w, viewerErrorStatus(err), err.Error())
+		return
+	}
+
+	created, err := s.CreateDashboard(ctx, dashboard, *userID)
+	if err != nil {
+		log.Printf("[Dashboards] Error creating dashboard: %v", err)
+		respondError(w, dashboardErrorStatus(err), err.Error())
+		return
+	}
+
+	log.Printf("[Dashboards] Successfully created dashboard %d: %s", *created.ID, created.Name)
+	respondJSON(w, http.StatusCreated, created)
+}
+
+func (s *Service) handleUpdateDashboard(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	idStr := mux.Vars(r)["id"]
+	log.Printf("[Dashboards] PUT /api/dashboards/%s/ - Request from %s", idStr, r.RemoteAddr)
+
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid dashboard ID")
+		return
+	}
+
+	var dashboard Dashboard
+	if err := json.NewDecoder(r.Body).Decode(&dashboard); err != nil {
+		log.Printf("[Dashboards] Error decoding request body: %v", err)
+		respondError(w, http.StatusBadRequest, fmt.Sprintf("Invalid request body: %v", err))
+		return
+	}
+	userID, err := getUserIDFromRequest(r)
+	if err != nil {
+		respondError(w, viewerErrorStatus(err), err.Error())
+		return
+	}
+
+	updated, err := s.UpdateDashboard(ctx, id, dashboard, *userID)
+	if err != nil {
+		log.Printf("[Dashboards] Error updating dashboard %d: %v", id, err)
+		respondError(w, dashboardErrorStatus(err), err.Error())
+		return
+	}
+
+	log.Printf("[Dashboards] Successfully updated dashboard %d", id)
+	respondJSON(w, http.StatusOK, updated)
+}
+
+func (s *Service) handleDeleteDashboard(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	idStr := mux.Vars(r)["id"]
+	log.Printf("[Dashboards] DELETE /api/dashboards/%s/ - Request from %s", idStr, r.RemoteAddr)
+
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid dashboard ID")
+		return
+	}
+	userID, err := getUserIDFromRequest(r)
+	if err != nil {
+		respondError(w, viewerErrorStatus(err), err.Error())
+		return
+	}
+
+	if err := s.DeleteDashboard(ctx, id, *userID); err != nil {
+		log.Printf("[Dashboards] Error deleting dashboard %d: %v", id, err)
+		respondError(w, dashboardErrorStatus(err), err.Error())
+		return
+	}
+
+	log.Printf("[Dashboards] Successfully deleted dashboard %d", id)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Service) handleGetDashboardData(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	idStr := mux.Vars(r)["id"]
+	log.Printf("[Dashboards] GET /api/dashboards/%s/data/ - Request from %s", idStr, r.RemoteAddr)
+
+	viewerID, err := viewerFromRequest(r)
+	if err != nil {
+		respondError(w, viewerErrorStatus(err), err.Error())
+		return
+	}
+
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid dashboard ID")
+		return
+	}
+	userID, err := getUserIDFromRequest(r)
+	if err != nil {
+		respondError(w, viewerErrorStatus(err), err.Error())
+		return
+	}
+
+	data, err := s.GetDashboardData(ctx, id, *userID, viewerID, bypassCache(r))
+	if err != nil {
+		log.Printf("[Dashboards] Error evaluating dashboard %d: %v", id, err)
+		respondError(w, dashboardErrorStatus(err), err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, data)
+}
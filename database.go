@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"fmt"
 	"log"
+	"strings"
 	"time"
 
 	_ "github.com/go-sql-driver/mysql"
@@ -22,15 +23,7 @@ func connectDB(config *Config) (*sql.DB, error) {
 	switch config.DBEngine {
 	case "postgresql", "postgres":
 		driverName = "postgres" // lib/pq uses "postgres" as driver name
-		dsn = fmt.Sprintf(
-			"host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
-			config.DBHost,
-			config.DBPort,
-			config.DBUser,
-			config.DBPass,
-			config.DBName,
-			config.DBSSLMode,
-		)
+		dsn = postgresDSN(config)
 	case "mysql", "mariadb":
 		driverName = "mysql"
 		dsn = fmt.Sprintf(
@@ -48,7 +41,7 @@ func connectDB(config *Config) (*sql.DB, error) {
 		return nil, fmt.Errorf("unsupported database engine: %s", config.DBEngine)
 	}
 
-	db, err := sql.Open(driverName, dsn)
+	db, err := openInstrumentedDB(driverName, dsn, config)
 	if err != nil {
 		return nil, err
 	}
@@ -61,6 +54,19 @@ func connectDB(config *Config) (*sql.DB, error) {
 	return db, nil
 }
 
+// postgresDSN builds the lib/pq connection string
+func postgresDSN(config *Config) string {
+	return fmt.Sprintf(
+		"host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
+		config.DBHost,
+		config.DBPort,
+		config.DBUser,
+		config.DBPass,
+		config.DBName,
+		config.DBSSLMode,
+	)
+}
+
 // initCustomViewsTable creates the custom_views table if it doesn't exist
 func (s *Service) initCustomViewsTable() error {
 	log.Printf("[Database] Initializing custom_views table for engine: %s", s.config.DBEngine)
@@ -174,6 +180,8 @@ func (s *Service) initCustomViewsTable() error {
 			"ALTER TABLE custom_views ADD COLUMN IF NOT EXISTS filter_types JSONB DEFAULT '{}'::jsonb",
 			"ALTER TABLE custom_views ADD COLUMN IF NOT EXISTS edit_mode_settings JSONB DEFAULT '{}'::jsonb",
 			"ALTER TABLE custom_views ADD COLUMN IF NOT EXISTS column_styles JSONB DEFAULT '{}'::jsonb",
+			"ALTER TABLE custom_views ADD COLUMN IF NOT EXISTS translations JSONB DEFAULT '{}'::jsonb",
+			"ALTER TABLE custom_views ADD COLUMN IF NOT EXISTS board_settings JSONB",
 		}
 	case "mysql", "mariadb":
 		migrationQueries = []string{
@@ -183,14 +191,16 @@ func (s *Service) initCustomViewsTable() error {
 			"ALTER TABLE custom_views ADD COLUMN IF NOT EXISTS filter_types JSON DEFAULT '{}'",
 			"ALTER TABLE custom_views ADD COLUMN IF NOT EXISTS edit_mode_settings JSON DEFAULT '{}'",
 			"ALTER TABLE custom_views ADD COLUMN IF NOT EXISTS column_styles JSON DEFAULT '{}'",
+			"ALTER TABLE custom_views ADD COLUMN IF NOT EXISTS translations JSON DEFAULT '{}'",
+			"ALTER TABLE custom_views ADD COLUMN IF NOT EXISTS board_settings JSON",
 		}
 	case "sqlite", "sqlite3":
 		// SQLite doesn't support IF NOT EXISTS for ALTER TABLE ADD COLUMN
 		// We'll check if columns exist first
 		var count int
-		checkQuery := "SELECT COUNT(*) FROM pragma_table_info('custom_views') WHERE name IN ('subrow_enabled', 'subrow_content', 'column_spanning', 'filter_types', 'edit_mode_settings', 'column_styles')"
+		checkQuery := "SELECT COUNT(*) FROM pragma_table_info('custom_views') WHERE name IN ('subrow_enabled', 'subrow_content', 'column_spanning', 'filter_types', 'edit_mode_settings', 'column_styles', 'translations', 'board_settings')"
 		err := s.db.QueryRow(checkQuery).Scan(&count)
-		if err == nil && count < 6 {
+		if err == nil && count < 8 {
 			migrationQueries = []string{
 				"ALTER TABLE custom_views ADD COLUMN subrow_enabled INTEGER DEFAULT 0",
 				"ALTER TABLE custom_views ADD COLUMN subrow_content TEXT",
@@ -198,6 +208,8 @@ func (s *Service) initCustomViewsTable() error {
 				"ALTER TABLE custom_views ADD COLUMN filter_types TEXT DEFAULT '{}'",
 				"ALTER TABLE custom_views ADD COLUMN edit_mode_settings TEXT DEFAULT '{}'",
 				"ALTER TABLE custom_views ADD COLUMN column_styles TEXT DEFAULT '{}'",
+				"ALTER TABLE custom_views ADD COLUMN translations TEXT DEFAULT '{}'",
+				"ALTER TABLE custom_views ADD COLUMN board_settings TEXT",
 			}
 		}
 	}
@@ -216,7 +228,7 @@ func (s *Service) initCustomViewsTable() error {
 }
 
 // initTagGroupsTables creates the tag_groups and tag_group_memberships tables if they don't exist
-// Also creates tag_descriptions table for storing descriptions for tags
+// Also creates the legacy tag_descriptions table, whose rows initEntityDescriptionsTable moves
 func (s *Service) initTagGroupsTables() error {
 	log.Printf("[Database] Initializing tag groups tables for engine: %s", s.config.DBEngine)
 
@@ -266,6 +278,36 @@ func (s *Service) initTagGroupsTables() error {
 		return fmt.Errorf("failed to create tag_groups table: %w", err)
 	}
 
+	// Columns added after the table was first released: nesting, then ownership
+	tagGroupColumns := []struct {
+		name             string
+		definition       string
+		sqliteDefinition string
+	}{
+		{"parent_group_id", "INTEGER", "INTEGER"},
+		{"owner_id", "INTEGER", "INTEGER"},
+		{"username", "VARCHAR(255)", "TEXT"},
+		{"is_global", "BOOLEAN DEFAULT FALSE", "INTEGER DEFAULT 0"},
+	}
+	for _, column := range tagGroupColumns {
+		var migration string
+		switch s.config.DBEngine {
+		case "postgresql", "postgres", "mysql", "mariadb":
+			migration = fmt.Sprintf("ALTER TABLE tag_groups ADD COLUMN IF NOT EXISTS %s %s", column.name, column.definition)
+		case "sqlite", "sqlite3":
+			var count int
+			err := s.db.QueryRow("SELECT COUNT(*) FROM pragma_table_info('tag_groups') WHERE name = ?", column.name).Scan(&count)
+			if err == nil && count == 0 {
+				migration = fmt.Sprintf("ALTER TABLE tag_groups ADD COLUMN %s %s", column.name, column.sqliteDefinition)
+			}
+		}
+		if migration != "" {
+			if _, err := s.db.Exec(migration); err != nil {
+				log.Printf("[Database] Migration query may have failed (column might already exist): %v", err)
+			}
+		}
+	}
+
 	// Create tag_group_memberships table (many-to-many relationship)
 	var createMembershipsQuery string
 	switch s.config.DBEngine {
@@ -362,3 +404,1976 @@ func (s *Service) initTagGroupsTables() error {
 	log.Printf("[Database] Successfully created/verified tag groups tables")
 	return nil
 }
+
+// initFieldValueSummariesTable creates the field_value_summaries table used to serve
+// precomputed value counts for hot fields
+func (s *Service) initFieldValueSummariesTable() error {
+	log.Printf("[Database] Initializing field_value_summaries table for engine: %s", s.config.DBEngine)
+	var createTableQuery string
+
+	switch s.config.DBEngine {
+	case "postgresql", "postgres":
+		createTableQuery = `
+			CREATE TABLE IF NOT EXISTS field_value_summaries (
+				field_id INTEGER PRIMARY KEY,
+				field_name VARCHAR(255),
+				value_counts JSONB NOT NULL DEFAULT '[]'::jsonb,
+				total_documents INTEGER DEFAULT 0,
+				computed_at TIMESTAMP NOT NULL
+			);
+		`
+	case "mysql", "mariadb":
+		createTableQuery = `
+			CREATE TABLE IF NOT EXISTS field_value_summaries (
+				field_id INT PRIMARY KEY,
+				field_name VARCHAR(255),
+				value_counts JSON NOT NULL,
+				total_documents INT DEFAULT 0,
+				computed_at TIMESTAMP NOT NULL
+			);
+		`
+	case "sqlite", "sqlite3":
+		createTableQuery = `
+			CREATE TABLE IF NOT EXISTS field_value_summaries (
+				field_id INTEGER PRIMARY KEY,
+				field_name TEXT,
+				value_counts TEXT NOT NULL DEFAULT '[]',
+				total_documents INTEGER DEFAULT 0,
+				computed_at TIMESTAMP NOT NULL
+			);
+		`
+	default:
+		return fmt.Errorf("unsupported database engine: %s", s.config.DBEngine)
+	}
+
+	log.Printf("[Database] Executing CREATE TABLE statement for field_value_summaries")
+	if _, err := s.db.Exec(createTableQuery); err != nil {
+		log.Printf("[Database] Error creating field_value_summaries table: %v", err)
+		return fmt.Errorf("failed to create field_value_summaries table: %w", err)
+	}
+
+	log.Printf("[Database] Successfully created/verified field_value_summaries table")
+	return nil
+}
+
+// initFilterPresetsTable creates the filter_presets table if it doesn't exist
+func (s *Service) initFilterPresetsTable() error {
+	log.Printf("[Database] Initializing filter_presets table for engine: %s", s.config.DBEngine)
+	var createTableQuery string
+
+	switch s.config.DBEngine {
+	case "postgresql", "postgres":
+		createTableQuery = `
+			CREATE TABLE IF NOT EXISTS filter_presets (
+				id SERIAL PRIMARY KEY,
+				name VARCHAR(255) NOT NULL,
+				description TEXT,
+				filter_rules JSONB NOT NULL DEFAULT '[]'::jsonb,
+				is_global BOOLEAN DEFAULT false,
+				owner_id INTEGER,
+				username VARCHAR(255),
+				created TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				modified TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+			);
+			CREATE INDEX IF NOT EXISTS idx_filter_presets_owner ON filter_presets(owner_id);
+		`
+	case "mysql", "mariadb":
+		createTableQuery = `
+			CREATE TABLE IF NOT EXISTS filter_presets (
+				id INT AUTO_INCREMENT PRIMARY KEY,
+				name VARCHAR(255) NOT NULL,
+				description TEXT,
+				filter_rules JSON NOT NULL,
+				is_global BOOLEAN DEFAULT false,
+				owner_id INT,
+				username VARCHAR(255),
+				created TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				modified TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+				INDEX idx_owner (owner_id)
+			);
+		`
+	case "sqlite", "sqlite3":
+		createTableQuery = `
+			CREATE TABLE IF NOT EXISTS filter_presets (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				name TEXT NOT NULL,
+				description TEXT,
+				filter_rules TEXT NOT NULL DEFAULT '[]',
+				is_global INTEGER DEFAULT 0,
+				owner_id INTEGER,
+				username TEXT,
+				created TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				modified TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+			);
+			CREATE INDEX IF NOT EXISTS idx_filter_presets_owner ON filter_presets(owner_id);
+		`
+	default:
+		return fmt.Errorf("unsupported database engine: %s", s.config.DBEngine)
+	}
+
+	log.Printf("[Database] Executing CREATE TABLE statement for filter_presets")
+	if _, err := s.db.Exec(createTableQuery); err != nil {
+		log.Printf("[Database] Error creating filter_presets table: %v", err)
+		return fmt.Errorf("failed to create filter_presets table: %w", err)
+	}
+
+	log.Printf("[Database] Successfully created/verified filter_presets table")
+	return nil
+}
+
+// initCustomViewRevisionsTable creates the custom_view_revisions table keeping previous
+// configurations of custom views
+func (s *Service) initCustomViewRevisionsTable() error {
+	log.Printf("[Database] Initializing custom_view_revisions table for engine: %s", s.config.DBEngine)
+	var createTableQuery string
+
+	switch s.config.DBEngine {
+	case "postgresql", "postgres":
+		createTableQuery = `
+			CREATE TABLE IF NOT EXISTS custom_view_revisions (
+				id SERIAL PRIMARY KEY,
+				view_id INTEGER NOT NULL,
+				revision INTEGER NOT NULL,
+				snapshot JSONB NOT NULL,
+				user_id INTEGER,
+				created TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				UNIQUE(view_id, revision)
+			);
+			CREATE INDEX IF NOT EXISTS idx_view_revisions_view ON custom_view_revisions(view_id);
+		`
+	case "mysql", "mariadb":
+		createTableQuery = `
+			CREATE TABLE IF NOT EXISTS custom_view_revisions (
+				id INT AUTO_INCREMENT PRIMARY KEY,
+				view_id INT NOT NULL,
+				revision INT NOT NULL,
+				snapshot JSON NOT NULL,
+				user_id INT,
+				created TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				UNIQUE KEY unique_view_revision (view_id, revision),
+				INDEX idx_view (view_id)
+			);
+		`
+	case "sqlite", "sqlite3":
+		createTableQuery = `
+			CREATE TABLE IF NOT EXISTS custom_view_revisions (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				view_id INTEGER NOT NULL,
+				revision INTEGER NOT NULL,
+				snapshot TEXT NOT NULL,
+				user_id INTEGER,
+				created TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				UNIQUE(view_id, revision)
+			);
+			CREATE INDEX IF NOT EXISTS idx_view_revisions_view ON custom_view_revisions(view_id);
+		`
+	default:
+		return fmt.Errorf("unsupported database engine: %s", s.config.DBEngine)
+	}
+
+	log.Printf("[Database] Executing CREATE TABLE statement for custom_view_revisions")
+	if _, err := s.db.Exec(createTableQuery); err != nil {
+		log.Printf("[Database] Error creating custom_view_revisions table: %v", err)
+		return fmt.Errorf("failed to create custom_view_revisions table: %w", err)
+	}
+
+	log.Printf("[Database] Successfully created/verified custom_view_revisions table")
+	return nil
+}
+
+func (s *Service) initUserViewPreferencesTable() error {
+	log.Printf("[Database] Initializing user_view_preferences table for engine: %s", s.config.DBEngine)
+	var createTableQuery string
+
+	switch s.config.DBEngine {
+	case "postgresql", "postgres":
+		createTableQuery = `
+			CREATE TABLE IF NOT EXISTS user_view_preferences (
+				id SERIAL PRIMARY KEY,
+				user_id INTEGER NOT NULL,
+				view_id INTEGER NOT NULL,
+				is_favorite BOOLEAN NOT NULL DEFAULT false,
+				position INTEGER,
+				is_default BOOLEAN NOT NULL DEFAULT false,
+				UNIQUE(user_id, view_id)
+			);
+			CREATE INDEX IF NOT EXISTS idx_view_preferences_view ON user_view_preferences(view_id);
+		`
+	case "mysql", "mariadb":
+		createTableQuery = `
+			CREATE TABLE IF NOT EXISTS user_view_preferences (
+				id INT AUTO_INCREMENT PRIMARY KEY,
+				user_id INT NOT NULL,
+				view_id INT NOT NULL,
+				is_favorite BOOLEAN NOT NULL DEFAULT false,
+				position INT,
+				is_default BOOLEAN NOT NULL DEFAULT false,
+				UNIQUE KEY unique_user_view (user_id, view_id),
+				INDEX idx_view (view_id)
+			);
+		`
+	case "sqlite", "sqlite3":
+		createTableQuery = `
+			CREATE TABLE IF NOT EXISTS user_view_preferences (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				user_id INTEGER NOT NULL,
+				view_id INTEGER NOT NULL,
+				is_favorite INTEGER NOT NULL DEFAULT 0,
+				position INTEGER,
+				is_default INTEGER NOT NULL DEFAULT 0,
+				UNIQUE(user_id, view_id)
+			);
+			CREATE INDEX IF NOT EXISTS idx_view_preferences_view ON user_view_preferences(view_id);
+		`
+	default:
+		return fmt.Errorf("unsupported database engine: %s", s.config.DBEngine)
+	}
+
+	log.Printf("[Database] Executing CREATE TABLE statement for user_view_preferences")
+	if _, err := s.db.Exec(createTableQuery); err != nil {
+		log.Printf("[Database] Error creating user_view_preferences table: %v", err)
+		return fmt.Errorf("failed to create user_view_preferences table: %w", err)
+	}
+
+	log.Printf("[Database] Successfully created/verified user_view_preferences table")
+	return nil
+}
+
+// rebind rewrites the ? placeholders of a query to $1, $2, ... for PostgreSQL
+func (s *Service) rebind(query string) string {
+	if s.config.DBEngine != "postgresql" && s.config.DBEngine != "postgres" {
+		return query
+	}
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			fmt.Fprintf(&b, "$%d", n)
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func (s *Service) initWebhooksTable() error {
+	log.Printf("[Database] Initializing webhooks table for engine: %s", s.config.DBEngine)
+	var createTableQuery string
+
+	switch s.config.DBEngine {
+	case "postgresql", "postgres":
+		createTableQuery = `
+			CREATE TABLE IF NOT EXISTS webhooks (
+				id SERIAL PRIMARY KEY,
+				url TEXT NOT NULL,
+				secret TEXT,
+				events JSONB NOT NULL DEFAULT '[]'::jsonb,
+				is_active BOOLEAN NOT NULL DEFAULT true,
+				owner_id INTEGER,
+				username VARCHAR(255),
+				created TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				modified TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+			);
+			CREATE INDEX IF NOT EXISTS idx_webhooks_owner ON webhooks(owner_id);
+		`
+	case "mysql", "mariadb":
+		createTableQuery = `
+			CREATE TABLE IF NOT EXISTS webhooks (
+				id INT AUTO_INCREMENT PRIMARY KEY,
+				url TEXT NOT NULL,
+				secret TEXT,
+				events JSON NOT NULL,
+				is_active BOOLEAN NOT NULL DEFAULT true,
+				owner_id INT,
+				username VARCHAR(255),
+				created TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				modified TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+				INDEX idx_owner (owner_id)
+			);
+		`
+	case "sqlite", "sqlite3":
+		createTableQuery = `
+			CREATE TABLE IF NOT EXISTS webhooks (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				url TEXT NOT NULL,
+				secret TEXT,
+				events TEXT NOT NULL DEFAULT '[]',
+				is_active INTEGER NOT NULL DEFAULT 1,
+				owner_id INTEGER,
+				username TEXT,
+				created TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				modified TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+			);
+			CREATE INDEX IF NOT EXISTS idx_webhooks_owner ON webhooks(owner_id);
+		`
+	default:
+		return fmt.Errorf("unsupported database engine: %s", s.config.DBEngine)
+	}
+
+	log.Printf("[Database] Executing CREATE TABLE statement for webhooks")
+	if _, err := s.db.Exec(createTableQuery); err != nil {
+		log.Printf("[Database] Error creating webhooks table: %v", err)
+		return fmt.Errorf("failed to create webhooks table: %w", err)
+	}
+
+	log.Printf("[Database] Successfully created/verified webhooks table")
+	return nil
+}
+
+// initColumnPresetsTable creates the column_presets table if it doesn't exist
+func (s *Service) initColumnPresetsTable() error {
+	log.Printf("[Database] Initializing column_presets table for engine: %s", s.config.DBEngine)
+	var createTableQuery string
+
+	switch s.config.DBEngine {
+	case "postgresql", "postgres":
+		createTableQuery = `
+			CREATE TABLE IF NOT EXISTS column_presets (
+				id SERIAL PRIMARY KEY,
+				name VARCHAR(255) NOT NULL,
+				description TEXT,
+				column_order JSONB NOT NULL DEFAULT '[]'::jsonb,
+				column_sizing JSONB NOT NULL DEFAULT '{}'::jsonb,
+				column_visibility JSONB NOT NULL DEFAULT '{}'::jsonb,
+				column_display_types JSONB NOT NULL DEFAULT '{}'::jsonb,
+				is_global BOOLEAN DEFAULT false,
+				owner_id INTEGER,
+				username VARCHAR(255),
+				created TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				modified TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+			);
+			CREATE INDEX IF NOT EXISTS idx_column_presets_owner ON column_presets(owner_id);
+		`
+	case "mysql", "mariadb":
+		createTableQuery = `
+			CREATE TABLE IF NOT EXISTS column_presets (
+				id INT AUTO_INCREMENT PRIMARY KEY,
+				name VARCHAR(255) NOT NULL,
+				description TEXT,
+				column_order JSON NOT NULL,
+				column_sizing JSON NOT NULL,
+				column_visibility JSON NOT NULL,
+				column_display_types JSON NOT NULL,
+				is_global BOOLEAN DEFAULT false,
+				owner_id INT,
+				username VARCHAR(255),
+				created TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				modified TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+				INDEX idx_owner (owner_id)
+			);
+		`
+	case "sqlite", "sqlite3":
+		createTableQuery = `
+			CREATE TABLE IF NOT EXISTS column_presets (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				name TEXT NOT NULL,
+				description TEXT,
+				column_order TEXT NOT NULL DEFAULT '[]',
+				column_sizing TEXT NOT NULL DEFAULT '{}',
+				column_visibility TEXT NOT NULL DEFAULT '{}',
+				column_display_types TEXT NOT NULL DEFAULT '{}',
+				is_global INTEGER DEFAULT 0,
+				owner_id INTEGER,
+				username TEXT,
+				created TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				modified TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+			);
+			CREATE INDEX IF NOT EXISTS idx_column_presets_owner ON column_presets(owner_id);
+		`
+	default:
+		return fmt.Errorf("unsupported database engine: %s", s.config.DBEngine)
+	}
+
+	log.Printf("[Database] Executing CREATE TABLE statement for column_presets")
+	if _, err := s.db.Exec(createTableQuery); err != nil {
+		log.Printf("[Database] Error creating column_presets table: %v", err)
+		return fmt.Errorf("failed to create column_presets table: %w", err)
+	}
+
+	log.Printf("[Database] Successfully created/verified column_presets table")
+	return nil
+}
+
+// initViewSnapshotsTable creates the view_snapshots table if it doesn't exist
+func (s *Service) initViewSnapshotsTable() error {
+	log.Printf("[Database] Initializing view_snapshots table for engine: %s", s.config.DBEngine)
+	var createTableQuery string
+
+	switch s.config.DBEngine {
+	case "postgresql", "postgres":
+		createTableQuery = `
+			CREATE TABLE IF NOT EXISTS view_snapshots (
+				id SERIAL PRIMARY KEY,
+				view_id INTEGER NOT NULL,
+				document_ids JSONB NOT NULL DEFAULT '[]'::jsonb,
+				document_count INTEGER NOT NULL DEFAULT 0,
+				trigger_type VARCHAR(16) NOT NULL,
+				created TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+			);
+			CREATE INDEX IF NOT EXISTS idx_view_snapshots_view ON view_snapshots(view_id);
+		`
+	case "mysql", "mariadb":
+		createTableQuery = `
+			CREATE TABLE IF NOT EXISTS view_snapshots (
+				id INT AUTO_INCREMENT PRIMARY KEY,
+				view_id INT NOT NULL,
+				document_ids JSON NOT NULL,
+				document_count INT NOT NULL DEFAULT 0,
+				trigger_type VARCHAR(16) NOT NULL,
+				created TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				INDEX idx_view (view_id)
+			);
+		`
+	case "sqlite", "sqlite3":
+		createTableQuery = `
+			CREATE TABLE IF NOT EXISTS view_snapshots (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				view_id INTEGER NOT NULL,
+				document_ids TEXT NOT NULL DEFAULT '[]',
+				document_count INTEGER NOT NULL DEFAULT 0,
+				trigger_type TEXT NOT NULL,
+				created TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+			);
+			CREATE INDEX IF NOT EXISTS idx_view_snapshots_view ON view_snapshots(view_id);
+		`
+	default:
+		return fmt.Errorf("unsupported database engine: %s", s.config.DBEngine)
+	}
+
+	log.Printf("[Database] Executing CREATE TABLE statement for view_snapshots")
+	if _, err := s.db.Exec(createTableQuery); err != nil {
+		log.Printf("[Database] Error creating view_snapshots table: %v", err)
+		return fmt.Errorf("failed to create view_snapshots table: %w", err)
+	}
+
+	log.Printf("[Database] Successfully created/verified view_snapshots table")
+	return nil
+}
+
+// initViewSnapshotSchedulesTable creates the view_snapshot_schedules table if it doesn't exist
+func (s *Service) initViewSnapshotSchedulesTable() error {
+	log.Printf("[Database] Initializing view_snapshot_schedules table for engine: %s", s.config.DBEngine)
+	var createTableQuery string
+
+	switch s.config.DBEngine {
+	case "postgresql", "postgres":
+		createTableQuery = `
+			CREATE TABLE IF NOT EXISTS view_snapshot_schedules (
+				view_id INTEGER PRIMARY KEY,
+				schedule_interval VARCHAR(32) NOT NULL,
+				next_run TIMESTAMP NOT NULL,
+				last_run TIMESTAMP,
+				user_id INTEGER,
+				created TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+			);
+		`
+	case "mysql", "mariadb":
+		createTableQuery = `
+			CREATE TABLE IF NOT EXISTS view_snapshot_schedules (
+				view_id INT PRIMARY KEY,
+				schedule_interval VARCHAR(32) NOT NULL,
+				next_run TIMESTAMP NOT NULL,
+				last_run TIMESTAMP NULL,
+				user_id INT,
+				created TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+			);
+		`
+	case "sqlite", "sqlite3":
+		createTableQuery = `
+			CREATE TABLE IF NOT EXISTS view_snapshot_schedules (
+				view_id INTEGER PRIMARY KEY,
+				schedule_interval TEXT NOT NULL,
+				next_run TIMESTAMP NOT NULL,
+				last_run TIMESTAMP,
+				user_id INTEGER,
+				created TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+			);
+		`
+	default:
+		return fmt.Errorf("unsupported database engine: %s", s.config.DBEngine)
+	}
+
+	log.Printf("[Database] Executing CREATE TABLE statement for view_snapshot_schedules")
+	if _, err := s.db.Exec(createTableQuery); err != nil {
+		log.Printf("[Database] Error creating view_snapshot_schedules table: %v", err)
+		return fmt.Errorf("failed to create view_snapshot_schedules table: %w", err)
+	}
+
+	log.Printf("[Database] Successfully created/verified view_snapshot_schedules table")
+	return nil
+}
+
+// initViewNotificationRulesTable creates the view_notification_rules table if it doesn't exist
+func (s *Service) initViewNotificationRulesTable() error {
+	log.Printf("[Database] Initializing view_notification_rules table for engine: %s", s.config.DBEngine)
+	var createTableQuery string
+
+	switch s.config.DBEngine {
+	case "postgresql", "postgres":
+		createTableQuery = `
+			CREATE TABLE IF NOT EXISTS view_notification_rules (
+				id SERIAL PRIMARY KEY,
+				view_id INTEGER NOT NULL,
+				condition_type VARCHAR(32) NOT NULL,
+				threshold INTEGER,
+				channel VARCHAR(16) NOT NULL,
+				target TEXT NOT NULL,
+				secret TEXT,
+				is_active BOOLEAN DEFAULT true,
+				condition_met BOOLEAN DEFAULT false,
+				last_document_ids JSONB,
+				last_checked TIMESTAMP,
+				last_triggered TIMESTAMP,
+				owner_id INTEGER,
+				username VARCHAR(255),
+				created TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				modified TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+			);
+			CREATE INDEX IF NOT EXISTS idx_view_notification_rules_view ON view_notification_rules(view_id);
+		`
+	case "mysql", "mariadb":
+		createTableQuery = `
+			CREATE TABLE IF NOT EXISTS view_notification_rules (
+				id INT AUTO_INCREMENT PRIMARY KEY,
+				view_id INT NOT NULL,
+				condition_type VARCHAR(32) NOT NULL,
+				threshold INT,
+				channel VARCHAR(16) NOT NULL,
+				target TEXT NOT NULL,
+				secret TEXT,
+				is_active BOOLEAN DEFAULT true,
+				condition_met BOOLEAN DEFAULT false,
+				last_document_ids JSON,
+				last_checked TIMESTAMP NULL,
+				last_triggered TIMESTAMP NULL,
+				owner_id INT,
+				username VARCHAR(255),
+				created TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				modified TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+				INDEX idx_view (view_id)
+			);
+		`
+	case "sqlite", "sqlite3":
+		createTableQuery = `
+			CREATE TABLE IF NOT EXISTS view_notification_rules (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				view_id INTEGER NOT NULL,
+				condition_type TEXT NOT NULL,
+				threshold INTEGER,
+				channel TEXT NOT NULL,
+				target TEXT NOT NULL,
+				secret TEXT,
+				is_active INTEGER DEFAULT 1,
+				condition_met INTEGER DEFAULT 0,
+				last_document_ids TEXT,
+				last_checked TIMESTAMP,
+				last_triggered TIMESTAMP,
+				owner_id INTEGER,
+				username TEXT,
+				created TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				modified TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+			);
+			CREATE INDEX IF NOT EXISTS idx_view_notification_rules_view ON view_notification_rules(view_id);
+		`
+	default:
+		return fmt.Errorf("unsupported database engine: %s", s.config.DBEngine)
+	}
+
+	log.Printf("[Database] Executing CREATE TABLE statement for view_notification_rules")
+	if _, err := s.db.Exec(createTableQuery); err != nil {
+		log.Printf("[Database] Error creating view_notification_rules table: %v", err)
+		return fmt.Errorf("failed to create view_notification_rules table: %w", err)
+	}
+
+	log.Printf("[Database] Successfully created/verified view_notification_rules table")
+	return nil
+}
+
+// initEntityDescriptionsTable creates the entity_descriptions table, which holds the
+// descriptions of tags, correspondents, document types and storage paths, and the table of
+// their earlier revisions. It moves the rows of the former tag_descriptions table over.
+func (s *Service) initEntityDescriptionsTable() error {
+	log.Printf("[Database] Initializing entity_descriptions table for engine: %s", s.config.DBEngine)
+	var createTableQuery string
+
+	switch s.config.DBEngine {
+	case "postgresql", "postgres":
+		createTableQuery = `
+			CREATE TABLE IF NOT EXISTS entity_descriptions (
+				id SERIAL PRIMARY KEY,
+				entity_type VARCHAR(32) NOT NULL,
+				entity_id INTEGER NOT NULL,
+				description TEXT,
+				created TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				modified TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				modified_by INTEGER,
+				UNIQUE(entity_type, entity_id)
+			);
+		`
+	case "mysql", "mariadb":
+		createTableQuery = `
+			CREATE TABLE IF NOT EXISTS entity_descriptions (
+				id INT AUTO_INCREMENT PRIMARY KEY,
+				entity_type VARCHAR(32) NOT NULL,
+				entity_id INT NOT NULL,
+				description TEXT,
+				created TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				modified TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+				modified_by INTEGER,
+				UNIQUE KEY unique_entity (entity_type, entity_id)
+			);
+		`
+	case "sqlite", "sqlite3":
+		createTableQuery = `
+			CREATE TABLE IF NOT EXISTS entity_descriptions (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				entity_type TEXT NOT NULL,
+				entity_id INTEGER NOT NULL,
+				description TEXT,
+				created TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				modified TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				modified_by INTEGER,
+				UNIQUE(entity_type, entity_id)
+			);
+		`
+	default:
+		return fmt.Errorf("unsupported database engine: %s", s.config.DBEngine)
+	}
+
+	log.Printf("[Database] Executing CREATE TABLE statement for entity_descriptions")
+	if _, err := s.db.Exec(createTableQuery); err != nil {
+		log.Printf("[Database] Error creating entity_descriptions table: %v", err)
+		return fmt.Errorf("failed to create entity_descriptions table: %w", err)
+	}
+
+	// modified_by was added after the table was first released
+	var migration string
+	switch s.config.DBEngine {
+	case "postgresql", "postgres", "mysql", "mariadb":
+		migration = "ALTER TABLE entity_descriptions ADD COLUMN IF NOT EXISTS modified_by INTEGER"
+	case "sqlite", "sqlite3":
+		var count int
+		err := s.db.QueryRow("SELECT COUNT(*) FROM pragma_table_info('entity_descriptions') WHERE name = 'modified_by'").Scan(&count)
+		if err == nil && count == 0 {
+			migration = "ALTER TABLE entity_descriptions ADD COLUMN modified_by INTEGER"
+		}
+	}
+	if migration != "" {
+		if _, err := s.db.Exec(migration); err != nil {
+			log.Printf("[Database] Migration query may have failed (column might already exist): %v", err)
+		}
+	}
+
+	// Earlier versions of descriptions, see recordEntityDescriptionRevision
+	var createRevisionsQuery string
+	switch s.config.DBEngine {
+	case "postgresql", "postgres":
+		createRevisionsQuery = `
+			CREATE TABLE IF NOT EXISTS entity_description_revisions (
+				id SERIAL PRIMARY KEY,
+				entity_type VARCHAR(32) NOT NULL,
+				entity_id INTEGER NOT NULL,
+				revision INTEGER NOT NULL,
+				description TEXT,
+				modified_by INTEGER,
+				modified TIMESTAMP,
+				created TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				UNIQUE(entity_type, entity_id, revision)
+			);
+		`
+	case "mysql", "mariadb":
+		createRevisionsQuery = `
+			CREATE TABLE IF NOT EXISTS entity_description_revisions (
+				id INT AUTO_INCREMENT PRIMARY KEY,
+				entity_type VARCHAR(32) NOT NULL,
+				entity_id INT NOT NULL,
+				revision INT NOT NULL,
+				description TEXT,
+				modified_by INT,
+				modified TIMESTAMP NULL,
+				created TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				UNIQUE KEY unique_entity_revision (entity_type, entity_id, revision)
+			);
+		`
+	case "sqlite", "sqlite3":
+		createRevisionsQuery = `
+			CREATE TABLE IF NOT EXISTS entity_description_revisions (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				entity_type TEXT NOT NULL,
+				entity_id INTEGER NOT NULL,
+				revision INTEGER NOT NULL,
+				description TEXT,
+				modified_by INTEGER,
+				modified TIMESTAMP,
+				created TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				UNIQUE(entity_type, entity_id, revision)
+			);
+		`
+	}
+
+	log.Printf("[Database] Executing CREATE TABLE statement for entity_description_revisions")
+	if _, err := s.db.Exec(createRevisionsQuery); err != nil {
+		log.Printf("[Database] Error creating entity_description_revisions table: %v", err)
+		return fmt.Errorf("failed to create entity_description_revisions table: %w", err)
+	}
+
+	// Tag descriptions used to have a table of their own. Its rows are moved, not copied,
+	// so that descriptions deleted later do not come back on the next start.
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin tag description migration: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		INSERT INTO entity_descriptions (entity_type, entity_id, description, created, modified)
+		SELECT 'tag', d.tag_id, d.description, d.created, d.modified
+		FROM tag_descriptions d
+		WHERE NOT EXISTS (
+			SELECT 1 FROM entity_descriptions e WHERE e.entity_type = 'tag' AND e.entity_id = d.tag_id
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to migrate tag descriptions: %w", err)
+	}
+	if _, err := tx.Exec("DELETE FROM tag_descriptions"); err != nil {
+		return fmt.Errorf("failed to migrate tag descriptions: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit tag description migration: %w", err)
+	}
+	if migrated, _ := result.RowsAffected(); migrated > 0 {
+		log.Printf("[Database] Moved %d tag descriptions to entity_descriptions", migrated)
+	}
+
+	log.Printf("[Database] Successfully created/verified entity descriptions tables")
+	return nil
+}
+
+// initTagAliasesTable creates the table mapping alias tags to their canonical tag
+func (s *Service) initTagAliasesTable() error {
+	log.Printf("[Database] Initializing tag_aliases table for engine: %s", s.config.DBEngine)
+	var createTableQuery string
+
+	switch s.config.DBEngine {
+	case "postgresql", "postgres":
+		createTableQuery = `
+			CREATE TABLE IF NOT EXISTS tag_aliases (
+				id SERIAL PRIMARY KEY,
+				alias_tag_id INTEGER NOT NULL UNIQUE,
+				canonical_tag_id INTEGER NOT NULL,
+				created TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				modified TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				created_by INTEGER
+			);
+			CREATE INDEX IF NOT EXISTS idx_tag_aliases_canonical ON tag_aliases(canonical_tag_id);
+		`
+	case "mysql", "mariadb":
+		createTableQuery = `
+			CREATE TABLE IF NOT EXISTS tag_aliases (
+				id INT AUTO_INCREMENT PRIMARY KEY,
+				alias_tag_id INT NOT NULL UNIQUE,
+				canonical_tag_id INT NOT NULL,
+				created TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				modified TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+				created_by INT,
+				INDEX idx_canonical (canonical_tag_id)
+			);
+		`
+	case "sqlite", "sqlite3":
+		createTableQuery = `
+			CREATE TABLE IF NOT EXISTS tag_aliases (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				alias_tag_id INTEGER NOT NULL UNIQUE,
+				canonical_tag_id INTEGER NOT NULL,
+				created TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				modified TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				created_by INTEGER
+			);
+			CREATE INDEX IF NOT EXISTS idx_tag_aliases_canonical ON tag_aliases(canonical_tag_id);
+		`
+	default:
+		return fmt.Errorf("unsupported database engine: %s", s.config.DBEngine)
+	}
+
+	log.Printf("[Database] Executing CREATE TABLE statement for tag_aliases")
+	if _, err := s.db.Exec(createTableQuery); err != nil {
+		log.Printf("[Database] Error creating tag_aliases table: %v", err)
+		return fmt.Errorf("failed to create tag_aliases table: %w", err)
+	}
+
+	log.Printf("[Database] Successfully created/verified tag_aliases table")
+	return nil
+}
+
+// initDocumentLinksTable creates the document_links table if it doesn't exist
+func (s *Service) initDocumentLinksTable() error {
+	log.Printf("[Database] Initializing document_links table for engine: %s", s.config.DBEngine)
+	var createTableQuery string
+
+	switch s.config.DBEngine {
+	case "postgresql", "postgres":
+		createTableQuery = `
+			CREATE TABLE IF NOT EXISTS document_links (
+				id SERIAL PRIMARY KEY,
+				source_id INTEGER NOT NULL,
+				target_id INTEGER NOT NULL,
+				link_type VARCHAR(64) NOT NULL,
+				note TEXT,
+				created TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				modified TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				created_by INTEGER,
+				UNIQUE(source_id, target_id, link_type)
+			);
+			CREATE INDEX IF NOT EXISTS idx_document_links_target ON document_links(target_id);
+		`
+	case "mysql", "mariadb":
+		createTableQuery = `
+			CREATE TABLE IF NOT EXISTS document_links (
+				id INT AUTO_INCREMENT PRIMARY KEY,
+				source_id INT NOT NULL,
+				target_id INT NOT NULL,
+				link_type VARCHAR(64) NOT NULL,
+				note TEXT,
+				created TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				modified TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+				created_by INT,
+				UNIQUE KEY unique_link (source_id, target_id, link_type),
+				INDEX idx_target (target_id)
+			);
+		`
+	case "sqlite", "sqlite3":
+		createTableQuery = `
+			CREATE TABLE IF NOT EXISTS document_links (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				source_id INTEGER NOT NULL,
+				target_id INTEGER NOT NULL,
+				link_type TEXT NOT NULL,
+				note TEXT,
+				created TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				modified TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				created_by INTEGER,
+				UNIQUE(source_id, target_id, link_type)
+			);
+			CREATE INDEX IF NOT EXISTS idx_document_links_target ON document_links(target_id);
+		`
+	default:
+		return fmt.Errorf("unsupported database engine: %s", s.config.DBEngine)
+	}
+
+	log.Printf("[Database] Executing CREATE TABLE statement for document_links")
+	if _, err := s.db.Exec(createTableQuery); err != nil {
+		log.Printf("[Database] Error creating document_links table: %v", err)
+		return fmt.Errorf("failed to create document_links table: %w", err)
+	}
+
+	log.Printf("[Database] Successfully created/verified document_links table")
+	return nil
+}
+
+// initLinkTypesTable creates the link_types table if it doesn't exist and defines the
+// default link type as well as the types of existing links
+func (s *Service) initLinkTypesTable() error {
+	log.Printf("[Database] Initializing link_types table for engine: %s", s.config.DBEngine)
+	var createTableQuery string
+
+	switch s.config.DBEngine {
+	case "postgresql", "postgres":
+		createTableQuery = `
+			CREATE TABLE IF NOT EXISTS link_types (
+				id SERIAL PRIMARY KEY,
+				name VARCHAR(64) NOT NULL UNIQUE,
+				inverse_name VARCHAR(64),
+				color VARCHAR(7),
+				directed BOOLEAN DEFAULT FALSE,
+				created TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				modified TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+			);
+		`
+	case "mysql", "mariadb":
+		createTableQuery = `
+			CREATE TABLE IF NOT EXISTS link_types (
+				id INT AUTO_INCREMENT PRIMARY KEY,
+				name VARCHAR(64) NOT NULL UNIQUE,
+				inverse_name VARCHAR(64),
+				color VARCHAR(7),
+				directed BOOLEAN DEFAULT FALSE,
+				created TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				modified TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
+			);
+		`
+	case "sqlite", "sqlite3":
+		createTableQuery = `
+			CREATE TABLE IF NOT EXISTS link_types (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				name TEXT NOT NULL UNIQUE,
+				inverse_name TEXT,
+				color TEXT,
+				directed INTEGER DEFAULT 0,
+				created TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				modified TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+			);
+		`
+	default:
+		return fmt.Errorf("unsupported database engine: %s", s.config.DBEngine)
+	}
+
+	log.Printf("[Database] Executing CREATE TABLE statement for link_types")
+	if _, err := s.db.Exec(createTableQuery); err != nil {
+		log.Printf("[Database] Error creating link_types table: %v", err)
+		return fmt.Errorf("failed to create link_types table: %w", err)
+	}
+
+	var defaults int
+	if err := s.db.QueryRow(s.rebind("SELECT COUNT(*) FROM link_types WHERE name = ?"), defaultDocumentLinkType).Scan(&defaults); err != nil {
+		return fmt.Errorf("failed to query default link type: %w", err)
+	}
+	if defaults == 0 {
+		if _, err := s.db.Exec(s.rebind("INSERT INTO link_types (name, directed) VALUES (?, ?)"), defaultDocumentLinkType, false); err != nil {
+			return fmt.Errorf("failed to define default link type: %w", err)
+		}
+	}
+
+	// Links used to have free text types, which become undirected link types
+	rows, err := s.db.Query(`
+		SELECT DISTINCT l.link_type FROM document_links l
+		WHERE NOT EXISTS (SELECT 1 FROM link_types t WHERE t.name = l.link_type)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to query undefined link types: %w", err)
+	}
+	undefined := []string{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan link type: %w", err)
+		}
+		undefined = append(undefined, name)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read undefined link types: %w", err)
+	}
+	for _, name := range undefined {
+		if _, err := s.db.Exec(s.rebind("INSERT INTO link_types (name, directed) VALUES (?, ?)"), name, false); err != nil {
+			return fmt.Errorf("failed to define link type %q: %w", name, err)
+		}
+		log.Printf("[Database] Defined link type %q of existing links", name)
+	}
+
+	log.Printf("[Database] Successfully created/verified link_types table")
+	return nil
+}
+
+// initLinkSuggestionsTable creates the link_suggestions table if it doesn't exist
+func (s *Service) initLinkSuggestionsTable() error {
+	log.Printf("[Database] Initializing link_suggestions table for engine: %s", s.config.DBEngine)
+	var createTableQuery string
+
+	switch s.config.DBEngine {
+	case "postgresql", "postgres":
+		createTableQuery = `
+			CREATE TABLE IF NOT EXISTS link_suggestions (
+				id SERIAL PRIMARY KEY,
+				document_id INTEGER NOT NULL,
+				related_id INTEGER NOT NULL,
+				score DOUBLE PRECISION NOT NULL,
+				reasons TEXT,
+				status VARCHAR(16) NOT NULL DEFAULT 'pending',
+				created TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				modified TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				UNIQUE(document_id, related_id)
+			);
+			CREATE INDEX IF NOT EXISTS idx_link_suggestions_related ON link_suggestions(related_id);
+		`
+	case "mysql", "mariadb":
+		createTableQuery = `
+			CREATE TABLE IF NOT EXISTS link_suggestions (
+				id INT AUTO_INCREMENT PRIMARY KEY,
+				document_id INT NOT NULL,
+				related_id INT NOT NULL,
+				score DOUBLE NOT NULL,
+				reasons TEXT,
+				status VARCHAR(16) NOT NULL DEFAULT 'pending',
+				created TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				modified TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+				UNIQUE KEY unique_suggestion (document_id, related_id),
+				INDEX idx_related (related_id)
+			);
+		`
+	case "sqlite", "sqlite3":
+		createTableQuery = `
+			CREATE TABLE IF NOT EXISTS link_suggestions (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				document_id INTEGER NOT NULL,
+				related_id INTEGER NOT NULL,
+				score REAL NOT NULL,
+				reasons TEXT,
+				status TEXT NOT NULL DEFAULT 'pending',
+				created TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				modified TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				UNIQUE(document_id, related_id)
+			);
+			CREATE INDEX IF NOT EXISTS idx_link_suggestions_related ON link_suggestions(related_id);
+		`
+	default:
+		return fmt.Errorf("unsupported database engine: %s", s.config.DBEngine)
+	}
+
+	log.Printf("[Database] Executing CREATE TABLE statement for link_suggestions")
+	if _, err := s.db.Exec(createTableQuery); err != nil {
+		log.Printf("[Database] Error creating link_suggestions table: %v", err)
+		return fmt.Errorf("failed to create link_suggestions table: %w", err)
+	}
+
+	log.Printf("[Database] Successfully created/verified link_suggestions table")
+	return nil
+}
+
+// initDashboardsTable creates the dashboards table if it doesn't exist
+func (s *Service) initDashboardsTable() error {
+	log.Printf("[Database] Initializing dashboards table for engine: %s", s.config.DBEngine)
+	var createTableQuery string
+
+	switch s.config.DBEngine {
+	case "postgresql", "postgres":
+		createTableQuery = `
+			CREATE TABLE IF NOT EXISTS dashboards (
+				id SERIAL PRIMARY KEY,
+				name VARCHAR(255) NOT NULL,
+				description TEXT,
+				widgets JSONB NOT NULL DEFAULT '[]'::jsonb,
+				owner_id INTEGER,
+				created TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				modified TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+			);
+			CREATE INDEX IF NOT EXISTS idx_dashboards_owner ON dashboards(owner_id);
+		`
+	case "mysql", "mariadb":
+		createTableQuery = `
+			CREATE TABLE IF NOT EXISTS dashboards (
+				id INT AUTO_INCREMENT PRIMARY KEY,
+				name VARCHAR(255) NOT NULL,
+				description TEXT,
+				widgets JSON NOT NULL,
+				owner_id INT,
+				created TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				modified TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+				INDEX idx_owner (owner_id)
+			);
+		`
+	case "sqlite", "sqlite3":
+		createTableQuery = `
+			CREATE TABLE IF NOT EXISTS dashboards (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				name TEXT NOT NULL,
+				description TEXT,
+				widgets TEXT NOT NULL DEFAULT '[]',
+				owner_id INTEGER,
+				created TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				modified TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+			);
+			CREATE INDEX IF NOT EXISTS idx_dashboards_owner ON dashboards(owner_id);
+		`
+	default:
+		return fmt.Errorf("unsupported database engine: %s", s.config.DBEngine)
+	}
+
+	log.Printf("[Database] Executing CREATE TABLE statement for dashboards")
+	if _, err := s.db.Exec(createTableQuery); err != nil {
+		log.Printf("[Database] Error creating dashboards table: %v", err)
+		return fmt.Errorf("failed to create dashboards table: %w", err)
+	}
+
+	log.Printf("[Database] Successfully created/verified dashboards table")
+	return nil
+}
+
+// initUserPreferencesTable creates the user_preferences table if it doesn't exist
+func (s *Service) initUserPreferencesTable() error {
+	log.Printf("[Database] Initializing user_preferences table for engine: %s", s.config.DBEngine)
+	var createTableQuery string
+
+	switch s.config.DBEngine {
+	case "postgresql", "postgres":
+		createTableQuery = `
+			CREATE TABLE IF NOT EXISTS user_preferences (
+				id SERIAL PRIMARY KEY,
+				user_id INTEGER NOT NULL,
+				namespace VARCHAR(100) NOT NULL,
+				data JSONB NOT NULL DEFAULT '{}'::jsonb,
+				version INTEGER NOT NULL DEFAULT 1,
+				created TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				modified TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				UNIQUE(user_id, namespace)
+			);
+		`
+	case "mysql", "mariadb":
+		createTableQuery = `
+			CREATE TABLE IF NOT EXISTS user_preferences (
+				id INT AUTO_INCREMENT PRIMARY KEY,
+				user_id INT NOT NULL,
+				namespace VARCHAR(100) NOT NULL,
+				data JSON NOT NULL,
+				version INT NOT NULL DEFAULT 1,
+				created TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				modified TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+				UNIQUE KEY unique_user_namespace (user_id, namespace)
+			);
+		`
+	case "sqlite", "sqlite3":
+		createTableQuery = `
+			CREATE TABLE IF NOT EXISTS user_preferences (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				user_id INTEGER NOT NULL,
+				namespace TEXT NOT NULL,
+				data TEXT NOT NULL DEFAULT '{}',
+				version INTEGER NOT NULL DEFAULT 1,
+				created TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				modified TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				UNIQUE(user_id, namespace)
+			);
+		`
+	default:
+		return fmt.Errorf("unsupported database engine: %s", s.config.DBEngine)
+	}
+
+	log.Printf("[Database] Executing CREATE TABLE statement for user_preferences")
+	if _, err := s.db.Exec(createTableQuery); err != nil {
+		log.Printf("[Database] Error creating user_preferences table: %v", err)
+		return fmt.Errorf("failed to create user_preferences table: %w", err)
+	}
+
+	log.Printf("[Database] Successfully created/verified user_preferences table")
+	return nil
+}
+
+// initDocumentActivityTable creates the user_document_activity table if it doesn't exist
+func (s *Service) initDocumentActivityTable() error {
+	log.Printf("[Database] Initializing user_document_activity table for engine: %s", s.config.DBEngine)
+	var createTableQuery string
+
+	switch s.config.DBEngine {
+	case "postgresql", "postgres":
+		createTableQuery = `
+			CREATE TABLE IF NOT EXISTS user_document_activity (
+				id SERIAL PRIMARY KEY,
+				user_id INTEGER NOT NULL,
+				document_id INTEGER NOT NULL,
+				last_viewed TIMESTAMP,
+				view_count INTEGER NOT NULL DEFAULT 0,
+				pinned BOOLEAN NOT NULL DEFAULT false,
+				pinned_at TIMESTAMP,
+				UNIQUE(user_id, document_id)
+			);
+			CREATE INDEX IF NOT EXISTS idx_document_activity_viewed ON user_document_activity(user_id, last_viewed);
+		`
+	case "mysql", "mariadb":
+		createTableQuery = `
+			CREATE TABLE IF NOT EXISTS user_document_activity (
+				id INT AUTO_INCREMENT PRIMARY KEY,
+				user_id INT NOT NULL,
+				document_id INT NOT NULL,
+				last_viewed DATETIME(6),
+				view_count INT NOT NULL DEFAULT 0,
+				pinned BOOLEAN NOT NULL DEFAULT false,
+				pinned_at DATETIME(6),
+				UNIQUE KEY unique_user_document (user_id, document_id),
+				INDEX idx_user_viewed (user_id, last_viewed)
+			);
+		`
+	case "sqlite", "sqlite3":
+		createTableQuery = `
+			CREATE TABLE IF NOT EXISTS user_document_activity (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				user_id INTEGER NOT NULL,
+				document_id INTEGER NOT NULL,
+				last_viewed TIMESTAMP,
+				view_count INTEGER NOT NULL DEFAULT 0,
+				pinned INTEGER NOT NULL DEFAULT 0,
+				pinned_at TIMESTAMP,
+				UNIQUE(user_id, document_id)
+			);
+			CREATE INDEX IF NOT EXISTS idx_document_activity_viewed ON user_document_activity(user_id, last_viewed);
+		`
+	default:
+		return fmt.Errorf("unsupported database engine: %s", s.config.DBEngine)
+	}
+
+	log.Printf("[Database] Executing CREATE TABLE statement for user_document_activity")
+	if _, err := s.db.Exec(createTableQuery); err != nil {
+		log.Printf("[Database] Error creating user_document_activity table: %v", err)
+		return fmt.Errorf("failed to create user_document_activity table: %w", err)
+	}
+
+	log.Printf("[Database] Successfully created/verified user_document_activity table")
+	return nil
+}
+
+// initDocumentRemindersTable creates the document_reminders table if it doesn't exist
+func (s *Service) initDocumentRemindersTable() error {
+	log.Printf("[Database] Initializing document_reminders table for engine: %s", s.config.DBEngine)
+	var createTableQuery string
+
+	switch s.config.DBEngine {
+	case "postgresql", "postgres":
+		createTableQuery = `
+			CREATE TABLE IF NOT EXISTS document_reminders (
+				id SERIAL PRIMARY KEY,
+				document_id INTEGER NOT NULL,
+				due_at TIMESTAMP NOT NULL,
+				note TEXT,
+				channel VARCHAR(16),
+				target TEXT,
+				secret TEXT,
+				completed_at TIMESTAMP,
+				notified_at TIMESTAMP,
+				owner_id INTEGER,
+				created TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				modified TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+			);
+			CREATE INDEX IF NOT EXISTS idx_document_reminders_owner ON document_reminders(owner_id, due_at);
+			CREATE INDEX IF NOT EXISTS idx_document_reminders_due ON document_reminders(due_at);
+		`
+	case "mysql", "mariadb":
+		createTableQuery = `
+			CREATE TABLE IF NOT EXISTS document_reminders (
+				id INT AUTO_INCREMENT PRIMARY KEY,
+				document_id INT NOT NULL,
+				due_at DATETIME(6) NOT NULL,
+				note TEXT,
+				channel VARCHAR(16),
+				target TEXT,
+				secret TEXT,
+				completed_at DATETIME(6) NULL,
+				notified_at TIMESTAMP NULL,
+				owner_id INT,
+				created TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				modified TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+				INDEX idx_owner_due (owner_id, due_at),
+				INDEX idx_due (due_at)
+			);
+		`
+	case "sqlite", "sqlite3":
+		createTableQuery = `
+			CREATE TABLE IF NOT EXISTS document_reminders (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				document_id INTEGER NOT NULL,
+				due_at TIMESTAMP NOT NULL,
+				note TEXT,
+				channel TEXT,
+				target TEXT,
+				secret TEXT,
+				completed_at TIMESTAMP,
+				notified_at TIMESTAMP,
+				owner_id INTEGER,
+				created TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				modified TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+			);
+			CREATE INDEX IF NOT EXISTS idx_document_reminders_owner ON document_reminders(owner_id, due_at);
+			CREATE INDEX IF NOT EXISTS idx_document_reminders_due ON document_reminders(due_at);
+		`
+	default:
+		return fmt.Errorf("unsupported database engine: %s", s.config.DBEngine)
+	}
+
+	log.Printf("[Database] Executing CREATE TABLE statement for document_reminders")
+	if _, err := s.db.Exec(createTableQuery); err != nil {
+		log.Printf("[Database] Error creating document_reminders table: %v", err)
+		return fmt.Errorf("failed to create document_reminders table: %w", err)
+	}
+
+	log.Printf("[Database] Successfully created/verified document_reminders table")
+	return nil
+}
+
+// initSavedSearchesTable creates the saved_searches table if it doesn't exist
+func (s *Service) initSavedSearchesTable() error {
+	log.Printf("[Database] Initializing saved_searches table for engine: %s", s.config.DBEngine)
+	var createTableQuery string
+
+	switch s.config.DBEngine {
+	case "postgresql", "postgres":
+		createTableQuery = `
+			CREATE TABLE IF NOT EXISTS saved_searches (
+				id SERIAL PRIMARY KEY,
+				name VARCHAR(255) NOT NULL,
+				filter_rules JSONB,
+				webhook_url TEXT,
+				secret TEXT,
+				last_seen_document_id INTEGER NOT NULL DEFAULT 0,
+				last_checked TIMESTAMP,
+				last_notified_document_id INTEGER NOT NULL DEFAULT 0,
+				last_triggered TIMESTAMP,
+				owner_id INTEGER,
+				created TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				modified TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+			);
+			CREATE INDEX IF NOT EXISTS idx_saved_searches_owner ON saved_searches(owner_id);
+		`
+	case "mysql", "mariadb":
+		createTableQuery = `
+			CREATE TABLE IF NOT EXISTS saved_searches (
+				id INT AUTO_INCREMENT PRIMARY KEY,
+				name VARCHAR(255) NOT NULL,
+				filter_rules JSON,
+				webhook_url TEXT,
+				secret TEXT,
+				last_seen_document_id INT NOT NULL DEFAULT 0,
+				last_checked TIMESTAMP NULL,
+				last_notified_document_id INT NOT NULL DEFAULT 0,
+				last_triggered TIMESTAMP NULL,
+				owner_id INT,
+				created TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				modified TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+				INDEX idx_owner (owner_id)
+			);
+		`
+	case "sqlite", "sqlite3":
+		createTableQuery = `
+			CREATE TABLE IF NOT EXISTS saved_searches (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				name TEXT NOT NULL,
+				filter_rules TEXT,
+				webhook_url TEXT,
+				secret TEXT,
+				last_seen_document_id INTEGER NOT NULL DEFAULT 0,
+				last_checked TIMESTAMP,
+				last_notified_document_id INTEGER NOT NULL DEFAULT 0,
+				last_triggered TIMESTAMP,
+				owner_id INTEGER,
+				created TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				modified TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+			);
+			CREATE INDEX IF NOT EXISTS idx_saved_searches_owner ON saved_searches(owner_id);
+		`
+	default:
+		return fmt.Errorf("unsupported database engine: %s", s.config.DBEngine)
+	}
+
+	log.Printf("[Database] Executing CREATE TABLE statement for saved_searches")
+	if _, err := s.db.Exec(createTableQuery); err != nil {
+		log.Printf("[Database] Error creating saved_searches table: %v", err)
+		return fmt.Errorf("failed to create saved_searches table: %w", err)
+	}
+
+	log.Printf("[Database] Successfully created/verified saved_searches table")
+	return nil
+}
+
+// initDocumentNotesTable creates the document_notes table if it doesn't exist
+func (s *Service) initDocumentNotesTable() error {
+	log.Printf("[Database] Initializing document_notes table for engine: %s", s.config.DBEngine)
+	var createTableQuery string
+
+	switch s.config.DBEngine {
+	case "postgresql", "postgres":
+		createTableQuery = `
+			CREATE TABLE IF NOT EXISTS document_notes (
+				id SERIAL PRIMARY KEY,
+				document_id INTEGER NOT NULL,
+				parent_id INTEGER,
+				body TEXT NOT NULL,
+				author_id INTEGER,
+				username VARCHAR(255),
+				created TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				modified TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+			);
+			CREATE INDEX IF NOT EXISTS idx_document_notes_document ON document_notes(document_id);
+		`
+	case "mysql", "mariadb":
+		createTableQuery = `
+			CREATE TABLE IF NOT EXISTS document_notes (
+				id INT AUTO_INCREMENT PRIMARY KEY,
+				document_id INT NOT NULL,
+				parent_id INT,
+				body TEXT NOT NULL,
+				author_id INT,
+				username VARCHAR(255),
+				created TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				modified TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+				INDEX idx_document (document_id)
+			);
+		`
+	case "sqlite", "sqlite3":
+		createTableQuery = `
+			CREATE TABLE IF NOT EXISTS document_notes (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				document_id INTEGER NOT NULL,
+				parent_id INTEGER,
+				body TEXT NOT NULL,
+				author_id INTEGER,
+				username TEXT,
+				created TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				modified TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+			);
+			CREATE INDEX IF NOT EXISTS idx_document_notes_document ON document_notes(document_id);
+		`
+	default:
+		return fmt.Errorf("unsupported database engine: %s", s.config.DBEngine)
+	}
+
+	log.Printf("[Database] Executing CREATE TABLE statement for document_notes")
+	if _, err := s.db.Exec(createTableQuery); err != nil {
+		log.Printf("[Database] Error creating document_notes table: %v", err)
+		return fmt.Errorf("failed to create document_notes table: %w", err)
+	}
+
+	log.Printf("[Database] Successfully created/verified document_notes table")
+	return nil
+}
+
+// initWorkflowsTables creates the workflows and document_workflow_status tables if they don't exist
+func (s *Service) initWorkflowsTables() error {
+	log.Printf("[Database] Initializing workflows tables for engine: %s", s.config.DBEngine)
+	var createTableQuery string
+
+	switch s.config.DBEngine {
+	case "postgresql", "postgres":
+		createTableQuery = `
+			CREATE TABLE IF NOT EXISTS workflows (
+				id SERIAL PRIMARY KEY,
+				name VARCHAR(255) NOT NULL,
+				description TEXT,
+				statuses JSONB NOT NULL,
+				owner_id INTEGER,
+				created TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				modified TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+			);
+			CREATE TABLE IF NOT EXISTS document_workflow_status (
+				id SERIAL PRIMARY KEY,
+				workflow_id INTEGER NOT NULL,
+				document_id INTEGER NOT NULL,
+				status VARCHAR(50) NOT NULL,
+				updated_by INTEGER,
+				username VARCHAR(255),
+				modified TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				UNIQUE(workflow_id, document_id)
+			);
+			CREATE INDEX IF NOT EXISTS idx_document_workflow_status_document ON document_workflow_status(document_id);
+		`
+	case "mysql", "mariadb":
+		createTableQuery = `
+			CREATE TABLE IF NOT EXISTS workflows (
+				id INT AUTO_INCREMENT PRIMARY KEY,
+				name VARCHAR(255) NOT NULL,
+				description TEXT,
+				statuses JSON NOT NULL,
+				owner_id INT,
+				created TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				modified TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
+			);
+			CREATE TABLE IF NOT EXISTS document_workflow_status (
+				id INT AUTO_INCREMENT PRIMARY KEY,
+				workflow_id INT NOT NULL,
+				document_id INT NOT NULL,
+				status VARCHAR(50) NOT NULL,
+				updated_by INT,
+				username VARCHAR(255),
+				modified TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+				UNIQUE KEY unique_workflow_document (workflow_id, document_id),
+				INDEX idx_document (document_id)
+			);
+		`
+	case "sqlite", "sqlite3":
+		createTableQuery = `
+			CREATE TABLE IF NOT EXISTS workflows (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				name TEXT NOT NULL,
+				description TEXT,
+				statuses TEXT NOT NULL,
+				owner_id INTEGER,
+				created TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				modified TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+			);
+			CREATE TABLE IF NOT EXISTS document_workflow_status (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				workflow_id INTEGER NOT NULL,
+				document_id INTEGER NOT NULL,
+				status TEXT NOT NULL,
+				updated_by INTEGER,
+				username TEXT,
+				modified TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				UNIQUE(workflow_id, document_id)
+			);
+			CREATE INDEX IF NOT EXISTS idx_document_workflow_status_document ON document_workflow_status(document_id);
+		`
+	default:
+		return fmt.Errorf("unsupported database engine: %s", s.config.DBEngine)
+	}
+
+	log.Printf("[Database] Executing CREATE TABLE statements for workflows")
+	if _, err := s.db.Exec(createTableQuery); err != nil {
+		log.Printf("[Database] Error creating workflows tables: %v", err)
+		return fmt.Errorf("failed to create workflows tables: %w", err)
+	}
+
+	log.Printf("[Database] Successfully created/verified workflows tables")
+	return nil
+}
+
+// initScheduledReportsTable creates the scheduled_reports table if it doesn't exist
+func (s *Service) initScheduledReportsTable() error {
+	log.Printf("[Database] Initializing scheduled_reports table for engine: %s", s.config.DBEngine)
+	var createTableQuery string
+
+	switch s.config.DBEngine {
+	case "postgresql", "postgres":
+		createTableQuery = `
+			CREATE TABLE IF NOT EXISTS scheduled_reports (
+				id SERIAL PRIMARY KEY,
+				view_id INTEGER NOT NULL,
+				name VARCHAR(255) NOT NULL,
+				format VARCHAR(10) NOT NULL,
+				columns TEXT,
+				facets TEXT,
+				destination VARCHAR(20) NOT NULL,
+				target VARCHAR(255),
+				schedule_interval VARCHAR(50) NOT NULL,
+				next_run TIMESTAMP NOT NULL,
+				last_run TIMESTAMP,
+				last_status VARCHAR(20),
+				last_error TEXT,
+				last_output TEXT,
+				owner_id INTEGER,
+				created TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				modified TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+			);
+			CREATE INDEX IF NOT EXISTS idx_scheduled_reports_next_run ON scheduled_reports(next_run);
+		`
+	case "mysql", "mariadb":
+		createTableQuery = `
+			CREATE TABLE IF NOT EXISTS scheduled_reports (
+				id INT AUTO_INCREMENT PRIMARY KEY,
+				view_id INT NOT NULL,
+				name VARCHAR(255) NOT NULL,
+				format VARCHAR(10) NOT NULL,
+				columns TEXT,
+				facets TEXT,
+				destination VARCHAR(20) NOT NULL,
+				target VARCHAR(255),
+				schedule_interval VARCHAR(50) NOT NULL,
+				next_run DATETIME NOT NULL,
+				last_run DATETIME,
+				last_status VARCHAR(20),
+				last_error TEXT,
+				last_output TEXT,
+				owner_id INT,
+				created TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				modified TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+				INDEX idx_next_run (next_run)
+			);
+		`
+	case "sqlite", "sqlite3":
+		createTableQuery = `
+			CREATE TABLE IF NOT EXISTS scheduled_reports (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				view_id INTEGER NOT NULL,
+				name TEXT NOT NULL,
+				format TEXT NOT NULL,
+				columns TEXT,
+				facets TEXT,
+				destination TEXT NOT NULL,
+				target TEXT,
+				schedule_interval TEXT NOT NULL,
+				next_run TIMESTAMP NOT NULL,
+				last_run TIMESTAMP,
+				last_status TEXT,
+				last_error TEXT,
+				last_output TEXT,
+				owner_id INTEGER,
+				created TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				modified TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+			);
+			CREATE INDEX IF NOT EXISTS idx_scheduled_reports_next_run ON scheduled_reports(next_run);
+		`
+	default:
+		return fmt.Errorf("unsupported database engine: %s", s.config.DBEngine)
+	}
+
+	log.Printf("[Database] Executing CREATE TABLE statement for scheduled_reports")
+	if _, err := s.db.Exec(createTableQuery); err != nil {
+		log.Printf("[Database] Error creating scheduled_reports table: %v", err)
+		return fmt.Errorf("failed to create scheduled_reports table: %w", err)
+	}
+
+	log.Printf("[Database] Successfully created/verified scheduled_reports table")
+	return nil
+}
+
+// initShareLinksTable creates the share_links table if it doesn't exist
+func (s *Service) initShareLinksTable() error {
+	log.Printf("[Database] Initializing share_links table for engine: %s", s.config.DBEngine)
+	var createTableQuery string
+
+	switch s.config.DBEngine {
+	case "postgresql", "postgres":
+		createTableQuery = `
+			CREATE TABLE IF NOT EXISTS share_links (
+				id SERIAL PRIMARY KEY,
+				name VARCHAR(255) NOT NULL,
+				token VARCHAR(64) NOT NULL UNIQUE,
+				filter_rules JSONB,
+				password_hash TEXT,
+				expires_at TIMESTAMP,
+				access_count INTEGER NOT NULL DEFAULT 0,
+				last_accessed TIMESTAMP,
+				owner_id INTEGER,
+				created TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				modified TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+			);
+			CREATE INDEX IF NOT EXISTS idx_share_links_owner ON share_links(owner_id);
+		`
+	case "mysql", "mariadb":
+		createTableQuery = `
+			CREATE TABLE IF NOT EXISTS share_links (
+				id INT AUTO_INCREMENT PRIMARY KEY,
+				name VARCHAR(255) NOT NULL,
+				token VARCHAR(64) NOT NULL UNIQUE,
+				filter_rules JSON,
+				password_hash TEXT,
+				expires_at DATETIME,
+				access_count INT NOT NULL DEFAULT 0,
+				last_accessed TIMESTAMP NULL,
+				owner_id INT,
+				created TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				modified TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+				INDEX idx_owner (owner_id)
+			);
+		`
+	case "sqlite", "sqlite3":
+		createTableQuery = `
+			CREATE TABLE IF NOT EXISTS share_links (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				name TEXT NOT NULL,
+				token TEXT NOT NULL UNIQUE,
+				filter_rules TEXT,
+				password_hash TEXT,
+				expires_at TIMESTAMP,
+				access_count INTEGER NOT NULL DEFAULT 0,
+				last_accessed TIMESTAMP,
+				owner_id INTEGER,
+				created TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				modified TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+			);
+			CREATE INDEX IF NOT EXISTS idx_share_links_owner ON share_links(owner_id);
+		`
+	default:
+		return fmt.Errorf("unsupported database engine: %s", s.config.DBEngine)
+	}
+
+	log.Printf("[Database] Executing CREATE TABLE statement for share_links")
+	if _, err := s.db.Exec(createTableQuery); err != nil {
+		log.Printf("[Database] Error creating share_links table: %v", err)
+		return fmt.Errorf("failed to create share_links table: %w", err)
+	}
+
+	log.Printf("[Database] Successfully created/verified share_links table")
+	return nil
+}
+
+// initActivityEventsTable creates the activity_events table if it doesn't exist
+func (s *Service) initActivityEventsTable() error {
+	log.Printf("[Database] Initializing activity_events table for engine: %s", s.config.DBEngine)
+	var createTableQuery string
+
+	switch s.config.DBEngine {
+	case "postgresql", "postgres":
+		createTableQuery = `
+			CREATE TABLE IF NOT EXISTS activity_events (
+				id SERIAL PRIMARY KEY,
+				event VARCHAR(50) NOT NULL,
+				document_id INTEGER,
+				user_id INTEGER,
+				title TEXT,
+				data JSONB,
+				created TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+			);
+			CREATE INDEX IF NOT EXISTS idx_activity_events_document ON activity_events(document_id);
+			CREATE INDEX IF NOT EXISTS idx_activity_events_created ON activity_events(created);
+		`
+	case "mysql", "mariadb":
+		createTableQuery = `
+			CREATE TABLE IF NOT EXISTS activity_events (
+				id INT AUTO_INCREMENT PRIMARY KEY,
+				event VARCHAR(50) NOT NULL,
+				document_id INT,
+				user_id INT,
+				title TEXT,
+				data JSON,
+				created TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				INDEX idx_document (document_id),
+				INDEX idx_created (created)
+			);
+		`
+	case "sqlite", "sqlite3":
+		createTableQuery = `
+			CREATE TABLE IF NOT EXISTS activity_events (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				event TEXT NOT NULL,
+				document_id INTEGER,
+				user_id INTEGER,
+				title TEXT,
+				data TEXT,
+				created TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+			);
+			CREATE INDEX IF NOT EXISTS idx_activity_events_document ON activity_events(document_id);
+			CREATE INDEX IF NOT EXISTS idx_activity_events_created ON activity_events(created);
+		`
+	default:
+		return fmt.Errorf("unsupported database engine: %s", s.config.DBEngine)
+	}
+
+	log.Printf("[Database] Executing CREATE TABLE statement for activity_events")
+	if _, err := s.db.Exec(createTableQuery); err != nil {
+		log.Printf("[Database] Error creating activity_events table: %v", err)
+		return fmt.Errorf("failed to create activity_events table: %w", err)
+	}
+
+	log.Printf("[Database] Successfully created/verified activity_events table")
+	return nil
+}
+
+// initCorrespondentProfilesTable creates the correspondent_profiles table if it doesn't exist
+func (s *Service) initCorrespondentProfilesTable() error {
+	log.Printf("[Database] Initializing correspondent_profiles table for engine: %s", s.config.DBEngine)
+	var createTableQuery string
+
+	switch s.config.DBEngine {
+	case "postgresql", "postgres":
+		createTableQuery = `
+			CREATE TABLE IF NOT EXISTS correspondent_profiles (
+				correspondent_id INTEGER PRIMARY KEY,
+				address TEXT,
+				email VARCHAR(254),
+				website TEXT,
+				vat_number VARCHAR(50),
+				logo_url TEXT,
+				extra JSONB,
+				created TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				modified TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				modified_by INTEGER
+			);
+		`
+	case "mysql", "mariadb":
+		createTableQuery = `
+			CREATE TABLE IF NOT EXISTS correspondent_profiles (
+				correspondent_id INT PRIMARY KEY,
+				address TEXT,
+				email VARCHAR(254),
+				website TEXT,
+				vat_number VARCHAR(50),
+				logo_url TEXT,
+				extra JSON,
+				created TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				modified TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+				modified_by INT
+			);
+		`
+	case "sqlite", "sqlite3":
+		createTableQuery = `
+			CREATE TABLE IF NOT EXISTS correspondent_profiles (
+				correspondent_id INTEGER PRIMARY KEY,
+				address TEXT,
+				email TEXT,
+				website TEXT,
+				vat_number TEXT,
+				logo_url TEXT,
+				extra TEXT,
+				created TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				modified TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				modified_by INTEGER
+			);
+		`
+	default:
+		return fmt.Errorf("unsupported database engine: %s", s.config.DBEngine)
+	}
+
+	log.Printf("[Database] Executing CREATE TABLE statement for correspondent_profiles")
+	if _, err := s.db.Exec(createTableQuery); err != nil {
+		log.Printf("[Database] Error creating correspondent_profiles table: %v", err)
+		return fmt.Errorf("failed to create correspondent_profiles table: %w", err)
+	}
+
+	log.Printf("[Database] Successfully created/verified correspondent_profiles table")
+	return nil
+}
+
+// initBulkJobsTable creates the bulk_jobs table if it doesn't exist. Jobs a restart
+// interrupted are marked failed.
+func (s *Service) initBulkJobsTable() error {
+	log.Printf("[Database] Initializing bulk_jobs table for engine: %s", s.config.DBEngine)
+	var createTableQuery string
+
+	switch s.config.DBEngine {
+	case "postgresql", "postgres":
+		createTableQuery = `
+			CREATE TABLE IF NOT EXISTS bulk_jobs (
+				id SERIAL PRIMARY KEY,
+				user_id INTEGER,
+				operation JSONB NOT NULL,
+				status VARCHAR(20) NOT NULL,
+				total INTEGER NOT NULL DEFAULT 0,
+				processed INTEGER NOT NULL DEFAULT 0,
+				failed INTEGER NOT NULL DEFAULT 0,
+				error TEXT,
+				created TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				modified TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				finished TIMESTAMP
+			);
+			CREATE INDEX IF NOT EXISTS idx_bulk_jobs_user ON bulk_jobs(user_id);
+		`
+	case "mysql", "mariadb":
+		createTableQuery = `
+			CREATE TABLE IF NOT EXISTS bulk_jobs (
+				id INT AUTO_INCREMENT PRIMARY KEY,
+				user_id INT,
+				operation JSON NOT NULL,
+				status VARCHAR(20) NOT NULL,
+				total INT NOT NULL DEFAULT 0,
+				processed INT NOT NULL DEFAULT 0,
+				failed INT NOT NULL DEFAULT 0,
+				error TEXT,
+				created TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				modified TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				finished DATETIME NULL,
+				INDEX idx_user (user_id)
+			);
+		`
+	case "sqlite", "sqlite3":
+		createTableQuery = `
+			CREATE TABLE IF NOT EXISTS bulk_jobs (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				user_id INTEGER,
+				operation TEXT NOT NULL,
+				status TEXT NOT NULL,
+				total INTEGER NOT NULL DEFAULT 0,
+				processed INTEGER NOT NULL DEFAULT 0,
+				failed INTEGER NOT NULL DEFAULT 0,
+				error TEXT,
+				created TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				modified TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+				finished TIMESTAMP
+			);
+			CREATE INDEX IF NOT EXISTS idx_bulk_jobs_user ON bulk_jobs(user_id);
+		`
+	default:
+		return fmt.Errorf("unsupported database engine: %s", s.config.DBEngine)
+	}
+
+	log.Printf("[Database] Executing CREATE TABLE statement for bulk_jobs")
+	if _, err := s.db.Exec(createTableQuery); err != nil {
+		log.Printf("[Database] Error creating bulk_jobs table: %v", err)
+		return fmt.Errorf("failed to create bulk_jobs table: %w", err)
+	}
+
+	_, err := s.db.Exec(s.rebind("UPDATE bulk_jobs SET status = ?, error = ?, finished = CURRENT_TIMESTAMP WHERE status = ?"),
+		BulkJobFailed, "interrupted by a restart", BulkJobRunning)
+	if err != nil {
+		return fmt.Errorf("failed to fail interrupted bulk jobs: %w", err)
+	}
+
+	log.Printf("[Database] Successfully created/verified bulk_jobs table")
+	return nil
+}
+
+// initFacetSummaryTables creates the tables of the materialized facet summaries: the
+// individual values of every document, the value counts derived from them, the state of
+// each summary and, on PostgreSQL, the log of changed documents filled by triggers
+func (s *Service) initFacetSummaryTables() error {
+	log.Printf("[Database] Initializing facet summary tables for engine: %s", s.config.DBEngine)
+	var createTableQuery string
+
+	switch s.config.DBEngine {
+	case "postgresql", "postgres":
+		createTableQuery = `
+			CREATE TABLE IF NOT EXISTS facet_summary_fields (
+				field_id INTEGER PRIMARY KEY,
+				data_type VARCHAR(50) NOT NULL,
+				total_documents INTEGER NOT NULL DEFAULT 0,
+				valued_documents INTEGER NOT NULL DEFAULT 0,
+				watermark VARCHAR(64),
+				data_as_of TIMESTAMP NOT NULL
+			);
+			CREATE TABLE IF NOT EXISTS facet_document_values (
+				field_id INTEGER NOT NULL,
+				document_id INTEGER NOT NULL,
+				value TEXT NOT NULL
+			);
+			CREATE INDEX IF NOT EXISTS idx_facet_document_values_document ON facet_document_values(field_id, document_id);
+			CREATE TABLE IF NOT EXISTS facet_value_counts (
+				field_id INTEGER NOT NULL,
+				value_hash CHAR(64) NOT NULL,
+				value TEXT NOT NULL,
+				document_count INTEGER NOT NULL DEFAULT 0,
+				PRIMARY KEY (field_id, value_hash)
+			);
+			CREATE TABLE IF NOT EXISTS facet_summary_changes (
+				id BIGSERIAL PRIMARY KEY,
+				document_id INTEGER NOT NULL,
+				changed_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+			);
+		`
+	case "mysql", "mariadb":
+		createTableQuery = `
+			CREATE TABLE IF NOT EXISTS facet_summary_fields (
+				field_id INT PRIMARY KEY,
+				data_type VARCHAR(50) NOT NULL,
+				total_documents INT NOT NULL DEFAULT 0,
+				valued_documents INT NOT NULL DEFAULT 0,
+				watermark VARCHAR(64),
+				data_as_of DATETIME NOT NULL
+			);
+			CREATE TABLE IF NOT EXISTS facet_document_values (
+				field_id INT NOT NULL,
+				document_id INT NOT NULL,
+				value TEXT NOT NULL,
+				INDEX idx_field_document (field_id, document_id)
+			);
+			CREATE TABLE IF NOT EXISTS facet_value_counts (
+				field_id INT NOT NULL,
+				value_hash CHAR(64) NOT NULL,
+				value TEXT NOT NULL,
+				document_count INT NOT NULL DEFAULT 0,
+				PRIMARY KEY (field_id, value_hash)
+			);
+		`
+	case "sqlite", "sqlite3":
+		createTableQuery = `
+			CREATE TABLE IF NOT EXISTS facet_summary_fields (
+				field_id INTEGER PRIMARY KEY,
+				data_type TEXT NOT NULL,
+				total_documents INTEGER NOT NULL DEFAULT 0,
+				valued_documents INTEGER NOT NULL DEFAULT 0,
+				watermark TEXT,
+				data_as_of TIMESTAMP NOT NULL
+			);
+			CREATE TABLE IF NOT EXISTS facet_document_values (
+				field_id INTEGER NOT NULL,
+				document_id INTEGER NOT NULL,
+				value TEXT NOT NULL
+			);
+			CREATE INDEX IF NOT EXISTS idx_facet_document_values_document ON facet_document_values(field_id, document_id);
+			CREATE TABLE IF NOT EXISTS facet_value_counts (
+				field_id INTEGER NOT NULL,
+				value_hash TEXT NOT NULL,
+				value TEXT NOT NULL,
+				document_count INTEGER NOT NULL DEFAULT 0,
+				PRIMARY KEY (field_id, value_hash)
+			);
+		`
+	default:
+		return fmt.Errorf("unsupported database engine: %s", s.config.DBEngine)
+	}
+
+	log.Printf("[Database] Executing CREATE TABLE statements for facet summaries")
+	if _, err := s.db.Exec(createTableQuery); err != nil {
+		log.Printf("[Database] Error creating facet summary tables: %v", err)
+		return fmt.Errorf("failed to create facet summary tables: %w", err)
+	}
+
+	log.Printf("[Database] Successfully created/verified facet summary tables")
+	return nil
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"context"
+)
+
+// shareQuery runs fn once for all concurrent callers using the same key and hands each
+// of them the result. The shared execution is detached from the first caller's
+// cancellation (a closed tab must not fail the others) but still bounded by the query
+// timeout; every caller stops waiting as soon as its own context is done.
+func (s *Service) shareQuery(ctx context.Context, key string, fn func(ctx context.Context) (interface{}, error)) (interface{}, error) {
+	ch := s.inflight.DoChan(key, func() (interface{}, error) {
+		sharedCtx := context.WithoutCancel(ctx)
+		if s.config.QueryTimeout > 0 {
+			var cancel context.CancelFunc
+			sharedCtx, cancel = context.WithTimeout(sharedCtx, s.config.QueryTimeout)
+			defer cancel()
+		}
+		return fn(sharedCtx)
+	})
+
+	select {
+	case result := <-ch:
+		return result.Val, result.Err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
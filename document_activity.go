@@ -0,0 +1,318 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// defaultRecentDocuments is the number of recently viewed documents listed without a limit
+const defaultRecentDocuments = 20
+
+// DocumentActivity is a document a user viewed or pinned
+type DocumentActivity struct {
+	DocumentID int     `json:"document_id"`
+	Title      string  `json:"title"`
+	LastViewed *string `json:"last_viewed,omitempty"`
+	ViewCount  int     `json:"view_count"`
+	Pinned     bool    `json:"pinned"`
+	PinnedAt   *string `json:"pinned_at,omitempty"`
+}
+
+// DocumentActivityResponse lists recently viewed or pinned documents
+type DocumentActivityResponse struct {
+	Count   int                `json:"count"`
+	Results []DocumentActivity `json:"results"`
+}
+
+// queryDocumentActivity retrieves the activity of a user selected by condition, for the
+// documents outside the trash the viewer can see
+func (s *Service) queryDocumentActivity(ctx context.Context, userID int, viewerID int, order string, limit int, condition string, args ...interface{}) ([]DocumentActivity, error) {
+	documentCondition := trashedCondition(false)
+	visibility, err := s.documentVisibilityCondition(ctx, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	if visibility != "" {
+		documentCondition = fmt.Sprintf("(%s AND %s)", documentCondition, visibility)
+	}
+	limitClause := ""
+	if limit > 0 {
+		limitClause = fmt.Sprintf("LIMIT %d", limit)
+	}
+
+	rows, err := s.db.QueryContext(ctx, s.rebind(fmt.Sprintf(`
+		SELECT a.document_id, d.title, a.last_viewed, a.view_count, a.pinned, a.pinned_at
+		FROM user_document_activity a
+		INNER JOIN documents_document d ON d.id = a.document_id
+		WHERE a.user_id = ? AND %s AND %s
+		ORDER BY %s, a.document_id DESC
+		%s
+	`, condition, documentCondition, order, limitClause)), append([]interface{}{userID}, args...)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query document activity: %w", err)
+	}
+	defer rows.Close()
+
+	activity := []DocumentActivity{}
+	for rows.Next() {
+		var entry DocumentActivity
+		var title sql.NullString
+		var pinned sql.NullBool
+		var lastViewed, pinnedAt dbTimestamp
+		if err := rows.Scan(&entry.DocumentID, &title, &lastViewed, &entry.ViewCount, &pinned, &pinnedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan document activity: %w", err)
+		}
+		entry.Title = title.String
+		entry.Pinned = pinned.Bool
+		entry.LastViewed = s.formatTimestamp(lastViewed)
+		entry.PinnedAt = s.formatTimestamp(pinnedAt)
+		activity = append(activity, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read document activity: %w", err)
+	}
+	return activity, nil
+}
+
+// ListRecentDocuments retrieves the documents a user viewed last, most recent first
+func (s *Service) ListRecentDocuments(ctx context.Context, userID int, viewerID int, limit int) ([]DocumentActivity, error) {
+	return s.queryDocumentActivity(ctx, userID, viewerID, "a.last_viewed DESC", limit, "a.last_viewed IS NOT NULL")
+}
+
+// ListPinnedDocuments retrieves the documents a user pinned, last pinned first
+func (s *Service) ListPinnedDocuments(ctx context.Context, userID int, viewerID int) ([]DocumentActivity, error) {
+	return s.queryDocumentActivity(ctx, userID, viewerID, "a.pinned_at DESC", 0, "a.pinned = ?", true)
+}
+
+// updateDocumentActivity applies set to the activity row of a user and document, or creates
+// the row with the given column values if there is none
+func (s *Service) updateDocumentActivity(ctx context.Context, userID int, documentID int, set string, setArgs []interface{}, columns []string, values []interface{}) error {
+	update := s.rebind("UPDATE user_document_activity SET " + set + " WHERE user_id = ? AND document_id = ?")
+	updateArgs := append(append([]interface{}{}, setArgs...), userID, documentID)
+	result, err := s.db.ExecContext(ctx, update, updateArgs...)
+	if err != nil {
+		return fmt.Errorf("failed to update document activity: %w", err)
+	}
+	if updated, _ := result.RowsAffected(); updated > 0 {
+		return nil
+	}
+
+	placeholders, _ := inPlaceholders(make([]int, len(values)))
+	insert := s.rebind(fmt.Sprintf("INSERT INTO user_document_activity (user_id, document_id, %s) VALUES (?, ?, %s)", strings.Join(columns, ", "), placeholders))
+	if _, err := s.db.ExecContext(ctx, insert, append([]interface{}{userID, documentID}, values...)...); err != nil {
+		// Another request may have created the row meanwhile
+		if _, retryErr := s.db.ExecContext(ctx, update, updateArgs...); retryErr != nil {
+			return fmt.Errorf("failed to create document activity: %w", err)
+		}
+	}
+	return nil
+}
+
+// RecordDocumentView records that a user opened a document and drops the oldest views of the
+// user beyond the configured history length; pinned documents are kept
+func (s *Service) RecordDocumentView(ctx context.Context, userID int, documentID int, viewerID int) error {
+	if err := s.checkDocumentsVisible(ctx, []int{documentID}, false, viewerID); err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	if err := s.updateDocumentActivity(ctx, userID, documentID, "last_viewed = ?, view_count = view_count + 1", []interface{}{now},
+		[]string{"last_viewed", "view_count"}, []interface{}{now, 1}); err != nil {
+		return err
+	}
+
+	if limit := s.config.RecentDocumentsLimit; limit > 0 {
+		var oldest dbTimestamp
+		err := s.db.QueryRowContext(ctx, s.rebind(fmt.Sprintf(`
+			SELECT last_viewed FROM user_document_activity
+			WHERE user_id = ? AND last_viewed IS NOT NULL
+			ORDER BY last_viewed DESC
+			LIMIT 1 OFFSET %d
+		`, limit-1)), userID).Scan(&oldest)
+		if err != nil && err != sql.ErrNoRows {
+			return fmt.Errorf("failed to query view history: %w", err)
+		}
+		if err == nil && oldest.Valid {
+			query := s.rebind("DELETE FROM user_document_activity WHERE user_id = ? AND pinned = ? AND last_viewed < ?")
+			if _, err := s.db.ExecContext(ctx, query, userID, false, oldest.Time); err != nil {
+				return fmt.Errorf("failed to prune view history: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// PinDocument pins a document for a user; pinning it again keeps its original position
+func (s *Service) PinDocument(ctx context.Context, userID int, documentID int, viewerID int) error {
+	log.Printf("[Activity] PinDocument - Document: %d, UserID: %d", documentID, userID)
+	if err := s.checkDocumentsVisible(ctx, []int{documentID}, false, viewerID); err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	return s.updateDocumentActivity(ctx, userID, documentID, "pinned_at = CASE WHEN pinned = ? THEN pinned_at ELSE ? END, pinned = ?", []interface{}{true, now, true},
+		[]string{"pinned_at", "pinned"}, []interface{}{now, true})
+}
+
+// UnpinDocument unpins a document for a user. Documents that were never viewed leave no
+// activity behind.
+func (s *Service) UnpinDocument(ctx context.Context, userID int, documentID int) error {
+	log.Printf("[Activity] UnpinDocument - Document: %d, UserID: %d", documentID, userID)
+	result, err := s.db.ExecContext(ctx, s.rebind("UPDATE user_document_activity SET pinned = ?, pinned_at = NULL WHERE user_id = ? AND document_id = ? AND pinned = ?"),
+		false, userID, documentID, true)
+	if err != nil {
+		return fmt.Errorf("failed to unpin document: %w", err)
+	}
+	if updated, _ := result.RowsAffected(); updated == 0 {
+		return fmt.Errorf("pinned document %d not found", documentID)
+	}
+	query := s.rebind("DELETE FROM user_document_activity WHERE user_id = ? AND document_id = ? AND last_viewed IS NULL")
+	if _, err := s.db.ExecContext(ctx, query, userID, documentID); err != nil {
+		return fmt.Errorf("failed to remove document activity: %w", err)
+	}
+	return nil
+}
+
+// HTTP Handlers for recently viewed and pinned documents
+
+func (s *Service) handleRecordDocumentView(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	idStr := mux.Vars(r)["documentId"]
+	log.Printf("[Activity] POST /api/activity/viewed/%s/ - Request from %s", idStr, r.RemoteAddr)
+
+	viewerID, err := viewerFromRequest(r)
+	if err != nil {
+		respondError(w, viewerErrorStatus(err), err.Error())
+		return
+	}
+
+	documentID, err := strconv.Atoi(idStr)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid document ID")
+		return
+	}
+	userID, err := getUserIDFromRequest(r)
+	if err != nil {
+		respondError(w, viewerErrorStatus(err), err.Error())
+		return
+	}
+
+	if err := s.RecordDocumentView(ctx, *userID, documentID, viewerID); err != nil {
+		log.Printf("[Activity] Error recording view of document %d: %v", documentID, err)
+		respondError(w, documentLinkErrorStatus(err), err.Error())
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Service) handleListRecentDocuments(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	log.Printf("[Activity] GET /api/activity/recent/ - Request from %s", r.RemoteAddr)
+
+	viewerID, err := viewerFromRequest(r)
+	if err != nil {
+		respondError(w, viewerErrorStatus(err), err.Error())
+		return
+	}
+
+	limit := defaultRecentDocuments
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 {
+			respondError(w, http.StatusBadRequest, "Invalid limit")
+			return
+		}
+		limit = parsed
+	}
+	userID, err := getUserIDFromRequest(r)
+	if err != nil {
+		respondError(w, viewerErrorStatus(err), err.Error())
+		return
+	}
+
+	documents, err := s.ListRecentDocuments(ctx, *userID, viewerID, limit)
+	if err != nil {
+		log.Printf("[Activity] Error listing recent documents: %v", err)
+		respondError(w, queryErrorStatus(err), err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, DocumentActivityResponse{Count: len(documents), Results: documents})
+}
+
+func (s *Service) handleListPinnedDocuments(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	log.Printf("[Activity] GET /api/activity/pinned/ - Request from %s", r.RemoteAddr)
+
+	viewerID, err := viewerFromRequest(r)
+	if err != nil {
+		respondError(w, viewerErrorStatus(err), err.Error())
+		return
+	}
+
+	userID, err := getUserIDFromRequest(r)
+	if err != nil {
+		respondError(w, viewerErrorStatus(err), err.Error())
+		return
+	}
+
+	documents, err := s.ListPinnedDocuments(ctx, *userID, viewerID)
+	if err != nil {
+		log.Printf("[Activity] Error listing pinned documents: %v", err)
+		respondError(w, queryErrorStatus(err), err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, DocumentActivityResponse{Count: len(documents), Results: documents})
+}
+
+func (s *Service) handlePinDocument(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	idStr := mux.Vars(r)["documentId"]
+	log.Printf("[Activity] %s /api/activity/pinned/%s/ - Request from %s", r.Method, idStr, r.RemoteAddr)
+
+	viewerID, err := viewerFromRequest(r)
+	if err != nil {
+		respondError(w, viewerErrorStatus(err), err.Error())
+		return
+	}
+
+	documentID, err := strconv.Atoi(idStr)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid document ID")
+		return
+	}
+	userID, err := getUserIDFromRequest(r)
+	if err != nil {
+		respondError(w, viewerErrorStatus(err), err.Error())
+		return
+	}
+
+	if r.Method == http.MethodDelete {
+		err = s.UnpinDocument(ctx, *userID, documentID)
+	} else {
+		err = s.PinDocument(ctx, *userID, documentID, viewerID)
+	}
+	if err != nil {
+		log.Printf("[Activity] Error changing pin of document %d: %v", documentID, err)
+		respondError(w, documentLinkErrorStatus(err), err.Error())
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,917 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Filter rule type constants, numbered like Paperless' FILTER_* rule types so saved views
+// and filter rules from Paperless can be used as they are
+const (
+	FILTER_TITLE                       = 0
+	FILTER_CONTENT                     = 1
+	FILTER_ASN                         = 2
+	FILTER_CORRESPONDENT               = 3
+	FILTER_DOCUMENT_TYPE               = 4
+	FILTER_IS_IN_INBOX                 = 5
+	FILTER_HAS_TAGS_ALL                = 6
+	FILTER_HAS_ANY_TAG                 = 7
+	FILTER_CREATED_BEFORE              = 8
+	FILTER_CREATED_AFTER               = 9
+	FILTER_CREATED_YEAR                = 10
+	FILTER_CREATED_MONTH               = 11
+	FILTER_CREATED_DAY                 = 12
+	FILTER_ADDED_BEFORE                = 13
+	FILTER_ADDED_AFTER                 = 14
+	FILTER_MODIFIED_BEFORE             = 15
+	FILTER_MODIFIED_AFTER              = 16
+	FILTER_DOES_NOT_HAVE_TAG           = 17
+	FILTER_ASN_ISNULL                  = 18
+	FILTER_TITLE_CONTENT               = 19
+	FILTER_FULLTEXT_QUERY              = 20
+	FILTER_FULLTEXT_MORELIKE           = 21
+	FILTER_HAS_TAGS_ANY                = 22
+	FILTER_ASN_GT                      = 23
+	FILTER_ASN_LT                      = 24
+	FILTER_STORAGE_PATH                = 25
+	FILTER_HAS_CORRESPONDENT_ANY       = 26
+	FILTER_DOES_NOT_HAVE_CORRESPONDENT = 27
+	FILTER_HAS_DOCUMENT_TYPE_ANY       = 28
+	FILTER_DOES_NOT_HAVE_DOCUMENT_TYPE = 29
+	FILTER_HAS_STORAGE_PATH_ANY        = 30
+	FILTER_DOES_NOT_HAVE_STORAGE_PATH  = 31
+	FILTER_OWNER                       = 32
+	FILTER_OWNER_ANY                   = 33
+	FILTER_OWNER_ISNULL                = 34
+	FILTER_OWNER_DOES_NOT_INCLUDE      = 35
+	FILTER_CUSTOM_FIELDS_TEXT          = 36
+	FILTER_SHARED_BY_USER              = 37
+	FILTER_HAS_CUSTOM_FIELDS_ALL       = 38
+	FILTER_HAS_CUSTOM_FIELDS_ANY       = 39
+	FILTER_DOES_NOT_HAVE_CUSTOM_FIELDS = 40
+	FILTER_HAS_ANY_CUSTOM_FIELDS       = 41
+	FILTER_CUSTOM_FIELDS_QUERY         = 42
+	FILTER_CREATED_TO                  = 43
+	FILTER_CREATED_FROM                = 44
+	FILTER_ADDED_TO                    = 45
+	FILTER_ADDED_FROM                  = 46
+	FILTER_MIME_TYPE                   = 47
+)
+
+// errInvalidFilterRules is wrapped by the errors of filter rules that cannot be applied.
+// Such rules are refused rather than skipped, which would match more documents than asked.
+var errInvalidFilterRules = errors.New("invalid filter_rules")
+
+// inboxTaggedCondition matches documents (alias d) in the inbox, i.e. carrying an inbox tag
+const inboxTaggedCondition = "EXISTS (SELECT 1 FROM documents_document_tags dt INNER JOIN documents_tag t ON t.id = dt.tag_id WHERE dt.document_id = d.id AND t.is_inbox_tag)"
+
+// filterRuleDimensions maps rule types to the rule type of the dimension they filter, so
+// excluding a dimension from its own counts drops all of its rules
+var filterRuleDimensions = map[int]int{
+	FILTER_HAS_TAGS_ALL:                FILTER_HAS_TAGS_ANY,
+	FILTER_HAS_ANY_TAG:                 FILTER_HAS_TAGS_ANY,
+	FILTER_DOES_NOT_HAVE_TAG:           FILTER_HAS_TAGS_ANY,
+	FILTER_HAS_CORRESPONDENT_ANY:       FILTER_CORRESPONDENT,
+	FILTER_DOES_NOT_HAVE_CORRESPONDENT: FILTER_CORRESPONDENT,
+	FILTER_HAS_DOCUMENT_TYPE_ANY:       FILTER_DOCUMENT_TYPE,
+	FILTER_DOES_NOT_HAVE_DOCUMENT_TYPE: FILTER_DOCUMENT_TYPE,
+	FILTER_HAS_STORAGE_PATH_ANY:        FILTER_STORAGE_PATH,
+	FILTER_DOES_NOT_HAVE_STORAGE_PATH:  FILTER_STORAGE_PATH,
+	FILTER_OWNER_ANY:                   FILTER_OWNER,
+	FILTER_OWNER_ISNULL:                FILTER_OWNER,
+	FILTER_OWNER_DOES_NOT_INCLUDE:      FILTER_OWNER,
+	FILTER_ASN_ISNULL:                  FILTER_ASN,
+	FILTER_ASN_GT:                      FILTER_ASN,
+	FILTER_ASN_LT:                      FILTER_ASN,
+	FILTER_CREATED_BEFORE:              FILTER_CREATED_AFTER,
+	FILTER_CREATED_YEAR:                FILTER_CREATED_AFTER,
+	FILTER_CREATED_MONTH:               FILTER_CREATED_AFTER,
+	FILTER_CREATED_DAY:                 FILTER_CREATED_AFTER,
+	FILTER_CREATED_TO:                  FILTER_CREATED_AFTER,
+	FILTER_CREATED_FROM:                FILTER_CREATED_AFTER,
+	FILTER_ADDED_BEFORE:                FILTER_ADDED_AFTER,
+	FILTER_ADDED_TO:                    FILTER_ADDED_AFTER,
+	FILTER_ADDED_FROM:                  FILTER_ADDED_AFTER,
+}
+
+// builtinFilterRuleTypes maps builtin filter types to the rule type of their dimension
+var builtinFilterRuleTypes = map[string]int{
+	"correspondent": FILTER_CORRESPONDENT,
+	"document_type": FILTER_DOCUMENT_TYPE,
+	"tag":           FILTER_HAS_TAGS_ANY,
+	"storage_path":  FILTER_STORAGE_PATH,
+	"owner":         FILTER_OWNER,
+	"asn":           FILTER_ASN,
+	"created_year":  FILTER_CREATED_AFTER,
+	"created_month": FILTER_CREATED_AFTER,
+	"added_month":   FILTER_ADDED_AFTER,
+}
+
+// filterRuleNullColumns are the columns of rules whose null value means "not assigned"
+var filterRuleNullColumns = map[int]string{
+	FILTER_CORRESPONDENT: "d.correspondent_id",
+	FILTER_DOCUMENT_TYPE: "d.document_type_id",
+	FILTER_STORAGE_PATH:  "d.storage_path_id",
+	FILTER_OWNER:         "d.owner_id",
+}
+
+// filterRuleAnyColumns are the columns of "any of" rules; Paperless sends one rule per
+// selected value and expects them to be OR-ed
+var filterRuleAnyColumns = map[int]string{
+	FILTER_HAS_CORRESPONDENT_ANY: "d.correspondent_id",
+	FILTER_HAS_DOCUMENT_TYPE_ANY: "d.document_type_id",
+	FILTER_HAS_STORAGE_PATH_ANY:  "d.storage_path_id",
+	FILTER_OWNER_ANY:             "d.owner_id",
+}
+
+// anyFilterRuleOrder fixes the order in which grouped "any of" rules are emitted
+var anyFilterRuleOrder = []int{
+	FILTER_HAS_TAGS_ANY,
+	FILTER_HAS_CORRESPONDENT_ANY,
+	FILTER_HAS_DOCUMENT_TYPE_ANY,
+	FILTER_HAS_STORAGE_PATH_ANY,
+	FILTER_OWNER_ANY,
+	FILTER_HAS_CUSTOM_FIELDS_ANY,
+}
+
+// buildDocumentFilterQuery builds a WHERE clause to filter documents based on filter rules.
+// It is shared by custom field value counts (which exclude their own field) and builtin
+// filter values (which exclude their own dimension).
+// Returns the WHERE clause and arguments, excluding filters for the specified fieldID or ruleType
+// excludeFieldID: exclude custom field filters for this field ID (0 = don't exclude)
+// excludeRuleType: exclude built-in filter rules of this dimension (0 = don't exclude),
+// see filterRuleDimensions and builtinFilterRuleTypes
+func (s *Service) buildDocumentFilterQuery(ctx context.Context, filterRulesJSON string, excludeFieldID int, excludeRuleType int) (string, []interface{}, error) {
+	if filterRulesJSON == "" {
+		return "", nil, nil
+	}
+
+	// Parse filter rules JSON
+	var filterRules []map[string]interface{}
+	if err := json.Unmarshal([]byte(filterRulesJSON), &filterRules); err != nil {
+		return "", nil, fmt.Errorf("%w: %v", errInvalidFilterRules, err)
+	}
+
+	if len(filterRules) == 0 {
+		return "", nil, nil
+	}
+
+	var conditions []string
+	var args []interface{}
+	argIndex := 1
+	usePostgres := s.config.DBEngine == "postgresql" || s.config.DBEngine == "postgres"
+
+	// nextArg registers a query argument and returns its placeholder
+	nextArg := func(value interface{}) string {
+		args = append(args, value)
+		argIndex++
+		if usePostgres {
+			return fmt.Sprintf("$%d", argIndex-1)
+		}
+		return "?"
+	}
+
+	// Values of "any of" rules, combined after all rules have been read
+	anyValues := make(map[int][]string)
+	anyNull := make(map[int]bool)
+
+	for i, rule := range filterRules {
+		// invalid returns the error of a rule whose value cannot be applied
+		invalid := func(format string, args ...interface{}) error {
+			return fmt.Errorf("%w: rule %d: %s", errInvalidFilterRules, i, fmt.Sprintf(format, args...))
+		}
+
+		ruleType, ok := rule["rule_type"].(float64)
+		if !ok {
+			return "", nil, invalid("rule_type must be a number")
+		}
+		ruleTypeInt := int(ruleType)
+
+		// Skip excluded rule type (and the other rules of the same dimension)
+		if excludeRuleType > 0 && (ruleTypeInt == excludeRuleType || filterRuleDimensions[ruleTypeInt] == excludeRuleType) {
+			continue
+		}
+
+		rawValue, ok := rule["value"]
+		if !ok {
+			return "", nil, invalid("value is required")
+		}
+		var value string
+		switch v := rawValue.(type) {
+		case string:
+			value = v
+		case float64:
+			value = strconv.FormatFloat(v, 'f', -1, 64)
+		case bool:
+			value = strconv.FormatBool(v)
+		case nil:
+			// A null value selects documents without an assignment ("Not assigned")
+			if _, isAny := filterRuleAnyColumns[ruleTypeInt]; isAny {
+				anyNull[ruleTypeInt] = true
+			} else if column, ok := filterRuleNullColumns[ruleTypeInt]; ok {
+				conditions = append(conditions, column+" IS NULL")
+			} else {
+				return "", nil, invalid("value must not be null for rule type %d", ruleTypeInt)
+			}
+			continue
+		default:
+			return "", nil, invalid("value must be a string, number, boolean or null")
+		}
+
+		switch ruleTypeInt {
+		case FILTER_CORRESPONDENT:
+			// Filter by correspondent ID
+			if usePostgres {
+				conditions = append(conditions, fmt.Sprintf("d.correspondent_id = $%d", argIndex))
+			} else {
+				conditions = append(conditions, "d.correspondent_id = ?")
+			}
+			args = append(args, value)
+			argIndex++
+
+		case FILTER_DOCUMENT_TYPE:
+			// Filter by document type ID (column is document_type_id, not category_id)
+			if usePostgres {
+				conditions = append(conditions, fmt.Sprintf("d.document_type_id = $%d", argIndex))
+			} else {
+				conditions = append(conditions, "d.document_type_id = ?")
+			}
+			args = append(args, value)
+			argIndex++
+
+		case FILTER_HAS_TAGS_ALL:
+			// Documents carrying the tag; one rule per tag, so all of them are required
+			if usePostgres {
+				conditions = append(conditions, fmt.Sprintf("EXISTS (SELECT 1 FROM documents_document_tags dt WHERE dt.document_id = d.id AND dt.tag_id = $%d)", argIndex))
+			} else {
+				conditions = append(conditions, "EXISTS (SELECT 1 FROM documents_document_tags dt WHERE dt.document_id = d.id AND dt.tag_id = ?)")
+			}
+			args = append(args, value)
+			argIndex++
+
+		case FILTER_STORAGE_PATH:
+			// Filter by storage path ID
+			if usePostgres {
+				conditions = append(conditions, fmt.Sprintf("d.storage_path_id = $%d", argIndex))
+			} else {
+				conditions = append(conditions, "d.storage_path_id = ?")
+			}
+			args = append(args, value)
+			argIndex++
+
+		case FILTER_OWNER:
+			// Filter by owner ID (owner is a ForeignKey to User)
+			if usePostgres {
+				conditions = append(conditions, fmt.Sprintf("d.owner_id = $%d", argIndex))
+			} else {
+				conditions = append(conditions, "d.owner_id = ?")
+			}
+			args = append(args, value)
+			argIndex++
+
+		case FILTER_ASN:
+			// Filter by ASN
+			if usePostgres {
+				conditions = append(conditions, fmt.Sprintf("d.archive_serial_number = $%d", argIndex))
+			} else {
+				conditions = append(conditions, "d.archive_serial_number = ?")
+			}
+			args = append(args, value)
+			argIndex++
+
+		case FILTER_IS_IN_INBOX:
+			// Documents are in the inbox while they carry an inbox tag
+			inInbox, ok := parseRuleBool(value)
+			if !ok {
+				return "", nil, invalid("value must be a boolean for rule type %d", ruleTypeInt)
+			}
+			inboxTagged := inboxTaggedCondition
+			if !inInbox {
+				inboxTagged = "NOT " + inboxTagged
+			}
+			conditions = append(conditions, inboxTagged)
+
+		case FILTER_HAS_ANY_TAG:
+			hasTags, ok := parseRuleBool(value)
+			if !ok {
+				return "", nil, invalid("value must be a boolean for rule type %d", ruleTypeInt)
+			}
+			tagged := "EXISTS (SELECT 1 FROM documents_document_tags dt WHERE dt.document_id = d.id)"
+			if !hasTags {
+				tagged = "NOT " + tagged
+			}
+			conditions = append(conditions, tagged)
+
+		case FILTER_DOES_NOT_HAVE_TAG:
+			// Exclude documents carrying the tag
+			if usePostgres {
+				conditions = append(conditions, fmt.Sprintf("NOT EXISTS (SELECT 1 FROM documents_document_tags dt WHERE dt.document_id = d.id AND dt.tag_id = $%d)", argIndex))
+			} else {
+				conditions = append(conditions, "NOT EXISTS (SELECT 1 FROM documents_document_tags dt WHERE dt.document_id = d.id AND dt.tag_id = ?)")
+			}
+			args = append(args, value)
+			argIndex++
+
+		case FILTER_DOES_NOT_HAVE_CORRESPONDENT, FILTER_DOES_NOT_HAVE_DOCUMENT_TYPE, FILTER_DOES_NOT_HAVE_STORAGE_PATH:
+			// Exclude documents assigned to the given object; unassigned documents match
+			column := map[int]string{
+				FILTER_DOES_NOT_HAVE_CORRESPONDENT: "d.correspondent_id",
+				FILTER_DOES_NOT_HAVE_DOCUMENT_TYPE: "d.document_type_id",
+				FILTER_DOES_NOT_HAVE_STORAGE_PATH:  "d.storage_path_id",
+			}[ruleTypeInt]
+			if usePostgres {
+				conditions = append(conditions, fmt.Sprintf("(%s IS NULL OR %s != $%d)", column, column, argIndex))
+			} else {
+				conditions = append(conditions, fmt.Sprintf("(%s IS NULL OR %s != ?)", column, column))
+			}
+			args = append(args, value)
+			argIndex++
+
+		case FILTER_TITLE, FILTER_CONTENT, FILTER_TITLE_CONTENT:
+			// Case-insensitive substring match on the title and/or content
+			pattern := "%" + escapeLikePattern(strings.ToLower(strings.TrimSpace(value))) + "%"
+			columns := map[int][]string{
+				FILTER_TITLE:         {"d.title"},
+				FILTER_CONTENT:       {"d.content"},
+				FILTER_TITLE_CONTENT: {"d.title", "d.content"},
+			}[ruleTypeInt]
+			var matches []string
+			for _, column := range columns {
+				if usePostgres {
+					matches = append(matches, fmt.Sprintf("%s ILIKE %s ESCAPE '!'", column, nextArg(pattern)))
+				} else {
+					matches = append(matches, fmt.Sprintf("LOWER(%s) LIKE %s ESCAPE '!'", column, nextArg(pattern)))
+				}
+			}
+			conditions = append(conditions, "("+strings.Join(matches, " OR ")+")")
+
+		case FILTER_MIME_TYPE:
+			conditions = append(conditions, fmt.Sprintf("d.mime_type = %s", nextArg(value)))
+
+		case FILTER_CREATED_YEAR, FILTER_CREATED_MONTH, FILTER_CREATED_DAY:
+			// Match a part of the created date, e.g. all documents created in March
+			number, err := strconv.Atoi(strings.TrimSpace(value))
+			if err != nil {
+				return "", nil, invalid("value must be an integer for rule type %d", ruleTypeInt)
+			}
+			part := map[int]string{FILTER_CREATED_YEAR: "year", FILTER_CREATED_MONTH: "month", FILTER_CREATED_DAY: "day"}[ruleTypeInt]
+			conditions = append(conditions, fmt.Sprintf("%s = %s", s.datePartExpression(part, "d.created"), nextArg(number)))
+
+		case FILTER_CREATED_BEFORE, FILTER_CREATED_AFTER, FILTER_ADDED_BEFORE, FILTER_ADDED_AFTER,
+			FILTER_MODIFIED_BEFORE, FILTER_MODIFIED_AFTER:
+			// Strict date comparisons like Paperless' __date__lt/__date__gt: before = an earlier
+			// day, after = a later day, whatever the time of day of the column
+			column := map[int]string{
+				FILTER_CREATED_BEFORE: "d.created", FILTER_CREATED_AFTER: "d.created",
+				FILTER_ADDED_BEFORE: "d.added", FILTER_ADDED_AFTER: "d.added",
+				FILTER_MODIFIED_BEFORE: "d.modified", FILTER_MODIFIED_AFTER: "d.modified",
+			}[ruleTypeInt]
+			if ruleTypeInt == FILTER_CREATED_BEFORE || ruleTypeInt == FILTER_ADDED_BEFORE || ruleTypeInt == FILTER_MODIFIED_BEFORE {
+				boundary, ok := shiftRuleDate(value, 0)
+				if !ok {
+					return "", nil, invalid("value must be a date (YYYY-MM-DD) for rule type %d", ruleTypeInt)
+				}
+				conditions = append(conditions, fmt.Sprintf("%s < %s", column, nextArg(boundary)))
+			} else {
+				boundary, ok := shiftRuleDate(value, 1)
+				if !ok {
+					return "", nil, invalid("value must be a date (YYYY-MM-DD) for rule type %d", ruleTypeInt)
+				}
+				conditions = append(conditions, fmt.Sprintf("%s >= %s", column, nextArg(boundary)))
+			}
+
+		case FILTER_CREATED_TO, FILTER_CREATED_FROM, FILTER_ADDED_TO, FILTER_ADDED_FROM:
+			// Inclusive date bounds like Paperless' __date__lte/__date__gte: to = up to the end
+			// of the day, from = from its start
+			column := "d.created"
+			if ruleTypeInt == FILTER_ADDED_TO || ruleTypeInt == FILTER_ADDED_FROM {
+				column = "d.added"
+			}
+			if ruleTypeInt == FILTER_CREATED_TO || ruleTypeInt == FILTER_ADDED_TO {
+				boundary, ok := shiftRuleDate(value, 1)
+				if !ok {
+					return "", nil, invalid("value must be a date (YYYY-MM-DD) for rule type %d", ruleTypeInt)
+				}
+				conditions = append(conditions, fmt.Sprintf("%s < %s", column, nextArg(boundary)))
+			} else {
+				boundary, ok := shiftRuleDate(value, 0)
+				if !ok {
+					return "", nil, invalid("value must be a date (YYYY-MM-DD) for rule type %d", ruleTypeInt)
+				}
+				conditions = append(conditions, fmt.Sprintf("%s >= %s", column, nextArg(boundary)))
+			}
+
+		case FILTER_ASN_ISNULL:
+			isNull, ok := parseRuleBool(value)
+			if !ok {
+				return "", nil, invalid("value must be a boolean for rule type %d", ruleTypeInt)
+			}
+			if isNull {
+				conditions = append(conditions, "d.archive_serial_number IS NULL")
+			} else {
+				conditions = append(conditions, "d.archive_serial_number IS NOT NULL")
+			}
+
+		case FILTER_ASN_GT, FILTER_ASN_LT:
+			number, err := strconv.Atoi(strings.TrimSpace(value))
+			if err != nil {
+				return "", nil, invalid("value must be an integer for rule type %d", ruleTypeInt)
+			}
+			operator := ">"
+			if ruleTypeInt == FILTER_ASN_LT {
+				operator = "<"
+			}
+			conditions = append(conditions, fmt.Sprintf("d.archive_serial_number %s %s", operator, nextArg(number)))
+
+		case FILTER_FULLTEXT_QUERY:
+			// Without access to the Paperless search index every term has to appear in the
+			// title or content (index syntax such as field prefixes is matched literally)
+			for _, term := range strings.Fields(value) {
+				pattern := "%" + escapeLikePattern(strings.ToLower(term)) + "%"
+				if usePostgres {
+					placeholder := nextArg(pattern)
+					conditions = append(conditions, fmt.Sprintf("(d.title ILIKE %s ESCAPE '!' OR d.content ILIKE %s ESCAPE '!')", placeholder, placeholder))
+				} else {
+					conditions = append(conditions, fmt.Sprintf("(LOWER(d.title) LIKE %s ESCAPE '!' OR LOWER(d.content) LIKE %s ESCAPE '!')", nextArg(pattern), nextArg(pattern)))
+				}
+			}
+
+		case FILTER_FULLTEXT_MORELIKE:
+			// "More like this" needs the Paperless search index
+			log.Printf("[DocumentFilter] Ignoring unsupported more-like-this rule (document %s)", value)
+
+		case FILTER_HAS_TAGS_ANY, FILTER_HAS_CORRESPONDENT_ANY, FILTER_HAS_DOCUMENT_TYPE_ANY,
+			FILTER_HAS_STORAGE_PATH_ANY, FILTER_OWNER_ANY:
+			anyValues[ruleTypeInt] = append(anyValues[ruleTypeInt], value)
+
+		case FILTER_HAS_CUSTOM_FIELDS_ANY:
+			fieldID, err := strconv.Atoi(value)
+			if err != nil {
+				return "", nil, invalid("value must be a custom field ID for rule type %d", ruleTypeInt)
+			}
+			if fieldID != excludeFieldID {
+				anyValues[ruleTypeInt] = append(anyValues[ruleTypeInt], value)
+			}
+
+		case FILTER_OWNER_ISNULL:
+			isNull, ok := parseRuleBool(value)
+			if !ok {
+				return "", nil, invalid("value must be a boolean for rule type %d", ruleTypeInt)
+			}
+			if isNull {
+				conditions = append(conditions, "d.owner_id IS NULL")
+			} else {
+				conditions = append(conditions, "d.owner_id IS NOT NULL")
+			}
+
+		case FILTER_OWNER_DOES_NOT_INCLUDE:
+			conditions = append(conditions, fmt.Sprintf("(d.owner_id IS NULL OR d.owner_id != %s)", nextArg(value)))
+
+		case FILTER_CUSTOM_FIELDS_TEXT:
+			// Field name or text value contains the search term
+			pattern := "%" + escapeLikePattern(strings.ToLower(strings.TrimSpace(value))) + "%"
+			var matches []string
+			for _, column := range []string{"cf2.name", "cfi2.value_text", "cfi2.value_url", "cfi2.value_long_text"} {
+				if usePostgres {
+					matches = append(matches, fmt.Sprintf("%s ILIKE %s ESCAPE '!'", column, nextArg(pattern)))
+				} else {
+					matches = append(matches, fmt.Sprintf("LOWER(%s) LIKE %s ESCAPE '!'", column, nextArg(pattern)))
+				}
+			}
+			conditions = append(conditions, fmt.Sprintf(`EXISTS (SELECT 1 FROM documents_customfieldinstance cfi2
+				INNER JOIN documents_customfield cf2 ON cf2.id = cfi2.field_id
+				WHERE cfi2.document_id = d.id AND cfi2.deleted_at IS NULL AND (%s))`, strings.Join(matches, " OR ")))
+
+		case FILTER_SHARED_BY_USER:
+			// Documents owned by the user that carry object permissions for other users or groups
+			conditions = append(conditions, fmt.Sprintf("(d.owner_id = %s AND %s)", nextArg(value), documentSharedCondition(s.config.DBEngine)))
+
+		case FILTER_HAS_CUSTOM_FIELDS_ALL, FILTER_DOES_NOT_HAVE_CUSTOM_FIELDS:
+			fieldID, err := strconv.Atoi(value)
+			if err != nil {
+				return "", nil, invalid("value must be a custom field ID for rule type %d", ruleTypeInt)
+			}
+			if fieldID == excludeFieldID {
+				continue
+			}
+			hasField := fmt.Sprintf("EXISTS (SELECT 1 FROM documents_customfieldinstance cfi2 WHERE cfi2.document_id = d.id AND cfi2.field_id = %s AND cfi2.deleted_at IS NULL)", nextArg(fieldID))
+			if ruleTypeInt == FILTER_DOES_NOT_HAVE_CUSTOM_FIELDS {
+				hasField = "NOT " + hasField
+			}
+			conditions = append(conditions, hasField)
+
+		case FILTER_HAS_ANY_CUSTOM_FIELDS:
+			hasFields, ok := parseRuleBool(value)
+			if !ok {
+				return "", nil, invalid("value must be a boolean for rule type %d", ruleTypeInt)
+			}
+			hasAny := "EXISTS (SELECT 1 FROM documents_customfieldinstance cfi2 WHERE cfi2.document_id = d.id AND cfi2.deleted_at IS NULL)"
+			if !hasFields {
+				hasAny = "NOT " + hasAny
+			}
+			conditions = append(conditions, hasAny)
+
+		case FILTER_CUSTOM_FIELDS_QUERY:
+			// Parse custom field query JSON
+			// Format: ["fieldId", "operator", value] or ["AND", [query1, query2]]
+			var customFieldQuery interface{}
+			if err := json.Unmarshal([]byte(value), &customFieldQuery); err != nil {
+				return "", nil, invalid("value must be a JSON encoded custom field query for rule type %d", ruleTypeInt)
+			}
+			// Build conditions for custom field filters, excluding the current field
+			customConditions, customArgs, customArgIndex := s.buildCustomFieldConditions(ctx, customFieldQuery, excludeFieldID, argIndex, usePostgres)
+			if len(customConditions) > 0 {
+				conditions = append(conditions, customConditions...)
+				args = append(args, customArgs...)
+				argIndex = customArgIndex
+			}
+		}
+	}
+
+	// Combine the values of "any of" rules into one condition per rule type
+	for _, ruleTypeInt := range anyFilterRuleOrder {
+		values := anyValues[ruleTypeInt]
+		if len(values) == 0 && !anyNull[ruleTypeInt] {
+			continue
+		}
+		placeholders := make([]string, len(values))
+		for i, value := range values {
+			placeholders[i] = nextArg(value)
+		}
+		inList := strings.Join(placeholders, ", ")
+
+		switch ruleTypeInt {
+		case FILTER_HAS_TAGS_ANY:
+			conditions = append(conditions, fmt.Sprintf("EXISTS (SELECT 1 FROM documents_document_tags dt WHERE dt.document_id = d.id AND dt.tag_id IN (%s))", inList))
+		case FILTER_HAS_CUSTOM_FIELDS_ANY:
+			conditions = append(conditions, fmt.Sprintf("EXISTS (SELECT 1 FROM documents_customfieldinstance cfi2 WHERE cfi2.document_id = d.id AND cfi2.field_id IN (%s) AND cfi2.deleted_at IS NULL)", inList))
+		default:
+			column := filterRuleAnyColumns[ruleTypeInt]
+			var alternatives []string
+			if len(values) > 0 {
+				alternatives = append(alternatives, fmt.Sprintf("%s IN (%s)", column, inList))
+			}
+			if anyNull[ruleTypeInt] {
+				alternatives = append(alternatives, column+" IS NULL")
+			}
+			conditions = append(conditions, "("+strings.Join(alternatives, " OR ")+")")
+		}
+	}
+
+	if len(conditions) == 0 {
+		return "", nil, nil
+	}
+
+	whereClause := "WHERE " + strings.Join(conditions, " AND ")
+	return whereClause, args, nil
+}
+
+// datePartExpression returns an integer SQL expression extracting part ("year", "month"
+// or "day") of a date column
+func (s *Service) datePartExpression(part string, column string) string {
+	switch s.config.DBEngine {
+	case "postgresql", "postgres":
+		return fmt.Sprintf("CAST(EXTRACT(%s FROM %s) AS INTEGER)", strings.ToUpper(part), column)
+	case "mysql", "mariadb":
+		return fmt.Sprintf("%s(%s)", strings.ToUpper(part), column)
+	default:
+		format := map[string]string{"year": "%Y", "month": "%m", "day": "%d"}[part]
+		return fmt.Sprintf("CAST(strftime('%s', %s) AS INTEGER)", format, column)
+	}
+}
+
+// dateBucketExpression returns a SQL expression formatting a date column as a bucket
+// label: "YYYY" for unit "year", "YYYY-MM" for unit "month"
+func dateBucketExpression(dbEngine string, unit string, column string) string {
+	switch dbEngine {
+	case "postgresql", "postgres":
+		format := map[string]string{"year": "YYYY", "month": "YYYY-MM"}[unit]
+		return fmt.Sprintf("TO_CHAR(%s, '%s')", column, format)
+	case "mysql", "mariadb":
+		format := map[string]string{"year": "%Y", "month": "%Y-%m"}[unit]
+		return fmt.Sprintf("DATE_FORMAT(%s, '%s')", column, format)
+	default:
+		format := map[string]string{"year": "%Y", "month": "%Y-%m"}[unit]
+		return fmt.Sprintf("strftime('%s', %s)", format, column)
+	}
+}
+
+// shiftRuleDate parses a rule date value (YYYY-MM-DD, optionally followed by a time)
+// and returns it shifted by days, formatted as YYYY-MM-DD
+func shiftRuleDate(value string, days int) (string, bool) {
+	value = strings.TrimSpace(value)
+	if len(value) < 10 {
+		return "", false
+	}
+	date, err := time.Parse("2006-01-02", value[:10])
+	if err != nil {
+		return "", false
+	}
+	return date.AddDate(0, 0, days).Format("2006-01-02"), true
+}
+
+// parseRuleBool parses boolean rule values ("true"/"false", "1"/"0")
+func parseRuleBool(value string) (bool, bool) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "true", "1":
+		return true, true
+	case "false", "0":
+		return false, true
+	}
+	return false, false
+}
+
+// documentPKExpression returns d.id as text, for comparison with guardian object_pk columns
+func documentPKExpression(dbEngine string) string {
+	if dbEngine == "mysql" || dbEngine == "mariadb" {
+		return "CAST(d.id AS CHAR)"
+	}
+	return "CAST(d.id AS TEXT)"
+}
+
+// documentSharedCondition returns a condition matching documents (alias d) that carry
+// object permissions for users or groups
+func documentSharedCondition(dbEngine string) string {
+	documentPK := documentPKExpression(dbEngine)
+	permissionExists := func(table string) string {
+		return fmt.Sprintf(`EXISTS (SELECT 1 FROM %s op
+			INNER JOIN django_content_type ct ON ct.id = op.content_type_id
+			WHERE ct.app_label = 'documents' AND ct.model = 'document' AND op.object_pk = %s)`, table, documentPK)
+	}
+	return fmt.Sprintf("(%s OR %s)", permissionExists("guardian_userobjectpermission"), permissionExists("guardian_groupobjectpermission"))
+}
+
+// trashedCondition returns the condition restricting documents (alias d) to those not in
+// the trash, or an always-true condition when trashed documents should be counted too
+func trashedCondition(includeTrashed bool) string {
+	if includeTrashed {
+		return "1 = 1"
+	}
+	return "d.deleted_at IS NULL"
+}
+
+// wantsTrashed reports whether the request asked to count trashed documents
+func wantsTrashed(r *http.Request) bool {
+	value := r.URL.Query().Get("include_trashed")
+	return value == "true" || value == "1"
+}
+
+// escapeLikePattern escapes LIKE wildcards in user input (using '!' as escape character,
+// which needs no quoting on any of the supported engines)
+func escapeLikePattern(value string) string {
+	return strings.NewReplacer("!", "!!", "%", "!%", "_", "!_").Replace(value)
+}
+
+// filterRulesFromBody extracts filter_rules from a counts request body and appends the
+// free-text query, if any, as a title (or title/content) rule
+func filterRulesFromBody(body map[string]interface{}) string {
+	rules, hasRules := body["filter_rules"].([]interface{})
+	if query, ok := body["query"].(string); ok && strings.TrimSpace(query) != "" {
+		ruleType := FILTER_TITLE
+		if searchContent, _ := body["search_content"].(bool); searchContent {
+			ruleType = FILTER_TITLE_CONTENT
+		}
+		rules = append(rules, map[string]interface{}{"rule_type": ruleType, "value": strings.TrimSpace(query)})
+		hasRules = true
+	}
+	if !hasRules {
+		return ""
+	}
+	rulesBytes, _ := json.Marshal(rules)
+	return string(rulesBytes)
+}
+
+// buildCustomFieldConditions builds SQL conditions for custom field filters
+// Excludes filters for the specified excludeFieldID
+func (s *Service) buildCustomFieldConditions(ctx context.Context, query interface{}, excludeFieldID int, startArgIndex int, usePostgres bool) ([]string, []interface{}, int) {
+	var conditions []string
+	var args []interface{}
+	argIndex := startArgIndex
+
+	queryArray, ok := query.([]interface{})
+	if !ok {
+		return conditions, args, argIndex
+	}
+
+	// Check if it's an AND or OR operator
+	if len(queryArray) > 0 {
+		if operator, ok := queryArray[0].(string); ok {
+			if operator == "AND" {
+				// Process all sub-queries with AND
+				if subQueries, ok := queryArray[1].([]interface{}); ok {
+					for _, subQuery := range subQueries {
+						subConditions, subArgs, newArgIndex := s.buildCustomFieldConditions(ctx, subQuery, excludeFieldID, argIndex, usePostgres)
+						conditions = append(conditions, subConditions...)
+						args = append(args, subArgs...)
+						argIndex = newArgIndex
+					}
+				}
+				return conditions, args, argIndex
+			} else if operator == "OR" {
+				// Process all sub-queries with OR
+				if subQueries, ok := queryArray[1].([]interface{}); ok {
+					var orConditions []string
+					for _, subQuery := range subQueries {
+						subConditions, subArgs, newArgIndex := s.buildCustomFieldConditions(ctx, subQuery, excludeFieldID, argIndex, usePostgres)
+						if len(subConditions) > 0 {
+							// Wrap each condition in parentheses and join with OR
+							for _, cond := range subConditions {
+								orConditions = append(orConditions, fmt.Sprintf("(%s)", cond))
+							}
+							args = append(args, subArgs...)
+							argIndex = newArgIndex
+						}
+					}
+					if len(orConditions) > 0 {
+						// Combine OR conditions into a single condition, wrapped in parentheses
+						// This ensures proper operator precedence when combined with AND
+						combinedOrCondition := strings.Join(orConditions, " OR ")
+						conditions = append(conditions, fmt.Sprintf("(%s)", combinedOrCondition))
+					}
+				}
+				return conditions, args, argIndex
+			} else if operator == "NOT" && len(queryArray) > 1 {
+				// Negate a sub-query: ["NOT", query]
+				subConditions, subArgs, newArgIndex := s.buildCustomFieldConditions(ctx, queryArray[1], excludeFieldID, argIndex, usePostgres)
+				if len(subConditions) > 0 {
+					conditions = append(conditions, fmt.Sprintf("NOT (%s)", strings.Join(subConditions, " AND ")))
+					args = append(args, subArgs...)
+					argIndex = newArgIndex
+				}
+				return conditions, args, argIndex
+			}
+		}
+	}
+
+	// Single query: [fieldId, "operator", value]
+	if len(queryArray) >= 3 {
+		fieldIDFloat, ok := queryArray[0].(float64)
+		if !ok {
+			return conditions, args, argIndex
+		}
+		fieldID := int(fieldIDFloat)
+
+		// Skip if this is the field we're querying
+		if fieldID == excludeFieldID {
+			return conditions, args, argIndex
+		}
+
+		operator, ok := queryArray[1].(string)
+		if !ok {
+			return conditions, args, argIndex
+		}
+
+		// Build condition based on operator
+		switch operator {
+		case "exists":
+			// Field exists (is not null); exists=false matches documents without the field
+			existsCondition := "EXISTS"
+			if exists, ok := queryArray[2].(bool); ok && !exists {
+				existsCondition = "NOT EXISTS"
+			} else if exists, ok := queryArray[2].(string); ok && strings.EqualFold(exists, "false") {
+				existsCondition = "NOT EXISTS"
+			}
+			conditions = append(conditions, fmt.Sprintf("%s (SELECT 1 FROM documents_customfieldinstance cfi2 WHERE cfi2.document_id = d.id AND cfi2.field_id = %d AND cfi2.deleted_at IS NULL)", existsCondition, fieldID))
+
+		case "isnull":
+			// Field is null or empty - check both missing instances and instances with NULL/empty values
+			// First, get the field's data type to determine which value column to check
+			var dataType string
+			switch s.config.DBEngine {
+			case "postgresql", "postgres":
+				if err := s.db.QueryRowContext(ctx, "SELECT data_type FROM documents_customfield WHERE id = $1", fieldID).Scan(&dataType); err != nil {
+					fmt.Printf("[buildCustomFieldConditions] Warning: Could not fetch data_type for field %d: %v\n", fieldID, err)
+					dataType = "string" // Default fallback
+				}
+			case "mysql", "mariadb", "sqlite", "sqlite3":
+				if err := s.db.QueryRowContext(ctx, "SELECT data_type FROM documents_customfield WHERE id = ?", fieldID).Scan(&dataType); err != nil {
+					fmt.Printf("[buildCustomFieldConditions] Warning: Could not fetch data_type for field %d: %v\n", fieldID, err)
+					dataType = "string" // Default fallback
+				}
+			}
+
+			valueColumn := getValueColumnName(dataType)
+
+			// Check for documents that either:
+			// 1. Don't have a custom field instance for this field, OR
+			// 2. Have an instance but the value column is NULL or empty
+			if usePostgres {
+				conditions = append(conditions, fmt.Sprintf("NOT EXISTS (SELECT 1 FROM documents_customfieldinstance cfi2 WHERE cfi2.document_id = d.id AND cfi2.field_id = %d AND cfi2.deleted_at IS NULL AND cfi2.%s IS NOT NULL AND cfi2.%s != '')", fieldID, valueColumn, valueColumn))
+			} else {
+				conditions = append(conditions, fmt.Sprintf("NOT EXISTS (SELECT 1 FROM documents_customfieldinstance cfi2 WHERE cfi2.document_id = d.id AND cfi2.field_id = %d AND cfi2.deleted_at IS NULL AND cfi2.%s IS NOT NULL AND cfi2.%s != '')", fieldID, valueColumn, valueColumn))
+			}
+
+		case "in":
+			// Field value in list
+			if values, ok := queryArray[2].([]interface{}); ok && len(values) > 0 {
+				// Check if this is a select field and map labels to option IDs
+				var dataType string
+				var extraDataJSON []byte
+
+				switch s.config.DBEngine {
+				case "postgresql", "postgres":
+					if err := s.db.QueryRowContext(ctx, "SELECT data_type, extra_data FROM documents_customfield WHERE id = $1", fieldID).Scan(&dataType, &extraDataJSON); err != nil {
+						// If we can't fetch field metadata, proceed without label mapping
+						fmt.Printf("[buildCustomFieldConditions] Warning: Could not fetch field metadata for field %d: %v\n", fieldID, err)
+						dataType = ""
+					}
+				case "mysql", "mariadb", "sqlite", "sqlite3":
+					if err := s.db.QueryRowContext(ctx, "SELECT data_type, extra_data FROM documents_customfield WHERE id = ?", fieldID).Scan(&dataType, &extraDataJSON); err != nil {
+						// If we can't fetch field metadata, proceed without label mapping
+						fmt.Printf("[buildCustomFieldConditions] Warning: Could not fetch field metadata for field %d: %v\n", fieldID, err)
+						dataType = ""
+					}
+				}
+
+				// Build label -> option ID map for select fields
+				labelToOptionIDMap := make(map[string]string)
+				if dataType == "select" && len(extraDataJSON) > 0 {
+					var extraData map[string]interface{}
+					if err := json.Unmarshal(extraDataJSON, &extraData); err == nil {
+						if selectOptions, ok := extraData["select_options"].([]interface{}); ok {
+							for _, opt := range selectOptions {
+								if optMap, ok := opt.(map[string]interface{}); ok {
+									if optID, ok := optMap["id"].(string); ok {
+										if optLabel, ok := optMap["label"].(string); ok {
+											labelToOptionIDMap[optLabel] = optID
+										}
+									}
+								}
+							}
+						}
+					}
+				}
+
+				// Determine the correct value column based on data type
+				valueColumn := getValueColumnName(dataType)
+				fmt.Printf("[buildCustomFieldConditions] Field %d: dataType=%s, valueColumn=%s, originalValues=%v\n", fieldID, dataType, valueColumn, values)
+
+				placeholders := []string{}
+				for _, val := range values {
+					valStr := fmt.Sprintf("%v", val)
+					originalValStr := valStr
+
+					// For select fields, map label to option ID
+					if dataType == "select" {
+						if optionID, found := labelToOptionIDMap[valStr]; found {
+							valStr = optionID
+							fmt.Printf("[buildCustomFieldConditions] Field %d: Mapped label '%s' to option ID '%s'\n", fieldID, originalValStr, valStr)
+						} else {
+							fmt.Printf("[buildCustomFieldConditions] Field %d: Label '%s' not found in map, using as-is (might already be an ID)\n", fieldID, valStr)
+						}
+					}
+
+					if usePostgres {
+						placeholders = append(placeholders, fmt.Sprintf("$%d", argIndex))
+					} else {
+						placeholders = append(placeholders, "?")
+					}
+					args = append(args, valStr)
+					argIndex++
+				}
+				placeholderStr := strings.Join(placeholders, ", ")
+				conditions = append(conditions, fmt.Sprintf("EXISTS (SELECT 1 FROM documents_customfieldinstance cfi2 WHERE cfi2.document_id = d.id AND cfi2.field_id = %d AND cfi2.%s IN (%s) AND cfi2.deleted_at IS NULL)", fieldID, valueColumn, placeholderStr))
+				fmt.Printf("[buildCustomFieldConditions] Field %d: Built condition with valueColumn=%s, args=%v\n", fieldID, valueColumn, args)
+			}
+
+		case "range":
+			// Date range
+			if dateRange, ok := queryArray[2].([]interface{}); ok && len(dateRange) >= 2 {
+				startDate := fmt.Sprintf("%v", dateRange[0])
+				endDate := fmt.Sprintf("%v", dateRange[1])
+				if usePostgres {
+					conditions = append(conditions, fmt.Sprintf("EXISTS (SELECT 1 FROM documents_customfieldinstance cfi2 WHERE cfi2.document_id = d.id AND cfi2.field_id = %d AND cfi2.value_date >= '%s'::date AND cfi2.value_date <= '%s'::date AND cfi2.deleted_at IS NULL)", fieldID, startDate, endDate))
+				} else {
+					conditions = append(conditions, fmt.Sprintf("EXISTS (SELECT 1 FROM documents_customfieldinstance cfi2 WHERE cfi2.document_id = d.id AND cfi2.field_id = %d AND cfi2.value_date >= '%s' AND cfi2.value_date <= '%s' AND cfi2.deleted_at IS NULL)", fieldID, startDate, endDate))
+				}
+			}
+
+		case "gte":
+			// Greater than or equal
+			val := fmt.Sprintf("%v", queryArray[2])
+			if usePostgres {
+				conditions = append(conditions, fmt.Sprintf("EXISTS (SELECT 1 FROM documents_customfieldinstance cfi2 WHERE cfi2.document_id = d.id AND cfi2.field_id = %d AND cfi2.value_date >= '%s'::date AND cfi2.deleted_at IS NULL)", fieldID, val))
+			} else {
+				conditions = append(conditions, fmt.Sprintf("EXISTS (SELECT 1 FROM documents_customfieldinstance cfi2 WHERE cfi2.document_id = d.id AND cfi2.field_id = %d AND cfi2.value_date >= '%s' AND cfi2.deleted_at IS NULL)", fieldID, val))
+			}
+
+		case "lte":
+			// Less than or equal
+			val := fmt.Sprintf("%v", queryArray[2])
+			if usePostgres {
+				conditions = append(conditions, fmt.Sprintf("EXISTS (SELECT 1 FROM documents_customfieldinstance cfi2 WHERE cfi2.document_id = d.id AND cfi2.field_id = %d AND cfi2.value_date <= '%s'::date AND cfi2.deleted_at IS NULL)", fieldID, val))
+			} else {
+				conditions = append(conditions, fmt.Sprintf("EXISTS (SELECT 1 FROM documents_customfieldinstance cfi2 WHERE cfi2.document_id = d.id AND cfi2.field_id = %d AND cfi2.value_date <= '%s' AND cfi2.deleted_at IS NULL)", fieldID, val))
+			}
+		}
+	}
+
+	return conditions, args, argIndex
+}
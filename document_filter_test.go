@@ -0,0 +1,261 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// newFilterTestService creates a service with three documents that differ in every
+// dimension a filter rule can select:
+//
+//	1 "Invoice ACME": correspondent/type/storage path 1, owner 1 (shared), ASN 10, tags Inbox and Finance
+//	2 "Letter": correspondent/type 2, no storage path, owner 2, ASN 20, tag Finance, custom field 1
+//	3 "Receipt": nothing assigned, storage path 2, no tags
+func newFilterTestService(t *testing.T) *Service {
+	t.Helper()
+	s := newTestService(t, nil)
+	mustExec(t, s,
+		`INSERT INTO documents_tag (id, name, is_inbox_tag) VALUES (1, 'Inbox', 1), (2, 'Finance', 0)`,
+		`INSERT INTO documents_document (id, title, content, archive_serial_number, correspondent_id, document_type_id,
+			storage_path_id, owner_id, created, added, modified, mime_type) VALUES
+			(1, 'Invoice ACME', 'payment due', 10, 1, 1, 1, 1, '2024-03-15', '2024-03-20 08:00:00', '2024-04-01 09:00:00', 'application/pdf'),
+			(2, 'Letter', 'invoice attached', 20, 2, 2, NULL, 2, '2024-06-01', '2024-06-02 08:00:00', '2024-06-03 09:00:00', 'image/png'),
+			(3, 'Receipt', '', NULL, NULL, NULL, 2, NULL, '2023-12-31', '2024-01-01 08:00:00', '2024-01-02 09:00:00', 'application/pdf')`,
+		`INSERT INTO documents_document_tags (document_id, tag_id) VALUES (1, 1), (1, 2), (2, 2)`,
+		`INSERT INTO documents_customfield (id, name, data_type) VALUES (1, 'Project', 'string')`,
+		`INSERT INTO documents_customfieldinstance (document_id, field_id, value_text) VALUES (2, 1, 'project x')`,
+		`INSERT INTO guardian_userobjectpermission (user_id, permission_id, content_type_id, object_pk) VALUES (2, 1, 1, '1')`,
+	)
+	return s
+}
+
+// filteredDocumentIDs returns the IDs of the documents matching filterRulesJSON
+func filteredDocumentIDs(t *testing.T, s *Service, filterRulesJSON string, excludeRuleType int) []int {
+	t.Helper()
+	where, args, err := s.buildDocumentFilterQuery(context.Background(), filterRulesJSON, 0, excludeRuleType)
+	if err != nil {
+		t.Fatalf("%s: %v", filterRulesJSON, err)
+	}
+	rows, err := s.db.Query("SELECT d.id FROM documents_document d "+where+" ORDER BY d.id", args...)
+	if err != nil {
+		t.Fatalf("%s: %v", filterRulesJSON, err)
+	}
+	defer rows.Close()
+	ids := []int{}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+func TestDocumentFilterRuleTypes(t *testing.T) {
+	s := newFilterTestService(t)
+
+	tests := []struct {
+		name  string
+		rules string
+		want  []int
+	}{
+		{"title", `[{"rule_type": 0, "value": "invoice"}]`, []int{1}},
+		{"content", `[{"rule_type": 1, "value": "invoice"}]`, []int{2}},
+		{"asn", `[{"rule_type": 2, "value": 10}]`, []int{1}},
+		{"correspondent", `[{"rule_type": 3, "value": "1"}]`, []int{1}},
+		{"correspondent not assigned", `[{"rule_type": 3, "value": null}]`, []int{3}},
+		{"document type", `[{"rule_type": 4, "value": "2"}]`, []int{2}},
+		{"in inbox", `[{"rule_type": 5, "value": true}]`, []int{1}},
+		{"not in inbox", `[{"rule_type": 5, "value": "false"}]`, []int{2, 3}},
+		{"has tag", `[{"rule_type": 6, "value": "2"}]`, []int{1, 2}},
+		{"has all tags", `[{"rule_type": 6, "value": "1"}, {"rule_type": 6, "value": "2"}]`, []int{1}},
+		{"has any tag", `[{"rule_type": 7, "value": true}]`, []int{1, 2}},
+		{"has no tags", `[{"rule_type": 7, "value": false}]`, []int{3}},
+		{"created before", `[{"rule_type": 8, "value": "2024-04-01"}]`, []int{1, 3}},
+		{"created after", `[{"rule_type": 9, "value": "2024-01-01"}]`, []int{1, 2}},
+		{"created year", `[{"rule_type": 10, "value": "2024"}]`, []int{1, 2}},
+		{"created month", `[{"rule_type": 11, "value": 3}]`, []int{1}},
+		{"created day", `[{"rule_type": 12, "value": "31"}]`, []int{3}},
+		{"added before", `[{"rule_type": 13, "value": "2024-03-20"}]`, []int{3}},
+		{"added after", `[{"rule_type": 14, "value": "2024-03-20"}]`, []int{2}},
+		{"modified before", `[{"rule_type": 15, "value": "2024-04-01"}]`, []int{3}},
+		{"modified after", `[{"rule_type": 16, "value": "2024-01-02"}]`, []int{1, 2}},
+		{"does not have tag", `[{"rule_type": 17, "value": "2"}]`, []int{3}},
+		{"asn is null", `[{"rule_type": 18, "value": true}]`, []int{3}},
+		{"asn is not null", `[{"rule_type": 18, "value": false}]`, []int{1, 2}},
+		{"title or content", `[{"rule_type": 19, "value": "invoice"}]`, []int{1, 2}},
+		{"full text", `[{"rule_type": 20, "value": "invoice due"}]`, []int{1}},
+		{"more like this is ignored", `[{"rule_type": 21, "value": "1"}]`, []int{1, 2, 3}},
+		{"has any of the tags", `[{"rule_type": 22, "value": "1"}, {"rule_type": 22, "value": "3"}]`, []int{1}},
+		{"asn greater than", `[{"rule_type": 23, "value": "10"}]`, []int{2}},
+		{"asn less than", `[{"rule_type": 24, "value": "20"}]`, []int{1}},
+		{"storage path", `[{"rule_type": 25, "value": "2"}]`, []int{3}},
+		{"storage path not assigned", `[{"rule_type": 25, "value": null}]`, []int{2}},
+		{"any correspondent", `[{"rule_type": 26, "value": "1"}, {"rule_type": 26, "value": "2"}]`, []int{1, 2}},
+		{"any correspondent or none", `[{"rule_type": 26, "value": "2"}, {"rule_type": 26, "value": null}]`, []int{2, 3}},
+		{"does not have correspondent", `[{"rule_type": 27, "value": "1"}]`, []int{2, 3}},
+		{"any document type", `[{"rule_type": 28, "value": "1"}]`, []int{1}},
+		{"does not have document type", `[{"rule_type": 29, "value": "1"}]`, []int{2, 3}},
+		{"any storage path", `[{"rule_type": 30, "value": "1"}]`, []int{1}},
+		{"does not have storage path", `[{"rule_type": 31, "value": "1"}]`, []int{2, 3}},
+		{"owner", `[{"rule_type": 32, "value": "2"}]`, []int{2}},
+		{"owner not assigned", `[{"rule_type": 32, "value": null}]`, []int{3}},
+		{"any owner", `[{"rule_type": 33, "value": "1"}, {"rule_type": 33, "value": "2"}]`, []int{1, 2}},
+		{"owner is null", `[{"rule_type": 34, "value": true}]`, []int{3}},
+		{"owner does not include", `[{"rule_type": 35, "value": "1"}]`, []int{2, 3}},
+		{"custom field text", `[{"rule_type": 36, "value": "project"}]`, []int{2}},
+		{"shared by user", `[{"rule_type": 37, "value": "1"}]`, []int{1}},
+		{"has all custom fields", `[{"rule_type": 38, "value": "1"}]`, []int{2}},
+		{"has any custom fields", `[{"rule_type": 39, "value": "1"}]`, []int{2}},
+		{"does not have custom fields", `[{"rule_type": 40, "value": "1"}]`, []int{1, 3}},
+		{"has any custom field", `[{"rule_type": 41, "value": true}]`, []int{2}},
+		{"custom field query", `[{"rule_type": 42, "value": "[1, \"exists\", true]"}]`, []int{2}},
+		{"created to", `[{"rule_type": 43, "value": "2024-03-15"}]`, []int{1, 3}},
+		{"created from", `[{"rule_type": 44, "value": "2024-06-01"}]`, []int{2}},
+		{"added to", `[{"rule_type": 45, "value": "2024-03-20"}]`, []int{1, 3}},
+		{"added from", `[{"rule_type": 46, "value": "2024-03-20"}]`, []int{1, 2}},
+		{"created from to", `[{"rule_type": 44, "value": "2024-03-15"}, {"rule_type": 43, "value": "2024-03-15"}]`, []int{1}},
+		{"mime type", `[{"rule_type": 47, "value": "image/png"}]`, []int{2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := filteredDocumentIDs(t, s, tt.rules, 0); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("%s matched %v, want %v", tt.rules, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDocumentFilterExcludesDimension(t *testing.T) {
+	s := newFilterTestService(t)
+	rules := `[{"rule_type": 6, "value": "1"}, {"rule_type": 7, "value": false}, {"rule_type": 17, "value": "2"},
+		{"rule_type": 22, "value": "1"}, {"rule_type": 27, "value": "2"}]`
+
+	// Every tag rule belongs to the tag dimension; the correspondent rule still applies
+	if got := filteredDocumentIDs(t, s, rules, builtinFilterRuleTypes["tag"]); !reflect.DeepEqual(got, []int{1, 3}) {
+		t.Errorf("without tag rules matched %v, want [1 3]", got)
+	}
+	if got := filteredDocumentIDs(t, s, rules, builtinFilterRuleTypes["correspondent"]); len(got) != 0 {
+		t.Errorf("without correspondent rules matched %v, want none", got)
+	}
+}
+
+func TestDocumentFilterRefusesInvalidRules(t *testing.T) {
+	s := newFilterTestService(t)
+	for _, rules := range []string{
+		`[{"rule_type": 5, "value": "maybe"}]`,
+		`[{"rule_type": 7, "value": "2024-01-31"}]`,
+		`[{"rule_type": 9, "value": "yesterday"}]`,
+		`[{"rule_type": 44, "value": true}]`,
+		`[{"rule_type": 10, "value": "last year"}]`,
+		`[{"rule_type": 23, "value": "ten"}]`,
+		`[{"rule_type": 38, "value": "Project"}]`,
+		`[{"rule_type": 39, "value": "Project"}]`,
+		`[{"rule_type": 42, "value": "not json"}]`,
+		`[{"rule_type": 9, "value": null}]`,
+		`[{"rule_type": 3}]`,
+		`[{"rule_type": "3", "value": "1"}]`,
+		`[{"rule_type": 3, "value": {"id": 1}}]`,
+		`{"rule_type": 3}`,
+	} {
+		_, _, err := s.buildDocumentFilterQuery(context.Background(), rules, 0, 0)
+		if !errors.Is(err, errInvalidFilterRules) {
+			t.Errorf("%s: got %v, want an invalid filter_rules error", rules, err)
+		} else if status := queryErrorStatus(fmt.Errorf("failed to build filter query: %w", err)); status != 400 {
+			t.Errorf("%s: status %d, want 400", rules, status)
+		}
+	}
+
+	// Invalid rules of an excluded dimension are left out with it
+	rules := `[{"rule_type": 7, "value": "maybe"}, {"rule_type": 3, "value": "1"}]`
+	if got := filteredDocumentIDs(t, s, rules, builtinFilterRuleTypes["tag"]); !reflect.DeepEqual(got, []int{1}) {
+		t.Errorf("without tag rules matched %v, want [1]", got)
+	}
+}
+
+func TestValidateFilterRuleTypes(t *testing.T) {
+	values := map[int]interface{}{
+		FILTER_TITLE: "invoice", FILTER_CONTENT: "invoice", FILTER_TITLE_CONTENT: "invoice",
+		FILTER_FULLTEXT_QUERY: "invoice", FILTER_FULLTEXT_MORELIKE: "1", FILTER_CUSTOM_FIELDS_TEXT: "x",
+		FILTER_CUSTOM_FIELDS_QUERY: `[1, "exists", true]`, FILTER_MIME_TYPE: "application/pdf",
+	}
+	for ruleType := FILTER_TITLE; ruleType <= FILTER_MIME_TYPE; ruleType++ {
+		value, ok := values[ruleType]
+		switch {
+		case ok:
+		case idFilterRules[ruleType], integerFilterRules[ruleType]:
+			value = "1"
+		case boolFilterRules[ruleType]:
+			value = true
+		case dateFilterRules[ruleType]:
+			value = "2024-01-31"
+		default:
+			t.Errorf("rule type %d has no value shape", ruleType)
+			continue
+		}
+		v := &customViewValidator{fieldIDs: map[int]bool{1: true}}
+		v.filterRule("filter_rules[0]", map[string]interface{}{"rule_type": float64(ruleType), "value": value})
+		if len(v.problems) > 0 {
+			t.Errorf("rule type %d with %v: %v", ruleType, value, v.problems)
+		}
+	}
+
+	invalid := []map[string]interface{}{
+		{"rule_type": float64(FILTER_MIME_TYPE + 1), "value": "1"},
+		{"rule_type": float64(FILTER_HAS_ANY_TAG), "value": "2024-01-31"},
+		{"rule_type": float64(FILTER_CREATED_BEFORE), "value": true},
+		{"rule_type": float64(FILTER_IS_IN_INBOX), "value": "2"},
+	}
+	for _, rule := range invalid {
+		v := &customViewValidator{}
+		v.filterRule("filter_rules[0]", rule)
+		if len(v.problems) == 0 {
+			t.Errorf("%v accepted", rule)
+		}
+	}
+}
+
+func TestDocumentFilterDateBoundaries(t *testing.T) {
+	s := newTestService(t, nil)
+	// Each document has the same value in created, added and modified: the last moment
+	// of the day before, the first and last moments of the day itself and the first
+	// moment of the day after, plus a date without time
+	mustExec(t, s,
+		`INSERT INTO documents_document (id, title, created, added, modified) VALUES
+			(1, 'day before', '2024-03-14 23:59:59', '2024-03-14 23:59:59', '2024-03-14 23:59:59'),
+			(2, 'start of day', '2024-03-15 00:00:00', '2024-03-15 00:00:00', '2024-03-15 00:00:00'),
+			(3, 'end of day', '2024-03-15 23:59:59', '2024-03-15 23:59:59', '2024-03-15 23:59:59'),
+			(4, 'day after', '2024-03-16 00:00:00', '2024-03-16 00:00:00', '2024-03-16 00:00:00'),
+			(5, 'date only', '2024-03-15', '2024-03-15', '2024-03-15')`,
+	)
+
+	tests := []struct {
+		ruleType int
+		want     []int
+	}{
+		{FILTER_CREATED_BEFORE, []int{1}},
+		{FILTER_CREATED_AFTER, []int{4}},
+		{FILTER_ADDED_BEFORE, []int{1}},
+		{FILTER_ADDED_AFTER, []int{4}},
+		{FILTER_MODIFIED_BEFORE, []int{1}},
+		{FILTER_MODIFIED_AFTER, []int{4}},
+	}
+	for _, tt := range tests {
+		for _, value := range []string{"2024-03-15", "2024-03-15T12:00:00Z"} {
+			rules := fmt.Sprintf(`[{"rule_type": %d, "value": %q}]`, tt.ruleType, value)
+			if got := filteredDocumentIDs(t, s, rules, 0); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("%s matched %v, want %v", rules, got, tt.want)
+			}
+		}
+	}
+
+	// A range of before and after keeps the days in between
+	rules := `[{"rule_type": 9, "value": "2024-03-14"}, {"rule_type": 8, "value": "2024-03-16"}]`
+	if got := filteredDocumentIDs(t, s, rules, 0); !reflect.DeepEqual(got, []int{2, 3, 5}) {
+		t.Errorf("%s matched %v, want [2 3 5]", rules, got)
+	}
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	defaultLinkGraphDepth = 2
+	maxLinkGraphDepth     = 10
+)
+
+// DocumentLinkGraphNode is a document of a link graph with its distance from the root
+type DocumentLinkGraphNode struct {
+	ID    int    `json:"id"`
+	Title string `json:"title"`
+	Depth int    `json:"depth"`
+}
+
+// DocumentLinkGraphEdge is a link of a link graph
+type DocumentLinkGraphEdge struct {
+	ID           int    `json:"id"`
+	Source       int    `json:"source"`
+	Target       int    `json:"target"`
+	LinkType     string `json:"link_type"`
+	InverseLabel string `json:"inverse_label"`
+	Directed     bool   `json:"directed"`
+}
+
+// DocumentLinkGraph is the neighbourhood of a document in the link graph. Truncated is set
+// when documents were left out because of the node cap.
+type DocumentLinkGraph struct {
+	Root      int                     `json:"root"`
+	Depth     int                     `json:"depth"`
+	Nodes     []DocumentLinkGraphNode `json:"nodes"`
+	Edges     []DocumentLinkGraphEdge `json:"edges"`
+	Truncated bool                    `json:"truncated"`
+}
+
+// GetDocumentLinkGraph walks the links of the documents the user can see, in both directions,
+// from root up to depth links away. Every document is visited once, so cycles end the walk,
+// and at most maxNodes documents are included. Edges are the links between included
+// documents.
+func (s *Service) GetDocumentLinkGraph(ctx context.Context, root int, depth int, maxNodes int, includeTrashed bool, viewerID int) (*DocumentLinkGraph, error) {
+	if err := s.checkDocumentsVisible(ctx, []int{root}, includeTrashed, viewerID); err != nil {
+		return nil, err
+	}
+
+	graph := &DocumentLinkGraph{Root: root, Depth: depth, Nodes: []DocumentLinkGraphNode{}, Edges: []DocumentLinkGraphEdge{}}
+	titles := make(map[int]string)
+	distances := map[int]int{root: 0}
+	order := []int{root}
+	seenLinks := make(map[int]bool)
+
+	// The walk goes one level further than depth to find the links among the outermost
+	// documents, without adding documents from that level
+	frontier := []int{root}
+	for level := 0; level <= depth && len(frontier) > 0; level++ {
+		var links []DocumentLink
+		batchSize := tagGroupMembershipBatchSize / 2
+		for start := 0; start < len(frontier); start += batchSize {
+			placeholders, args := inPlaceholders(frontier[start:min(start+batchSize, len(frontier))])
+			batch, err := s.queryDocumentLinks(ctx, includeTrashed, viewerID,
+				fmt.Sprintf("(l.source_id IN (%[1]s) OR l.target_id IN (%[1]s))", placeholders), append(args, args...)...)
+			if err != nil {
+				return nil, err
+			}
+			links = append(links, batch...)
+		}
+
+		next := []int{}
+		for _, link := range links {
+			titles[link.SourceID] = link.SourceTitle
+			titles[link.TargetID] = link.TargetTitle
+			for _, id := range []int{link.SourceID, link.TargetID} {
+				if _, visited := distances[id]; visited || level == depth {
+					continue
+				}
+				if len(order) >= maxNodes {
+					graph.Truncated = true
+					continue
+				}
+				distances[id] = level + 1
+				order = append(order, id)
+				next = append(next, id)
+			}
+		}
+		for _, link := range links {
+			_, sourceIncluded := distances[link.SourceID]
+			_, targetIncluded := distances[link.TargetID]
+			if seenLinks[*link.ID] || !sourceIncluded || !targetIncluded {
+				continue
+			}
+			seenLinks[*link.ID] = true
+			graph.Edges = append(graph.Edges, DocumentLinkGraphEdge{ID: *link.ID, Source: link.SourceID, Target: link.TargetID, LinkType: link.LinkType,
+				InverseLabel: link.InverseLabel, Directed: link.Directed})
+		}
+		frontier = next
+	}
+
+	// A root without links has no title yet
+	if _, ok := titles[root]; !ok {
+		var title string
+		if err := s.db.QueryRowContext(ctx, s.rebind("SELECT COALESCE(title, '') FROM documents_document WHERE id = ?"), root).Scan(&title); err != nil {
+			return nil, fmt.Errorf("failed to query document %d: %w", root, err)
+		}
+		titles[root] = title
+	}
+	for _, id := range order {
+		graph.Nodes = append(graph.Nodes, DocumentLinkGraphNode{ID: id, Title: titles[id], Depth: distances[id]})
+	}
+	return graph, nil
+}
+
+// HTTP Handler for the link graph around a document
+func (s *Service) handleGetDocumentLinkGraph(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	log.Printf("[DocumentLinks] GET /api/document-links/graph/ - Request from %s", r.RemoteAddr)
+
+	viewerID, err := viewerFromRequest(r)
+	if err != nil {
+		respondError(w, viewerErrorStatus(err), err.Error())
+		return
+	}
+
+	params := r.URL.Query()
+	root, err := strconv.Atoi(strings.TrimSpace(params.Get("root")))
+	if err != nil || root <= 0 {
+		respondError(w, http.StatusBadRequest, "Invalid root, expected a document ID")
+		return
+	}
+	depth := defaultLinkGraphDepth
+	if value := params.Get("depth"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > maxLinkGraphDepth {
+			respondError(w, http.StatusBadRequest, fmt.Sprintf("Invalid depth, expected 1 to %d", maxLinkGraphDepth))
+			return
+		}
+		depth = parsed
+	}
+	maxNodes := s.config.LinkGraphMaxNodes
+	if value := params.Get("max_nodes"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 {
+			respondError(w, http.StatusBadRequest, "Invalid max_nodes")
+			return
+		}
+		maxNodes = min(parsed, maxNodes)
+	}
+
+	graph, err := s.GetDocumentLinkGraph(ctx, root, depth, maxNodes, wantsTrashed(r), viewerID)
+	if err != nil {
+		log.Printf("[DocumentLinks] Error building link graph of document %d: %v", root, err)
+		respondError(w, documentLinkErrorStatus(err), err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, graph)
+}
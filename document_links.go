@@ -0,0 +1,549 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	// defaultDocumentLinkType is the type of links created without one
+	defaultDocumentLinkType = "related"
+	// maxDocumentLinkTypeLength is the size of the link_type column
+	maxDocumentLinkTypeLength = 64
+)
+
+// linkedDocumentsCondition returns a condition on the link l restricting it to links between
+// documents the user can see, leaving out links to trashed documents unless includeTrashed
+func (s *Service) linkedDocumentsCondition(ctx context.Context, includeTrashed bool, viewerID int) (string, error) {
+	documentCondition := trashedCondition(includeTrashed)
+	visibility, err := s.documentVisibilityCondition(ctx, viewerID)
+	if err != nil {
+		return "", err
+	}
+	if visibility != "" {
+		documentCondition = fmt.Sprintf("(%s AND %s)", documentCondition, visibility)
+	}
+	return fmt.Sprintf(`l.source_id IN (SELECT d.id FROM documents_document d WHERE %[1]s)
+		AND l.target_id IN (SELECT d.id FROM documents_document d WHERE %[1]s)`, documentCondition), nil
+}
+
+// queryDocumentLinks retrieves the links selected by condition, with the titles of their
+// documents, that are visible to the user
+func (s *Service) queryDocumentLinks(ctx context.Context, includeTrashed bool, viewerID int, condition string, args ...interface{}) ([]DocumentLink, error) {
+	visible, err := s.linkedDocumentsCondition(ctx, includeTrashed, viewerID)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.QueryContext(ctx, s.rebind(fmt.Sprintf(`
+		SELECT l.id, l.source_id, l.target_id, l.link_type, l.note, l.created, l.modified, l.created_by, src.title, tgt.title,
+			lt.inverse_name, lt.directed
+		FROM document_links l
+		INNER JOIN documents_document src ON src.id = l.source_id
+		INNER JOIN documents_document tgt ON tgt.id = l.target_id
+		LEFT JOIN link_types lt ON lt.name = l.link_type
+		WHERE %s AND %s
+		ORDER BY l.id ASC
+	`, visible, condition)), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query document links: %w", err)
+	}
+	defer rows.Close()
+
+	links := []DocumentLink{}
+	for rows.Next() {
+		var link DocumentLink
+		var id int
+		var note, sourceTitle, targetTitle, inverseName sql.NullString
+		var created, modified dbTimestamp
+		var createdBy sql.NullInt64
+		var directed sql.NullBool
+		if err := rows.Scan(&id, &link.SourceID, &link.TargetID, &link.LinkType, &note, &created, &modified, &createdBy, &sourceTitle, &targetTitle,
+			&inverseName, &directed); err != nil {
+			return nil, fmt.Errorf("failed to scan document link: %w", err)
+		}
+		link.ID = &id
+		link.Directed = directed.Bool
+		link.Label = link.LinkType
+		link.InverseLabel = link.LinkType
+		if link.Directed && inverseName.Valid {
+			link.InverseLabel = inverseName.String
+		}
+		if note.Valid {
+			link.Note = &note.String
+		}
+		link.SourceTitle = sourceTitle.String
+		link.TargetTitle = targetTitle.String
+		link.Created = s.formatTimestamp(created)
+		link.Modified = s.formatTimestamp(modified)
+		if createdBy.Valid {
+			userID := int(createdBy.Int64)
+			link.CreatedBy = &userID
+		}
+		links = append(links, link)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read document links: %w", err)
+	}
+	return links, nil
+}
+
+// linksSeenFrom labels the links from the perspective of a document: links pointing to it
+// swap their label and inverse label
+func linksSeenFrom(links []DocumentLink, documentID int) []DocumentLink {
+	for i := range links {
+		if links[i].TargetID == documentID && links[i].SourceID != documentID {
+			links[i].Label, links[i].InverseLabel = links[i].InverseLabel, links[i].Label
+		}
+	}
+	return links
+}
+
+// checkDocumentsVisible fails with "document N not found" for the first of the documents
+// that does not exist, is hidden from the user or, unless includeTrashed, is in the trash
+func (s *Service) checkDocumentsVisible(ctx context.Context, documentIDs []int, includeTrashed bool, viewerID int) error {
+	documentCondition := trashedCondition(includeTrashed)
+	visibility, err := s.documentVisibilityCondition(ctx, viewerID)
+	if err != nil {
+		return err
+	}
+	if visibility != "" {
+		documentCondition = fmt.Sprintf("(%s AND %s)", documentCondition, visibility)
+	}
+
+	documentIDs = uniqueTagIDs(documentIDs)
+	placeholders, args := inPlaceholders(documentIDs)
+	rows, err := s.db.QueryContext(ctx, s.rebind(fmt.Sprintf("SELECT d.id FROM documents_document d WHERE d.id IN (%s) AND %s", placeholders, documentCondition)), args...)
+	if err != nil {
+		return fmt.Errorf("failed to query documents: %w", err)
+	}
+	defer rows.Close()
+
+	found := make(map[int]bool, len(documentIDs))
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return fmt.Errorf("failed to scan document: %w", err)
+		}
+		found[id] = true
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read documents: %w", err)
+	}
+	for _, id := range documentIDs {
+		if !found[id] {
+			return fmt.Errorf("document %d not found", id)
+		}
+	}
+	return nil
+}
+
+// normalizeLinkType trims a link type, defaulting to defaultDocumentLinkType
+func normalizeLinkType(linkType string) (string, error) {
+	linkType = strings.TrimSpace(linkType)
+	if linkType == "" {
+		return defaultDocumentLinkType, nil
+	}
+	if len(linkType) > maxDocumentLinkTypeLength {
+		return "", fmt.Errorf("invalid link_type: at most %d characters", maxDocumentLinkTypeLength)
+	}
+	return linkType, nil
+}
+
+// checkDuplicateDocumentLink fails when another link than id (0 for a new link) already
+// relates the documents with the type, in either direction for undirected types
+func (s *Service) checkDuplicateDocumentLink(ctx context.Context, link DocumentLink, directed bool, id int) error {
+	var existingID int
+	query := "SELECT id FROM document_links WHERE link_type = ? AND id <> ? AND ((source_id = ? AND target_id = ?)"
+	args := []interface{}{link.LinkType, id, link.SourceID, link.TargetID}
+	if !directed {
+		query += " OR (source_id = ? AND target_id = ?)"
+		args = append(args, link.TargetID, link.SourceID)
+	}
+	err := s.db.QueryRowContext(ctx, s.rebind(query+")"), args...).Scan(&existingID)
+	if err == nil {
+		return fmt.Errorf("a %q link from document %d to document %d already exists", link.LinkType, link.SourceID, link.TargetID)
+	} else if err != sql.ErrNoRows {
+		return fmt.Errorf("failed to check document link: %w", err)
+	}
+	return nil
+}
+
+// ListDocumentLinks retrieves the links between documents the user can see, optionally only
+// those starting at or pointing to documentID (0 for all), labelled as seen from it, and of
+// one link type
+func (s *Service) ListDocumentLinks(ctx context.Context, documentID int, linkType string, includeTrashed bool, viewerID int) ([]DocumentLink, error) {
+	conditions := []string{"1 = 1"}
+	args := []interface{}{}
+	if documentID != 0 {
+		conditions = append(conditions, "(l.source_id = ? OR l.target_id = ?)")
+		args = append(args, documentID, documentID)
+	}
+	if linkType != "" {
+		conditions = append(conditions, "l.link_type = ?")
+		args = append(args, linkType)
+	}
+	links, err := s.queryDocumentLinks(ctx, includeTrashed, viewerID, strings.Join(conditions, " AND "), args...)
+	if err != nil {
+		return nil, err
+	}
+	return linksSeenFrom(links, documentID), nil
+}
+
+// GetDocumentLink retrieves a link between documents the user can see, also when they are
+// in the trash
+func (s *Service) GetDocumentLink(ctx context.Context, id int, viewerID int) (*DocumentLink, error) {
+	links, err := s.queryDocumentLinks(ctx, true, viewerID, "l.id = ?", id)
+	if err != nil {
+		return nil, err
+	}
+	if len(links) == 0 {
+		return nil, fmt.Errorf("document link not found")
+	}
+	return &links[0], nil
+}
+
+// GetDocumentLinks retrieves the outbound and inbound links of a document the user can see,
+// labelled as seen from the document
+func (s *Service) GetDocumentLinks(ctx context.Context, documentID int, includeTrashed bool, viewerID int) (*DocumentLinksResponse, error) {
+	if err := s.checkDocumentsVisible(ctx, []int{documentID}, includeTrashed, viewerID); err != nil {
+		return nil, err
+	}
+
+	outbound, err := s.queryDocumentLinks(ctx, includeTrashed, viewerID, "l.source_id = ?", documentID)
+	if err != nil {
+		return nil, err
+	}
+	inbound, err := s.queryDocumentLinks(ctx, includeTrashed, viewerID, "l.target_id = ?", documentID)
+	if err != nil {
+		return nil, err
+	}
+	return &DocumentLinksResponse{DocumentID: documentID, Outbound: outbound, Inbound: linksSeenFrom(inbound, documentID)}, nil
+}
+
+// CreateDocumentLink links two documents the user can see. The link type must be defined;
+// links without a type are of type defaultDocumentLinkType.
+func (s *Service) CreateDocumentLink(ctx context.Context, link DocumentLink, userID int) (*DocumentLink, error) {
+	log.Printf("[DocumentLinks] CreateDocumentLink - Document %d -> %d, UserID: %d", link.SourceID, link.TargetID, userID)
+	if link.SourceID <= 0 || link.TargetID <= 0 {
+		return nil, fmt.Errorf("source_id and target_id are required")
+	}
+	if link.SourceID == link.TargetID {
+		return nil, fmt.Errorf("invalid link: a document cannot link to itself")
+	}
+	linkType, err := normalizeLinkType(link.LinkType)
+	if err != nil {
+		return nil, err
+	}
+	link.LinkType = linkType
+	definition, err := s.linkTypeByName(ctx, link.LinkType)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkDocumentsVisible(ctx, []int{link.SourceID, link.TargetID}, false, userID); err != nil {
+		return nil, err
+	}
+	if err := s.checkDuplicateDocumentLink(ctx, link, definition.Directed, 0); err != nil {
+		return nil, err
+	}
+	var createdBy *int
+	if userID > 0 {
+		createdBy = &userID
+	}
+
+	var id int64
+	if s.config.DBEngine == "postgresql" || s.config.DBEngine == "postgres" {
+		err := s.db.QueryRowContext(ctx, `
+			INSERT INTO document_links (source_id, target_id, link_type, note, created_by)
+			VALUES ($1, $2, $3, $4, $5)
+			RETURNING id
+		`, link.SourceID, link.TargetID, link.LinkType, link.Note, createdBy).Scan(&id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create document link: %w", err)
+		}
+	} else {
+		result, err := s.db.ExecContext(ctx, `
+			INSERT INTO document_links (source_id, target_id, link_type, note, created_by)
+			VALUES (?, ?, ?, ?, ?)
+		`, link.SourceID, link.TargetID, link.LinkType, link.Note, createdBy)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create document link: %w", err)
+		}
+		if id, err = result.LastInsertId(); err != nil {
+			return nil, fmt.Errorf("failed to get document link ID: %w", err)
+		}
+	}
+
+	created, err := s.GetDocumentLink(ctx, int(id), userID)
+	if err != nil {
+		return nil, err
+	}
+	data := map[string]interface{}{"link_id": int(id), "target_id": link.TargetID, "link_type": link.LinkType}
+	if created.TargetTitle != "" {
+		data["target_title"] = created.TargetTitle
+	}
+	if _, err := s.recordActivity(ctx, EventDocumentLinked, &link.SourceID, nil, created.SourceTitle, data); err != nil {
+		log.Printf("[DocumentLinks] Failed to record link %d in the activity feed: %v", id, err)
+	}
+	return created, nil
+}
+
+// UpdateDocumentLink changes the type and note of a link. An empty link type keeps the
+// current one; the note is replaced, so leaving it out clears it. The linked documents
+// cannot change.
+func (s *Service) UpdateDocumentLink(ctx context.Context, id int, link DocumentLink, userID int) (*DocumentLink, error) {
+	log.Printf("[DocumentLinks] UpdateDocumentLink - ID: %d, UserID: %d", id, userID)
+	existing, err := s.GetDocumentLink(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if (link.SourceID != 0 && link.SourceID != existing.SourceID) || (link.TargetID != 0 && link.TargetID != existing.TargetID) {
+		return nil, fmt.Errorf("invalid link: source_id and target_id cannot be changed")
+	}
+	link.SourceID = existing.SourceID
+	link.TargetID = existing.TargetID
+	if strings.TrimSpace(link.LinkType) == "" {
+		link.LinkType = existing.LinkType
+	}
+	if link.LinkType, err = normalizeLinkType(link.LinkType); err != nil {
+		return nil, err
+	}
+	definition, err := s.linkTypeByName(ctx, link.LinkType)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkDuplicateDocumentLink(ctx, link, definition.Directed, id); err != nil {
+		return nil, err
+	}
+
+	_, err = s.db.ExecContext(ctx, s.rebind(`
+		UPDATE document_links
+		SET link_type = ?, note = ?, modified = CURRENT_TIMESTAMP
+		WHERE id = ?
+	`), link.LinkType, link.Note, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update document link: %w", err)
+	}
+
+	return s.GetDocumentLink(ctx, id, userID)
+}
+
+// DeleteDocumentLink removes a link between documents the user can see
+func (s *Service) DeleteDocumentLink(ctx context.Context, id int, userID int) error {
+	log.Printf("[DocumentLinks] DeleteDocumentLink - ID: %d, UserID: %d", id, userID)
+	if _, err := s.GetDocumentLink(ctx, id, userID); err != nil {
+		return err
+	}
+	if _, err := s.db.ExecContext(ctx, s.rebind("DELETE FROM document_links WHERE id = ?"), id); err != nil {
+		return fmt.Errorf("failed to delete document link: %w", err)
+	}
+	return nil
+}
+
+// documentLinkErrorStatus maps document link errors to HTTP status codes
+func documentLinkErrorStatus(err error) int {
+	switch {
+	case strings.HasPrefix(err.Error(), "invalid"), strings.Contains(err.Error(), "required"):
+		return http.StatusBadRequest
+	case strings.Contains(err.Error(), "not found"):
+		return http.StatusNotFound
+	case strings.Contains(err.Error(), "already exists"), strings.Contains(err.Error(), "still in use"):
+		return http.StatusConflict
+	}
+	return queryErrorStatus(err)
+}
+
+// HTTP Handlers for document links
+
+func (s *Service) handleListDocumentLinks(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	log.Printf("[DocumentLinks] GET /api/document-links/ - Request from %s", r.RemoteAddr)
+
+	viewerID, err := viewerFromRequest(r)
+	if err != nil {
+		respondError(w, viewerErrorStatus(err), err.Error())
+		return
+	}
+
+	documentID := 0
+	if value := r.URL.Query().Get("document_id"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			respondError(w, http.StatusBadRequest, "Invalid document_id")
+			return
+		}
+		documentID = parsed
+	}
+
+	links, err := s.ListDocumentLinks(ctx, documentID, strings.TrimSpace(r.URL.Query().Get("link_type")), wantsTrashed(r), viewerID)
+	if err != nil {
+		log.Printf("[DocumentLinks] Error listing links: %v", err)
+		respondError(w, queryErrorStatus(err), err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, DocumentLinkListResponse{Count: len(links), Results: links})
+}
+
+func (s *Service) handleGetDocumentLink(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	idStr := mux.Vars(r)["id"]
+	log.Printf("[DocumentLinks] GET /api/document-links/%s/ - Request from %s", idStr, r.RemoteAddr)
+
+	viewerID, err := viewerFromRequest(r)
+	if err != nil {
+		respondError(w, viewerErrorStatus(err), err.Error())
+		return
+	}
+
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid link ID")
+		return
+	}
+
+	link, err := s.GetDocumentLink(ctx, id, viewerID)
+	if err != nil {
+		respondError(w, documentLinkErrorStatus(err), err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, link)
+}
+
+func (s *Service) handleCreateDocumentLink(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	log.Printf("[DocumentLinks] POST /api/document-links/ - Request from %s", r.RemoteAddr)
+
+	viewerID, err := viewerFromRequest(r)
+	if err != nil {
+		respondError(w, viewerErrorStatus(err), err.Error())
+		return
+	}
+
+	var link DocumentLink
+	if err := json.NewDecoder(r.Body).Decode(&link); err != nil {
+		log.Printf("[DocumentLinks] Error decoding request body: %v", err)
+		respondError(w, http.StatusBadRequest, fmt.Sprintf("Invalid request body: %v", err))
+		return
+	}
+
+	created, err := s.CreateDocumentLink(ctx, link, viewerID)
+	if err != nil {
+		log.Printf("[DocumentLinks] Error creating link: %v", err)
+		respondError(w, documentLinkErrorStatus(err), err.Error())
+		return
+	}
+
+	log.Printf("[DocumentLinks] Successfully created link %d: document %d -> %d", *created.ID, created.SourceID, created.TargetID)
+	respondJSON(w, http.StatusCreated, created)
+}
+
+func (s *Service) handleUpdateDocumentLink(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	idStr := mux.Vars(r)["id"]
+	log.Printf("[DocumentLinks] PUT /api/document-links/%s/ - Request from %s", idStr, r.RemoteAddr)
+
+	viewerID, err := viewerFromRequest(r)
+	if err != nil {
+		respondError(w, viewerErrorStatus(err), err.Error())
+		return
+	}
+
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid link ID")
+		return
+	}
+
+	var link DocumentLink
+	if err := json.NewDecoder(r.Body).Decode(&link); err != nil {
+		log.Printf("[DocumentLinks] Error decoding request body: %v", err)
+		respondError(w, http.StatusBadRequest, fmt.Sprintf("Invalid request body: %v", err))
+		return
+	}
+
+	updated, err := s.UpdateDocumentLink(ctx, id, link, viewerID)
+	if err != nil {
+		log.Printf("[DocumentLinks] Error updating link %d: %v", id, err)
+		respondError(w, documentLinkErrorStatus(err), err.Error())
+		return
+	}
+
+	log.Printf("[DocumentLinks] Successfully updated link %d", id)
+	respondJSON(w, http.StatusOK, updated)
+}
+
+func (s *Service) handleDeleteDocumentLink(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	idStr := mux.Vars(r)["id"]
+	log.Printf("[DocumentLinks] DELETE /api/document-links/%s/ - Request from %s", idStr, r.RemoteAddr)
+
+	viewerID, err := viewerFromRequest(r)
+	if err != nil {
+		respondError(w, viewerErrorStatus(err), err.Error())
+		return
+	}
+
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid link ID")
+		return
+	}
+
+	if err := s.DeleteDocumentLink(ctx, id, viewerID); err != nil {
+		log.Printf("[DocumentLinks] Error deleting link %d: %v", id, err)
+		respondError(w, documentLinkErrorStatus(err), err.Error())
+		return
+	}
+
+	log.Printf("[DocumentLinks] Successfully deleted link %d", id)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Service) handleGetDocumentLinks(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	idStr := mux.Vars(r)["id"]
+	log.Printf("[DocumentLinks] GET /api/documents/%s/links/ - Request from %s", idStr, r.RemoteAddr)
+
+	viewerID, err := viewerFromRequest(r)
+	if err != nil {
+		respondError(w, viewerErrorStatus(err), err.Error())
+		return
+	}
+
+	documentID, err := strconv.Atoi(idStr)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid document ID")
+		return
+	}
+
+	links, err := s.GetDocumentLinks(ctx, documentID, wantsTrashed(r), viewerID)
+	if err != nil {
+		log.Printf("[DocumentLinks] Error getting links of document %d: %v", documentID, err)
+		respondError(w, documentLinkErrorStatus(err), err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, links)
+}
@@ -0,0 +1,402 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// maxDocumentNoteLength caps the body of a note
+const maxDocumentNoteLength = 10000
+
+// queryDocumentNotes retrieves the notes of a document selected by condition, oldest first
+func (s *Service) queryDocumentNotes(ctx context.Context, documentID int, condition string, args ...interface{}) ([]DocumentNote, error) {
+	rows, err := s.db.QueryContext(ctx, s.rebind(`
+		SELECT id, document_id, parent_id, body, author_id, username, created, modified
+		FROM document_notes
+		WHERE document_id = ? AND `+condition+`
+		ORDER BY created ASC, id ASC
+	`), append([]interface{}{documentID}, args...)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query document notes: %w", err)
+	}
+	defer rows.Close()
+
+	notes := []DocumentNote{}
+	for rows.Next() {
+		var note DocumentNote
+		var id int
+		var parentID, authorID sql.NullInt64
+		var username sql.NullString
+		var created, modified dbTimestamp
+		if err := rows.Scan(&id, &note.DocumentID, &parentID, &note.Body, &authorID, &username, &created, &modified); err != nil {
+			return nil, fmt.Errorf("failed to scan document note: %w", err)
+		}
+		note.ID = &id
+		if parentID.Valid {
+			parent := int(parentID.Int64)
+			note.ParentID = &parent
+		}
+		if authorID.Valid {
+			author := int(authorID.Int64)
+			note.AuthorID = &author
+		}
+		if username.Valid {
+			note.Username = &username.String
+		}
+		note.Created = s.formatTimestamp(created)
+		note.Modified = s.formatTimestamp(modified)
+		notes = append(notes, note)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read document notes: %w", err)
+	}
+	return notes, nil
+}
+
+// noteThreads nests replies under their parents. Replies whose parent is gone are kept as
+// threads of their own.
+func noteThreads(notes []DocumentNote) []DocumentNote {
+	children := make(map[int][]DocumentNote)
+	known := make(map[int]bool, len(notes))
+	for _, note := range notes {
+		known[*note.ID] = true
+	}
+	roots := []DocumentNote{}
+	for _, note := range notes {
+		if note.ParentID != nil && known[*note.ParentID] {
+			children[*note.ParentID] = append(children[*note.ParentID], note)
+		} else {
+			roots = append(roots, note)
+		}
+	}
+
+	var attach func(note DocumentNote) DocumentNote
+	attach = func(note DocumentNote) DocumentNote {
+		for _, reply := range children[*note.ID] {
+			note.Replies = append(note.Replies, attach(reply))
+		}
+		return note
+	}
+	for i := range roots {
+		roots[i] = attach(roots[i])
+	}
+	return roots
+}
+
+// ListDocumentNotes retrieves the notes of a document the viewer can see as threads
+func (s *Service) ListDocumentNotes(ctx context.Context, documentID int, includeTrashed bool, viewerID int) (*DocumentNoteListResponse, error) {
+	if err := s.checkDocumentsVisible(ctx, []int{documentID}, includeTrashed, viewerID); err != nil {
+		return nil, err
+	}
+	notes, err := s.queryDocumentNotes(ctx, documentID, "1 = 1")
+	if err != nil {
+		return nil, err
+	}
+	return &DocumentNoteListResponse{Count: len(notes), Results: noteThreads(notes)}, nil
+}
+
+// GetDocumentNote retrieves a note of a document
+func (s *Service) GetDocumentNote(ctx context.Context, documentID int, id int) (*DocumentNote, error) {
+	notes, err := s.queryDocumentNotes(ctx, documentID, "id = ?", id)
+	if err != nil {
+		return nil, err
+	}
+	if len(notes) == 0 {
+		return nil, fmt.Errorf("note with id %d not found", id)
+	}
+	return &notes[0], nil
+}
+
+// checkDocumentNoteBody trims and validates the Markdown body of a note
+func checkDocumentNoteBody(body string) (string, error) {
+	body = strings.TrimSpace(body)
+	if body == "" {
+		return "", fmt.Errorf("body is required")
+	}
+	if len(body) > maxDocumentNoteLength {
+		return "", fmt.Errorf("invalid body: at most %d characters", maxDocumentNoteLength)
+	}
+	return body, nil
+}
+
+// CreateDocumentNote adds a note by the user to a document the viewer can see, as a reply
+// when it has a parent_id
+func (s *Service) CreateDocumentNote(ctx context.Context, documentID int, note DocumentNote, userID int, username string, viewerID int) (*DocumentNote, error) {
+	log.Printf("[DocumentNotes] CreateDocumentNote - Document: %d, UserID: %d", documentID, userID)
+	body, err := checkDocumentNoteBody(note.Body)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkDocumentsVisible(ctx, []int{documentID}, false, viewerID); err != nil {
+		return nil, err
+	}
+	if note.ParentID != nil {
+		if _, err := s.GetDocumentNote(ctx, documentID, *note.ParentID); err != nil {
+			return nil, fmt.Errorf("invalid parent_id: note %d not found on document %d", *note.ParentID, documentID)
+		}
+	}
+
+	var id int64
+	if s.config.DBEngine == "postgresql" || s.config.DBEngine == "postgres" {
+		err := s.db.QueryRowContext(ctx, `
+			INSERT INTO document_notes (document_id, parent_id, body, author_id, username)
+			VALUES ($1, $2, $3, $4, $5)
+			RETURNING id
+		`, documentID, note.ParentID, body, userID, username).Scan(&id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create document note: %w", err)
+		}
+	} else {
+		result, err := s.db.ExecContext(ctx, `
+			INSERT INTO document_notes (document_id, parent_id, body, author_id, username)
+			VALUES (?, ?, ?, ?, ?)
+		`, documentID, note.ParentID, body, userID, username)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create document note: %w", err)
+		}
+		if id, err = result.LastInsertId(); err != nil {
+			return nil, fmt.Errorf("failed to get document note ID: %w", err)
+		}
+	}
+
+	return s.GetDocumentNote(ctx, documentID, int(id))
+}
+
+// UpdateDocumentNote replaces the body of a note; only its author can edit it
+func (s *Service) UpdateDocumentNote(ctx context.Context, documentID int, id int, note DocumentNote, userID int, viewerID int) (*DocumentNote, error) {
+	log.Printf("[DocumentNotes] UpdateDocumentNote - Document: %d, ID: %d, UserID: %d", documentID, id, userID)
+	body, err := checkDocumentNoteBody(note.Body)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkDocumentsVisible(ctx, []int{documentID}, false, viewerID); err != nil {
+		return nil, err
+	}
+	existing, err := s.GetDocumentNote(ctx, documentID, id)
+	if err != nil {
+		return nil, err
+	}
+	if existing.AuthorID == nil || *existing.AuthorID != userID {
+		return nil, fmt.Errorf("permission denied: only the author can edit note %d", id)
+	}
+
+	query := s.rebind("UPDATE document_notes SET body = ?, modified = CURRENT_TIMESTAMP WHERE id = ?")
+	if _, err := s.db.ExecContext(ctx, query, body, id); err != nil {
+		return nil, fmt.Errorf("failed to update document note: %w", err)
+	}
+	return s.GetDocumentNote(ctx, documentID, id)
+}
+
+// DeleteDocumentNote deletes a note together with its replies. The author and superusers
+// can delete a note.
+func (s *Service) DeleteDocumentNote(ctx context.Context, documentID int, id int, userID int, viewerID int) error {
+	log.Printf("[DocumentNotes] DeleteDocumentNote - Document: %d, ID: %d, UserID: %d", documentID, id, userID)
+	if err := s.checkDocumentsVisible(ctx, []int{documentID}, true, viewerID); err != nil {
+		return err
+	}
+	notes, err := s.queryDocumentNotes(ctx, documentID, "1 = 1")
+	if err != nil {
+		return err
+	}
+	var existing *DocumentNote
+	for i := range notes {
+		if *notes[i].ID == id {
+			existing = &notes[i]
+		}
+	}
+	if existing == nil {
+		return fmt.Errorf("note with id %d not found", id)
+	}
+	if existing.AuthorID == nil || *existing.AuthorID != userID {
+		superuser, err := s.isSuperuser(ctx, userID)
+		if err != nil {
+			return err
+		}
+		if !superuser {
+			return fmt.Errorf("permission denied: only the author can delete note %d", id)
+		}
+	}
+
+	replies := make(map[int][]int)
+	for _, note := range notes {
+		if note.ParentID != nil {
+			replies[*note.ParentID] = append(replies[*note.ParentID], *note.ID)
+		}
+	}
+	ids := []int{id}
+	for i := 0; i < len(ids); i++ {
+		ids = append(ids, replies[ids[i]]...)
+	}
+
+	placeholders, args := inPlaceholders(ids)
+	if _, err := s.db.ExecContext(ctx, s.rebind(fmt.Sprintf("DELETE FROM document_notes WHERE id IN (%s)", placeholders)), args...); err != nil {
+		return fmt.Errorf("failed to delete document note: %w", err)
+	}
+	return nil
+}
+
+// documentNoteErrorStatus maps document note errors to HTTP status codes
+func documentNoteErrorStatus(err error) int {
+	switch {
+	case strings.HasPrefix(err.Error(), "invalid"), strings.Contains(err.Error(), "required"):
+		return http.StatusBadRequest
+	case strings.HasPrefix(err.Error(), "permission denied"):
+		return http.StatusForbidden
+	case strings.Contains(err.Error(), "not found"):
+		return http.StatusNotFound
+	}
+	return queryErrorStatus(err)
+}
+
+// documentNoteRequest reads the document ID, the note ID when withNote is set, and the user
+// of a note request. It answers the request itself when it returns false.
+func documentNoteRequest(w http.ResponseWriter, r *http.Request, withNote bool) (int, int, int, bool) {
+	vars := mux.Vars(r)
+	documentID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid document ID")
+		return 0, 0, 0, false
+	}
+	noteID := 0
+	if withNote {
+		if noteID, err = strconv.Atoi(vars["noteId"]); err != nil {
+			respondError(w, http.StatusBadRequest, "Invalid note ID")
+			return 0, 0, 0, false
+		}
+	}
+	userID, err := getUserIDFromRequest(r)
+	if err != nil {
+		respondError(w, viewerErrorStatus(err), err.Error())
+		return 0, 0, 0, false
+	}
+	return documentID, noteID, *userID, true
+}
+
+// HTTP Handlers for document notes
+
+func (s *Service) handleListDocumentNotes(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	log.Printf("[DocumentNotes] GET /api/documents/%s/notes/ - Request from %s", mux.Vars(r)["id"], r.RemoteAddr)
+
+	viewerID, err := viewerFromRequest(r)
+	if err != nil {
+		respondError(w, viewerErrorStatus(err), err.Error())
+		return
+	}
+
+	documentID, _, _, ok := documentNoteRequest(w, r, false)
+	if !ok {
+		return
+	}
+
+	notes, err := s.ListDocumentNotes(ctx, documentID, wantsTrashed(r), viewerID)
+	if err != nil {
+		log.Printf("[DocumentNotes] Error listing notes of document %d: %v", documentID, err)
+		respondError(w, documentNoteErrorStatus(err), err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, notes)
+}
+
+func (s *Service) handleCreateDocumentNote(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	log.Printf("[DocumentNotes] POST /api/documents/%s/notes/ - Request from %s", mux.Vars(r)["id"], r.RemoteAddr)
+
+	viewerID, err := viewerFromRequest(r)
+	if err != nil {
+		respondError(w, viewerErrorStatus(err), err.Error())
+		return
+	}
+
+	documentID, _, userID, ok := documentNoteRequest(w, r, false)
+	if !ok {
+		return
+	}
+	var note DocumentNote
+	if err := json.NewDecoder(r.Body).Decode(&note); err != nil {
+		respondError(w, http.StatusBadRequest, fmt.Sprintf("Invalid request body: %v", err))
+		return
+	}
+
+	created, err := s.CreateDocumentNote(ctx, documentID, note, userID, *getUsernameFromRequest(r), viewerID)
+	if err != nil {
+		log.Printf("[DocumentNotes] Error creating note on document %d: %v", documentID, err)
+		respondError(w, documentNoteErrorStatus(err), err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, created)
+}
+
+func (s *Service) handleUpdateDocumentNote(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	vars := mux.Vars(r)
+	log.Printf("[DocumentNotes] %s /api/documents/%s/notes/%s/ - Request from %s", r.Method, vars["id"], vars["noteId"], r.RemoteAddr)
+
+	viewerID, err := viewerFromRequest(r)
+	if err != nil {
+		respondError(w, viewerErrorStatus(err), err.Error())
+		return
+	}
+
+	documentID, noteID, userID, ok := documentNoteRequest(w, r, true)
+	if !ok {
+		return
+	}
+	var note DocumentNote
+	if err := json.NewDecoder(r.Body).Decode(&note); err != nil {
+		respondError(w, http.StatusBadRequest, fmt.Sprintf("Invalid request body: %v", err))
+		return
+	}
+
+	updated, err := s.UpdateDocumentNote(ctx, documentID, noteID, note, userID, viewerID)
+	if err != nil {
+		log.Printf("[DocumentNotes] Error updating note %d: %v", noteID, err)
+		respondError(w, documentNoteErrorStatus(err), err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, updated)
+}
+
+func (s *Service) handleDeleteDocumentNote(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	vars := mux.Vars(r)
+	log.Printf("[DocumentNotes] DELETE /api/documents/%s/notes/%s/ - Request from %s", vars["id"], vars["noteId"], r.RemoteAddr)
+
+	viewerID, err := viewerFromRequest(r)
+	if err != nil {
+		respondError(w, viewerErrorStatus(err), err.Error())
+		return
+	}
+
+	documentID, noteID, userID, ok := documentNoteRequest(w, r, true)
+	if !ok {
+		return
+	}
+
+	if err := s.DeleteDocumentNote(ctx, documentID, noteID, userID, viewerID); err != nil {
+		log.Printf("[DocumentNotes] Error deleting note %d: %v", noteID, err)
+		respondError(w, documentNoteErrorStatus(err), err.Error())
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// DocumentSummary holds the headline document counts for a filter set
+type DocumentSummary struct {
+	TotalDocuments   int                        `json:"total_documents"`   // Matching documents not in the trash
+	InboxDocuments   int                        `json:"inbox_documents"`   // Of those, documents in the inbox
+	TrashedDocuments int                        `json:"trashed_documents"` // Matching documents in the trash
+	Owners           []BuiltinFilterValueOption `json:"owners"`            // Matching documents per owner, not in the trash
+}
+
+// GetDocumentSummary counts the documents matching the filter rules in a single query
+// grouped by owner: totals, inbox and trash counts are summed up from the owner rows
+func (s *Service) GetDocumentSummary(ctx context.Context, filterRulesJSON string) (*DocumentSummary, error) {
+	docFilterWhere, docFilterArgs, err := s.buildDocumentFilterQuery(ctx, filterRulesJSON, 0, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build filter query: %w", err)
+	}
+
+	filterCondition := "1 = 1"
+	args := []interface{}{}
+	if docFilterWhere != "" {
+		filterCondition = strings.Replace(docFilterWhere, "WHERE ", "", 1)
+		args = docFilterArgs
+	}
+
+	query := fmt.Sprintf(`
+		SELECT d.owner_id, u.username, u.first_name, u.last_name,
+			SUM(CASE WHEN d.deleted_at IS NULL THEN 1 ELSE 0 END) as doc_count,
+			SUM(CASE WHEN d.deleted_at IS NULL AND %s THEN 1 ELSE 0 END) as inbox_count,
+			SUM(CASE WHEN d.deleted_at IS NOT NULL THEN 1 ELSE 0 END) as trash_count
+		FROM documents_document d
+		LEFT JOIN auth_user u ON u.id = d.owner_id
+		WHERE %s
+		GROUP BY d.owner_id, u.username, u.first_name, u.last_name
+		ORDER BY doc_count DESC, u.username ASC
+	`, inboxTaggedCondition, filterCondition)
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query document summary: %w", err)
+	}
+	defer rows.Close()
+
+	summary := &DocumentSummary{Owners: []BuiltinFilterValueOption{}}
+	for rows.Next() {
+		var ownerID sql.NullInt64
+		var username, firstName, lastName sql.NullString
+		var count, inboxCount, trashCount int
+		if err := rows.Scan(&ownerID, &username, &firstName, &lastName, &count, &inboxCount, &trashCount); err != nil {
+			continue
+		}
+
+		summary.TotalDocuments += count
+		summary.InboxDocuments += inboxCount
+		summary.TrashedDocuments += trashCount
+
+		// Owners with only trashed documents are not listed
+		if count == 0 {
+			continue
+		}
+		if !ownerID.Valid {
+			summary.Owners = append(summary.Owners, BuiltinFilterValueOption{ID: nil, Label: noOwnerLabel, Count: count})
+			continue
+		}
+		label := username.String
+		if !username.Valid {
+			label = strconv.FormatInt(ownerID.Int64, 10)
+		}
+		summary.Owners = append(summary.Owners, BuiltinFilterValueOption{
+			ID:        ownerID.Int64,
+			Label:     label,
+			Count:     count,
+			Username:  username.String,
+			FirstName: firstName.String,
+			LastName:  lastName.String,
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read document summary: %w", err)
+	}
+
+	return summary, nil
+}
+
+// HTTP Handler for the document count summary
+func (s *Service) handleGetDocumentSummary(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	log.Printf("[DocumentSummary] POST /api/builtin-filter-values/summary/ - Request from %s", r.RemoteAddr)
+
+	var body map[string]interface{}
+	if r.Body != nil {
+		json.NewDecoder(r.Body).Decode(&body)
+	}
+	body, err := s.applyFilterPreset(ctx, r, body)
+	if err != nil {
+		respondError(w, filterPresetErrorStatus(err), err.Error())
+		return
+	}
+	filterRulesJSON := filterRulesFromBody(body)
+
+	summary, err := s.GetDocumentSummary(ctx, filterRulesJSON)
+	if err != nil {
+		respondError(w, queryErrorStatus(err), err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, summary)
+}
@@ -0,0 +1,274 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultDocumentsOverTimePeriods = 12
+	maxDocumentsOverTimePeriods     = 120
+)
+
+// DocumentsOverTimeSeries is the number of documents with a value per period; Total is
+// their number over all periods
+type DocumentsOverTimeSeries struct {
+	ID     string `json:"id"`
+	Label  string `json:"label"`
+	Total  int    `json:"total"`
+	Counts []int  `json:"counts"`
+}
+
+// DocumentsOverTimeResponse counts documents per period of documents_document.created, in
+// total and per value of the group_by dimension. Periods are labelled "YYYY-MM", "YYYY-Qn"
+// or "YYYY", oldest first.
+type DocumentsOverTimeResponse struct {
+	GroupBy  string                    `json:"group_by,omitempty"`
+	FieldID  *int                      `json:"field_id,omitempty"`
+	Name     string                    `json:"name,omitempty"` // Name of the dimension or custom field
+	Interval string                    `json:"interval"`
+	Periods  []string                  `json:"periods"`
+	Counts   []int                     `json:"counts"` // All documents per period
+	Series   []DocumentsOverTimeSeries `json:"series"`
+}
+
+// overTimePeriodLabel returns the label of the period of interval containing t
+func overTimePeriodLabel(t time.Time, interval string) string {
+	switch interval {
+	case "quarter":
+		return fmt.Sprintf("%d-Q%d", t.Year(), (int(t.Month())-1)/3+1)
+	case "year":
+		return t.Format("2006")
+	}
+	return t.Format("2006-01")
+}
+
+// overTimePeriods returns the labels of the given number of periods of interval ending with
+// the period of now, oldest first, the month labels ("YYYY-MM") of the period they belong to
+// and the first and the day after the last day of the periods
+func overTimePeriods(now time.Time, interval string, periods int) ([]string, map[string]int, time.Time, time.Time) {
+	now = now.UTC()
+	months := map[string]int{"month": 1, "quarter": 3, "year": 12}[interval]
+	first := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	switch interval {
+	case "quarter":
+		first = time.Date(now.Year(), now.Month()-(now.Month()-1)%3, 1, 0, 0, 0, 0, time.UTC)
+	case "year":
+		first = time.Date(now.Year(), time.January, 1, 0, 0, 0, 0, time.UTC)
+	}
+	start := first.AddDate(0, -months*(periods-1), 0)
+	end := first.AddDate(0, months, 0)
+
+	labels := make([]string, periods)
+	positions := make(map[string]int, periods*months)
+	for i := range labels {
+		period := start.AddDate(0, i*months, 0)
+		labels[i] = overTimePeriodLabel(period, interval)
+		for m := 0; m < months; m++ {
+			positions[period.AddDate(0, m, 0).Format("2006-01")] = i
+		}
+	}
+	return labels, positions, start, end
+}
+
+// GetDocumentsOverTime counts the documents created in each of the given number of periods
+// up to the one of now, optionally per value of a dimension ("correspondent",
+// "document_type", "storage_path", "tag", "owner" or a custom field, like the axes of the
+// co-occurrence matrix). Documents are counted like in the other counts: trashed ones only
+// when includeTrashed is set and, for a viewerID other than 0, only those visible to that
+// user. With includeBlank the documents without a value form a "__blank__" series.
+func (s *Service) GetDocumentsOverTime(ctx context.Context, axis CooccurrenceAxis, interval string, periods int, includeTrashed bool, includeBlank bool, viewerID int, now time.Time) (*DocumentsOverTimeResponse, error) {
+	labels, positions, start, end := overTimePeriods(now, interval, periods)
+	where := "WHERE d.created >= ? AND d.created < ?"
+	visibility, err := s.documentVisibilityCondition(ctx, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	if visibility != "" {
+		where += " AND " + visibility
+	}
+	where = s.rebind(where)
+	args := []interface{}{start.Format("2006-01-02"), end.Format("2006-01-02")}
+
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf(`
+		SELECT d.id, %s
+		FROM documents_document d
+		%s AND %s
+	`, dateBucketExpression(s.config.DBEngine, "month", "d.created"), where, trashedCondition(includeTrashed)), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query documents: %w", err)
+	}
+	defer rows.Close()
+
+	response := &DocumentsOverTimeResponse{
+		GroupBy:  axis.Dimension,
+		FieldID:  axis.FieldID,
+		Interval: interval,
+		Periods:  labels,
+		Counts:   make([]int, periods),
+		Series:   []DocumentsOverTimeSeries{},
+	}
+	documentPeriods := make(map[int]int)
+	for rows.Next() {
+		var id int
+		var month sql.NullString
+		if err := rows.Scan(&id, &month); err != nil {
+			return nil, fmt.Errorf("failed to scan document: %w", err)
+		}
+		position, ok := positions[month.String]
+		if !month.Valid || !ok {
+			continue
+		}
+		documentPeriods[id] = position
+		response.Counts[position]++
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read documents: %w", err)
+	}
+
+	if axis.FieldID == nil && axis.Dimension == "" {
+		return response, nil
+	}
+	name, documentValues, err := s.loadCooccurrenceAxis(ctx, axis, where, args, includeTrashed)
+	if err != nil {
+		return nil, err
+	}
+	response.Name = name
+
+	series := make(map[string]*DocumentsOverTimeSeries)
+	add := func(id string, label string, position int) {
+		if series[id] == nil {
+			series[id] = &DocumentsOverTimeSeries{ID: id, Label: label, Counts: make([]int, periods)}
+		}
+		series[id].Total++
+		series[id].Counts[position]++
+	}
+	for documentID, position := range documentPeriods {
+		values := documentValues[documentID]
+		if len(values) == 0 && includeBlank {
+			add("__blank__", "(Blank)", position)
+		}
+		seen := make(map[string]bool, len(values))
+		for _, value := range values {
+			if !seen[value.id] {
+				seen[value.id] = true
+				add(value.id, value.label, position)
+			}
+		}
+	}
+	for _, entry := range series {
+		response.Series = append(response.Series, *entry)
+	}
+	sort.Slice(response.Series, func(i, j int) bool {
+		a, b := response.Series[i], response.Series[j]
+		if a.Total != b.Total {
+			return a.Total > b.Total
+		}
+		return a.Label < b.Label
+	})
+	return response, nil
+}
+
+// getDocumentsOverTimeCached wraps GetDocumentsOverTime with the builtin filter value cache.
+// The periods follow the current date, so entries only live for the cache TTL.
+func (s *Service) getDocumentsOverTimeCached(ctx context.Context, axis CooccurrenceAxis, interval string, periods int, includeTrashed bool, includeBlank bool, viewerID int, bypass bool) (*DocumentsOverTimeResponse, bool, error) {
+	fieldID := 0
+	if axis.FieldID != nil {
+		fieldID = *axis.FieldID
+	}
+	key := fmt.Sprintf("documents_over_time|%s|%d|%s|%d|%t|%t|%d", axis.Dimension, fieldID, interval, periods, includeTrashed, includeBlank, viewerID)
+	if !bypass {
+		var cached *DocumentsOverTimeResponse
+		if s.builtinCache.Get(key, &cached) {
+			return cached, true, nil
+		}
+	}
+
+	result, err := s.shareQuery(ctx, key, func(ctx context.Context) (interface{}, error) {
+		stats, err := s.GetDocumentsOverTime(ctx, axis, interval, periods, includeTrashed, includeBlank, viewerID, time.Now())
+		if err != nil {
+			return nil, err
+		}
+		s.builtinCache.Set(key, stats)
+		return stats, nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return result.(*DocumentsOverTimeResponse), false, nil
+}
+
+// HTTP Handler for document counts over time
+func (s *Service) handleGetDocumentsOverTime(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	log.Printf("[Stats] GET /api/stats/documents-over-time/ - Request from %s", r.RemoteAddr)
+
+	viewerID, err := viewerFromRequest(r)
+	if err != nil {
+		respondError(w, viewerErrorStatus(err), err.Error())
+		return
+	}
+
+	params := r.URL.Query()
+	var axis CooccurrenceAxis
+	switch groupBy := params.Get("group_by"); groupBy {
+	case "":
+	case "custom_field":
+		fieldID, err := strconv.Atoi(strings.TrimSpace(params.Get("field_id")))
+		if err != nil || fieldID <= 0 {
+			respondError(w, http.StatusBadRequest, "Invalid field_id, required to group by custom_field")
+			return
+		}
+		axis.FieldID = &fieldID
+	case "correspondent", "document_type", "storage_path", "tag", "owner":
+		axis.Dimension = groupBy
+	case "type":
+		axis.Dimension = "document_type"
+	default:
+		respondError(w, http.StatusBadRequest, "Invalid group_by, expected correspondent, document_type, storage_path, tag, owner or custom_field")
+		return
+	}
+	interval := params.Get("interval")
+	switch interval {
+	case "":
+		interval = "month"
+	case "month", "quarter", "year":
+	default:
+		respondError(w, http.StatusBadRequest, "Invalid interval, expected month, quarter or year")
+		return
+	}
+	periods := defaultDocumentsOverTimePeriods
+	if value := params.Get("periods"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > maxDocumentsOverTimePeriods {
+			respondError(w, http.StatusBadRequest, fmt.Sprintf("Invalid periods, expected 1 to %d", maxDocumentsOverTimePeriods))
+			return
+		}
+		periods = parsed
+	}
+
+	includeBlank := params.Get("include_blank") == "true" || params.Get("include_blank") == "1"
+
+	stats, hit, err := s.getDocumentsOverTimeCached(ctx, axis, interval, periods, wantsTrashed(r), includeBlank, viewerID, bypassCache(r))
+	if err != nil {
+		log.Printf("[Stats] Error counting documents over time: %v", err)
+		if strings.Contains(err.Error(), "not found") {
+			respondError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		respondError(w, queryErrorStatus(err), err.Error())
+		return
+	}
+	setCacheHeader(w, hit)
+
+	respondJSON(w, http.StatusOK, stats)
+}
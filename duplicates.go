@@ -0,0 +1,252 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// Reasons documents are reported as probable duplicates
+const (
+	DuplicateByChecksum = "checksum" // Same original file checksum
+	DuplicateByASN      = "asn"      // Same archive serial number
+	DuplicateByTitle    = "title"    // Same title (ignoring case), correspondent and created date
+)
+
+// duplicateReasons are the supported duplicate reasons in report order
+var duplicateReasons = []string{DuplicateByChecksum, DuplicateByASN, DuplicateByTitle}
+
+// duplicateKeyExpressions are the SQL expressions documents are grouped by per reason
+var duplicateKeyExpressions = map[string][]string{
+	DuplicateByChecksum: {"d.checksum"},
+	DuplicateByASN:      {"d.archive_serial_number"},
+	DuplicateByTitle:    {"LOWER(TRIM(d.title))", "COALESCE(d.correspondent_id, 0)", "d.created"},
+}
+
+// duplicateKeyConditions select the documents with a key per reason; a missing correspondent
+// is part of the title key
+var duplicateKeyConditions = map[string]string{
+	DuplicateByChecksum: "d.checksum IS NOT NULL AND d.checksum <> ''",
+	DuplicateByASN:      "d.archive_serial_number IS NOT NULL",
+	DuplicateByTitle:    "d.title IS NOT NULL AND TRIM(d.title) <> '' AND d.created IS NOT NULL",
+}
+
+// DuplicateDocument is a document of a duplicate group
+type DuplicateDocument struct {
+	ID              int     `json:"id"`
+	Title           string  `json:"title"`
+	CorrespondentID *int    `json:"correspondent_id"`
+	Created         *string `json:"created"`
+	Added           *string `json:"added"`
+}
+
+// DuplicateGroup is a set of documents sharing the key of a duplicate reason, oldest first
+type DuplicateGroup struct {
+	Reason    string              `json:"reason"`
+	Key       string              `json:"key"`
+	Count     int                 `json:"count"`
+	Documents []DuplicateDocument `json:"documents"`
+}
+
+// DuplicateReportResponse is a page of duplicate groups, largest first. Count is the number
+// of groups and DocumentCount the number of distinct documents in any of them.
+type DuplicateReportResponse struct {
+	Count         int              `json:"count"`
+	DocumentCount int              `json:"document_count"`
+	Next          *string          `json:"next,omitempty"`
+	Previous      *string          `json:"previous,omitempty"`
+	Results       []DuplicateGroup `json:"results"`
+}
+
+// parseDuplicateReasons reads a comma separated list of duplicate reasons; empty selects all
+func parseDuplicateReasons(value string) ([]string, error) {
+	if strings.TrimSpace(value) == "" {
+		return duplicateReasons, nil
+	}
+	requested := make(map[string]bool)
+	for _, reason := range strings.Split(value, ",") {
+		reason = strings.ToLower(strings.TrimSpace(reason))
+		if _, ok := duplicateKeyExpressions[reason]; !ok {
+			return nil, fmt.Errorf("Invalid by %q, expected checksum, asn or title", reason)
+		}
+		requested[reason] = true
+	}
+	reasons := []string{}
+	for _, reason := range duplicateReasons {
+		if requested[reason] {
+			reasons = append(reasons, reason)
+		}
+	}
+	return reasons, nil
+}
+
+// GetDuplicateReport finds the groups of documents sharing the key of any of the reasons.
+// Documents are considered like the builtin filter values: trashed ones only with
+// includeTrashed, and only those visible to viewerID (0 = all). A document may appear in
+// groups of several reasons.
+func (s *Service) GetDuplicateReport(ctx context.Context, reasons []string, includeTrashed bool, viewerID int) (*DuplicateReportResponse, error) {
+	log.Printf("[Duplicates] GetDuplicateReport - Reasons: %v, IncludeTrashed: %t, Viewer: %d", reasons, includeTrashed, viewerID)
+	conditions := []string{trashedCondition(includeTrashed)}
+	visibility, err := s.documentVisibilityCondition(ctx, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	if visibility != "" {
+		conditions = append(conditions, visibility)
+	}
+	documentCondition := strings.Join(conditions, " AND ")
+
+	groups := []DuplicateGroup{}
+	for _, reason := range reasons {
+		reasonGroups, err := s.queryDuplicateGroups(ctx, reason, documentCondition)
+		if err != nil {
+			return nil, err
+		}
+		groups = append(groups, reasonGroups...)
+	}
+	sort.SliceStable(groups, func(i, j int) bool {
+		return groups[i].Count > groups[j].Count
+	})
+
+	documents := make(map[int]bool)
+	for _, group := range groups {
+		for _, document := range group.Documents {
+			documents[document.ID] = true
+		}
+	}
+	return &DuplicateReportResponse{Count: len(groups), DocumentCount: len(documents), Results: groups}, nil
+}
+
+// queryDuplicateGroups returns the groups of documents matching documentCondition that share
+// the key of a reason, ordered by key
+func (s *Service) queryDuplicateGroups(ctx context.Context, reason string, documentCondition string) ([]DuplicateGroup, error) {
+	expressions := duplicateKeyExpressions[reason]
+	joinConditions := make([]string, len(expressions))
+	keyColumns := make([]string, len(expressions))
+	groupBy := make([]string, len(expressions))
+	for i, expression := range expressions {
+		joinConditions[i] = fmt.Sprintf("%s = g.k%d", expression, i)
+		keyColumns[i] = fmt.Sprintf("%s AS k%d", expression, i)
+		groupBy[i] = fmt.Sprintf("g.k%d", i)
+	}
+
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf(`
+		SELECT d.id, d.title, d.correspondent_id, d.created, d.added, %s
+		FROM documents_document d
+		INNER JOIN (
+			SELECT %s
+			FROM documents_document d
+			WHERE %s AND %s
+			GROUP BY %s
+			HAVING COUNT(*) > 1
+		) g ON %s
+		WHERE %s
+		ORDER BY %s, d.created ASC, d.id ASC
+	`, strings.Join(groupBy, ", "), strings.Join(keyColumns, ", "), documentCondition, duplicateKeyConditions[reason],
+		strings.Join(expressions, ", "), strings.Join(joinConditions, " AND "), documentCondition, strings.Join(groupBy, ", ")))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query %s duplicates: %w", reason, err)
+	}
+	defer rows.Close()
+
+	groups := []DuplicateGroup{}
+	for rows.Next() {
+		var document DuplicateDocument
+		var title sql.NullString
+		var correspondentID sql.NullInt64
+		var created, added dbTimestamp
+		keyValues := make([]interface{}, len(expressions))
+		scan := []interface{}{&document.ID, &title, &correspondentID, &created, &added}
+		for i := range keyValues {
+			scan = append(scan, &keyValues[i])
+		}
+		if err := rows.Scan(scan...); err != nil {
+			return nil, fmt.Errorf("failed to scan %s duplicate: %w", reason, err)
+		}
+		document.Title = title.String
+		if correspondentID.Valid {
+			id := int(correspondentID.Int64)
+			document.CorrespondentID = &id
+		}
+		if date := s.formatTimestamp(created); date != nil && len(*date) >= 10 {
+			day := (*date)[:10]
+			document.Created = &day
+		}
+		document.Added = s.formatTimestamp(added)
+
+		key := duplicateGroupKey(reason, keyValues, document)
+		if len(groups) == 0 || groups[len(groups)-1].Key != key {
+			groups = append(groups, DuplicateGroup{Reason: reason, Key: key})
+		}
+		group := &groups[len(groups)-1]
+		group.Documents = append(group.Documents, document)
+		group.Count++
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s duplicates: %w", reason, err)
+	}
+	return groups, nil
+}
+
+// duplicateGroupKey returns the readable key of a duplicate group: the checksum, the ASN,
+// or the title, correspondent and created date joined by "|"
+func duplicateGroupKey(reason string, keyValues []interface{}, document DuplicateDocument) string {
+	parts := make([]string, len(keyValues))
+	for i, value := range keyValues {
+		parts[i] = fmt.Sprint(viewDocumentValue("", value))
+	}
+	if reason == DuplicateByTitle && document.Created != nil {
+		parts[len(parts)-1] = *document.Created
+	}
+	return strings.Join(parts, "|")
+}
+
+// HTTP Handler for the duplicate document report
+func (s *Service) handleGetDuplicateDocuments(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	log.Printf("[Duplicates] GET /api/documents/duplicates/ - Request from %s", r.RemoteAddr)
+
+	viewerID, err := viewerFromRequest(r)
+	if err != nil {
+		respondError(w, viewerErrorStatus(err), err.Error())
+		return
+	}
+
+	reasons, err := parseDuplicateReasons(r.URL.Query().Get("by"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	pagination, err := parsePagination(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	// Groups are always paginated
+	if pagination.PageSize == 0 {
+		pagination.PageSize = defaultPageSize
+	}
+
+	report, hit, err := s.getDuplicateReportCached(ctx, reasons, wantsTrashed(r), viewerID, bypassCache(r))
+	if err != nil {
+		log.Printf("[Duplicates] Error finding duplicate documents: %v", err)
+		respondError(w, queryErrorStatus(err), err.Error())
+		return
+	}
+	setCacheHeader(w, hit)
+
+	// The cached report holds all groups; copy the page so it stays untouched
+	page := *report
+	start := min((pagination.Page-1)*pagination.PageSize, len(report.Results))
+	end := min(start+pagination.PageSize, len(report.Results))
+	page.Results = report.Results[start:end]
+	page.Next, page.Previous = pageLinks(r, pagination, report.Count)
+
+	respondJSON(w, http.StatusOK, page)
+}
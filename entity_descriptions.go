@@ -0,0 +1,412 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// entityDescriptionTypes are the Paperless objects that can have a description, as used in
+// /api/descriptions/{entityType}/ and the entity_type column
+var entityDescriptionTypes = map[string]bool{
+	"tag":           true,
+	"correspondent": true,
+	"document_type": true,
+	"storage_path":  true,
+}
+
+// EntityDescriptionMapResponse holds the descriptions of one entity type keyed by entity ID
+type EntityDescriptionMapResponse struct {
+	Count   int                       `json:"count"`
+	Results map[int]EntityDescription `json:"results"`
+}
+
+// checkEntityType rejects entity types that cannot have a description
+func checkEntityType(entityType string) error {
+	if !entityDescriptionTypes[entityType] {
+		return fmt.Errorf("invalid entity type %q: expected tag, correspondent, document_type or storage_path", entityType)
+	}
+	return nil
+}
+
+// queryEntityDescriptions adds the descriptions of the entity type selected by condition to
+// descriptions
+func (s *Service) queryEntityDescriptions(ctx context.Context, descriptions map[int]EntityDescription, entityType string, condition string, args ...interface{}) error {
+	query := s.rebind("SELECT id, entity_type, entity_id, description, created, modified, modified_by FROM entity_descriptions WHERE entity_type = ? AND " + condition)
+	rows, err := s.db.QueryContext(ctx, query, append([]interface{}{entityType}, args...)...)
+	if err != nil {
+		return fmt.Errorf("failed to query descriptions: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var desc EntityDescription
+		var id int
+		var description sql.NullString
+		var created, modified dbTimestamp
+		var modifiedBy sql.NullInt64
+		if err := rows.Scan(&id, &desc.EntityType, &desc.EntityID, &description, &created, &modified, &modifiedBy); err != nil {
+			return fmt.Errorf("failed to scan description: %w", err)
+		}
+		desc.ID = &id
+		if description.Valid {
+			desc.Description = &description.String
+		}
+		if modifiedBy.Valid {
+			userID := int(modifiedBy.Int64)
+			desc.ModifiedBy = &userID
+		}
+		desc.Created = s.formatTimestamp(created)
+		desc.Modified = s.formatTimestamp(modified)
+		descriptions[desc.EntityID] = desc
+	}
+	return rows.Err()
+}
+
+// ListEntityDescriptions returns the descriptions of the entities keyed by ID, or all stored
+// descriptions of the type when ids is nil. Requested entities without a description get an
+// empty one, like from GetEntityDescription.
+func (s *Service) ListEntityDescriptions(ctx context.Context, entityType string, ids []int) (map[int]EntityDescription, error) {
+	if err := checkEntityType(entityType); err != nil {
+		return nil, err
+	}
+
+	descriptions := make(map[int]EntityDescription)
+	if ids == nil {
+		if err := s.queryEntityDescriptions(ctx, descriptions, entityType, "1 = 1"); err != nil {
+			return nil, err
+		}
+		return descriptions, nil
+	}
+
+	ids = uniqueTagIDs(ids)
+	for start := 0; start < len(ids); start += tagGroupMembershipBatchSize {
+		placeholders, args := inPlaceholders(ids[start:min(start+tagGroupMembershipBatchSize, len(ids))])
+		if err := s.queryEntityDescriptions(ctx, descriptions, entityType, "entity_id IN ("+placeholders+")", args...); err != nil {
+			return nil, err
+		}
+	}
+	for _, id := range ids {
+		if _, ok := descriptions[id]; !ok {
+			descriptions[id] = EntityDescription{EntityType: entityType, EntityID: id}
+		}
+	}
+	return descriptions, nil
+}
+
+// GetEntityDescription retrieves the description of an entity, or an empty description
+// when it has none
+func (s *Service) GetEntityDescription(ctx context.Context, entityType string, entityID int) (*EntityDescription, error) {
+	descriptions, err := s.ListEntityDescriptions(ctx, entityType, []int{entityID})
+	if err != nil {
+		return nil, err
+	}
+	desc := descriptions[entityID]
+	return &desc, nil
+}
+
+// recordEntityDescriptionRevision keeps the current version of a description, if there is
+// one, as a revision before it is replaced or deleted, and drops revisions beyond the
+// configured limit
+func (s *Service) recordEntityDescriptionRevision(ctx context.Context, tx *sql.Tx, entityType string, entityID int) error {
+	if s.config.DescriptionRevisionLimit <= 0 {
+		return nil
+	}
+
+	var latest int
+	query := s.rebind("SELECT COALESCE(MAX(revision), 0) FROM entity_description_revisions WHERE entity_type = ? AND entity_id = ?")
+	if err := tx.QueryRowContext(ctx, query, entityType, entityID).Scan(&latest); err != nil {
+		return fmt.Errorf("failed to query description revisions: %w", err)
+	}
+	revision := latest + 1
+
+	result, err := tx.ExecContext(ctx, s.rebind(`
+		INSERT INTO entity_description_revisions (entity_type, entity_id, revision, description, modified_by, modified)
+		SELECT entity_type, entity_id, ?, description, modified_by, modified
+		FROM entity_descriptions
+		WHERE entity_type = ? AND entity_id = ?
+	`), revision, entityType, entityID)
+	if err != nil {
+		return fmt.Errorf("failed to record description revision: %w", err)
+	}
+	if recorded, _ := result.RowsAffected(); recorded == 0 {
+		return nil
+	}
+
+	query = s.rebind("DELETE FROM entity_description_revisions WHERE entity_type = ? AND entity_id = ? AND revision <= ?")
+	if _, err := tx.ExecContext(ctx, query, entityType, entityID, revision-s.config.DescriptionRevisionLimit); err != nil {
+		return fmt.Errorf("failed to prune description revisions: %w", err)
+	}
+	return nil
+}
+
+// SetEntityDescription creates or updates the description of an entity on behalf of a user
+// (0 when unknown). The version it replaces is kept as a revision.
+func (s *Service) SetEntityDescription(ctx context.Context, desc EntityDescription, userID int) (*EntityDescription, error) {
+	log.Printf("[Descriptions] SetEntityDescription - %s %d, UserID: %d", desc.EntityType, desc.EntityID, userID)
+	if err := checkEntityType(desc.EntityType); err != nil {
+		return nil, err
+	}
+	var modifiedBy *int
+	if userID > 0 {
+		modifiedBy = &userID
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := s.recordEntityDescriptionRevision(ctx, tx, desc.EntityType, desc.EntityID); err != nil {
+		return nil, err
+	}
+	result, err := tx.ExecContext(ctx, s.rebind(`
+		UPDATE entity_descriptions
+		SET description = ?, modified = CURRENT_TIMESTAMP, modified_by = ?
+		WHERE entity_type = ? AND entity_id = ?
+	`), desc.Description, modifiedBy, desc.EntityType, desc.EntityID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save description: %w", err)
+	}
+	if updated, _ := result.RowsAffected(); updated == 0 {
+		_, err := tx.ExecContext(ctx, s.rebind(`
+			INSERT INTO entity_descriptions (entity_type, entity_id, description, modified_by)
+			VALUES (?, ?, ?, ?)
+		`), desc.EntityType, desc.EntityID, desc.Description, modifiedBy)
+		if err != nil {
+			return nil, fmt.Errorf("failed to save description: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit description: %w", err)
+	}
+
+	return s.GetEntityDescription(ctx, desc.EntityType, desc.EntityID)
+}
+
+// DeleteEntityDescription deletes the description of an entity. The deleted version is kept
+// as a revision.
+func (s *Service) DeleteEntityDescription(ctx context.Context, entityType string, entityID int) error {
+	log.Printf("[Descriptions] DeleteEntityDescription - %s %d", entityType, entityID)
+	if err := checkEntityType(entityType); err != nil {
+		return err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := s.recordEntityDescriptionRevision(ctx, tx, entityType, entityID); err != nil {
+		return err
+	}
+	query := s.rebind("DELETE FROM entity_descriptions WHERE entity_type = ? AND entity_id = ?")
+	if _, err := tx.ExecContext(ctx, query, entityType, entityID); err != nil {
+		return fmt.Errorf("failed to delete description: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit description deletion: %w", err)
+	}
+	return nil
+}
+
+// ListEntityDescriptionRevisions retrieves the earlier versions of a description, latest
+// first. They are kept after the description itself is deleted.
+func (s *Service) ListEntityDescriptionRevisions(ctx context.Context, entityType string, entityID int) ([]EntityDescriptionRevision, error) {
+	if err := checkEntityType(entityType); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.QueryContext(ctx, s.rebind(`
+		SELECT id, entity_type, entity_id, revision, description, modified_by, modified, created
+		FROM entity_description_revisions
+		WHERE entity_type = ? AND entity_id = ?
+		ORDER BY revision DESC
+	`), entityType, entityID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query description revisions: %w", err)
+	}
+	defer rows.Close()
+
+	revisions := []EntityDescriptionRevision{}
+	for rows.Next() {
+		var revision EntityDescriptionRevision
+		var description sql.NullString
+		var modifiedBy sql.NullInt64
+		var modified, created dbTimestamp
+		if err := rows.Scan(&revision.ID, &revision.EntityType, &revision.EntityID, &revision.Revision, &description, &modifiedBy, &modified, &created); err != nil {
+			return nil, fmt.Errorf("failed to scan description revision: %w", err)
+		}
+		if description.Valid {
+			revision.Description = &description.String
+		}
+		if modifiedBy.Valid {
+			id := int(modifiedBy.Int64)
+			revision.ModifiedBy = &id
+		}
+		revision.Modified = s.formatTimestamp(modified)
+		revision.Created = s.formatTimestamp(created)
+		revisions = append(revisions, revision)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read description revisions: %w", err)
+	}
+	return revisions, nil
+}
+
+// entityDescriptionErrorStatus maps description errors to HTTP status codes
+func entityDescriptionErrorStatus(err error) int {
+	if err != nil && strings.HasPrefix(err.Error(), "invalid entity type") {
+		return http.StatusBadRequest
+	}
+	return queryErrorStatus(err)
+}
+
+// HTTP Handlers for entity descriptions
+
+func (s *Service) handleListEntityDescriptions(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	entityType := mux.Vars(r)["entityType"]
+	log.Printf("[Descriptions] GET /api/descriptions/%s/ - Request from %s", entityType, r.RemoteAddr)
+
+	var ids []int
+	if r.URL.Query().Has("ids") {
+		var err error
+		if ids, err = parseIDList(r.URL.Query().Get("ids"), "ids"); err != nil {
+			respondError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	descriptions, err := s.ListEntityDescriptions(ctx, entityType, ids)
+	if err != nil {
+		log.Printf("[Descriptions] Error listing %s descriptions: %v", entityType, err)
+		respondError(w, entityDescriptionErrorStatus(err), err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, EntityDescriptionMapResponse{Count: len(descriptions), Results: descriptions})
+}
+
+func (s *Service) handleGetEntityDescription(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	vars := mux.Vars(r)
+	entityType := vars["entityType"]
+	log.Printf("[Descriptions] GET /api/descriptions/%s/%s/ - Request from %s", entityType, vars["id"], r.RemoteAddr)
+
+	entityID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid ID")
+		return
+	}
+
+	desc, err := s.GetEntityDescription(ctx, entityType, entityID)
+	if err != nil {
+		log.Printf("[Descriptions] Error getting description for %s %d: %v", entityType, entityID, err)
+		respondError(w, entityDescriptionErrorStatus(err), err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, desc)
+}
+
+func (s *Service) handleSetEntityDescription(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	vars := mux.Vars(r)
+	entityType := vars["entityType"]
+	log.Printf("[Descriptions] PUT /api/descriptions/%s/%s/ - Request from %s", entityType, vars["id"], r.RemoteAddr)
+
+	viewerID, err := viewerFromRequest(r)
+	if err != nil {
+		respondError(w, viewerErrorStatus(err), err.Error())
+		return
+	}
+
+	entityID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid ID")
+		return
+	}
+
+	var desc EntityDescription
+	if err := json.NewDecoder(r.Body).Decode(&desc); err != nil {
+		log.Printf("[Descriptions] Error decoding request body: %v", err)
+		respondError(w, http.StatusBadRequest, fmt.Sprintf("Invalid request body: %v", err))
+		return
+	}
+
+	desc.EntityType = entityType
+	desc.EntityID = entityID
+	saved, err := s.SetEntityDescription(ctx, desc, viewerID)
+	if err != nil {
+		log.Printf("[Descriptions] Error saving description for %s %d: %v", entityType, entityID, err)
+		respondError(w, entityDescriptionErrorStatus(err), err.Error())
+		return
+	}
+
+	log.Printf("[Descriptions] Successfully saved description for %s %d", entityType, entityID)
+	respondJSON(w, http.StatusOK, saved)
+}
+
+func (s *Service) handleDeleteEntityDescription(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	vars := mux.Vars(r)
+	entityType := vars["entityType"]
+	log.Printf("[Descriptions] DELETE /api/descriptions/%s/%s/ - Request from %s", entityType, vars["id"], r.RemoteAddr)
+
+	entityID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid ID")
+		return
+	}
+
+	if err := s.DeleteEntityDescription(ctx, entityType, entityID); err != nil {
+		log.Printf("[Descriptions] Error deleting description for %s %d: %v", entityType, entityID, err)
+		respondError(w, entityDescriptionErrorStatus(err), err.Error())
+		return
+	}
+
+	log.Printf("[Descriptions] Successfully deleted description for %s %d", entityType, entityID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Service) handleGetEntityDescriptionHistory(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	vars := mux.Vars(r)
+	entityType := vars["entityType"]
+	log.Printf("[Descriptions] GET /api/descriptions/%s/%s/history/ - Request from %s", entityType, vars["id"], r.RemoteAddr)
+
+	entityID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid ID")
+		return
+	}
+
+	revisions, err := s.ListEntityDescriptionRevisions(ctx, entityType, entityID)
+	if err != nil {
+		log.Printf("[Descriptions] Error listing history of %s %d: %v", entityType, entityID, err)
+		respondError(w, entityDescriptionErrorStatus(err), err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, EntityDescriptionHistoryResponse{Count: len(revisions), Results: revisions})
+}
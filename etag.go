@@ -0,0 +1,237 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// polledDataVersion is a data version read from the database and when it was read
+type polledDataVersion struct {
+	version string
+	read    time.Time
+}
+
+// documentDataVersion identifies the current state of the data builtin filter values are
+// computed from. With change notifications it is a counter bumped on every notification
+// (prefixed by the process start, since the counter starts over). Otherwise it is the
+// version last read by runDataVersionPoller; it is only read on demand while no poll
+// happened for two DATA_VERSION_INTERVALs, e.g. before the poller started.
+func (s *Service) documentDataVersion(ctx context.Context) (string, error) {
+	if s.documentNotify {
+		return fmt.Sprintf("n%d-%d", s.startedAt.UnixNano(), s.documentGeneration.Load()), nil
+	}
+	if polled, ok := s.dataVersion.Load().(polledDataVersion); ok && time.Since(polled.read) < 2*s.config.DataVersionInterval {
+		return polled.version, nil
+	}
+	version, err := s.shareQuery(ctx, "data-version", s.pollDataVersion)
+	if err != nil {
+		return "", err
+	}
+	return version.(string), nil
+}
+
+// pollDataVersion reads the data version from the database and, when it changed since the
+// previous read, drops the builtin filter values cached from the older data
+func (s *Service) pollDataVersion(ctx context.Context) (interface{}, error) {
+	version, err := s.queryDataVersion(ctx)
+	if err != nil {
+		return nil, err
+	}
+	previous, _ := s.dataVersion.Swap(polledDataVersion{version: version, read: time.Now()}).(polledDataVersion)
+	if previous.version != "" && previous.version != version {
+		if removed := s.builtinCache.DeletePrefix(""); removed > 0 {
+			log.Printf("[BuiltinCache] Data version changed, invalidated %d entries", removed)
+		}
+	}
+	return version, nil
+}
+
+// runDataVersionPoller reads the data version every DATA_VERSION_INTERVAL where no change
+// notifications are available, so conditional requests never wait for it
+func (s *Service) runDataVersionPoller(ctx context.Context) {
+	ticker := time.NewTicker(s.config.DataVersionInterval)
+	defer ticker.Stop()
+	for {
+		if _, err := s.shareQuery(ctx, "data-version", s.pollDataVersion); err != nil && ctx.Err() == nil {
+			log.Printf("[BuiltinCache] Failed to poll data version: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// queryDataVersion derives a data version from document counts, modification times and
+// tag assignments, which misses renames of tags, correspondents, document types and
+// storage paths
+func (s *Service) queryDataVersion(ctx context.Context) (string, error) {
+	var documents, trashed, assignments, entities, memberships interface{}
+	var lastModified, groupsModified, aliases, aliasesModified, profiles, profilesModified interface{}
+	err := s.db.QueryRowContext(ctx, `
+		SELECT
+			(SELECT COUNT(*) FROM documents_document),
+			(SELECT COUNT(deleted_at) FROM documents_document),
+			(SELECT MAX(modified) FROM documents_document),
+			(SELECT COUNT(*) FROM documents_document_tags),
+			(SELECT COUNT(*) FROM documents_tag) + (SELECT COUNT(*) FROM documents_correspondent)
+				+ (SELECT COUNT(*) FROM documents_documenttype) + (SELECT COUNT(*) FROM documents_storagepath),
+			(SELECT MAX(modified) FROM tag_groups),
+			(SELECT COUNT(*) FROM tag_group_memberships),
+			(SELECT COUNT(*) FROM tag_aliases),
+			(SELECT MAX(modified) FROM tag_aliases),
+			(SELECT COUNT(*) FROM correspondent_profiles),
+			(SELECT MAX(modified) FROM correspondent_profiles)
+	`).Scan(&documents, &trashed, &lastModified, &assignments, &entities, &groupsModified, &memberships, &aliases, &aliasesModified,
+		&profiles, &profilesModified)
+	if err != nil {
+		return "", fmt.Errorf("failed to read data version: %w", err)
+	}
+	return fmt.Sprintf("q%s|%s|%s|%s|%s|%s|%s|%s|%s|%s|%s", statsString(documents), statsString(trashed), statsString(lastModified),
+		statsString(assignments), statsString(entities), statsString(groupsModified), statsString(memberships),
+		statsString(aliases), statsString(aliasesModified), statsString(profiles), statsString(profilesModified)), nil
+}
+
+// builtinFilterValuesETag builds a strong ETag for a builtin filter values response from
+// the data version, the filter and the request's query parameters and viewer
+func (s *Service) builtinFilterValuesETag(ctx context.Context, r *http.Request, filterType string, filterRulesJSON string, viewerID int) (string, error) {
+	version, err := s.documentDataVersion(ctx)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s|%s|%s|%s|%d", version, filterType, filterRulesJSON,
+		r.URL.Query().Encode(), viewerID)))
+	return `"` + hex.EncodeToString(sum[:12]) + `"`, nil
+}
+
+// etagMatches reports whether an If-None-Match header value matches etag
+func etagMatches(ifNoneMatch string, etag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// maxETagBodySize bounds the response bodies conditionalGetHandler buffers to hash them;
+// larger responses are sent on without an ETag
+const maxETagBodySize = 1 << 20
+
+// etagResponseWriter buffers a JSON response so its ETag can be computed before anything
+// is sent. Other responses, and responses that outgrow maxETagBodySize or are flushed,
+// pass through unchanged.
+type etagResponseWriter struct {
+	w         http.ResponseWriter
+	status    int
+	decided   bool // Whether the response is buffered has been decided
+	buffering bool
+	body      bytes.Buffer
+}
+
+func (e *etagResponseWriter) Header() http.Header { return e.w.Header() }
+
+func (e *etagResponseWriter) WriteHeader(status int) {
+	if e.decided {
+		return
+	}
+	e.decided = true
+	e.status = status
+	header := e.w.Header()
+	mediaType, _, _ := mime.ParseMediaType(header.Get("Content-Type"))
+	e.buffering = status == http.StatusOK && mediaType == "application/json" && header.Get("ETag") == "" &&
+		!strings.Contains(header.Get("Cache-Control"), "no-store")
+	if !e.buffering {
+		e.w.WriteHeader(status)
+	}
+}
+
+func (e *etagResponseWriter) Write(p []byte) (int, error) {
+	if !e.decided {
+		e.WriteHeader(http.StatusOK)
+	}
+	if e.buffering && e.body.Len()+len(p) > maxETagBodySize {
+		if err := e.passThrough(); err != nil {
+			return 0, err
+		}
+	}
+	if e.buffering {
+		return e.body.Write(p)
+	}
+	return e.w.Write(p)
+}
+
+// Flush sends what was buffered and passes the rest of the response through, since a
+// handler that flushes streams its response
+func (e *etagResponseWriter) Flush() {
+	if e.buffering {
+		if err := e.passThrough(); err != nil {
+			return
+		}
+	}
+	if flusher, ok := e.w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// passThrough stops buffering and sends the buffered part of the response
+func (e *etagResponseWriter) passThrough() error {
+	e.buffering = false
+	e.w.WriteHeader(e.status)
+	_, err := e.w.Write(e.body.Bytes())
+	e.body = bytes.Buffer{}
+	return err
+}
+
+// finish sends a buffered response with a weak ETag hashed from its body, or 304 Not
+// Modified if the request's If-None-Match matches it
+func (e *etagResponseWriter) finish(r *http.Request) {
+	if !e.buffering {
+		return
+	}
+	header := e.w.Header()
+	sum := sha256.Sum256(e.body.Bytes())
+	etag := `"` + hex.EncodeToString(sum[:12]) + `"`
+	header.Set("ETag", "W/"+etag)
+	if header.Get("Cache-Control") == "" {
+		header.Set("Cache-Control", "private, no-cache")
+	}
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		header.Del("Content-Type")
+		e.w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	e.w.WriteHeader(e.status)
+	e.w.Write(e.body.Bytes())
+}
+
+// conditionalGetHandler gives successful JSON GET responses of up to maxETagBodySize a
+// weak ETag hashed from their body and a default Cache-Control of "private, no-cache", and
+// answers a matching If-None-Match with 304 Not Modified. Handlers that set their own ETag
+// (derived from a data version) are passed through, as are other content types such as
+// NDJSON streams and downloads, flushed responses and responses marked no-store.
+func conditionalGetHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		writer := &etagResponseWriter{w: w}
+		next.ServeHTTP(writer, r)
+		writer.finish(r)
+	})
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDocumentDataVersionIsPolled(t *testing.T) {
+	s := newTestService(t, func(config *Config) {
+		config.DataVersionInterval = time.Hour
+	})
+	ctx := context.Background()
+
+	before, err := s.documentDataVersion(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.builtinCache.Set("builtin:test", []BuiltinFilterValueOption{{ID: 1, Label: "cached", Count: 1}})
+	mustExec(t, s, `INSERT INTO documents_document (id, title) VALUES (1, 'new')`)
+
+	// Conditional requests read the polled version and leave the cache alone
+	if version, err := s.documentDataVersion(ctx); err != nil || version != before {
+		t.Errorf("version %q (%v) before the next poll, want %q", version, err, before)
+	}
+	var cached []BuiltinFilterValueOption
+	if !s.builtinCache.Get("builtin:test", &cached) {
+		t.Error("reading the version invalidated the cache")
+	}
+
+	// The poll sees the change and invalidates the cache
+	if _, err := s.pollDataVersion(ctx); err != nil {
+		t.Fatal(err)
+	}
+	after, err := s.documentDataVersion(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if after == before {
+		t.Errorf("version %q unchanged after the poll", after)
+	}
+	if s.builtinCache.Get("builtin:test", &cached) {
+		t.Error("cache kept values of the older data version")
+	}
+
+	// Polling an unchanged version keeps the cache
+	s.builtinCache.Set("builtin:test", cached)
+	if _, err := s.pollDataVersion(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if !s.builtinCache.Get("builtin:test", &cached) {
+		t.Error("poll of an unchanged version invalidated the cache")
+	}
+}
+
+func TestDocumentDataVersionWithoutPoller(t *testing.T) {
+	s := newTestService(t, func(config *Config) {
+		config.DataVersionInterval = 0
+	})
+	ctx := context.Background()
+
+	before, err := s.documentDataVersion(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustExec(t, s, `INSERT INTO documents_document (id, title) VALUES (1, 'new')`)
+	if after, err := s.documentDataVersion(ctx); err != nil || after == before {
+		t.Errorf("version %q (%v) unchanged without a poller", after, err)
+	}
+}
+
+func TestRunDataVersionPollerStopsOnShutdown(t *testing.T) {
+	s := newTestService(t, func(config *Config) {
+		config.DataVersionInterval = 10 * time.Millisecond
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.runDataVersionPoller(ctx)
+		close(done)
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for s.dataVersion.Load() == nil && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if s.dataVersion.Load() == nil {
+		t.Error("poller did not read the data version")
+	}
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("poller did not stop")
+	}
+}
+
+func TestConditionalGetHandler(t *testing.T) {
+	large := strings.Repeat("x", maxETagBodySize)
+	handlers := map[string]http.HandlerFunc{
+		"/json": func(w http.ResponseWriter, r *http.Request) {
+			respondJSON(w, http.StatusOK, map[string]string{"name": "view"})
+		},
+		"/error": func(w http.ResponseWriter, r *http.Request) {
+			respondError(w, http.StatusNotFound, "not found")
+		},
+		"/large": func(w http.ResponseWriter, r *http.Request) {
+			respondJSON(w, http.StatusOK, large)
+		},
+		"/own-etag": func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("ETag", `"v1"`)
+			respondJSON(w, http.StatusOK, "own")
+		},
+		"/no-store": func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Cache-Control", "no-store")
+			respondJSON(w, http.StatusOK, "private")
+		},
+		"/csv": func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/csv")
+			w.Write([]byte("a,b\n"))
+		},
+		"/ndjson": func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", ndjsonContentType)
+			w.Write([]byte("{}\n"))
+			w.(http.Flusher).Flush()
+			w.Write([]byte("{}\n"))
+		},
+		"/flushed-json": func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte("["))
+			w.(http.Flusher).Flush()
+			w.Write([]byte("]"))
+		},
+	}
+	mux := http.NewServeMux()
+	for path, handler := range handlers {
+		mux.HandleFunc(path, handler)
+	}
+	handler := conditionalGetHandler(mux)
+
+	get := func(path string, ifNoneMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := get("/json", "")
+	etag := rec.Header().Get("ETag")
+	if rec.Code != http.StatusOK || !strings.HasPrefix(etag, `W/"`) || rec.Header().Get("Cache-Control") != "private, no-cache" {
+		t.Fatalf("json: status %d, ETag %q, Cache-Control %q", rec.Code, etag, rec.Header().Get("Cache-Control"))
+	}
+	if rec := get("/json", etag); rec.Code != http.StatusNotModified || rec.Body.Len() != 0 {
+		t.Errorf("json revalidation: status %d with %d bytes, want 304", rec.Code, rec.Body.Len())
+	}
+
+	passedThrough := map[string]struct {
+		status  int
+		body    string
+		etag    string
+		flushed bool
+	}{
+		"/error":        {http.StatusNotFound, "", "", false},
+		"/large":        {http.StatusOK, `"` + large + "\"\n", "", false},
+		"/own-etag":     {http.StatusOK, "\"own\"\n", `"v1"`, false},
+		"/no-store":     {http.StatusOK, "\"private\"\n", "", false},
+		"/csv":          {http.StatusOK, "a,b\n", "", false},
+		"/ndjson":       {http.StatusOK, "{}\n{}\n", "", true},
+		"/flushed-json": {http.StatusOK, "[]", "", true},
+	}
+	for path, want := range passedThrough {
+		rec := get(path, "*")
+		if rec.Code != want.status || rec.Header().Get("ETag") != want.etag || rec.Flushed != want.flushed {
+			t.Errorf("%s: status %d, ETag %q, flushed %v", path, rec.Code, rec.Header().Get("ETag"), rec.Flushed)
+		}
+		if want.body != "" && rec.Body.String() != want.body {
+			t.Errorf("%s: body of %d bytes, want %d", path, rec.Body.Len(), len(want.body))
+		}
+	}
+}
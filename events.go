@@ -0,0 +1,278 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Events recorded in the activity feed besides the view events and EventReminderDue
+const (
+	EventDocumentConsumed = "document.consumed"
+	EventDocumentLinked   = "document.linked"
+)
+
+// activityEvents are the events of the activity feed
+var activityEvents = map[string]bool{
+	EventDocumentConsumed: true,
+	EventDocumentLinked:   true,
+	EventReminderDue:      true,
+	EventViewCreated:      true,
+	EventViewUpdated:      true,
+	EventViewDeleted:      true,
+	EventViewRestored:     true,
+}
+
+// DocumentConsumedEvent is the outcome of a document.consumed event
+type DocumentConsumedEvent struct {
+	Event       string `json:"event"`
+	DocumentID  int    `json:"document_id"`
+	ActivityID  int    `json:"activity_id"`
+	Invalidated int    `json:"invalidated"` // Cache entries dropped
+	Precomputed []int  `json:"precomputed"` // Hot fields of the document refreshed in the background
+}
+
+// recordActivity appends an event to the activity feed. A document event is seen by the
+// users who can see the document; userID restricts an event to one user.
+func (s *Service) recordActivity(ctx context.Context, event string, documentID *int, userID *int, title string, data map[string]interface{}) (int, error) {
+	dataJSON := "{}"
+	if len(data) > 0 {
+		encoded, err := json.Marshal(data)
+		if err != nil {
+			return 0, fmt.Errorf("failed to encode activity data: %w", err)
+		}
+		dataJSON = string(encoded)
+	}
+
+	var id int64
+	if s.config.DBEngine == "postgresql" || s.config.DBEngine == "postgres" {
+		err := s.db.QueryRowContext(ctx, `
+			INSERT INTO activity_events (event, document_id, user_id, title, data)
+			VALUES ($1, $2, $3, $4, $5::jsonb)
+			RETURNING id
+		`, event, documentID, userID, title, dataJSON).Scan(&id)
+		if err != nil {
+			return 0, fmt.Errorf("failed to record activity: %w", err)
+		}
+	} else {
+		result, err := s.db.ExecContext(ctx, `
+			INSERT INTO activity_events (event, document_id, user_id, title, data)
+			VALUES (?, ?, ?, ?, ?)
+		`, event, documentID, userID, title, dataJSON)
+		if err != nil {
+			return 0, fmt.Errorf("failed to record activity: %w", err)
+		}
+		if id, err = result.LastInsertId(); err != nil {
+			return 0, fmt.Errorf("failed to get activity ID: %w", err)
+		}
+	}
+	return int(id), nil
+}
+
+// documentFieldIDs returns the custom fields a document has values for
+func (s *Service) documentFieldIDs(ctx context.Context, documentID int) (map[int]bool, error) {
+	rows, err := s.db.QueryContext(ctx, s.rebind("SELECT DISTINCT field_id FROM documents_customfieldinstance WHERE document_id = ?"), documentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query document fields: %w", err)
+	}
+	defer rows.Close()
+
+	fieldIDs := make(map[int]bool)
+	for rows.Next() {
+		var fieldID int
+		if err := rows.Scan(&fieldID); err != nil {
+			return nil, fmt.Errorf("failed to scan document field: %w", err)
+		}
+		fieldIDs[fieldID] = true
+	}
+	return fieldIDs, rows.Err()
+}
+
+// HandleDocumentConsumed processes a document Paperless has just consumed: the cached
+// values are dropped, the event is appended to the activity feed, and the hot fields of the
+// document and the saved search notifications are refreshed in the background. data holds
+// the details posted by the consumption script and is kept with the event.
+func (s *Service) HandleDocumentConsumed(ctx context.Context, documentID int, data map[string]interface{}) (*DocumentConsumedEvent, error) {
+	log.Printf("[Events] HandleDocumentConsumed - DocumentID: %d", documentID)
+	var title sql.NullString
+	err := s.db.QueryRowContext(ctx, s.rebind("SELECT title FROM documents_document WHERE id = ?"), documentID).Scan(&title)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("document with id %d not found", documentID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query document: %w", err)
+	}
+
+	s.documentGeneration.Add(1)
+	invalidated := s.builtinCache.DeletePrefix("") + s.valueCache.DeletePrefix("")
+
+	activityID, err := s.recordActivity(ctx, EventDocumentConsumed, &documentID, nil, title.String, data)
+	if err != nil {
+		return nil, err
+	}
+
+	precomputed := []int{}
+	if hot := s.hotFieldIDs(ctx); len(hot) > 0 {
+		fieldIDs, err := s.documentFieldIDs(ctx, documentID)
+		if err != nil {
+			return nil, err
+		}
+		for _, fieldID := range hot {
+			if fieldIDs[fieldID] {
+				precomputed = append(precomputed, fieldID)
+			}
+		}
+	}
+
+	// The request is answered before the refreshes, which the worker runs in the background
+	s.consumedRefreshes.add(precomputed, s.config.SavedSearchInterval > 0)
+
+	return &DocumentConsumedEvent{Event: EventDocumentConsumed, DocumentID: documentID, ActivityID: activityID, Invalidated: invalidated, Precomputed: precomputed}, nil
+}
+
+// consumedRefreshes are the refreshes due after consumed documents, run one at a time by
+// runConsumedRefresher: the hot fields to precompute and whether the saved searches are
+// checked. Events arriving meanwhile are merged into the next refresh, so a burst of
+// consumed documents queues each field once and no goroutine per event.
+type consumedRefreshes struct {
+	mu            sync.Mutex
+	fieldIDs      map[int]bool
+	savedSearches bool
+	pending       chan struct{} // Holds a signal while refreshes are queued
+}
+
+// newConsumedRefreshes creates an empty refresh queue
+func newConsumedRefreshes() *consumedRefreshes {
+	return &consumedRefreshes{fieldIDs: make(map[int]bool), pending: make(chan struct{}, 1)}
+}
+
+// add queues the precomputation of fields and optionally the saved search checks
+func (q *consumedRefreshes) add(fieldIDs []int, savedSearches bool) {
+	if len(fieldIDs) == 0 && !savedSearches {
+		return
+	}
+	q.mu.Lock()
+	for _, fieldID := range fieldIDs {
+		q.fieldIDs[fieldID] = true
+	}
+	q.savedSearches = q.savedSearches || savedSearches
+	q.mu.Unlock()
+
+	select {
+	case q.pending <- struct{}{}:
+	default:
+	}
+}
+
+// take returns the queued refreshes, in field ID order, and empties the queue
+func (q *consumedRefreshes) take() ([]int, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	fieldIDs := make([]int, 0, len(q.fieldIDs))
+	for fieldID := range q.fieldIDs {
+		fieldIDs = append(fieldIDs, fieldID)
+	}
+	sort.Ints(fieldIDs)
+	savedSearches := q.savedSearches
+	q.fieldIDs = make(map[int]bool)
+	q.savedSearches = false
+	return fieldIDs, savedSearches
+}
+
+// runConsumedRefresher runs the refreshes queued by consumed documents until ctx is cancelled
+func (s *Service) runConsumedRefresher(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.consumedRefreshes.pending:
+		}
+
+		fieldIDs, savedSearches := s.consumedRefreshes.take()
+		for _, fieldID := range fieldIDs {
+			if err := s.PrecomputeFieldValues(ctx, fieldID); err != nil {
+				log.Printf("[Events] Failed to precompute field %d: %v", fieldID, err)
+			}
+		}
+		if savedSearches {
+			s.runSavedSearchChecks(ctx)
+		}
+	}
+}
+
+// eventSecretFromRequest returns the secret of an event request, from the X-Event-Secret
+// header or a bearer token
+func eventSecretFromRequest(r *http.Request) string {
+	if secret := r.Header.Get("X-Event-Secret"); secret != "" {
+		return secret
+	}
+	return strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+}
+
+// eventDocumentID reads the document_id of an event body; the consumption script passes
+// Paperless' environment variables, so it may be a string
+func eventDocumentID(body map[string]interface{}) (int, error) {
+	switch value := body["document_id"].(type) {
+	case float64:
+		if value == float64(int(value)) && value > 0 {
+			return int(value), nil
+		}
+	case string:
+		if id, err := strconv.Atoi(strings.TrimSpace(value)); err == nil && id > 0 {
+			return id, nil
+		}
+	case nil:
+		return 0, fmt.Errorf("document_id is required")
+	}
+	return 0, fmt.Errorf("invalid document_id: expected a positive integer")
+}
+
+// HTTP Handler for document.consumed events of the Paperless post-consumption script
+func (s *Service) handleDocumentConsumedEvent(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	log.Printf("[Events] POST /api/events/document-consumed/ - Request from %s", r.RemoteAddr)
+
+	if s.config.EventSecret == "" {
+		respondError(w, http.StatusForbidden, "Event ingestion is disabled: EVENT_SECRET is not set")
+		return
+	}
+	if subtle.ConstantTimeCompare([]byte(eventSecretFromRequest(r)), []byte(s.config.EventSecret)) != 1 {
+		respondError(w, http.StatusUnauthorized, "Invalid event secret")
+		return
+	}
+
+	var body map[string]interface{}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		respondError(w, http.StatusBadRequest, fmt.Sprintf("Invalid request body: %v", err))
+		return
+	}
+	documentID, err := eventDocumentID(body)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	delete(body, "document_id")
+
+	event, err := s.HandleDocumentConsumed(ctx, documentID, body)
+	if err != nil {
+		log.Printf("[Events] Error handling consumed document %d: %v", documentID, err)
+		status := queryErrorStatus(err)
+		if strings.Contains(err.Error(), "not found") {
+			status = http.StatusNotFound
+		}
+		respondError(w, status, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusAccepted, event)
+}
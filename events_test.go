@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestConsumedRefreshesMergeEvents(t *testing.T) {
+	q := newConsumedRefreshes()
+	q.add(nil, false)
+	select {
+	case <-q.pending:
+		t.Fatal("an event without refreshes was queued")
+	default:
+	}
+
+	q.add([]int{3, 1}, false)
+	q.add([]int{1, 2}, true)
+	q.add([]int{3}, false)
+	if len(q.pending) != 1 {
+		t.Fatalf("%d signals pending, want 1", len(q.pending))
+	}
+	fieldIDs, savedSearches := q.take()
+	if !reflect.DeepEqual(fieldIDs, []int{1, 2, 3}) || !savedSearches {
+		t.Errorf("took %v, saved searches %v", fieldIDs, savedSearches)
+	}
+	if fieldIDs, savedSearches := q.take(); len(fieldIDs) != 0 || savedSearches {
+		t.Errorf("queue not emptied: %v, %v", fieldIDs, savedSearches)
+	}
+}
+
+func TestRunConsumedRefresherStopsOnShutdown(t *testing.T) {
+	s := newTestService(t, nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.runConsumedRefresher(ctx)
+		close(done)
+	}()
+
+	// Refreshes of unknown fields fail and are logged; the worker keeps running
+	s.consumedRefreshes.add([]int{42}, false)
+	queued := func() int {
+		s.consumedRefreshes.mu.Lock()
+		defer s.consumedRefreshes.mu.Unlock()
+		return len(s.consumedRefreshes.fieldIDs)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for queued() > 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if queued() > 0 {
+		t.Error("refreshes were not taken by the worker")
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("worker did not stop on shutdown")
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+)
+
+// defaultASNBucketSize is the number of serial numbers per ASN range option
+const defaultASNBucketSize = 1000
+
+// ASNBucketing controls the "asn" filter type: options are ranges of BucketSize serial
+// numbers, or with DrillDown the individual serial numbers from From to To
+type ASNBucketing struct {
+	BucketSize int
+	DrillDown  bool
+	From       int
+	To         int
+}
+
+// bucketSize returns the configured bucket size or the default
+func (a ASNBucketing) bucketSize() int {
+	if a.BucketSize > 0 {
+		return a.BucketSize
+	}
+	return defaultASNBucketSize
+}
+
+// asnFacetProvider counts documents per range of archive serial numbers
+type asnFacetProvider struct{}
+
+func init() {
+	RegisterFacetProvider("asn", asnFacetProvider{})
+}
+
+func (asnFacetProvider) BuildQuery(q FacetQuery) (string, []interface{}, error) {
+	if q.ASN.DrillDown {
+		// Individual serial numbers of the selected range
+		return fmt.Sprintf(`
+			SELECT d.archive_serial_number as asn, d.archive_serial_number as label, COUNT(DISTINCT d.id) as doc_count
+			FROM documents_document d
+			WHERE %s AND d.archive_serial_number BETWEEN %d AND %d AND %s
+			GROUP BY d.archive_serial_number
+			ORDER BY d.archive_serial_number ASC
+		`, q.DocumentCondition, q.ASN.From, q.ASN.To, q.FilterCondition), q.FilterArgs, nil
+	}
+
+	// One option per range of bucketSize serial numbers, labelled by Scan
+	size := q.ASN.bucketSize()
+	bucket := fmt.Sprintf("(d.archive_serial_number / %d) * %d", size, size)
+	if q.DBEngine == "mysql" || q.DBEngine == "mariadb" {
+		bucket = fmt.Sprintf("(d.archive_serial_number DIV %d) * %d", size, size)
+	}
+	return fmt.Sprintf(`
+		SELECT %s as bucket, %s as label, COUNT(DISTINCT d.id) as doc_count
+		FROM documents_document d
+		WHERE %s AND d.archive_serial_number IS NOT NULL AND %s
+		GROUP BY 1
+		ORDER BY 1 ASC
+	`, bucket, bucket, q.DocumentCondition, q.FilterCondition), q.FilterArgs, nil
+}
+
+func (asnFacetProvider) Scan(rows *sql.Rows, q FacetQuery) ([]BuiltinFilterValueOption, error) {
+	values, err := scanFacetOptions(rows)
+	if err != nil || q.ASN.DrillDown {
+		return values, err
+	}
+
+	for i := range values {
+		start, err := strconv.Atoi(builtinOptionKey(values[i].ID))
+		if err != nil {
+			continue
+		}
+		label := fmt.Sprintf("%d-%d", start, start+q.ASN.bucketSize()-1)
+		values[i].ID = label
+		values[i].Label = label
+	}
+	return values, nil
+}
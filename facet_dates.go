@@ -0,0 +1,34 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// dateFacetProvider counts documents per year or month of a date column, in
+// chronological order
+type dateFacetProvider struct {
+	column string
+	unit   string // "year" or "month"
+}
+
+func init() {
+	RegisterFacetProvider("created_year", dateFacetProvider{column: "d.created", unit: "year"})
+	RegisterFacetProvider("created_month", dateFacetProvider{column: "d.created", unit: "month"})
+	RegisterFacetProvider("added_month", dateFacetProvider{column: "d.added", unit: "month"})
+}
+
+func (p dateFacetProvider) BuildQuery(q FacetQuery) (string, []interface{}, error) {
+	bucket := dateBucketExpression(q.DBEngine, p.unit, p.column)
+	return fmt.Sprintf(`
+		SELECT %s as bucket, %s as label, COUNT(DISTINCT d.id) as doc_count
+		FROM documents_document d
+		WHERE %s AND %s IS NOT NULL AND %s
+		GROUP BY 1
+		ORDER BY 1 ASC
+	`, bucket, bucket, q.DocumentCondition, p.column, q.FilterCondition), q.FilterArgs, nil
+}
+
+func (p dateFacetProvider) Scan(rows *sql.Rows, q FacetQuery) ([]BuiltinFilterValueOption, error) {
+	return scanFacetOptions(rows)
+}
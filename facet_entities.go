@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// entityFacetProvider counts documents per row of a Paperless object table
+// (correspondents, document types, tags, storage paths)
+type entityFacetProvider struct {
+	table           string // Object table, aliased as "e"
+	link            string // Join from "e" to the documents; must end in a join onto "d"
+	withLastCreated bool   // Also report the latest document date
+	metadataQuery   string // Display metadata read by Decorate, if any
+}
+
+func init() {
+	RegisterFacetProvider("correspondent", entityFacetProvider{
+		table:           "documents_correspondent",
+		link:            "%s JOIN documents_document d ON d.correspondent_id = e.id",
+		withLastCreated: true,
+		metadataQuery:   "SELECT id, match, matching_algorithm, is_insensitive FROM documents_correspondent",
+	})
+	RegisterFacetProvider("document_type", entityFacetProvider{
+		table:           "documents_documenttype",
+		link:            "%s JOIN documents_document d ON d.document_type_id = e.id",
+		withLastCreated: true,
+	})
+	RegisterFacetProvider("tag", entityFacetProvider{
+		table:         "documents_tag",
+		link:          "%[1]s JOIN documents_document_tags dtags ON dtags.tag_id = e.id %[1]s JOIN documents_document d ON d.id = dtags.document_id",
+		metadataQuery: "SELECT id, color, is_inbox_tag FROM documents_tag",
+	})
+	RegisterFacetProvider("storage_path", entityFacetProvider{
+		table:         "documents_storagepath",
+		link:          "%s JOIN documents_document d ON d.storage_path_id = e.id",
+		metadataQuery: "SELECT id, path FROM documents_storagepath",
+	})
+}
+
+func (p entityFacetProvider) BuildQuery(q FacetQuery) (string, []interface{}, error) {
+	columns := "e.id, e.name, COUNT(DISTINCT d.id) as doc_count"
+	if p.withLastCreated {
+		columns += ", MAX(d.created) as last_created"
+	}
+
+	// Zero-count options need the filter conditions inside the document join
+	if q.IncludeEmpty {
+		return fmt.Sprintf(`
+			SELECT %s
+			FROM %s e
+			%s AND %s AND %s
+			GROUP BY e.id, e.name
+			ORDER BY doc_count DESC, e.name ASC
+		`, columns, p.table, fmt.Sprintf(p.link, "LEFT"), q.DocumentCondition, q.FilterCondition), q.FilterArgs, nil
+	}
+
+	return fmt.Sprintf(`
+		SELECT %s
+		FROM %s e
+		%s AND %s
+		WHERE %s
+		GROUP BY e.id, e.name
+		ORDER BY doc_count DESC, e.name ASC
+	`, columns, p.table, fmt.Sprintf(p.link, "INNER"), q.DocumentCondition, q.FilterCondition), q.FilterArgs, nil
+}
+
+func (p entityFacetProvider) Scan(rows *sql.Rows, q FacetQuery) ([]BuiltinFilterValueOption, error) {
+	var values []BuiltinFilterValueOption
+	for rows.Next() {
+		var id interface{}
+		var label string
+		var count int
+		var lastCreated interface{}
+
+		dest := []interface{}{&id, &label, &count}
+		if p.withLastCreated {
+			dest = append(dest, &lastCreated)
+		}
+		if err := rows.Scan(dest...); err != nil {
+			continue
+		}
+
+		option := BuiltinFilterValueOption{ID: id, Label: label, Count: count}
+		if date, ok := parseStatsDate(lastCreated); ok {
+			formatted := date.Format("2006-01-02")
+			option.LastDocumentCreated = &formatted
+		}
+		values = append(values, option)
+	}
+	return values, rows.Err()
+}
+
+// Decorate fills in the display metadata of tag (color, inbox flag), storage path (path
+// template) and correspondent (matching rule) options
+func (p entityFacetProvider) Decorate(ctx context.Context, db *sql.DB, q FacetQuery, values []BuiltinFilterValueOption) error {
+	if p.metadataQuery == "" || len(values) == 0 {
+		return nil
+	}
+
+	indexes := make(map[string]int, len(values))
+	for i, option := range values {
+		indexes[builtinOptionKey(option.ID)] = i
+	}
+
+	rows, err := db.QueryContext(ctx, p.metadataQuery)
+	if err != nil {
+		return fmt.Errorf("failed to query %s metadata: %w", q.FilterType, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id interface{}
+		var text sql.NullString
+		var flag sql.NullBool
+		var algorithm sql.NullInt64
+
+		var scanErr error
+		switch q.FilterType {
+		case "tag":
+			scanErr = rows.Scan(&id, &text, &flag)
+		case "storage_path":
+			scanErr = rows.Scan(&id, &text)
+		case "correspondent":
+			scanErr = rows.Scan(&id, &text, &algorithm, &flag)
+		}
+		if scanErr != nil {
+			continue
+		}
+
+		i, ok := indexes[builtinOptionKey(id)]
+		if !ok {
+			continue
+		}
+		option := &values[i]
+		switch q.FilterType {
+		case "tag":
+			option.Color = text.String
+			if flag.Valid {
+				option.IsInboxTag = &flag.Bool
+			}
+		case "storage_path":
+			option.Path = text.String
+		case "correspondent":
+			option.Match = text.String
+			if algorithm.Valid {
+				value := int(algorithm.Int64)
+				option.MatchingAlgorithm = &value
+			}
+			if flag.Valid {
+				option.IsInsensitive = &flag.Bool
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read %s metadata: %w", q.FilterType, err)
+	}
+
+	return nil
+}
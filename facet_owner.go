@@ -0,0 +1,61 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+)
+
+// noOwnerLabel is the label of the owner option for documents without an owner
+const noOwnerLabel = "(No owner)"
+
+// ownerFacetProvider counts documents per owner, resolving owner IDs to users. Documents
+// without an owner are counted in a "(No owner)" option with a null ID.
+type ownerFacetProvider struct{}
+
+func init() {
+	RegisterFacetProvider("owner", ownerFacetProvider{})
+}
+
+func (ownerFacetProvider) BuildQuery(q FacetQuery) (string, []interface{}, error) {
+	return fmt.Sprintf(`
+		SELECT d.owner_id, u.username, u.first_name, u.last_name, COUNT(DISTINCT d.id) as doc_count
+		FROM documents_document d
+		LEFT JOIN auth_user u ON u.id = d.owner_id
+		WHERE %s AND %s
+		GROUP BY d.owner_id, u.username, u.first_name, u.last_name
+		ORDER BY doc_count DESC, u.username ASC
+	`, q.DocumentCondition, q.FilterCondition), q.FilterArgs, nil
+}
+
+func (ownerFacetProvider) Scan(rows *sql.Rows, q FacetQuery) ([]BuiltinFilterValueOption, error) {
+	var values []BuiltinFilterValueOption
+	for rows.Next() {
+		var ownerID sql.NullInt64
+		var username, firstName, lastName sql.NullString
+		var count int
+		if err := rows.Scan(&ownerID, &username, &firstName, &lastName, &count); err != nil {
+			continue
+		}
+
+		if !ownerID.Valid {
+			values = append(values, BuiltinFilterValueOption{ID: nil, Label: noOwnerLabel, Count: count})
+			continue
+		}
+
+		// Owners whose user was deleted keep their raw ID as label
+		label := username.String
+		if !username.Valid {
+			label = strconv.FormatInt(ownerID.Int64, 10)
+		}
+		values = append(values, BuiltinFilterValueOption{
+			ID:        ownerID.Int64,
+			Label:     label,
+			Count:     count,
+			Username:  username.String,
+			FirstName: firstName.String,
+			LastName:  lastName.String,
+		})
+	}
+	return values, rows.Err()
+}
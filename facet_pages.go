@@ -0,0 +1,72 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// pageCountBuckets are the upper bounds (inclusive) of the "pages" filter type buckets;
+// larger page counts fall into a final open bucket
+var pageCountBuckets = []struct{ Max int }{{1}, {5}, {10}, {20}, {50}, {100}}
+
+// pageCountBucketLabel returns the label of a "pages" bucket index ("1", "2-5", "101+"),
+// where index 0 holds documents with an unknown page count
+func pageCountBucketLabel(index int) string {
+	if index <= 0 {
+		return "(Unknown)"
+	}
+	lower := 1
+	if index > 1 {
+		lower = pageCountBuckets[index-2].Max + 1
+	}
+	if index > len(pageCountBuckets) {
+		return fmt.Sprintf("%d+", lower)
+	}
+	upper := pageCountBuckets[index-1].Max
+	if lower == upper {
+		return strconv.Itoa(lower)
+	}
+	return fmt.Sprintf("%d-%d", lower, upper)
+}
+
+// pagesFacetProvider counts documents per page count bucket
+type pagesFacetProvider struct{}
+
+func init() {
+	RegisterFacetProvider("pages", pagesFacetProvider{})
+}
+
+func (pagesFacetProvider) BuildQuery(q FacetQuery) (string, []interface{}, error) {
+	// Bucket 0 collects unknown page counts; Scan replaces indexes by labels
+	var cases []string
+	for i, bucket := range pageCountBuckets {
+		cases = append(cases, fmt.Sprintf("WHEN d.page_count <= %d THEN %d", bucket.Max, i+1))
+	}
+	bucket := fmt.Sprintf("CASE WHEN d.page_count IS NULL THEN 0 %s ELSE %d END", strings.Join(cases, " "), len(pageCountBuckets)+1)
+	return fmt.Sprintf(`
+		SELECT %s as bucket, %s as label, COUNT(DISTINCT d.id) as doc_count
+		FROM documents_document d
+		WHERE %s AND %s
+		GROUP BY 1
+		ORDER BY 1 ASC
+	`, bucket, bucket, q.DocumentCondition, q.FilterCondition), q.FilterArgs, nil
+}
+
+func (pagesFacetProvider) Scan(rows *sql.Rows, q FacetQuery) ([]BuiltinFilterValueOption, error) {
+	values, err := scanFacetOptions(rows)
+	if err != nil {
+		return nil, err
+	}
+	for i := range values {
+		index, err := strconv.Atoi(builtinOptionKey(values[i].ID))
+		if err != nil {
+			continue
+		}
+		label := pageCountBucketLabel(index)
+		values[i].ID = label
+		values[i].Label = label
+	}
+	return values, nil
+}
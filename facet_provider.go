@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+)
+
+// FacetQuery holds what a FacetProvider needs to count the documents matching the current
+// filter rules. The conditions refer to the documents table as "d". Queries use either
+// DocumentCondition or Visibility once, before FilterCondition, so that FilterArgs match
+// their placeholders.
+type FacetQuery struct {
+	FilterType        string
+	DBEngine          string
+	DocumentCondition string        // Trash and visibility condition of counted documents
+	Visibility        string        // Visibility condition alone ("1 = 1" when unscoped)
+	FilterCondition   string        // Filter rules of the other dimensions ("1 = 1" without rules)
+	FilterArgs        []interface{} // Arguments of DocumentCondition or Visibility and FilterCondition, in that order
+	Filtered          bool          // Whether FilterCondition restricts the documents
+	IncludeEmpty      bool          // Also return options without matching documents
+	ASN               ASNBucketing
+}
+
+// usePostgres reports whether queries use PostgreSQL placeholders and syntax
+func (q FacetQuery) usePostgres() bool {
+	return q.DBEngine == "postgresql" || q.DBEngine == "postgres"
+}
+
+// FacetProvider computes the options of one builtin filter type. BuildQuery returns the
+// counting query with its arguments and Scan turns its rows into options.
+type FacetProvider interface {
+	BuildQuery(q FacetQuery) (string, []interface{}, error)
+	Scan(rows *sql.Rows, q FacetQuery) ([]BuiltinFilterValueOption, error)
+}
+
+// FacetDecorator is implemented by providers that add details to the scanned options
+// with further queries, e.g. tag colors
+type FacetDecorator interface {
+	Decorate(ctx context.Context, db *sql.DB, q FacetQuery, values []BuiltinFilterValueOption) error
+}
+
+// facetProviders are the registered builtin filter types
+var facetProviders = make(map[string]FacetProvider)
+
+// RegisterFacetProvider makes a builtin filter type available under filterType. It is meant
+// to be called from init functions and panics on duplicate registrations.
+func RegisterFacetProvider(filterType string, provider FacetProvider) {
+	if provider == nil {
+		panic("facet provider is nil")
+	}
+	if _, exists := facetProviders[filterType]; exists {
+		panic(fmt.Sprintf("facet provider registered twice for %s", filterType))
+	}
+	facetProviders[filterType] = provider
+}
+
+// lookupFacetProvider returns the provider of a builtin filter type
+func lookupFacetProvider(filterType string) (FacetProvider, bool) {
+	provider, ok := facetProviders[filterType]
+	return provider, ok
+}
+
+// registeredFacetTypes lists the registered builtin filter types in alphabetical order
+func registeredFacetTypes() []string {
+	types := make([]string, 0, len(facetProviders))
+	for filterType := range facetProviders {
+		types = append(types, filterType)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// scanFacetOptions scans rows of (id, label, doc_count) into options
+func scanFacetOptions(rows *sql.Rows) ([]BuiltinFilterValueOption, error) {
+	var values []BuiltinFilterValueOption
+	for rows.Next() {
+		var id interface{}
+		var label string
+		var count int
+		if err := rows.Scan(&id, &label, &count); err != nil {
+			continue
+		}
+		values = append(values, BuiltinFilterValueOption{ID: id, Label: label, Count: count})
+	}
+	return values, rows.Err()
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// newFacetTestService creates a service with four documents spread over the builtin
+// filter types; document 3 is in the trash and document 4 belongs to a deleted user
+func newFacetTestService(t *testing.T) *Service {
+	t.Helper()
+	s := newTestService(t, nil)
+	mustExec(t, s,
+		`INSERT INTO auth_user (id, username, first_name, last_name, is_superuser) VALUES (1, 'admin', 'Ada', 'Admin', 1)`,
+		`INSERT INTO documents_correspondent (id, name, match, matching_algorithm, is_insensitive) VALUES
+			(1, 'ACME', 'acme', 1, 1), (2, 'Bank', '', 0, 0)`,
+		`INSERT INTO documents_documenttype (id, name) VALUES (1, 'Invoice')`,
+		`INSERT INTO documents_tag (id, name, color, is_inbox_tag) VALUES (1, 'Inbox', '#ff0000', 1), (2, 'Finance', '#00ff00', 0)`,
+		`INSERT INTO documents_storagepath (id, name, path) VALUES (1, 'Archive', '{title}')`,
+		`INSERT INTO documents_document (id, title, correspondent_id, document_type_id, storage_path_id, owner_id,
+			archive_serial_number, page_count, created, added, deleted_at) VALUES
+			(1, 'first', 1, 1, 1, 1, 5, 1, '2023-05-01', '2024-01-10', NULL),
+			(2, 'second', 1, 1, NULL, NULL, 1500, 3, '2024-02-01', '2024-02-05', NULL),
+			(3, 'trashed', 2, NULL, NULL, 1, NULL, NULL, '2024-02-20', '2024-02-21', '2024-04-01'),
+			(4, 'orphaned', 2, NULL, NULL, 99, NULL, 12, '2024-03-01', '2024-03-02', NULL)`,
+		`INSERT INTO documents_document_tags (document_id, tag_id) VALUES (1, 1), (1, 2), (2, 2)`,
+	)
+	return s
+}
+
+// facetCounts returns the options as "label:count" in their order
+func facetCounts(values []BuiltinFilterValueOption) []string {
+	counts := []string{}
+	for _, option := range values {
+		counts = append(counts, fmt.Sprintf("%s:%d", option.Label, option.Count))
+	}
+	return counts
+}
+
+func TestRegisteredFacetTypes(t *testing.T) {
+	want := []string{"added_month", "asn", "correspondent", "created_month", "created_year", "document_type",
+		"owner", "pages", "status", "storage_path", "tag"}
+	if got := registeredFacetTypes(); !reflect.DeepEqual(got, want) {
+		t.Errorf("registered %v, want %v", got, want)
+	}
+	for filterType := range builtinFilterRuleTypes {
+		if _, ok := lookupFacetProvider(filterType); !ok {
+			t.Errorf("filter type %s has a rule dimension but no provider", filterType)
+		}
+	}
+}
+
+func TestRegisterFacetProviderPanics(t *testing.T) {
+	tests := map[string]func(){
+		"duplicate": func() { RegisterFacetProvider("tag", pagesFacetProvider{}) },
+		"nil":       func() { RegisterFacetProvider("nil_provider", nil) },
+	}
+	for name, register := range tests {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s registration did not panic", name)
+				}
+			}()
+			register()
+		}()
+	}
+	if _, ok := lookupFacetProvider("nil_provider"); ok {
+		t.Error("nil provider was registered")
+	}
+	if provider, _ := lookupFacetProvider("tag"); reflect.TypeOf(provider) != reflect.TypeOf(entityFacetProvider{}) {
+		t.Errorf("duplicate registration replaced the tag provider with %T", provider)
+	}
+}
+
+// constantFacetProvider is a provider as a third party would compile in: one option
+// counting all documents, decorated with a color
+type constantFacetProvider struct{}
+
+func (constantFacetProvider) BuildQuery(q FacetQuery) (string, []interface{}, error) {
+	return fmt.Sprintf("SELECT 'all', 'All documents', COUNT(*) FROM documents_document d WHERE %s AND %s",
+		q.DocumentCondition, q.FilterCondition), q.FilterArgs, nil
+}
+
+func (constantFacetProvider) Scan(rows *sql.Rows, q FacetQuery) ([]BuiltinFilterValueOption, error) {
+	return scanFacetOptions(rows)
+}
+
+func (constantFacetProvider) Decorate(ctx context.Context, db *sql.DB, q FacetQuery, values []BuiltinFilterValueOption) error {
+	for i := range values {
+		values[i].Color = "#123456"
+	}
+	return nil
+}
+
+func TestCustomFacetProvider(t *testing.T) {
+	s := newFacetTestService(t)
+	ctx := context.Background()
+	RegisterFacetProvider("test_constant", constantFacetProvider{})
+	t.Cleanup(func() { delete(facetProviders, "test_constant") })
+
+	values, err := s.GetBuiltinFilterValues(ctx, "test_constant", "", false, false, ASNBucketing{}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(values) != 1 || values[0].Count != 3 || values[0].Color != "#123456" {
+		t.Errorf("got %+v, want one decorated option counting 3 documents", values)
+	}
+
+	// Filter rules of all dimensions apply to providers without a rule dimension
+	values, err = s.GetBuiltinFilterValues(ctx, "test_constant", `[{"rule_type": 3, "value": "1"}]`, true, false, ASNBucketing{}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := facetCounts(values); !reflect.DeepEqual(got, []string{"All documents:2"}) {
+		t.Errorf("filtered: got %v", got)
+	}
+
+	_, err = s.GetBuiltinFilterValues(ctx, "unknown", "", false, false, ASNBucketing{}, 0)
+	if err == nil || !strings.Contains(err.Error(), "test_constant") {
+		t.Errorf("unknown filter type: got %v, want an error listing the registered types", err)
+	}
+}
+
+func TestFacetProviders(t *testing.T) {
+	s := newFacetTestService(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		filterType     string
+		filterRules    string
+		includeTrashed bool
+		includeEmpty   bool
+		asn            ASNBucketing
+		want           []string
+	}{
+		{filterType: "correspondent", want: []string{"ACME:2", "Bank:1"}},
+		{filterType: "correspondent", includeTrashed: true, want: []string{"ACME:2", "Bank:2"}},
+		// Rules of the own dimension are ignored, the others apply
+		{filterType: "correspondent", filterRules: `[{"rule_type": 3, "value": "2"}, {"rule_type": 4, "value": "1"}]`, want: []string{"ACME:2"}},
+		{filterType: "correspondent", filterRules: `[{"rule_type": 4, "value": "1"}]`, includeEmpty: true, want: []string{"ACME:2", "Bank:0"}},
+		{filterType: "document_type", want: []string{"Invoice:2"}},
+		{filterType: "document_type", filterRules: `[{"rule_type": 3, "value": "2"}]`, want: []string{}},
+		{filterType: "tag", want: []string{"Finance:2", "Inbox:1"}},
+		{filterType: "tag", filterRules: `[{"rule_type": 22, "value": "1"}, {"rule_type": 17, "value": "2"}]`, want: []string{"Finance:2", "Inbox:1"}},
+		{filterType: "storage_path", want: []string{"Archive:1"}},
+		{filterType: "asn", want: []string{"0-999:1", "1000-1999:1"}},
+		{filterType: "asn", asn: ASNBucketing{BucketSize: 10}, want: []string{"0-9:1", "1500-1509:1"}},
+		{filterType: "asn", asn: ASNBucketing{DrillDown: true, From: 0, To: 999}, want: []string{"5:1"}},
+		{filterType: "pages", want: []string{"1:1", "2-5:1", "11-20:1"}},
+		{filterType: "pages", includeTrashed: true, want: []string{"(Unknown):1", "1:1", "2-5:1", "11-20:1"}},
+		{filterType: "created_year", want: []string{"2023:1", "2024:2"}},
+		{filterType: "created_year", filterRules: `[{"rule_type": 9, "value": "2024-02-01"}]`, want: []string{"2023:1", "2024:2"}},
+		{filterType: "created_month", want: []string{"2023-05:1", "2024-02:1", "2024-03:1"}},
+		{filterType: "added_month", want: []string{"2024-01:1", "2024-02:1", "2024-03:1"}},
+		{filterType: "status", want: []string{"Inbox:1", "Archived:2", "Trash:1"}},
+		{filterType: "status", includeEmpty: true, want: []string{"Inbox:1", "Archived:2", "Trash:1", "Shared:0"}},
+	}
+	for _, tt := range tests {
+		name := fmt.Sprintf("%s %s trashed=%v empty=%v %+v", tt.filterType, tt.filterRules, tt.includeTrashed, tt.includeEmpty, tt.asn)
+		values, err := s.GetBuiltinFilterValues(ctx, tt.filterType, tt.filterRules, tt.includeTrashed, tt.includeEmpty, tt.asn, 0)
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if got := facetCounts(values); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %v, want %v", name, got, tt.want)
+		}
+	}
+}
+
+func TestOwnerFacetProvider(t *testing.T) {
+	s := newFacetTestService(t)
+
+	values, err := s.GetBuiltinFilterValues(context.Background(), "owner", "", false, false, ASNBucketing{}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]BuiltinFilterValueOption{}
+	for _, option := range values {
+		got[option.Label] = option
+	}
+	if len(got) != 3 {
+		t.Fatalf("got options %v, want admin, (No owner) and 99", facetCounts(values))
+	}
+	if admin := got["admin"]; admin.ID != int64(1) || admin.Count != 1 || admin.FirstName != "Ada" || admin.LastName != "Admin" {
+		t.Errorf("admin option %+v", admin)
+	}
+	if none, ok := got[noOwnerLabel]; !ok || none.ID != nil || none.Count != 1 {
+		t.Errorf("no owner option %+v", none)
+	}
+	if deleted, ok := got["99"]; !ok || deleted.ID != int64(99) || deleted.Username != "" {
+		t.Errorf("deleted owner option %+v", deleted)
+	}
+}
+
+func TestEntityFacetProviderDecorates(t *testing.T) {
+	s := newFacetTestService(t)
+	ctx := context.Background()
+
+	options := func(filterType string) map[string]BuiltinFilterValueOption {
+		values, err := s.GetBuiltinFilterValues(ctx, filterType, "", false, false, ASNBucketing{}, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		byLabel := map[string]BuiltinFilterValueOption{}
+		for _, option := range values {
+			byLabel[option.Label] = option
+		}
+		return byLabel
+	}
+
+	tags := options("tag")
+	if inbox := tags["Inbox"]; inbox.Color != "#ff0000" || inbox.IsInboxTag == nil || !*inbox.IsInboxTag {
+		t.Errorf("inbox tag %+v", inbox)
+	}
+	if finance := tags["Finance"]; finance.Color != "#00ff00" || finance.IsInboxTag == nil || *finance.IsInboxTag {
+		t.Errorf("finance tag %+v", finance)
+	}
+
+	if archive := options("storage_path")["Archive"]; archive.Path != "{title}" {
+		t.Errorf("storage path %+v", archive)
+	}
+
+	acme := options("correspondent")["ACME"]
+	if acme.Match != "acme" || acme.MatchingAlgorithm == nil || *acme.MatchingAlgorithm != 1 || acme.IsInsensitive == nil || !*acme.IsInsensitive {
+		t.Errorf("correspondent %+v", acme)
+	}
+	if acme.LastDocumentCreated == nil || *acme.LastDocumentCreated != "2024-02-01" {
+		t.Errorf("correspondent last document created %v, want 2024-02-01", acme.LastDocumentCreated)
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// documentStatuses are the options of the "status" filter type, in display order
+var documentStatuses = []struct{ ID, Label string }{
+	{"inbox", "Inbox"},
+	{"archived", "Archived"},
+	{"trashed", "Trash"},
+	{"shared", "Shared"},
+}
+
+// statusFacetProvider counts documents in the inbox, archived (out of the inbox), in the
+// trash and shared with other users or groups. Statuses overlap: a shared document is
+// also counted as inbox or archived. Trashed documents are always counted in their own
+// option and never in the others.
+type statusFacetProvider struct{}
+
+func init() {
+	RegisterFacetProvider("status", statusFacetProvider{})
+}
+
+func (statusFacetProvider) BuildQuery(q FacetQuery) (string, []interface{}, error) {
+	inbox := inboxTaggedCondition
+	return fmt.Sprintf(`
+		SELECT
+			SUM(CASE WHEN d.deleted_at IS NULL AND %s THEN 1 ELSE 0 END) as inbox_count,
+			SUM(CASE WHEN d.deleted_at IS NULL AND NOT (%s) THEN 1 ELSE 0 END) as archived_count,
+			SUM(CASE WHEN d.deleted_at IS NOT NULL THEN 1 ELSE 0 END) as trash_count,
+			SUM(CASE WHEN d.deleted_at IS NULL AND %s THEN 1 ELSE 0 END) as shared_count
+		FROM documents_document d
+		WHERE %s AND %s
+	`, inbox, inbox, documentSharedCondition(q.DBEngine), q.Visibility, q.FilterCondition), q.FilterArgs, nil
+}
+
+func (statusFacetProvider) Scan(rows *sql.Rows, q FacetQuery) ([]BuiltinFilterValueOption, error) {
+	counts := make([]sql.NullInt64, len(documentStatuses))
+	if rows.Next() {
+		dest := make([]interface{}, len(counts))
+		for i := range counts {
+			dest[i] = &counts[i]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	values := make([]BuiltinFilterValueOption, 0, len(documentStatuses))
+	for i, status := range documentStatuses {
+		if counts[i].Int64 == 0 && !q.IncludeEmpty {
+			continue
+		}
+		values = append(values, BuiltinFilterValueOption{ID: status.ID, Label: status.Label, Count: int(counts[i].Int64)})
+	}
+	return values, nil
+}
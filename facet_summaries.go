@@ -0,0 +1,612 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// facetSummaryBatchSize is the number of changed documents applied per transaction
+const facetSummaryBatchSize = 500
+
+// facetSummaryState is the bookkeeping row of a materialized facet summary
+type facetSummaryState struct {
+	DataType        string
+	TotalDocuments  int
+	ValuedDocuments int
+	Watermark       sql.NullString // Latest document modification seen (scheduled deltas)
+	DataAsOf        time.Time
+}
+
+// facetSummariesEnabled reports whether facet summaries are materialized for any field
+func (s *Service) facetSummariesEnabled() bool {
+	return len(s.config.FacetSummaryFields) > 0
+}
+
+// isFacetSummaryField reports whether the value counts of fieldID are materialized
+func (s *Service) isFacetSummaryField(fieldID int) bool {
+	for _, id := range s.config.FacetSummaryFields {
+		if id == fieldID {
+			return true
+		}
+	}
+	return false
+}
+
+// facetValueHash keys a value in facet_value_counts; values are too long for a primary key
+func facetValueHash(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:])
+}
+
+// placeholders returns n comma separated ? placeholders
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+// watermarkString formats the latest document modification time so it compares correctly
+// with the modified column of the engine
+func (s *Service) watermarkString(raw interface{}) sql.NullString {
+	switch v := raw.(type) {
+	case nil:
+		return sql.NullString{}
+	case time.Time:
+		if s.config.DBEngine == "mysql" || s.config.DBEngine == "mariadb" {
+			return sql.NullString{String: v.UTC().Format("2006-01-02 15:04:05.999999"), Valid: true}
+		}
+		return sql.NullString{String: v.UTC().Format("2006-01-02 15:04:05.999999-07:00"), Valid: true}
+	default:
+		return sql.NullString{String: statsString(v), Valid: true}
+	}
+}
+
+// installFacetChangeTriggers logs changed documents to facet_summary_changes on PostgreSQL,
+// so summaries are refreshed from the change log instead of scanning modification times
+func (s *Service) installFacetChangeTriggers() bool {
+	if s.config.DBEngine != "postgresql" && s.config.DBEngine != "postgres" {
+		return false
+	}
+
+	statements := []string{`
+		CREATE OR REPLACE FUNCTION paperless_link_log_facet_changes() RETURNS trigger AS $$
+		BEGIN
+			IF TG_OP = 'DELETE' THEN
+				INSERT INTO facet_summary_changes (document_id) VALUES ((to_jsonb(OLD)->>TG_ARGV[0])::integer);
+			ELSE
+				INSERT INTO facet_summary_changes (document_id) VALUES ((to_jsonb(NEW)->>TG_ARGV[0])::integer);
+			END IF;
+			RETURN NULL;
+		END;
+		$$ LANGUAGE plpgsql;
+	`, `
+		DROP TRIGGER IF EXISTS paperless_link_facet_changes ON documents_customfieldinstance;
+		CREATE TRIGGER paperless_link_facet_changes
+			AFTER INSERT OR UPDATE OR DELETE ON documents_customfieldinstance
+			FOR EACH ROW EXECUTE PROCEDURE paperless_link_log_facet_changes('document_id');
+	`, `
+		DROP TRIGGER IF EXISTS paperless_link_facet_changes ON documents_document;
+		CREATE TRIGGER paperless_link_facet_changes
+			AFTER UPDATE OF deleted_at OR DELETE ON documents_document
+			FOR EACH ROW EXECUTE PROCEDURE paperless_link_log_facet_changes('id');
+	`}
+	for _, statement := range statements {
+		if _, err := s.db.Exec(statement); err != nil {
+			log.Printf("[FacetSummary] Could not install change triggers, falling back to scheduled deltas: %v", err)
+			return false
+		}
+	}
+	log.Printf("[FacetSummary] Change triggers installed")
+	return true
+}
+
+// removeFacetChangeTriggers drops the triggers and function of installFacetChangeTriggers,
+// which would otherwise keep logging changes to the Paperless tables, and clears the log
+func (s *Service) removeFacetChangeTriggers() {
+	if s.config.DBEngine != "postgresql" && s.config.DBEngine != "postgres" {
+		return
+	}
+
+	statements := []string{
+		"DROP TRIGGER IF EXISTS paperless_link_facet_changes ON documents_customfieldinstance",
+		"DROP TRIGGER IF EXISTS paperless_link_facet_changes ON documents_document",
+		"DROP FUNCTION IF EXISTS paperless_link_log_facet_changes()",
+		"DELETE FROM facet_summary_changes",
+	}
+	for _, statement := range statements {
+		if _, err := s.db.Exec(statement); err != nil {
+			log.Printf("[FacetSummary] Could not remove change triggers: %v", err)
+			return
+		}
+	}
+}
+
+// loadFacetSummaryState returns the bookkeeping row of a field's summary, or nil if the
+// summary was never built
+func (s *Service) loadFacetSummaryState(ctx context.Context, fieldID int) (*facetSummaryState, error) {
+	state := &facetSummaryState{}
+	err := s.db.QueryRowContext(ctx, s.rebind(`
+		SELECT data_type, total_documents, valued_documents, watermark, data_as_of
+		FROM facet_summary_fields
+		WHERE field_id = ?
+	`), fieldID).Scan(&state.DataType, &state.TotalDocuments, &state.ValuedDocuments, &state.Watermark, &state.DataAsOf)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read facet summary state: %w", err)
+	}
+	return state, nil
+}
+
+// saveFacetSummaryState recounts the documents of a field's summary and stores its state
+func (s *Service) saveFacetSummaryState(ctx context.Context, fieldID int, dataType string, watermark sql.NullString) error {
+	var total, valued int
+	if err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM documents_document d WHERE "+trashedCondition(false)).Scan(&total); err != nil {
+		return fmt.Errorf("failed to count documents: %w", err)
+	}
+	if err := s.db.QueryRowContext(ctx, s.rebind("SELECT COUNT(DISTINCT document_id) FROM facet_document_values WHERE field_id = ?"), fieldID).Scan(&valued); err != nil {
+		return fmt.Errorf("failed to count documents with values: %w", err)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, s.rebind("DELETE FROM facet_summary_fields WHERE field_id = ?"), fieldID); err != nil {
+		return fmt.Errorf("failed to clear facet summary state: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, s.rebind(`
+		INSERT INTO facet_summary_fields (field_id, data_type, total_documents, valued_documents, watermark, data_as_of)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`), fieldID, dataType, total, valued, watermark, time.Now().UTC()); err != nil {
+		return fmt.Errorf("failed to store facet summary state: %w", err)
+	}
+	return tx.Commit()
+}
+
+// latestDocumentModification returns the watermark of the current document data
+func (s *Service) latestDocumentModification(ctx context.Context) (sql.NullString, error) {
+	var latest interface{}
+	if err := s.db.QueryRowContext(ctx, "SELECT MAX(modified) FROM documents_document").Scan(&latest); err != nil {
+		return sql.NullString{}, fmt.Errorf("failed to read latest modification: %w", err)
+	}
+	return s.watermarkString(latest), nil
+}
+
+// documentValueSets reads the current values of a field per document, split into their
+// individual entries. With documentIDs only those documents are read.
+func (s *Service) documentValueSets(ctx context.Context, valueColumn string, fieldID int, documentIDs []int) (map[int]map[string]bool, error) {
+	var rows *sql.Rows
+	var err error
+	if documentIDs == nil {
+		rows, err = s.queryPrepared(ctx, s.fieldValuesQuery(valueColumn, false), fieldID)
+	} else {
+		args := []interface{}{fieldID}
+		for _, id := range documentIDs {
+			args = append(args, id)
+		}
+		rows, err = s.db.QueryContext(ctx, s.rebind(fmt.Sprintf(`
+			SELECT cfi.%s, cfi.document_id
+			FROM documents_customfieldinstance cfi
+			INNER JOIN documents_document d ON cfi.document_id = d.id
+			WHERE cfi.field_id = ?
+				AND cfi.deleted_at IS NULL
+				AND cfi.%s IS NOT NULL
+				AND cfi.%s != ''
+				AND %s
+				AND cfi.document_id IN (%s)
+		`, valueColumn, valueColumn, valueColumn, trashedCondition(false), placeholders(len(documentIDs)))), args...)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query field values: %w", err)
+	}
+	defer rows.Close()
+
+	sets := make(map[int]map[string]bool)
+	for rows.Next() {
+		var value string
+		var documentID int
+		if err := rows.Scan(&value, &documentID); err != nil {
+			continue
+		}
+		for _, part := range parseValueList(value) {
+			if part = strings.TrimSpace(part); part == "" {
+				continue
+			}
+			if sets[documentID] == nil {
+				sets[documentID] = make(map[string]bool)
+			}
+			sets[documentID][part] = true
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read field values: %w", err)
+	}
+	return sets, nil
+}
+
+// rebuildFacetSummary materializes the value counts of a field from scratch
+func (s *Service) rebuildFacetSummary(ctx context.Context, fieldID int, meta *fieldMeta) error {
+	watermark, err := s.latestDocumentModification(ctx)
+	if err != nil {
+		return err
+	}
+	sets, err := s.documentValueSets(ctx, getValueColumnName(meta.DataType), fieldID, nil)
+	if err != nil {
+		return err
+	}
+
+	counts := make(map[string]int)
+	for _, values := range sets {
+		for value := range values {
+			counts[value]++
+		}
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, table := range []string{"facet_document_values", "facet_value_counts"} {
+		if _, err := tx.ExecContext(ctx, s.rebind("DELETE FROM "+table+" WHERE field_id = ?"), fieldID); err != nil {
+			return fmt.Errorf("failed to clear %s: %w", table, err)
+		}
+	}
+	insertValue, err := tx.PrepareContext(ctx, s.rebind("INSERT INTO facet_document_values (field_id, document_id, value) VALUES (?, ?, ?)"))
+	if err != nil {
+		return fmt.Errorf("failed to prepare value insert: %w", err)
+	}
+	defer insertValue.Close()
+	for documentID, values := range sets {
+		for value := range values {
+			if _, err := insertValue.ExecContext(ctx, fieldID, documentID, value); err != nil {
+				return fmt.Errorf("failed to store document value: %w", err)
+			}
+		}
+	}
+	insertCount, err := tx.PrepareContext(ctx, s.rebind("INSERT INTO facet_value_counts (field_id, value_hash, value, document_count) VALUES (?, ?, ?, ?)"))
+	if err != nil {
+		return fmt.Errorf("failed to prepare count insert: %w", err)
+	}
+	defer insertCount.Close()
+	for value, count := range counts {
+		if _, err := insertCount.ExecContext(ctx, fieldID, facetValueHash(value), value, count); err != nil {
+			return fmt.Errorf("failed to store value count: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	log.Printf("[FacetSummary] Rebuilt field %d: %d values over %d documents", fieldID, len(counts), len(sets))
+	return s.saveFacetSummaryState(ctx, fieldID, meta.DataType, watermark)
+}
+
+// applyFacetDeltas refreshes the materialized values of the given documents, adjusting the
+// value counts by the difference between their stored and current values
+func (s *Service) applyFacetDeltas(ctx context.Context, fieldID int, valueColumn string, documentIDs []int) error {
+	for start := 0; start < len(documentIDs); start += facetSummaryBatchSize {
+		end := start + facetSummaryBatchSize
+		if end > len(documentIDs) {
+			end = len(documentIDs)
+		}
+		if err := s.applyFacetDeltaBatch(ctx, fieldID, valueColumn, documentIDs[start:end]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyFacetDeltaBatch refreshes one batch of changed documents in a transaction
+func (s *Service) applyFacetDeltaBatch(ctx context.Context, fieldID int, valueColumn string, documentIDs []int) error {
+	current, err := s.documentValueSets(ctx, valueColumn, fieldID, documentIDs)
+	if err != nil {
+		return err
+	}
+
+	args := []interface{}{fieldID}
+	for _, id := range documentIDs {
+		args = append(args, id)
+	}
+	inDocuments := placeholders(len(documentIDs))
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	deltas := make(map[string]int)
+	rows, err := tx.QueryContext(ctx, s.rebind("SELECT value FROM facet_document_values WHERE field_id = ? AND document_id IN ("+inDocuments+")"), args...)
+	if err != nil {
+		return fmt.Errorf("failed to read stored values: %w", err)
+	}
+	for rows.Next() {
+		var value string
+		if err := rows.Scan(&value); err == nil {
+			deltas[value]--
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read stored values: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, s.rebind("DELETE FROM facet_document_values WHERE field_id = ? AND document_id IN ("+inDocuments+")"), args...); err != nil {
+		return fmt.Errorf("failed to clear stored values: %w", err)
+	}
+	insertValue, err := tx.PrepareContext(ctx, s.rebind("INSERT INTO facet_document_values (field_id, document_id, value) VALUES (?, ?, ?)"))
+	if err != nil {
+		return fmt.Errorf("failed to prepare value insert: %w", err)
+	}
+	defer insertValue.Close()
+	for documentID, values := range current {
+		for value := range values {
+			if _, err := insertValue.ExecContext(ctx, fieldID, documentID, value); err != nil {
+				return fmt.Errorf("failed to store document value: %w", err)
+			}
+			deltas[value]++
+		}
+	}
+
+	for value, delta := range deltas {
+		if delta == 0 {
+			continue
+		}
+		result, err := tx.ExecContext(ctx, s.rebind("UPDATE facet_value_counts SET document_count = document_count + ? WHERE field_id = ? AND value_hash = ?"),
+			delta, fieldID, facetValueHash(value))
+		if err != nil {
+			return fmt.Errorf("failed to update value count: %w", err)
+		}
+		if updated, _ := result.RowsAffected(); updated == 0 && delta > 0 {
+			if _, err := tx.ExecContext(ctx, s.rebind("INSERT INTO facet_value_counts (field_id, value_hash, value, document_count) VALUES (?, ?, ?, ?)"),
+				fieldID, facetValueHash(value), value, delta); err != nil {
+				return fmt.Errorf("failed to store value count: %w", err)
+			}
+		}
+	}
+	if _, err := tx.ExecContext(ctx, s.rebind("DELETE FROM facet_value_counts WHERE field_id = ? AND document_count <= 0"), fieldID); err != nil {
+		return fmt.Errorf("failed to remove unused values: %w", err)
+	}
+	return tx.Commit()
+}
+
+// scanDocumentIDs reads a single column of document IDs
+func scanDocumentIDs(rows *sql.Rows) ([]int, error) {
+	defer rows.Close()
+	ids := []int{}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// changedDocumentsSince returns the documents modified or trashed after the watermark of a
+// field's summary, and the documents of the summary that no longer exist
+func (s *Service) changedDocumentsSince(ctx context.Context, fieldID int, watermark sql.NullString) ([]int, error) {
+	seen := make(map[int]bool)
+	ids := []int{}
+	collect := func(query string, args ...interface{}) error {
+		rows, err := s.db.QueryContext(ctx, s.rebind(query), args...)
+		if err != nil {
+			return fmt.Errorf("failed to query changed documents: %w", err)
+		}
+		found, err := scanDocumentIDs(rows)
+		if err != nil {
+			return fmt.Errorf("failed to read changed documents: %w", err)
+		}
+		for _, id := range found {
+			if !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+		return nil
+	}
+
+	if watermark.Valid {
+		if err := collect("SELECT id FROM documents_document WHERE modified > ? OR deleted_at > ?", watermark.String, watermark.String); err != nil {
+			return nil, err
+		}
+	} else if err := collect("SELECT id FROM documents_document"); err != nil {
+		return nil, err
+	}
+	err := collect(`
+		SELECT DISTINCT f.document_id FROM facet_document_values f
+		WHERE f.field_id = ?
+			AND NOT EXISTS (SELECT 1 FROM documents_document d WHERE d.id = f.document_id)
+	`, fieldID)
+	return ids, err
+}
+
+// pendingFacetChanges returns the documents in the change log up to its latest entry. A log
+// longer than FACET_SUMMARY_CHANGE_LIMIT returns nil documents: it is cheaper to rebuild the
+// summaries than to apply it.
+func (s *Service) pendingFacetChanges(ctx context.Context) ([]int, int64, error) {
+	var minID, maxID sql.NullInt64
+	if err := s.db.QueryRowContext(ctx, "SELECT MIN(id), MAX(id) FROM facet_summary_changes").Scan(&minID, &maxID); err != nil {
+		return nil, 0, fmt.Errorf("failed to read change log: %w", err)
+	}
+	if !maxID.Valid {
+		return []int{}, 0, nil
+	}
+	if limit := s.config.FacetSummaryChangeLimit; limit > 0 && maxID.Int64-minID.Int64 >= int64(limit) {
+		return nil, maxID.Int64, nil
+	}
+	rows, err := s.db.QueryContext(ctx, "SELECT DISTINCT document_id FROM facet_summary_changes WHERE id <= $1", maxID.Int64)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to read change log: %w", err)
+	}
+	ids, err := scanDocumentIDs(rows)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to read change log: %w", err)
+	}
+	return ids, maxID.Int64, nil
+}
+
+// refreshFacetSummary brings the summary of one field up to date. Summaries that were never
+// built, whose field changed its data type or that are asked to are rebuilt; others apply the
+// changed documents, taken from the change log (changed != nil) or from modification times.
+func (s *Service) refreshFacetSummary(ctx context.Context, fieldID int, changed []int, rebuild bool) error {
+	meta, err := s.getFieldMeta(ctx, fieldID)
+	if err != nil {
+		return fmt.Errorf("failed to get field info: %w", err)
+	}
+	state, err := s.loadFacetSummaryState(ctx, fieldID)
+	if err != nil {
+		return err
+	}
+	if rebuild || state == nil || state.DataType != meta.DataType {
+		return s.rebuildFacetSummary(ctx, fieldID, meta)
+	}
+
+	watermark, err := s.latestDocumentModification(ctx)
+	if err != nil {
+		return err
+	}
+	if changed == nil {
+		if changed, err = s.changedDocumentsSince(ctx, fieldID, state.Watermark); err != nil {
+			return err
+		}
+	}
+	if err := s.applyFacetDeltas(ctx, fieldID, getValueColumnName(meta.DataType), changed); err != nil {
+		return err
+	}
+	return s.saveFacetSummaryState(ctx, fieldID, meta.DataType, watermark)
+}
+
+// refreshFacetSummaries refreshes the summaries of all configured fields once. The change
+// log is only cleared when every field applied it, or was rebuilt instead.
+func (s *Service) refreshFacetSummaries(ctx context.Context) {
+	var changed []int
+	var logged int64
+	rebuild := false
+	if s.facetTriggers {
+		var err error
+		if changed, logged, err = s.pendingFacetChanges(ctx); err != nil {
+			log.Printf("[FacetSummary] %v", err)
+			return
+		}
+		if changed == nil {
+			log.Printf("[FacetSummary] Change log exceeds %d entries, rebuilding the summaries", s.config.FacetSummaryChangeLimit)
+			rebuild = true
+		}
+	}
+
+	started := time.Now()
+	failed := 0
+	for _, fieldID := range s.config.FacetSummaryFields {
+		if ctx.Err() != nil {
+			return
+		}
+		if err := s.refreshFacetSummary(ctx, fieldID, changed, rebuild); err != nil {
+			log.Printf("[FacetSummary] Failed to refresh field %d: %v", fieldID, err)
+			failed++
+		}
+	}
+
+	if s.facetTriggers && failed == 0 && logged > 0 {
+		if _, err := s.db.ExecContext(ctx, "DELETE FROM facet_summary_changes WHERE id <= $1", logged); err != nil {
+			log.Printf("[FacetSummary] Failed to clear change log: %v", err)
+		}
+	}
+	if len(changed) > 0 || failed > 0 {
+		log.Printf("[FacetSummary] Refreshed %d/%d summaries (%d changed documents) in %s",
+			len(s.config.FacetSummaryFields)-failed, len(s.config.FacetSummaryFields), len(changed), time.Since(started))
+	}
+}
+
+// runFacetSummaryRefresher keeps the facet summaries up to date until ctx is cancelled
+func (s *Service) runFacetSummaryRefresher(ctx context.Context) {
+	log.Printf("[FacetSummary] Refresher started - Fields: %v, Interval: %s, Triggers: %t",
+		s.config.FacetSummaryFields, s.config.FacetSummaryInterval, s.facetTriggers)
+	s.refreshFacetSummaries(ctx)
+
+	ticker := time.NewTicker(s.config.FacetSummaryInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Printf("[FacetSummary] Refresher stopped")
+			return
+		case <-ticker.C:
+			s.refreshFacetSummaries(ctx)
+		}
+	}
+}
+
+// getFacetSummary returns the unfiltered value counts of a field from its materialized
+// summary, with the time of the last refresh as data_as_of
+func (s *Service) getFacetSummary(ctx context.Context, fieldID int) (*CustomFieldValuesResponse, bool) {
+	if !s.isFacetSummaryField(fieldID) {
+		return nil, false
+	}
+	state, err := s.loadFacetSummaryState(ctx, fieldID)
+	if err != nil || state == nil {
+		if err != nil {
+			log.Printf("[FacetSummary] %v", err)
+		}
+		return nil, false
+	}
+	meta, err := s.getFieldMeta(ctx, fieldID)
+	if err != nil || meta.DataType != state.DataType {
+		return nil, false
+	}
+
+	rows, err := s.db.QueryContext(ctx, s.rebind("SELECT value, document_count FROM facet_value_counts WHERE field_id = ?"), fieldID)
+	if err != nil {
+		log.Printf("[FacetSummary] Failed to read summary for field %d: %v", fieldID, err)
+		return nil, false
+	}
+	defer rows.Close()
+
+	labels := meta.SelectLabels
+	values := []CustomFieldValueOption{}
+	for rows.Next() {
+		var value string
+		var count int
+		if err := rows.Scan(&value, &count); err != nil {
+			continue
+		}
+		option := CustomFieldValueOption{ID: generateID(value), Label: value, Count: count}
+		if meta.DataType == "select" {
+			option.ID = value
+			if label, ok := labels[value]; ok {
+				option.Label = label
+			}
+		}
+		values = append(values, option)
+	}
+	if err := rows.Err(); err != nil {
+		log.Printf("[FacetSummary] Failed to read summary for field %d: %v", fieldID, err)
+		return nil, false
+	}
+	if blank := state.TotalDocuments - state.ValuedDocuments; blank > 0 {
+		values = append(values, CustomFieldValueOption{ID: "__blank__", Label: "(Blank)", Count: blank})
+	}
+
+	dataAsOf := state.DataAsOf.UTC().Format(time.RFC3339)
+	staleSeconds := int(time.Since(state.DataAsOf).Seconds())
+	return &CustomFieldValuesResponse{
+		FieldID:        fieldID,
+		FieldName:      meta.Name,
+		Values:         values,
+		TotalDocuments: state.TotalDocuments,
+		DataAsOf:       &dataAsOf,
+		StaleSeconds:   &staleSeconds,
+	}, true
+}
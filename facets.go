@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// FacetSpec selects one dimension of the facet response: either a custom field or a
+// builtin filter ("correspondent", "document_type", "tag", "storage_path", "owner", "asn")
+type FacetSpec struct {
+	FieldID   *int   `json:"field_id,omitempty"`
+	Dimension string `json:"dimension,omitempty"`
+	Sort      string `json:"sort,omitempty"`   // "recent" orders builtin values by their latest document
+	Expand    string `json:"expand,omitempty"` // "profile" adds the correspondent profiles
+}
+
+// FacetsRequest represents the request body for the combined facet endpoint
+type FacetsRequest struct {
+	Facets         []FacetSpec   `json:"facets"`
+	FilterRules    []interface{} `json:"filter_rules,omitempty"`
+	PresetID       *int          `json:"preset_id,omitempty"` // Saved filter preset whose rules precede FilterRules
+	Query          string        `json:"query,omitempty"`
+	SearchContent  bool          `json:"search_content,omitempty"`
+	IncludeTrashed bool          `json:"include_trashed,omitempty"`
+	IncludeEmpty   bool          `json:"include_empty,omitempty"` // Also list builtin values without matching documents
+}
+
+// FacetResult holds the value counts of one requested dimension
+type FacetResult struct {
+	FieldID   *int        `json:"field_id,omitempty"`
+	Dimension string      `json:"dimension,omitempty"`
+	Values    interface{} `json:"values"`               // []CustomFieldValueOption or []BuiltinFilterValueOption
+	DataAsOf  *string     `json:"data_as_of,omitempty"` // Set when served from a materialized summary
+}
+
+// FacetsResponse contains one result per requested facet, in request order
+type FacetsResponse struct {
+	Facets []FacetResult `json:"facets"`
+}
+
+// GetFacets computes counts for every requested dimension against the full set of
+// selections. Each dimension's own selection is left out of its counts (custom fields
+// via excludeFieldID, builtin filters via their rule type), so users can still see
+// and pick alternative values of a dimension they already filter on. Builtin dimensions
+// only count documents visible to viewerID (0 = all documents).
+func (s *Service) GetFacets(ctx context.Context, req FacetsRequest, viewerID int, bypass bool) (*FacetsResponse, error) {
+	body := map[string]interface{}{"query": req.Query, "search_content": req.SearchContent}
+	if req.FilterRules != nil {
+		body["filter_rules"] = req.FilterRules
+	}
+	filterRulesJSON := filterRulesFromBody(body)
+
+	response := &FacetsResponse{Facets: []FacetResult{}}
+	for _, spec := range req.Facets {
+		if spec.FieldID != nil && spec.Dimension != "" {
+			return nil, fmt.Errorf("facet must specify either field_id or dimension, not both")
+		}
+		if err := checkFacetExpand(spec.Dimension, spec.Expand); err != nil {
+			return nil, err
+		}
+
+		result := FacetResult{FieldID: spec.FieldID, Dimension: spec.Dimension}
+		switch {
+		case spec.FieldID != nil:
+			// Unfiltered counts of materialized fields come from their summary
+			if !bypass && !req.IncludeTrashed && filterRulesJSON == "" {
+				if summary, ok := s.getFacetSummary(ctx, *spec.FieldID); ok {
+					result.Values = sortValues(summary.Values, "count", "desc", false)
+					result.DataAsOf = summary.DataAsOf
+					break
+				}
+			}
+			values, _, err := s.getValueCountsCached(ctx, *spec.FieldID, filterRulesJSON, "count", "desc", false, req.IncludeTrashed, bypass)
+			if err != nil {
+				return nil, fmt.Errorf("failed to count values for field %d: %w", *spec.FieldID, err)
+			}
+			result.Values = values
+		case spec.Dimension != "":
+			values, _, err := s.getBuiltinFilterValuesCached(ctx, spec.Dimension, filterRulesJSON, req.IncludeTrashed, req.IncludeEmpty, ASNBucketing{}, viewerID, bypass)
+			if err != nil {
+				return nil, err
+			}
+			if values == nil {
+				values = []BuiltinFilterValueOption{}
+			}
+			if spec.Sort == "recent" {
+				values = sortBuiltinValuesByRecency(values)
+			}
+			if spec.Expand == "profile" {
+				if values, err = s.expandCorrespondentProfiles(ctx, values); err != nil {
+					return nil, err
+				}
+			}
+			result.Values = values
+		default:
+			return nil, fmt.Errorf("facet must specify field_id or dimension")
+		}
+		response.Facets = append(response.Facets, result)
+	}
+
+	return response, nil
+}
+
+// HTTP Handler for combined facet counts
+func (s *Service) handleGetFacets(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	log.Printf("[Facets] POST /api/facets/ - Request from %s", r.RemoteAddr)
+
+	viewerID, err := viewerFromRequest(r)
+	if err != nil {
+		respondError(w, viewerErrorStatus(err), err.Error())
+		return
+	}
+
+	var req FacetsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, fmt.Sprintf("Invalid request body: %v", err))
+		return
+	}
+	if len(req.Facets) == 0 {
+		respondError(w, http.StatusBadRequest, "At least one facet is required")
+		return
+	}
+	if wantsTrashed(r) {
+		req.IncludeTrashed = true
+	}
+	if wantsEmpty(r) {
+		req.IncludeEmpty = true
+	}
+	var presetID interface{}
+	if req.PresetID != nil {
+		presetID = *req.PresetID
+	}
+	presetRules, err := s.presetFilterRules(ctx, r, presetID)
+	if err != nil {
+		respondError(w, filterPresetErrorStatus(err), err.Error())
+		return
+	}
+	if presetRules != nil {
+		req.FilterRules = append(presetRules, req.FilterRules...)
+	}
+
+	response, err := s.GetFacets(ctx, req, viewerID, bypassCache(r))
+	if err != nil {
+		if strings.Contains(err.Error(), "facet must") || strings.Contains(err.Error(), "unsupported filter type") ||
+			strings.HasPrefix(err.Error(), "Invalid expand") {
+			respondError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if strings.Contains(err.Error(), "not found") {
+			respondError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		respondError(w, queryErrorStatus(err), err.Error())
+		return
+	}
+
+	respondOptions(w, r, http.StatusOK, response)
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// maxCachedFieldMeta bounds the number of custom fields whose metadata is cached
+const maxCachedFieldMeta = 1000
+
+// fieldMeta is the definition of a custom field as needed to list its values
+type fieldMeta struct {
+	Name         string
+	DataType     string
+	ExtraData    []byte            // Raw extra_data JSON, nil when unset
+	SelectLabels map[string]string // Option labels by option ID of SELECT fields
+}
+
+// fieldMetaCacheKey returns the cache key of the metadata of a field; it shares the
+// field's value cache prefix so invalidating the field drops both
+func fieldMetaCacheKey(fieldID int) string {
+	return fieldCacheKeyPrefix(fieldID) + "meta"
+}
+
+// getFieldMeta returns the name, data type and extra data of a custom field in one query.
+// Field definitions change rarely, so results are cached for FIELD_META_CACHE_TTL. A
+// missing field returns sql.ErrNoRows.
+func (s *Service) getFieldMeta(ctx context.Context, fieldID int) (*fieldMeta, error) {
+	key := fieldMetaCacheKey(fieldID)
+	var cached *fieldMeta
+	if s.fieldMeta.Get(key, &cached) {
+		return cached, nil
+	}
+
+	meta := &fieldMeta{}
+	if err := s.queryRowPrepared(ctx, s.rebind(fieldInfoQuery), fieldID).Scan(&meta.Name, &meta.DataType, &meta.ExtraData); err != nil {
+		return nil, err
+	}
+	meta.SelectLabels = meta.selectOptionLabels()
+	s.fieldMeta.Set(key, meta)
+	return meta, nil
+}
+
+// selectOptionLabels maps the option IDs of a SELECT field to their labels
+func (m *fieldMeta) selectOptionLabels() map[string]string {
+	labels := make(map[string]string)
+	if m.DataType != "select" || len(m.ExtraData) == 0 {
+		return labels
+	}
+	var extraData struct {
+		SelectOptions []struct {
+			ID    string `json:"id"`
+			Label string `json:"label"`
+		} `json:"select_options"`
+	}
+	if err := json.Unmarshal(m.ExtraData, &extraData); err != nil {
+		return labels
+	}
+	for _, option := range extraData.SelectOptions {
+		labels[option.ID] = option.Label
+	}
+	return labels
+}
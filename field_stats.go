@@ -0,0 +1,263 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// FieldStatistics describes the data quality of a custom field
+type FieldStatistics struct {
+	FieldID         int                      `json:"field_id"`
+	FieldName       string                   `json:"field_name"`
+	DataType        string                   `json:"data_type"`
+	TotalDocuments  int                      `json:"total_documents"`
+	FilledDocuments int                      `json:"filled_documents"`
+	FillRate        float64                  `json:"fill_rate"`   // Percentage of documents with a non-blank value
+	Cardinality     int                      `json:"cardinality"` // Number of distinct (split) values
+	Min             interface{}              `json:"min,omitempty"`
+	Max             interface{}              `json:"max,omitempty"`
+	Avg             interface{}              `json:"avg,omitempty"`
+	TopValues       []CustomFieldValueOption `json:"top_values"`
+}
+
+// statsTopValues is the number of most frequent values included in field statistics
+const statsTopValues = 5
+
+// GetFieldStatistics computes cardinality, fill rate, numeric/date ranges and top values for a field
+func (s *Service) GetFieldStatistics(ctx context.Context, fieldID int, includeTrashed bool) (*FieldStatistics, error) {
+	valuesResponse, err := s.GetFieldValues(ctx, fieldID, "count", "desc", false, includeTrashed)
+	if err != nil {
+		return nil, err
+	}
+
+	var dataType string
+	usePostgres := s.config.DBEngine == "postgresql" || s.config.DBEngine == "postgres"
+	dataTypeQuery := "SELECT data_type FROM documents_customfield WHERE id = ?"
+	if usePostgres {
+		dataTypeQuery = "SELECT data_type FROM documents_customfield WHERE id = $1"
+	}
+	if err := s.db.QueryRowContext(ctx, dataTypeQuery, fieldID).Scan(&dataType); err != nil {
+		return nil, fmt.Errorf("failed to get field data type: %w", err)
+	}
+
+	stats := &FieldStatistics{
+		FieldID:        fieldID,
+		FieldName:      valuesResponse.FieldName,
+		DataType:       dataType,
+		TotalDocuments: valuesResponse.TotalDocuments,
+		TopValues:      []CustomFieldValueOption{},
+	}
+
+	// Date values are not split into list entries, so they are counted from the scan below
+	if dataType != "date" {
+		for _, option := range valuesResponse.Values {
+			if option.ID == "__blank__" || option.ID == otherValuesID {
+				continue
+			}
+			stats.Cardinality++
+			if len(stats.TopValues) < statsTopValues {
+				stats.TopValues = append(stats.TopValues, option)
+			}
+		}
+	}
+
+	// Scan the non-blank values once for fill rate and ranges
+	valueColumn := getValueColumnName(dataType)
+	fieldPlaceholder := "?"
+	if usePostgres {
+		fieldPlaceholder = "$1"
+	}
+	query := fmt.Sprintf(`
+		SELECT cfi.document_id, cfi.%s
+		FROM documents_customfieldinstance cfi
+		INNER JOIN documents_document d ON cfi.document_id = d.id
+		WHERE cfi.field_id = %s
+			AND cfi.deleted_at IS NULL
+			AND %s
+			AND cfi.%s IS NOT NULL
+	`, valueColumn, fieldPlaceholder, trashedCondition(includeTrashed), valueColumn)
+
+	rows, err := s.db.QueryContext(ctx, query, fieldID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query field values: %w", err)
+	}
+	defer rows.Close()
+
+	filled := make(map[int]bool)
+	var numericCount int
+	var numericSum float64
+	numericMin := math.Inf(1)
+	numericMax := math.Inf(-1)
+	var dateCount int
+	var dateSum float64
+	var dateMin, dateMax time.Time
+	dateCounts := make(map[string]int)
+
+	for rows.Next() {
+		var documentID int
+		var raw interface{}
+		if err := rows.Scan(&documentID, &raw); err != nil {
+			continue
+		}
+
+		switch dataType {
+		case "date":
+			date, ok := parseStatsDate(raw)
+			if !ok {
+				continue
+			}
+			filled[documentID] = true
+			if dateCount == 0 || date.Before(dateMin) {
+				dateMin = date
+			}
+			if dateCount == 0 || date.After(dateMax) {
+				dateMax = date
+			}
+			dateSum += float64(date.Unix())
+			dateCount++
+			dateCounts[date.Format("2006-01-02")]++
+		case "integer", "float", "monetary":
+			number, ok := parseStatsNumber(raw)
+			if !ok {
+				continue
+			}
+			filled[documentID] = true
+			numericMin = math.Min(numericMin, number)
+			numericMax = math.Max(numericMax, number)
+			numericSum += number
+			numericCount++
+		default:
+			if strings.TrimSpace(statsString(raw)) != "" {
+				filled[documentID] = true
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read field values: %w", err)
+	}
+
+	stats.FilledDocuments = len(filled)
+	if stats.TotalDocuments > 0 {
+		stats.FillRate = math.Round(float64(stats.FilledDocuments)/float64(stats.TotalDocuments)*10000) / 100
+	}
+
+	if numericCount > 0 {
+		stats.Min = numericMin
+		stats.Max = numericMax
+		stats.Avg = math.Round(numericSum/float64(numericCount)*100) / 100
+	}
+	if dateCount > 0 {
+		stats.Min = dateMin.Format("2006-01-02")
+		stats.Max = dateMax.Format("2006-01-02")
+		stats.Avg = time.Unix(int64(dateSum/float64(dateCount)), 0).UTC().Format("2006-01-02")
+	}
+
+	if dataType == "date" {
+		dateOptions := []CustomFieldValueOption{}
+		for date, count := range dateCounts {
+			dateOptions = append(dateOptions, CustomFieldValueOption{ID: generateID(date), Label: date, Count: count})
+		}
+		dateOptions = sortValues(dateOptions, "count", "desc", false)
+		stats.Cardinality = len(dateOptions)
+		if len(dateOptions) > statsTopValues {
+			dateOptions = dateOptions[:statsTopValues]
+		}
+		stats.TopValues = dateOptions
+	}
+
+	return stats, nil
+}
+
+// statsString converts a scanned database value to a string
+func statsString(raw interface{}) string {
+	switch v := raw.(type) {
+	case nil:
+		return ""
+	case []byte:
+		return string(v)
+	case string:
+		return v
+	case time.Time:
+		return v.Format("2006-01-02")
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+// parseStatsNumber parses numeric and monetary values ("EUR12.50" style prefixes are stripped)
+func parseStatsNumber(raw interface{}) (float64, bool) {
+	switch v := raw.(type) {
+	case int64:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	str := strings.TrimSpace(statsString(raw))
+	str = strings.TrimLeftFunc(str, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '-' && r != '.'
+	})
+	if str == "" {
+		return 0, false
+	}
+	number, err := strconv.ParseFloat(str, 64)
+	if err != nil {
+		return 0, false
+	}
+	return number, true
+}
+
+// parseStatsDate parses a date value as returned by the different drivers
+func parseStatsDate(raw interface{}) (time.Time, bool) {
+	if t, ok := raw.(time.Time); ok {
+		return t, true
+	}
+	str := strings.TrimSpace(statsString(raw))
+	if str == "" {
+		return time.Time{}, false
+	}
+	for _, layout := range []string{"2006-01-02", time.RFC3339, "2006-01-02 15:04:05"} {
+		if t, err := time.Parse(layout, str); err == nil {
+			return t, true
+		}
+	}
+	if len(str) >= 10 {
+		if t, err := time.Parse("2006-01-02", str[:10]); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// HTTP Handler for field statistics
+func (s *Service) handleGetFieldStatistics(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	vars := mux.Vars(r)
+	fieldIDStr := vars["fieldId"]
+
+	fieldID, err := strconv.Atoi(fieldIDStr)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid field ID")
+		return
+	}
+
+	stats, err := s.GetFieldStatistics(ctx, fieldID, wantsTrashed(r))
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			respondError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		respondError(w, queryErrorStatus(err), err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, stats)
+}
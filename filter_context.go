@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// FilterContextRequest represents the request body of the filter sidebar endpoint. The
+// filter_visibility map is the one stored with a custom view: builtin filter names and
+// custom field IDs mapped to whether the filter is shown.
+type FilterContextRequest struct {
+	FilterVisibility map[string]bool `json:"filter_visibility"`
+	FilterRules      []interface{}   `json:"filter_rules,omitempty"`
+	PresetID         *int            `json:"preset_id,omitempty"`
+	Query            string          `json:"query,omitempty"`
+	SearchContent    bool            `json:"search_content,omitempty"`
+	IncludeTrashed   bool            `json:"include_trashed,omitempty"`
+	IncludeEmpty     bool            `json:"include_empty,omitempty"`
+}
+
+// FilterContextResponse holds the values of every visible filter, keyed like filter_visibility
+type FilterContextResponse struct {
+	Builtin      map[string][]BuiltinFilterValueOption `json:"builtin"`
+	CustomFields map[string][]CustomFieldValueOption   `json:"custom_fields"`
+	Unsupported  []string                              `json:"unsupported,omitempty"` // Visible filters without countable values (e.g. title)
+}
+
+// filterContextAliases maps the plural filter names used by the frontend to builtin filter types
+var filterContextAliases = map[string]string{
+	"tags":           "tag",
+	"correspondents": "correspondent",
+	"document_types": "document_type",
+	"storage_paths":  "storage_path",
+	"owners":         "owner",
+}
+
+// filterContextFacet resolves a filter_visibility key to a facet: a custom field ID
+// ("12" or "custom_field_12") or a builtin filter type
+func filterContextFacet(key string) (FacetSpec, bool) {
+	fieldKey := strings.TrimPrefix(key, "custom_field_")
+	if fieldID, err := strconv.Atoi(fieldKey); err == nil && fieldID > 0 {
+		return FacetSpec{FieldID: &fieldID}, true
+	}
+
+	dimension := key
+	if alias, ok := filterContextAliases[key]; ok {
+		dimension = alias
+	}
+	if _, ok := lookupFacetProvider(dimension); ok {
+		return FacetSpec{Dimension: dimension}, true
+	}
+	return FacetSpec{}, false
+}
+
+// GetFilterContext counts the values of all visible filters against the current selections,
+// each filter leaving out its own selection like GetFacets
+func (s *Service) GetFilterContext(ctx context.Context, req FilterContextRequest, viewerID int, bypass bool) (*FilterContextResponse, error) {
+	keys := make([]string, 0, len(req.FilterVisibility))
+	for key, visible := range req.FilterVisibility {
+		if visible {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	response := &FilterContextResponse{
+		Builtin:      make(map[string][]BuiltinFilterValueOption),
+		CustomFields: make(map[string][]CustomFieldValueOption),
+	}
+	facetsRequest := FacetsRequest{
+		FilterRules:    req.FilterRules,
+		Query:          req.Query,
+		SearchContent:  req.SearchContent,
+		IncludeTrashed: req.IncludeTrashed,
+		IncludeEmpty:   req.IncludeEmpty,
+	}
+	facetKeys := []string{}
+	for _, key := range keys {
+		spec, ok := filterContextFacet(key)
+		if !ok {
+			response.Unsupported = append(response.Unsupported, key)
+			continue
+		}
+		facetsRequest.Facets = append(facetsRequest.Facets, spec)
+		facetKeys = append(facetKeys, key)
+	}
+	if len(facetsRequest.Facets) == 0 {
+		return response, nil
+	}
+
+	facets, err := s.GetFacets(ctx, facetsRequest, viewerID, bypass)
+	if err != nil {
+		return nil, err
+	}
+	for i, facet := range facets.Facets {
+		switch values := facet.Values.(type) {
+		case []BuiltinFilterValueOption:
+			response.Builtin[facetKeys[i]] = values
+		case []CustomFieldValueOption:
+			response.CustomFields[facetKeys[i]] = values
+		}
+	}
+
+	return response, nil
+}
+
+// HTTP Handler for the filter sidebar values
+func (s *Service) handleGetFilterContext(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	log.Printf("[FilterContext] POST /api/filter-context/ - Request from %s", r.RemoteAddr)
+
+	viewerID, err := viewerFromRequest(r)
+	if err != nil {
+		respondError(w, viewerErrorStatus(err), err.Error())
+		return
+	}
+
+	var req FilterContextRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, fmt.Sprintf("Invalid request body: %v", err))
+		return
+	}
+	if wantsTrashed(r) {
+		req.IncludeTrashed = true
+	}
+	if wantsEmpty(r) {
+		req.IncludeEmpty = true
+	}
+	var presetID interface{}
+	if req.PresetID != nil {
+		presetID = *req.PresetID
+	}
+	presetRules, err := s.presetFilterRules(ctx, r, presetID)
+	if err != nil {
+		respondError(w, filterPresetErrorStatus(err), err.Error())
+		return
+	}
+	if presetRules != nil {
+		req.FilterRules = append(presetRules, req.FilterRules...)
+	}
+
+	response, err := s.GetFilterContext(ctx, req, viewerID, bypassCache(r))
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			respondError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		respondError(w, queryErrorStatus(err), err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, response)
+}
@@ -0,0 +1,454 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+const filterPresetColumns = "id, name, description, filter_rules, is_global, owner_id, username, created, modified"
+
+// ListFilterPresets retrieves the presets of a user together with the global presets
+func (s *Service) ListFilterPresets(ctx context.Context, userID int) ([]FilterPreset, error) {
+	var query string
+	switch s.config.DBEngine {
+	case "postgresql", "postgres":
+		query = `SELECT ` + filterPresetColumns + ` FROM filter_presets
+			WHERE owner_id = $1 OR is_global = true
+			ORDER BY name ASC`
+	case "mysql", "mariadb", "sqlite", "sqlite3":
+		query = `SELECT ` + filterPresetColumns + ` FROM filter_presets
+			WHERE owner_id = ? OR is_global = 1
+			ORDER BY name ASC`
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query filter presets: %w", err)
+	}
+	defer rows.Close()
+
+	presets := []FilterPreset{}
+	for rows.Next() {
+		preset, err := s.scanFilterPreset(rows)
+		if err != nil {
+			continue
+		}
+		presets = append(presets, preset)
+	}
+
+	return presets, nil
+}
+
+// GetFilterPreset retrieves a preset visible to the user: their own or a global one.
+// Other users' presets are reported as not found.
+func (s *Service) GetFilterPreset(ctx context.Context, id int, userID int) (*FilterPreset, error) {
+	var query string
+	switch s.config.DBEngine {
+	case "postgresql", "postgres":
+		query = `SELECT ` + filterPresetColumns + ` FROM filter_presets WHERE id = $1`
+	case "mysql", "mariadb", "sqlite", "sqlite3":
+		query = `SELECT ` + filterPresetColumns + ` FROM filter_presets WHERE id = ?`
+	}
+
+	preset, err := s.scanFilterPreset(s.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("filter preset with id %d not found", id)
+		}
+		return nil, fmt.Errorf("failed to query filter preset: %w", err)
+	}
+
+	isGlobal := preset.IsGlobal != nil && *preset.IsGlobal
+	if !isGlobal && preset.OwnerID != nil && *preset.OwnerID != userID {
+		return nil, fmt.Errorf("filter preset with id %d not found", id)
+	}
+
+	return &preset, nil
+}
+
+// CreateFilterPreset creates a new filter preset owned by the user
+func (s *Service) CreateFilterPreset(ctx context.Context, preset FilterPreset, userID int, username string) (*FilterPreset, error) {
+	log.Printf("[FilterPresets] CreateFilterPreset - Name: %s, UserID: %d", preset.Name, userID)
+	if preset.FilterRules == nil {
+		preset.FilterRules = []map[string]interface{}{}
+	}
+	filterRulesJSON, _ := json.Marshal(preset.FilterRules)
+	isGlobal := preset.IsGlobal != nil && *preset.IsGlobal
+
+	var newID int
+	if s.config.DBEngine == "postgresql" || s.config.DBEngine == "postgres" {
+		err := s.db.QueryRowContext(ctx, `
+			INSERT INTO filter_presets (name, description, filter_rules, is_global, owner_id, username)
+			VALUES ($1, $2, $3::jsonb, $4, $5, $6)
+			RETURNING id
+		`, preset.Name, preset.Description, string(filterRulesJSON), isGlobal, userID, username).Scan(&newID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create filter preset: %w", err)
+		}
+	} else {
+		result, err := s.db.ExecContext(ctx, `
+			INSERT INTO filter_presets (name, description, filter_rules, is_global, owner_id, username)
+			VALUES (?, ?, ?, ?, ?, ?)
+		`, preset.Name, preset.Description, string(filterRulesJSON), isGlobal, userID, username)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create filter preset: %w", err)
+		}
+		lastID, err := result.LastInsertId()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get last insert ID: %w", err)
+		}
+		newID = int(lastID)
+	}
+
+	return s.GetFilterPreset(ctx, newID, userID)
+}
+
+// UpdateFilterPreset updates the provided fields of a preset owned by the user
+func (s *Service) UpdateFilterPreset(ctx context.Context, id int, updates FilterPreset, userID int) (*FilterPreset, error) {
+	log.Printf("[FilterPresets] UpdateFilterPreset - ID: %d, UserID: %d", id, userID)
+	existing, err := s.GetFilterPreset(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if existing.OwnerID != nil && *existing.OwnerID != userID {
+		return nil, fmt.Errorf("permission denied: preset belongs to another user")
+	}
+
+	usePostgres := s.config.DBEngine == "postgresql" || s.config.DBEngine == "postgres"
+	setParts := []string{}
+	args := []interface{}{}
+	addSet := func(column string, value interface{}, cast string) {
+		args = append(args, value)
+		if usePostgres {
+			setParts = append(setParts, fmt.Sprintf("%s = $%d%s", column, len(args), cast))
+		} else {
+			setParts = append(setParts, column+" = ?")
+		}
+	}
+
+	if updates.Name != "" {
+		addSet("name", updates.Name, "")
+	}
+	if updates.Description != nil {
+		addSet("description", *updates.Description, "")
+	}
+	if updates.FilterRules != nil {
+		filterRulesJSON, _ := json.Marshal(updates.FilterRules)
+		addSet("filter_rules", string(filterRulesJSON), "::jsonb")
+	}
+	if updates.IsGlobal != nil {
+		addSet("is_global", *updates.IsGlobal, "")
+	}
+	setParts = append(setParts, "modified = CURRENT_TIMESTAMP")
+
+	args = append(args, id)
+	updateQuery := fmt.Sprintf("UPDATE filter_presets SET %s WHERE id = ?", strings.Join(setParts, ", "))
+	if usePostgres {
+		updateQuery = fmt.Sprintf("UPDATE filter_presets SET %s WHERE id = $%d", strings.Join(setParts, ", "), len(args))
+	}
+
+	if _, err := s.db.ExecContext(ctx, updateQuery, args...); err != nil {
+		return nil, fmt.Errorf("failed to update filter preset: %w", err)
+	}
+
+	return s.GetFilterPreset(ctx, id, userID)
+}
+
+// DeleteFilterPreset deletes a preset owned by the user
+func (s *Service) DeleteFilterPreset(ctx context.Context, id int, userID int) error {
+	log.Printf("[FilterPresets] DeleteFilterPreset - ID: %d, UserID: %d", id, userID)
+	existing, err := s.GetFilterPreset(ctx, id, userID)
+	if err != nil {
+		return err
+	}
+	if existing.OwnerID != nil && *existing.OwnerID != userID {
+		return fmt.Errorf("permission denied: preset belongs to another user")
+	}
+
+	deleteQuery := "DELETE FROM filter_presets WHERE id = ?"
+	if s.config.DBEngine == "postgresql" || s.config.DBEngine == "postgres" {
+		deleteQuery = "DELETE FROM filter_presets WHERE id = $1"
+	}
+	if _, err := s.db.ExecContext(ctx, deleteQuery, id); err != nil {
+		return fmt.Errorf("failed to delete filter preset: %w", err)
+	}
+
+	return nil
+}
+
+// scanFilterPreset scans a FilterPreset from a database row or rows
+func (s *Service) scanFilterPreset(scanner interface{ Scan(...interface{}) error }) (FilterPreset, error) {
+	var preset FilterPreset
+	var id int
+	var ownerID sql.NullInt64
+	var description, filterRulesJSON, username sql.NullString
+	var created, modified dbTimestamp
+	var isGlobal sql.NullBool
+
+	if err := scanner.Scan(&id, &preset.Name, &description, &filterRulesJSON, &isGlobal, &ownerID, &username, &created, &modified); err != nil {
+		return preset, err
+	}
+
+	preset.ID = &id
+	if description.Valid {
+		preset.Description = &description.String
+	}
+	preset.FilterRules = []map[string]interface{}{}
+	if filterRulesJSON.Valid {
+		json.Unmarshal([]byte(filterRulesJSON.String), &preset.FilterRules)
+	}
+	if isGlobal.Valid {
+		preset.IsGlobal = &isGlobal.Bool
+	}
+	if ownerID.Valid {
+		owner := int(ownerID.Int64)
+		preset.OwnerID = &owner
+	}
+	if username.Valid {
+		preset.Username = &username.String
+	}
+	preset.Created = s.formatTimestamp(created)
+	preset.Modified = s.formatTimestamp(modified)
+
+	return preset, nil
+}
+
+// requestedPresetID reads the preset_id of a counts request from its body, falling back
+// to the preset_id query parameter. It returns 0 when no preset is requested.
+func requestedPresetID(r *http.Request, bodyValue interface{}) (int, error) {
+	switch value := bodyValue.(type) {
+	case float64:
+		if value > 0 && value == float64(int(value)) {
+			return int(value), nil
+		}
+		return 0, fmt.Errorf("invalid preset_id")
+	case int:
+		return value, nil
+	case string:
+		if value != "" {
+			return parsePresetID(value)
+		}
+	}
+	if value := r.URL.Query().Get("preset_id"); value != "" {
+		return parsePresetID(value)
+	}
+	return 0, nil
+}
+
+func parsePresetID(value string) (int, error) {
+	id, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil || id <= 0 {
+		return 0, fmt.Errorf("invalid preset_id")
+	}
+	return id, nil
+}
+
+// presetFilterRules returns the rules of the requested filter preset, nil when none is requested
+func (s *Service) presetFilterRules(ctx context.Context, r *http.Request, bodyValue interface{}) ([]interface{}, error) {
+	presetID, err := requestedPresetID(r, bodyValue)
+	if err != nil || presetID == 0 {
+		return nil, err
+	}
+
+	userID, err := viewerFromRequest(r)
+	if err != nil {
+		return nil, err
+	}
+	preset, err := s.GetFilterPreset(ctx, presetID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	rules := make([]interface{}, 0, len(preset.FilterRules))
+	for _, rule := range preset.FilterRules {
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// applyFilterPreset resolves the preset_id of a counts request server-side: the preset's
+// rules are put in front of the filter_rules sent with the request. body may be nil.
+func (s *Service) applyFilterPreset(ctx context.Context, r *http.Request, body map[string]interface{}) (map[string]interface{}, error) {
+	if body == nil {
+		body = map[string]interface{}{}
+	}
+	presetRules, err := s.presetFilterRules(ctx, r, body["preset_id"])
+	if err != nil || presetRules == nil {
+		return body, err
+	}
+
+	requestRules, _ := body["filter_rules"].([]interface{})
+	body["filter_rules"] = append(presetRules, requestRules...)
+	return body, nil
+}
+
+// filterPresetErrorStatus maps preset resolution errors to HTTP status codes
+func filterPresetErrorStatus(err error) int {
+	if errors.Is(err, errNoViewer) || errors.Is(err, errInvalidViewer) {
+		return viewerErrorStatus(err)
+	}
+	if strings.Contains(err.Error(), "not found") {
+		return http.StatusNotFound
+	}
+	if strings.Contains(err.Error(), "invalid preset_id") {
+		return http.StatusBadRequest
+	}
+	return queryErrorStatus(err)
+}
+
+// HTTP Handlers for Filter Presets
+func (s *Service) handleListFilterPresets(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	log.Printf("[FilterPresets] GET /api/filter_presets/ - Request from %s", r.RemoteAddr)
+
+	userID, err := getUserIDFromRequest(r)
+	if err != nil {
+		respondError(w, viewerErrorStatus(err), err.Error())
+		return
+	}
+
+	presets, err := s.ListFilterPresets(ctx, *userID)
+	if err != nil {
+		log.Printf("[FilterPresets] Error listing presets: %v", err)
+		respondError(w, queryErrorStatus(err), err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, FilterPresetListResponse{Count: len(presets), Results: presets})
+}
+
+func (s *Service) handleGetFilterPreset(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid preset ID")
+		return
+	}
+
+	userID, err := getUserIDFromRequest(r)
+	if err != nil {
+		respondError(w, viewerErrorStatus(err), err.Error())
+		return
+	}
+
+	preset, err := s.GetFilterPreset(ctx, id, *userID)
+	if err != nil {
+		respondError(w, filterPresetErrorStatus(err), err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, preset)
+}
+
+func (s *Service) handleCreateFilterPreset(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	log.Printf("[FilterPresets] POST /api/filter_presets/ - Request from %s", r.RemoteAddr)
+
+	var preset FilterPreset
+	if err := json.NewDecoder(r.Body).Decode(&preset); err != nil {
+		respondError(w, http.StatusBadRequest, fmt.Sprintf("Invalid request body: %v", err))
+		return
+	}
+	if strings.TrimSpace(preset.Name) == "" {
+		respondError(w, http.StatusBadRequest, "Name is required")
+		return
+	}
+
+	userID, err := getUserIDFromRequest(r)
+	if err != nil {
+		respondError(w, viewerErrorStatus(err), err.Error())
+		return
+	}
+	username := getUsernameFromRequest(r)
+
+	created, err := s.CreateFilterPreset(ctx, preset, *userID, *username)
+	if err != nil {
+		log.Printf("[FilterPresets] Error creating preset: %v", err)
+		respondError(w, queryErrorStatus(err), err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, created)
+}
+
+func (s *Service) handleUpdateFilterPreset(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid preset ID")
+		return
+	}
+	log.Printf("[FilterPresets] %s /api/filter_presets/%d/ - Request from %s", r.Method, id, r.RemoteAddr)
+
+	var updates FilterPreset
+	if err := json.NewDecoder(r.Body).Decode(&updates); err != nil {
+		respondError(w, http.StatusBadRequest, fmt.Sprintf("Invalid request body: %v", err))
+		return
+	}
+
+	userID, err := getUserIDFromRequest(r)
+	if err != nil {
+		respondError(w, viewerErrorStatus(err), err.Error())
+		return
+	}
+
+	updated, err := s.UpdateFilterPreset(ctx, id, updates, *userID)
+	if err != nil {
+		log.Printf("[FilterPresets] Error updating preset %d: %v", id, err)
+		if strings.Contains(err.Error(), "permission denied") {
+			respondError(w, http.StatusForbidden, err.Error())
+			return
+		}
+		respondError(w, filterPresetErrorStatus(err), err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, updated)
+}
+
+func (s *Service) handleDeleteFilterPreset(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid preset ID")
+		return
+	}
+	log.Printf("[FilterPresets] DELETE /api/filter_presets/%d/ - Request from %s", id, r.RemoteAddr)
+
+	userID, err := getUserIDFromRequest(r)
+	if err != nil {
+		respondError(w, viewerErrorStatus(err), err.Error())
+		return
+	}
+
+	if err := s.DeleteFilterPreset(ctx, id, *userID); err != nil {
+		log.Printf("[FilterPresets] Error deleting preset %d: %v", id, err)
+		if strings.Contains(err.Error(), "permission denied") {
+			respondError(w, http.StatusForbidden, err.Error())
+			return
+		}
+		respondError(w, filterPresetErrorStatus(err), err.Error())
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+)
+
+// filterRuleTypesMigration names the migration renumbering stored filter rules
+const filterRuleTypesMigration = "filter_rule_types_paperless"
+
+// legacyFilterRuleTypes maps the rule types 1-9 of versions before the Paperless numbering to
+// their Paperless rule types. The earlier created after/before dates were inclusive, which
+// Paperless' created from/to are as well.
+var legacyFilterRuleTypes = map[int]int{
+	1: FILTER_CORRESPONDENT,
+	2: FILTER_DOCUMENT_TYPE,
+	3: FILTER_HAS_TAGS_ANY,
+	4: FILTER_STORAGE_PATH,
+	5: FILTER_OWNER_ANY,
+	6: FILTER_CREATED_FROM,
+	7: FILTER_CREATED_TO,
+	8: FILTER_ASN,
+	9: FILTER_IS_IN_INBOX,
+}
+
+// storedFilterRuleColumns are the columns storing filter rules: lists of rules, or objects
+// whose "filter_rules" members are (view revisions, dashboard widgets, bulk jobs)
+var storedFilterRuleColumns = []struct {
+	table, column string
+	rules         bool
+}{
+	{"custom_views", "filter_rules", true},
+	{"filter_presets", "filter_rules", true},
+	{"saved_searches", "filter_rules", true},
+	{"share_links", "filter_rules", true},
+	{"custom_view_revisions", "snapshot", false},
+	{"dashboards", "widgets", false},
+	{"bulk_jobs", "operation", false},
+}
+
+// initServiceMigrationsTable creates the table recording the data migrations already applied
+func (s *Service) initServiceMigrationsTable() error {
+	log.Printf("[Database] Initializing service_migrations table for engine: %s", s.config.DBEngine)
+	var createTableQuery string
+
+	switch s.config.DBEngine {
+	case "postgresql", "postgres":
+		createTableQuery = `
+			CREATE TABLE IF NOT EXISTS service_migrations (
+				name VARCHAR(100) PRIMARY KEY,
+				applied TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+			);
+		`
+	case "mysql", "mariadb":
+		createTableQuery = `
+			CREATE TABLE IF NOT EXISTS service_migrations (
+				name VARCHAR(100) PRIMARY KEY,
+				applied TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+			);
+		`
+	default: // sqlite
+		createTableQuery = `
+			CREATE TABLE IF NOT EXISTS service_migrations (
+				name TEXT PRIMARY KEY,
+				applied TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+			);
+		`
+	}
+
+	if _, err := s.db.Exec(createTableQuery); err != nil {
+		log.Printf("[Database] Error creating service_migrations table: %v", err)
+		return fmt.Errorf("failed to create service_migrations table: %w", err)
+	}
+	return nil
+}
+
+// migrateFilterRuleTypes rewrites the stored filter rules numbered before the Paperless rule
+// types, once. The migration is recorded in the same transaction, so a replica starting at the
+// same time waits for it and then finds it applied.
+func (s *Service) migrateFilterRuleTypes() error {
+	if err := s.initServiceMigrationsTable(); err != nil {
+		return err
+	}
+
+	var applied int
+	if err := s.db.QueryRow(s.rebind("SELECT COUNT(*) FROM service_migrations WHERE name = ?"), filterRuleTypesMigration).Scan(&applied); err != nil {
+		return fmt.Errorf("failed to check filter rule migration: %w", err)
+	}
+	if applied > 0 {
+		return nil
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin filter rule migration: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(s.rebind("INSERT INTO service_migrations (name) VALUES (?)"), filterRuleTypesMigration); err != nil {
+		// Another replica applied it in the meantime
+		if s.db.QueryRow(s.rebind("SELECT COUNT(*) FROM service_migrations WHERE name = ?"), filterRuleTypesMigration).Scan(&applied) == nil && applied > 0 {
+			return nil
+		}
+		return fmt.Errorf("failed to record filter rule migration: %w", err)
+	}
+
+	migrated := 0
+	for _, stored := range storedFilterRuleColumns {
+		count, err := s.migrateFilterRuleColumn(tx, stored.table, stored.column, stored.rules)
+		if err != nil {
+			return err
+		}
+		migrated += count
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit filter rule migration: %w", err)
+	}
+	log.Printf("[Database] Renumbered the filter rules of %d stored rows to the Paperless rule types", migrated)
+	return nil
+}
+
+// migrateFilterRuleColumn renumbers the filter rules stored in one column and returns the
+// number of rows changed. Values that are not JSON are left alone.
+func (s *Service) migrateFilterRuleColumn(tx *sql.Tx, table, column string, rules bool) (int, error) {
+	rows, err := tx.Query(fmt.Sprintf("SELECT id, %s FROM %s WHERE %s IS NOT NULL", column, table, column))
+	if err != nil {
+		return 0, fmt.Errorf("failed to read %s.%s for filter rule migration: %w", table, column, err)
+	}
+	updates := make(map[int]string)
+	for rows.Next() {
+		var id int
+		var raw []byte
+		if err := rows.Scan(&id, &raw); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to read %s.%s for filter rule migration: %w", table, column, err)
+		}
+		decoder := json.NewDecoder(bytes.NewReader(raw))
+		decoder.UseNumber()
+		var value interface{}
+		if decoder.Decode(&value) != nil {
+			continue
+		}
+		if !upgradeStoredFilterRules(value, rules) {
+			continue
+		}
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			continue
+		}
+		updates[id] = string(encoded)
+	}
+	err = rows.Err()
+	rows.Close()
+	if err != nil {
+		return 0, fmt.Errorf("failed to read %s.%s for filter rule migration: %w", table, column, err)
+	}
+
+	update := s.rebind(fmt.Sprintf("UPDATE %s SET %s = ? WHERE id = ?", table, column))
+	for id, encoded := range updates {
+		if _, err := tx.Exec(update, encoded, id); err != nil {
+			return 0, fmt.Errorf("failed to migrate filter rules of %s %d: %w", table, id, err)
+		}
+	}
+	return len(updates), nil
+}
+
+// upgradeStoredFilterRules renumbers the legacy rules of a decoded value, which is a list of
+// rules when rules is set and searched for "filter_rules" members otherwise, and reports
+// whether anything changed
+func upgradeStoredFilterRules(value interface{}, rules bool) bool {
+	changed := false
+	switch v := value.(type) {
+	case []interface{}:
+		for _, item := range v {
+			if rules {
+				if rule, ok := item.(map[string]interface{}); ok && upgradeLegacyFilterRule(rule) {
+					changed = true
+				}
+			} else if upgradeStoredFilterRules(item, false) {
+				changed = true
+			}
+		}
+	case map[string]interface{}:
+		if rules {
+			return false
+		}
+		for key, member := range v {
+			if upgradeStoredFilterRules(member, key == "filter_rules") {
+				changed = true
+			}
+		}
+	}
+	return changed
+}
+
+// upgradeLegacyFilterRule renumbers a rule of the earlier numbering and reports whether it
+// changed. Rule 7 with a boolean value already had Paperless' "has any tag" meaning.
+func upgradeLegacyFilterRule(rule map[string]interface{}) bool {
+	var ruleType int
+	switch t := rule["rule_type"].(type) {
+	case json.Number:
+		n, err := strconv.Atoi(t.String())
+		if err != nil {
+			return false
+		}
+		ruleType = n
+	case float64:
+		ruleType = int(t)
+	default:
+		return false
+	}
+	upgraded, ok := legacyFilterRuleTypes[ruleType]
+	if !ok {
+		return false
+	}
+	if ruleType == FILTER_HAS_ANY_TAG {
+		var value string
+		switch v := rule["value"].(type) {
+		case string:
+			value = v
+		case json.Number:
+			value = v.String()
+		case bool:
+			value = strconv.FormatBool(v)
+		}
+		if _, isBool := parseRuleBool(value); isBool {
+			return false
+		}
+	}
+	rule["rule_type"] = upgraded
+	return true
+}
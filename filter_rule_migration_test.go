@@ -0,0 +1,55 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMigrateFilterRuleTypes(t *testing.T) {
+	s := newTestService(t, nil)
+	mustExec(t, s,
+		`INSERT INTO custom_views (name, column_order, is_global, filter_rules) VALUES ('Legacy', '[]', 1,
+			'[{"rule_type": 1, "value": "4"}, {"rule_type": 6, "value": "2024-01-01"}, {"rule_type": 7, "value": "2024-02-01"}, {"rule_type": 7, "value": "true"}, {"rule_type": 20, "value": "x"}]')`,
+		`INSERT INTO dashboards (name, widgets) VALUES ('Home', '[{"type": "count", "filter_rules": [{"rule_type": 9, "value": "true"}]}]')`,
+		`INSERT INTO bulk_jobs (operation, status) VALUES ('{"filter_rules": [{"rule_type": 8, "value": 12}], "action": "add_tag"}', 'done')`,
+		"DELETE FROM service_migrations",
+	)
+
+	if err := s.migrateFilterRuleTypes(); err != nil {
+		t.Fatalf("migrateFilterRuleTypes: %v", err)
+	}
+
+	var rules, widgets, operation string
+	if err := s.db.QueryRow("SELECT filter_rules FROM custom_views WHERE name = 'Legacy'").Scan(&rules); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`"rule_type":3,`, `"rule_type":44,`, `"rule_type":43,`, `"rule_type":7,"value":"true"`, `"rule_type":20,`} {
+		if !strings.Contains(rules, want) {
+			t.Errorf("view rules %s lack %s", rules, want)
+		}
+	}
+	if err := s.db.QueryRow("SELECT widgets FROM dashboards").Scan(&widgets); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(widgets, `"rule_type":5,`) {
+		t.Errorf("widget rules not migrated: %s", widgets)
+	}
+	if err := s.db.QueryRow("SELECT operation FROM bulk_jobs").Scan(&operation); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(operation, `"rule_type":2,"value":12`) {
+		t.Errorf("bulk job rules not migrated: %s", operation)
+	}
+
+	// Rules saved after the migration already use the Paperless numbering
+	mustExec(t, s, `UPDATE custom_views SET filter_rules = '[{"rule_type": 3, "value": "4"}]'`)
+	if err := s.migrateFilterRuleTypes(); err != nil {
+		t.Fatalf("migrateFilterRuleTypes again: %v", err)
+	}
+	if err := s.db.QueryRow("SELECT filter_rules FROM custom_views WHERE name = 'Legacy'").Scan(&rules); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(rules, `"rule_type": 3,`) {
+		t.Errorf("migration ran twice: %s", rules)
+	}
+}
@@ -1,14 +1,30 @@
 module github.com/paperless-link/paperless-link-service
 
-go 1.21
+go 1.23
 
 require (
+	github.com/RoaringBitmap/roaring v0.4.23
+	github.com/axiomhq/hyperloglog v0.2.5
 	github.com/go-sql-driver/mysql v1.7.1
 	github.com/gorilla/handlers v1.5.2
 	github.com/gorilla/mux v1.8.1
 	github.com/joho/godotenv v1.5.1
 	github.com/lib/pq v1.10.9
 	github.com/mattn/go-sqlite3 v1.14.18
+	github.com/redis/go-redis/v9 v9.17.2
+	golang.org/x/crypto v0.31.0
+	golang.org/x/sync v0.8.0
 )
 
-require github.com/felixge/httpsnoop v1.0.3 // indirect
+require (
+	github.com/cespare/xxhash/v2 v2.3.0 // indirect
+	github.com/dgryski/go-metro v0.0.0-20180109044635-280f6062b5bc // indirect
+	github.com/dgryski/go-rendezvous v0.0.0-20200823014737-9f7001d12a5f // indirect
+	github.com/felixge/httpsnoop v1.0.3 // indirect
+	github.com/glycerine/go-unsnap-stream v0.0.0-20181221182339-f9677308dec2 // indirect
+	github.com/golang/snappy v0.0.1 // indirect
+	github.com/kamstrup/intmap v0.5.1 // indirect
+	github.com/philhofer/fwd v1.0.0 // indirect
+	github.com/tinylib/msgp v1.1.0 // indirect
+	github.com/willf/bitset v1.1.10 // indirect
+)
@@ -0,0 +1,374 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Paperless does not index custom field instances for the access paths of this service: the
+// value and facet queries look up instances by field, deleted_at and value column, and the
+// blank counts probe them by document. The index advisor compares those access paths with the
+// indexes of the database and creates the missing ones. Its indexes are named with the
+// paperless_link_ prefix and are only added (or rebuilt after an interrupted build), so Paperless's
+// own migrations are unaffected; an existing index starting with the same columns counts as
+// covering an access path.
+
+// supportingIndex is an index the service's queries benefit from
+type supportingIndex struct {
+	table   string
+	name    string
+	columns []string
+}
+
+// indexedValueColumns are the value columns worth indexing; document ID lists and long texts
+// are not compared with plain operators (and cannot be indexed as a whole on MySQL)
+var indexedValueColumns = []string{"value_text", "value_url", "value_date", "value_bool", "value_int",
+	"value_float", "value_monetary", "value_select"}
+
+// supportingIndexes lists the indexes the index advisor checks
+func supportingIndexes() []supportingIndex {
+	indexes := []supportingIndex{
+		{table: "documents_customfieldinstance", name: "paperless_link_cfi_document_field", columns: []string{"document_id", "field_id"}},
+		{table: "documents_document", name: "paperless_link_document_deleted", columns: []string{"deleted_at"}},
+	}
+	for _, column := range indexedValueColumns {
+		indexes = append(indexes, supportingIndex{
+			table:   "documents_customfieldinstance",
+			name:    "paperless_link_cfi_" + column,
+			columns: []string{"field_id", "deleted_at", column},
+		})
+	}
+	return indexes
+}
+
+// Index advisor statuses
+const (
+	indexPresent     = "present"     // The index exists
+	indexCovered     = "covered"     // Another index starts with the same columns
+	indexMissing     = "missing"     // The index would be created
+	indexInvalid     = "invalid"     // An interrupted build left the index unusable; it is rebuilt
+	indexUnavailable = "unavailable" // The table or a column does not exist in this Paperless version
+	indexCreated     = "created"
+	indexFailed      = "failed"
+)
+
+// IndexAdvice is the state of one supporting index
+type IndexAdvice struct {
+	Table     string   `json:"table"`
+	Name      string   `json:"name"`
+	Columns   []string `json:"columns"`
+	Status    string   `json:"status"`
+	CoveredBy string   `json:"covered_by,omitempty"`
+	Statement string   `json:"statement,omitempty"` // SQL creating the index, for missing and invalid indexes
+	Error     string   `json:"error,omitempty"`
+}
+
+// IndexAdvisorResponse is the index advisor report; Building is set while indexes are created
+type IndexAdvisorResponse struct {
+	Engine   string        `json:"engine"`
+	Missing  int           `json:"missing"`
+	Building bool          `json:"building"`
+	Results  []IndexAdvice `json:"results"`
+}
+
+// existingIndex is an index found in the database catalog
+type existingIndex struct {
+	columns []string
+	valid   bool
+}
+
+// tableIndexes returns the indexes of a table by name with their columns in index order
+func (s *Service) tableIndexes(ctx context.Context, table string) (map[string]*existingIndex, error) {
+	var query string
+	switch s.config.DBEngine {
+	case "postgresql", "postgres":
+		query = `
+			SELECT i.relname, x.indisvalid, a.attname
+			FROM pg_index x
+			JOIN pg_class t ON t.oid = x.indrelid
+			JOIN pg_class i ON i.oid = x.indexrelid
+			CROSS JOIN LATERAL unnest(x.indkey::int2[]) WITH ORDINALITY AS k(attnum, ord)
+			LEFT JOIN pg_attribute a ON a.attrelid = t.oid AND a.attnum = k.attnum
+			WHERE t.relname = $1 AND pg_table_is_visible(t.oid)
+			ORDER BY i.relname, k.ord
+		`
+	case "mysql", "mariadb":
+		query = `
+			SELECT index_name, TRUE, column_name
+			FROM information_schema.statistics
+			WHERE table_schema = DATABASE() AND table_name = ?
+			ORDER BY index_name, seq_in_index
+		`
+	default:
+		query = `
+			SELECT il.name, 1, ii.name
+			FROM pragma_index_list(?) il
+			JOIN pragma_index_info(il.name) ii
+			ORDER BY il.name, ii.seqno
+		`
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	indexes := make(map[string]*existingIndex)
+	for rows.Next() {
+		var name string
+		var valid bool
+		var column sql.NullString // NULL for expression columns, which never match
+		if err := rows.Scan(&name, &valid, &column); err != nil {
+			return nil, err
+		}
+		index, ok := indexes[name]
+		if !ok {
+			index = &existingIndex{valid: valid}
+			indexes[name] = index
+		}
+		index.columns = append(index.columns, column.String)
+	}
+	return indexes, rows.Err()
+}
+
+// tableColumns returns the columns of a table; it is empty when the table does not exist
+func (s *Service) tableColumns(ctx context.Context, table string) (map[string]bool, error) {
+	var query string
+	switch s.config.DBEngine {
+	case "postgresql", "postgres":
+		query = "SELECT column_name FROM information_schema.columns WHERE table_schema = current_schema() AND table_name = $1"
+	case "mysql", "mariadb":
+		query = "SELECT column_name FROM information_schema.columns WHERE table_schema = DATABASE() AND table_name = ?"
+	default:
+		query = "SELECT name FROM pragma_table_info(?)"
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns := make(map[string]bool)
+	for rows.Next() {
+		var column string
+		if err := rows.Scan(&column); err != nil {
+			return nil, err
+		}
+		columns[strings.ToLower(column)] = true
+	}
+	return columns, rows.Err()
+}
+
+// createIndexStatement returns the SQL creating a supporting index without blocking writes
+// where the engine allows it
+func (s *Service) createIndexStatement(index supportingIndex) string {
+	columns := strings.Join(index.columns, ", ")
+	switch s.config.DBEngine {
+	case "postgresql", "postgres":
+		return fmt.Sprintf("CREATE INDEX CONCURRENTLY IF NOT EXISTS %s ON %s (%s)", index.name, index.table, columns)
+	case "mysql", "mariadb":
+		return fmt.Sprintf("CREATE INDEX %s ON %s (%s) ALGORITHM=INPLACE LOCK=NONE", index.name, index.table, columns)
+	default:
+		return fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s ON %s (%s)", index.name, index.table, columns)
+	}
+}
+
+// AdviseIndexes reports the state of the supporting indexes
+func (s *Service) AdviseIndexes(ctx context.Context) (*IndexAdvisorResponse, error) {
+	response := &IndexAdvisorResponse{Engine: s.config.DBEngine, Building: s.indexBuild.Load(), Results: []IndexAdvice{}}
+	indexesByTable := make(map[string]map[string]*existingIndex)
+	columnsByTable := make(map[string]map[string]bool)
+
+	for _, index := range supportingIndexes() {
+		if _, ok := indexesByTable[index.table]; !ok {
+			existing, err := s.tableIndexes(ctx, index.table)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read indexes of %s: %w", index.table, err)
+			}
+			columns, err := s.tableColumns(ctx, index.table)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read columns of %s: %w", index.table, err)
+			}
+			indexesByTable[index.table] = existing
+			columnsByTable[index.table] = columns
+		}
+
+		advice := IndexAdvice{Table: index.table, Name: index.name, Columns: index.columns}
+		advice.Status, advice.CoveredBy = indexStatus(index, indexesByTable[index.table], columnsByTable[index.table])
+		if advice.Status == indexMissing || advice.Status == indexInvalid {
+			advice.Statement = s.createIndexStatement(index)
+			response.Missing++
+		}
+		response.Results = append(response.Results, advice)
+	}
+	return response, nil
+}
+
+// indexStatus compares a supporting index with the indexes and columns of its table
+func indexStatus(index supportingIndex, existing map[string]*existingIndex, columns map[string]bool) (status, coveredBy string) {
+	for _, column := range index.columns {
+		if !columns[column] {
+			return indexUnavailable, ""
+		}
+	}
+	if own, ok := existing[index.name]; ok {
+		if !own.valid {
+			return indexInvalid, ""
+		}
+		return indexPresent, ""
+	}
+	for name, candidate := range existing {
+		if candidate.valid && hasColumnPrefix(candidate.columns, index.columns) {
+			return indexCovered, name
+		}
+	}
+	return indexMissing, ""
+}
+
+// hasColumnPrefix reports whether an index on columns can serve lookups on prefix
+func hasColumnPrefix(columns, prefix []string) bool {
+	if len(columns) < len(prefix) {
+		return false
+	}
+	for i, column := range prefix {
+		if !strings.EqualFold(columns[i], column) {
+			return false
+		}
+	}
+	return true
+}
+
+// CreateSupportingIndexes creates the missing supporting indexes one at a time and returns
+// the report with their outcome. Index builds are not bounded by STATEMENT_TIMEOUT.
+func (s *Service) CreateSupportingIndexes(ctx context.Context) (*IndexAdvisorResponse, error) {
+	response, err := s.AdviseIndexes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ctx = withoutStatementTimeout(ctx)
+
+	for i := range response.Results {
+		advice := &response.Results[i]
+		if advice.Status != indexMissing && advice.Status != indexInvalid {
+			continue
+		}
+		if advice.Status == indexInvalid {
+			// Only indexes named by the advisor are ever dropped
+			if _, err := s.db.ExecContext(ctx, "DROP INDEX CONCURRENTLY IF EXISTS "+advice.Name); err != nil {
+				advice.Status, advice.Error = indexFailed, err.Error()
+				log.Printf("[IndexAdvisor] Failed to drop invalid index %s: %v", advice.Name, err)
+				continue
+			}
+		}
+
+		started := time.Now()
+		if _, err := s.db.ExecContext(ctx, advice.Statement); err != nil {
+			advice.Status, advice.Error = indexFailed, err.Error()
+			log.Printf("[IndexAdvisor] Failed to create index %s: %v", advice.Name, err)
+			if ctx.Err() != nil {
+				return response, ctx.Err()
+			}
+			continue
+		}
+		advice.Status = indexCreated
+		response.Missing--
+		log.Printf("[IndexAdvisor] Created index %s on %s(%s) in %v",
+			advice.Name, advice.Table, strings.Join(advice.Columns, ", "), time.Since(started).Round(time.Millisecond))
+	}
+	return response, nil
+}
+
+// startIndexBuild creates the missing indexes in the background unless a build is already
+// running; it reports whether it started one
+func (s *Service) startIndexBuild(ctx context.Context) bool {
+	if !s.indexBuild.CompareAndSwap(false, true) {
+		return false
+	}
+	go func() {
+		defer s.indexBuild.Store(false)
+		response, err := s.CreateSupportingIndexes(ctx)
+		if err != nil {
+			log.Printf("[IndexAdvisor] Index build stopped: %v", err)
+			return
+		}
+		log.Printf("[IndexAdvisor] Index build finished - %d supporting indexes still missing", response.Missing)
+	}()
+	return true
+}
+
+// runIndexAdvisor is the startup step of the index advisor: in "report" mode it logs the
+// missing indexes with the SQL creating them, in "create" mode it creates them
+func (s *Service) runIndexAdvisor(ctx context.Context) {
+	if s.config.IndexAdvisor == "create" {
+		log.Printf("[IndexAdvisor] Creating missing supporting indexes")
+		s.startIndexBuild(ctx)
+		return
+	}
+
+	response, err := s.AdviseIndexes(ctx)
+	if err != nil {
+		log.Printf("[IndexAdvisor] Failed to analyze indexes: %v", err)
+		return
+	}
+	for _, advice := range response.Results {
+		if advice.Statement != "" {
+			log.Printf("[IndexAdvisor] Index %s is %s: %s", advice.Name, advice.Status, advice.Statement)
+		}
+	}
+	log.Printf("[IndexAdvisor] %d supporting indexes missing; set INDEX_ADVISOR=create or POST /api/indexes/ to create them", response.Missing)
+}
+
+// handleIndexAdvisor handles GET /api/indexes/ (dry-run report) and POST /api/indexes/,
+// which starts creating the missing indexes in the background. Indexes change the Paperless
+// database, so both are limited to superusers.
+func (s *Service) handleIndexAdvisor(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	log.Printf("[IndexAdvisor] %s /api/indexes/ - Request from %s", r.Method, r.RemoteAddr)
+
+	userID, err := viewerFromRequest(r)
+	if err != nil {
+		respondError(w, viewerErrorStatus(err), err.Error())
+		return
+	}
+	superuser, err := s.isSuperuser(ctx, userID)
+	if err != nil {
+		respondError(w, queryErrorStatus(err), err.Error())
+		return
+	}
+	if !superuser {
+		respondError(w, http.StatusForbidden, "permission denied: only superusers can manage database indexes")
+		return
+	}
+
+	response, err := s.AdviseIndexes(ctx)
+	if err != nil {
+		log.Printf("[IndexAdvisor] Error analyzing indexes: %v", err)
+		respondError(w, queryErrorStatus(err), err.Error())
+		return
+	}
+	if r.Method == http.MethodGet {
+		respondJSON(w, http.StatusOK, response)
+		return
+	}
+
+	if response.Missing == 0 {
+		respondJSON(w, http.StatusOK, response)
+		return
+	}
+	// Index builds outlast requests, like bulk jobs, until the service shuts down
+	if !s.startIndexBuild(s.lifecycle) {
+		respondError(w, http.StatusConflict, "an index build is already running")
+		return
+	}
+	response.Building = true
+	respondJSON(w, http.StatusAccepted, response)
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestIndexAdvisorRequiresSuperuser(t *testing.T) {
+	s := newTestService(t, nil)
+	mustExec(t, s, `INSERT INTO auth_user (id, username, is_superuser) VALUES (1, 'admin', 1), (2, 'clerk', 0)`)
+
+	for header, want := range map[string]int{"": http.StatusUnauthorized, "x": http.StatusBadRequest, "2": http.StatusForbidden} {
+		req := httptest.NewRequest(http.MethodPost, "/api/indexes/", nil)
+		if header != "" {
+			req.Header.Set("X-User-ID", header)
+		}
+		rec := httptest.NewRecorder()
+		s.handleIndexAdvisor(rec, req)
+		if rec.Code != want {
+			t.Errorf("X-User-ID %q: status %d, want %d", header, rec.Code, want)
+		}
+	}
+	if s.indexBuild.Load() {
+		t.Error("an index build was started without a superuser")
+	}
+}
@@ -0,0 +1,583 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	// minLinkSuggestionScore is the confidence below which candidates are not suggested
+	minLinkSuggestionScore = 0.3
+	// maxLinkSuggestionsPerDocument caps the suggestions kept per analyzed document
+	maxLinkSuggestionsPerDocument = 20
+	// linkSuggestionASNBlock is the size of the ASN ranges documents are grouped by: documents
+	// archived together get serial numbers with the same prefix
+	linkSuggestionASNBlock = 100
+	// maxSharedFieldDocuments is the number of documents a custom field value may be shared by
+	// to count as identifying, like an order number (unlike e.g. a status)
+	maxSharedFieldDocuments = 5
+)
+
+// Scores of the single signals; a candidate with several signals scores 1 - (1-a)(1-b)...
+const (
+	linkSuggestionASNScore         = 0.3
+	linkSuggestionCorrespondentMax = 0.4 // Same day; decreasing to 0 at the end of the window
+	linkSuggestionFieldScore       = 0.6
+)
+
+// LinkSuggestionReason explains why a document was suggested: "asn_prefix",
+// "correspondent_date" or "custom_field"
+type LinkSuggestionReason struct {
+	Kind   string `json:"kind"`
+	Detail string `json:"detail"`
+}
+
+// LinkSuggestion proposes relating a document to the suggested one
+type LinkSuggestion struct {
+	ID          int                    `json:"id"`
+	DocumentID  int                    `json:"document_id"`
+	SuggestedID int                    `json:"suggested_id"`
+	Title       string                 `json:"title"` // Title of the suggested document
+	Score       float64                `json:"score"`
+	Reasons     []LinkSuggestionReason `json:"reasons"`
+}
+
+// LinkSuggestionsResponse lists the pending suggestions of a document, best first
+type LinkSuggestionsResponse struct {
+	DocumentID int              `json:"document_id"`
+	Count      int              `json:"count"`
+	Results    []LinkSuggestion `json:"results"`
+}
+
+// linkSuggestionCandidate collects the signals relating a candidate to the analyzed document
+type linkSuggestionCandidate struct {
+	scores  []float64
+	reasons []LinkSuggestionReason
+}
+
+func (c *linkSuggestionCandidate) add(score float64, kind string, detail string) {
+	c.scores = append(c.scores, score)
+	c.reasons = append(c.reasons, LinkSuggestionReason{Kind: kind, Detail: detail})
+}
+
+func (c *linkSuggestionCandidate) score() float64 {
+	remaining := 1.0
+	for _, score := range c.scores {
+		remaining *= 1 - score
+	}
+	return math.Round((1-remaining)*100) / 100
+}
+
+// findLinkSuggestionCandidates scores the documents outside the trash that share metadata with
+// a document: the ASN prefix, the correspondent with a close date or an identifying custom
+// field value. It returns nil for documents that are missing or trashed.
+func (s *Service) findLinkSuggestionCandidates(ctx context.Context, documentID int) (map[int]*linkSuggestionCandidate, error) {
+	var correspondentID, asn sql.NullInt64
+	var created interface{}
+	err := s.db.QueryRowContext(ctx, s.rebind(`
+		SELECT correspondent_id, created, archive_serial_number
+		FROM documents_document
+		WHERE id = ? AND deleted_at IS NULL
+	`), documentID).Scan(&correspondentID, &created, &asn)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to query document %d: %w", documentID, err)
+	}
+
+	candidates := make(map[int]*linkSuggestionCandidate)
+	candidate := func(id int) *linkSuggestionCandidate {
+		if candidates[id] == nil {
+			candidates[id] = &linkSuggestionCandidate{}
+		}
+		return candidates[id]
+	}
+
+	if asn.Valid {
+		from := asn.Int64 / linkSuggestionASNBlock * linkSuggestionASNBlock
+		rows, err := s.db.QueryContext(ctx, s.rebind(`
+			SELECT id FROM documents_document
+			WHERE archive_serial_number >= ? AND archive_serial_number < ? AND id <> ? AND deleted_at IS NULL
+		`), from, from+linkSuggestionASNBlock, documentID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query documents by ASN: %w", err)
+		}
+		detail := fmt.Sprintf("ASN %d-%d", from, from+linkSuggestionASNBlock-1)
+		for rows.Next() {
+			var id int
+			if err := rows.Scan(&id); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("failed to scan document: %w", err)
+			}
+			candidate(id).add(linkSuggestionASNScore, "asn_prefix", detail)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("failed to read documents by ASN: %w", err)
+		}
+	}
+
+	createdDate, hasDate := parseStatsDate(created)
+	if window := s.config.LinkSuggestionDays; correspondentID.Valid && hasDate && window > 0 {
+		rows, err := s.db.QueryContext(ctx, s.rebind(`
+			SELECT id, created FROM documents_document
+			WHERE correspondent_id = ? AND created >= ? AND created <= ? AND id <> ? AND deleted_at IS NULL
+		`), correspondentID.Int64, createdDate.AddDate(0, 0, -window).Format("2006-01-02"),
+			createdDate.AddDate(0, 0, window).Format("2006-01-02"), documentID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query documents by correspondent: %w", err)
+		}
+		for rows.Next() {
+			var id int
+			var otherCreated interface{}
+			if err := rows.Scan(&id, &otherCreated); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("failed to scan document: %w", err)
+			}
+			otherDate, ok := parseStatsDate(otherCreated)
+			if !ok {
+				continue
+			}
+			days := int(math.Abs(otherDate.Sub(createdDate).Hours()) / 24)
+			score := linkSuggestionCorrespondentMax * (1 - float64(days)/float64(window+1))
+			candidate(id).add(score, "correspondent_date", fmt.Sprintf("same correspondent, %d days apart", days))
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("failed to read documents by correspondent: %w", err)
+		}
+	}
+
+	rows, err := s.db.QueryContext(ctx, s.rebind(`
+		SELECT other.document_id, f.name, cur.value_text
+		FROM documents_customfieldinstance cur
+		INNER JOIN documents_customfield f ON f.id = cur.field_id
+		INNER JOIN documents_customfieldinstance other ON other.field_id = cur.field_id
+			AND other.value_text = cur.value_text AND other.document_id <> cur.document_id AND other.deleted_at IS NULL
+		INNER JOIN documents_document d ON d.id = other.document_id AND d.deleted_at IS NULL
+		WHERE cur.document_id = ? AND cur.deleted_at IS NULL AND cur.value_text IS NOT NULL AND cur.value_text <> ''
+			AND (SELECT COUNT(*) FROM documents_customfieldinstance x
+				WHERE x.field_id = cur.field_id AND x.value_text = cur.value_text AND x.deleted_at IS NULL) <= ?
+	`), documentID, maxSharedFieldDocuments)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query documents by custom field value: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id int
+		var field, value string
+		if err := rows.Scan(&id, &field, &value); err != nil {
+			return nil, fmt.Errorf("failed to scan custom field value: %w", err)
+		}
+		candidate(id).add(linkSuggestionFieldScore, "custom_field", fmt.Sprintf("%s: %s", field, value))
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read custom field values: %w", err)
+	}
+
+	return candidates, nil
+}
+
+// AnalyzeLinkSuggestions replaces the pending suggestions of a document with the best scored
+// candidates that are not linked to it yet. Suggestions that were confirmed or dismissed are
+// not proposed again. It returns the number of pending suggestions stored.
+func (s *Service) AnalyzeLinkSuggestions(ctx context.Context, documentID int) (int, error) {
+	candidates, err := s.findLinkSuggestionCandidates(ctx, documentID)
+	if err != nil {
+		return 0, err
+	}
+
+	// Pairs are stored once, lower document ID first
+	pair := func(id int) (int, int) {
+		if id < documentID {
+			return id, documentID
+		}
+		return documentID, id
+	}
+
+	decided := make(map[int]bool)
+	rows, err := s.db.QueryContext(ctx, s.rebind(`
+		SELECT document_id, related_id FROM link_suggestions WHERE (document_id = ? OR related_id = ?) AND status <> 'pending'
+		UNION
+		SELECT source_id, target_id FROM document_links WHERE source_id = ? OR target_id = ?
+	`), documentID, documentID, documentID, documentID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query decided suggestions: %w", err)
+	}
+	for rows.Next() {
+		var a, b int
+		if err := rows.Scan(&a, &b); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan decided suggestion: %w", err)
+		}
+		if a == documentID {
+			decided[b] = true
+		} else {
+			decided[a] = true
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to read decided suggestions: %w", err)
+	}
+
+	type scored struct {
+		id      int
+		score   float64
+		reasons []LinkSuggestionReason
+	}
+	suggestions := []scored{}
+	for id, candidate := range candidates {
+		if score := candidate.score(); !decided[id] && score >= minLinkSuggestionScore {
+			suggestions = append(suggestions, scored{id: id, score: score, reasons: candidate.reasons})
+		}
+	}
+	sort.Slice(suggestions, func(i, j int) bool {
+		if suggestions[i].score != suggestions[j].score {
+			return suggestions[i].score > suggestions[j].score
+		}
+		return suggestions[i].id < suggestions[j].id
+	})
+	suggestions = suggestions[:min(maxLinkSuggestionsPerDocument, len(suggestions))]
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := s.rebind("DELETE FROM link_suggestions WHERE (document_id = ? OR related_id = ?) AND status = 'pending'")
+	if _, err := tx.ExecContext(ctx, query, documentID, documentID); err != nil {
+		return 0, fmt.Errorf("failed to clear suggestions: %w", err)
+	}
+	insert := s.rebind("INSERT INTO link_suggestions (document_id, related_id, score, reasons, status) VALUES (?, ?, ?, ?, 'pending')")
+	for _, suggestion := range suggestions {
+		reasons, err := json.Marshal(suggestion.reasons)
+		if err != nil {
+			return 0, fmt.Errorf("failed to encode suggestion reasons: %w", err)
+		}
+		low, high := pair(suggestion.id)
+		if _, err := tx.ExecContext(ctx, insert, low, high, suggestion.score, string(reasons)); err != nil {
+			return 0, fmt.Errorf("failed to store suggestion: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit suggestions: %w", err)
+	}
+	return len(suggestions), nil
+}
+
+// ListLinkSuggestions retrieves the pending suggestions of a document the user can see, best
+// first. Suggested documents that are hidden from the user, trashed or linked meanwhile are
+// left out.
+func (s *Service) ListLinkSuggestions(ctx context.Context, documentID int, viewerID int) ([]LinkSuggestion, error) {
+	if err := s.checkDocumentsVisible(ctx, []int{documentID}, false, viewerID); err != nil {
+		return nil, err
+	}
+	return s.queryLinkSuggestions(ctx, documentID, viewerID, "1 = 1")
+}
+
+// queryLinkSuggestions retrieves the pending suggestions of a document selected by condition
+func (s *Service) queryLinkSuggestions(ctx context.Context, documentID int, viewerID int, condition string, args ...interface{}) ([]LinkSuggestion, error) {
+	documentCondition := trashedCondition(false)
+	visibility, err := s.documentVisibilityCondition(ctx, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	if visibility != "" {
+		documentCondition = fmt.Sprintf("(%s AND %s)", documentCondition, visibility)
+	}
+
+	rows, err := s.db.QueryContext(ctx, s.rebind(fmt.Sprintf(`
+		SELECT ls.id, d.id, d.title, ls.score, ls.reasons
+		FROM link_suggestions ls
+		INNER JOIN documents_document d ON d.id = CASE WHEN ls.document_id = ? THEN ls.related_id ELSE ls.document_id END
+		WHERE (ls.document_id = ? OR ls.related_id = ?) AND ls.status = 'pending' AND %s AND %s
+			AND NOT EXISTS (
+				SELECT 1 FROM document_links l
+				WHERE (l.source_id = ls.document_id AND l.target_id = ls.related_id)
+					OR (l.source_id = ls.related_id AND l.target_id = ls.document_id)
+			)
+		ORDER BY ls.score DESC, d.id ASC
+	`, documentCondition, condition)), append([]interface{}{documentID, documentID, documentID}, args...)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query link suggestions: %w", err)
+	}
+	defer rows.Close()
+
+	suggestions := []LinkSuggestion{}
+	for rows.Next() {
+		suggestion := LinkSuggestion{DocumentID: documentID}
+		var title, reasons sql.NullString
+		if err := rows.Scan(&suggestion.ID, &suggestion.SuggestedID, &title, &suggestion.Score, &reasons); err != nil {
+			return nil, fmt.Errorf("failed to scan link suggestion: %w", err)
+		}
+		suggestion.Title = title.String
+		if err := json.Unmarshal([]byte(reasons.String), &suggestion.Reasons); err != nil || suggestion.Reasons == nil {
+			suggestion.Reasons = []LinkSuggestionReason{}
+		}
+		suggestions = append(suggestions, suggestion)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read link suggestions: %w", err)
+	}
+	return suggestions, nil
+}
+
+// pendingLinkSuggestion retrieves a pending suggestion of a document the user can see
+func (s *Service) pendingLinkSuggestion(ctx context.Context, documentID int, suggestionID int, viewerID int) (*LinkSuggestion, error) {
+	if err := s.checkDocumentsVisible(ctx, []int{documentID}, false, viewerID); err != nil {
+		return nil, err
+	}
+	suggestions, err := s.queryLinkSuggestions(ctx, documentID, viewerID, "ls.id = ?", suggestionID)
+	if err != nil {
+		return nil, err
+	}
+	if len(suggestions) == 0 {
+		return nil, fmt.Errorf("link suggestion not found")
+	}
+	return &suggestions[0], nil
+}
+
+// ConfirmLinkSuggestion turns a pending suggestion into a link from the document to the
+// suggested one, of the given type and with the given note
+func (s *Service) ConfirmLinkSuggestion(ctx context.Context, documentID int, suggestionID int, link DocumentLink, userID int) (*DocumentLink, error) {
+	log.Printf("[LinkSuggestions] ConfirmLinkSuggestion - Document: %d, Suggestion: %d, UserID: %d", documentID, suggestionID, userID)
+	suggestion, err := s.pendingLinkSuggestion(ctx, documentID, suggestionID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	link.SourceID = documentID
+	link.TargetID = suggestion.SuggestedID
+	created, err := s.CreateDocumentLink(ctx, link, userID)
+	if err != nil {
+		return nil, err
+	}
+	query := s.rebind("UPDATE link_suggestions SET status = 'confirmed', modified = CURRENT_TIMESTAMP WHERE id = ?")
+	if _, err := s.db.ExecContext(ctx, query, suggestionID); err != nil {
+		return nil, fmt.Errorf("failed to update link suggestion: %w", err)
+	}
+	return created, nil
+}
+
+// DismissLinkSuggestion rejects a pending suggestion; the documents are not suggested to each
+// other again
+func (s *Service) DismissLinkSuggestion(ctx context.Context, documentID int, suggestionID int, userID int) error {
+	log.Printf("[LinkSuggestions] DismissLinkSuggestion - Document: %d, Suggestion: %d, UserID: %d", documentID, suggestionID, userID)
+	if _, err := s.pendingLinkSuggestion(ctx, documentID, suggestionID, userID); err != nil {
+		return err
+	}
+	query := s.rebind("UPDATE link_suggestions SET status = 'dismissed', modified = CURRENT_TIMESTAMP WHERE id = ?")
+	if _, err := s.db.ExecContext(ctx, query, suggestionID); err != nil {
+		return fmt.Errorf("failed to update link suggestion: %w", err)
+	}
+	return nil
+}
+
+// analyzeChangedDocuments analyzes the documents outside the trash that are new or were
+// modified since they were last analyzed, as recorded in analyzed (document ID to modification
+// time), and drops the pending suggestions of documents that disappeared
+func (s *Service) analyzeChangedDocuments(ctx context.Context, analyzed map[int]string) {
+	rows, err := s.db.QueryContext(ctx, "SELECT id, modified FROM documents_document WHERE deleted_at IS NULL")
+	if err != nil {
+		log.Printf("[LinkSuggestions] Failed to query documents: %v", err)
+		return
+	}
+	current := make(map[int]string)
+	for rows.Next() {
+		var id int
+		var modified interface{}
+		if err := rows.Scan(&id, &modified); err == nil {
+			current[id] = statsString(modified)
+		}
+	}
+	rows.Close()
+
+	changed := []int{}
+	for id, modified := range current {
+		if previous, ok := analyzed[id]; !ok || previous != modified {
+			changed = append(changed, id)
+		}
+	}
+	for id := range analyzed {
+		if _, ok := current[id]; !ok {
+			// Trashed or deleted: its pending suggestions go, decided ones stay
+			query := s.rebind("DELETE FROM link_suggestions WHERE (document_id = ? OR related_id = ?) AND status = 'pending'")
+			if _, err := s.db.ExecContext(ctx, query, id, id); err != nil {
+				log.Printf("[LinkSuggestions] Failed to drop suggestions of document %d: %v", id, err)
+				continue
+			}
+			delete(analyzed, id)
+		}
+	}
+	sort.Ints(changed)
+
+	stored := 0
+	for _, id := range changed {
+		if ctx.Err() != nil {
+			return
+		}
+		count, err := s.AnalyzeLinkSuggestions(ctx, id)
+		if err != nil {
+			log.Printf("[LinkSuggestions] Failed to analyze document %d: %v", id, err)
+			continue
+		}
+		analyzed[id] = current[id]
+		stored += count
+	}
+	if len(changed) > 0 {
+		log.Printf("[LinkSuggestions] Analyzed %d documents, %d suggestions found", len(changed), stored)
+	}
+}
+
+// runLinkSuggestionAnalyzer periodically analyzes new and modified documents for link
+// suggestions; the first run analyzes all documents
+func (s *Service) runLinkSuggestionAnalyzer(ctx context.Context) {
+	log.Printf("[LinkSuggestions] Analyzer started - Interval: %s", s.config.LinkSuggestionInterval)
+	analyzed := make(map[int]string)
+	s.analyzeChangedDocuments(ctx, analyzed)
+
+	ticker := time.NewTicker(s.config.LinkSuggestionInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Printf("[LinkSuggestions] Analyzer stopped")
+			return
+		case <-ticker.C:
+			s.analyzeChangedDocuments(ctx, analyzed)
+		}
+	}
+}
+
+// HTTP Handlers for link suggestions
+
+func (s *Service) handleListLinkSuggestions(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	idStr := mux.Vars(r)["id"]
+	log.Printf("[LinkSuggestions] GET /api/documents/%s/link-suggestions/ - Request from %s", idStr, r.RemoteAddr)
+
+	documentID, err := strconv.Atoi(idStr)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid document ID")
+		return
+	}
+	viewerID, err := viewerFromRequest(r)
+	if err != nil {
+		respondError(w, viewerErrorStatus(err), err.Error())
+		return
+	}
+
+	// Without the analyzer suggestions are always computed on request
+	refresh := r.URL.Query().Get("refresh")
+	if refresh == "true" || refresh == "1" || s.config.LinkSuggestionInterval <= 0 {
+		if err := s.checkDocumentsVisible(ctx, []int{documentID}, false, viewerID); err != nil {
+			respondError(w, documentLinkErrorStatus(err), err.Error())
+			return
+		}
+		if _, err := s.AnalyzeLinkSuggestions(ctx, documentID); err != nil {
+			log.Printf("[LinkSuggestions] Error analyzing document %d: %v", documentID, err)
+			respondError(w, queryErrorStatus(err), err.Error())
+			return
+		}
+	}
+
+	suggestions, err := s.ListLinkSuggestions(ctx, documentID, viewerID)
+	if err != nil {
+		log.Printf("[LinkSuggestions] Error listing suggestions of document %d: %v", documentID, err)
+		respondError(w, documentLinkErrorStatus(err), err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, LinkSuggestionsResponse{DocumentID: documentID, Count: len(suggestions), Results: suggestions})
+}
+
+func (s *Service) handleConfirmLinkSuggestion(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	vars := mux.Vars(r)
+	log.Printf("[LinkSuggestions] POST /api/documents/%s/link-suggestions/%s/confirm/ - Request from %s", vars["id"], vars["suggestionId"], r.RemoteAddr)
+
+	viewerID, err := viewerFromRequest(r)
+	if err != nil {
+		respondError(w, viewerErrorStatus(err), err.Error())
+		return
+	}
+
+	documentID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid document ID")
+		return
+	}
+	suggestionID, err := strconv.Atoi(vars["suggestionId"])
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid suggestion ID")
+		return
+	}
+
+	// The body (link_type and note of the new link) is optional
+	var link DocumentLink
+	if r.Body != nil && r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&link); err != nil {
+			respondError(w, http.StatusBadRequest, fmt.Sprintf("Invalid request body: %v", err))
+			return
+		}
+	}
+
+	created, err := s.ConfirmLinkSuggestion(ctx, documentID, suggestionID, link, viewerID)
+	if err != nil {
+		log.Printf("[LinkSuggestions] Error confirming suggestion %d: %v", suggestionID, err)
+		respondError(w, documentLinkErrorStatus(err), err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, created)
+}
+
+func (s *Service) handleDismissLinkSuggestion(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	vars := mux.Vars(r)
+	log.Printf("[LinkSuggestions] POST /api/documents/%s/link-suggestions/%s/dismiss/ - Request from %s", vars["id"], vars["suggestionId"], r.RemoteAddr)
+
+	viewerID, err := viewerFromRequest(r)
+	if err != nil {
+		respondError(w, viewerErrorStatus(err), err.Error())
+		return
+	}
+
+	documentID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid document ID")
+		return
+	}
+	suggestionID, err := strconv.Atoi(vars["suggestionId"])
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid suggestion ID")
+		return
+	}
+
+	if err := s.DismissLinkSuggestion(ctx, documentID, suggestionID, viewerID); err != nil {
+		log.Printf("[LinkSuggestions] Error dismissing suggestion %d: %v", suggestionID, err)
+		respondError(w, documentLinkErrorStatus(err), err.Error())
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
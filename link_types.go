@@ -0,0 +1,338 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// linkTypeColorPattern matches the hex colors of link types, like Paperless tag colors
+var linkTypeColorPattern = regexp.MustCompile(`^#[0-9A-Fa-f]{6}$`)
+
+// queryLinkTypes retrieves the link types selected by condition, ordered by name
+func (s *Service) queryLinkTypes(ctx context.Context, condition string, args ...interface{}) ([]LinkType, error) {
+	rows, err := s.db.QueryContext(ctx, s.rebind(`
+		SELECT id, name, inverse_name, color, directed, created, modified
+		FROM link_types
+		WHERE `+condition+`
+		ORDER BY name ASC
+	`), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query link types: %w", err)
+	}
+	defer rows.Close()
+
+	linkTypes := []LinkType{}
+	for rows.Next() {
+		var linkType LinkType
+		var id int
+		var inverseName, color sql.NullString
+		var directed sql.NullBool
+		var created, modified dbTimestamp
+		if err := rows.Scan(&id, &linkType.Name, &inverseName, &color, &directed, &created, &modified); err != nil {
+			return nil, fmt.Errorf("failed to scan link type: %w", err)
+		}
+		linkType.ID = &id
+		if inverseName.Valid {
+			linkType.InverseName = &inverseName.String
+		}
+		if color.Valid {
+			linkType.Color = &color.String
+		}
+		linkType.Directed = directed.Bool
+		linkType.Created = s.formatTimestamp(created)
+		linkType.Modified = s.formatTimestamp(modified)
+		linkTypes = append(linkTypes, linkType)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read link types: %w", err)
+	}
+	return linkTypes, nil
+}
+
+// ListLinkTypes retrieves all link types
+func (s *Service) ListLinkTypes(ctx context.Context) ([]LinkType, error) {
+	return s.queryLinkTypes(ctx, "1 = 1")
+}
+
+// GetLinkType retrieves a link type by ID
+func (s *Service) GetLinkType(ctx context.Context, id int) (*LinkType, error) {
+	linkTypes, err := s.queryLinkTypes(ctx, "id = ?", id)
+	if err != nil {
+		return nil, err
+	}
+	if len(linkTypes) == 0 {
+		return nil, fmt.Errorf("link type not found")
+	}
+	return &linkTypes[0], nil
+}
+
+// linkTypeByName retrieves the link type links of the given type refer to; unknown names
+// are invalid
+func (s *Service) linkTypeByName(ctx context.Context, name string) (*LinkType, error) {
+	linkTypes, err := s.queryLinkTypes(ctx, "name = ?", name)
+	if err != nil {
+		return nil, err
+	}
+	if len(linkTypes) == 0 {
+		return nil, fmt.Errorf("invalid link_type %q: not a defined link type", name)
+	}
+	return &linkTypes[0], nil
+}
+
+// checkLinkType normalizes and validates a link type before it is stored under id (0 for a
+// new link type)
+func (s *Service) checkLinkType(ctx context.Context, linkType *LinkType, id int) error {
+	linkType.Name = strings.TrimSpace(linkType.Name)
+	if linkType.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if len(linkType.Name) > maxDocumentLinkTypeLength {
+		return fmt.Errorf("invalid name: at most %d characters", maxDocumentLinkTypeLength)
+	}
+
+	if linkType.InverseName != nil {
+		inverseName := strings.TrimSpace(*linkType.InverseName)
+		switch {
+		case inverseName == "":
+			linkType.InverseName = nil
+		case len(inverseName) > maxDocumentLinkTypeLength:
+			return fmt.Errorf("invalid inverse_name: at most %d characters", maxDocumentLinkTypeLength)
+		case !linkType.Directed:
+			return fmt.Errorf("invalid inverse_name: only directed link types have an inverse name")
+		default:
+			linkType.InverseName = &inverseName
+		}
+	}
+	if linkType.Color != nil && !linkTypeColorPattern.MatchString(*linkType.Color) {
+		return fmt.Errorf("invalid color %q: expected #rrggbb", *linkType.Color)
+	}
+
+	var existingID int
+	err := s.db.QueryRowContext(ctx, s.rebind("SELECT id FROM link_types WHERE name = ? AND id <> ?"), linkType.Name, id).Scan(&existingID)
+	if err == nil {
+		return fmt.Errorf("link type %q already exists", linkType.Name)
+	} else if err != sql.ErrNoRows {
+		return fmt.Errorf("failed to check link type: %w", err)
+	}
+	return nil
+}
+
+// CreateLinkType defines a new link type
+func (s *Service) CreateLinkType(ctx context.Context, linkType LinkType) (*LinkType, error) {
+	log.Printf("[LinkTypes] CreateLinkType - Name: %s", linkType.Name)
+	if err := s.checkLinkType(ctx, &linkType, 0); err != nil {
+		return nil, err
+	}
+
+	var id int64
+	if s.config.DBEngine == "postgresql" || s.config.DBEngine == "postgres" {
+		err := s.db.QueryRowContext(ctx, `
+			INSERT INTO link_types (name, inverse_name, color, directed)
+			VALUES ($1, $2, $3, $4)
+			RETURNING id
+		`, linkType.Name, linkType.InverseName, linkType.Color, linkType.Directed).Scan(&id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create link type: %w", err)
+		}
+	} else {
+		result, err := s.db.ExecContext(ctx, `
+			INSERT INTO link_types (name, inverse_name, color, directed)
+			VALUES (?, ?, ?, ?)
+		`, linkType.Name, linkType.InverseName, linkType.Color, linkType.Directed)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create link type: %w", err)
+		}
+		if id, err = result.LastInsertId(); err != nil {
+			return nil, fmt.Errorf("failed to get link type ID: %w", err)
+		}
+	}
+
+	return s.GetLinkType(ctx, int(id))
+}
+
+// UpdateLinkType replaces a link type. Renaming it renames the type of its links as well.
+func (s *Service) UpdateLinkType(ctx context.Context, id int, linkType LinkType) (*LinkType, error) {
+	log.Printf("[LinkTypes] UpdateLinkType - ID: %d", id)
+	existing, err := s.GetLinkType(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkLinkType(ctx, &linkType, id); err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, s.rebind(`
+		UPDATE link_types
+		SET name = ?, inverse_name = ?, color = ?, directed = ?, modified = CURRENT_TIMESTAMP
+		WHERE id = ?
+	`), linkType.Name, linkType.InverseName, linkType.Color, linkType.Directed, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update link type: %w", err)
+	}
+	if linkType.Name != existing.Name {
+		query := s.rebind("UPDATE document_links SET link_type = ? WHERE link_type = ?")
+		if _, err := tx.ExecContext(ctx, query, linkType.Name, existing.Name); err != nil {
+			return nil, fmt.Errorf("failed to rename links: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit link type: %w", err)
+	}
+
+	return s.GetLinkType(ctx, id)
+}
+
+// DeleteLinkType deletes a link type no link uses
+func (s *Service) DeleteLinkType(ctx context.Context, id int) error {
+	log.Printf("[LinkTypes] DeleteLinkType - ID: %d", id)
+	existing, err := s.GetLinkType(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	var links int
+	if err := s.db.QueryRowContext(ctx, s.rebind("SELECT COUNT(*) FROM document_links WHERE link_type = ?"), existing.Name).Scan(&links); err != nil {
+		return fmt.Errorf("failed to count links: %w", err)
+	}
+	if links > 0 {
+		return fmt.Errorf("link type %q is still in use (%d links)", existing.Name, links)
+	}
+
+	if _, err := s.db.ExecContext(ctx, s.rebind("DELETE FROM link_types WHERE id = ?"), id); err != nil {
+		return fmt.Errorf("failed to delete link type: %w", err)
+	}
+	return nil
+}
+
+// HTTP Handlers for link types
+
+func (s *Service) handleListLinkTypes(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	log.Printf("[LinkTypes] GET /api/link-types/ - Request from %s", r.RemoteAddr)
+
+	linkTypes, err := s.ListLinkTypes(ctx)
+	if err != nil {
+		log.Printf("[LinkTypes] Error listing link types: %v", err)
+		respondError(w, queryErrorStatus(err), err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, LinkTypeListResponse{Count: len(linkTypes), Results: linkTypes})
+}
+
+func (s *Service) handleGetLinkType(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	idStr := mux.Vars(r)["id"]
+	log.Printf("[LinkTypes] GET /api/link-types/%s/ - Request from %s", idStr, r.RemoteAddr)
+
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid link type ID")
+		return
+	}
+
+	linkType, err := s.GetLinkType(ctx, id)
+	if err != nil {
+		respondError(w, documentLinkErrorStatus(err), err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, linkType)
+}
+
+func (s *Service) handleCreateLinkType(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	log.Printf("[LinkTypes] POST /api/link-types/ - Request from %s", r.RemoteAddr)
+
+	var linkType LinkType
+	if err := json.NewDecoder(r.Body).Decode(&linkType); err != nil {
+		log.Printf("[LinkTypes] Error decoding request body: %v", err)
+		respondError(w, http.StatusBadRequest, fmt.Sprintf("Invalid request body: %v", err))
+		return
+	}
+
+	created, err := s.CreateLinkType(ctx, linkType)
+	if err != nil {
+		log.Printf("[LinkTypes] Error creating link type: %v", err)
+		respondError(w, documentLinkErrorStatus(err), err.Error())
+		return
+	}
+
+	log.Printf("[LinkTypes] Successfully created link type %d: %s", *created.ID, created.Name)
+	respondJSON(w, http.StatusCreated, created)
+}
+
+func (s *Service) handleUpdateLinkType(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	idStr := mux.Vars(r)["id"]
+	log.Printf("[LinkTypes] PUT /api/link-types/%s/ - Request from %s", idStr, r.RemoteAddr)
+
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid link type ID")
+		return
+	}
+
+	var linkType LinkType
+	if err := json.NewDecoder(r.Body).Decode(&linkType); err != nil {
+		log.Printf("[LinkTypes] Error decoding request body: %v", err)
+		respondError(w, http.StatusBadRequest, fmt.Sprintf("Invalid request body: %v", err))
+		return
+	}
+
+	updated, err := s.UpdateLinkType(ctx, id, linkType)
+	if err != nil {
+		log.Printf("[LinkTypes] Error updating link type %d: %v", id, err)
+		respondError(w, documentLinkErrorStatus(err), err.Error())
+		return
+	}
+
+	log.Printf("[LinkTypes] Successfully updated link type %d", id)
+	respondJSON(w, http.StatusOK, updated)
+}
+
+func (s *Service) handleDeleteLinkType(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	idStr := mux.Vars(r)["id"]
+	log.Printf("[LinkTypes] DELETE /api/link-types/%s/ - Request from %s", idStr, r.RemoteAddr)
+
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid link type ID")
+		return
+	}
+
+	if err := s.DeleteLinkType(ctx, id); err != nil {
+		log.Printf("[LinkTypes] Error deleting link type %d: %v", id, err)
+		respondError(w, documentLinkErrorStatus(err), err.Error())
+		return
+	}
+
+	log.Printf("[LinkTypes] Successfully deleted link type %d", id)
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -25,44 +25,248 @@ func main() {
 	}
 	defer func() {
 		log.Printf("[Main] Closing database connection")
+		service.statements.Close()
 		service.db.Close()
 	}()
 
+	// Start background jobs; they are stopped during graceful shutdown
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+	service.StartBackgroundJobs(jobsCtx)
+
 	log.Printf("[Main] Setting up router and routes")
 	// Setup router
 	router := mux.NewRouter()
 
 	// API routes for custom field values
 	customFieldValuesAPI := router.PathPrefix("/api/custom-field-values").Subrouter()
+	customFieldValuesAPI.HandleFunc("/cooccurrence/", service.handleGetCooccurrence).Methods("POST")
 	customFieldValuesAPI.HandleFunc("/{fieldId:[0-9]+}/", service.handleGetFieldValues).Methods("GET")
 	customFieldValuesAPI.HandleFunc("/{fieldId:[0-9]+}/search/", service.handleSearchFieldValues).Methods("GET")
 	customFieldValuesAPI.HandleFunc("/{fieldId:[0-9]+}/counts/", service.handleGetValueCounts).Methods("POST")
+	customFieldValuesAPI.HandleFunc("/{fieldId:[0-9]+}/stats/", service.handleGetFieldStatistics).Methods("GET")
+	customFieldValuesAPI.HandleFunc("/{fieldId:[0-9]+}/merge-suggestions/", service.handleGetMergeSuggestions).Methods("GET")
+	customFieldValuesAPI.HandleFunc("/cache/", service.handleInvalidateValueCache).Methods("DELETE")
+	customFieldValuesAPI.HandleFunc("/{fieldId:[0-9]+}/cache/", service.handleInvalidateValueCache).Methods("DELETE")
 
 	// API routes for built-in filter values
 	builtinFilterValuesAPI := router.PathPrefix("/api/builtin-filter-values").Subrouter()
-	builtinFilterValuesAPI.HandleFunc("/{filterType}/", service.handleGetBuiltinFilterValues).Methods("POST")
+	builtinFilterValuesAPI.HandleFunc("/summary/", service.handleGetDocumentSummary).Methods("POST")
+	builtinFilterValuesAPI.HandleFunc("/cache/", service.handleInvalidateBuiltinCache).Methods("DELETE")
+	builtinFilterValuesAPI.HandleFunc("/{filterType}/", service.handleGetBuiltinFilterValues).Methods("GET", "POST")
+
+	// API route for combined facet counts
+	router.HandleFunc("/api/facets/", service.handleGetFacets).Methods("POST")
+
+	// API route for the values of all visible filters of a view
+	router.HandleFunc("/api/filter-context/", service.handleGetFilterContext).Methods("POST")
 
 	// API routes for custom views
 	customViewsAPI := router.PathPrefix("/api/custom_views").Subrouter()
 	customViewsAPI.HandleFunc("/", service.handleListCustomViews).Methods("GET")
+	customViewsAPI.HandleFunc("/mine/", service.handleListMyCustomViews).Methods("GET")
+	customViewsAPI.HandleFunc("/global/", service.handleListGlobalCustomViews).Methods("GET")
+	customViewsAPI.HandleFunc("/usage/", service.handleGetViewUsage).Methods("GET")
 	customViewsAPI.HandleFunc("/", service.handleCreateCustomView).Methods("POST")
+	customViewsAPI.HandleFunc("/trash/", service.handleListTrashedCustomViews).Methods("GET")
+	customViewsAPI.HandleFunc("/templates/", service.handleListViewTemplates).Methods("GET")
+	customViewsAPI.HandleFunc("/from-template/", service.handleCreateViewFromTemplate).Methods("POST")
+	customViewsAPI.HandleFunc("/preferences/", service.handleGetViewPreferences).Methods("GET")
+	customViewsAPI.HandleFunc("/order/", service.handleSetViewOrder).Methods("PUT")
+	customViewsAPI.HandleFunc("/default/", service.handleGetDefaultView).Methods("GET")
+	customViewsAPI.HandleFunc("/default/", service.handleSetDefaultView).Methods("PUT")
+	customViewsAPI.HandleFunc("/{id:[0-9]+}/favorite/", service.handleSetViewFavorite).Methods("PUT", "DELETE")
+	customViewsAPI.HandleFunc("/export/", service.handleExportCustomViews).Methods("GET")
+	customViewsAPI.HandleFunc("/import/", service.handleImportCustomViews).Methods("POST")
 	customViewsAPI.HandleFunc("/{id:[0-9]+}/", service.handleGetCustomView).Methods("GET")
+	customViewsAPI.HandleFunc("/{id:[0-9]+}/export/", service.handleExportCustomViews).Methods("GET")
+	customViewsAPI.HandleFunc("/{id:[0-9]+}/documents/", service.handleGetViewDocuments).Methods("GET")
+	customViewsAPI.HandleFunc("/{id:[0-9]+}/validate/", service.handleCheckCustomView).Methods("GET")
+	customViewsAPI.HandleFunc("/{id:[0-9]+}/restore/", service.handleRestoreCustomView).Methods("POST")
+	customViewsAPI.HandleFunc("/{id:[0-9]+}/purge/", service.handlePurgeCustomView).Methods("DELETE")
+	customViewsAPI.HandleFunc("/{id:[0-9]+}/revisions/", service.handleListCustomViewRevisions).Methods("GET")
+	customViewsAPI.HandleFunc("/{id:[0-9]+}/revisions/{rev:[0-9]+}/restore/", service.handleRestoreCustomViewRevision).Methods("POST")
+	customViewsAPI.HandleFunc("/{id:[0-9]+}/snapshots/", service.handleListViewSnapshots).Methods("GET")
+	customViewsAPI.HandleFunc("/{id:[0-9]+}/snapshots/", service.handleCreateViewSnapshot).Methods("POST")
+	customViewsAPI.HandleFunc("/{id:[0-9]+}/snapshots/diff/", service.handleDiffViewSnapshots).Methods("GET")
+	customViewsAPI.HandleFunc("/{id:[0-9]+}/snapshots/schedule/", service.handleGetViewSnapshotSchedule).Methods("GET")
+	customViewsAPI.HandleFunc("/{id:[0-9]+}/snapshots/schedule/", service.handleSetViewSnapshotSchedule).Methods("PUT")
+	customViewsAPI.HandleFunc("/{id:[0-9]+}/snapshots/schedule/", service.handleDeleteViewSnapshotSchedule).Methods("DELETE")
+	customViewsAPI.HandleFunc("/{id:[0-9]+}/snapshots/{snapshot:[0-9]+}/", service.handleGetViewSnapshot).Methods("GET")
+	customViewsAPI.HandleFunc("/{id:[0-9]+}/rules/", service.handleListViewRules).Methods("GET")
+	customViewsAPI.HandleFunc("/{id:[0-9]+}/rules/", service.handleCreateViewRule).Methods("POST")
+	customViewsAPI.HandleFunc("/{id:[0-9]+}/rules/{rule:[0-9]+}/", service.handleGetViewRule).Methods("GET")
+	customViewsAPI.HandleFunc("/{id:[0-9]+}/rules/{rule:[0-9]+}/", service.handleUpdateViewRule).Methods("PUT", "PATCH")
+	customViewsAPI.HandleFunc("/{id:[0-9]+}/rules/{rule:[0-9]+}/", service.handleDeleteViewRule).Methods("DELETE")
 	customViewsAPI.HandleFunc("/{id:[0-9]+}/", service.handleUpdateCustomView).Methods("PUT", "PATCH")
 	customViewsAPI.HandleFunc("/{id:[0-9]+}/", service.handleDeleteCustomView).Methods("DELETE")
 
-	// API routes for tag groups
-	tagGroupsAPI := router.PathPrefix("/api/tag-groups").Subrouter()
-	tagGroupsAPI.HandleFunc("/", service.handleListTagGroups).Methods("GET")
-	tagGroupsAPI.HandleFunc("/", service.handleCreateTagGroup).Methods("POST")
-	tagGroupsAPI.HandleFunc("/{id:[0-9]+}/", service.handleGetTagGroup).Methods("GET")
-	tagGroupsAPI.HandleFunc("/{id:[0-9]+}/", service.handleUpdateTagGroup).Methods("PUT", "PATCH")
-	tagGroupsAPI.HandleFunc("/{id:[0-9]+}/", service.handleDeleteTagGroup).Methods("DELETE")
+	// API routes for filter presets
+	filterPresetsAPI := router.PathPrefix("/api/filter_presets").Subrouter()
+	filterPresetsAPI.HandleFunc("/", service.handleListFilterPresets).Methods("GET")
+	filterPresetsAPI.HandleFunc("/", service.handleCreateFilterPreset).Methods("POST")
+	filterPresetsAPI.HandleFunc("/{id:[0-9]+}/", service.handleGetFilterPreset).Methods("GET")
+	filterPresetsAPI.HandleFunc("/{id:[0-9]+}/", service.handleUpdateFilterPreset).Methods("PUT", "PATCH")
+	filterPresetsAPI.HandleFunc("/{id:[0-9]+}/", service.handleDeleteFilterPreset).Methods("DELETE")
 
-	// API routes for tag descriptions
-	tagDescriptionsAPI := router.PathPrefix("/api/tag-descriptions").Subrouter()
-	tagDescriptionsAPI.HandleFunc("/{tagId:[0-9]+}/", service.handleGetTagDescription).Methods("GET")
-	tagDescriptionsAPI.HandleFunc("/{tagId:[0-9]+}/", service.handleSetTagDescription).Methods("PUT")
-	tagDescriptionsAPI.HandleFunc("/{tagId:[0-9]+}/", service.handleDeleteTagDescription).Methods("DELETE")
+	// API routes for column presets
+	columnPresetsAPI := router.PathPrefix("/api/column_presets").Subrouter()
+	columnPresetsAPI.HandleFunc("/", service.handleListColumnPresets).Methods("GET")
+	columnPresetsAPI.HandleFunc("/", service.handleCreateColumnPreset).Methods("POST")
+	columnPresetsAPI.HandleFunc("/{id:[0-9]+}/", service.handleGetColumnPreset).Methods("GET")
+	columnPresetsAPI.HandleFunc("/{id:[0-9]+}/", service.handleUpdateColumnPreset).Methods("PUT", "PATCH")
+	columnPresetsAPI.HandleFunc("/{id:[0-9]+}/", service.handleDeleteColumnPreset).Methods("DELETE")
+
+	// API routes for webhooks
+	webhooksAPI := router.PathPrefix("/api/webhooks").Subrouter()
+	webhooksAPI.HandleFunc("/", service.handleListWebhooks).Methods("GET")
+	webhooksAPI.HandleFunc("/", service.handleCreateWebhook).Methods("POST")
+	webhooksAPI.HandleFunc("/{id:[0-9]+}/", service.handleGetWebhook).Methods("GET")
+	webhooksAPI.HandleFunc("/{id:[0-9]+}/", service.handleUpdateWebhook).Methods("PUT", "PATCH")
+	webhooksAPI.HandleFunc("/{id:[0-9]+}/", service.handleDeleteWebhook).Methods("DELETE")
+
+	registerTagGroupRoutes(router, service)
+
+	// API routes for tag aliases
+	tagAliasesAPI := router.PathPrefix("/api/tag-aliases").Subrouter()
+	tagAliasesAPI.HandleFunc("/", service.handleListTagAliases).Methods("GET")
+	tagAliasesAPI.HandleFunc("/", service.handleCreateTagAlias).Methods("POST")
+	tagAliasesAPI.HandleFunc("/{id:[0-9]+}/", service.handleGetTagAlias).Methods("GET")
+	tagAliasesAPI.HandleFunc("/{id:[0-9]+}/", service.handleUpdateTagAlias).Methods("PUT")
+	tagAliasesAPI.HandleFunc("/{id:[0-9]+}/", service.handleDeleteTagAlias).Methods("DELETE")
+
+	// API routes for links between documents
+	documentLinksAPI := router.PathPrefix("/api/document-links").Subrouter()
+	documentLinksAPI.HandleFunc("/", service.handleListDocumentLinks).Methods("GET")
+	documentLinksAPI.HandleFunc("/", service.handleCreateDocumentLink).Methods("POST")
+	documentLinksAPI.HandleFunc("/graph/", service.handleGetDocumentLinkGraph).Methods("GET")
+	documentLinksAPI.HandleFunc("/{id:[0-9]+}/", service.handleGetDocumentLink).Methods("GET")
+	documentLinksAPI.HandleFunc("/{id:[0-9]+}/", service.handleUpdateDocumentLink).Methods("PUT")
+	documentLinksAPI.HandleFunc("/{id:[0-9]+}/", service.handleDeleteDocumentLink).Methods("DELETE")
+
+	linkTypesAPI := router.PathPrefix("/api/link-types").Subrouter()
+	linkTypesAPI.HandleFunc("/", service.handleListLinkTypes).Methods("GET")
+	linkTypesAPI.HandleFunc("/", service.handleCreateLinkType).Methods("POST")
+	linkTypesAPI.HandleFunc("/{id:[0-9]+}/", service.handleGetLinkType).Methods("GET")
+	linkTypesAPI.HandleFunc("/{id:[0-9]+}/", service.handleUpdateLinkType).Methods("PUT")
+	linkTypesAPI.HandleFunc("/{id:[0-9]+}/", service.handleDeleteLinkType).Methods("DELETE")
+
+	documentsAPI := router.PathPrefix("/api/documents").Subrouter()
+	documentsAPI.HandleFunc("/{id:[0-9]+}/links/", service.handleGetDocumentLinks).Methods("GET")
+	documentsAPI.HandleFunc("/{id:[0-9]+}/link-suggestions/", service.handleListLinkSuggestions).Methods("GET")
+	documentsAPI.HandleFunc("/{id:[0-9]+}/link-suggestions/{suggestionId:[0-9]+}/confirm/", service.handleConfirmLinkSuggestion).Methods("POST")
+	documentsAPI.HandleFunc("/{id:[0-9]+}/link-suggestions/{suggestionId:[0-9]+}/dismiss/", service.handleDismissLinkSuggestion).Methods("POST")
+	documentsAPI.HandleFunc("/{id:[0-9]+}/notes/", service.handleListDocumentNotes).Methods("GET")
+	documentsAPI.HandleFunc("/{id:[0-9]+}/notes/", service.handleCreateDocumentNote).Methods("POST")
+	documentsAPI.HandleFunc("/{id:[0-9]+}/notes/{noteId:[0-9]+}/", service.handleUpdateDocumentNote).Methods("PUT", "PATCH")
+	documentsAPI.HandleFunc("/{id:[0-9]+}/notes/{noteId:[0-9]+}/", service.handleDeleteDocumentNote).Methods("DELETE")
+	documentsAPI.HandleFunc("/{id:[0-9]+}/workflows/", service.handleGetDocumentWorkflowStatuses).Methods("GET")
+	documentsAPI.HandleFunc("/duplicates/", service.handleGetDuplicateDocuments).Methods("GET")
+
+	// API routes for statistics
+	statsAPI := router.PathPrefix("/api/stats").Subrouter()
+	statsAPI.HandleFunc("/documents-over-time/", service.handleGetDocumentsOverTime).Methods("GET")
+
+	// API routes for user preferences
+	preferencesAPI := router.PathPrefix("/api/preferences").Subrouter()
+	preferencesAPI.HandleFunc("/", service.handleListUserPreferences).Methods("GET")
+	preferencesAPI.HandleFunc("/{namespace}/", service.handleGetUserPreference).Methods("GET")
+	preferencesAPI.HandleFunc("/{namespace}/", service.handleWriteUserPreference).Methods("PUT", "PATCH")
+	preferencesAPI.HandleFunc("/{namespace}/", service.handleDeleteUserPreference).Methods("DELETE")
+
+	// API routes for recently viewed and pinned documents
+	activityAPI := router.PathPrefix("/api/activity").Subrouter()
+	activityAPI.HandleFunc("/viewed/{documentId:[0-9]+}/", service.handleRecordDocumentView).Methods("POST")
+	activityAPI.HandleFunc("/recent/", service.handleListRecentDocuments).Methods("GET")
+	activityAPI.HandleFunc("/pinned/", service.handleListPinnedDocuments).Methods("GET")
+	activityAPI.HandleFunc("/pinned/{documentId:[0-9]+}/", service.handlePinDocument).Methods("POST", "DELETE")
+	activityAPI.HandleFunc("/feed/", service.handleGetActivityFeed).Methods("GET")
+
+	// API routes for document reminders
+	remindersAPI := router.PathPrefix("/api/reminders").Subrouter()
+	remindersAPI.HandleFunc("/", service.handleListReminders).Methods("GET")
+	remindersAPI.HandleFunc("/", service.handleCreateReminder).Methods("POST")
+	remindersAPI.HandleFunc("/{id:[0-9]+}/", service.handleGetReminder).Methods("GET")
+	remindersAPI.HandleFunc("/{id:[0-9]+}/", service.handleUpdateReminder).Methods("PUT", "PATCH")
+	remindersAPI.HandleFunc("/{id:[0-9]+}/", service.handleDeleteReminder).Methods("DELETE")
+
+	// API routes for saved searches
+	savedSearchesAPI := router.PathPrefix("/api/saved-searches").Subrouter()
+	savedSearchesAPI.HandleFunc("/", service.handleListSavedSearches).Methods("GET")
+	savedSearchesAPI.HandleFunc("/", service.handleCreateSavedSearch).Methods("POST")
+	savedSearchesAPI.HandleFunc("/{id:[0-9]+}/", service.handleGetSavedSearch).Methods("GET")
+	savedSearchesAPI.HandleFunc("/{id:[0-9]+}/", service.handleUpdateSavedSearch).Methods("PUT", "PATCH")
+	savedSearchesAPI.HandleFunc("/{id:[0-9]+}/", service.handleDeleteSavedSearch).Methods("DELETE")
+	savedSearchesAPI.HandleFunc("/{id:[0-9]+}/new/", service.handleGetSavedSearchNewDocuments).Methods("GET")
+
+	// API routes for workflows
+	workflowsAPI := router.PathPrefix("/api/workflows").Subrouter()
+	workflowsAPI.HandleFunc("/", service.handleListWorkflows).Methods("GET")
+	workflowsAPI.HandleFunc("/", service.handleCreateWorkflow).Methods("POST")
+	workflowsAPI.HandleFunc("/{id:[0-9]+}/", service.handleGetWorkflow).Methods("GET")
+	workflowsAPI.HandleFunc("/{id:[0-9]+}/", service.handleUpdateWorkflow).Methods("PUT", "PATCH")
+	workflowsAPI.HandleFunc("/{id:[0-9]+}/", service.handleDeleteWorkflow).Methods("DELETE")
+	workflowsAPI.HandleFunc("/{id:[0-9]+}/assign/", service.handleAssignWorkflowStatus).Methods("POST")
+	workflowsAPI.HandleFunc("/{id:[0-9]+}/counts/", service.handleGetWorkflowCounts).Methods("GET", "POST")
+
+	// API routes for the custom field audit
+	auditAPI := router.PathPrefix("/api/audit").Subrouter()
+	auditAPI.HandleFunc("/custom-fields/", service.handleAuditCustomFields).Methods("GET")
+	auditAPI.HandleFunc("/custom-fields/{fieldId:[0-9]+}/", service.handleAuditCustomField).Methods("GET")
+	auditAPI.HandleFunc("/custom-fields/{fieldId:[0-9]+}/missing/", service.handleGetCustomFieldMissingDocuments).Methods("GET")
+
+	// API routes for scheduled reports
+	reportsAPI := router.PathPrefix("/api/reports").Subrouter()
+	reportsAPI.HandleFunc("/", service.handleListScheduledReports).Methods("GET")
+	reportsAPI.HandleFunc("/", service.handleCreateScheduledReport).Methods("POST")
+	reportsAPI.HandleFunc("/{id:[0-9]+}/", service.handleGetScheduledReport).Methods("GET")
+	reportsAPI.HandleFunc("/{id:[0-9]+}/", service.handleUpdateScheduledReport).Methods("PUT", "PATCH")
+	reportsAPI.HandleFunc("/{id:[0-9]+}/", service.handleDeleteScheduledReport).Methods("DELETE")
+	reportsAPI.HandleFunc("/{id:[0-9]+}/run/", service.handleRunScheduledReport).Methods("POST")
+	reportsAPI.HandleFunc("/{id:[0-9]+}/download/", service.handleDownloadScheduledReport).Methods("GET")
+
+	// API routes for share links and their public listings
+	shareLinksAPI := router.PathPrefix("/api/share-links").Subrouter()
+	shareLinksAPI.HandleFunc("/", service.handleListShareLinks).Methods("GET")
+	shareLinksAPI.HandleFunc("/", service.handleCreateShareLink).Methods("POST")
+	shareLinksAPI.HandleFunc("/{id:[0-9]+}/", service.handleGetShareLink).Methods("GET")
+	shareLinksAPI.HandleFunc("/{id:[0-9]+}/", service.handleUpdateShareLink).Methods("PUT", "PATCH")
+	shareLinksAPI.HandleFunc("/{id:[0-9]+}/", service.handleDeleteShareLink).Methods("DELETE")
+	router.HandleFunc("/api/shared/{token:[0-9a-f]{40}}/", service.handleGetSharedDocuments).Methods("GET", "POST")
+
+	// API routes for events posted by Paperless
+	router.HandleFunc("/api/events/document-consumed/", service.handleDocumentConsumedEvent).Methods("POST")
+
+	// API routes for dashboards
+	dashboardsAPI := router.PathPrefix("/api/dashboards").Subrouter()
+	dashboardsAPI.HandleFunc("/", service.handleListDashboards).Methods("GET")
+	dashboardsAPI.HandleFunc("/", service.handleCreateDashboard).Methods("POST")
+	dashboardsAPI.HandleFunc("/{id:[0-9]+}/", service.handleGetDashboard).Methods("GET")
+	dashboardsAPI.HandleFunc("/{id:[0-9]+}/", service.handleUpdateDashboard).Methods("PUT")
+	dashboardsAPI.HandleFunc("/{id:[0-9]+}/", service.handleDeleteDashboard).Methods("DELETE")
+	dashboardsAPI.HandleFunc("/{id:[0-9]+}/data/", service.handleGetDashboardData).Methods("GET")
+
+	// API routes for descriptions of tags, correspondents, document types and storage paths
+	descriptionsAPI := router.PathPrefix("/api/descriptions").Subrouter()
+	descriptionsAPI.HandleFunc("/{entityType}/", service.handleListEntityDescriptions).Methods("GET")
+	descriptionsAPI.HandleFunc("/{entityType}/{id:[0-9]+}/history/", service.handleGetEntityDescriptionHistory).Methods("GET")
+	descriptionsAPI.HandleFunc("/{entityType}/{id:[0-9]+}/", service.handleGetEntityDescription).Methods("GET")
+	descriptionsAPI.HandleFunc("/{entityType}/{id:[0-9]+}/", service.handleSetEntityDescription).Methods("PUT")
+	descriptionsAPI.HandleFunc("/{entityType}/{id:[0-9]+}/", service.handleDeleteEntityDescription).Methods("DELETE")
+
+	// API routes for correspondent profiles
+	correspondentProfilesAPI := router.PathPrefix("/api/correspondent-profiles").Subrouter()
+	correspondentProfilesAPI.HandleFunc("/", service.handleListCorrespondentProfiles).Methods("GET")
+	correspondentProfilesAPI.HandleFunc("/{correspondentId:[0-9]+}/", service.handleGetCorrespondentProfile).Methods("GET")
+	correspondentProfilesAPI.HandleFunc("/{correspondentId:[0-9]+}/", service.handleSetCorrespondentProfile).Methods("PUT", "PATCH")
+	correspondentProfilesAPI.HandleFunc("/{correspondentId:[0-9]+}/", service.handleDeleteCorrespondentProfile).Methods("DELETE")
+
+	// API routes for bulk metadata operations
+	bulkAPI := router.PathPrefix("/api/bulk").Subrouter()
+	bulkAPI.HandleFunc("/preview/", service.handlePreviewBulkOperation).Methods("POST")
+	bulkAPI.HandleFunc("/apply/", service.handleApplyBulkOperation).Methods("POST")
+	bulkAPI.HandleFunc("/jobs/", service.handleListBulkJobs).Methods("GET")
+	bulkAPI.HandleFunc("/jobs/{id:[0-9]+}/", service.handleGetBulkJob).Methods("GET")
 
 	// Health check
 	router.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
@@ -74,17 +278,30 @@ func main() {
 		w.Write([]byte("OK"))
 	}).Methods("GET")
 
-	// CORS middleware
+	// Index advisor for the supporting indexes of the Paperless tables
+	router.HandleFunc("/api/indexes/", service.handleIndexAdvisor).Methods("GET", "POST")
+
+	// Prometheus metrics
+	router.HandleFunc("/metrics", handleMetrics).Methods("GET")
+
+	// CORS middleware around ETags and Cache-Control for GET responses
 	corsHandler := handlers.CORS(
 		handlers.AllowedOrigins([]string{"*"}),
 		handlers.AllowedMethods([]string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}),
-		handlers.AllowedHeaders([]string{"Content-Type", "Authorization"}),
-	)(router)
+		handlers.AllowedHeaders([]string{"Content-Type", "Authorization", "If-Match", "If-None-Match", "X-User-ID"}),
+		handlers.ExposedHeaders([]string{"ETag"}),
+	)(conditionalGetHandler(router))
+
+	// Compression middleware: gzip or deflate, negotiated with Accept-Encoding
+	handler := corsHandler
+	if config.Compression {
+		handler = handlers.CompressHandler(corsHandler)
+	}
 
 	// Setup server
 	srv := &http.Server{
 		Addr:         ":" + config.Port,
-		Handler:      corsHandler,
+		Handler:      handler,
 		ReadTimeout:  config.ReadTimeout,
 		WriteTimeout: config.WriteTimeout,
 	}
@@ -93,20 +310,198 @@ func main() {
 	go func() {
 		log.Printf("[Main] Server listening on :%s", config.Port)
 		log.Printf("[Main] API endpoints available:")
+		log.Printf("[Main]   POST   /api/custom-field-values/cooccurrence/")
+		log.Printf("[Main]   GET    /api/custom-field-values/{fieldId}/stats/")
+		log.Printf("[Main]   GET    /api/custom-field-values/{fieldId}/merge-suggestions/")
+		log.Printf("[Main]   DELETE /api/custom-field-values/cache/")
+		log.Printf("[Main]   DELETE /api/custom-field-values/{fieldId}/cache/")
+		log.Printf("[Main]   POST   /api/builtin-filter-values/summary/")
+		log.Printf("[Main]   DELETE /api/builtin-filter-values/cache/")
+		log.Printf("[Main]   POST   /api/facets/")
+		log.Printf("[Main]   POST   /api/filter-context/")
 		log.Printf("[Main]   GET    /api/custom_views/")
+		log.Printf("[Main]   GET    /api/custom_views/mine/")
+		log.Printf("[Main]   GET    /api/custom_views/global/")
+		log.Printf("[Main]   GET    /api/custom_views/usage/")
 		log.Printf("[Main]   POST   /api/custom_views/")
 		log.Printf("[Main]   GET    /api/custom_views/{id}/")
 		log.Printf("[Main]   PUT    /api/custom_views/{id}/")
 		log.Printf("[Main]   PATCH  /api/custom_views/{id}/")
 		log.Printf("[Main]   DELETE /api/custom_views/{id}/")
+		log.Printf("[Main]   GET    /api/custom_views/trash/")
+		log.Printf("[Main]   POST   /api/custom_views/{id}/restore/")
+		log.Printf("[Main]   DELETE /api/custom_views/{id}/purge/")
+		log.Printf("[Main]   GET    /api/custom_views/export/")
+		log.Printf("[Main]   GET    /api/custom_views/{id}/export/")
+		log.Printf("[Main]   POST   /api/custom_views/import/")
+		log.Printf("[Main]   GET    /api/custom_views/{id}/validate/")
+		log.Printf("[Main]   GET    /api/custom_views/{id}/revisions/")
+		log.Printf("[Main]   POST   /api/custom_views/{id}/revisions/{rev}/restore/")
+		log.Printf("[Main]   GET    /api/custom_views/preferences/")
+		log.Printf("[Main]   PUT    /api/custom_views/order/")
+		log.Printf("[Main]   GET    /api/custom_views/default/")
+		log.Printf("[Main]   PUT    /api/custom_views/default/")
+		log.Printf("[Main]   PUT    /api/custom_views/{id}/favorite/")
+		log.Printf("[Main]   DELETE /api/custom_views/{id}/favorite/")
+		log.Printf("[Main]   GET    /api/custom_views/{id}/documents/")
+		log.Printf("[Main]   GET    /api/custom_views/{id}/snapshots/")
+		log.Printf("[Main]   POST   /api/custom_views/{id}/snapshots/")
+		log.Printf("[Main]   GET    /api/custom_views/{id}/snapshots/{snapshot}/")
+		log.Printf("[Main]   GET    /api/custom_views/{id}/snapshots/diff/")
+		log.Printf("[Main]   GET    /api/custom_views/{id}/snapshots/schedule/")
+		log.Printf("[Main]   PUT    /api/custom_views/{id}/snapshots/schedule/")
+		log.Printf("[Main]   DELETE /api/custom_views/{id}/snapshots/schedule/")
+		log.Printf("[Main]   GET    /api/custom_views/{id}/rules/")
+		log.Printf("[Main]   POST   /api/custom_views/{id}/rules/")
+		log.Printf("[Main]   GET    /api/custom_views/{id}/rules/{rule}/")
+		log.Printf("[Main]   PUT    /api/custom_views/{id}/rules/{rule}/")
+		log.Printf("[Main]   PATCH  /api/custom_views/{id}/rules/{rule}/")
+		log.Printf("[Main]   DELETE /api/custom_views/{id}/rules/{rule}/")
+		log.Printf("[Main]   GET    /api/custom_views/templates/")
+		log.Printf("[Main]   POST   /api/custom_views/from-template/")
+		log.Printf("[Main]   GET    /api/filter_presets/")
+		log.Printf("[Main]   POST   /api/filter_presets/")
+		log.Printf("[Main]   GET    /api/filter_presets/{id}/")
+		log.Printf("[Main]   PUT    /api/filter_presets/{id}/")
+		log.Printf("[Main]   PATCH  /api/filter_presets/{id}/")
+		log.Printf("[Main]   DELETE /api/filter_presets/{id}/")
+		log.Printf("[Main]   GET    /api/column_presets/")
+		log.Printf("[Main]   POST   /api/column_presets/")
+		log.Printf("[Main]   GET    /api/column_presets/{id}/")
+		log.Printf("[Main]   PUT    /api/column_presets/{id}/")
+		log.Printf("[Main]   PATCH  /api/column_presets/{id}/")
+		log.Printf("[Main]   DELETE /api/column_presets/{id}/")
+		log.Printf("[Main]   GET    /api/webhooks/")
+		log.Printf("[Main]   POST   /api/webhooks/")
+		log.Printf("[Main]   GET    /api/webhooks/{id}/")
+		log.Printf("[Main]   PUT    /api/webhooks/{id}/")
+		log.Printf("[Main]   PATCH  /api/webhooks/{id}/")
+		log.Printf("[Main]   DELETE /api/webhooks/{id}/")
 		log.Printf("[Main]   GET    /api/tag-groups/")
 		log.Printf("[Main]   POST   /api/tag-groups/")
+		log.Printf("[Main]   GET    /api/tag-groups/tree/")
+		log.Printf("[Main]   POST   /api/tag-groups/counts/")
+		log.Printf("[Main]   POST   /api/tag-groups/move/")
+		log.Printf("[Main]   GET    /api/tag-groups/export/")
+		log.Printf("[Main]   POST   /api/tag-groups/import/")
+		log.Printf("[Main]   GET    /api/tag-groups/ungrouped/")
+		log.Printf("[Main]   GET    /api/tag-groups/analytics/")
+		log.Printf("[Main]   GET    /api/tag-groups/stale-memberships/")
+		log.Printf("[Main]   DELETE /api/tag-groups/stale-memberships/")
+		log.Printf("[Main]   POST   /api/tag-groups/{id}/tags/")
+		log.Printf("[Main]   DELETE /api/tag-groups/{id}/tags/")
+		log.Printf("[Main]   GET    /api/tag-groups/{id}/tree/")
+		log.Printf("[Main]   GET    /api/tag-groups/{id}/filter-rules/")
 		log.Printf("[Main]   GET    /api/tag-groups/{id}/")
 		log.Printf("[Main]   PUT    /api/tag-groups/{id}/")
+		log.Printf("[Main]   PATCH  /api/tag-groups/{id}/")
 		log.Printf("[Main]   DELETE /api/tag-groups/{id}/")
+		log.Printf("[Main]   GET    /api/tag-descriptions/")
 		log.Printf("[Main]   GET    /api/tag-descriptions/{tagId}/")
 		log.Printf("[Main]   PUT    /api/tag-descriptions/{tagId}/")
 		log.Printf("[Main]   DELETE /api/tag-descriptions/{tagId}/")
+		log.Printf("[Main]   GET    /api/tag-descriptions/{tagId}/history/")
+		log.Printf("[Main]   GET    /api/tag-aliases/")
+		log.Printf("[Main]   POST   /api/tag-aliases/")
+		log.Printf("[Main]   GET    /api/tag-aliases/{id}/")
+		log.Printf("[Main]   PUT    /api/tag-aliases/{id}/")
+		log.Printf("[Main]   DELETE /api/tag-aliases/{id}/")
+		log.Printf("[Main]   GET    /api/document-links/")
+		log.Printf("[Main]   POST   /api/document-links/")
+		log.Printf("[Main]   GET    /api/document-links/graph/")
+		log.Printf("[Main]   GET    /api/document-links/{id}/")
+		log.Printf("[Main]   PUT    /api/document-links/{id}/")
+		log.Printf("[Main]   DELETE /api/document-links/{id}/")
+		log.Printf("[Main]   GET    /api/link-types/")
+		log.Printf("[Main]   POST   /api/link-types/")
+		log.Printf("[Main]   GET    /api/link-types/{id}/")
+		log.Printf("[Main]   PUT    /api/link-types/{id}/")
+		log.Printf("[Main]   DELETE /api/link-types/{id}/")
+		log.Printf("[Main]   GET    /api/documents/{id}/links/")
+		log.Printf("[Main]   GET    /api/documents/{id}/link-suggestions/")
+		log.Printf("[Main]   POST   /api/documents/{id}/link-suggestions/{suggestionId}/confirm/")
+		log.Printf("[Main]   POST   /api/documents/{id}/link-suggestions/{suggestionId}/dismiss/")
+		log.Printf("[Main]   GET    /api/documents/{id}/notes/")
+		log.Printf("[Main]   POST   /api/documents/{id}/notes/")
+		log.Printf("[Main]   PUT    /api/documents/{id}/notes/{noteId}/")
+		log.Printf("[Main]   PATCH  /api/documents/{id}/notes/{noteId}/")
+		log.Printf("[Main]   DELETE /api/documents/{id}/notes/{noteId}/")
+		log.Printf("[Main]   GET    /api/documents/{id}/workflows/")
+		log.Printf("[Main]   GET    /api/documents/duplicates/")
+		log.Printf("[Main]   GET    /api/stats/documents-over-time/")
+		log.Printf("[Main]   GET    /api/preferences/")
+		log.Printf("[Main]   GET    /api/preferences/{namespace}/")
+		log.Printf("[Main]   PUT    /api/preferences/{namespace}/")
+		log.Printf("[Main]   PATCH  /api/preferences/{namespace}/")
+		log.Printf("[Main]   DELETE /api/preferences/{namespace}/")
+		log.Printf("[Main]   POST   /api/activity/viewed/{documentId}/")
+		log.Printf("[Main]   GET    /api/activity/recent/")
+		log.Printf("[Main]   GET    /api/activity/pinned/")
+		log.Printf("[Main]   POST   /api/activity/pinned/{documentId}/")
+		log.Printf("[Main]   DELETE /api/activity/pinned/{documentId}/")
+		log.Printf("[Main]   GET    /api/activity/feed/")
+		log.Printf("[Main]   GET    /api/reminders/")
+		log.Printf("[Main]   POST   /api/reminders/")
+		log.Printf("[Main]   GET    /api/reminders/{id}/")
+		log.Printf("[Main]   PUT    /api/reminders/{id}/")
+		log.Printf("[Main]   PATCH  /api/reminders/{id}/")
+		log.Printf("[Main]   DELETE /api/reminders/{id}/")
+		log.Printf("[Main]   GET    /api/saved-searches/")
+		log.Printf("[Main]   POST   /api/saved-searches/")
+		log.Printf("[Main]   GET    /api/saved-searches/{id}/")
+		log.Printf("[Main]   PUT    /api/saved-searches/{id}/")
+		log.Printf("[Main]   PATCH  /api/saved-searches/{id}/")
+		log.Printf("[Main]   DELETE /api/saved-searches/{id}/")
+		log.Printf("[Main]   GET    /api/saved-searches/{id}/new/")
+		log.Printf("[Main]   GET    /api/workflows/")
+		log.Printf("[Main]   POST   /api/workflows/")
+		log.Printf("[Main]   GET    /api/workflows/{id}/")
+		log.Printf("[Main]   PUT    /api/workflows/{id}/")
+		log.Printf("[Main]   PATCH  /api/workflows/{id}/")
+		log.Printf("[Main]   DELETE /api/workflows/{id}/")
+		log.Printf("[Main]   POST   /api/workflows/{id}/assign/")
+		log.Printf("[Main]   GET    /api/workflows/{id}/counts/")
+		log.Printf("[Main]   POST   /api/workflows/{id}/counts/")
+		log.Printf("[Main]   GET    /api/audit/custom-fields/")
+		log.Printf("[Main]   GET    /api/audit/custom-fields/{fieldId}/")
+		log.Printf("[Main]   GET    /api/audit/custom-fields/{fieldId}/missing/")
+		log.Printf("[Main]   GET    /api/reports/")
+		log.Printf("[Main]   POST   /api/reports/")
+		log.Printf("[Main]   GET    /api/reports/{id}/")
+		log.Printf("[Main]   PUT    /api/reports/{id}/")
+		log.Printf("[Main]   PATCH  /api/reports/{id}/")
+		log.Printf("[Main]   DELETE /api/reports/{id}/")
+		log.Printf("[Main]   POST   /api/reports/{id}/run/")
+		log.Printf("[Main]   GET    /api/reports/{id}/download/")
+		log.Printf("[Main]   GET    /api/share-links/")
+		log.Printf("[Main]   POST   /api/share-links/")
+		log.Printf("[Main]   GET    /api/share-links/{id}/")
+		log.Printf("[Main]   PUT    /api/share-links/{id}/")
+		log.Printf("[Main]   PATCH  /api/share-links/{id}/")
+		log.Printf("[Main]   DELETE /api/share-links/{id}/")
+		log.Printf("[Main]   GET    /api/shared/{token}/")
+		log.Printf("[Main]   POST   /api/shared/{token}/")
+		log.Printf("[Main]   POST   /api/events/document-consumed/")
+		log.Printf("[Main]   GET    /api/dashboards/")
+		log.Printf("[Main]   POST   /api/dashboards/")
+		log.Printf("[Main]   GET    /api/dashboards/{id}/")
+		log.Printf("[Main]   PUT    /api/dashboards/{id}/")
+		log.Printf("[Main]   DELETE /api/dashboards/{id}/")
+		log.Printf("[Main]   GET    /api/dashboards/{id}/data/")
+		log.Printf("[Main]   GET    /api/descriptions/{entityType}/")
+		log.Printf("[Main]   GET    /api/descriptions/{entityType}/{id}/")
+		log.Printf("[Main]   PUT    /api/descriptions/{entityType}/{id}/")
+		log.Printf("[Main]   DELETE /api/descriptions/{entityType}/{id}/")
+		log.Printf("[Main]   GET    /api/descriptions/{entityType}/{id}/history/")
+		log.Printf("[Main]   GET    /api/correspondent-profiles/")
+		log.Printf("[Main]   GET    /api/correspondent-profiles/{correspondentId}/")
+		log.Printf("[Main]   PUT    /api/correspondent-profiles/{correspondentId}/")
+		log.Printf("[Main]   PATCH  /api/correspondent-profiles/{correspondentId}/")
+		log.Printf("[Main]   DELETE /api/correspondent-profiles/{correspondentId}/")
+		log.Printf("[Main]   POST   /api/bulk/preview/")
+		log.Printf("[Main]   POST   /api/bulk/apply/")
+		log.Printf("[Main]   GET    /api/bulk/jobs/")
+		log.Printf("[Main]   GET    /api/bulk/jobs/{id}/")
 		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 			log.Fatalf("[Main] Server failed: %v", err)
 		}
@@ -118,6 +513,7 @@ func main() {
 	<-quit
 
 	log.Println("Shutting down server...")
+	stopJobs()
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
@@ -128,3 +524,33 @@ func main() {
 
 	log.Println("Server exited")
 }
+
+// registerTagGroupRoutes mounts the tag group and tag description API on router
+func registerTagGroupRoutes(router *mux.Router, service *Service) {
+	// API routes for tag groups
+	tagGroupsAPI := router.PathPrefix("/api/tag-groups").Subrouter()
+	tagGroupsAPI.HandleFunc("/", service.handleListTagGroups).Methods("GET")
+	tagGroupsAPI.HandleFunc("/", service.handleCreateTagGroup).Methods("POST")
+	tagGroupsAPI.HandleFunc("/tree/", service.handleGetTagGroupTree).Methods("GET")
+	tagGroupsAPI.HandleFunc("/counts/", service.handleGetTagGroupCounts).Methods("POST")
+	tagGroupsAPI.HandleFunc("/move/", service.handleMoveTagGroupTags).Methods("POST")
+	tagGroupsAPI.HandleFunc("/export/", service.handleExportTagGroups).Methods("GET")
+	tagGroupsAPI.HandleFunc("/import/", service.handleImportTagGroups).Methods("POST")
+	tagGroupsAPI.HandleFunc("/ungrouped/", service.handleListUngroupedTags).Methods("GET")
+	tagGroupsAPI.HandleFunc("/analytics/", service.handleGetTagAnalytics).Methods("GET")
+	tagGroupsAPI.HandleFunc("/stale-memberships/", service.handleStaleTagGroupMemberships).Methods("GET", "DELETE")
+	tagGroupsAPI.HandleFunc("/{id:[0-9]+}/tags/", service.handleChangeTagGroupTags).Methods("POST", "DELETE")
+	tagGroupsAPI.HandleFunc("/{id:[0-9]+}/tree/", service.handleGetTagGroupSubtree).Methods("GET")
+	tagGroupsAPI.HandleFunc("/{id:[0-9]+}/filter-rules/", service.handleGetTagGroupFilterRules).Methods("GET")
+	tagGroupsAPI.HandleFunc("/{id:[0-9]+}/", service.handleGetTagGroup).Methods("GET")
+	tagGroupsAPI.HandleFunc("/{id:[0-9]+}/", service.handleUpdateTagGroup).Methods("PUT", "PATCH")
+	tagGroupsAPI.HandleFunc("/{id:[0-9]+}/", service.handleDeleteTagGroup).Methods("DELETE")
+
+	// API routes for tag descriptions
+	tagDescriptionsAPI := router.PathPrefix("/api/tag-descriptions").Subrouter()
+	tagDescriptionsAPI.HandleFunc("/", service.handleListTagDescriptions).Methods("GET")
+	tagDescriptionsAPI.HandleFunc("/{tagId:[0-9]+}/history/", service.handleGetTagDescriptionHistory).Methods("GET")
+	tagDescriptionsAPI.HandleFunc("/{tagId:[0-9]+}/", service.handleGetTagDescription).Methods("GET")
+	tagDescriptionsAPI.HandleFunc("/{tagId:[0-9]+}/", service.handleSetTagDescription).Methods("PUT")
+	tagDescriptionsAPI.HandleFunc("/{tagId:[0-9]+}/", service.handleDeleteTagDescription).Methods("DELETE")
+}
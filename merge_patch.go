@@ -0,0 +1,58 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// applyMergePatch applies a JSON Merge Patch (RFC 7386) to a decoded JSON document: object
+// members of the patch replace those of the target, null members remove them and nested
+// objects are merged recursively. Any other patch value replaces the target as a whole.
+func applyMergePatch(target interface{}, patch interface{}) interface{} {
+	patchObject, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	targetObject, ok := target.(map[string]interface{})
+	if !ok {
+		targetObject = make(map[string]interface{})
+	}
+	for key, value := range patchObject {
+		if value == nil {
+			delete(targetObject, key)
+			continue
+		}
+		targetObject[key] = applyMergePatch(targetObject[key], value)
+	}
+	return targetObject
+}
+
+// mergePatchJSON applies the merge patch document patch to the JSON encoding of current
+// and decodes the result into result. The patch must be a JSON object.
+func mergePatchJSON(current interface{}, patch []byte, result interface{}) error {
+	var patchDocument interface{}
+	if err := json.Unmarshal(patch, &patchDocument); err != nil {
+		return fmt.Errorf("invalid merge patch: %w", err)
+	}
+	if _, ok := patchDocument.(map[string]interface{}); !ok {
+		return fmt.Errorf("invalid merge patch: expected a JSON object")
+	}
+
+	data, err := json.Marshal(current)
+	if err != nil {
+		return fmt.Errorf("failed to encode document: %w", err)
+	}
+	var document interface{}
+	if err := json.Unmarshal(data, &document); err != nil {
+		return fmt.Errorf("failed to decode document: %w", err)
+	}
+
+	merged, err := json.Marshal(applyMergePatch(document, patchDocument))
+	if err != nil {
+		return fmt.Errorf("failed to encode patched document: %w", err)
+	}
+	if err := json.Unmarshal(merged, result); err != nil {
+		return fmt.Errorf("invalid merge patch: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,325 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// MergeVariant is a value that looks like a spelling variant of the canonical value
+type MergeVariant struct {
+	ID       string `json:"id"`
+	Label    string `json:"label"`
+	Count    int    `json:"count"`
+	Reason   string `json:"reason"`             // "case", "whitespace" or "similar"
+	Distance int    `json:"distance,omitempty"` // Edit distance for "similar" variants
+}
+
+// MergeSuggestion groups near-duplicate values of a field around the most used one.
+// A canonical value with a zero count is a rename target that does not exist yet.
+type MergeSuggestion struct {
+	Canonical         CustomFieldValueOption `json:"canonical"`
+	Variants          []MergeVariant         `json:"variants"`
+	AffectedDocuments int                    `json:"affected_documents"` // Documents carrying one of the variants
+}
+
+// MergeSuggestionsResponse lists merge suggestions for a field
+type MergeSuggestionsResponse struct {
+	FieldID     int               `json:"field_id"`
+	FieldName   string            `json:"field_name"`
+	Suggestions []MergeSuggestion `json:"suggestions"`
+}
+
+// mergeSuggestionFieldTypes are the data types holding free text that can be merged
+var mergeSuggestionFieldTypes = map[string]bool{"string": true, "url": true, "longtext": true}
+
+// GetMergeSuggestions detects near-duplicate values of a free-text field: values that only
+// differ by case or whitespace, and (if maxDistance > 0) values of at least minLength
+// characters within maxDistance edits of each other
+func (s *Service) GetMergeSuggestions(ctx context.Context, fieldID int, maxDistance int, minLength int) (*MergeSuggestionsResponse, error) {
+	usePostgres := s.config.DBEngine == "postgresql" || s.config.DBEngine == "postgres"
+
+	var fieldName, dataType string
+	metaQuery := "SELECT name, data_type FROM documents_customfield WHERE id = ?"
+	if usePostgres {
+		metaQuery = "SELECT name, data_type FROM documents_customfield WHERE id = $1"
+	}
+	if err := s.db.QueryRowContext(ctx, metaQuery, fieldID).Scan(&fieldName, &dataType); err != nil {
+		return nil, fmt.Errorf("custom field with id %d not found: %w", fieldID, err)
+	}
+	if !mergeSuggestionFieldTypes[dataType] {
+		return nil, fmt.Errorf("merge suggestions are only available for text fields, field %d is %s", fieldID, dataType)
+	}
+
+	// Value lists trim their entries, so raw values are scanned here to keep
+	// whitespace variants apart
+	valueColumn := getValueColumnName(dataType)
+	fieldPlaceholder := "?"
+	if usePostgres {
+		fieldPlaceholder = "$1"
+	}
+	query := fmt.Sprintf(`
+		SELECT cfi.%s, cfi.document_id
+		FROM documents_customfieldinstance cfi
+		INNER JOIN documents_document d ON cfi.document_id = d.id
+		WHERE cfi.field_id = %s
+			AND cfi.deleted_at IS NULL
+			AND d.deleted_at IS NULL
+			AND cfi.%s IS NOT NULL
+			AND cfi.%s != ''
+	`, valueColumn, fieldPlaceholder, valueColumn, valueColumn)
+
+	rows, err := s.db.QueryContext(ctx, query, fieldID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query field values: %w", err)
+	}
+	defer rows.Close()
+
+	valueDocuments := make(map[string]map[int]bool)
+	for rows.Next() {
+		var value string
+		var documentID int
+		if err := rows.Scan(&value, &documentID); err != nil {
+			continue
+		}
+		for i, part := range parseValueList(value) {
+			// A space after a list separator is conventional, not a variant
+			if i > 0 {
+				part = strings.TrimLeft(part, " ")
+			}
+			if strings.TrimSpace(part) == "" {
+				continue
+			}
+			if valueDocuments[part] == nil {
+				valueDocuments[part] = make(map[int]bool)
+			}
+			valueDocuments[part][documentID] = true
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read field values: %w", err)
+	}
+
+	// Group values that are identical after case folding and whitespace normalization
+	groups := make(map[string][]CustomFieldValueOption)
+	keys := []string{}
+	for value, documents := range valueDocuments {
+		option := CustomFieldValueOption{ID: generateID(value), Label: value, Count: len(documents)}
+		key := normalizeMergeKey(value)
+		if _, exists := groups[key]; !exists {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], option)
+	}
+
+	sort.Strings(keys)
+
+	// Cluster normalized keys within the edit distance (union-find over key indexes)
+	parent := make([]int, len(keys))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+
+	if maxDistance > 0 {
+		// Only keys whose lengths differ by at most maxDistance can be close enough
+		byLength := make([]int, len(keys))
+		for i := range byLength {
+			byLength[i] = i
+		}
+		sort.Slice(byLength, func(a, b int) bool {
+			return len([]rune(keys[byLength[a]])) < len([]rune(keys[byLength[b]]))
+		})
+		for a := 0; a < len(byLength); a++ {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			keyA := []rune(keys[byLength[a]])
+			if len(keyA) < minLength {
+				continue
+			}
+			for b := a + 1; b < len(byLength); b++ {
+				keyB := []rune(keys[byLength[b]])
+				if len(keyB)-len(keyA) > maxDistance {
+					break
+				}
+				if boundedEditDistance(keyA, keyB, maxDistance) <= maxDistance {
+					parent[find(byLength[a])] = find(byLength[b])
+				}
+			}
+		}
+	}
+
+	clusters := make(map[int][]int)
+	for i := range keys {
+		root := find(i)
+		clusters[root] = append(clusters[root], i)
+	}
+
+	suggestions := []MergeSuggestion{}
+	for _, members := range clusters {
+		var options []CustomFieldValueOption
+		for _, keyIndex := range members {
+			options = append(options, groups[keys[keyIndex]]...)
+		}
+
+		// Without a cleanly trimmed value, suggest renaming to the trimmed form
+		hasTrimmed := false
+		for _, option := range options {
+			if option.Label == strings.TrimSpace(option.Label) {
+				hasTrimmed = true
+				break
+			}
+		}
+		if !hasTrimmed {
+			best := options[0]
+			for _, option := range options[1:] {
+				if option.Count > best.Count {
+					best = option
+				}
+			}
+			trimmed := strings.TrimSpace(best.Label)
+			options = append(options, CustomFieldValueOption{ID: generateID(trimmed), Label: trimmed})
+		}
+
+		if len(options) < 2 {
+			continue
+		}
+
+		// The most used cleanly trimmed value is the merge target
+		sort.Slice(options, func(a, b int) bool {
+			trimmedA := options[a].Label == strings.TrimSpace(options[a].Label)
+			trimmedB := options[b].Label == strings.TrimSpace(options[b].Label)
+			if trimmedA != trimmedB {
+				return trimmedA
+			}
+			if options[a].Count != options[b].Count {
+				return options[a].Count > options[b].Count
+			}
+			return options[a].Label < options[b].Label
+		})
+		canonical := options[0]
+		suggestion := MergeSuggestion{Canonical: canonical, Variants: []MergeVariant{}}
+		for _, option := range options[1:] {
+			variant := MergeVariant{ID: option.ID, Label: option.Label, Count: option.Count}
+			switch {
+			case strings.EqualFold(option.Label, canonical.Label):
+				variant.Reason = "case"
+			case normalizeMergeKey(option.Label) == normalizeMergeKey(canonical.Label):
+				variant.Reason = "whitespace"
+			default:
+				variant.Reason = "similar"
+				variant.Distance = boundedEditDistance([]rune(normalizeMergeKey(option.Label)), []rune(normalizeMergeKey(canonical.Label)), len(option.Label)+len(canonical.Label))
+			}
+			suggestion.Variants = append(suggestion.Variants, variant)
+			suggestion.AffectedDocuments += option.Count
+		}
+		suggestions = append(suggestions, suggestion)
+	}
+
+	sort.Slice(suggestions, func(a, b int) bool {
+		if suggestions[a].AffectedDocuments != suggestions[b].AffectedDocuments {
+			return suggestions[a].AffectedDocuments > suggestions[b].AffectedDocuments
+		}
+		return suggestions[a].Canonical.Label < suggestions[b].Canonical.Label
+	})
+
+	return &MergeSuggestionsResponse{
+		FieldID:     fieldID,
+		FieldName:   fieldName,
+		Suggestions: suggestions,
+	}, nil
+}
+
+// normalizeMergeKey folds case and collapses whitespace
+func normalizeMergeKey(value string) string {
+	return strings.ToLower(strings.Join(strings.Fields(value), " "))
+}
+
+// boundedEditDistance returns the Levenshtein distance between a and b, or limit+1 as soon
+// as the distance is known to exceed limit
+func boundedEditDistance(a, b []rune, limit int) int {
+	if diff := len(a) - len(b); diff > limit || diff < -limit {
+		return limit + 1
+	}
+
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		rowMin := current[0]
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+			rowMin = min(rowMin, current[j])
+		}
+		if rowMin > limit {
+			return limit + 1
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
+
+// HTTP Handler for value merge suggestions
+func (s *Service) handleGetMergeSuggestions(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	vars := mux.Vars(r)
+	fieldID, err := strconv.Atoi(vars["fieldId"])
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid field ID")
+		return
+	}
+
+	maxDistance := 2
+	if value := r.URL.Query().Get("max_distance"); value != "" {
+		maxDistance, err = strconv.Atoi(value)
+		if err != nil || maxDistance < 0 {
+			respondError(w, http.StatusBadRequest, "Invalid max_distance")
+			return
+		}
+	}
+	minLength := 4
+	if value := r.URL.Query().Get("min_length"); value != "" {
+		minLength, err = strconv.Atoi(value)
+		if err != nil || minLength < 0 {
+			respondError(w, http.StatusBadRequest, "Invalid min_length")
+			return
+		}
+	}
+
+	response, err := s.GetMergeSuggestions(ctx, fieldID, maxDistance, minLength)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			respondError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		if strings.Contains(err.Error(), "only available for text fields") {
+			respondError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		respondError(w, queryErrorStatus(err), err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, response)
+}
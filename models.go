@@ -8,9 +8,10 @@ type ErrorResponse struct {
 
 // CustomFieldValueOption represents a single custom field value option
 type CustomFieldValueOption struct {
-	ID    string `json:"id"`
-	Label string `json:"label"`
-	Count int    `json:"count"`
+	ID          string `json:"id"`
+	Label       string `json:"label"`
+	Count       int    `json:"count"`
+	Approximate bool   `json:"approximate,omitempty"` // Count estimated with VALUE_COUNT_MODE=approximate
 }
 
 // CustomFieldValuesResponse represents the response for custom field values
@@ -19,33 +20,88 @@ type CustomFieldValuesResponse struct {
 	FieldName      string                   `json:"field_name"`
 	Values         []CustomFieldValueOption `json:"values"`
 	TotalDocuments int                      `json:"total_documents"`
+	DataAsOf       *string                  `json:"data_as_of,omitempty"`    // Set when served from a precomputed summary
+	StaleSeconds   *int                     `json:"stale_seconds,omitempty"` // Age of the precomputed summary
 }
 
 // CustomView represents a custom document list view configuration
 type CustomView struct {
-	ID                 *int                     `json:"id,omitempty"`
-	Name               string                   `json:"name"`
-	Description        *string                  `json:"description,omitempty"`
-	ColumnOrder        []interface{}            `json:"column_order"` // []string or []number
-	ColumnSizing       map[string]int           `json:"column_sizing"`
-	ColumnVisibility   map[string]bool          `json:"column_visibility"`
-	ColumnDisplayTypes map[string]string        `json:"column_display_types"`
-	FilterRules        []map[string]interface{} `json:"filter_rules,omitempty"`
-	FilterVisibility   map[string]bool          `json:"filter_visibility,omitempty"`
-	FilterTypes        map[string]string        `json:"filter_types,omitempty"`
-	EditModeSettings   map[string]interface{}   `json:"edit_mode_settings,omitempty"` // map[fieldId]{enabled: bool, entry_type: string}
-	ColumnStyles       map[string]string        `json:"column_styles,omitempty"`      // map[fieldId]cssString
-	SubrowEnabled      *bool                    `json:"subrow_enabled,omitempty"`
-	SubrowContent      *string                  `json:"subrow_content,omitempty"` // 'summary', 'tags', or 'none'
-	ColumnSpanning     map[string]bool          `json:"column_spanning,omitempty"`
-	SortField          *string                  `json:"sort_field,omitempty"`
-	SortReverse        *bool                    `json:"sort_reverse,omitempty"`
-	IsGlobal           *bool                    `json:"is_global,omitempty"`
-	Created            *string                  `json:"created,omitempty"`
-	Modified           *string                  `json:"modified,omitempty"`
-	DeletedAt          *string                  `json:"deleted_at,omitempty"`
-	Username           *string                  `json:"username,omitempty"`
-	OwnerID            *int                     `json:"owner_id,omitempty"` // Internal: user ID
+	ID                 *int                       `json:"id,omitempty"`
+	Name               string                     `json:"name"`
+	Description        *string                    `json:"description,omitempty"`
+	ColumnOrder        []interface{}              `json:"column_order"` // []string or []number
+	ColumnSizing       map[string]int             `json:"column_sizing"`
+	ColumnVisibility   map[string]bool            `json:"column_visibility"`
+	ColumnDisplayTypes map[string]string          `json:"column_display_types"`
+	FilterRules        []map[string]interface{}   `json:"filter_rules,omitempty"`
+	FilterVisibility   map[string]bool            `json:"filter_visibility,omitempty"`
+	FilterTypes        map[string]string          `json:"filter_types,omitempty"`
+	EditModeSettings   map[string]interface{}     `json:"edit_mode_settings,omitempty"` // map[fieldId]{enabled: bool, entry_type: string}
+	ColumnStyles       map[string]string          `json:"column_styles,omitempty"`      // map[fieldId]cssString
+	SubrowEnabled      *bool                      `json:"subrow_enabled,omitempty"`
+	SubrowContent      *string                    `json:"subrow_content,omitempty"` // 'summary', 'tags', or 'none'
+	ColumnSpanning     map[string]bool            `json:"column_spanning,omitempty"`
+	SortField          *string                    `json:"sort_field,omitempty"`
+	SortReverse        *bool                      `json:"sort_reverse,omitempty"`
+	IsGlobal           *bool                      `json:"is_global,omitempty"`
+	Created            *string                    `json:"created,omitempty"`
+	Modified           *string                    `json:"modified,omitempty"`
+	DeletedAt          *string                    `json:"deleted_at,omitempty"`
+	Username           *string                    `json:"username,omitempty"`
+	OwnerID            *int                       `json:"owner_id,omitempty"`            // Internal: user ID
+	DocumentCount      *int                       `json:"document_count,omitempty"`      // Matching documents, with with_counts=true
+	CountedAt          *string                    `json:"counted_at,omitempty"`          // When DocumentCount was computed
+	ColumnPresetID     *int                       `json:"column_preset_id,omitempty"`    // Request only: column preset to expand
+	Translations       map[string]ViewTranslation `json:"translations,omitempty"`        // Display names and descriptions by locale
+	DisplayName        *string                    `json:"display_name,omitempty"`        // Name in the requested locale, when translated
+	DisplayDescription *string                    `json:"display_description,omitempty"` // Description in the requested locale, when translated
+	Locale             *string                    `json:"locale,omitempty"`              // Locale of DisplayName and DisplayDescription
+	DanglingFieldIDs   []int                      `json:"dangling_field_ids,omitempty"`  // Referenced custom fields that no longer exist
+	BoardSettings      *ViewBoardSettings         `json:"board_settings,omitempty"`      // Kanban layout of the view
+}
+
+// ViewBoardSettings is the kanban layout of a custom view: its documents shown as cards in
+// one lane per value of a field
+type ViewBoardSettings struct {
+	GroupBy       string        `json:"group_by"`                 // Column the lanes are made of, e.g. "correspondent" or "custom_field_12"
+	SwimlaneOrder []interface{} `json:"swimlane_order,omitempty"` // Lane values (IDs or select options) in display order
+	CardFields    []string      `json:"card_fields,omitempty"`    // Columns shown on each card
+}
+
+// ViewTranslation is the display name and description of a custom view in one locale
+type ViewTranslation struct {
+	Name        string  `json:"name,omitempty"`
+	Description *string `json:"description,omitempty"`
+}
+
+// DanglingFieldRef is a reference of a view to a custom field that no longer exists
+type DanglingFieldRef struct {
+	Field   string `json:"field"` // Where the view refers to the field, e.g. "column_sizing.12"
+	FieldID int    `json:"field_id"`
+}
+
+// CustomViewCheck is the consistency report of a custom view
+type CustomViewCheck struct {
+	ViewID       int                `json:"view_id"`
+	Valid        bool               `json:"valid"`
+	Problems     []ValidationError  `json:"problems"`
+	DanglingRefs []DanglingFieldRef `json:"dangling_refs"`
+}
+
+// ViewUsage is the number of custom views owned by one user
+type ViewUsage struct {
+	OwnerID      *int    `json:"owner_id"` // Null for views without an owner
+	Username     *string `json:"username,omitempty"`
+	Views        int     `json:"views"`        // Views outside the trash, counted against the quota
+	GlobalViews  int     `json:"global_views"` // Of Views, those shared globally
+	TrashedViews int     `json:"trashed_views"`
+}
+
+// ViewUsageResponse lists the view usage of all users
+type ViewUsageResponse struct {
+	Limit   *int        `json:"limit"` // MAX_VIEWS_PER_USER, null when unlimited
+	Count   int         `json:"count"`
+	Results []ViewUsage `json:"results"`
 }
 
 // CustomViewListResponse represents a paginated list of custom views
@@ -58,12 +114,423 @@ type CustomViewListResponse struct {
 
 // TagGroup represents a group of tags
 type TagGroup struct {
-	ID          *int    `json:"id,omitempty"`
-	Name        string  `json:"name"`
-	Description *string `json:"description,omitempty"`
-	TagIDs      []int   `json:"tag_ids,omitempty"` // Tags in this group
-	Created     *string `json:"created,omitempty"`
-	Modified    *string `json:"modified,omitempty"`
+	ID            *int          `json:"id,omitempty"`
+	Name          string        `json:"name"`
+	Description   *string       `json:"description,omitempty"`
+	TagIDs        []int         `json:"tag_ids,omitempty"`         // Tags in this group
+	ParentGroupID *int          `json:"parent_group_id,omitempty"` // Group this one is nested in; 0 in updates moves it to the top level
+	Tags          []TagGroupTag `json:"tags,omitempty"`            // Member tags from Paperless, with expand=tags
+	IsGlobal      *bool         `json:"is_global,omitempty"`       // Shared with all users
+	OwnerID       *int          `json:"owner_id,omitempty"`        // Null for groups without an owner
+	Username      *string       `json:"username,omitempty"`
+	Created       *string       `json:"created,omitempty"`
+	Modified      *string       `json:"modified,omitempty"`
+}
+
+// TagGroupTag is a member tag of a tag group as Paperless has it
+type TagGroupTag struct {
+	ID            int     `json:"id"`
+	Name          string  `json:"name"`
+	Color         *string `json:"color,omitempty"`
+	DocumentCount int     `json:"document_count"`
+}
+
+// TagGroupNode is a tag group in the group hierarchy with its subgroups. DocumentCount
+// counts the documents having any tag of the group or of its descendants.
+type TagGroupNode struct {
+	TagGroup
+	DocumentCount int            `json:"document_count"`
+	Children      []TagGroupNode `json:"children"`
+}
+
+// CustomViewRevision is the configuration a custom view had before one of its updates
+type CustomViewRevision struct {
+	ID       int        `json:"id"`
+	ViewID   int        `json:"view_id"`
+	Revision int        `json:"revision"`
+	View     CustomView `json:"view"`
+	UserID   *int       `json:"user_id,omitempty"` // User whose update replaced this configuration
+	Created  *string    `json:"created,omitempty"`
+}
+
+// CustomViewRevisionListResponse represents the list of revisions of a custom view
+type CustomViewRevisionListResponse struct {
+	Count   int                  `json:"count"`
+	Results []CustomViewRevision `json:"results"`
+}
+
+// ViewSnapshot is the set of documents matching a custom view at one point in time
+type ViewSnapshot struct {
+	ID            int     `json:"id"`
+	ViewID        int     `json:"view_id"`
+	DocumentCount int     `json:"document_count"`
+	DocumentIDs   []int   `json:"document_ids,omitempty"` // Left out of lists
+	Trigger       string  `json:"trigger"`                // "schedule" or "manual"
+	Created       *string `json:"created,omitempty"`
+}
+
+// ViewSnapshotListResponse represents the list of snapshots of a custom view
+type ViewSnapshotListResponse struct {
+	Count   int            `json:"count"`
+	Results []ViewSnapshot `json:"results"`
+}
+
+// ViewSnapshotDiff lists the documents added to and removed from a view between two snapshots
+type ViewSnapshotDiff struct {
+	From    ViewSnapshot `json:"from"`
+	To      ViewSnapshot `json:"to"`
+	Added   []int        `json:"added"`
+	Removed []int        `json:"removed"`
+}
+
+// ViewSnapshotSchedule makes snapshots of a custom view at a fixed interval: "daily",
+// "weekly", "monthly" or a duration such as "12h"
+type ViewSnapshotSchedule struct {
+	ViewID   int     `json:"view_id"`
+	Interval string  `json:"interval"`
+	NextRun  *string `json:"next_run,omitempty"`
+	LastRun  *string `json:"last_run,omitempty"`
+}
+
+// ViewNotificationRule notifies its owner through a channel when a condition on the
+// documents of a custom view becomes true
+type ViewNotificationRule struct {
+	ID              *int    `json:"id,omitempty"`
+	ViewID          int     `json:"view_id"`
+	Condition       string  `json:"condition"`           // "count_above", "count_below" or "new_documents"
+	Threshold       *int    `json:"threshold,omitempty"` // For the count conditions
+	Channel         string  `json:"channel"`             // "webhook" or "email"
+	Target          string  `json:"target"`              // Webhook URL or email address
+	Secret          *string `json:"secret,omitempty"`    // Signs webhook payloads; never returned
+	HasSecret       bool    `json:"has_secret"`
+	IsActive        *bool   `json:"is_active,omitempty"`
+	LastCheckedAt   *string `json:"last_checked_at,omitempty"`
+	LastTriggeredAt *string `json:"last_triggered_at,omitempty"`
+	Created         *string `json:"created,omitempty"`
+	Modified        *string `json:"modified,omitempty"`
+	Username        *string `json:"username,omitempty"`
+	OwnerID         *int    `json:"owner_id,omitempty"`
+}
+
+// ViewNotificationRuleListResponse represents a list of notification rules
+type ViewNotificationRuleListResponse struct {
+	Count   int                    `json:"count"`
+	Results []ViewNotificationRule `json:"results"`
+}
+
+// ViewPreferences are a user's personal settings for the custom views they can see
+type ViewPreferences struct {
+	DefaultViewID *int  `json:"default_view_id"`
+	Favorites     []int `json:"favorites"` // Favorite view IDs, in display order
+	Order         []int `json:"order"`     // View IDs in the user's display order
+}
+
+// Webhook is an outgoing notification of custom view changes. The secret signs the payloads.
+// Secret is only read from requests: webhooks read from the database hold it in the
+// unexported signingSecret, so it cannot be encoded into a response.
+type Webhook struct {
+	ID        *int     `json:"id,omitempty"`
+	URL       string   `json:"url"`
+	Secret    *string  `json:"secret,omitempty"` // Set by requests only
+	HasSecret bool     `json:"has_secret"`
+	Events    []string `json:"events"` // Empty means all events
+	IsActive  *bool    `json:"is_active,omitempty"`
+	Created   *string  `json:"created,omitempty"`
+	Modified  *string  `json:"modified,omitempty"`
+	Username  *string  `json:"username,omitempty"`
+	OwnerID   *int     `json:"owner_id,omitempty"`
+
+	signingSecret string // Secret of a stored webhook
+}
+
+// WebhookListResponse represents a list of webhooks
+type WebhookListResponse struct {
+	Count   int       `json:"count"`
+	Results []Webhook `json:"results"`
+}
+
+// FilterPreset is a named set of filter rules saved independently of a custom view
+type FilterPreset struct {
+	ID          *int                     `json:"id,omitempty"`
+	Name        string                   `json:"name"`
+	Description *string                  `json:"description,omitempty"`
+	FilterRules []map[string]interface{} `json:"filter_rules"`
+	IsGlobal    *bool                    `json:"is_global,omitempty"`
+	Created     *string                  `json:"created,omitempty"`
+	Modified    *string                  `json:"modified,omitempty"`
+	Username    *string                  `json:"username,omitempty"`
+	OwnerID     *int                     `json:"owner_id,omitempty"`
+}
+
+// FilterPresetListResponse represents a list of filter presets
+type FilterPresetListResponse struct {
+	Count   int            `json:"count"`
+	Results []FilterPreset `json:"results"`
+}
+
+// Dashboard is a user's composition of widgets evaluated together
+type Dashboard struct {
+	ID          *int              `json:"id,omitempty"`
+	Name        string            `json:"name"`
+	Description *string           `json:"description,omitempty"`
+	Widgets     []DashboardWidget `json:"widgets"`
+	OwnerID     *int              `json:"owner_id,omitempty"`
+	Created     *string           `json:"created,omitempty"`
+	Modified    *string           `json:"modified,omitempty"`
+}
+
+// DashboardWidget is a widget of a dashboard. Type is "value_counts" (document counts per
+// value of the custom field FieldID), "view_count" (documents of the custom view ViewID) or
+// "recent_documents" (the latest added documents). FilterRules narrow the documents of
+// value_counts and recent_documents widgets; Limit caps their entries.
+type DashboardWidget struct {
+	ID          string                   `json:"id"`
+	Type        string                   `json:"type"`
+	Title       *string                  `json:"title,omitempty"`
+	FieldID     int                      `json:"field_id,omitempty"`
+	ViewID      int                      `json:"view_id,omitempty"`
+	FilterRules []map[string]interface{} `json:"filter_rules,omitempty"`
+	Limit       int                      `json:"limit,omitempty"`
+}
+
+// DashboardListResponse represents a list of dashboards
+type DashboardListResponse struct {
+	Count   int         `json:"count"`
+	Results []Dashboard `json:"results"`
+}
+
+// DashboardWidgetData is the evaluated data of a widget; Error is set instead when the widget
+// could not be evaluated, e.g. because its view was deleted
+type DashboardWidgetData struct {
+	ID    string      `json:"id"`
+	Type  string      `json:"type"`
+	Title *string     `json:"title,omitempty"`
+	Data  interface{} `json:"data"`
+	Error *string     `json:"error,omitempty"`
+}
+
+// DashboardDataResponse holds the data of all widgets of a dashboard, in widget order
+type DashboardDataResponse struct {
+	DashboardID int                   `json:"dashboard_id"`
+	Widgets     []DashboardWidgetData `json:"widgets"`
+}
+
+// UserPreference is a namespace of a user's UI settings, e.g. "theme" or "sidebar". Version
+// counts its changes and is the ETag of conditional updates.
+type UserPreference struct {
+	Namespace string                 `json:"namespace"`
+	Value     map[string]interface{} `json:"value"`
+	Version   int                    `json:"version"`
+	Created   *string                `json:"created,omitempty"`
+	Modified  *string                `json:"modified,omitempty"`
+}
+
+// UserPreferencesResponse lists the preference namespaces of a user
+type UserPreferencesResponse struct {
+	Count   int              `json:"count"`
+	Results []UserPreference `json:"results"`
+}
+
+// Reminder is a follow-up date a user set on a document, optionally announced by webhook or
+// email when it comes due
+type Reminder struct {
+	ID            *int    `json:"id,omitempty"`
+	DocumentID    int     `json:"document_id"`
+	DocumentTitle string  `json:"document_title,omitempty"`
+	DueAt         string  `json:"due_at"` // RFC3339, or a date for midnight UTC
+	Note          *string `json:"note,omitempty"`
+	Channel       *string `json:"channel,omitempty"` // "webhook", "email" or none
+	Target        *string `json:"target,omitempty"`  // Webhook URL or email address
+	Secret        *string `json:"secret,omitempty"`  // Signs webhook payloads; never returned
+	HasSecret     bool    `json:"has_secret"`
+	Done          *bool   `json:"done,omitempty"`
+	Overdue       bool    `json:"overdue"`
+	CompletedAt   *string `json:"completed_at,omitempty"`
+	NotifiedAt    *string `json:"notified_at,omitempty"`
+	OwnerID       *int    `json:"owner_id,omitempty"`
+	Created       *string `json:"created,omitempty"`
+	Modified      *string `json:"modified,omitempty"`
+}
+
+// ReminderListResponse represents a list of reminders
+type ReminderListResponse struct {
+	Count   int        `json:"count"`
+	Results []Reminder `json:"results"`
+}
+
+// SavedSearch is a user's filter whose new matches can be fetched or pushed to a webhook.
+// Documents are new when their ID is above the watermark of the last check.
+type SavedSearch struct {
+	ID              *int                     `json:"id,omitempty"`
+	Name            string                   `json:"name"`
+	FilterRules     []map[string]interface{} `json:"filter_rules"`
+	WebhookURL      *string                  `json:"webhook_url,omitempty"` // Receives new matches; none disables the push
+	Secret          *string                  `json:"secret,omitempty"`      // Signs webhook payloads; never returned
+	HasSecret       bool                     `json:"has_secret"`
+	LastSeenID      int                      `json:"last_seen_document_id"`
+	LastCheckedAt   *string                  `json:"last_checked_at,omitempty"`
+	LastNotifiedID  int                      `json:"last_notified_document_id"`
+	LastTriggeredAt *string                  `json:"last_triggered_at,omitempty"`
+	OwnerID         *int                     `json:"owner_id,omitempty"`
+	Created         *string                  `json:"created,omitempty"`
+	Modified        *string                  `json:"modified,omitempty"`
+}
+
+// SavedSearchListResponse represents a list of saved searches
+type SavedSearchListResponse struct {
+	Count   int           `json:"count"`
+	Results []SavedSearch `json:"results"`
+}
+
+// DocumentNote is a comment on a document kept by this service, apart from the notes of
+// Paperless. Notes with a ParentID are replies; listings nest them under Replies.
+type DocumentNote struct {
+	ID         *int           `json:"id,omitempty"`
+	DocumentID int            `json:"document_id"`
+	ParentID   *int           `json:"parent_id,omitempty"`
+	Body       string         `json:"body"` // Markdown
+	AuthorID   *int           `json:"author_id,omitempty"`
+	Username   *string        `json:"username,omitempty"`
+	Created    *string        `json:"created,omitempty"`
+	Modified   *string        `json:"modified,omitempty"`
+	Replies    []DocumentNote `json:"replies,omitempty"`
+}
+
+// DocumentNoteListResponse lists the note threads of a document; Count includes replies
+type DocumentNoteListResponse struct {
+	Count   int            `json:"count"`
+	Results []DocumentNote `json:"results"`
+}
+
+// Workflow is a named, ordered set of statuses documents move through, e.g. "To review",
+// "Approved", "Filed". Workflows are shared by all users; their owner maintains them.
+type Workflow struct {
+	ID          *int             `json:"id,omitempty"`
+	Name        string           `json:"name"`
+	Description *string          `json:"description,omitempty"`
+	Statuses    []WorkflowStatus `json:"statuses"`
+	OwnerID     *int             `json:"owner_id,omitempty"`
+	Created     *string          `json:"created,omitempty"`
+	Modified    *string          `json:"modified,omitempty"`
+}
+
+// WorkflowStatus is one status of a workflow; Key identifies it in assignments
+type WorkflowStatus struct {
+	Key   string  `json:"key"`
+	Label string  `json:"label"`
+	Color *string `json:"color,omitempty"`
+}
+
+// WorkflowListResponse represents a list of workflows
+type WorkflowListResponse struct {
+	Count   int        `json:"count"`
+	Results []Workflow `json:"results"`
+}
+
+// DocumentWorkflowStatus is the status of a document in a workflow
+type DocumentWorkflowStatus struct {
+	WorkflowID   int     `json:"workflow_id"`
+	WorkflowName string  `json:"workflow_name"`
+	Status       string  `json:"status"`
+	StatusLabel  string  `json:"status_label"`
+	UpdatedBy    *int    `json:"updated_by,omitempty"`
+	Username     *string `json:"username,omitempty"`
+	Modified     *string `json:"modified,omitempty"`
+}
+
+// ScheduledReport periodically exports the documents of a custom view, as a CSV of columns
+// or as a PDF summary of facet counts, to a destination
+type ScheduledReport struct {
+	ID          *int     `json:"id,omitempty"`
+	ViewID      int      `json:"view_id"`
+	Name        string   `json:"name"`
+	Format      string   `json:"format"`            // "csv" or "pdf"
+	Columns     []string `json:"columns,omitempty"` // CSV columns; empty uses the view's visible columns
+	Facets      []string `json:"facets,omitempty"`  // PDF facets; empty uses correspondent, document_type and tag
+	Destination string   `json:"destination"`       // "directory", "s3" or "email"
+	Target      *string  `json:"target,omitempty"`  // Email address of the email destination
+	Interval    string   `json:"interval"`          // "daily", "weekly", "monthly" or a duration such as "12h"
+	NextRun     *string  `json:"next_run,omitempty"`
+	LastRun     *string  `json:"last_run,omitempty"`
+	LastStatus  *string  `json:"last_status,omitempty"` // "ok" or "failed"
+	LastError   *string  `json:"last_error,omitempty"`
+	LastOutput  *string  `json:"last_output,omitempty"` // File, object URL or address the last report went to
+	OwnerID     *int     `json:"owner_id,omitempty"`
+	Created     *string  `json:"created,omitempty"`
+	Modified    *string  `json:"modified,omitempty"`
+}
+
+// ScheduledReportListResponse represents the list of a user's scheduled reports
+type ScheduledReportListResponse struct {
+	Count   int               `json:"count"`
+	Results []ScheduledReport `json:"results"`
+}
+
+// ShareLink is a public, read-only link to the documents matching a filter, listed as its
+// owner sees them. The token is the secret part of the link.
+type ShareLink struct {
+	ID           *int                     `json:"id,omitempty"`
+	Name         string                   `json:"name"`
+	Token        string                   `json:"token"`
+	FilterRules  []map[string]interface{} `json:"filter_rules"`
+	Password     *string                  `json:"password,omitempty"` // Required to open the link; never returned
+	HasPassword  bool                     `json:"has_password"`
+	ExpiresAt    *string                  `json:"expires_at,omitempty"`
+	AccessCount  int                      `json:"access_count"`
+	LastAccessed *string                  `json:"last_accessed,omitempty"`
+	OwnerID      *int                     `json:"owner_id,omitempty"`
+	Created      *string                  `json:"created,omitempty"`
+	Modified     *string                  `json:"modified,omitempty"`
+}
+
+// ShareLinkListResponse represents the list of a user's share links
+type ShareLinkListResponse struct {
+	Count   int         `json:"count"`
+	Results []ShareLink `json:"results"`
+}
+
+// CorrespondentProfile holds contact details of a Paperless correspondent, which Paperless
+// itself has no place for. Extra holds any further details as a JSON object.
+type CorrespondentProfile struct {
+	CorrespondentID   int                    `json:"correspondent_id"`
+	CorrespondentName string                 `json:"correspondent_name,omitempty"` // Read-only
+	Address           *string                `json:"address,omitempty"`
+	Email             *string                `json:"email,omitempty"`
+	Website           *string                `json:"website,omitempty"`
+	VATNumber         *string                `json:"vat_number,omitempty"`
+	LogoURL           *string                `json:"logo_url,omitempty"`
+	Extra             map[string]interface{} `json:"extra,omitempty"`
+	Created           *string                `json:"created,omitempty"`
+	Modified          *string                `json:"modified,omitempty"`
+	ModifiedBy        *int                   `json:"modified_by,omitempty"` // User who last changed the profile
+}
+
+// CorrespondentProfileListResponse represents a list of correspondent profiles
+type CorrespondentProfileListResponse struct {
+	Count   int                    `json:"count"`
+	Results []CorrespondentProfile `json:"results"`
+}
+
+// ColumnPreset is a named column configuration saved independently of a custom view
+type ColumnPreset struct {
+	ID                 *int              `json:"id,omitempty"`
+	Name               string            `json:"name"`
+	Description        *string           `json:"description,omitempty"`
+	ColumnOrder        []interface{}     `json:"column_order"`
+	ColumnSizing       map[string]int    `json:"column_sizing"`
+	ColumnVisibility   map[string]bool   `json:"column_visibility"`
+	ColumnDisplayTypes map[string]string `json:"column_display_types"`
+	IsGlobal           *bool             `json:"is_global,omitempty"`
+	Created            *string           `json:"created,omitempty"`
+	Modified           *string           `json:"modified,omitempty"`
+	Username           *string           `json:"username,omitempty"`
+	OwnerID            *int              `json:"owner_id,omitempty"`
+}
+
+// ColumnPresetListResponse represents a list of column presets
+type ColumnPresetListResponse struct {
+	Count   int            `json:"count"`
+	Results []ColumnPreset `json:"results"`
 }
 
 // TagGroupListResponse represents a list of tag groups
@@ -72,11 +539,158 @@ type TagGroupListResponse struct {
 	Results []TagGroup `json:"results"`
 }
 
-// TagDescription represents a description for a tag
+// TagGroupTreeResponse represents the tag group hierarchy; Count is the number of top level groups
+type TagGroupTreeResponse struct {
+	Count   int            `json:"count"`
+	Results []TagGroupNode `json:"results"`
+}
+
+// EntityDescription is a description of a Paperless tag, correspondent, document type or
+// storage path
+type EntityDescription struct {
+	ID          *int    `json:"id,omitempty"`
+	EntityType  string  `json:"entity_type"`
+	EntityID    int     `json:"entity_id"`
+	Description *string `json:"description,omitempty"`
+	Created     *string `json:"created,omitempty"`
+	Modified    *string `json:"modified,omitempty"`
+	ModifiedBy  *int    `json:"modified_by,omitempty"` // User who last set the description
+}
+
+// EntityDescriptionRevision is an earlier version of a description, kept when it was
+// replaced or deleted
+type EntityDescriptionRevision struct {
+	ID          int     `json:"id"`
+	EntityType  string  `json:"entity_type"`
+	EntityID    int     `json:"entity_id"`
+	Revision    int     `json:"revision"`
+	Description *string `json:"description,omitempty"`
+	Modified    *string `json:"modified,omitempty"`    // When this version was written
+	ModifiedBy  *int    `json:"modified_by,omitempty"` // Who wrote this version
+	Created     *string `json:"created,omitempty"`     // When it was replaced
+}
+
+// EntityDescriptionHistoryResponse lists the earlier versions of a description
+type EntityDescriptionHistoryResponse struct {
+	Count   int                         `json:"count"`
+	Results []EntityDescriptionRevision `json:"results"`
+}
+
+// TagDescription represents a description for a tag, the tag view of an EntityDescription
 type TagDescription struct {
 	ID          *int    `json:"id,omitempty"`
 	TagID       int     `json:"tag_id"`
 	Description *string `json:"description,omitempty"`
 	Created     *string `json:"created,omitempty"`
 	Modified    *string `json:"modified,omitempty"`
+	ModifiedBy  *int    `json:"modified_by,omitempty"`
+}
+
+// TagAlias maps a Paperless tag, e.g. an old spelling, to the canonical tag it duplicates
+type TagAlias struct {
+	ID             *int    `json:"id,omitempty"`
+	AliasTagID     int     `json:"alias_tag_id"`
+	CanonicalTagID int     `json:"canonical_tag_id"`
+	AliasName      string  `json:"alias_name,omitempty"`     // Read-only, empty when the tag was deleted
+	CanonicalName  string  `json:"canonical_name,omitempty"` // Read-only, empty when the tag was deleted
+	Created        *string `json:"created,omitempty"`
+	Modified       *string `json:"modified,omitempty"`
+	CreatedBy      *int    `json:"created_by,omitempty"`
+}
+
+// TagAliasesResponse represents a list of tag aliases
+type TagAliasesResponse struct {
+	Count   int        `json:"count"`
+	Results []TagAlias `json:"results"`
+}
+
+// DocumentLink relates a source document to a target document
+type DocumentLink struct {
+	ID           *int    `json:"id,omitempty"`
+	SourceID     int     `json:"source_id"`
+	TargetID     int     `json:"target_id"`
+	LinkType     string  `json:"link_type"`
+	Note         *string `json:"note,omitempty"`
+	SourceTitle  string  `json:"source_title,omitempty"` // Read-only
+	TargetTitle  string  `json:"target_title,omitempty"` // Read-only
+	Directed     bool    `json:"directed"`               // Read-only, from the link type
+	Label        string  `json:"label"`                  // Read-only, the link type seen from the document asked about
+	InverseLabel string  `json:"inverse_label"`          // Read-only, the link type seen from the other document
+	Created      *string `json:"created,omitempty"`
+	Modified     *string `json:"modified,omitempty"`
+	CreatedBy    *int    `json:"created_by,omitempty"`
+}
+
+// LinkType defines the meaning of links of a type. Links of a directed type read differently
+// from the target document, e.g. "invoice for" and "paid by".
+type LinkType struct {
+	ID          *int    `json:"id,omitempty"`
+	Name        string  `json:"name"`
+	InverseName *string `json:"inverse_name,omitempty"` // Label seen from the target of directed links
+	Color       *string `json:"color,omitempty"`
+	Directed    bool    `json:"directed"`
+	Created     *string `json:"created,omitempty"`
+	Modified    *string `json:"modified,omitempty"`
+}
+
+// LinkTypeListResponse represents a list of link types
+type LinkTypeListResponse struct {
+	Count   int        `json:"count"`
+	Results []LinkType `json:"results"`
+}
+
+// DocumentLinkListResponse represents a list of document links
+type DocumentLinkListResponse struct {
+	Count   int            `json:"count"`
+	Results []DocumentLink `json:"results"`
+}
+
+// DocumentLinksResponse holds the links of one document: Outbound links start at it,
+// Inbound links point to it
+type DocumentLinksResponse struct {
+	DocumentID int            `json:"document_id"`
+	Outbound   []DocumentLink `json:"outbound"`
+	Inbound    []DocumentLink `json:"inbound"`
+}
+
+// BulkOperation is a metadata change applied to the documents matching FilterRules:
+// "add_tag" (TagID), "set_custom_field" (FieldID and Value) or "set_storage_path"
+// (StoragePathID)
+type BulkOperation struct {
+	FilterRules   []map[string]interface{} `json:"filter_rules"`
+	Operation     string                   `json:"operation"`
+	TagID         *int                     `json:"tag_id,omitempty"`
+	FieldID       *int                     `json:"field_id,omitempty"`
+	Value         interface{}              `json:"value,omitempty"`
+	StoragePathID *int                     `json:"storage_path_id,omitempty"`
+}
+
+// BulkPreview reports what a bulk operation would change: of the Matched documents,
+// Unchanged already have the tag, value or storage path
+type BulkPreview struct {
+	Operation string `json:"operation"`
+	Matched   int    `json:"matched"`
+	Affected  int    `json:"affected"`
+	Unchanged int    `json:"unchanged"`
+}
+
+// BulkJob tracks a bulk operation applied through the Paperless REST API
+type BulkJob struct {
+	ID        int           `json:"id"`
+	UserID    *int          `json:"user_id,omitempty"`
+	Operation BulkOperation `json:"operation"`
+	Status    string        `json:"status"` // "running", "completed" or "failed"
+	Total     int           `json:"total"`
+	Processed int           `json:"processed"` // Documents changed so far
+	Failed    int           `json:"failed"`    // Documents of batches Paperless rejected
+	Error     *string       `json:"error,omitempty"`
+	Created   *string       `json:"created,omitempty"`
+	Modified  *string       `json:"modified,omitempty"`
+	Finished  *string       `json:"finished,omitempty"`
+}
+
+// BulkJobListResponse represents the response for listing bulk jobs
+type BulkJobListResponse struct {
+	Count   int       `json:"count"`
+	Results []BulkJob `json:"results"`
 }
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+const (
+	// defaultPageSize applies when a page is requested without a page_size
+	defaultPageSize = 25
+	// maxPageSize caps page_size
+	maxPageSize = 100
+)
+
+// Pagination is a page of a list endpoint; a zero PageSize means everything on one page
+type Pagination struct {
+	Page     int
+	PageSize int
+}
+
+// limitClause returns the LIMIT/OFFSET clause of the page, empty when unpaginated
+func (p Pagination) limitClause() string {
+	if p.PageSize <= 0 {
+		return ""
+	}
+	return fmt.Sprintf("LIMIT %d OFFSET %d", p.PageSize, (p.Page-1)*p.PageSize)
+}
+
+// parsePagination reads the page and page_size query parameters. Lists stay unpaginated
+// unless one of them is given.
+func parsePagination(r *http.Request) (Pagination, error) {
+	params := r.URL.Query()
+	p := Pagination{Page: 1}
+	if value := params.Get("page"); value != "" {
+		page, err := strconv.Atoi(value)
+		if err != nil || page < 1 {
+			return p, fmt.Errorf("Invalid page")
+		}
+		p.Page = page
+		p.PageSize = defaultPageSize
+	}
+	if value := params.Get("page_size"); value != "" {
+		size, err := strconv.Atoi(value)
+		if err != nil || size < 1 {
+			return p, fmt.Errorf("Invalid page_size")
+		}
+		if size > maxPageSize {
+			size = maxPageSize
+		}
+		p.PageSize = size
+	}
+	return p, nil
+}
+
+// pageLinks returns the URLs of the next and previous pages of a list of total items,
+// nil where there is no such page
+func pageLinks(r *http.Request, p Pagination, total int) (next *string, previous *string) {
+	if p.PageSize <= 0 {
+		return nil, nil
+	}
+	link := func(page int) *string {
+		scheme := "http"
+		if r.TLS != nil {
+			scheme = "https"
+		}
+		if proto := r.Header.Get("X-Forwarded-Proto"); proto != "" {
+			scheme = proto
+		}
+		params := r.URL.Query()
+		params.Set("page", strconv.Itoa(page))
+		params.Set("page_size", strconv.Itoa(p.PageSize))
+		u := url.URL{Scheme: scheme, Host: r.Host, Path: r.URL.Path, RawQuery: params.Encode()}
+		value := u.String()
+		return &value
+	}
+	if p.Page*p.PageSize < total {
+		next = link(p.Page + 1)
+	}
+	if p.Page > 1 {
+		previous = link(p.Page - 1)
+	}
+	return next, previous
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// compactLeadingColumns are the option keys listed first in compact responses
+var compactLeadingColumns = []string{"id", "label", "count"}
+
+// optionFields returns the option keys selected with ?fields=id,count, or nil for all keys
+func optionFields(r *http.Request) []string {
+	var fields []string
+	for _, field := range strings.Split(r.URL.Query().Get("fields"), ",") {
+		if field = strings.TrimSpace(field); field != "" {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// wantsCompact reports whether the client asked for option lists encoded as arrays
+func wantsCompact(r *http.Request) bool {
+	return r.URL.Query().Get("format") == "compact"
+}
+
+// respondOptions sends a JSON response containing value options, either as a top-level
+// list or under "values" keys. With ?fields=... the options only keep the selected keys;
+// with ?format=compact every option list becomes {"columns": [...], "rows": [[...], ...]}.
+func respondOptions(w http.ResponseWriter, r *http.Request, status int, data interface{}) {
+	fields := optionFields(r)
+	compact := wantsCompact(r)
+	if fields == nil && !compact {
+		respondJSON(w, status, data)
+		return
+	}
+
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	var generic interface{}
+	decoder := json.NewDecoder(bytes.NewReader(encoded))
+	decoder.UseNumber()
+	if err := decoder.Decode(&generic); err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondJSON(w, status, slimOptions(generic, fields, compact, true))
+}
+
+// slimOptions rewrites the option lists in value: the top-level list when top is set, and
+// lists under "values" keys of nested objects
+func slimOptions(value interface{}, fields []string, compact bool, top bool) interface{} {
+	switch v := value.(type) {
+	case []interface{}:
+		if top {
+			return slimOptionList(v, fields, compact)
+		}
+		for i, item := range v {
+			v[i] = slimOptions(item, fields, compact, false)
+		}
+		return v
+	case map[string]interface{}:
+		for key, item := range v {
+			if list, ok := item.([]interface{}); ok && key == "values" {
+				v[key] = slimOptionList(list, fields, compact)
+				continue
+			}
+			v[key] = slimOptions(item, fields, compact, false)
+		}
+		return v
+	default:
+		return value
+	}
+}
+
+// slimOptionList keeps the selected keys of every option and encodes the list as columns
+// and rows when compact is set. Lists that do not hold objects are returned unchanged.
+func slimOptionList(options []interface{}, fields []string, compact bool) interface{} {
+	objects := make([]map[string]interface{}, 0, len(options))
+	for _, option := range options {
+		object, ok := option.(map[string]interface{})
+		if !ok {
+			return options
+		}
+		objects = append(objects, object)
+	}
+
+	if fields != nil {
+		selected := make(map[string]bool, len(fields))
+		for _, field := range fields {
+			selected[field] = true
+		}
+		for _, object := range objects {
+			for key := range object {
+				if !selected[key] {
+					delete(object, key)
+				}
+			}
+		}
+	}
+	if !compact {
+		return objects
+	}
+
+	columns := fields
+	if columns == nil {
+		columns = optionColumns(objects)
+	}
+	rows := make([][]interface{}, 0, len(objects))
+	for _, object := range objects {
+		row := make([]interface{}, len(columns))
+		for i, column := range columns {
+			row[i] = object[column]
+		}
+		rows = append(rows, row)
+	}
+	return map[string]interface{}{"columns": columns, "rows": rows}
+}
+
+// optionColumns returns the keys used by any of the options: id, label and count first,
+// the others in alphabetical order
+func optionColumns(objects []map[string]interface{}) []string {
+	seen := make(map[string]bool)
+	for _, object := range objects {
+		for key := range object {
+			seen[key] = true
+		}
+	}
+
+	columns := []string{}
+	for _, column := range compactLeadingColumns {
+		if seen[column] {
+			columns = append(columns, column)
+			delete(seen, column)
+		}
+	}
+	rest := make([]string, 0, len(seen))
+	for key := range seen {
+		rest = append(rest, key)
+	}
+	sort.Strings(rest)
+	return append(columns, rest...)
+}
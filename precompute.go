@@ -0,0 +1,217 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// parseFieldIDList parses a comma separated list of custom field IDs
+func parseFieldIDList(value string) []int {
+	ids := []int{}
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		id, err := strconv.Atoi(part)
+		if err != nil {
+			log.Printf("[Precompute] Ignoring invalid field ID %q", part)
+			continue
+		}
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// hotFieldIDs returns the custom fields whose value counts should be precomputed:
+// the configured fields plus, optionally, custom field columns of saved views
+func (s *Service) hotFieldIDs(ctx context.Context) []int {
+	seen := make(map[int]bool)
+	ids := []int{}
+	for _, id := range s.config.PrecomputeFields {
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+
+	if s.config.PrecomputeViewFields {
+		rows, err := s.db.QueryContext(ctx, "SELECT column_order FROM custom_views WHERE deleted_at IS NULL")
+		if err != nil {
+			log.Printf("[Precompute] Failed to load view columns: %v", err)
+		} else {
+			defer rows.Close()
+			for rows.Next() {
+				var columnOrderJSON sql.NullString
+				if err := rows.Scan(&columnOrderJSON); err != nil || !columnOrderJSON.Valid {
+					continue
+				}
+				var columnOrder []interface{}
+				if err := json.Unmarshal([]byte(columnOrderJSON.String), &columnOrder); err != nil {
+					continue
+				}
+				// Numeric column IDs reference custom fields; builtin columns are strings
+				for _, column := range columnOrder {
+					if idFloat, ok := column.(float64); ok {
+						id := int(idFloat)
+						if !seen[id] {
+							seen[id] = true
+							ids = append(ids, id)
+						}
+					}
+				}
+			}
+		}
+	}
+
+	sort.Ints(ids)
+	return ids
+}
+
+// PrecomputeFieldValues computes the unfiltered value counts of a field and stores
+// them in the field_value_summaries table
+func (s *Service) PrecomputeFieldValues(ctx context.Context, fieldID int) error {
+	response, err := s.GetFieldValues(ctx, fieldID, "", "", false, false)
+	if err != nil {
+		return err
+	}
+
+	valuesJSON, err := json.Marshal(response.Values)
+	if err != nil {
+		return fmt.Errorf("failed to encode value counts: %w", err)
+	}
+
+	var deleteQuery, insertQuery string
+	switch s.config.DBEngine {
+	case "postgresql", "postgres":
+		deleteQuery = "DELETE FROM field_value_summaries WHERE field_id = $1"
+		insertQuery = `
+			INSERT INTO field_value_summaries (field_id, field_name, value_counts, total_documents, computed_at)
+			VALUES ($1, $2, $3::jsonb, $4, $5)
+		`
+	case "mysql", "mariadb", "sqlite", "sqlite3":
+		deleteQuery = "DELETE FROM field_value_summaries WHERE field_id = ?"
+		insertQuery = `
+			INSERT INTO field_value_summaries (field_id, field_name, value_counts, total_documents, computed_at)
+			VALUES (?, ?, ?, ?, ?)
+		`
+	default:
+		return fmt.Errorf("unsupported database engine: %s", s.config.DBEngine)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, deleteQuery, fieldID); err != nil {
+		return fmt.Errorf("failed to clear value summary: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, insertQuery, fieldID, response.FieldName, string(valuesJSON), response.TotalDocuments, time.Now().UTC()); err != nil {
+		return fmt.Errorf("failed to store value summary: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// getFieldValueSummary returns the materialized value counts of a field, or its
+// precomputed value counts if a summary exists and is younger than the configured maximum age
+func (s *Service) getFieldValueSummary(ctx context.Context, fieldID int) (*CustomFieldValuesResponse, bool) {
+	if summary, ok := s.getFacetSummary(ctx, fieldID); ok {
+		return summary, true
+	}
+	if s.config.PrecomputeInterval <= 0 {
+		return nil, false
+	}
+
+	query := `
+		SELECT field_name, value_counts, total_documents, computed_at
+		FROM field_value_summaries
+		WHERE field_id = ?
+	`
+	if s.config.DBEngine == "postgresql" || s.config.DBEngine == "postgres" {
+		query = strings.Replace(query, "?", "$1", 1)
+	}
+
+	var fieldName sql.NullString
+	var valuesJSON string
+	var totalDocuments int
+	var computedAt time.Time
+	if err := s.db.QueryRowContext(ctx, query, fieldID).Scan(&fieldName, &valuesJSON, &totalDocuments, &computedAt); err != nil {
+		if err != sql.ErrNoRows {
+			log.Printf("[Precompute] Failed to read summary for field %d: %v", fieldID, err)
+		}
+		return nil, false
+	}
+
+	age := time.Since(computedAt)
+	if age > s.config.PrecomputeMaxAge {
+		return nil, false
+	}
+
+	var values []CustomFieldValueOption
+	if err := json.Unmarshal([]byte(valuesJSON), &values); err != nil {
+		log.Printf("[Precompute] Failed to decode summary for field %d: %v", fieldID, err)
+		return nil, false
+	}
+
+	dataAsOf := computedAt.UTC().Format(time.RFC3339)
+	staleSeconds := int(age.Seconds())
+	return &CustomFieldValuesResponse{
+		FieldID:        fieldID,
+		FieldName:      fieldName.String,
+		Values:         values,
+		TotalDocuments: totalDocuments,
+		DataAsOf:       &dataAsOf,
+		StaleSeconds:   &staleSeconds,
+	}, true
+}
+
+// precomputeHotFields refreshes the summaries of all hot fields once
+func (s *Service) precomputeHotFields(ctx context.Context) {
+	fieldIDs := s.hotFieldIDs(ctx)
+	if len(fieldIDs) == 0 {
+		return
+	}
+
+	started := time.Now()
+	refreshed := 0
+	for _, fieldID := range fieldIDs {
+		if ctx.Err() != nil {
+			return
+		}
+		if err := s.PrecomputeFieldValues(ctx, fieldID); err != nil {
+			log.Printf("[Precompute] Failed to precompute field %d: %v", fieldID, err)
+			continue
+		}
+		refreshed++
+	}
+	log.Printf("[Precompute] Refreshed %d/%d hot field summaries in %s", refreshed, len(fieldIDs), time.Since(started))
+}
+
+// runPrecomputeScheduler periodically refreshes hot field summaries until ctx is cancelled
+func (s *Service) runPrecomputeScheduler(ctx context.Context) {
+	log.Printf("[Precompute] Scheduler started - Interval: %s", s.config.PrecomputeInterval)
+	s.precomputeHotFields(ctx)
+
+	ticker := time.NewTicker(s.config.PrecomputeInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Printf("[Precompute] Scheduler stopped")
+			return
+		case <-ticker.C:
+			s.precomputeHotFields(ctx)
+		}
+	}
+}
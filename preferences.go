@@ -0,0 +1,365 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	// maxPreferenceBytes caps the JSON encoding of a preference namespace
+	maxPreferenceBytes = 64 * 1024
+	// preferenceWriteAttempts is how often an unconditional write is retried when another
+	// client changed the namespace between reading and writing it
+	preferenceWriteAttempts = 3
+)
+
+// preferenceNamespacePattern matches the names of preference namespaces
+var preferenceNamespacePattern = regexp.MustCompile(`^[A-Za-z0-9_.-]{1,100}$`)
+
+// PreferenceConflictError is returned by conditional writes of a preference namespace that
+// has changed since the client read it; Current is the namespace as it is now, nil when it
+// does not exist
+type PreferenceConflictError struct {
+	Current *UserPreference
+}
+
+func (e *PreferenceConflictError) Error() string {
+	return "precondition failed: preference has been modified"
+}
+
+// PreferenceConflictResponse is the 412 response of a stale preference write
+type PreferenceConflictResponse struct {
+	Error   string          `json:"error"`
+	Message string          `json:"message"`
+	Current *UserPreference `json:"current"`
+}
+
+// preferenceETag returns the ETag of a version of a preference namespace
+func preferenceETag(preference *UserPreference) string {
+	return `"` + strconv.Itoa(preference.Version) + `"`
+}
+
+// queryUserPreferences retrieves the preference namespaces of a user selected by condition,
+// ordered by namespace
+func (s *Service) queryUserPreferences(ctx context.Context, userID int, condition string, args ...interface{}) ([]UserPreference, error) {
+	rows, err := s.db.QueryContext(ctx, s.rebind(`
+		SELECT namespace, data, version, created, modified
+		FROM user_preferences
+		WHERE user_id = ? AND `+condition+`
+		ORDER BY namespace ASC
+	`), append([]interface{}{userID}, args...)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query preferences: %w", err)
+	}
+	defer rows.Close()
+
+	preferences := []UserPreference{}
+	for rows.Next() {
+		var preference UserPreference
+		var data sql.NullString
+		var created, modified dbTimestamp
+		if err := rows.Scan(&preference.Namespace, &data, &preference.Version, &created, &modified); err != nil {
+			return nil, fmt.Errorf("failed to scan preference: %w", err)
+		}
+		json.Unmarshal([]byte(data.String), &preference.Value)
+		if preference.Value == nil {
+			preference.Value = map[string]interface{}{}
+		}
+		preference.Created = s.formatTimestamp(created)
+		preference.Modified = s.formatTimestamp(modified)
+		preferences = append(preferences, preference)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read preferences: %w", err)
+	}
+	return preferences, nil
+}
+
+// ListUserPreferences retrieves all preference namespaces of a user
+func (s *Service) ListUserPreferences(ctx context.Context, userID int) ([]UserPreference, error) {
+	return s.queryUserPreferences(ctx, userID, "1 = 1")
+}
+
+// GetUserPreference retrieves a preference namespace of a user
+func (s *Service) GetUserPreference(ctx context.Context, userID int, namespace string) (*UserPreference, error) {
+	preferences, err := s.queryUserPreferences(ctx, userID, "namespace = ?", namespace)
+	if err != nil {
+		return nil, err
+	}
+	if len(preferences) == 0 {
+		return nil, fmt.Errorf("preference %q not found", namespace)
+	}
+	return &preferences[0], nil
+}
+
+// WriteUserPreference sets a preference namespace of a user to the value build derives from
+// its current value (nil when the namespace does not exist yet), creating the namespace if
+// needed. With ifMatch (an If-Match header value) the write only succeeds if the namespace
+// is still at that version; without it, writes racing with other clients are retried on the
+// new value. It reports whether the namespace was created.
+func (s *Service) WriteUserPreference(ctx context.Context, userID int, namespace string, ifMatch string, build func(current map[string]interface{}) (map[string]interface{}, error)) (*UserPreference, bool, error) {
+	if !preferenceNamespacePattern.MatchString(namespace) {
+		return nil, false, fmt.Errorf("invalid namespace %q: expected up to 100 letters, digits, '_', '.' or '-'", namespace)
+	}
+	cast := ""
+	if s.config.DBEngine == "postgresql" || s.config.DBEngine == "postgres" {
+		cast = "::jsonb"
+	}
+
+	for attempt := 1; ; attempt++ {
+		current, err := s.GetUserPreference(ctx, userID, namespace)
+		if err != nil && !strings.Contains(err.Error(), "not found") {
+			return nil, false, err
+		}
+		if ifMatch != "" && (current == nil || !etagMatches(ifMatch, preferenceETag(current))) {
+			return nil, false, &PreferenceConflictError{Current: current}
+		}
+
+		var value map[string]interface{}
+		if current != nil {
+			value = current.Value
+		}
+		value, err = build(value)
+		if err != nil {
+			return nil, false, err
+		}
+		if value == nil {
+			value = map[string]interface{}{}
+		}
+		data, err := json.Marshal(value)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to encode preference: %w", err)
+		}
+		if len(data) > maxPreferenceBytes {
+			return nil, false, fmt.Errorf("invalid value: larger than %d bytes", maxPreferenceBytes)
+		}
+
+		// Writes only apply to the version read above, so a concurrent write is detected
+		// instead of being overwritten
+		written := false
+		if current == nil {
+			query := s.rebind("INSERT INTO user_preferences (user_id, namespace, data, version) VALUES (?, ?, ?" + cast + ", 1)")
+			_, err := s.db.ExecContext(ctx, query, userID, namespace, string(data))
+			written = err == nil
+			if err != nil {
+				// Created by another client meanwhile unless the namespace is still missing
+				if _, lookupErr := s.GetUserPreference(ctx, userID, namespace); lookupErr != nil {
+					return nil, false, fmt.Errorf("failed to create preference: %w", err)
+				}
+			}
+		} else {
+			query := s.rebind("UPDATE user_preferences SET data = ?" + cast + ", version = version + 1, modified = CURRENT_TIMESTAMP WHERE user_id = ? AND namespace = ? AND version = ?")
+			result, err := s.db.ExecContext(ctx, query, string(data), userID, namespace, current.Version)
+			if err != nil {
+				return nil, false, fmt.Errorf("failed to update preference: %w", err)
+			}
+			updated, _ := result.RowsAffected()
+			written = updated > 0
+		}
+
+		if written {
+			preference, err := s.GetUserPreference(ctx, userID, namespace)
+			return preference, current == nil, err
+		}
+		if ifMatch != "" || attempt >= preferenceWriteAttempts {
+			latest, _ := s.GetUserPreference(ctx, userID, namespace)
+			return nil, false, &PreferenceConflictError{Current: latest}
+		}
+		log.Printf("[Preferences] Retrying write of preference %q of user %d after a concurrent change", namespace, userID)
+	}
+}
+
+// DeleteUserPreference removes a preference namespace of a user. With ifMatch the namespace
+// is only removed if it is still at that version.
+func (s *Service) DeleteUserPreference(ctx context.Context, userID int, namespace string, ifMatch string) error {
+	current, err := s.GetUserPreference(ctx, userID, namespace)
+	if err != nil {
+		return err
+	}
+	query := "DELETE FROM user_preferences WHERE user_id = ? AND namespace = ?"
+	args := []interface{}{userID, namespace}
+	if ifMatch != "" {
+		if !etagMatches(ifMatch, preferenceETag(current)) {
+			return &PreferenceConflictError{Current: current}
+		}
+		query += " AND version = ?"
+		args = append(args, current.Version)
+	}
+
+	result, err := s.db.ExecContext(ctx, s.rebind(query), args...)
+	if err != nil {
+		return fmt.Errorf("failed to delete preference: %w", err)
+	}
+	if deleted, _ := result.RowsAffected(); deleted == 0 {
+		latest, _ := s.GetUserPreference(ctx, userID, namespace)
+		return &PreferenceConflictError{Current: latest}
+	}
+	return nil
+}
+
+// respondPreferenceError sends the response of a failed preference request: 412 with the
+// current version for conflicts, 400 for invalid namespaces and values and 404 for missing
+// namespaces
+func respondPreferenceError(w http.ResponseWriter, err error) {
+	if conflict, ok := err.(*PreferenceConflictError); ok {
+		if conflict.Current != nil {
+			w.Header().Set("ETag", preferenceETag(conflict.Current))
+		}
+		respondJSON(w, http.StatusPreconditionFailed, PreferenceConflictResponse{
+			Error:   http.StatusText(http.StatusPreconditionFailed),
+			Message: err.Error(),
+			Current: conflict.Current,
+		})
+		return
+	}
+	switch {
+	case strings.HasPrefix(err.Error(), "invalid"):
+		respondError(w, http.StatusBadRequest, err.Error())
+	case strings.Contains(err.Error(), "not found"):
+		respondError(w, http.StatusNotFound, err.Error())
+	default:
+		respondError(w, queryErrorStatus(err), err.Error())
+	}
+}
+
+// readPreferenceBody reads the JSON object of a preference write request
+func readPreferenceBody(r *http.Request) ([]byte, error) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxPreferenceBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("invalid request body: %w", err)
+	}
+	if len(body) > maxPreferenceBytes {
+		return nil, fmt.Errorf("invalid value: larger than %d bytes", maxPreferenceBytes)
+	}
+	return body, nil
+}
+
+// HTTP Handlers for user preferences
+
+func (s *Service) handleListUserPreferences(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	log.Printf("[Preferences] GET /api/preferences/ - Request from %s", r.RemoteAddr)
+
+	userID, err := getUserIDFromRequest(r)
+	if err != nil {
+		respondError(w, viewerErrorStatus(err), err.Error())
+		return
+	}
+
+	preferences, err := s.ListUserPreferences(ctx, *userID)
+	if err != nil {
+		log.Printf("[Preferences] Error listing preferences: %v", err)
+		respondError(w, queryErrorStatus(err), err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, UserPreferencesResponse{Count: len(preferences), Results: preferences})
+}
+
+func (s *Service) handleGetUserPreference(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	namespace := mux.Vars(r)["namespace"]
+	log.Printf("[Preferences] GET /api/preferences/%s/ - Request from %s", namespace, r.RemoteAddr)
+
+	userID, err := getUserIDFromRequest(r)
+	if err != nil {
+		respondError(w, viewerErrorStatus(err), err.Error())
+		return
+	}
+
+	preference, err := s.GetUserPreference(ctx, *userID, namespace)
+	if err != nil {
+		respondPreferenceError(w, err)
+		return
+	}
+
+	w.Header().Set("ETag", preferenceETag(preference))
+	respondJSON(w, http.StatusOK, preference)
+}
+
+// handleWriteUserPreference replaces (PUT) or merge-patches (PATCH) a preference namespace
+func (s *Service) handleWriteUserPreference(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	namespace := mux.Vars(r)["namespace"]
+	log.Printf("[Preferences] %s /api/preferences/%s/ - Request from %s", r.Method, namespace, r.RemoteAddr)
+
+	userID, err := getUserIDFromRequest(r)
+	if err != nil {
+		respondError(w, viewerErrorStatus(err), err.Error())
+		return
+	}
+	body, err := readPreferenceBody(r)
+	if err != nil {
+		respondPreferenceError(w, err)
+		return
+	}
+
+	build := func(current map[string]interface{}) (map[string]interface{}, error) {
+		var value map[string]interface{}
+		if r.Method == http.MethodPatch {
+			if current == nil {
+				current = map[string]interface{}{}
+			}
+			if err := mergePatchJSON(current, body, &value); err != nil {
+				return nil, err
+			}
+			return value, nil
+		}
+		if err := json.Unmarshal(body, &value); err != nil || value == nil {
+			return nil, fmt.Errorf("invalid value: expected a JSON object")
+		}
+		return value, nil
+	}
+
+	preference, created, err := s.WriteUserPreference(ctx, *userID, namespace, r.Header.Get("If-Match"), build)
+	if err != nil {
+		log.Printf("[Preferences] Error writing preference %q of user %d: %v", namespace, *userID, err)
+		respondPreferenceError(w, err)
+		return
+	}
+
+	w.Header().Set("ETag", preferenceETag(preference))
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+	respondJSON(w, status, preference)
+}
+
+func (s *Service) handleDeleteUserPreference(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	namespace := mux.Vars(r)["namespace"]
+	log.Printf("[Preferences] DELETE /api/preferences/%s/ - Request from %s", namespace, r.RemoteAddr)
+
+	userID, err := getUserIDFromRequest(r)
+	if err != nil {
+		respondError(w, viewerErrorStatus(err), err.Error())
+		return
+	}
+
+	if err := s.DeleteUserPreference(ctx, *userID, namespace, r.Header.Get("If-Match")); err != nil {
+		log.Printf("[Preferences] Error deleting preference %q of user %d: %v", namespace, *userID, err)
+		respondPreferenceError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,425 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Every statement of the service goes through an instrumented connector: it bounds the
+// statement with STATEMENT_TIMEOUT, records its duration in a histogram exported on
+// /metrics and logs statements slower than SLOW_QUERY_THRESHOLD with their normalized SQL
+// and a hash of their parameters, so pathological facet queries can be found without
+// logging document contents.
+
+// queryDurationBuckets are the upper bounds in seconds of the query duration histogram
+var queryDurationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+// durationHistogram is a cumulative Prometheus histogram per statement operation
+type durationHistogram struct {
+	mu     sync.Mutex
+	series map[string]*histogramSeries
+}
+
+type histogramSeries struct {
+	buckets []uint64
+	count   uint64
+	sum     float64
+}
+
+var queryDurations = &durationHistogram{series: make(map[string]*histogramSeries)}
+
+// observe records one statement of the given operation ("query" or "exec")
+func (h *durationHistogram) observe(operation string, elapsed time.Duration) {
+	seconds := elapsed.Seconds()
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	series, ok := h.series[operation]
+	if !ok {
+		series = &histogramSeries{buckets: make([]uint64, len(queryDurationBuckets))}
+		h.series[operation] = series
+	}
+	for i, bound := range queryDurationBuckets {
+		if seconds <= bound {
+			series.buckets[i]++
+		}
+	}
+	series.count++
+	series.sum += seconds
+}
+
+// writeTo writes the histogram in the Prometheus text exposition format
+func (h *durationHistogram) writeTo(w http.ResponseWriter) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	const name = "paperless_link_query_duration_seconds"
+	fmt.Fprintf(w, "# HELP %s Duration of database statements.\n", name)
+	fmt.Fprintf(w, "# TYPE %s histogram\n", name)
+	for _, operation := range []string{"query", "exec"} {
+		series, ok := h.series[operation]
+		if !ok {
+			continue
+		}
+		for i, bound := range queryDurationBuckets {
+			fmt.Fprintf(w, "%s_bucket{operation=%q,le=\"%g\"} %d\n", name, operation, bound, series.buckets[i])
+		}
+		fmt.Fprintf(w, "%s_bucket{operation=%q,le=\"+Inf\"} %d\n", name, operation, series.count)
+		fmt.Fprintf(w, "%s_sum{operation=%q} %g\n", name, operation, series.sum)
+		fmt.Fprintf(w, "%s_count{operation=%q} %d\n", name, operation, series.count)
+	}
+}
+
+// handleMetrics handles GET /metrics
+func handleMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	queryDurations.writeTo(w)
+}
+
+var (
+	sqlStringLiteral  = regexp.MustCompile(`'(?:[^']|'')*'`)
+	sqlNumberLiteral  = regexp.MustCompile(`\b\d+(?:\.\d+)?\b`)
+	sqlPlaceholder    = regexp.MustCompile(`\$\d+`)
+	sqlPlaceholderRun = regexp.MustCompile(`\?(?:\s*,\s*\?)+`)
+)
+
+// normalizeSQL collapses whitespace and replaces literals and placeholder lists, so the
+// same statement logs the same way whatever its parameters
+func normalizeSQL(query string) string {
+	query = strings.Join(strings.Fields(query), " ")
+	query = sqlStringLiteral.ReplaceAllString(query, "?")
+	query = sqlPlaceholder.ReplaceAllString(query, "?")
+	query = sqlNumberLiteral.ReplaceAllString(query, "?")
+	return sqlPlaceholderRun.ReplaceAllString(query, "?, ...")
+}
+
+// hashQueryArgs identifies the parameters of a statement without logging their values
+func hashQueryArgs(args []driver.NamedValue) string {
+	hash := sha256.New()
+	for _, arg := range args {
+		fmt.Fprintf(hash, "%T:%v\x00", arg.Value, arg.Value)
+	}
+	return hex.EncodeToString(hash.Sum(nil))[:16]
+}
+
+// queryObserver applies the statement timeout and records statements
+type queryObserver struct {
+	timeout       time.Duration
+	slowThreshold time.Duration
+}
+
+// noStatementTimeoutKey marks contexts of statements exempt from STATEMENT_TIMEOUT
+type noStatementTimeoutKey struct{}
+
+// withoutStatementTimeout exempts the statements run with ctx from STATEMENT_TIMEOUT, for
+// maintenance work such as index builds; the deadline of ctx itself still applies
+func withoutStatementTimeout(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noStatementTimeoutKey{}, true)
+}
+
+// withTimeout bounds one statement; the returned cancel must run when it is finished
+func (o *queryObserver) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if o.timeout <= 0 || ctx.Value(noStatementTimeoutKey{}) != nil {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, o.timeout)
+}
+
+// record adds a finished statement to the histogram and logs it when it was slow
+func (o *queryObserver) record(operation, query string, args []driver.NamedValue, started time.Time, err error) {
+	if err == driver.ErrSkip {
+		return
+	}
+	elapsed := time.Since(started)
+	queryDurations.observe(operation, elapsed)
+	if err == context.DeadlineExceeded && o.timeout > 0 && elapsed >= o.timeout {
+		log.Printf("[Database] Statement timed out after %v (params %s): %s",
+			elapsed.Round(time.Millisecond), hashQueryArgs(args), normalizeSQL(query))
+		return
+	}
+	if o.slowThreshold > 0 && elapsed >= o.slowThreshold {
+		log.Printf("[Database] Slow %s took %v (params %s): %s",
+			operation, elapsed.Round(time.Millisecond), hashQueryArgs(args), normalizeSQL(query))
+	}
+}
+
+// openInstrumentedDB opens the database through an instrumented connector
+func openInstrumentedDB(driverName, dsn string, config *Config) (*sql.DB, error) {
+	// database/sql does not expose registered drivers, so look it up through a handle
+	probe, err := sql.Open(driverName, dsn)
+	if err != nil {
+		return nil, err
+	}
+	drv := probe.Driver()
+	probe.Close()
+
+	var connector driver.Connector = dsnConnector{dsn: dsn, driver: drv}
+	if driverContext, ok := drv.(driver.DriverContext); ok {
+		if connector, err = driverContext.OpenConnector(dsn); err != nil {
+			return nil, err
+		}
+	}
+	observer := &queryObserver{timeout: config.StatementTimeout, slowThreshold: config.SlowQueryThreshold}
+	return sql.OpenDB(&instrumentedConnector{Connector: connector, observer: observer}), nil
+}
+
+// dsnConnector adapts drivers without a driver.Connector of their own (sqlite3)
+type dsnConnector struct {
+	dsn    string
+	driver driver.Driver
+}
+
+func (c dsnConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	return c.driver.Open(c.dsn)
+}
+
+func (c dsnConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+type instrumentedConnector struct {
+	driver.Connector
+	observer *queryObserver
+}
+
+func (c *instrumentedConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &instrumentedConn{Conn: conn, observer: c.observer}, nil
+}
+
+// instrumentedConn times the statements of one driver connection. Optional driver
+// interfaces are passed through; driver.ErrSkip makes database/sql fall back to a
+// prepared statement when the driver does not implement them.
+type instrumentedConn struct {
+	driver.Conn
+	observer *queryObserver
+}
+
+func (c *instrumentedConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	queryer, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	ctx, cancel := c.observer.withTimeout(ctx)
+	started := time.Now()
+	rows, err := queryer.QueryContext(ctx, query, args)
+	c.observer.record("query", query, args, started, ctxErr(ctx, err))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	return &instrumentedRows{Rows: rows, cancel: cancel}, nil
+}
+
+func (c *instrumentedConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	execer, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	ctx, cancel := c.observer.withTimeout(ctx)
+	defer cancel()
+	started := time.Now()
+	result, err := execer.ExecContext(ctx, query, args)
+	c.observer.record("exec", query, args, started, ctxErr(ctx, err))
+	return result, err
+}
+
+func (c *instrumentedConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	var stmt driver.Stmt
+	var err error
+	if preparer, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		stmt, err = preparer.PrepareContext(ctx, query)
+	} else {
+		stmt, err = c.Conn.Prepare(query)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &instrumentedStmt{Stmt: stmt, query: query, observer: c.observer}, nil
+}
+
+func (c *instrumentedConn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c *instrumentedConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if beginner, ok := c.Conn.(driver.ConnBeginTx); ok {
+		return beginner.BeginTx(ctx, opts)
+	}
+	return c.Conn.Begin()
+}
+
+func (c *instrumentedConn) Ping(ctx context.Context) error {
+	if pinger, ok := c.Conn.(driver.Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *instrumentedConn) ResetSession(ctx context.Context) error {
+	if resetter, ok := c.Conn.(driver.SessionResetter); ok {
+		return resetter.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *instrumentedConn) IsValid() bool {
+	if validator, ok := c.Conn.(driver.Validator); ok {
+		return validator.IsValid()
+	}
+	return true
+}
+
+func (c *instrumentedConn) CheckNamedValue(value *driver.NamedValue) error {
+	if checker, ok := c.Conn.(driver.NamedValueChecker); ok {
+		return checker.CheckNamedValue(value)
+	}
+	return driver.ErrSkip
+}
+
+// instrumentedStmt times prepared statements, such as those of the statement cache
+type instrumentedStmt struct {
+	driver.Stmt
+	query    string
+	observer *queryObserver
+}
+
+func (s *instrumentedStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	ctx, cancel := s.observer.withTimeout(ctx)
+	started := time.Now()
+	var rows driver.Rows
+	var err error
+	if queryer, ok := s.Stmt.(driver.StmtQueryContext); ok {
+		rows, err = queryer.QueryContext(ctx, args)
+	} else {
+		var values []driver.Value
+		if values, err = namedValuesToValues(args); err == nil {
+			rows, err = s.Stmt.Query(values)
+		}
+	}
+	s.observer.record("query", s.query, args, started, ctxErr(ctx, err))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	return &instrumentedRows{Rows: rows, cancel: cancel}, nil
+}
+
+func (s *instrumentedStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	ctx, cancel := s.observer.withTimeout(ctx)
+	defer cancel()
+	started := time.Now()
+	var result driver.Result
+	var err error
+	if execer, ok := s.Stmt.(driver.StmtExecContext); ok {
+		result, err = execer.ExecContext(ctx, args)
+	} else {
+		var values []driver.Value
+		if values, err = namedValuesToValues(args); err == nil {
+			result, err = s.Stmt.Exec(values)
+		}
+	}
+	s.observer.record("exec", s.query, args, started, ctxErr(ctx, err))
+	return result, err
+}
+
+func (s *instrumentedStmt) CheckNamedValue(value *driver.NamedValue) error {
+	if checker, ok := s.Stmt.(driver.NamedValueChecker); ok {
+		return checker.CheckNamedValue(value)
+	}
+	return driver.ErrSkip
+}
+
+func namedValuesToValues(args []driver.NamedValue) ([]driver.Value, error) {
+	values := make([]driver.Value, len(args))
+	for i, arg := range args {
+		if arg.Name != "" {
+			return nil, fmt.Errorf("driver does not support named parameter %s", arg.Name)
+		}
+		values[i] = arg.Value
+	}
+	return values, nil
+}
+
+// instrumentedRows keeps the statement timeout running until the rows are closed
+type instrumentedRows struct {
+	driver.Rows
+	cancel context.CancelFunc
+}
+
+func (r *instrumentedRows) Close() error {
+	err := r.Rows.Close()
+	r.cancel()
+	return err
+}
+
+func (r *instrumentedRows) HasNextResultSet() bool {
+	if sets, ok := r.Rows.(driver.RowsNextResultSet); ok {
+		return sets.HasNextResultSet()
+	}
+	return false
+}
+
+func (r *instrumentedRows) NextResultSet() error {
+	if sets, ok := r.Rows.(driver.RowsNextResultSet); ok {
+		return sets.NextResultSet()
+	}
+	return fmt.Errorf("driver does not support multiple result sets")
+}
+
+func (r *instrumentedRows) ColumnTypeScanType(index int) reflect.Type {
+	if types, ok := r.Rows.(driver.RowsColumnTypeScanType); ok {
+		return types.ColumnTypeScanType(index)
+	}
+	return reflect.TypeOf(new(interface{})).Elem()
+}
+
+func (r *instrumentedRows) ColumnTypeDatabaseTypeName(index int) string {
+	if types, ok := r.Rows.(driver.RowsColumnTypeDatabaseTypeName); ok {
+		return types.ColumnTypeDatabaseTypeName(index)
+	}
+	return ""
+}
+
+func (r *instrumentedRows) ColumnTypeNullable(index int) (nullable, ok bool) {
+	if types, isTyped := r.Rows.(driver.RowsColumnTypeNullable); isTyped {
+		return types.ColumnTypeNullable(index)
+	}
+	return false, false
+}
+
+func (r *instrumentedRows) ColumnTypeLength(index int) (length int64, ok bool) {
+	if types, isTyped := r.Rows.(driver.RowsColumnTypeLength); isTyped {
+		return types.ColumnTypeLength(index)
+	}
+	return 0, false
+}
+
+func (r *instrumentedRows) ColumnTypePrecisionScale(index int) (precision, scale int64, ok bool) {
+	if types, isTyped := r.Rows.(driver.RowsColumnTypePrecisionScale); isTyped {
+		return types.ColumnTypePrecisionScale(index)
+	}
+	return 0, 0, false
+}
+
+// ctxErr reports a statement cut off by its deadline as context.DeadlineExceeded, whatever
+// error the driver returned for the cancelled statement
+func ctxErr(ctx context.Context, err error) error {
+	if err != nil && ctx.Err() == context.DeadlineExceeded {
+		return context.DeadlineExceeded
+	}
+	return err
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// querySlotKey marks the context of a task holding a slot of the query pool
+type querySlotKey struct{}
+
+// runConcurrently runs independent queries of one request at the same time. Every task
+// takes a slot of the service-wide query pool (QUERY_CONCURRENCY), so parallel requests
+// cannot exhaust the database connections. The first error cancels the context of the
+// other tasks and is returned.
+//
+// Calls never nest on the pool: a task that calls runConcurrently again already holds a
+// slot, and waiting for more while holding it could starve the pool, so its tasks run one
+// after another in the slot it holds.
+func (s *Service) runConcurrently(ctx context.Context, tasks ...func(ctx context.Context) error) error {
+	if ctx.Value(querySlotKey{}) != nil {
+		for _, task := range tasks {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if err := task(ctx); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	group, groupCtx := errgroup.WithContext(ctx)
+	for _, task := range tasks {
+		task := task
+		group.Go(func() error {
+			if err := s.queryPool.Acquire(groupCtx, 1); err != nil {
+				return err
+			}
+			defer s.queryPool.Release(1)
+			return task(context.WithValue(groupCtx, querySlotKey{}, true))
+		})
+	}
+	return group.Wait()
+}
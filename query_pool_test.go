@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/sync/semaphore"
+)
+
+// poolService returns a service with only a query pool of the given size
+func poolService(size int) *Service {
+	return &Service{queryPool: semaphore.NewWeighted(int64(size))}
+}
+
+// concurrencyProbe records the highest number of tasks running at the same time
+type concurrencyProbe struct {
+	running atomic.Int32
+	peak    atomic.Int32
+}
+
+func (p *concurrencyProbe) task(ctx context.Context) error {
+	running := p.running.Add(1)
+	defer p.running.Add(-1)
+	for {
+		peak := p.peak.Load()
+		if running <= peak || p.peak.CompareAndSwap(peak, running) {
+			break
+		}
+	}
+	time.Sleep(5 * time.Millisecond)
+	return nil
+}
+
+func TestRunConcurrentlyBoundsTasksByPoolSize(t *testing.T) {
+	const poolSize = 3
+	s := poolService(poolSize)
+	probe := &concurrencyProbe{}
+
+	tasks := make([]func(ctx context.Context) error, 4*poolSize)
+	for i := range tasks {
+		tasks[i] = probe.task
+	}
+	if err := s.runConcurrently(context.Background(), tasks...); err != nil {
+		t.Fatal(err)
+	}
+	if peak := probe.peak.Load(); peak > poolSize {
+		t.Errorf("%d tasks ran at once, pool size is %d", peak, poolSize)
+	}
+}
+
+func TestRunConcurrentlyNestedAtPoolSize(t *testing.T) {
+	for _, poolSize := range []int{1, 2, 4} {
+		s := poolService(poolSize)
+		probe := &concurrencyProbe{}
+
+		// As many outer tasks as slots, each running nested queries: all slots are held
+		// while the nested calls run
+		outer := make([]func(ctx context.Context) error, poolSize)
+		for i := range outer {
+			outer[i] = func(ctx context.Context) error {
+				return s.runConcurrently(ctx, probe.task, probe.task, probe.task)
+			}
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		err := s.runConcurrently(ctx, outer...)
+		cancel()
+		if err != nil {
+			t.Fatalf("pool size %d: %v", poolSize, err)
+		}
+		if peak := probe.peak.Load(); peak > int32(poolSize) {
+			t.Errorf("pool size %d: %d tasks ran at once", poolSize, peak)
+		}
+	}
+}
+
+func TestRunConcurrentlyNestedCallsFromParallelRequests(t *testing.T) {
+	s := poolService(2)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 8)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- s.runConcurrently(ctx, func(ctx context.Context) error {
+				return s.runConcurrently(ctx, func(context.Context) error { return nil }, func(context.Context) error { return nil })
+			})
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestRunConcurrentlyReturnsFirstError(t *testing.T) {
+	s := poolService(2)
+	failure := errors.New("query failed")
+	err := s.runConcurrently(context.Background(),
+		func(context.Context) error { return failure },
+		func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		},
+	)
+	if !errors.Is(err, failure) {
+		t.Errorf("got %v, want %v", err, failure)
+	}
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// redisScanCount is the number of keys Redis is asked to check per SCAN call
+const redisScanCount = 1000
+
+// After redisBreakerFailures consecutive failed commands the cache stops talking to Redis
+// for redisBreakerCooldown, then lets a single command through to see whether it is back
+const (
+	redisBreakerFailures = 3
+	redisBreakerCooldown = 10 * time.Second
+)
+
+// errRedisUnavailable is returned without contacting Redis while the breaker is open
+var errRedisUnavailable = errors.New("redis: unavailable, skipping command")
+
+// redisCache is a Cache in Redis, shared by all replicas of the service: values are stored
+// as JSON under the configured key prefix and the name of the cache, and expire after the
+// TTL. Redis errors are logged and treated as misses, so the aggregations are computed
+// from the database while Redis is unavailable; a circuit breaker then fails commands
+// fast instead of waiting for the timeout on every one.
+type redisCache struct {
+	client  *redis.Client
+	address string
+	prefix  string
+	ttl     time.Duration
+	enabled bool
+	breaker redisBreaker
+}
+
+// redisBreaker counts the consecutive failed commands of a cache. Once there are
+// redisBreakerFailures it opens until cooldown has passed, then admits one probe at a time.
+type redisBreaker struct {
+	mu        sync.Mutex
+	cooldown  time.Duration
+	failures  int
+	openUntil time.Time
+	probing   bool
+}
+
+// allow reports whether a command may be sent to Redis
+func (b *redisBreaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.failures < redisBreakerFailures {
+		return true
+	}
+	if b.probing || time.Now().Before(b.openUntil) {
+		return false
+	}
+	b.probing = true
+	return true
+}
+
+// record notes the outcome of a command allowed by allow; error replies of Redis count
+// as successes, since Redis answered
+func (b *redisBreaker) record(address string, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+	if err == nil {
+		if b.failures >= redisBreakerFailures {
+			log.Printf("[Cache] Redis at %s is reachable again", address)
+		}
+		b.failures = 0
+		return
+	}
+	b.failures++
+	if b.failures >= redisBreakerFailures {
+		if b.failures == redisBreakerFailures {
+			log.Printf("[Cache] Redis at %s failed %d times in a row, skipping it for %s: %v", address, b.failures, b.cooldown, err)
+		}
+		b.openUntil = time.Now().Add(b.cooldown)
+	}
+}
+
+// newRedisCache connects to the Redis of the configuration. Like the in-memory cache, a
+// cache with a non-positive size or TTL never stores anything; the size is otherwise left
+// to the memory limit of Redis.
+func newRedisCache(config *Config, name string, ttl time.Duration, maxSize int) (*redisCache, error) {
+	options, err := redis.ParseURL(config.RedisURL)
+	if err != nil || !strings.HasPrefix(config.RedisURL, "redis") {
+		return nil, fmt.Errorf("invalid REDIS_URL: expected redis://[:password@]host:port/db")
+	}
+	// The breaker decides when to try again, so failed commands are not retried
+	options.Protocol = 2
+	options.DisableIdentity = true
+	options.MaxRetries = -1
+	options.DialTimeout = config.RedisTimeout
+	options.ReadTimeout = config.RedisTimeout
+	options.WriteTimeout = config.RedisTimeout
+	options.MaxIdleConns = max(config.RedisPool, 1)
+
+	cache := &redisCache{
+		client:  redis.NewClient(options),
+		address: options.Addr,
+		prefix:  config.RedisPrefix + name + ":",
+		ttl:     ttl,
+		enabled: ttl > 0 && maxSize > 0,
+		breaker: redisBreaker{cooldown: redisBreakerCooldown},
+	}
+	if err := cache.do(func(ctx context.Context) error { return cache.client.Ping(ctx).Err() }); err != nil {
+		cache.client.Close()
+		return nil, fmt.Errorf("failed to connect to Redis at %s: %w", cache.address, err)
+	}
+	log.Printf("[Cache] Using Redis at %s for the %s cache", cache.address, name)
+	return cache, nil
+}
+
+// do runs a command and records its outcome with the breaker; error replies of Redis
+// (missing keys included) count as successes, since Redis answered. While the breaker is
+// open it returns errRedisUnavailable right away.
+func (c *redisCache) do(command func(ctx context.Context) error) error {
+	if !c.breaker.allow() {
+		return errRedisUnavailable
+	}
+	err := command(context.Background())
+	var reply redis.Error
+	if err != nil && !errors.As(err, &reply) {
+		c.breaker.record(c.address, err)
+	} else {
+		c.breaker.record(c.address, nil)
+	}
+	return err
+}
+
+// logRedisFailure logs a failed command, unless it was skipped by the open breaker
+func logRedisFailure(command string, err error) {
+	if err != errRedisUnavailable {
+		log.Printf("[Cache] Redis %s failed: %v", command, err)
+	}
+}
+
+// Get decodes the value stored under key into target, like the in-memory cache
+func (c *redisCache) Get(key string, target interface{}) bool {
+	if !c.enabled {
+		return false
+	}
+	var data []byte
+	err := c.do(func(ctx context.Context) (err error) {
+		data, err = c.client.Get(ctx, c.prefix+key).Bytes()
+		return err
+	})
+	if err != nil {
+		if err != redis.Nil {
+			logRedisFailure("GET", err)
+		}
+		return false
+	}
+	return decodeCachedValue(key, data, target)
+}
+
+// Set stores value under key for the TTL of the cache
+func (c *redisCache) Set(key string, value interface{}) {
+	if !c.enabled {
+		return
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		log.Printf("[Cache] Failed to encode %s for Redis: %v", key, err)
+		return
+	}
+	if err := c.do(func(ctx context.Context) error { return c.client.Set(ctx, c.prefix+key, data, c.ttl).Err() }); err != nil {
+		logRedisFailure("SET", err)
+	}
+}
+
+// DeletePrefix removes the keys of the cache starting with prefix for all replicas
+func (c *redisCache) DeletePrefix(prefix string) int {
+	// Glob characters of the prefix are matched literally
+	var pattern strings.Builder
+	for _, r := range c.prefix + prefix {
+		if strings.ContainsRune(`*?[]\`, r) {
+			pattern.WriteRune('\\')
+		}
+		pattern.WriteRune(r)
+	}
+	pattern.WriteRune('*')
+
+	removed := 0
+	var cursor uint64
+	for {
+		var keys []string
+		err := c.do(func(ctx context.Context) (err error) {
+			keys, cursor, err = c.client.Scan(ctx, cursor, pattern.String(), redisScanCount).Result()
+			return err
+		})
+		if err != nil {
+			logRedisFailure("SCAN", err)
+			return removed
+		}
+		if len(keys) > 0 {
+			var count int64
+			err := c.do(func(ctx context.Context) (err error) {
+				count, err = c.client.Del(ctx, keys...).Result()
+				return err
+			})
+			if err != nil {
+				logRedisFailure("DEL", err)
+				return removed
+			}
+			removed += int(count)
+		}
+		if cursor == 0 {
+			return removed
+		}
+	}
+}
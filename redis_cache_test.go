@@ -0,0 +1,286 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"path"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeRedis is a RESP server keeping keys in memory, for the commands of redisCache. While
+// hanging it reads commands without answering them, like an unreachable Redis.
+type fakeRedis struct {
+	listener net.Listener
+	mu       sync.Mutex
+	keys     map[string]string
+	commands int
+	hanging  bool
+}
+
+// newFakeRedis starts a fake Redis on a local port
+func newFakeRedis(t *testing.T) *fakeRedis {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &fakeRedis{listener: listener, keys: map[string]string{}}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go server.serve(conn)
+		}
+	}()
+	return server
+}
+
+func (f *fakeRedis) serve(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	for {
+		args, err := readFakeRedisCommand(reader)
+		if err != nil {
+			return
+		}
+
+		f.mu.Lock()
+		f.commands++
+		hanging := f.hanging
+		reply := ""
+		if !hanging {
+			reply = f.run(args)
+		}
+		f.mu.Unlock()
+		if !hanging {
+			conn.Write([]byte(reply))
+		}
+	}
+}
+
+// readFakeRedisCommand reads a command sent as a RESP array of bulk strings
+func readFakeRedisCommand(reader *bufio.Reader) ([]string, error) {
+	var count int
+	if _, err := fmt.Fscanf(reader, "*%d\r\n", &count); err != nil {
+		return nil, err
+	}
+	args := make([]string, count)
+	for i := range args {
+		var size int
+		if _, err := fmt.Fscanf(reader, "$%d\r\n", &size); err != nil {
+			return nil, err
+		}
+		data := make([]byte, size+2)
+		if _, err := io.ReadFull(reader, data); err != nil {
+			return nil, err
+		}
+		args[i] = string(data[:size])
+	}
+	return args, nil
+}
+
+// run executes a command on the keys and returns its encoded reply
+func (f *fakeRedis) run(args []string) string {
+	switch strings.ToUpper(args[0]) {
+	case "PING":
+		return "+PONG\r\n"
+	case "GET":
+		value, ok := f.keys[args[1]]
+		if !ok {
+			return "$-1\r\n"
+		}
+		return fmt.Sprintf("$%d\r\n%s\r\n", len(value), value)
+	case "SET":
+		f.keys[args[1]] = args[2]
+		return "+OK\r\n"
+	case "SCAN":
+		// One page with all matching keys
+		var matches []string
+		for key := range f.keys {
+			if ok, _ := path.Match(args[3], key); ok {
+				matches = append(matches, key)
+			}
+		}
+		sort.Strings(matches)
+		reply := fmt.Sprintf("*2\r\n$1\r\n0\r\n*%d\r\n", len(matches))
+		for _, key := range matches {
+			reply += fmt.Sprintf("$%d\r\n%s\r\n", len(key), key)
+		}
+		return reply
+	case "DEL":
+		removed := 0
+		for _, key := range args[1:] {
+			if _, ok := f.keys[key]; ok {
+				delete(f.keys, key)
+				removed++
+			}
+		}
+		return fmt.Sprintf(":%d\r\n", removed)
+	}
+	return "-ERR unknown command '" + args[0] + "'\r\n"
+}
+
+// setHanging makes the server stop or resume answering
+func (f *fakeRedis) setHanging(hanging bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.hanging = hanging
+}
+
+// commandCount returns the number of commands received
+func (f *fakeRedis) commandCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.commands
+}
+
+// newFakeRedisCache creates a Redis cache on a fake Redis
+func newFakeRedisCache(t *testing.T, server *fakeRedis, timeout time.Duration) *redisCache {
+	t.Helper()
+	config := &Config{
+		RedisURL:     "redis://" + server.listener.Addr().String() + "/0",
+		RedisPrefix:  "test:",
+		RedisTimeout: timeout,
+		RedisPool:    2,
+	}
+	cache, err := newRedisCache(config, "values", time.Minute, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cache
+}
+
+func TestCacheBackendsReturnTheSameTypes(t *testing.T) {
+	redis := newFakeRedisCache(t, newFakeRedis(t), time.Second)
+	memory, err := newCache(&Config{}, "values", time.Minute, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	color := "#ff0000"
+	stored := []BuiltinFilterValueOption{
+		{ID: int64(1), Label: "ACME", Count: 2, Color: color},
+		{ID: "inbox", Label: "Inbox", Count: 1},
+		{ID: nil, Label: noOwnerLabel, Count: 3},
+	}
+	var fromRedis, fromMemory []BuiltinFilterValueOption
+	for _, backend := range []struct {
+		cache  Cache
+		target *[]BuiltinFilterValueOption
+	}{{redis, &fromRedis}, {memory, &fromMemory}} {
+		backend.cache.Set("tag", stored)
+		if !backend.cache.Get("tag", backend.target) {
+			t.Fatalf("%T: cached value missing", backend.cache)
+		}
+	}
+	if !reflect.DeepEqual(fromRedis, fromMemory) {
+		t.Errorf("redis returned %#v, memory %#v", fromRedis, fromMemory)
+	}
+	if fmt.Sprint(fromMemory[0].ID) != "1" || fromMemory[1].ID != "inbox" || fromMemory[2].ID != nil {
+		t.Errorf("decoded IDs %v, %v, %v", fromMemory[0].ID, fromMemory[1].ID, fromMemory[2].ID)
+	}
+
+	// Changing a value read from the cache does not change the cached one
+	fromMemory[0].Label = "changed"
+	var again []BuiltinFilterValueOption
+	if memory.Get("tag", &again); again[0].Label != "ACME" {
+		t.Errorf("cached label changed to %q", again[0].Label)
+	}
+}
+
+func TestRedisCacheDeletePrefix(t *testing.T) {
+	server := newFakeRedis(t)
+	cache := newFakeRedisCache(t, server, time.Second)
+	for _, key := range []string{"field:1|values", "field:1|counts", "field:10|values", "field:[1]|values"} {
+		cache.Set(key, key)
+	}
+
+	if removed := cache.DeletePrefix("field:1|"); removed != 2 {
+		t.Errorf("removed %d keys, want 2", removed)
+	}
+	// Glob characters of the prefix match literally
+	if removed := cache.DeletePrefix("field:[1]"); removed != 1 {
+		t.Errorf("removed %d keys with a glob prefix, want 1", removed)
+	}
+	var value string
+	if !cache.Get("field:10|values", &value) || value != "field:10|values" {
+		t.Errorf("unrelated key removed, got %q", value)
+	}
+}
+
+func TestRedisCacheCircuitBreaker(t *testing.T) {
+	server := newFakeRedis(t)
+	cache := newFakeRedisCache(t, server, 50*time.Millisecond)
+	cache.breaker.cooldown = 200 * time.Millisecond
+	cache.Set("key", "value")
+
+	server.setHanging(true)
+	var value string
+	for i := 0; i < redisBreakerFailures; i++ {
+		if cache.Get("key", &value) {
+			t.Fatal("got a value from a hanging Redis")
+		}
+	}
+
+	// The open breaker fails commands without sending them
+	sent := server.commandCount()
+	start := time.Now()
+	for i := 0; i < 10; i++ {
+		if cache.Get("key", &value) {
+			t.Fatal("got a value with the breaker open")
+		}
+	}
+	if elapsed := time.Since(start); elapsed > 25*time.Millisecond {
+		t.Errorf("commands with the breaker open took %s", elapsed)
+	}
+	if server.commandCount() != sent {
+		t.Errorf("sent %d commands with the breaker open", server.commandCount()-sent)
+	}
+
+	// After the cooldown a probe closes the breaker again
+	server.setHanging(false)
+	time.Sleep(cache.breaker.cooldown)
+	if !cache.Get("key", &value) || value != "value" {
+		t.Fatalf("got %q after Redis came back", value)
+	}
+	if !cache.breaker.allow() {
+		t.Error("breaker still open after a successful probe")
+	}
+}
+
+func TestRedisBreakerAdmitsOneProbe(t *testing.T) {
+	breaker := redisBreaker{cooldown: time.Millisecond}
+	for i := 0; i < redisBreakerFailures; i++ {
+		breaker.record("redis", net.ErrClosed)
+	}
+	if breaker.allow() {
+		t.Fatal("breaker allowed a command right after opening")
+	}
+	time.Sleep(2 * time.Millisecond)
+	if !breaker.allow() || breaker.allow() {
+		t.Fatal("breaker did not admit exactly one probe after the cooldown")
+	}
+	// A failed probe opens the breaker for another cooldown
+	breaker.record("redis", net.ErrClosed)
+	if breaker.allow() {
+		t.Error("breaker allowed a command after a failed probe")
+	}
+	time.Sleep(2 * time.Millisecond)
+	if !breaker.allow() {
+		t.Fatal("breaker admitted no probe after the second cooldown")
+	}
+	breaker.record("redis", nil)
+	if !breaker.allow() || !breaker.allow() {
+		t.Error("breaker did not close after a successful probe")
+	}
+}
@@ -0,0 +1,668 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/mail"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// EventReminderDue is the event of reminder notifications sent by webhook
+const EventReminderDue = "document.reminder_due"
+
+// maxReminderNoteLength caps the note of a reminder
+const maxReminderNoteLength = 4000
+
+// reminderColumns are the columns read by scanReminder, of document_reminders r joined with
+// documents_document d
+const reminderColumns = "r.id, r.document_id, d.title, r.due_at, r.note, r.channel, r.target, r.secret, r.completed_at, r.notified_at, r.owner_id, r.created, r.modified"
+
+// ReminderNotification is the JSON body posted by reminders with a webhook channel
+type ReminderNotification struct {
+	ID            string  `json:"id"` // Delivery ID, the same for all attempts
+	Event         string  `json:"event"`
+	Timestamp     string  `json:"timestamp"`
+	ReminderID    int     `json:"reminder_id"`
+	DocumentID    int     `json:"document_id"`
+	DocumentTitle string  `json:"document_title"`
+	DueAt         string  `json:"due_at"`
+	Note          *string `json:"note,omitempty"`
+}
+
+// scanReminder scans a Reminder from a database row or rows; now decides whether it is overdue
+func (s *Service) scanReminder(scanner interface{ Scan(...interface{}) error }, now time.Time) (Reminder, error) {
+	var reminder Reminder
+	var id int
+	var title, note, channel, target, secret sql.NullString
+	var ownerID sql.NullInt64
+	var dueAt, completedAt, notifiedAt, created, modified dbTimestamp
+
+	if err := scanner.Scan(&id, &reminder.DocumentID, &title, &dueAt, &note, &channel, &target, &secret,
+		&completedAt, &notifiedAt, &ownerID, &created, &modified); err != nil {
+		return reminder, err
+	}
+
+	reminder.ID = &id
+	reminder.DocumentTitle = title.String
+	if due := s.formatTimestamp(dueAt); due != nil {
+		reminder.DueAt = *due
+	}
+	if note.Valid {
+		reminder.Note = &note.String
+	}
+	if channel.Valid && channel.String != "" {
+		reminder.Channel = &channel.String
+		reminder.Target = &target.String
+	}
+	if secret.Valid && secret.String != "" {
+		reminder.Secret = &secret.String
+		reminder.HasSecret = true
+	}
+	done := completedAt.Valid
+	reminder.Done = &done
+	reminder.Overdue = !done && dueAt.Valid && dueAt.Time.Before(now)
+	reminder.CompletedAt = s.formatTimestamp(completedAt)
+	reminder.NotifiedAt = s.formatTimestamp(notifiedAt)
+	if ownerID.Valid {
+		owner := int(ownerID.Int64)
+		reminder.OwnerID = &owner
+	}
+	reminder.Created = s.formatTimestamp(created)
+	reminder.Modified = s.formatTimestamp(modified)
+
+	return reminder, nil
+}
+
+// parseReminderDueAt reads the due date of a reminder, an RFC3339 timestamp or a date
+// ("2006-01-02") for midnight UTC
+func parseReminderDueAt(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, fmt.Errorf("due_at is required")
+	}
+	if dueAt, err := time.Parse(time.RFC3339, value); err == nil {
+		return dueAt.UTC(), nil
+	}
+	if dueAt, err := time.Parse("2006-01-02", value); err == nil {
+		return dueAt, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid due_at: expected an RFC3339 timestamp or a date (YYYY-MM-DD)")
+}
+
+// validateReminder checks the note and the notification channel of a reminder
+func (s *Service) validateReminder(reminder Reminder) error {
+	if reminder.Note != nil && len(*reminder.Note) > maxReminderNoteLength {
+		return fmt.Errorf("invalid reminder: note must be at most %d characters", maxReminderNoteLength)
+	}
+	if reminder.Channel == nil || *reminder.Channel == "" {
+		return nil
+	}
+
+	target := ""
+	if reminder.Target != nil {
+		target = *reminder.Target
+	}
+	switch *reminder.Channel {
+	case RuleChannelWebhook:
+		if err := validateWebhook(Webhook{URL: target}); err != nil {
+			return fmt.Errorf("invalid reminder: target must be an absolute http or https URL")
+		}
+	case RuleChannelEmail:
+		if _, err := mail.ParseAddress(target); err != nil {
+			return fmt.Errorf("invalid reminder: target must be an email address")
+		}
+		if s.config.SMTPHost == "" {
+			return fmt.Errorf("invalid reminder: email notifications need SMTP_HOST to be configured")
+		}
+	default:
+		return fmt.Errorf("invalid reminder: channel must be webhook, email or empty")
+	}
+	return nil
+}
+
+// queryReminders retrieves the reminders of a user selected by condition, soonest due first.
+// Only reminders of documents outside the trash the viewer can see are returned.
+func (s *Service) queryReminders(ctx context.Context, userID int, viewerID int, condition string, args ...interface{}) ([]Reminder, error) {
+	documentCondition := trashedCondition(false)
+	visibility, err := s.documentVisibilityCondition(ctx, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	if visibility != "" {
+		documentCondition = fmt.Sprintf("(%s AND %s)", documentCondition, visibility)
+	}
+
+	rows, err := s.db.QueryContext(ctx, s.rebind(fmt.Sprintf(`
+		SELECT %s
+		FROM document_reminders r
+		INNER JOIN documents_document d ON d.id = r.document_id
+		WHERE r.owner_id = ? AND %s AND %s
+		ORDER BY r.due_at ASC, r.id ASC
+	`, reminderColumns, condition, documentCondition)), append([]interface{}{userID}, args...)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query reminders: %w", err)
+	}
+	defer rows.Close()
+
+	now := time.Now()
+	reminders := []Reminder{}
+	for rows.Next() {
+		reminder, err := s.scanReminder(rows, now)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan reminder: %w", err)
+		}
+		reminders = append(reminders, reminder)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read reminders: %w", err)
+	}
+	return reminders, nil
+}
+
+// ListReminders retrieves the reminders of a user by status: "open" (not done, the default),
+// "overdue", "upcoming", "done" or "all". For open and upcoming reminders, withinDays > 0
+// keeps those due in the next withinDays days. documentID > 0 keeps the reminders of one
+// document.
+func (s *Service) ListReminders(ctx context.Context, userID int, viewerID int, status string, withinDays int, documentID int) ([]Reminder, error) {
+	now := time.Now().UTC()
+	conditions := []string{}
+	args := []interface{}{}
+	switch status {
+	case "", "open":
+		conditions = append(conditions, "r.completed_at IS NULL")
+	case "overdue":
+		conditions = append(conditions, "r.completed_at IS NULL", "r.due_at < ?")
+		args = append(args, now)
+	case "upcoming":
+		conditions = append(conditions, "r.completed_at IS NULL", "r.due_at >= ?")
+		args = append(args, now)
+	case "done":
+		conditions = append(conditions, "r.completed_at IS NOT NULL")
+	case "all":
+	default:
+		return nil, fmt.Errorf("invalid status: expected open, overdue, upcoming, done or all")
+	}
+	if withinDays > 0 && (status == "" || status == "open" || status == "upcoming") {
+		conditions = append(conditions, "r.due_at < ?")
+		args = append(args, now.AddDate(0, 0, withinDays))
+	}
+	if documentID > 0 {
+		conditions = append(conditions, "r.document_id = ?")
+		args = append(args, documentID)
+	}
+	if len(conditions) == 0 {
+		conditions = append(conditions, "1 = 1")
+	}
+	return s.queryReminders(ctx, userID, viewerID, strings.Join(conditions, " AND "), args...)
+}
+
+// GetReminder retrieves a reminder of the user; other users' reminders and reminders of
+// documents the viewer cannot see are not found
+func (s *Service) GetReminder(ctx context.Context, id int, userID int, viewerID int) (*Reminder, error) {
+	reminders, err := s.queryReminders(ctx, userID, viewerID, "r.id = ?", id)
+	if err != nil {
+		return nil, err
+	}
+	if len(reminders) == 0 {
+		return nil, fmt.Errorf("reminder with id %d not found", id)
+	}
+	return &reminders[0], nil
+}
+
+// CreateReminder sets a reminder of the user on a document the viewer can see
+func (s *Service) CreateReminder(ctx context.Context, reminder Reminder, userID int, viewerID int) (*Reminder, error) {
+	log.Printf("[Reminders] CreateReminder - Document: %d, Due: %s, UserID: %d", reminder.DocumentID, reminder.DueAt, userID)
+	if reminder.DocumentID <= 0 {
+		return nil, fmt.Errorf("document_id is required")
+	}
+	dueAt, err := parseReminderDueAt(reminder.DueAt)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.validateReminder(reminder); err != nil {
+		return nil, err
+	}
+	if err := s.checkDocumentsVisible(ctx, []int{reminder.DocumentID}, false, viewerID); err != nil {
+		return nil, err
+	}
+	if reminder.Channel != nil && *reminder.Channel == "" {
+		reminder.Channel, reminder.Target = nil, nil
+	}
+
+	var id int64
+	if s.config.DBEngine == "postgresql" || s.config.DBEngine == "postgres" {
+		err := s.db.QueryRowContext(ctx, `
+			INSERT INTO document_reminders (document_id, due_at, note, channel, target, secret, owner_id)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+			RETURNING id
+		`, reminder.DocumentID, dueAt, reminder.Note, reminder.Channel, reminder.Target, reminder.Secret, userID).Scan(&id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create reminder: %w", err)
+		}
+	} else {
+		result, err := s.db.ExecContext(ctx, `
+			INSERT INTO document_reminders (document_id, due_at, note, channel, target, secret, owner_id)
+			VALUES (?, ?, ?, ?, ?, ?, ?)
+		`, reminder.DocumentID, dueAt, reminder.Note, reminder.Channel, reminder.Target, reminder.Secret, userID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create reminder: %w", err)
+		}
+		if id, err = result.LastInsertId(); err != nil {
+			return nil, fmt.Errorf("failed to get reminder ID: %w", err)
+		}
+	}
+
+	return s.GetReminder(ctx, int(id), userID, viewerID)
+}
+
+// UpdateReminder updates the provided fields of a reminder of the user. Moving the due date
+// lets the reminder notify again; marking it done or not done sets or clears completed_at.
+// An empty channel stops notifications, an empty secret removes the signature.
+func (s *Service) UpdateReminder(ctx context.Context, id int, updates Reminder, userID int, viewerID int) (*Reminder, error) {
+	log.Printf("[Reminders] UpdateReminder - ID: %d, UserID: %d", id, userID)
+	existing, err := s.GetReminder(ctx, id, userID, viewerID)
+	if err != nil {
+		return nil, err
+	}
+
+	merged := *existing
+	if updates.Note != nil {
+		merged.Note = updates.Note
+	}
+	if updates.Channel != nil {
+		merged.Channel = updates.Channel
+	}
+	if updates.Target != nil {
+		merged.Target = updates.Target
+	}
+	if err := s.validateReminder(merged); err != nil {
+		return nil, err
+	}
+
+	setParts := []string{}
+	args := []interface{}{}
+	addSet := func(column string, value interface{}) {
+		setParts = append(setParts, column+" = ?")
+		args = append(args, value)
+	}
+
+	if updates.DueAt != "" {
+		dueAt, err := parseReminderDueAt(updates.DueAt)
+		if err != nil {
+			return nil, err
+		}
+		addSet("due_at", dueAt)
+		setParts = append(setParts, "notified_at = NULL")
+	}
+	if updates.Note != nil {
+		addSet("note", *updates.Note)
+	}
+	if updates.Channel != nil {
+		if *updates.Channel == "" {
+			setParts = append(setParts, "channel = NULL", "target = NULL")
+		} else {
+			addSet("channel", *merged.Channel)
+			addSet("target", *merged.Target)
+		}
+	} else if updates.Target != nil && merged.Channel != nil {
+		addSet("target", *updates.Target)
+	}
+	if updates.Secret != nil {
+		addSet("secret", *updates.Secret)
+	}
+	if updates.Done != nil && *updates.Done != *existing.Done {
+		if *updates.Done {
+			addSet("completed_at", time.Now().UTC())
+		} else {
+			setParts = append(setParts, "completed_at = NULL")
+		}
+	}
+	setParts = append(setParts, "modified = CURRENT_TIMESTAMP")
+
+	args = append(args, id)
+	query := fmt.Sprintf("UPDATE document_reminders SET %s WHERE id = ?", strings.Join(setParts, ", "))
+	if _, err := s.db.ExecContext(ctx, s.rebind(query), args...); err != nil {
+		return nil, fmt.Errorf("failed to update reminder: %w", err)
+	}
+
+	return s.GetReminder(ctx, id, userID, viewerID)
+}
+
+// DeleteReminder deletes a reminder of the user
+func (s *Service) DeleteReminder(ctx context.Context, id int, userID int, viewerID int) error {
+	log.Printf("[Reminders] DeleteReminder - ID: %d, UserID: %d", id, userID)
+	if _, err := s.GetReminder(ctx, id, userID, viewerID); err != nil {
+		return err
+	}
+	if _, err := s.db.ExecContext(ctx, s.rebind("DELETE FROM document_reminders WHERE id = ?"), id); err != nil {
+		return fmt.Errorf("failed to delete reminder: %w", err)
+	}
+	return nil
+}
+
+// runDueReminders records the reminders that came due since the last run in the activity
+// feed and notifies those with a channel. Each reminder is claimed by setting notified_at
+// before it is sent, so it notifies once even with several instances running. Reminders of documents in the trash or no longer visible to
+// their owner wait until the document is back.
+func (s *Service) runDueReminders(ctx context.Context) {
+	now := time.Now().UTC()
+	rows, err := s.db.QueryContext(ctx, s.rebind(`
+		SELECT `+reminderColumns+`
+		FROM document_reminders r
+		INNER JOIN documents_document d ON d.id = r.document_id
+		WHERE r.due_at <= ? AND r.completed_at IS NULL AND r.notified_at IS NULL
+		ORDER BY r.due_at ASC
+	`), now)
+	if err != nil {
+		log.Printf("[Reminders] Failed to query due reminders: %v", err)
+		return
+	}
+	var due []Reminder
+	for rows.Next() {
+		reminder, err := s.scanReminder(rows, now)
+		if err != nil {
+			log.Printf("[Reminders] Failed to scan reminder: %v", err)
+			continue
+		}
+		due = append(due, reminder)
+	}
+	rows.Close()
+
+	for _, reminder := range due {
+		ownerID := 0
+		if reminder.OwnerID != nil {
+			ownerID = *reminder.OwnerID
+		}
+		if err := s.checkDocumentsVisible(ctx, []int{reminder.DocumentID}, false, ownerID); err != nil {
+			continue
+		}
+		result, err := s.db.ExecContext(ctx, s.rebind("UPDATE document_reminders SET notified_at = CURRENT_TIMESTAMP WHERE id = ? AND notified_at IS NULL"), *reminder.ID)
+		if err != nil {
+			log.Printf("[Reminders] Failed to claim reminder %d: %v", *reminder.ID, err)
+			continue
+		}
+		if claimed, _ := result.RowsAffected(); claimed == 0 {
+			continue
+		}
+		log.Printf("[Reminders] Reminder %d of document %d came due", *reminder.ID, reminder.DocumentID)
+		data := map[string]interface{}{"reminder_id": *reminder.ID, "due_at": reminder.DueAt}
+		if reminder.Note != nil {
+			data["note"] = *reminder.Note
+		}
+		if _, err := s.recordActivity(ctx, EventReminderDue, &reminder.DocumentID, reminder.OwnerID, reminder.DocumentTitle, data); err != nil {
+			log.Printf("[Reminders] Failed to record reminder %d in the activity feed: %v", *reminder.ID, err)
+		}
+		if reminder.Channel != nil {
+			go s.sendReminderNotification(reminder)
+		}
+	}
+}
+
+// runReminderNotifier periodically notifies reminders that came due
+func (s *Service) runReminderNotifier(ctx context.Context) {
+	log.Printf("[Reminders] Reminder notifier started - Interval: %s", s.config.ReminderInterval)
+	s.runDueReminders(ctx)
+
+	ticker := time.NewTicker(s.config.ReminderInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Printf("[Reminders] Reminder notifier stopped")
+			return
+		case <-ticker.C:
+			s.runDueReminders(ctx)
+		}
+	}
+}
+
+// sendReminderNotification delivers a due reminder through its channel
+func (s *Service) sendReminderNotification(reminder Reminder) {
+	switch *reminder.Channel {
+	case RuleChannelWebhook:
+		deliveryID := make([]byte, 8)
+		rand.Read(deliveryID)
+		payload, err := json.Marshal(ReminderNotification{
+			ID:            hex.EncodeToString(deliveryID),
+			Event:         EventReminderDue,
+			Timestamp:     time.Now().UTC().Format(time.RFC3339),
+			ReminderID:    *reminder.ID,
+			DocumentID:    reminder.DocumentID,
+			DocumentTitle: reminder.DocumentTitle,
+			DueAt:         reminder.DueAt,
+			Note:          reminder.Note,
+		})
+		if err != nil {
+			log.Printf("[Reminders] Failed to encode notification of reminder %d: %v", *reminder.ID, err)
+			return
+		}
+		s.postWebhookWithRetry(s.lifecycle, fmt.Sprintf("reminder %d", *reminder.ID), Webhook{URL: *reminder.Target, Secret: reminder.Secret}, EventReminderDue, payload)
+	case RuleChannelEmail:
+		body := fmt.Sprintf("The reminder on document \"%s\" (ID %d) is due since %s.\r\n", reminder.DocumentTitle, reminder.DocumentID, reminder.DueAt)
+		if reminder.Note != nil && *reminder.Note != "" {
+			body += "\r\n" + *reminder.Note + "\r\n"
+		}
+		if err := s.sendEmail(*reminder.Target, "Paperless reminder: "+reminder.DocumentTitle, body); err != nil {
+			log.Printf("[Reminders] Emailing reminder %d to %s failed: %v", *reminder.ID, *reminder.Target, err)
+			return
+		}
+		log.Printf("[Reminders] Emailed reminder %d to %s", *reminder.ID, *reminder.Target)
+	}
+}
+
+// reminderErrorStatus maps reminder errors to HTTP status codes
+func reminderErrorStatus(err error) int {
+	switch {
+	case strings.HasPrefix(err.Error(), "invalid"), strings.Contains(err.Error(), "required"):
+		return http.StatusBadRequest
+	case strings.Contains(err.Error(), "not found"):
+		return http.StatusNotFound
+	}
+	return queryErrorStatus(err)
+}
+
+// respondReminder sends a reminder without its secret
+func respondReminder(w http.ResponseWriter, status int, reminder *Reminder) {
+	reminder.Secret = nil
+	respondJSON(w, status, reminder)
+}
+
+// HTTP Handlers for reminders
+func (s *Service) handleListReminders(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	log.Printf("[Reminders] GET /api/reminders/ - Request from %s", r.RemoteAddr)
+
+	viewerID, err := viewerFromRequest(r)
+	if err != nil {
+		respondError(w, viewerErrorStatus(err), err.Error())
+		return
+	}
+
+	params := r.URL.Query()
+	withinDays := 0
+	if value := params.Get("within_days"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 {
+			respondError(w, http.StatusBadRequest, "Invalid within_days")
+			return
+		}
+		withinDays = parsed
+	}
+	documentID := 0
+	if value := params.Get("document_id"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 {
+			respondError(w, http.StatusBadRequest, "Invalid document_id")
+			return
+		}
+		documentID = parsed
+	}
+	userID, err := getUserIDFromRequest(r)
+	if err != nil {
+		respondError(w, viewerErrorStatus(err), err.Error())
+		return
+	}
+
+	reminders, err := s.ListReminders(ctx, *userID, viewerID, params.Get("status"), withinDays, documentID)
+	if err != nil {
+		log.Printf("[Reminders] Error listing reminders: %v", err)
+		respondError(w, reminderErrorStatus(err), err.Error())
+		return
+	}
+	for i := range reminders {
+		reminders[i].Secret = nil
+	}
+
+	respondJSON(w, http.StatusOK, ReminderListResponse{Count: len(reminders), Results: reminders})
+}
+
+func (s *Service) handleGetReminder(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	idStr := mux.Vars(r)["id"]
+	log.Printf("[Reminders] GET /api/reminders/%s/ - Request from %s", idStr, r.RemoteAddr)
+
+	viewerID, err := viewerFromRequest(r)
+	if err != nil {
+		respondError(w, viewerErrorStatus(err), err.Error())
+		return
+	}
+
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid reminder ID")
+		return
+	}
+	userID, err := getUserIDFromRequest(r)
+	if err != nil {
+		respondError(w, viewerErrorStatus(err), err.Error())
+		return
+	}
+
+	reminder, err := s.GetReminder(ctx, id, *userID, viewerID)
+	if err != nil {
+		respondError(w, reminderErrorStatus(err), err.Error())
+		return
+	}
+
+	respondReminder(w, http.StatusOK, reminder)
+}
+
+func (s *Service) handleCreateReminder(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	log.Printf("[Reminders] POST /api/reminders/ - Request from %s", r.RemoteAddr)
+
+	viewerID, err := viewerFromRequest(r)
+	if err != nil {
+		respondError(w, viewerErrorStatus(err), err.Error())
+		return
+	}
+
+	var reminder Reminder
+	if err := json.NewDecoder(r.Body).Decode(&reminder); err != nil {
+		respondError(w, http.StatusBadRequest, fmt.Sprintf("Invalid request body: %v", err))
+		return
+	}
+	userID, err := getUserIDFromRequest(r)
+	if err != nil {
+		respondError(w, viewerErrorStatus(err), err.Error())
+		return
+	}
+
+	created, err := s.CreateReminder(ctx, reminder, *userID, viewerID)
+	if err != nil {
+		log.Printf("[Reminders] Error creating reminder: %v", err)
+		respondError(w, reminderErrorStatus(err), err.Error())
+		return
+	}
+
+	respondReminder(w, http.StatusCreated, created)
+}
+
+func (s *Service) handleUpdateReminder(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	idStr := mux.Vars(r)["id"]
+	log.Printf("[Reminders] %s /api/reminders/%s/ - Request from %s", r.Method, idStr, r.RemoteAddr)
+
+	viewerID, err := viewerFromRequest(r)
+	if err != nil {
+		respondError(w, viewerErrorStatus(err), err.Error())
+		return
+	}
+
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid reminder ID")
+		return
+	}
+	var updates Reminder
+	if err := json.NewDecoder(r.Body).Decode(&updates); err != nil {
+		respondError(w, http.StatusBadRequest, fmt.Sprintf("Invalid request body: %v", err))
+		return
+	}
+	userID, err := getUserIDFromRequest(r)
+	if err != nil {
+		respondError(w, viewerErrorStatus(err), err.Error())
+		return
+	}
+
+	updated, err := s.UpdateReminder(ctx, id, updates, *userID, viewerID)
+	if err != nil {
+		log.Printf("[Reminders] Error updating reminder %d: %v", id, err)
+		respondError(w, reminderErrorStatus(err), err.Error())
+		return
+	}
+
+	respondReminder(w, http.StatusOK, updated)
+}
+
+func (s *Service) handleDeleteReminder(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	idStr := mux.Vars(r)["id"]
+	log.Printf("[Reminders] DELETE /api/reminders/%s/ - Request from %s", idStr, r.RemoteAddr)
+
+	viewerID, err := viewerFromRequest(r)
+	if err != nil {
+		respondError(w, viewerErrorStatus(err), err.Error())
+		return
+	}
+
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid reminder ID")
+		return
+	}
+	userID, err := getUserIDFromRequest(r)
+	if err != nil {
+		respondError(w, viewerErrorStatus(err), err.Error())
+		return
+	}
+
+	if err := s.DeleteReminder(ctx, id, *userID, viewerID); err != nil {
+		log.Printf("[Reminders] Error deleting reminder %d: %v", id, err)
+		respondError(w, reminderErrorStatus(err), err.Error())
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,550 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// reportPageSize is the number of documents read per query while exporting a view
+	reportPageSize = 500
+	// maxReportDocuments caps the documents of a CSV report
+	maxReportDocuments = 50000
+	// reportFacetLimit is the number of values listed per facet in a PDF report
+	reportFacetLimit = 25
+)
+
+// defaultReportColumns are exported when neither the report nor the view selects columns
+var defaultReportColumns = []string{"title", "created", "correspondent", "document_type", "tags"}
+
+// defaultReportFacets are summarized when the report does not select facets
+var defaultReportFacets = []string{"correspondent", "document_type", "tag"}
+
+// reportNameTables are the tables the IDs of name columns are resolved with
+var reportNameTables = map[string]string{
+	"correspondent": "SELECT id, name FROM documents_correspondent",
+	"document_type": "SELECT id, name FROM documents_documenttype",
+	"storage_path":  "SELECT id, name FROM documents_storagepath",
+	"owner":         "SELECT id, username FROM auth_user",
+	"tags":          "SELECT id, name FROM documents_tag",
+}
+
+// reportFile is a generated report
+type reportFile struct {
+	Name        string
+	ContentType string
+	Data        []byte
+	Documents   int
+}
+
+// reportColumns returns the CSV columns of a report: its own, the view's visible columns,
+// or the defaults
+func reportColumns(report *ScheduledReport, view *CustomView) []string {
+	if len(report.Columns) > 0 {
+		return report.Columns
+	}
+	builtin, tags, fieldIDs := viewColumns(view)
+	columns := append([]string{}, builtin...)
+	if tags {
+		columns = append(columns, "tags")
+	}
+	for _, fieldID := range fieldIDs {
+		columns = append(columns, fmt.Sprintf("custom_field_%d", fieldID))
+	}
+	if len(columns) == 0 {
+		return defaultReportColumns
+	}
+	return columns
+}
+
+// reportFileName names a report file after the report and the time it was generated
+func reportFileName(report *ScheduledReport, now time.Time) string {
+	slug := strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '-'
+	}, strings.ToLower(report.Name))
+	for strings.Contains(slug, "--") {
+		slug = strings.ReplaceAll(slug, "--", "-")
+	}
+	slug = strings.Trim(slug, "-")
+	if slug == "" {
+		slug = "report"
+	}
+	return fmt.Sprintf("report-%d-%s-%s.%s", *report.ID, slug, now.UTC().Format("20060102-150405"), report.Format)
+}
+
+// generateReport renders a report of the documents of a view that the report's owner can see
+func (s *Service) generateReport(ctx context.Context, report *ScheduledReport, view *CustomView, now time.Time) (*reportFile, error) {
+	viewerID := 0
+	if report.OwnerID != nil {
+		viewerID = *report.OwnerID
+	}
+	file := &reportFile{Name: reportFileName(report, now)}
+	var err error
+	if report.Format == "pdf" {
+		file.ContentType = "application/pdf"
+		file.Data, file.Documents, err = s.renderReportPDF(ctx, report, view, viewerID, now)
+	} else {
+		file.ContentType = "text/csv; charset=utf-8"
+		file.Data, file.Documents, err = s.renderReportCSV(ctx, report, view, viewerID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
+// renderReportCSV writes the documents of a view in the view's sort order with the report's
+// columns. Correspondents, document types, storage paths, owners and tags are written by
+// name, custom field columns are headed by the field name.
+func (s *Service) renderReportCSV(ctx context.Context, report *ScheduledReport, view *CustomView, viewerID int) ([]byte, int, error) {
+	columns := reportColumns(report, view)
+	export := *view
+	export.ColumnOrder = make([]interface{}, len(columns))
+	for i, column := range columns {
+		export.ColumnOrder[i] = column
+	}
+	export.ColumnVisibility = nil
+
+	names := make(map[string]map[int]string)
+	for _, column := range columns {
+		if query, ok := reportNameTables[column]; ok {
+			lookup, err := s.queryReportNames(ctx, query)
+			if err != nil {
+				return nil, 0, err
+			}
+			names[column] = lookup
+		}
+	}
+	fieldNames, err := s.queryReportNames(ctx, "SELECT id, name FROM documents_customfield")
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var buffer bytes.Buffer
+	writer := csv.NewWriter(&buffer)
+	header := []string{"id"}
+	for _, column := range columns {
+		if fieldID, err := strconv.Atoi(strings.TrimPrefix(column, "custom_field_")); err == nil && fieldNames[fieldID] != "" {
+			column = fieldNames[fieldID]
+		}
+		header = append(header, column)
+	}
+	writer.Write(header)
+
+	written := 0
+	for page := 1; written < maxReportDocuments; page++ {
+		documents, err := s.GetViewDocuments(ctx, &export, viewerID, Pagination{Page: page, PageSize: reportPageSize})
+		if err != nil {
+			return nil, 0, err
+		}
+		for _, document := range documents.Results {
+			record := []string{fmt.Sprint(document["id"])}
+			for _, column := range columns {
+				record = append(record, reportCell(document[column], names[column]))
+			}
+			writer.Write(record)
+			written++
+		}
+		if len(documents.Results) < reportPageSize || written >= documents.Count {
+			break
+		}
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return nil, 0, fmt.Errorf("failed to write CSV: %w", err)
+	}
+	return buffer.Bytes(), written, nil
+}
+
+// queryReportNames reads an (id, name) query into a lookup
+func (s *Service) queryReportNames(ctx context.Context, query string) (map[int]string, error) {
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query names: %w", err)
+	}
+	defer rows.Close()
+
+	names := make(map[int]string)
+	for rows.Next() {
+		var id int
+		var name sql.NullString
+		if err := rows.Scan(&id, &name); err != nil {
+			return nil, fmt.Errorf("failed to scan name: %w", err)
+		}
+		names[id] = name.String
+	}
+	return names, rows.Err()
+}
+
+// reportCell formats a column value for a CSV cell; IDs with a name in names are replaced
+// by it and lists are joined with "; "
+func reportCell(value interface{}, names map[int]string) string {
+	name := func(value interface{}) string {
+		if names != nil {
+			var id int
+			switch typed := value.(type) {
+			case int:
+				id = typed
+			case int64:
+				id = int(typed)
+			case float64:
+				id = int(typed)
+			default:
+				return fmt.Sprint(value)
+			}
+			if label, ok := names[id]; ok {
+				return label
+			}
+		}
+		return fmt.Sprint(value)
+	}
+
+	switch typed := value.(type) {
+	case nil:
+		return ""
+	case []int:
+		parts := make([]string, len(typed))
+		for i, item := range typed {
+			parts[i] = name(item)
+		}
+		return strings.Join(parts, "; ")
+	case []interface{}:
+		parts := make([]string, len(typed))
+		for i, item := range typed {
+			parts[i] = name(item)
+		}
+		return strings.Join(parts, "; ")
+	case []string:
+		return strings.Join(typed, "; ")
+	case bool:
+		return strconv.FormatBool(typed)
+	}
+	return name(value)
+}
+
+// renderReportPDF writes a summary of the documents of a view: their number and the counts
+// of the values of the report's facets, largest first
+func (s *Service) renderReportPDF(ctx context.Context, report *ScheduledReport, view *CustomView, viewerID int, now time.Time) ([]byte, int, error) {
+	where, args, err := s.viewDocumentCondition(ctx, view, viewerID)
+	if err != nil {
+		return nil, 0, err
+	}
+	count, err := s.countViewDocuments(ctx, where, args)
+	if err != nil {
+		return nil, 0, err
+	}
+	filterRulesJSON := ""
+	if len(view.FilterRules) > 0 {
+		data, err := json.Marshal(view.FilterRules)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to encode filter rules: %w", err)
+		}
+		filterRulesJSON = string(data)
+	}
+
+	lines := []pdfLine{
+		{Text: report.Name, Bold: true},
+		{Text: "View: " + view.Name},
+		{Text: "Generated: " + now.UTC().Format("2006-01-02 15:04 MST")},
+		{Text: fmt.Sprintf("Documents: %d", count)},
+	}
+	facets := report.Facets
+	if len(facets) == 0 {
+		facets = defaultReportFacets
+	}
+	for _, facet := range facets {
+		values, err := s.GetBuiltinFilterValues(ctx, facet, filterRulesJSON, false, false, ASNBucketing{}, viewerID)
+		if err != nil {
+			return nil, 0, err
+		}
+		sort.SliceStable(values, func(i, j int) bool { return values[i].Count > values[j].Count })
+		lines = append(lines, pdfLine{}, pdfLine{Text: strings.ReplaceAll(facet, "_", " "), Bold: true})
+		for i, value := range values {
+			if i == reportFacetLimit {
+				lines = append(lines, pdfLine{Text: fmt.Sprintf("... and %d more", len(values)-reportFacetLimit)})
+				break
+			}
+			lines = append(lines, pdfLine{Text: fmt.Sprintf("%8d   %s", value.Count, value.Label)})
+		}
+		if len(values) == 0 {
+			lines = append(lines, pdfLine{Text: "No documents"})
+		}
+	}
+	return renderPDF(lines), count, nil
+}
+
+// pdfLine is a line of text of a generated PDF
+type pdfLine struct {
+	Text string
+	Bold bool
+}
+
+// pdfLinesPerPage is the number of lines fitting an A4 page at the PDF leading
+const pdfLinesPerPage = 52
+
+// renderPDF lays out lines of text on A4 pages in Helvetica. Characters outside Latin-1 are
+// replaced by "?".
+func renderPDF(lines []pdfLine) []byte {
+	var pages [][]pdfLine
+	for len(lines) > pdfLinesPerPage {
+		pages = append(pages, lines[:pdfLinesPerPage])
+		lines = lines[pdfLinesPerPage:]
+	}
+	pages = append(pages, lines)
+
+	// Objects 1-4 are the catalog, the page tree and the fonts; each page adds a page and
+	// a content object
+	objects := []string{"", "", "<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>"}
+	kids := []string{}
+	for _, page := range pages {
+		var content bytes.Buffer
+		content.WriteString("BT\n14 TL\n50 792 Td\n")
+		for _, line := range page {
+			font := "/F1 10 Tf"
+			if line.Bold {
+				font = "/F2 11 Tf"
+			}
+			fmt.Fprintf(&content, "%s (%s) Tj T*\n", font, pdfEscape(line.Text))
+		}
+		content.WriteString("ET\n")
+
+		pageNumber := len(objects) + 1
+		kids = append(kids, fmt.Sprintf("%d 0 R", pageNumber))
+		objects = append(objects,
+			fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 595 842] /Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>", pageNumber+1),
+			fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", content.Len(), content.String()))
+	}
+	objects[0] = "<< /Type /Catalog /Pages 2 0 R >>"
+	objects[1] = fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages))
+
+	var buffer bytes.Buffer
+	buffer.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, object := range objects {
+		offsets[i] = buffer.Len()
+		fmt.Fprintf(&buffer, "%d 0 obj\n%s\nendobj\n", i+1, object)
+	}
+	xref := buffer.Len()
+	fmt.Fprintf(&buffer, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buffer, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buffer, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return buffer.Bytes()
+}
+
+// pdfEscape encodes text as a Latin-1 PDF string literal body
+func pdfEscape(text string) string {
+	var escaped strings.Builder
+	for _, r := range text {
+		switch {
+		case r == '\\' || r == '(' || r == ')':
+			escaped.WriteByte('\\')
+			escaped.WriteByte(byte(r))
+		case r < 0x20 || (r >= 0x7f && r < 0xa0) || r > 0xff:
+			escaped.WriteByte('?')
+		default:
+			escaped.WriteByte(byte(r))
+		}
+	}
+	return escaped.String()
+}
+
+// deliverReport sends a generated report to the destination of its schedule and returns
+// where it went: the file path, the object URL or the email address
+func (s *Service) deliverReport(ctx context.Context, report *ScheduledReport, view *CustomView, file *reportFile) (string, error) {
+	switch report.Destination {
+	case ReportDestinationDirectory:
+		if err := os.MkdirAll(s.config.ReportDirectory, 0o755); err != nil {
+			return "", fmt.Errorf("failed to deliver report: %w", err)
+		}
+		path := filepath.Join(s.config.ReportDirectory, file.Name)
+		if err := os.WriteFile(path, file.Data, 0o644); err != nil {
+			return "", fmt.Errorf("failed to deliver report: %w", err)
+		}
+		return path, nil
+	case ReportDestinationS3:
+		key := file.Name
+		if prefix := strings.Trim(s.config.ReportS3Prefix, "/"); prefix != "" {
+			key = prefix + "/" + key
+		}
+		location, err := s.putS3Object(ctx, key, file.ContentType, file.Data)
+		if err != nil {
+			return "", fmt.Errorf("failed to deliver report: %w", err)
+		}
+		return location, nil
+	case ReportDestinationEmail:
+		// The allowed addresses or the owner's address may have changed since the schedule
+		// was created
+		ownerID := 0
+		if report.OwnerID != nil {
+			ownerID = *report.OwnerID
+		}
+		if err := s.checkReportRecipient(ctx, report, ownerID); err != nil {
+			return "", fmt.Errorf("failed to deliver report: %w", err)
+		}
+		body := fmt.Sprintf("Report %q of the view %q: %d documents.\n\nThe report is attached as %s.\n", report.Name, view.Name, file.Documents, file.Name)
+		if err := s.sendAttachmentEmail(*report.Target, "Paperless report: "+report.Name, body, file); err != nil {
+			return "", fmt.Errorf("failed to deliver report: %w", err)
+		}
+		return *report.Target, nil
+	}
+	return "", fmt.Errorf("invalid destination: %s", report.Destination)
+}
+
+// sendAttachmentEmail sends a plain text email with a file attached
+func (s *Service) sendAttachmentEmail(to string, subject string, body string, file *reportFile) error {
+	boundary := fmt.Sprintf("paperless-link-%d", time.Now().UnixNano())
+	var message strings.Builder
+	message.WriteString("--" + boundary + "\r\n")
+	message.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	message.WriteString(body + "\r\n")
+	message.WriteString("--" + boundary + "\r\n")
+	message.WriteString("Content-Type: " + file.ContentType + "\r\n")
+	message.WriteString("Content-Transfer-Encoding: base64\r\n")
+	message.WriteString("Content-Disposition: attachment; filename=\"" + file.Name + "\"\r\n\r\n")
+	encoded := base64.StdEncoding.EncodeToString(file.Data)
+	for len(encoded) > 76 {
+		message.WriteString(encoded[:76] + "\r\n")
+		encoded = encoded[76:]
+	}
+	message.WriteString(encoded + "\r\n")
+	message.WriteString("--" + boundary + "--\r\n")
+	return s.sendMail(to, subject, "multipart/mixed; boundary=\""+boundary+"\"", message.String())
+}
+
+// putS3Object uploads an object to the report bucket with a Signature Version 4 signed PUT
+// and returns its URL
+func (s *Service) putS3Object(ctx context.Context, key string, contentType string, data []byte) (string, error) {
+	region := s.config.ReportS3Region
+	scheme, host, path := "https", fmt.Sprintf("%s.s3.%s.amazonaws.com", s.config.ReportS3Bucket, region), "/"+key
+	if s.config.ReportS3Endpoint != "" {
+		endpoint, err := url.Parse(s.config.ReportS3Endpoint)
+		if err != nil || endpoint.Host == "" {
+			return "", fmt.Errorf("invalid REPORT_S3_ENDPOINT %q", s.config.ReportS3Endpoint)
+		}
+		scheme, host = endpoint.Scheme, endpoint.Host
+		path = strings.TrimSuffix(endpoint.Path, "/") + "/" + s.config.ReportS3Bucket + "/" + key
+	}
+	location := scheme + "://" + host + s3EscapePath(path)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, location, bytes.NewReader(data))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", contentType)
+	payloadHash := sha256Hex(data)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	signRequestV4(req, payloadHash, region, "s3", s.config.ReportS3AccessKey, s.config.ReportS3SecretKey, time.Now())
+
+	client := &http.Client{Timeout: time.Minute}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return "", fmt.Errorf("S3 upload returned %d: %s", resp.StatusCode, strings.TrimSpace(string(detail)))
+	}
+	return location, nil
+}
+
+// signRequestV4 signs a request without a query string with Signature Version 4: it sets
+// the X-Amz-Date and Authorization headers, signing the host and every header of the request
+func signRequestV4(req *http.Request, payloadHash string, region string, service string, accessKey string, secretKey string, now time.Time) {
+	now = now.UTC()
+	amzDate := now.Format("20060102T150405Z")
+	day := now.Format("20060102")
+	req.Header.Set("X-Amz-Date", amzDate)
+
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	headers := map[string]string{"host": host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.Join(strings.Fields(strings.Join(values, ",")), " ")
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		s3EscapePath(req.URL.Path),
+		"",
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	scope := day + "/" + region + "/" + service + "/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+	signature := hex.EncodeToString(hmacSHA256(signingKeyV4(secretKey, day, region, service), stringToSign))
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		accessKey, scope, signedHeaders, signature))
+}
+
+// signingKeyV4 derives the Signature Version 4 key of a day, region and service
+func signingKeyV4(secretKey string, day string, region string, service string) []byte {
+	key := hmacSHA256([]byte("AWS4"+secretKey), day)
+	for _, part := range []string{region, service, "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	return key
+}
+
+// s3EscapePath percent-encodes a path the way Signature Version 4 expects: everything but
+// unreserved characters and slashes
+func s3EscapePath(path string) string {
+	var escaped strings.Builder
+	for i := 0; i < len(path); i++ {
+		c := path[i]
+		if (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') || strings.IndexByte("-._~/", c) >= 0 {
+			escaped.WriteByte(c)
+		} else {
+			fmt.Fprintf(&escaped, "%%%02X", c)
+		}
+	}
+	return escaped.String()
+}
+
+// sha256Hex returns the hex encoded SHA-256 of data
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// hmacSHA256 returns the HMAC-SHA256 of data with key
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,687 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/mail"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Report destinations
+const (
+	ReportDestinationDirectory = "directory" // A file in REPORT_DIR
+	ReportDestinationS3        = "s3"        // An object in REPORT_S3_BUCKET
+	ReportDestinationEmail     = "email"     // An attachment emailed to the report's target
+)
+
+// Outcomes of the last run of a report
+const (
+	ReportStatusOK     = "ok"
+	ReportStatusFailed = "failed"
+)
+
+// scanReport scans a scheduled report row selected by queryScheduledReports
+func (s *Service) scanReport(rows *sql.Rows) (*ScheduledReport, error) {
+	var report ScheduledReport
+	var id, ownerID sql.NullInt64
+	var columnsJSON, facetsJSON, target, lastStatus, lastError, lastOutput sql.NullString
+	var nextRun, lastRun, created, modified dbTimestamp
+	if err := rows.Scan(&id, &report.ViewID, &report.Name, &report.Format, &columnsJSON, &facetsJSON, &report.Destination,
+		&target, &report.Interval, &nextRun, &lastRun, &lastStatus, &lastError, &lastOutput, &ownerID, &created, &modified); err != nil {
+		return nil, fmt.Errorf("failed to scan report: %w", err)
+	}
+	reportID := int(id.Int64)
+	report.ID = &reportID
+	if columnsJSON.Valid {
+		json.Unmarshal([]byte(columnsJSON.String), &report.Columns)
+	}
+	if facetsJSON.Valid {
+		json.Unmarshal([]byte(facetsJSON.String), &report.Facets)
+	}
+	if target.Valid {
+		report.Target = &target.String
+	}
+	if lastStatus.Valid {
+		report.LastStatus = &lastStatus.String
+	}
+	if lastError.Valid {
+		report.LastError = &lastError.String
+	}
+	if lastOutput.Valid {
+		report.LastOutput = &lastOutput.String
+	}
+	if ownerID.Valid {
+		owner := int(ownerID.Int64)
+		report.OwnerID = &owner
+	}
+	// Schedule times are stored in UTC without a zone, so they are always written as RFC 3339
+	if nextRun.Valid {
+		formatted := nextRun.Time.UTC().Format(time.RFC3339)
+		report.NextRun = &formatted
+	}
+	if lastRun.Valid {
+		formatted := lastRun.Time.UTC().Format(time.RFC3339)
+		report.LastRun = &formatted
+	}
+	report.Created = s.formatTimestamp(created)
+	report.Modified = s.formatTimestamp(modified)
+	return &report, nil
+}
+
+// queryScheduledReports retrieves the reports selected by condition
+func (s *Service) queryScheduledReports(ctx context.Context, condition string, args ...interface{}) ([]ScheduledReport, error) {
+	rows, err := s.db.QueryContext(ctx, s.rebind(`
+		SELECT id, view_id, name, format, columns, facets, destination, target, schedule_interval,
+			next_run, last_run, last_status, last_error, last_output, owner_id, created, modified
+		FROM scheduled_reports
+		WHERE `+condition+`
+		ORDER BY name ASC, id ASC
+	`), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query reports: %w", err)
+	}
+	defer rows.Close()
+
+	reports := []ScheduledReport{}
+	for rows.Next() {
+		report, err := s.scanReport(rows)
+		if err != nil {
+			return nil, err
+		}
+		reports = append(reports, *report)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read reports: %w", err)
+	}
+	return reports, nil
+}
+
+// ListScheduledReports retrieves the reports of a user
+func (s *Service) ListScheduledReports(ctx context.Context, userID int) ([]ScheduledReport, error) {
+	return s.queryScheduledReports(ctx, "owner_id = ?", userID)
+}
+
+// GetScheduledReport retrieves a report of a user; reports of other users are not found
+func (s *Service) GetScheduledReport(ctx context.Context, id int, userID int) (*ScheduledReport, error) {
+	reports, err := s.queryScheduledReports(ctx, "id = ? AND owner_id = ?", id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(reports) == 0 {
+		return nil, fmt.Errorf("report with id %d not found", id)
+	}
+	return &reports[0], nil
+}
+
+// checkScheduledReport normalizes and validates a report before it is stored. Custom field
+// columns may be given by ID and are stored as "custom_field_<id>".
+func (s *Service) checkScheduledReport(report *ScheduledReport) error {
+	report.Name = strings.TrimSpace(report.Name)
+	if report.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if report.ViewID <= 0 {
+		return fmt.Errorf("view_id is required")
+	}
+
+	report.Format = strings.ToLower(strings.TrimSpace(report.Format))
+	if report.Format == "" {
+		report.Format = "csv"
+	}
+	if report.Format != "csv" && report.Format != "pdf" {
+		return fmt.Errorf("invalid format: use csv or pdf")
+	}
+	for i, column := range report.Columns {
+		if _, err := strconv.Atoi(column); err == nil {
+			column = "custom_field_" + column
+			report.Columns[i] = column
+		}
+		_, builtin := viewDocumentColumns[column]
+		_, fieldErr := strconv.Atoi(strings.TrimPrefix(column, "custom_field_"))
+		if !builtin && column != "tags" && (!strings.HasPrefix(column, "custom_field_") || fieldErr != nil) {
+			return fmt.Errorf("invalid column %q", column)
+		}
+	}
+	for _, facet := range report.Facets {
+		if _, ok := lookupFacetProvider(facet); !ok {
+			return fmt.Errorf("invalid facet %q (supported: %s)", facet, strings.Join(registeredFacetTypes(), ", "))
+		}
+	}
+
+	switch report.Destination {
+	case ReportDestinationDirectory:
+		if s.config.ReportDirectory == "" {
+			return fmt.Errorf("invalid destination: REPORT_DIR is not configured")
+		}
+	case ReportDestinationS3:
+		if s.config.ReportS3Bucket == "" || s.config.ReportS3AccessKey == "" || s.config.ReportS3SecretKey == "" {
+			return fmt.Errorf("invalid destination: REPORT_S3_BUCKET and its credentials are not configured")
+		}
+	case ReportDestinationEmail:
+		if s.config.SMTPHost == "" {
+			return fmt.Errorf("invalid destination: SMTP_HOST is not configured")
+		}
+		if report.Target == nil || strings.TrimSpace(*report.Target) == "" {
+			return fmt.Errorf("target is required for the email destination")
+		}
+		address, err := mail.ParseAddress(strings.TrimSpace(*report.Target))
+		if err != nil {
+			return fmt.Errorf("invalid target: %v", err)
+		}
+		report.Target = &address.Address
+	default:
+		return fmt.Errorf("invalid destination: use directory, s3 or email")
+	}
+	if report.Destination != ReportDestinationEmail {
+		report.Target = nil
+	}
+
+	if _, err := snapshotIntervalStep(report.Interval); err != nil {
+		return err
+	}
+	return nil
+}
+
+// reportRecipientAllowed reports whether a report may be emailed to address: the owner's
+// own address, or an address or "@domain" of the comma-separated allowed list
+func reportRecipientAllowed(address string, ownerEmail string, allowed string) bool {
+	address = strings.ToLower(strings.TrimSpace(address))
+	if ownerEmail != "" && address == strings.ToLower(strings.TrimSpace(ownerEmail)) {
+		return true
+	}
+	for _, entry := range strings.Split(allowed, ",") {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if entry == "" {
+			continue
+		}
+		if strings.HasPrefix(entry, "@") {
+			if strings.HasSuffix(address, entry) {
+				return true
+			}
+		} else if address == entry {
+			return true
+		}
+	}
+	return false
+}
+
+// checkReportRecipient refuses email reports to addresses other than the owner's own and
+// those of REPORT_EMAIL_ALLOWED, so reports cannot be sent to arbitrary addresses
+func (s *Service) checkReportRecipient(ctx context.Context, report *ScheduledReport, ownerID int) error {
+	if report.Destination != ReportDestinationEmail || report.Target == nil {
+		return nil
+	}
+	var ownerEmail sql.NullString
+	err := s.db.QueryRowContext(ctx, s.rebind("SELECT email FROM auth_user WHERE id = ?"), ownerID).Scan(&ownerEmail)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("failed to query report owner: %w", err)
+	}
+	if !reportRecipientAllowed(*report.Target, ownerEmail.String, s.config.ReportEmailAllowed) {
+		return fmt.Errorf("permission denied: reports can only be emailed to the owner's address or those of REPORT_EMAIL_ALLOWED")
+	}
+	return nil
+}
+
+// reportNextRun returns the first run of a schedule: next_run when given, otherwise one
+// interval from now
+func reportNextRun(report *ScheduledReport) (time.Time, error) {
+	if report.NextRun != nil && *report.NextRun != "" {
+		return parseSnapshotTime(*report.NextRun)
+	}
+	step, err := snapshotIntervalStep(report.Interval)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return step(time.Now().UTC()), nil
+}
+
+// CreateScheduledReport schedules a report of a view the user can see
+func (s *Service) CreateScheduledReport(ctx context.Context, report ScheduledReport, userID int) (*ScheduledReport, error) {
+	log.Printf("[Reports] CreateScheduledReport - ViewID: %d, Format: %s, Destination: %s, UserID: %d", report.ViewID, report.Format, report.Destination, userID)
+	if err := s.checkScheduledReport(&report); err != nil {
+		return nil, err
+	}
+	if err := s.checkReportRecipient(ctx, &report, userID); err != nil {
+		return nil, err
+	}
+	if _, err := s.accessibleCustomView(ctx, report.ViewID, userID); err != nil {
+		return nil, err
+	}
+	nextRun, err := reportNextRun(&report)
+	if err != nil {
+		return nil, err
+	}
+	columnsJSON, _ := json.Marshal(report.Columns)
+	facetsJSON, _ := json.Marshal(report.Facets)
+
+	var id int64
+	if s.config.DBEngine == "postgresql" || s.config.DBEngine == "postgres" {
+		err := s.db.QueryRowContext(ctx, `
+			INSERT INTO scheduled_reports (view_id, name, format, columns, facets, destination, target, schedule_interval, next_run, owner_id)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+			RETURNING id
+		`, report.ViewID, report.Name, report.Format, string(columnsJSON), string(facetsJSON), report.Destination, report.Target,
+			report.Interval, nextRun.Format(snapshotTimeLayout), userID).Scan(&id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create report: %w", err)
+		}
+	} else {
+		result, err := s.db.ExecContext(ctx, `
+			INSERT INTO scheduled_reports (view_id, name, format, columns, facets, destination, target, schedule_interval, next_run, owner_id)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`, report.ViewID, report.Name, report.Format, string(columnsJSON), string(facetsJSON), report.Destination, report.Target,
+			report.Interval, nextRun.Format(snapshotTimeLayout), userID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create report: %w", err)
+		}
+		if id, err = result.LastInsertId(); err != nil {
+			return nil, fmt.Errorf("failed to get report ID: %w", err)
+		}
+	}
+
+	return s.GetScheduledReport(ctx, int(id), userID)
+}
+
+// UpdateScheduledReport updates the provided fields of a user's report. A new interval
+// without a next_run restarts the schedule from now.
+func (s *Service) UpdateScheduledReport(ctx context.Context, id int, updates ScheduledReport, userID int) (*ScheduledReport, error) {
+	log.Printf("[Reports] UpdateScheduledReport - ID: %d, UserID: %d", id, userID)
+	existing, err := s.GetScheduledReport(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	merged := *existing
+	if updates.ViewID != 0 {
+		merged.ViewID = updates.ViewID
+	}
+	if updates.Name != "" {
+		merged.Name = updates.Name
+	}
+	if updates.Format != "" {
+		merged.Format = updates.Format
+	}
+	if updates.Columns != nil {
+		merged.Columns = updates.Columns
+	}
+	if updates.Facets != nil {
+		merged.Facets = updates.Facets
+	}
+	if updates.Destination != "" {
+		merged.Destination = updates.Destination
+	}
+	if updates.Target != nil {
+		merged.Target = updates.Target
+	}
+	if updates.Interval != "" {
+		merged.Interval = updates.Interval
+	}
+	// A new interval restarts the schedule unless a next_run is given
+	if updates.NextRun != nil {
+		merged.NextRun = updates.NextRun
+	} else if updates.Interval != "" {
+		merged.NextRun = nil
+	}
+	if err := s.checkScheduledReport(&merged); err != nil {
+		return nil, err
+	}
+	if err := s.checkReportRecipient(ctx, &merged, userID); err != nil {
+		return nil, err
+	}
+	if merged.ViewID != existing.ViewID {
+		if _, err := s.accessibleCustomView(ctx, merged.ViewID, userID); err != nil {
+			return nil, err
+		}
+	}
+	nextRun, err := reportNextRun(&merged)
+	if err != nil {
+		return nil, err
+	}
+	columnsJSON, _ := json.Marshal(merged.Columns)
+	facetsJSON, _ := json.Marshal(merged.Facets)
+
+	_, err = s.db.ExecContext(ctx, s.rebind(`
+		UPDATE scheduled_reports
+		SET view_id = ?, name = ?, format = ?, columns = ?, facets = ?, destination = ?, target = ?,
+			schedule_interval = ?, next_run = ?, modified = CURRENT_TIMESTAMP
+		WHERE id = ?
+	`), merged.ViewID, merged.Name, merged.Format, string(columnsJSON), string(facetsJSON), merged.Destination, merged.Target,
+		merged.Interval, nextRun.Format(snapshotTimeLayout), id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update report: %w", err)
+	}
+
+	return s.GetScheduledReport(ctx, id, userID)
+}
+
+// DeleteScheduledReport deletes a user's report; files already delivered are kept
+func (s *Service) DeleteScheduledReport(ctx context.Context, id int, userID int) error {
+	result, err := s.db.ExecContext(ctx, s.rebind("DELETE FROM scheduled_reports WHERE id = ? AND owner_id = ?"), id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete report: %w", err)
+	}
+	if deleted, _ := result.RowsAffected(); deleted == 0 {
+		return fmt.Errorf("report with id %d not found", id)
+	}
+	return nil
+}
+
+// reportView returns the view of a report if its owner can still see it
+func (s *Service) reportView(ctx context.Context, report *ScheduledReport) (*CustomView, error) {
+	if report.OwnerID == nil {
+		return s.GetCustomView(ctx, report.ViewID)
+	}
+	return s.accessibleCustomView(ctx, report.ViewID, *report.OwnerID)
+}
+
+// RunScheduledReport generates a report and delivers it to its destination, recording the
+// outcome on the report. The schedule itself is left alone.
+func (s *Service) RunScheduledReport(ctx context.Context, report *ScheduledReport) error {
+	now := time.Now().UTC()
+	output, runErr := s.produceReport(ctx, report, now)
+
+	status, lastError, lastOutput := ReportStatusOK, (*string)(nil), &output
+	if runErr != nil {
+		log.Printf("[Reports] Report %d failed: %v", *report.ID, runErr)
+		message := runErr.Error()
+		status, lastError, lastOutput = ReportStatusFailed, &message, nil
+	}
+	if _, err := s.db.ExecContext(ctx, s.rebind("UPDATE scheduled_reports SET last_run = ?, last_status = ?, last_error = ?, last_output = ? WHERE id = ?"),
+		now.Format(snapshotTimeLayout), status, lastError, lastOutput, *report.ID); err != nil {
+		log.Printf("[Reports] Failed to record run of report %d: %v", *report.ID, err)
+	}
+	return runErr
+}
+
+// produceReport generates a report and delivers it, returning where it went
+func (s *Service) produceReport(ctx context.Context, report *ScheduledReport, now time.Time) (string, error) {
+	view, err := s.reportView(ctx, report)
+	if err != nil {
+		return "", err
+	}
+	file, err := s.generateReport(ctx, report, view, now)
+	if err != nil {
+		return "", err
+	}
+	output, err := s.deliverReport(ctx, report, view, file)
+	if err != nil {
+		return "", err
+	}
+	log.Printf("[Reports] Delivered report %d (%d documents, %d bytes) to %s", *report.ID, file.Documents, len(file.Data), output)
+	return output, nil
+}
+
+// runDueReports runs the reports whose schedule is due. Each schedule is moved past now
+// before its report runs, so a failing destination is retried at the next interval
+// instead of on every check. Reports of views in the trash are skipped until they are
+// restored.
+func (s *Service) runDueReports(ctx context.Context) {
+	now := time.Now().UTC()
+	due, err := s.queryScheduledReports(ctx, `next_run <= ? AND view_id IN (SELECT id FROM custom_views WHERE deleted_at IS NULL)`,
+		now.Format(snapshotTimeLayout))
+	if err != nil {
+		log.Printf("[Reports] Failed to query due reports: %v", err)
+		return
+	}
+
+	for i := range due {
+		report := &due[i]
+		// Runs missed while the service was down are not caught up
+		step, err := snapshotIntervalStep(report.Interval)
+		if err != nil {
+			log.Printf("[Reports] Invalid interval of report %d: %v", *report.ID, err)
+			continue
+		}
+		next, err := parseSnapshotTime(*report.NextRun)
+		if err != nil {
+			log.Printf("[Reports] Invalid next run of report %d: %v", *report.ID, err)
+			continue
+		}
+		for !next.After(now) {
+			next = step(next)
+		}
+		if _, err := s.db.ExecContext(ctx, s.rebind("UPDATE scheduled_reports SET next_run = ? WHERE id = ?"),
+			next.Format(snapshotTimeLayout), *report.ID); err != nil {
+			log.Printf("[Reports] Failed to advance schedule of report %d: %v", *report.ID, err)
+			continue
+		}
+		s.RunScheduledReport(ctx, report)
+	}
+}
+
+// runReportScheduler periodically runs the due reports
+func (s *Service) runReportScheduler(ctx context.Context) {
+	log.Printf("[Reports] Scheduler started - Check interval: %s", s.config.ReportInterval)
+	s.runDueReports(ctx)
+
+	ticker := time.NewTicker(s.config.ReportInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Printf("[Reports] Scheduler stopped")
+			return
+		case <-ticker.C:
+			s.runDueReports(ctx)
+		}
+	}
+}
+
+// reportErrorStatus maps report errors to HTTP status codes
+func reportErrorStatus(err error) int {
+	switch {
+	case strings.HasPrefix(err.Error(), "invalid"), strings.Contains(err.Error(), "required"):
+		return http.StatusBadRequest
+	case strings.Contains(err.Error(), "permission denied"):
+		return http.StatusForbidden
+	case strings.Contains(err.Error(), "not found"):
+		return http.StatusNotFound
+	case strings.HasPrefix(err.Error(), "failed to deliver"):
+		return http.StatusBadGateway
+	}
+	return queryErrorStatus(err)
+}
+
+// reportRequest reads the report ID and the user of a report request. It answers the
+// request itself when it returns false.
+func reportRequest(w http.ResponseWriter, r *http.Request) (int, int, bool) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid report ID")
+		return 0, 0, false
+	}
+	userID, err := viewerFromRequest(r)
+	if err != nil {
+		respondError(w, viewerErrorStatus(err), err.Error())
+		return 0, 0, false
+	}
+	return id, userID, true
+}
+
+// HTTP Handlers for scheduled reports
+func (s *Service) handleListScheduledReports(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	log.Printf("[Reports] GET /api/reports/ - Request from %s", r.RemoteAddr)
+
+	userID, err := viewerFromRequest(r)
+	if err != nil {
+		respondError(w, viewerErrorStatus(err), err.Error())
+		return
+	}
+
+	reports, err := s.ListScheduledReports(ctx, userID)
+	if err != nil {
+		log.Printf("[Reports] Error listing reports: %v", err)
+		respondError(w, queryErrorStatus(err), err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, ScheduledReportListResponse{Count: len(reports), Results: reports})
+}
+
+func (s *Service) handleGetScheduledReport(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	log.Printf("[Reports] GET /api/reports/%s/ - Request from %s", mux.Vars(r)["id"], r.RemoteAddr)
+
+	id, userID, ok := reportRequest(w, r)
+	if !ok {
+		return
+	}
+
+	report, err := s.GetScheduledReport(ctx, id, userID)
+	if err != nil {
+		respondError(w, reportErrorStatus(err), err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, report)
+}
+
+func (s *Service) handleCreateScheduledReport(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	log.Printf("[Reports] POST /api/reports/ - Request from %s", r.RemoteAddr)
+
+	var report ScheduledReport
+	if err := json.NewDecoder(r.Body).Decode(&report); err != nil {
+		respondError(w, http.StatusBadRequest, fmt.Sprintf("Invalid request body: %v", err))
+		return
+	}
+	userID, err := viewerFromRequest(r)
+	if err != nil {
+		respondError(w, viewerErrorStatus(err), err.Error())
+		return
+	}
+
+	created, err := s.CreateScheduledReport(ctx, report, userID)
+	if err != nil {
+		log.Printf("[Reports] Error creating report: %v", err)
+		respondError(w, reportErrorStatus(err), err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, created)
+}
+
+func (s *Service) handleUpdateScheduledReport(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	log.Printf("[Reports] %s /api/reports/%s/ - Request from %s", r.Method, mux.Vars(r)["id"], r.RemoteAddr)
+
+	id, userID, ok := reportRequest(w, r)
+	if !ok {
+		return
+	}
+	var updates ScheduledReport
+	if err := json.NewDecoder(r.Body).Decode(&updates); err != nil {
+		respondError(w, http.StatusBadRequest, fmt.Sprintf("Invalid request body: %v", err))
+		return
+	}
+
+	updated, err := s.UpdateScheduledReport(ctx, id, updates, userID)
+	if err != nil {
+		log.Printf("[Reports] Error updating report %d: %v", id, err)
+		respondError(w, reportErrorStatus(err), err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, updated)
+}
+
+func (s *Service) handleDeleteScheduledReport(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	log.Printf("[Reports] DELETE /api/reports/%s/ - Request from %s", mux.Vars(r)["id"], r.RemoteAddr)
+
+	id, userID, ok := reportRequest(w, r)
+	if !ok {
+		return
+	}
+
+	if err := s.DeleteScheduledReport(ctx, id, userID); err != nil {
+		log.Printf("[Reports] Error deleting report %d: %v", id, err)
+		respondError(w, reportErrorStatus(err), err.Error())
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Service) handleRunScheduledReport(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	log.Printf("[Reports] POST /api/reports/%s/run/ - Request from %s", mux.Vars(r)["id"], r.RemoteAddr)
+
+	id, userID, ok := reportRequest(w, r)
+	if !ok {
+		return
+	}
+
+	report, err := s.GetScheduledReport(ctx, id, userID)
+	if err != nil {
+		respondError(w, reportErrorStatus(err), err.Error())
+		return
+	}
+	if err := s.RunScheduledReport(ctx, report); err != nil {
+		respondError(w, reportErrorStatus(err), err.Error())
+		return
+	}
+
+	report, err = s.GetScheduledReport(ctx, id, userID)
+	if err != nil {
+		respondError(w, reportErrorStatus(err), err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, report)
+}
+
+func (s *Service) handleDownloadScheduledReport(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	log.Printf("[Reports] GET /api/reports/%s/download/ - Request from %s", mux.Vars(r)["id"], r.RemoteAddr)
+
+	id, userID, ok := reportRequest(w, r)
+	if !ok {
+		return
+	}
+
+	report, err := s.GetScheduledReport(ctx, id, userID)
+	if err != nil {
+		respondError(w, reportErrorStatus(err), err.Error())
+		return
+	}
+	view, err := s.reportView(ctx, report)
+	if err != nil {
+		respondError(w, reportErrorStatus(err), err.Error())
+		return
+	}
+	file, err := s.generateReport(ctx, report, view, time.Now())
+	if err != nil {
+		log.Printf("[Reports] Error generating report %d: %v", id, err)
+		respondError(w, reportErrorStatus(err), err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", file.ContentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", file.Name))
+	w.WriteHeader(http.StatusOK)
+	w.Write(file.Data)
+}
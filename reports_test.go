@@ -0,0 +1,68 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestReportRecipientAllowed(t *testing.T) {
+	tests := []struct {
+		address string
+		owner   string
+		allowed string
+		want    bool
+	}{
+		{"me@example.com", "me@example.com", "", true},
+		{"Me@Example.com", "me@example.com", "", true},
+		{"someone@example.com", "me@example.com", "", false},
+		{"someone@example.com", "", "", false},
+		{"accounts@partner.org", "", "@example.com, accounts@partner.org", true},
+		{"sales@partner.org", "", "@example.com, accounts@partner.org", false},
+		{"team@example.com", "", "@example.com", true},
+		{"team@notexample.com", "", "@example.com", false},
+		{"team@example.com.evil.org", "", "@example.com", false},
+		{"", "", ",", false},
+	}
+	for _, tt := range tests {
+		if got := reportRecipientAllowed(tt.address, tt.owner, tt.allowed); got != tt.want {
+			t.Errorf("%q (owner %q, allowed %q): %v, want %v", tt.address, tt.owner, tt.allowed, got, tt.want)
+		}
+	}
+}
+
+func TestCreateScheduledReportChecksUserAndRecipient(t *testing.T) {
+	s := newTestService(t, func(config *Config) {
+		config.SMTPHost = "smtp.example.com"
+		config.ReportEmailAllowed = "@example.com"
+	})
+	mustExec(t, s, `INSERT INTO auth_user (id, username, email, is_superuser) VALUES (1, 'admin', 'admin@paperless.local', 1)`)
+
+	create := func(header string, target string) int {
+		body := `{"view_id": 1, "name": "Invoices", "format": "csv", "destination": "email", "target": "` + target + `", "interval": "daily"}`
+		req := httptest.NewRequest(http.MethodPost, "/api/reports/", strings.NewReader(body))
+		if header != "" {
+			req.Header.Set("X-User-ID", header)
+		}
+		rec := httptest.NewRecorder()
+		s.handleCreateScheduledReport(rec, req)
+		return rec.Code
+	}
+
+	if status := create("", "admin@paperless.local"); status != http.StatusUnauthorized {
+		t.Errorf("without X-User-ID: status %d, want 401", status)
+	}
+	if status := create("admin", "admin@paperless.local"); status != http.StatusBadRequest {
+		t.Errorf("invalid X-User-ID: status %d, want 400", status)
+	}
+	if status := create("1", "attacker@elsewhere.net"); status != http.StatusForbidden {
+		t.Errorf("foreign recipient: status %d, want 403", status)
+	}
+	// Allowed recipients pass the check and fail on the missing view instead
+	for _, target := range []string{"admin@paperless.local", "accounts@example.com"} {
+		if status := create("1", target); status != http.StatusNotFound {
+			t.Errorf("%s: status %d, want 404 for the missing view", target, status)
+		}
+	}
+}
@@ -0,0 +1,654 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// EventSavedSearchMatches is the event of saved search notifications sent by webhook
+const EventSavedSearchMatches = "saved_search.new_matches"
+
+// savedSearchColumns are the columns read by scanSavedSearch
+const savedSearchColumns = "id, name, filter_rules, webhook_url, secret, last_seen_document_id, last_checked, last_notified_document_id, last_triggered, owner_id, created, modified"
+
+// SavedSearchDocument is a document newly matching a saved search
+type SavedSearchDocument struct {
+	ID      int     `json:"id"`
+	Title   string  `json:"title"`
+	Created *string `json:"created,omitempty"`
+	Added   *string `json:"added,omitempty"`
+}
+
+// SavedSearchNewResponse lists the documents matching a saved search with an ID above Since
+// and up to Until, the watermark of the check. Results are capped; Count is the full number.
+type SavedSearchNewResponse struct {
+	SavedSearchID int                   `json:"saved_search_id"`
+	Since         int                   `json:"since"`
+	Until         int                   `json:"until"`
+	Count         int                   `json:"count"`
+	Results       []SavedSearchDocument `json:"results"`
+}
+
+// SavedSearchNotification is the JSON body posted to the webhook of a saved search
+type SavedSearchNotification struct {
+	ID            string `json:"id"` // Delivery ID, the same for all attempts
+	Event         string `json:"event"`
+	Timestamp     string `json:"timestamp"`
+	SavedSearchID int    `json:"saved_search_id"`
+	Name          string `json:"name"`
+	DocumentCount int    `json:"document_count"`
+	DocumentIDs   []int  `json:"document_ids"` // At most maxPageSize, lowest first
+}
+
+// scanSavedSearch scans a SavedSearch from a database row or rows
+func (s *Service) scanSavedSearch(scanner interface{ Scan(...interface{}) error }) (SavedSearch, error) {
+	var search SavedSearch
+	var id int
+	var filterRulesJSON, webhookURL, secret sql.NullString
+	var lastSeenID, lastNotifiedID, ownerID sql.NullInt64
+	var lastChecked, lastTriggered, created, modified dbTimestamp
+
+	if err := scanner.Scan(&id, &search.Name, &filterRulesJSON, &webhookURL, &secret, &lastSeenID, &lastChecked,
+		&lastNotifiedID, &lastTriggered, &ownerID, &created, &modified); err != nil {
+		return search, err
+	}
+
+	search.ID = &id
+	search.FilterRules = []map[string]interface{}{}
+	if filterRulesJSON.Valid {
+		json.Unmarshal([]byte(filterRulesJSON.String), &search.FilterRules)
+	}
+	if webhookURL.Valid && webhookURL.String != "" {
+		search.WebhookURL = &webhookURL.String
+	}
+	if secret.Valid && secret.String != "" {
+		search.Secret = &secret.String
+		search.HasSecret = true
+	}
+	search.LastSeenID = int(lastSeenID.Int64)
+	search.LastNotifiedID = int(lastNotifiedID.Int64)
+	search.LastCheckedAt = s.formatTimestamp(lastChecked)
+	search.LastTriggeredAt = s.formatTimestamp(lastTriggered)
+	if ownerID.Valid {
+		owner := int(ownerID.Int64)
+		search.OwnerID = &owner
+	}
+	search.Created = s.formatTimestamp(created)
+	search.Modified = s.formatTimestamp(modified)
+
+	return search, nil
+}
+
+// ListSavedSearches retrieves the saved searches of a user
+func (s *Service) ListSavedSearches(ctx context.Context, userID int) ([]SavedSearch, error) {
+	rows, err := s.db.QueryContext(ctx, s.rebind("SELECT "+savedSearchColumns+" FROM saved_searches WHERE owner_id = ? ORDER BY name ASC, id ASC"), userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query saved searches: %w", err)
+	}
+	defer rows.Close()
+
+	searches := []SavedSearch{}
+	for rows.Next() {
+		search, err := s.scanSavedSearch(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan saved search: %w", err)
+		}
+		searches = append(searches, search)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read saved searches: %w", err)
+	}
+	return searches, nil
+}
+
+// GetSavedSearch retrieves a saved search of the user; other users' searches are not found
+func (s *Service) GetSavedSearch(ctx context.Context, id int, userID int) (*SavedSearch, error) {
+	search, err := s.scanSavedSearch(s.db.QueryRowContext(ctx, s.rebind("SELECT "+savedSearchColumns+" FROM saved_searches WHERE id = ? AND owner_id = ?"), id, userID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("saved search with id %d not found", id)
+		}
+		return nil, fmt.Errorf("failed to query saved search: %w", err)
+	}
+	return &search, nil
+}
+
+// checkSavedSearch normalizes and validates a saved search before it is stored
+func (s *Service) checkSavedSearch(ctx context.Context, search *SavedSearch) error {
+	search.Name = strings.TrimSpace(search.Name)
+	if search.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if search.FilterRules == nil {
+		search.FilterRules = []map[string]interface{}{}
+	}
+	filterRulesJSON, err := json.Marshal(search.FilterRules)
+	if err != nil {
+		return fmt.Errorf("invalid filter_rules: %v", err)
+	}
+	if _, _, err := s.buildDocumentFilterQuery(ctx, string(filterRulesJSON), 0, 0); err != nil {
+		return err
+	}
+	if search.WebhookURL != nil && *search.WebhookURL != "" {
+		if err := validateWebhook(Webhook{URL: *search.WebhookURL}); err != nil {
+			return fmt.Errorf("invalid webhook_url: must be an absolute http or https URL")
+		}
+	}
+	return nil
+}
+
+// documentWatermark returns the highest document ID, the watermark of a check. Paperless
+// never reuses document IDs, so documents above it are the ones added later.
+func (s *Service) documentWatermark(ctx context.Context) (int, error) {
+	var watermark sql.NullInt64
+	if err := s.db.QueryRowContext(ctx, "SELECT MAX(id) FROM documents_document").Scan(&watermark); err != nil {
+		return 0, fmt.Errorf("failed to query document watermark: %w", err)
+	}
+	return int(watermark.Int64), nil
+}
+
+// CreateSavedSearch creates a saved search of the user. Only documents added afterwards
+// count as new.
+func (s *Service) CreateSavedSearch(ctx context.Context, search SavedSearch, userID int) (*SavedSearch, error) {
+	log.Printf("[SavedSearches] CreateSavedSearch - Name: %s, UserID: %d", search.Name, userID)
+	if err := s.checkSavedSearch(ctx, &search); err != nil {
+		return nil, err
+	}
+	filterRulesJSON, _ := json.Marshal(search.FilterRules)
+	if search.WebhookURL != nil && *search.WebhookURL == "" {
+		search.WebhookURL = nil
+	}
+	watermark, err := s.documentWatermark(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var id int64
+	if s.config.DBEngine == "postgresql" || s.config.DBEngine == "postgres" {
+		err := s.db.QueryRowContext(ctx, `
+			INSERT INTO saved_searches (name, filter_rules, webhook_url, secret, last_seen_document_id, last_notified_document_id, owner_id)
+			VALUES ($1, $2::jsonb, $3, $4, $5, $6, $7)
+			RETURNING id
+		`, search.Name, string(filterRulesJSON), search.WebhookURL, search.Secret, watermark, watermark, userID).Scan(&id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create saved search: %w", err)
+		}
+	} else {
+		result, err := s.db.ExecContext(ctx, `
+			INSERT INTO saved_searches (name, filter_rules, webhook_url, secret, last_seen_document_id, last_notified_document_id, owner_id)
+			VALUES (?, ?, ?, ?, ?, ?, ?)
+		`, search.Name, string(filterRulesJSON), search.WebhookURL, search.Secret, watermark, watermark, userID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create saved search: %w", err)
+		}
+		if id, err = result.LastInsertId(); err != nil {
+			return nil, fmt.Errorf("failed to get saved search ID: %w", err)
+		}
+	}
+
+	return s.GetSavedSearch(ctx, int(id), userID)
+}
+
+// UpdateSavedSearch updates the provided fields of a saved search of the user. The
+// watermarks are kept. An empty webhook_url stops notifications, an empty secret removes
+// the signature.
+func (s *Service) UpdateSavedSearch(ctx context.Context, id int, updates SavedSearch, userID int) (*SavedSearch, error) {
+	log.Printf("[SavedSearches] UpdateSavedSearch - ID: %d, UserID: %d", id, userID)
+	existing, err := s.GetSavedSearch(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	merged := *existing
+	if updates.Name != "" {
+		merged.Name = updates.Name
+	}
+	if updates.FilterRules != nil {
+		merged.FilterRules = updates.FilterRules
+	}
+	if updates.WebhookURL != nil {
+		merged.WebhookURL = updates.WebhookURL
+	}
+	if err := s.checkSavedSearch(ctx, &merged); err != nil {
+		return nil, err
+	}
+
+	setParts := []string{"name = ?"}
+	args := []interface{}{merged.Name}
+	if updates.FilterRules != nil {
+		filterRulesJSON, _ := json.Marshal(merged.FilterRules)
+		cast := ""
+		if s.config.DBEngine == "postgresql" || s.config.DBEngine == "postgres" {
+			cast = "::jsonb"
+		}
+		setParts = append(setParts, "filter_rules = ?"+cast)
+		args = append(args, string(filterRulesJSON))
+	}
+	if updates.WebhookURL != nil {
+		if *updates.WebhookURL == "" {
+			setParts = append(setParts, "webhook_url = NULL")
+		} else {
+			setParts = append(setParts, "webhook_url = ?")
+			args = append(args, *updates.WebhookURL)
+		}
+	}
+	if updates.Secret != nil {
+		setParts = append(setParts, "secret = ?")
+		args = append(args, *updates.Secret)
+	}
+	setParts = append(setParts, "modified = CURRENT_TIMESTAMP")
+
+	args = append(args, id)
+	query := fmt.Sprintf("UPDATE saved_searches SET %s WHERE id = ?", strings.Join(setParts, ", "))
+	if _, err := s.db.ExecContext(ctx, s.rebind(query), args...); err != nil {
+		return nil, fmt.Errorf("failed to update saved search: %w", err)
+	}
+
+	return s.GetSavedSearch(ctx, id, userID)
+}
+
+// DeleteSavedSearch deletes a saved search of the user
+func (s *Service) DeleteSavedSearch(ctx context.Context, id int, userID int) error {
+	log.Printf("[SavedSearches] DeleteSavedSearch - ID: %d, UserID: %d", id, userID)
+	if _, err := s.GetSavedSearch(ctx, id, userID); err != nil {
+		return err
+	}
+	if _, err := s.db.ExecContext(ctx, s.rebind("DELETE FROM saved_searches WHERE id = ?"), id); err != nil {
+		return fmt.Errorf("failed to delete saved search: %w", err)
+	}
+	return nil
+}
+
+// savedSearchMatches returns the number of documents matching a saved search with an ID in
+// (since, until] that the viewer can see, and the first limit of them by ID
+func (s *Service) savedSearchMatches(ctx context.Context, search *SavedSearch, since int, until int, viewerID int, limit int) (int, []SavedSearchDocument, error) {
+	where, args, err := s.viewDocumentCondition(ctx, &CustomView{FilterRules: search.FilterRules}, viewerID)
+	if err != nil {
+		return 0, nil, err
+	}
+	if s.config.DBEngine == "postgresql" || s.config.DBEngine == "postgres" {
+		where += fmt.Sprintf(" AND d.id > $%d AND d.id <= $%d", len(args)+1, len(args)+2)
+	} else {
+		where += " AND d.id > ? AND d.id <= ?"
+	}
+	args = append(args, since, until)
+
+	count, err := s.countViewDocuments(ctx, where, args)
+	if err != nil {
+		return 0, nil, err
+	}
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf(`
+		SELECT d.id, d.title, d.created, d.added
+		FROM documents_document d
+		WHERE %s
+		ORDER BY d.id ASC
+		LIMIT %d
+	`, where, limit), args...)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to query saved search documents: %w", err)
+	}
+	defer rows.Close()
+
+	documents := []SavedSearchDocument{}
+	for rows.Next() {
+		var document SavedSearchDocument
+		var title sql.NullString
+		var created, added interface{}
+		if err := rows.Scan(&document.ID, &title, &created, &added); err != nil {
+			return 0, nil, fmt.Errorf("failed to scan saved search document: %w", err)
+		}
+		document.Title = title.String
+		if value, ok := viewDocumentValue("created", created).(string); ok {
+			document.Created = &value
+		}
+		if value, ok := viewDocumentValue("added", added).(string); ok {
+			document.Added = &value
+		}
+		documents = append(documents, document)
+	}
+	if err := rows.Err(); err != nil {
+		return 0, nil, fmt.Errorf("failed to read saved search documents: %w", err)
+	}
+	return count, documents, nil
+}
+
+// GetSavedSearchNewDocuments returns the documents that started matching a saved search of
+// the user since the last check, at most limit of them. Unless peek is set, the check moves
+// the watermark so they are not returned again.
+func (s *Service) GetSavedSearchNewDocuments(ctx context.Context, id int, userID int, viewerID int, limit int, peek bool) (*SavedSearchNewResponse, error) {
+	search, err := s.GetSavedSearch(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	until, err := s.documentWatermark(ctx)
+	if err != nil {
+		return nil, err
+	}
+	until = max(until, search.LastSeenID)
+
+	count, documents, err := s.savedSearchMatches(ctx, search, search.LastSeenID, until, viewerID, limit)
+	if err != nil {
+		return nil, err
+	}
+	if !peek {
+		query := s.rebind("UPDATE saved_searches SET last_seen_document_id = ?, last_checked = CURRENT_TIMESTAMP WHERE id = ?")
+		if _, err := s.db.ExecContext(ctx, query, until, id); err != nil {
+			return nil, fmt.Errorf("failed to store saved search watermark: %w", err)
+		}
+	}
+
+	return &SavedSearchNewResponse{SavedSearchID: id, Since: search.LastSeenID, Until: until, Count: count, Results: documents}, nil
+}
+
+// runSavedSearchChecks posts the new matches of each saved search with a webhook. A search
+// is claimed by moving its notification watermark before the delivery, so new matches are
+// posted once even with several instances running.
+func (s *Service) runSavedSearchChecks(ctx context.Context) {
+	rows, err := s.db.QueryContext(ctx, "SELECT "+savedSearchColumns+" FROM saved_searches WHERE webhook_url IS NOT NULL AND webhook_url <> ''")
+	if err != nil {
+		log.Printf("[SavedSearches] Failed to query saved searches: %v", err)
+		return
+	}
+	var searches []SavedSearch
+	for rows.Next() {
+		search, err := s.scanSavedSearch(rows)
+		if err != nil {
+			log.Printf("[SavedSearches] Failed to scan saved search: %v", err)
+			continue
+		}
+		searches = append(searches, search)
+	}
+	rows.Close()
+	if len(searches) == 0 {
+		return
+	}
+
+	until, err := s.documentWatermark(ctx)
+	if err != nil {
+		log.Printf("[SavedSearches] %v", err)
+		return
+	}
+	for i := range searches {
+		search := &searches[i]
+		if until <= search.LastNotifiedID {
+			continue
+		}
+		ownerID := 0
+		if search.OwnerID != nil {
+			ownerID = *search.OwnerID
+		}
+		count, documents, err := s.savedSearchMatches(ctx, search, search.LastNotifiedID, until, ownerID, maxPageSize)
+		if err != nil {
+			log.Printf("[SavedSearches] Checking saved search %d failed: %v", *search.ID, err)
+			continue
+		}
+
+		query := "UPDATE saved_searches SET last_notified_document_id = ?"
+		if count > 0 {
+			query += ", last_triggered = CURRENT_TIMESTAMP"
+		}
+		result, err := s.db.ExecContext(ctx, s.rebind(query+" WHERE id = ? AND last_notified_document_id = ?"), until, *search.ID, search.LastNotifiedID)
+		if err != nil {
+			log.Printf("[SavedSearches] Failed to store notification watermark of saved search %d: %v", *search.ID, err)
+			continue
+		}
+		if claimed, _ := result.RowsAffected(); claimed == 0 || count == 0 {
+			continue
+		}
+
+		log.Printf("[SavedSearches] Saved search %d has %d new matches", *search.ID, count)
+		notification := SavedSearchNotification{SavedSearchID: *search.ID, Name: search.Name, DocumentCount: count, DocumentIDs: make([]int, len(documents))}
+		for j, document := range documents {
+			notification.DocumentIDs[j] = document.ID
+		}
+		go s.sendSavedSearchNotification(*search, notification)
+	}
+}
+
+// runSavedSearchNotifier periodically posts new matches of saved searches to their webhooks
+func (s *Service) runSavedSearchNotifier(ctx context.Context) {
+	log.Printf("[SavedSearches] Saved search notifier started - Interval: %s", s.config.SavedSearchInterval)
+	s.runSavedSearchChecks(ctx)
+
+	ticker := time.NewTicker(s.config.SavedSearchInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Printf("[SavedSearches] Saved search notifier stopped")
+			return
+		case <-ticker.C:
+			s.runSavedSearchChecks(ctx)
+		}
+	}
+}
+
+// sendSavedSearchNotification posts new matches to the webhook of a saved search
+func (s *Service) sendSavedSearchNotification(search SavedSearch, notification SavedSearchNotification) {
+	deliveryID := make([]byte, 8)
+	rand.Read(deliveryID)
+	notification.ID = hex.EncodeToString(deliveryID)
+	notification.Event = EventSavedSearchMatches
+	notification.Timestamp = time.Now().UTC().Format(time.RFC3339)
+
+	payload, err := json.Marshal(notification)
+	if err != nil {
+		log.Printf("[SavedSearches] Failed to encode notification of saved search %d: %v", *search.ID, err)
+		return
+	}
+	s.postWebhookWithRetry(s.lifecycle, fmt.Sprintf("saved search %d", *search.ID), Webhook{URL: *search.WebhookURL, Secret: search.Secret}, EventSavedSearchMatches, payload)
+}
+
+// savedSearchErrorStatus maps saved search errors to HTTP status codes
+func savedSearchErrorStatus(err error) int {
+	switch {
+	case strings.HasPrefix(err.Error(), "invalid"), strings.Contains(err.Error(), "required"):
+		return http.StatusBadRequest
+	case strings.Contains(err.Error(), "not found"):
+		return http.StatusNotFound
+	}
+	return queryErrorStatus(err)
+}
+
+// respondSavedSearch sends a saved search without its secret
+func respondSavedSearch(w http.ResponseWriter, status int, search *SavedSearch) {
+	search.Secret = nil
+	respondJSON(w, status, search)
+}
+
+// HTTP Handlers for saved searches
+func (s *Service) handleListSavedSearches(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	log.Printf("[SavedSearches] GET /api/saved-searches/ - Request from %s", r.RemoteAddr)
+
+	userID, err := getUserIDFromRequest(r)
+	if err != nil {
+		respondError(w, viewerErrorStatus(err), err.Error())
+		return
+	}
+
+	searches, err := s.ListSavedSearches(ctx, *userID)
+	if err != nil {
+		log.Printf("[SavedSearches] Error listing saved searches: %v", err)
+		respondError(w, queryErrorStatus(err), err.Error())
+		return
+	}
+	for i := range searches {
+		searches[i].Secret = nil
+	}
+
+	respondJSON(w, http.StatusOK, SavedSearchListResponse{Count: len(searches), Results: searches})
+}
+
+func (s *Service) handleGetSavedSearch(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	idStr := mux.Vars(r)["id"]
+	log.Printf("[SavedSearches] GET /api/saved-searches/%s/ - Request from %s", idStr, r.RemoteAddr)
+
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid saved search ID")
+		return
+	}
+	userID, err := getUserIDFromRequest(r)
+	if err != nil {
+		respondError(w, viewerErrorStatus(err), err.Error())
+		return
+	}
+
+	search, err := s.GetSavedSearch(ctx, id, *userID)
+	if err != nil {
+		respondError(w, savedSearchErrorStatus(err), err.Error())
+		return
+	}
+
+	respondSavedSearch(w, http.StatusOK, search)
+}
+
+func (s *Service) handleCreateSavedSearch(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	log.Printf("[SavedSearches] POST /api/saved-searches/ - Request from %s", r.RemoteAddr)
+
+	var search SavedSearch
+	if err := json.NewDecoder(r.Body).Decode(&search); err != nil {
+		respondError(w, http.StatusBadRequest, fmt.Sprintf("Invalid request body: %v", err))
+		return
+	}
+	userID, err := getUserIDFromRequest(r)
+	if err != nil {
+		respondError(w, viewerErrorStatus(err), err.Error())
+		return
+	}
+
+	created, err := s.CreateSavedSearch(ctx, search, *userID)
+	if err != nil {
+		log.Printf("[SavedSearches] Error creating saved search: %v", err)
+		respondError(w, savedSearchErrorStatus(err), err.Error())
+		return
+	}
+
+	respondSavedSearch(w, http.StatusCreated, created)
+}
+
+func (s *Service) handleUpdateSavedSearch(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	idStr := mux.Vars(r)["id"]
+	log.Printf("[SavedSearches] %s /api/saved-searches/%s/ - Request from %s", r.Method, idStr, r.RemoteAddr)
+
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid saved search ID")
+		return
+	}
+	var updates SavedSearch
+	if err := json.NewDecoder(r.Body).Decode(&updates); err != nil {
+		respondError(w, http.StatusBadRequest, fmt.Sprintf("Invalid request body: %v", err))
+		return
+	}
+	userID, err := getUserIDFromRequest(r)
+	if err != nil {
+		respondError(w, viewerErrorStatus(err), err.Error())
+		return
+	}
+
+	updated, err := s.UpdateSavedSearch(ctx, id, updates, *userID)
+	if err != nil {
+		log.Printf("[SavedSearches] Error updating saved search %d: %v", id, err)
+		respondError(w, savedSearchErrorStatus(err), err.Error())
+		return
+	}
+
+	respondSavedSearch(w, http.StatusOK, updated)
+}
+
+func (s *Service) handleDeleteSavedSearch(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	idStr := mux.Vars(r)["id"]
+	log.Printf("[SavedSearches] DELETE /api/saved-searches/%s/ - Request from %s", idStr, r.RemoteAddr)
+
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid saved search ID")
+		return
+	}
+	userID, err := getUserIDFromRequest(r)
+	if err != nil {
+		respondError(w, viewerErrorStatus(err), err.Error())
+		return
+	}
+
+	if err := s.DeleteSavedSearch(ctx, id, *userID); err != nil {
+		log.Printf("[SavedSearches] Error deleting saved search %d: %v", id, err)
+		respondError(w, savedSearchErrorStatus(err), err.Error())
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Service) handleGetSavedSearchNewDocuments(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	idStr := mux.Vars(r)["id"]
+	log.Printf("[SavedSearches] GET /api/saved-searches/%s/new/ - Request from %s", idStr, r.RemoteAddr)
+
+	viewerID, err := viewerFromRequest(r)
+	if err != nil {
+		respondError(w, viewerErrorStatus(err), err.Error())
+		return
+	}
+
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid saved search ID")
+		return
+	}
+	params := r.URL.Query()
+	limit := maxPageSize
+	if value := params.Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > maxPageSize {
+			respondError(w, http.StatusBadRequest, fmt.Sprintf("Invalid limit, expected 1 to %d", maxPageSize))
+			return
+		}
+		limit = parsed
+	}
+	peek := params.Get("peek") == "true" || params.Get("peek") == "1"
+	userID, err := getUserIDFromRequest(r)
+	if err != nil {
+		respondError(w, viewerErrorStatus(err), err.Error())
+		return
+	}
+
+	response, err := s.GetSavedSearchNewDocuments(ctx, id, *userID, viewerID, limit, peek)
+	if err != nil {
+		log.Printf("[SavedSearches] Error checking saved search %d: %v", id, err)
+		respondError(w, savedSearchErrorStatus(err), err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, response)
+}
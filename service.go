@@ -1,21 +1,50 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"log"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/sync/semaphore"
+	"golang.org/x/sync/singleflight"
 )
 
 // Service represents the application service with database connection
 type Service struct {
-	db     *sql.DB
-	config *Config
+	db         *sql.DB
+	statements *statementCache // Prepared queries of a fixed shape
+	config     *Config
+	valueCache Cache
+	fieldMeta  *ttlCache           // Custom field metadata by field ID
+	inflight   singleflight.Group  // Deduplicates concurrent identical aggregation queries
+	queryPool  *semaphore.Weighted // Bounds the queries run concurrently by runConcurrently
+
+	builtinCache       Cache
+	documentNotify     bool         // Document change notifications invalidate builtinCache
+	documentGeneration atomic.Int64 // Bumped on every document change notification
+	facetTriggers      bool         // Changed documents are logged for the facet summaries
+	indexBuild         atomic.Bool  // The index advisor is creating indexes
+	dataVersion        atomic.Value // polledDataVersion, without change notifications
+	startedAt          time.Time
+	lifecycle          context.Context // Cancelled on shutdown; work outliving a request runs on it
+
+	viewUpdates       sync.Mutex            // Serializes conditional custom view updates
+	consumedRefreshes *consumedRefreshes    // Refreshes due after consumed documents
+	viewEvents        chan viewEvent        // View events for runWebhookDispatcher
+	sharePasswords    sharePasswordAttempts // Wrong share link passwords, for the lockout
+	webhookClient     *http.Client          // Delivers webhook notifications
+	paperlessClient   *http.Client          // Calls the Paperless REST API
 }
 
 // NewService creates a new service instance with database connection
 func NewService(config *Config) (*Service, error) {
 	log.Printf("[Service] Initializing service with DB engine: %s", config.DBEngine)
-	
+
 	db, err := connectDB(config)
 	if err != nil {
 		log.Printf("[Service] Failed to connect to database: %v", err)
@@ -30,9 +59,31 @@ func NewService(config *Config) (*Service, error) {
 	}
 	log.Printf("[Service] Database ping successful")
 
+	valueCache, err := newCache(config, "values", config.ValueCacheTTL, config.ValueCacheSize)
+	if err != nil {
+		log.Printf("[Service] Failed to set up value cache: %v", err)
+		return nil, fmt.Errorf("failed to set up value cache: %w", err)
+	}
+
+	queryConcurrency := config.QueryConcurrency
+	if queryConcurrency < 1 {
+		queryConcurrency = 1
+	}
+
 	service := &Service{
-		db:     db,
-		config: config,
+		db:         db,
+		statements: newStatementCache(db),
+		queryPool:  semaphore.NewWeighted(int64(queryConcurrency)),
+		config:     config,
+		valueCache: valueCache,
+		fieldMeta:  newTTLCache(config.FieldMetaCacheTTL, maxCachedFieldMeta),
+		startedAt:  time.Now(),
+		lifecycle:  context.Background(),
+
+		consumedRefreshes: newConsumedRefreshes(),
+		viewEvents:        make(chan viewEvent, viewEventQueueSize),
+		webhookClient:     newWebhookClient(config.WebhookTimeout, config.WebhookAllowPrivate),
+		paperlessClient:   &http.Client{Timeout: config.PaperlessTimeout},
 	}
 
 	// Initialize custom views table
@@ -43,6 +94,22 @@ func NewService(config *Config) (*Service, error) {
 	}
 	log.Printf("[Service] Custom views table initialized successfully")
 
+	// Initialize custom view revisions table
+	log.Printf("[Service] Initializing custom view revisions table")
+	if err := service.initCustomViewRevisionsTable(); err != nil {
+		log.Printf("[Service] Failed to initialize custom view revisions table: %v", err)
+		return nil, fmt.Errorf("failed to initialize custom view revisions table: %w", err)
+	}
+	log.Printf("[Service] Custom view revisions table initialized successfully")
+
+	// Initialize user view preferences table
+	log.Printf("[Service] Initializing user view preferences table")
+	if err := service.initUserViewPreferencesTable(); err != nil {
+		log.Printf("[Service] Failed to initialize user view preferences table: %v", err)
+		return nil, fmt.Errorf("failed to initialize user view preferences table: %w", err)
+	}
+	log.Printf("[Service] User view preferences table initialized successfully")
+
 	// Initialize tag groups tables
 	log.Printf("[Service] Initializing tag groups tables")
 	if err := service.initTagGroupsTables(); err != nil {
@@ -51,6 +118,267 @@ func NewService(config *Config) (*Service, error) {
 	}
 	log.Printf("[Service] Tag groups tables initialized successfully")
 
+	log.Printf("[Service] Initializing entity descriptions table")
+	if err := service.initEntityDescriptionsTable(); err != nil {
+		log.Printf("[Service] Failed to initialize entity descriptions table: %v", err)
+		return nil, fmt.Errorf("failed to initialize entity descriptions table: %w", err)
+	}
+	log.Printf("[Service] Entity descriptions table initialized successfully")
+
+	log.Printf("[Service] Initializing tag aliases table")
+	if err := service.initTagAliasesTable(); err != nil {
+		log.Printf("[Service] Failed to initialize tag aliases table: %v", err)
+		return nil, fmt.Errorf("failed to initialize tag aliases table: %w", err)
+	}
+	log.Printf("[Service] Tag aliases table initialized successfully")
+
+	log.Printf("[Service] Initializing document links table")
+	if err := service.initDocumentLinksTable(); err != nil {
+		log.Printf("[Service] Failed to initialize document links table: %v", err)
+		return nil, fmt.Errorf("failed to initialize document links table: %w", err)
+	}
+	log.Printf("[Service] Document links table initialized successfully")
+
+	log.Printf("[Service] Initializing link types table")
+	if err := service.initLinkTypesTable(); err != nil {
+		log.Printf("[Service] Failed to initialize link types table: %v", err)
+		return nil, fmt.Errorf("failed to initialize link types table: %w", err)
+	}
+	log.Printf("[Service] Link types table initialized successfully")
+
+	log.Printf("[Service] Initializing link suggestions table")
+	if err := service.initLinkSuggestionsTable(); err != nil {
+		log.Printf("[Service] Failed to initialize link suggestions table: %v", err)
+		return nil, fmt.Errorf("failed to initialize link suggestions table: %w", err)
+	}
+	log.Printf("[Service] Link suggestions table initialized successfully")
+
+	log.Printf("[Service] Initializing dashboards table")
+	if err := service.initDashboardsTable(); err != nil {
+		log.Printf("[Service] Failed to initialize dashboards table: %v", err)
+		return nil, fmt.Errorf("failed to initialize dashboards table: %w", err)
+	}
+	log.Printf("[Service] Dashboards table initialized successfully")
+
+	log.Printf("[Service] Initializing user preferences table")
+	if err := service.initUserPreferencesTable(); err != nil {
+		log.Printf("[Service] Failed to initialize user preferences table: %v", err)
+		return nil, fmt.Errorf("failed to initialize user preferences table: %w", err)
+	}
+	log.Printf("[Service] User preferences table initialized successfully")
+
+	log.Printf("[Service] Initializing document activity table")
+	if err := service.initDocumentActivityTable(); err != nil {
+		log.Printf("[Service] Failed to initialize document activity table: %v", err)
+		return nil, fmt.Errorf("failed to initialize document activity table: %w", err)
+	}
+	log.Printf("[Service] Document activity table initialized successfully")
+
+	log.Printf("[Service] Initializing document reminders table")
+	if err := service.initDocumentRemindersTable(); err != nil {
+		log.Printf("[Service] Failed to initialize document reminders table: %v", err)
+		return nil, fmt.Errorf("failed to initialize document reminders table: %w", err)
+	}
+	log.Printf("[Service] Document reminders table initialized successfully")
+
+	log.Printf("[Service] Initializing saved searches table")
+	if err := service.initSavedSearchesTable(); err != nil {
+		log.Printf("[Service] Failed to initialize saved searches table: %v", err)
+		return nil, fmt.Errorf("failed to initialize saved searches table: %w", err)
+	}
+	log.Printf("[Service] Saved searches table initialized successfully")
+
+	log.Printf("[Service] Initializing document notes table")
+	if err := service.initDocumentNotesTable(); err != nil {
+		log.Printf("[Service] Failed to initialize document notes table: %v", err)
+		return nil, fmt.Errorf("failed to initialize document notes table: %w", err)
+	}
+	log.Printf("[Service] Document notes table initialized successfully")
+
+	log.Printf("[Service] Initializing workflows tables")
+	if err := service.initWorkflowsTables(); err != nil {
+		log.Printf("[Service] Failed to initialize workflows tables: %v", err)
+		return nil, fmt.Errorf("failed to initialize workflows tables: %w", err)
+	}
+	log.Printf("[Service] Workflows tables initialized successfully")
+
+	log.Printf("[Service] Initializing scheduled reports table")
+	if err := service.initScheduledReportsTable(); err != nil {
+		log.Printf("[Service] Failed to initialize scheduled reports table: %v", err)
+		return nil, fmt.Errorf("failed to initialize scheduled reports table: %w", err)
+	}
+	log.Printf("[Service] Scheduled reports table initialized successfully")
+
+	log.Printf("[Service] Initializing share links table")
+	if err := service.initShareLinksTable(); err != nil {
+		log.Printf("[Service] Failed to initialize share links table: %v", err)
+		return nil, fmt.Errorf("failed to initialize share links table: %w", err)
+	}
+	log.Printf("[Service] Share links table initialized successfully")
+
+	log.Printf("[Service] Initializing activity events table")
+	if err := service.initActivityEventsTable(); err != nil {
+		log.Printf("[Service] Failed to initialize activity events table: %v", err)
+		return nil, fmt.Errorf("failed to initialize activity events table: %w", err)
+	}
+	log.Printf("[Service] Activity events table initialized successfully")
+
+	log.Printf("[Service] Initializing correspondent profiles table")
+	if err := service.initCorrespondentProfilesTable(); err != nil {
+		log.Printf("[Service] Failed to initialize correspondent profiles table: %v", err)
+		return nil, fmt.Errorf("failed to initialize correspondent profiles table: %w", err)
+	}
+	log.Printf("[Service] Correspondent profiles table initialized successfully")
+
+	log.Printf("[Service] Initializing bulk jobs table")
+	if err := service.initBulkJobsTable(); err != nil {
+		log.Printf("[Service] Failed to initialize bulk jobs table: %v", err)
+		return nil, fmt.Errorf("failed to initialize bulk jobs table: %w", err)
+	}
+	log.Printf("[Service] Bulk jobs table initialized successfully")
+
+	log.Printf("[Service] Initializing facet summary tables")
+	if err := service.initFacetSummaryTables(); err != nil {
+		log.Printf("[Service] Failed to initialize facet summary tables: %v", err)
+		return nil, fmt.Errorf("failed to initialize facet summary tables: %w", err)
+	}
+	log.Printf("[Service] Facet summary tables initialized successfully")
+
+	// Initialize precomputed value summaries table
+	log.Printf("[Service] Initializing field value summaries table")
+	if err := service.initFieldValueSummariesTable(); err != nil {
+		log.Printf("[Service] Failed to initialize field value summaries table: %v", err)
+		return nil, fmt.Errorf("failed to initialize field value summaries table: %w", err)
+	}
+	log.Printf("[Service] Field value summaries table initialized successfully")
+
+	// Initialize filter presets table
+	log.Printf("[Service] Initializing filter presets table")
+	if err := service.initFilterPresetsTable(); err != nil {
+		log.Printf("[Service] Failed to initialize filter presets table: %v", err)
+		return nil, fmt.Errorf("failed to initialize filter presets table: %w", err)
+	}
+	log.Printf("[Service] Filter presets table initialized successfully")
+
+	// Initialize view snapshots tables
+	log.Printf("[Service] Initializing view snapshots tables")
+	if err := service.initViewSnapshotsTable(); err != nil {
+		log.Printf("[Service] Failed to initialize view snapshots table: %v", err)
+		return nil, fmt.Errorf("failed to initialize view snapshots table: %w", err)
+	}
+	if err := service.initViewSnapshotSchedulesTable(); err != nil {
+		log.Printf("[Service] Failed to initialize view snapshot schedules table: %v", err)
+		return nil, fmt.Errorf("failed to initialize view snapshot schedules table: %w", err)
+	}
+	log.Printf("[Service] View snapshots tables initialized successfully")
+
+	log.Printf("[Service] Initializing view notification rules table")
+	if err := service.initViewNotificationRulesTable(); err != nil {
+		log.Printf("[Service] Failed to initialize view notification rules table: %v", err)
+		return nil, fmt.Errorf("failed to initialize view notification rules table: %w", err)
+	}
+	log.Printf("[Service] View notification rules table initialized successfully")
+
+	log.Printf("[Service] Initializing column presets table")
+	if err := service.initColumnPresetsTable(); err != nil {
+		log.Printf("[Service] Failed to initialize column presets table: %v", err)
+		return nil, fmt.Errorf("failed to initialize column presets table: %w", err)
+	}
+	log.Printf("[Service] Column presets table initialized successfully")
+
+	log.Printf("[Service] Initializing webhooks table")
+	if err := service.initWebhooksTable(); err != nil {
+		log.Printf("[Service] Failed to initialize webhooks table: %v", err)
+		return nil, fmt.Errorf("failed to initialize webhooks table: %w", err)
+	}
+	log.Printf("[Service] Webhooks table initialized successfully")
+
+	log.Printf("[Service] Migrating stored filter rules")
+	if err := service.migrateFilterRuleTypes(); err != nil {
+		log.Printf("[Service] Failed to migrate stored filter rules: %v", err)
+		return nil, fmt.Errorf("failed to migrate stored filter rules: %w", err)
+	}
+	log.Printf("[Service] Stored filter rules migrated successfully")
+
+	// Builtin filter values are cached until the documents change where PostgreSQL can
+	// notify us, and for a short time otherwise
+	service.documentNotify = service.installDocumentChangeTriggers()
+	builtinCacheTTL := config.BuiltinCacheTTL
+	if service.documentNotify {
+		builtinCacheTTL = config.BuiltinCacheNotifyTTL
+	}
+	if service.builtinCache, err = newCache(config, "builtin", builtinCacheTTL, config.BuiltinCacheSize); err != nil {
+		log.Printf("[Service] Failed to set up builtin filter value cache: %v", err)
+		return nil, fmt.Errorf("failed to set up builtin filter value cache: %w", err)
+	}
+
+	// The change log is only drained by the refresher, so the triggers are removed again
+	// when it does not run
+	if service.facetSummariesEnabled() && config.FacetSummaryInterval > 0 {
+		service.facetTriggers = service.installFacetChangeTriggers()
+	} else {
+		service.removeFacetChangeTriggers()
+	}
+
+	service.prepareStatements(context.Background())
+
 	return service, nil
 }
 
+// requestContext returns the context for the database work of a request. It is cancelled
+// when the client disconnects and bounded by the configured query timeout.
+func (s *Service) requestContext(r *http.Request) (context.Context, context.CancelFunc) {
+	if s.config.QueryTimeout > 0 {
+		return context.WithTimeout(r.Context(), s.config.QueryTimeout)
+	}
+	return context.WithCancel(r.Context())
+}
+
+// StartBackgroundJobs launches the enabled background jobs; they stop when ctx is cancelled,
+// as does the work requests leave running in the background
+func (s *Service) StartBackgroundJobs(ctx context.Context) {
+	s.lifecycle = ctx
+	go s.runConsumedRefresher(ctx)
+	go s.runWebhookDispatcher(ctx)
+	if s.config.WarmUp {
+		go s.warmUp(ctx)
+	}
+	if s.config.IndexAdvisor == "report" || s.config.IndexAdvisor == "create" {
+		go s.runIndexAdvisor(ctx)
+	}
+	if s.config.PrecomputeInterval > 0 {
+		go s.runPrecomputeScheduler(ctx)
+	}
+	if s.facetSummariesEnabled() && s.config.FacetSummaryInterval > 0 {
+		go s.runFacetSummaryRefresher(ctx)
+	}
+	if s.documentNotify {
+		go s.runDocumentChangeListener(ctx)
+	} else if s.config.DataVersionInterval > 0 {
+		go s.runDataVersionPoller(ctx)
+	}
+	if s.config.ViewTrashRetention > 0 {
+		go s.runViewTrashPurge(ctx)
+	}
+	if s.config.ViewFieldCheckInterval > 0 {
+		go s.runViewFieldCheck(ctx)
+	}
+	if s.config.ViewSnapshotCheckInterval > 0 {
+		go s.runViewSnapshotScheduler(ctx)
+	}
+	if s.config.ViewRuleInterval > 0 {
+		go s.runViewNotificationRules(ctx)
+	}
+	if s.config.LinkSuggestionInterval > 0 {
+		go s.runLinkSuggestionAnalyzer(ctx)
+	}
+	if s.config.ReminderInterval > 0 {
+		go s.runReminderNotifier(ctx)
+	}
+	if s.config.SavedSearchInterval > 0 {
+		go s.runSavedSearchNotifier(ctx)
+	}
+	if s.config.ReportInterval > 0 {
+		go s.runReportScheduler(ctx)
+	}
+}
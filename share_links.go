@@ -0,0 +1,755 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// A share link refusing passwords after maxSharePasswordFailures wrong ones in a row stays
+// locked for sharePasswordLockout
+const (
+	maxSharePasswordFailures = 5
+	sharePasswordLockout     = 15 * time.Minute
+)
+
+// shareLinkColumns are the columns read by scanShareLink
+const shareLinkColumns = "id, name, token, filter_rules, password_hash, expires_at, access_count, last_accessed, owner_id, created, modified"
+
+// sharedDocumentColumns are the view columns listed by a share link
+var sharedDocumentColumns = []string{"title", "created", "added", "correspondent", "document_type", "tags"}
+
+// SharedDocument is a document listed by a share link, with names instead of IDs
+type SharedDocument struct {
+	ID            int      `json:"id"`
+	Title         string   `json:"title"`
+	Created       *string  `json:"created,omitempty"`
+	Added         *string  `json:"added,omitempty"`
+	Correspondent *string  `json:"correspondent,omitempty"`
+	DocumentType  *string  `json:"document_type,omitempty"`
+	Tags          []string `json:"tags"`
+}
+
+// SharedDocumentsResponse is a page of the documents of a share link
+type SharedDocumentsResponse struct {
+	Name      string           `json:"name"`
+	ExpiresAt *string          `json:"expires_at,omitempty"`
+	Count     int              `json:"count"`
+	Next      *string          `json:"next,omitempty"`
+	Previous  *string          `json:"previous,omitempty"`
+	Results   []SharedDocument `json:"results"`
+}
+
+// shareLinkState is a share link as stored: the link with its password hash and expiry
+type shareLinkState struct {
+	ShareLink
+	passwordHash string
+	expiresAt    dbTimestamp
+}
+
+// scanShareLink scans a share link from a database row or rows
+func (s *Service) scanShareLink(scanner interface{ Scan(...interface{}) error }) (shareLinkState, error) {
+	var state shareLinkState
+	var id int
+	var filterRulesJSON, passwordHash sql.NullString
+	var accessCount, ownerID sql.NullInt64
+	var lastAccessed, created, modified dbTimestamp
+
+	if err := scanner.Scan(&id, &state.Name, &state.Token, &filterRulesJSON, &passwordHash, &state.expiresAt,
+		&accessCount, &lastAccessed, &ownerID, &created, &modified); err != nil {
+		return state, err
+	}
+
+	state.ID = &id
+	state.FilterRules = []map[string]interface{}{}
+	if filterRulesJSON.Valid {
+		json.Unmarshal([]byte(filterRulesJSON.String), &state.FilterRules)
+	}
+	state.passwordHash = passwordHash.String
+	state.HasPassword = passwordHash.String != ""
+	state.ExpiresAt = s.formatTimestamp(state.expiresAt)
+	state.AccessCount = int(accessCount.Int64)
+	state.LastAccessed = s.formatTimestamp(lastAccessed)
+	if ownerID.Valid {
+		owner := int(ownerID.Int64)
+		state.OwnerID = &owner
+	}
+	state.Created = s.formatTimestamp(created)
+	state.Modified = s.formatTimestamp(modified)
+
+	return state, nil
+}
+
+// expired reports whether the link can no longer be opened
+func (state *shareLinkState) expired(now time.Time) bool {
+	return state.expiresAt.Valid && !state.expiresAt.Time.IsZero() && !now.Before(state.expiresAt.Time)
+}
+
+// newShareToken returns a random share link token
+func newShareToken() string {
+	token := make([]byte, 20)
+	rand.Read(token)
+	return hex.EncodeToString(token)
+}
+
+// hashSharePassword returns the bcrypt hash a share link password is stored as
+func hashSharePassword(password string) (string, error) {
+	if len(password) > 72 {
+		return "", fmt.Errorf("invalid password: must be at most 72 bytes")
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash password: %w", err)
+	}
+	return string(hash), nil
+}
+
+// checkSharePassword reports whether password matches a hash of hashSharePassword
+func checkSharePassword(hash string, password string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
+
+// sharePasswordAttempts limits the password attempts of share links: after
+// maxSharePasswordFailures wrong passwords in a row a link refuses all passwords for
+// sharePasswordLockout. Attempts are counted per process.
+type sharePasswordAttempts struct {
+	mu    sync.Mutex
+	links map[int]*sharePasswordFailures
+}
+
+// sharePasswordFailures are the wrong passwords of one share link
+type sharePasswordFailures struct {
+	count       int
+	lockedUntil time.Time
+}
+
+// begin records a password attempt on a link and reports whether the password may be
+// checked. The attempt counts as wrong until succeed is called, so concurrent attempts
+// cannot exceed the limit.
+func (a *sharePasswordAttempts) begin(linkID int, now time.Time) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.links == nil {
+		a.links = make(map[int]*sharePasswordFailures)
+	}
+	failures, ok := a.links[linkID]
+	if !ok {
+		failures = &sharePasswordFailures{}
+		a.links[linkID] = failures
+	}
+	if failures.count >= maxSharePasswordFailures {
+		if now.Before(failures.lockedUntil) {
+			return false
+		}
+		failures.count = 0
+	}
+	failures.count++
+	if failures.count == maxSharePasswordFailures {
+		failures.lockedUntil = now.Add(sharePasswordLockout)
+	}
+	return true
+}
+
+// succeed forgets the wrong passwords of a link after the right one was given
+func (a *sharePasswordAttempts) succeed(linkID int) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(a.links, linkID)
+}
+
+// parseShareExpiry reads an expires_at given as RFC 3339 or as a date (midnight UTC)
+func parseShareExpiry(value string) (time.Time, error) {
+	expiresAt, err := parseSnapshotTime(value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid expires_at: use RFC 3339 or YYYY-MM-DD")
+	}
+	return expiresAt, nil
+}
+
+// ListShareLinks retrieves the share links of a user
+func (s *Service) ListShareLinks(ctx context.Context, userID int) ([]ShareLink, error) {
+	rows, err := s.db.QueryContext(ctx, s.rebind("SELECT "+shareLinkColumns+" FROM share_links WHERE owner_id = ? ORDER BY name ASC, id ASC"), userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query share links: %w", err)
+	}
+	defer rows.Close()
+
+	links := []ShareLink{}
+	for rows.Next() {
+		state, err := s.scanShareLink(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan share link: %w", err)
+		}
+		links = append(links, state.ShareLink)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read share links: %w", err)
+	}
+	return links, nil
+}
+
+// GetShareLink retrieves a share link of the user; other users' links are not found
+func (s *Service) GetShareLink(ctx context.Context, id int, userID int) (*ShareLink, error) {
+	state, err := s.scanShareLink(s.db.QueryRowContext(ctx, s.rebind("SELECT "+shareLinkColumns+" FROM share_links WHERE id = ? AND owner_id = ?"), id, userID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("share link with id %d not found", id)
+		}
+		return nil, fmt.Errorf("failed to query share link: %w", err)
+	}
+	return &state.ShareLink, nil
+}
+
+// checkShareLink normalizes and validates a share link before it is stored
+func (s *Service) checkShareLink(ctx context.Context, link *ShareLink) error {
+	link.Name = strings.TrimSpace(link.Name)
+	if link.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if link.FilterRules == nil {
+		link.FilterRules = []map[string]interface{}{}
+	}
+	filterRulesJSON, err := json.Marshal(link.FilterRules)
+	if err != nil {
+		return fmt.Errorf("invalid filter_rules: %v", err)
+	}
+	if _, _, err := s.buildDocumentFilterQuery(ctx, string(filterRulesJSON), 0, 0); err != nil {
+		return err
+	}
+	return nil
+}
+
+// shareLinkExpiry returns the expiry to store for an expires_at: nil for none, otherwise a
+// time in the future
+func shareLinkExpiry(expiresAt *string) (*string, error) {
+	if expiresAt == nil || *expiresAt == "" {
+		return nil, nil
+	}
+	parsed, err := parseShareExpiry(*expiresAt)
+	if err != nil {
+		return nil, err
+	}
+	if !parsed.After(time.Now()) {
+		return nil, fmt.Errorf("invalid expires_at: must be in the future")
+	}
+	formatted := parsed.UTC().Format(snapshotTimeLayout)
+	return &formatted, nil
+}
+
+// CreateShareLink creates a share link of the user with a new token
+func (s *Service) CreateShareLink(ctx context.Context, link ShareLink, userID int) (*ShareLink, error) {
+	log.Printf("[ShareLinks] CreateShareLink - Name: %s, UserID: %d", link.Name, userID)
+	if err := s.checkShareLink(ctx, &link); err != nil {
+		return nil, err
+	}
+	expiresAt, err := shareLinkExpiry(link.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+	var passwordHash *string
+	if link.Password != nil && *link.Password != "" {
+		hash, err := hashSharePassword(*link.Password)
+		if err != nil {
+			return nil, err
+		}
+		passwordHash = &hash
+	}
+	filterRulesJSON, _ := json.Marshal(link.FilterRules)
+	token := newShareToken()
+
+	var id int64
+	if s.config.DBEngine == "postgresql" || s.config.DBEngine == "postgres" {
+		err := s.db.QueryRowContext(ctx, `
+			INSERT INTO share_links (name, token, filter_rules, password_hash, expires_at, owner_id)
+			VALUES ($1, $2, $3::jsonb, $4, $5, $6)
+			RETURNING id
+		`, link.Name, token, string(filterRulesJSON), passwordHash, expiresAt, userID).Scan(&id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create share link: %w", err)
+		}
+	} else {
+		result, err := s.db.ExecContext(ctx, `
+			INSERT INTO share_links (name, token, filter_rules, password_hash, expires_at, owner_id)
+			VALUES (?, ?, ?, ?, ?, ?)
+		`, link.Name, token, string(filterRulesJSON), passwordHash, expiresAt, userID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create share link: %w", err)
+		}
+		if id, err = result.LastInsertId(); err != nil {
+			return nil, fmt.Errorf("failed to get share link ID: %w", err)
+		}
+	}
+
+	return s.GetShareLink(ctx, int(id), userID)
+}
+
+// UpdateShareLink updates the provided fields of a share link of the user. The token is
+// kept. An empty password removes the password, an empty expires_at the expiry.
+func (s *Service) UpdateShareLink(ctx context.Context, id int, updates ShareLink, userID int) (*ShareLink, error) {
+	log.Printf("[ShareLinks] UpdateShareLink - ID: %d, UserID: %d", id, userID)
+	existing, err := s.GetShareLink(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	merged := *existing
+	if updates.Name != "" {
+		merged.Name = updates.Name
+	}
+	if updates.FilterRules != nil {
+		merged.FilterRules = updates.FilterRules
+	}
+	if err := s.checkShareLink(ctx, &merged); err != nil {
+		return nil, err
+	}
+
+	setParts := []string{"name = ?"}
+	args := []interface{}{merged.Name}
+	if updates.FilterRules != nil {
+		filterRulesJSON, _ := json.Marshal(merged.FilterRules)
+		cast := ""
+		if s.config.DBEngine == "postgresql" || s.config.DBEngine == "postgres" {
+			cast = "::jsonb"
+		}
+		setParts = append(setParts, "filter_rules = ?"+cast)
+		args = append(args, string(filterRulesJSON))
+	}
+	if updates.Password != nil {
+		if *updates.Password == "" {
+			setParts = append(setParts, "password_hash = NULL")
+		} else {
+			hash, err := hashSharePassword(*updates.Password)
+			if err != nil {
+				return nil, err
+			}
+			setParts = append(setParts, "password_hash = ?")
+			args = append(args, hash)
+		}
+	}
+	if updates.ExpiresAt != nil {
+		expiresAt, err := shareLinkExpiry(updates.ExpiresAt)
+		if err != nil {
+			return nil, err
+		}
+		if expiresAt == nil {
+			setParts = append(setParts, "expires_at = NULL")
+		} else {
+			setParts = append(setParts, "expires_at = ?")
+			args = append(args, *expiresAt)
+		}
+	}
+	setParts = append(setParts, "modified = CURRENT_TIMESTAMP")
+
+	args = append(args, id)
+	query := fmt.Sprintf("UPDATE share_links SET %s WHERE id = ?", strings.Join(setParts, ", "))
+	if _, err := s.db.ExecContext(ctx, s.rebind(query), args...); err != nil {
+		return nil, fmt.Errorf("failed to update share link: %w", err)
+	}
+
+	return s.GetShareLink(ctx, id, userID)
+}
+
+// DeleteShareLink deletes a share link of the user, revoking it
+func (s *Service) DeleteShareLink(ctx context.Context, id int, userID int) error {
+	log.Printf("[ShareLinks] DeleteShareLink - ID: %d, UserID: %d", id, userID)
+	if _, err := s.GetShareLink(ctx, id, userID); err != nil {
+		return err
+	}
+	if _, err := s.db.ExecContext(ctx, s.rebind("DELETE FROM share_links WHERE id = ?"), id); err != nil {
+		return fmt.Errorf("failed to delete share link: %w", err)
+	}
+	return nil
+}
+
+// openShareLink looks up the link of a token and checks that it can be opened with password
+func (s *Service) openShareLink(ctx context.Context, token string, password string) (*shareLinkState, error) {
+	state, err := s.scanShareLink(s.db.QueryRowContext(ctx, s.rebind("SELECT "+shareLinkColumns+" FROM share_links WHERE token = ?"), token))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("share link not found")
+		}
+		return nil, fmt.Errorf("failed to query share link: %w", err)
+	}
+	if state.expired(time.Now()) {
+		return nil, fmt.Errorf("share link has expired")
+	}
+	if state.HasPassword {
+		if password == "" {
+			return nil, fmt.Errorf("password required")
+		}
+		if !s.sharePasswords.begin(*state.ID, time.Now()) {
+			return nil, fmt.Errorf("too many wrong passwords, try again later")
+		}
+		if !checkSharePassword(state.passwordHash, password) {
+			return nil, fmt.Errorf("wrong password")
+		}
+		s.sharePasswords.succeed(*state.ID)
+	}
+	return &state, nil
+}
+
+// GetSharedDocuments returns a page of the documents of a share link, as its owner sees
+// them outside the trash, newest first, and counts the access
+func (s *Service) GetSharedDocuments(ctx context.Context, token string, password string, p Pagination) (*SharedDocumentsResponse, error) {
+	state, err := s.openShareLink(ctx, token, password)
+	if err != nil {
+		return nil, err
+	}
+	ownerID := 0
+	if state.OwnerID != nil {
+		ownerID = *state.OwnerID
+	}
+
+	view := &CustomView{FilterRules: state.FilterRules, ColumnOrder: make([]interface{}, len(sharedDocumentColumns))}
+	for i, column := range sharedDocumentColumns {
+		view.ColumnOrder[i] = column
+	}
+	documents, err := s.GetViewDocuments(ctx, view, ownerID, p)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &SharedDocumentsResponse{Name: state.Name, ExpiresAt: state.ExpiresAt, Count: documents.Count, Results: []SharedDocument{}}
+	if len(documents.Results) > 0 {
+		names := make(map[string]map[int]string)
+		for _, column := range []string{"correspondent", "document_type", "tags"} {
+			lookup, err := s.queryReportNames(ctx, reportNameTables[column])
+			if err != nil {
+				return nil, err
+			}
+			names[column] = lookup
+		}
+		for _, document := range documents.Results {
+			id, _ := document["id"].(int)
+			shared := SharedDocument{ID: id, Title: reportCell(document["title"], nil), Tags: []string{}}
+			if value, ok := document["created"].(string); ok {
+				shared.Created = &value
+			}
+			if value, ok := document["added"].(string); ok {
+				shared.Added = &value
+			}
+			if document["correspondent"] != nil {
+				name := reportCell(document["correspondent"], names["correspondent"])
+				shared.Correspondent = &name
+			}
+			if document["document_type"] != nil {
+				name := reportCell(document["document_type"], names["document_type"])
+				shared.DocumentType = &name
+			}
+			tagIDs, _ := document["tags"].([]int)
+			for _, tagID := range tagIDs {
+				shared.Tags = append(shared.Tags, reportCell(tagID, names["tags"]))
+			}
+			response.Results = append(response.Results, shared)
+		}
+	}
+
+	query := s.rebind("UPDATE share_links SET access_count = access_count + 1, last_accessed = CURRENT_TIMESTAMP WHERE id = ?")
+	if _, err := s.db.ExecContext(ctx, query, *state.ID); err != nil {
+		log.Printf("[ShareLinks] Failed to count access of share link %d: %v", *state.ID, err)
+	}
+	return response, nil
+}
+
+// shareLinkErrorStatus maps share link errors to HTTP status codes
+func shareLinkErrorStatus(err error) int {
+	switch {
+	case err.Error() == "password required", err.Error() == "wrong password":
+		return http.StatusUnauthorized
+	case err.Error() == "too many wrong passwords, try again later":
+		return http.StatusTooManyRequests
+	case err.Error() == "share link has expired":
+		return http.StatusGone
+	case strings.HasPrefix(err.Error(), "invalid"), strings.Contains(err.Error(), "required"):
+		return http.StatusBadRequest
+	case strings.Contains(err.Error(), "not found"):
+		return http.StatusNotFound
+	}
+	return queryErrorStatus(err)
+}
+
+// respondShareLink sends a share link without its password
+func respondShareLink(w http.ResponseWriter, status int, link *ShareLink) {
+	link.Password = nil
+	respondJSON(w, status, link)
+}
+
+// HTTP Handlers for share links
+func (s *Service) handleListShareLinks(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	log.Printf("[ShareLinks] GET /api/share-links/ - Request from %s", r.RemoteAddr)
+
+	userID, err := viewerFromRequest(r)
+	if err != nil {
+		respondError(w, viewerErrorStatus(err), err.Error())
+		return
+	}
+
+	links, err := s.ListShareLinks(ctx, userID)
+	if err != nil {
+		log.Printf("[ShareLinks] Error listing share links: %v", err)
+		respondError(w, queryErrorStatus(err), err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, ShareLinkListResponse{Count: len(links), Results: links})
+}
+
+func (s *Service) handleGetShareLink(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	idStr := mux.Vars(r)["id"]
+	log.Printf("[ShareLinks] GET /api/share-links/%s/ - Request from %s", idStr, r.RemoteAddr)
+
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid share link ID")
+		return
+	}
+	userID, err := viewerFromRequest(r)
+	if err != nil {
+		respondError(w, viewerErrorStatus(err), err.Error())
+		return
+	}
+
+	link, err := s.GetShareLink(ctx, id, userID)
+	if err != nil {
+		respondError(w, shareLinkErrorStatus(err), err.Error())
+		return
+	}
+
+	respondShareLink(w, http.StatusOK, link)
+}
+
+func (s *Service) handleCreateShareLink(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	log.Printf("[ShareLinks] POST /api/share-links/ - Request from %s", r.RemoteAddr)
+
+	var link ShareLink
+	if err := json.NewDecoder(r.Body).Decode(&link); err != nil {
+		respondError(w, http.StatusBadRequest, fmt.Sprintf("Invalid request body: %v", err))
+		return
+	}
+	userID, err := viewerFromRequest(r)
+	if err != nil {
+		respondError(w, viewerErrorStatus(err), err.Error())
+		return
+	}
+
+	created, err := s.CreateShareLink(ctx, link, userID)
+	if err != nil {
+		log.Printf("[ShareLinks] Error creating share link: %v", err)
+		respondError(w, shareLinkErrorStatus(err), err.Error())
+		return
+	}
+
+	respondShareLink(w, http.StatusCreated, created)
+}
+
+func (s *Service) handleUpdateShareLink(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	idStr := mux.Vars(r)["id"]
+	log.Printf("[ShareLinks] %s /api/share-links/%s/ - Request from %s", r.Method, idStr, r.RemoteAddr)
+
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid share link ID")
+		return
+	}
+	var updates ShareLink
+	if err := json.NewDecoder(r.Body).Decode(&updates); err != nil {
+		respondError(w, http.StatusBadRequest, fmt.Sprintf("Invalid request body: %v", err))
+		return
+	}
+	userID, err := viewerFromRequest(r)
+	if err != nil {
+		respondError(w, viewerErrorStatus(err), err.Error())
+		return
+	}
+
+	updated, err := s.UpdateShareLink(ctx, id, updates, userID)
+	if err != nil {
+		log.Printf("[ShareLinks] Error updating share link %d: %v", id, err)
+		respondError(w, shareLinkErrorStatus(err), err.Error())
+		return
+	}
+
+	respondShareLink(w, http.StatusOK, updated)
+}
+
+func (s *Service) handleDeleteShareLink(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	idStr := mux.Vars(r)["id"]
+	log.Printf("[ShareLinks] DELETE /api/share-links/%s/ - Request from %s", idStr, r.RemoteAddr)
+
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid share link ID")
+		return
+	}
+	userID, err := viewerFromRequest(r)
+	if err != nil {
+		respondError(w, viewerErrorStatus(err), err.Error())
+		return
+	}
+
+	if err := s.DeleteShareLink(ctx, id, userID); err != nil {
+		log.Printf("[ShareLinks] Error deleting share link %d: %v", id, err)
+		respondError(w, shareLinkErrorStatus(err), err.Error())
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// wantsSharedHTML reports whether the documents of a share link are rendered as a page:
+// with format=html, or for browsers asking for HTML unless format=json
+func wantsSharedHTML(r *http.Request) bool {
+	switch r.URL.Query().Get("format") {
+	case "html":
+		return true
+	case "json":
+		return false
+	}
+	return strings.Contains(r.Header.Get("Accept"), "text/html")
+}
+
+// handleGetSharedDocuments serves the public listing of a share link. It needs no user; the
+// token is the credential, plus the password of protected links from the X-Share-Password
+// header or a password form field.
+func (s *Service) handleGetSharedDocuments(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	// Only a prefix of the token is logged so the logs do not hold usable links
+	token := mux.Vars(r)["token"]
+	log.Printf("[ShareLinks] %s /api/shared/%s.../ - Request from %s", r.Method, token[:8], r.RemoteAddr)
+
+	html := wantsSharedHTML(r)
+	pagination, err := parsePagination(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	// Shared lists are always paginated
+	if pagination.PageSize == 0 {
+		pagination.PageSize = defaultPageSize
+	}
+	password := r.Header.Get("X-Share-Password")
+	if password == "" {
+		password = r.PostFormValue("password")
+	}
+
+	response, err := s.GetSharedDocuments(ctx, token, password, pagination)
+	if err != nil {
+		status := shareLinkErrorStatus(err)
+		if status >= http.StatusInternalServerError {
+			log.Printf("[ShareLinks] Error listing shared documents: %v", err)
+		}
+		if html {
+			renderSharedHTML(w, status, sharedPage{Error: err.Error(), NeedsPassword: status == http.StatusUnauthorized})
+			return
+		}
+		respondError(w, status, err.Error())
+		return
+	}
+	response.Next, response.Previous = pageLinks(r, pagination, response.Count)
+
+	if html {
+		page := sharedPage{SharedDocumentsResponse: response, Password: password}
+		if response.Next != nil {
+			page.NextPage = pagination.Page + 1
+		}
+		if response.Previous != nil {
+			page.PreviousPage = pagination.Page - 1
+		}
+		page.PageSize = pagination.PageSize
+		renderSharedHTML(w, http.StatusOK, page)
+		return
+	}
+	respondJSON(w, http.StatusOK, response)
+}
+
+// sharedPage is the data of the HTML page of a share link
+type sharedPage struct {
+	*SharedDocumentsResponse
+	Error         string
+	NeedsPassword bool
+	Password      string // Posted again by the paging buttons of protected links
+	PreviousPage  int
+	NextPage      int
+	PageSize      int
+}
+
+// sharedPageTemplate renders a share link as a plain page. Paging posts a form so the
+// password of protected links stays out of the URL.
+var sharedPageTemplate = template.Must(template.New("shared").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="robots" content="noindex">
+<title>{{if .SharedDocumentsResponse}}{{.Name}}{{else}}Shared documents{{end}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+table { border-collapse: collapse; width: 100%; }
+th, td { text-align: left; padding: 0.4em 0.6em; border-bottom: 1px solid #ddd; }
+form { display: inline; }
+</style>
+</head>
+<body>
+{{if .SharedDocumentsResponse}}
+<h1>{{.Name}}</h1>
+<p>{{.Count}} documents{{if .ExpiresAt}}, shared until {{.ExpiresAt}}{{end}}</p>
+<table>
+<tr><th>Title</th><th>Created</th><th>Correspondent</th><th>Document type</th><th>Tags</th></tr>
+{{range .Results}}<tr><td>{{.Title}}</td><td>{{if .Created}}{{.Created}}{{end}}</td><td>{{if .Correspondent}}{{.Correspondent}}{{end}}</td><td>{{if .DocumentType}}{{.DocumentType}}{{end}}</td><td>{{range $i, $tag := .Tags}}{{if $i}}, {{end}}{{$tag}}{{end}}</td></tr>
+{{end}}</table>
+<p>
+{{if .PreviousPage}}<form method="post" action="?format=html&amp;page={{.PreviousPage}}&amp;page_size={{.PageSize}}"><input type="hidden" name="password" value="{{.Password}}"><button>Previous</button></form>{{end}}
+{{if .NextPage}}<form method="post" action="?format=html&amp;page={{.NextPage}}&amp;page_size={{.PageSize}}"><input type="hidden" name="password" value="{{.Password}}"><button>Next</button></form>{{end}}
+</p>
+{{else if .NeedsPassword}}
+<h1>Shared documents</h1>
+<form method="post" action="?format=html">
+<p>This list is protected by a password.{{if eq .Error "wrong password"}} The password was wrong.{{end}}</p>
+<input type="password" name="password" autofocus> <button>Open</button>
+</form>
+{{else}}
+<h1>Shared documents</h1>
+<p>This link is not available: {{.Error}}.</p>
+{{end}}
+</body>
+</html>
+`))
+
+// renderSharedHTML writes the HTML page of a share link
+func renderSharedHTML(w http.ResponseWriter, status int, page sharedPage) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	if err := sharedPageTemplate.Execute(w, page); err != nil {
+		log.Printf("[ShareLinks] Error rendering shared documents: %v", err)
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+func TestSharePasswordHashes(t *testing.T) {
+	hash, err := hashSharePassword("s3cret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(hash, "$2") {
+		t.Errorf("hash %q is not a bcrypt hash", hash)
+	}
+	if !checkSharePassword(hash, "s3cret") {
+		t.Error("right password refused")
+	}
+	if checkSharePassword(hash, "s3cret!") {
+		t.Error("wrong password accepted")
+	}
+	if _, err := hashSharePassword(strings.Repeat("x", 73)); err == nil || !strings.HasPrefix(err.Error(), "invalid") {
+		t.Errorf("password over 72 bytes: got %v, want an invalid password error", err)
+	}
+
+}
+
+func TestSharePasswordAttempts(t *testing.T) {
+	var attempts sharePasswordAttempts
+	now := time.Now()
+
+	for i := 0; i < maxSharePasswordFailures; i++ {
+		if !attempts.begin(1, now) {
+			t.Fatalf("attempt %d refused", i+1)
+		}
+	}
+	if attempts.begin(1, now) {
+		t.Fatal("attempt allowed after the limit")
+	}
+	if !attempts.begin(2, now) {
+		t.Error("wrong passwords of one link locked another")
+	}
+	if !attempts.begin(1, now.Add(sharePasswordLockout)) {
+		t.Error("link still locked after the lockout")
+	}
+
+	// The right password forgets the wrong ones
+	for i := 0; i < maxSharePasswordFailures-1; i++ {
+		attempts.begin(3, now)
+	}
+	attempts.succeed(3)
+	for i := 0; i < maxSharePasswordFailures; i++ {
+		if !attempts.begin(3, now) {
+			t.Fatalf("attempt %d after the right password refused", i+1)
+		}
+	}
+}
+
+func TestSharedDocumentsLockOutWrongPasswords(t *testing.T) {
+	s := newTestService(t, nil)
+	mustExec(t, s, `INSERT INTO auth_user (id, username, is_superuser) VALUES (1, 'admin', 1)`)
+	password := "s3cret"
+	link, err := s.CreateShareLink(context.Background(), ShareLink{Name: "Tax", Password: &password}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	open := func(password string) int {
+		req := httptest.NewRequest(http.MethodGet, "/api/shared/"+link.Token+"/?format=json", nil)
+		req = mux.SetURLVars(req, map[string]string{"token": link.Token})
+		req.Header.Set("X-Share-Password", password)
+		rec := httptest.NewRecorder()
+		s.handleGetSharedDocuments(rec, req)
+		return rec.Code
+	}
+
+	if status := open(password); status != http.StatusOK {
+		t.Fatalf("right password: status %d", status)
+	}
+	for i := 0; i < maxSharePasswordFailures; i++ {
+		if status := open("guess"); status != http.StatusUnauthorized {
+			t.Fatalf("wrong password %d: status %d, want 401", i+1, status)
+		}
+	}
+	if status := open(password); status != http.StatusTooManyRequests {
+		t.Errorf("right password while locked: status %d, want 429", status)
+	}
+}
+
+func TestShareLinkHandlersRequireUser(t *testing.T) {
+	s := newTestService(t, nil)
+	mustExec(t, s, `INSERT INTO auth_user (id, username, is_superuser) VALUES (1, 'admin', 1)`)
+
+	for header, want := range map[string]int{"": http.StatusUnauthorized, "admin": http.StatusBadRequest, "0": http.StatusBadRequest} {
+		req := httptest.NewRequest(http.MethodPost, "/api/share-links/", strings.NewReader(`{"name": "Everything"}`))
+		if header != "" {
+			req.Header.Set("X-User-ID", header)
+		}
+		rec := httptest.NewRecorder()
+		s.handleCreateShareLink(rec, req)
+		if rec.Code != want {
+			t.Errorf("X-User-ID %q: status %d, want %d", header, rec.Code, want)
+		}
+	}
+	var links int
+	if err := s.db.QueryRow("SELECT COUNT(*) FROM share_links").Scan(&links); err != nil {
+		t.Fatal(err)
+	}
+	if links != 0 {
+		t.Errorf("%d share links created without a user", links)
+	}
+}
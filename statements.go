@@ -0,0 +1,227 @@
+package main
+
+import (
+	"container/list"
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// maxPreparedStatements bounds the statements kept prepared; the least recently used one
+// is closed to make room for a new query
+const maxPreparedStatements = 256
+
+// fieldInfoQuery looks up the metadata of a custom field
+const fieldInfoQuery = "SELECT name, data_type, extra_data FROM documents_customfield WHERE id = ?"
+
+// valueColumns are the value columns of custom field instances
+var valueColumns = []string{"value_text", "value_url", "value_date", "value_bool", "value_int", "value_float",
+	"value_monetary", "value_document_ids", "value_select", "value_long_text"}
+
+// statementCache keeps queries of a fixed shape prepared, so the database parses and plans
+// them once per connection instead of on every request. Statements are keyed by their SQL
+// and evicted least recently used first. Queries are prepared outside the lock, once even
+// when requested concurrently, so a slow prepare does not hold up the cached statements.
+type statementCache struct {
+	db      *sql.DB
+	mu      sync.Mutex
+	entries map[string]*list.Element // Values are *cachedStatement
+	order   *list.List               // Most recently used first
+	prepare singleflight.Group
+}
+
+// cachedStatement is a prepared statement of the cache
+type cachedStatement struct {
+	query   string
+	stmt    *sql.Stmt
+	users   int  // Queries being started on the statement
+	evicted bool // Evicted while in use, closed by the last user
+}
+
+// newStatementCache creates an empty statement cache on db
+func newStatementCache(db *sql.DB) *statementCache {
+	return &statementCache{db: db, entries: make(map[string]*list.Element), order: list.New()}
+}
+
+// get returns the prepared statement of query, preparing it on first use, and a function
+// releasing it once the query has been started: rows keep their statement usable until
+// they are closed, even when it is evicted meanwhile.
+func (c *statementCache) get(ctx context.Context, query string) (*sql.Stmt, func(), error) {
+	if entry := c.acquire(query); entry != nil {
+		return entry.stmt, func() { c.release(entry) }, nil
+	}
+
+	// The prepared statement outlives the request that happened to prepare it
+	prepareCtx := context.WithoutCancel(ctx)
+	_, err, _ := c.prepare.Do(query, func() (interface{}, error) {
+		c.mu.Lock()
+		_, ok := c.entries[query]
+		c.mu.Unlock()
+		if ok {
+			return nil, nil
+		}
+		stmt, err := c.db.PrepareContext(prepareCtx, query)
+		if err != nil {
+			return nil, err
+		}
+		c.add(query, stmt)
+		return nil, nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	entry := c.acquire(query)
+	if entry == nil {
+		// Evicted again right away by concurrent queries; run this one unprepared
+		return nil, func() {}, nil
+	}
+	return entry.stmt, func() { c.release(entry) }, nil
+}
+
+// acquire returns the cached statement of query marked as in use, or nil
+func (c *statementCache) acquire(query string) *cachedStatement {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	element, ok := c.entries[query]
+	if !ok {
+		return nil
+	}
+	c.order.MoveToFront(element)
+	entry := element.Value.(*cachedStatement)
+	entry.users++
+	return entry
+}
+
+// release marks a statement returned by acquire as no longer in use, closing it when it
+// was evicted meanwhile
+func (c *statementCache) release(entry *cachedStatement) {
+	c.mu.Lock()
+	entry.users--
+	closing := entry.evicted && entry.users == 0
+	c.mu.Unlock()
+	if closing {
+		entry.stmt.Close()
+	}
+}
+
+// add caches a newly prepared statement, evicting the least recently used ones beyond
+// maxPreparedStatements
+func (c *statementCache) add(query string, stmt *sql.Stmt) {
+	var closing []*sql.Stmt
+	c.mu.Lock()
+	c.entries[query] = c.order.PushFront(&cachedStatement{query: query, stmt: stmt})
+	for c.order.Len() > maxPreparedStatements {
+		entry := c.order.Remove(c.order.Back()).(*cachedStatement)
+		delete(c.entries, entry.query)
+		entry.evicted = true
+		if entry.users == 0 {
+			closing = append(closing, entry.stmt)
+		}
+	}
+	c.mu.Unlock()
+	for _, stmt := range closing {
+		stmt.Close()
+	}
+}
+
+// Len returns the number of prepared statements
+func (c *statementCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
+
+// Close closes the prepared statements
+func (c *statementCache) Close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for query, element := range c.entries {
+		element.Value.(*cachedStatement).stmt.Close()
+		delete(c.entries, query)
+	}
+	c.order.Init()
+}
+
+// queryRowPrepared runs a query of a fixed shape with its prepared statement. Queries that
+// cannot be prepared run as usual, reporting their errors when scanned.
+func (s *Service) queryRowPrepared(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	stmt, release, err := s.statements.get(ctx, query)
+	if err != nil || stmt == nil {
+		return s.db.QueryRowContext(ctx, query, args...)
+	}
+	defer release()
+	return stmt.QueryRowContext(ctx, args...)
+}
+
+// queryPrepared runs a query of a fixed shape with its prepared statement
+func (s *Service) queryPrepared(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	stmt, release, err := s.statements.get(ctx, query)
+	if err != nil || stmt == nil {
+		return s.db.QueryContext(ctx, query, args...)
+	}
+	defer release()
+	return stmt.QueryContext(ctx, args...)
+}
+
+// fieldValuesQuery returns the query of the values of a custom field with their documents,
+// without filter rules
+func (s *Service) fieldValuesQuery(valueColumn string, includeTrashed bool) string {
+	return s.rebind(fmt.Sprintf(`
+		SELECT
+			cfi.%s as value,
+			cfi.document_id
+		FROM documents_customfieldinstance cfi
+		INNER JOIN documents_document d ON cfi.document_id = d.id
+		WHERE cfi.field_id = ?
+			AND cfi.deleted_at IS NULL
+			AND cfi.%s IS NOT NULL
+			AND cfi.%s != ''
+			AND %s
+	`, valueColumn, valueColumn, valueColumn, trashedCondition(includeTrashed)))
+}
+
+// blankCountQuery returns the query counting the documents without a value of a custom
+// field, without filter rules
+func (s *Service) blankCountQuery(valueColumn string, includeTrashed bool) string {
+	return s.rebind(fmt.Sprintf(`
+		SELECT COUNT(DISTINCT d.id)
+		FROM documents_document d
+		WHERE %s
+		AND NOT EXISTS (
+			SELECT 1 FROM documents_customfieldinstance cfi3
+			WHERE cfi3.document_id = d.id
+			AND cfi3.field_id = ?
+			AND cfi3.deleted_at IS NULL
+			AND cfi3.%s IS NOT NULL
+			AND cfi3.%s != ''
+		)
+	`, trashedCondition(includeTrashed), valueColumn, valueColumn))
+}
+
+// prepareStatements prepares the field metadata lookup and the unfiltered value and blank
+// count queries of every value column. Builtin filter value queries are prepared when
+// first run. Failures are logged; the queries then run unprepared.
+func (s *Service) prepareStatements(ctx context.Context) {
+	queries := []string{s.rebind(fieldInfoQuery)}
+	for _, column := range valueColumns {
+		for _, includeTrashed := range []bool{false, true} {
+			queries = append(queries, s.fieldValuesQuery(column, includeTrashed), s.blankCountQuery(column, includeTrashed))
+		}
+	}
+
+	failed := 0
+	for _, query := range queries {
+		if _, release, err := s.statements.get(ctx, query); err != nil {
+			failed++
+			log.Printf("[Statements] Failed to prepare %s: %v", strings.Join(strings.Fields(query), " "), err)
+		} else {
+			release()
+		}
+	}
+	log.Printf("[Statements] Prepared %d statements (%d failed)", len(queries)-failed, failed)
+}
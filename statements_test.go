@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+// getStatement returns the prepared statement of query, failing the test on errors
+func getStatement(t *testing.T, c *statementCache, query string) (*sql.Stmt, func()) {
+	t.Helper()
+	stmt, release, err := c.get(context.Background(), query)
+	if err != nil || stmt == nil {
+		t.Fatalf("failed to prepare %s: %v", query, err)
+	}
+	return stmt, release
+}
+
+func TestStatementCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	s := newTestService(t, nil)
+	c := newStatementCache(s.db)
+	t.Cleanup(c.Close)
+
+	query := func(i int) string { return fmt.Sprintf("SELECT %d", i) }
+	for i := 0; i < maxPreparedStatements; i++ {
+		_, release := getStatement(t, c, query(i))
+		release()
+	}
+	// Using the oldest statement again makes the second one the least recently used
+	_, release := getStatement(t, c, query(0))
+	release()
+	_, release = getStatement(t, c, query(maxPreparedStatements))
+	release()
+
+	if c.Len() != maxPreparedStatements {
+		t.Errorf("cached %d statements, want %d", c.Len(), maxPreparedStatements)
+	}
+	if c.acquire(query(1)) != nil {
+		t.Errorf("%s was not evicted", query(1))
+	}
+	for _, i := range []int{0, 2, maxPreparedStatements} {
+		entry := c.acquire(query(i))
+		if entry == nil {
+			t.Errorf("%s was evicted", query(i))
+			continue
+		}
+		c.release(entry)
+	}
+}
+
+func TestStatementCacheKeepsStatementsInUse(t *testing.T) {
+	s := newTestService(t, nil)
+	c := newStatementCache(s.db)
+	t.Cleanup(c.Close)
+
+	stmt, release := getStatement(t, c, "SELECT 42 AS answer")
+	for i := 0; i < maxPreparedStatements; i++ {
+		_, releaseOther := getStatement(t, c, fmt.Sprintf("SELECT %d", i))
+		releaseOther()
+	}
+
+	var value int
+	if err := stmt.QueryRow().Scan(&value); err != nil || value != 42 {
+		t.Fatalf("statement evicted while in use: got %d, %v", value, err)
+	}
+	release()
+	if err := stmt.QueryRow().Scan(&value); err == nil {
+		t.Error("evicted statement was not closed after its last use")
+	}
+}
+
+func TestStatementCachePreparesConcurrentQueriesOnce(t *testing.T) {
+	s := newTestService(t, nil)
+	c := newStatementCache(s.db)
+	t.Cleanup(c.Close)
+
+	statements := make([]*sql.Stmt, 20)
+	var wg sync.WaitGroup
+	for i := range statements {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			stmt, release, err := c.get(context.Background(), "SELECT 1")
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			statements[i] = stmt
+			release()
+		}(i)
+	}
+	wg.Wait()
+
+	for _, stmt := range statements {
+		if stmt == nil || stmt != statements[0] {
+			t.Fatal("concurrent queries got different statements")
+		}
+	}
+	if c.Len() != 1 {
+		t.Errorf("cached %d statements, want 1", c.Len())
+	}
+}
+
+func TestBuiltinFilterValuesBindViewer(t *testing.T) {
+	s := newFacetTestService(t)
+	mustExec(t, s,
+		`INSERT INTO auth_user (id, username, is_superuser) VALUES (2, 'alice', 0), (3, 'bob', 0)`,
+		`INSERT INTO guardian_userobjectpermission (user_id, permission_id, content_type_id, object_pk) VALUES (2, 1, 1, '1')`,
+	)
+	ctx := context.Background()
+
+	tests := []struct {
+		viewerID    int
+		filterRules string
+		want        []string
+	}{
+		{2, "", []string{"ACME:2"}},
+		{3, "", []string{"ACME:1"}},
+		{99, "", []string{"ACME:1", "Bank:1"}},
+		{2, `[{"rule_type": 0, "value": "first"}]`, []string{"ACME:1"}},
+		{99, `[{"rule_type": 4, "value": "1"}]`, []string{"ACME:1"}},
+	}
+	before := s.statements.Len()
+	for _, tt := range tests {
+		values, err := s.GetBuiltinFilterValues(ctx, "correspondent", tt.filterRules, false, false, ASNBucketing{}, tt.viewerID)
+		if err != nil {
+			t.Fatalf("viewer %d %s: %v", tt.viewerID, tt.filterRules, err)
+		}
+		if got := facetCounts(values); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("viewer %d %s: got %v, want %v", tt.viewerID, tt.filterRules, got, tt.want)
+		}
+	}
+	if prepared := s.statements.Len() - before; prepared != 1 {
+		t.Errorf("prepared %d statements for the viewers, want 1", prepared)
+	}
+}
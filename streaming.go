@@ -0,0 +1,229 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// ndjsonContentType is the media type for newline-delimited JSON responses
+const ndjsonContentType = "application/x-ndjson"
+
+// ndjsonFlushEvery controls how many options are written between flushes
+const ndjsonFlushEvery = 500
+
+// wantsNDJSON reports whether the client asked for a newline-delimited JSON stream
+func wantsNDJSON(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), ndjsonContentType)
+}
+
+// StreamValueCounts aggregates value counts for a custom field and passes each option
+// to emit, instead of building and encoding the whole value list in one response.
+//
+// List values are split into their entries and counted per trimmed entry in SQL, ordered
+// by the byte order of the entries whatever the collation of the column, so only the
+// option being counted is held while scanning. Options are emitted in byte order of their
+// values followed by the blank option; sort parameters are not applied in streaming mode.
+func (s *Service) StreamValueCounts(ctx context.Context, fieldID int, filterRulesJSON string, includeTrashed bool, emit func(CustomFieldValueOption) error) error {
+	usePostgres := s.config.DBEngine == "postgresql" || s.config.DBEngine == "postgres"
+
+	meta, err := s.getFieldMeta(ctx, fieldID)
+	if err != nil {
+		return fmt.Errorf("custom field with id %d not found: %w", fieldID, err)
+	}
+	dataType, extraDataJSON := meta.DataType, meta.ExtraData
+
+	// Parse select_options for SELECT fields
+	selectOptionMap := make(map[string]string)
+	if dataType == "select" && len(extraDataJSON) > 0 {
+		var extraData map[string]interface{}
+		if err := json.Unmarshal(extraDataJSON, &extraData); err == nil {
+			if selectOptions, ok := extraData["select_options"].([]interface{}); ok {
+				for _, opt := range selectOptions {
+					if optMap, ok := opt.(map[string]interface{}); ok {
+						if optID, ok := optMap["id"].(string); ok {
+							if optLabel, ok := optMap["label"].(string); ok {
+								selectOptionMap[optID] = optLabel
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+
+	toOption := func(value string, count int) CustomFieldValueOption {
+		if dataType == "select" {
+			label := value
+			if mappedLabel, exists := selectOptionMap[value]; exists {
+				label = mappedLabel
+			}
+			return CustomFieldValueOption{ID: value, Label: label, Count: count}
+		}
+		return CustomFieldValueOption{ID: generateID(value), Label: value, Count: count}
+	}
+
+	valueColumn := getValueColumnName(dataType)
+
+	docFilterWhere, docFilterArgs, err := s.buildDocumentFilterQuery(ctx, filterRulesJSON, fieldID, 0)
+	if err != nil {
+		return err
+	}
+
+	where := "WHERE " + trashedCondition(includeTrashed)
+	if docFilterWhere != "" {
+		where = docFilterWhere + " AND " + trashedCondition(includeTrashed)
+	}
+	fieldPlaceholder := "?"
+	if usePostgres {
+		fieldPlaceholder = fmt.Sprintf("$%d", len(docFilterArgs)+1)
+	}
+	args := append(append([]interface{}{}, docFilterArgs...), fieldID)
+
+	// Values are split into their comma/colon/semicolon separated entries in SQL, on the
+	// textual value so numeric and date columns work too, and counted per trimmed entry in
+	// byte order
+	var textValue, position, entry string
+	appendSeparator := func(expression string) string { return expression + " || ','" }
+	switch s.config.DBEngine {
+	case "postgresql", "postgres":
+		textValue = fmt.Sprintf("CAST(cfi.%s AS TEXT)", valueColumn)
+		position = "STRPOS(%s, ',')"
+		entry = `TRIM(part) COLLATE "C"`
+	case "mysql", "mariadb":
+		textValue = fmt.Sprintf("CAST(cfi.%s AS CHAR)", valueColumn)
+		position = "INSTR(%s, ',')"
+		entry = "CAST(TRIM(part) AS BINARY)"
+		appendSeparator = func(expression string) string { return "CONCAT(" + expression + ", ',')" }
+	default:
+		textValue = fmt.Sprintf("CAST(cfi.%s AS TEXT)", valueColumn)
+		position = "INSTR(%s, ',')"
+		entry = "TRIM(part)"
+	}
+	entries := appendSeparator(fmt.Sprintf("REPLACE(REPLACE(%s, ':', ','), ';', ',')", textValue))
+	first := fmt.Sprintf(position, entries)
+	next := fmt.Sprintf(position, "rest")
+
+	// A document has at most one instance per field and is counted once per entry, however
+	// often its list repeats it
+	query := fmt.Sprintf(`
+		WITH RECURSIVE entries (document_id, part, rest) AS (
+			SELECT cfi.document_id, SUBSTR(%s, 1, %s - 1), SUBSTR(%s, %s + 1)
+			FROM documents_customfieldinstance cfi
+			INNER JOIN documents_document d ON cfi.document_id = d.id
+			%s
+			AND cfi.field_id = %s
+			AND cfi.deleted_at IS NULL
+			AND cfi.%s IS NOT NULL
+			AND cfi.%s != ''
+			UNION ALL
+			SELECT document_id, SUBSTR(rest, 1, %s - 1), SUBSTR(rest, %s + 1)
+			FROM entries
+			WHERE rest != ''
+		)
+		SELECT value, COUNT(DISTINCT document_id) as doc_count
+		FROM (SELECT document_id, %s as value FROM entries WHERE TRIM(part) != '') e
+		GROUP BY value
+		ORDER BY value
+	`, entries, first, entries, first, where, fieldPlaceholder, valueColumn, valueColumn, next, next, entry)
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to query field values: %w", err)
+	}
+	defer rows.Close()
+
+	// SQL only trims spaces; entries that are equal once trimmed of other whitespace as
+	// well arrive next to each other and are merged
+	var current string
+	total := 0
+	for rows.Next() {
+		var value string
+		var count int
+		if err := rows.Scan(&value, &count); err != nil {
+			continue
+		}
+		if value = strings.TrimSpace(value); value == "" {
+			continue
+		}
+		if total > 0 && value != current {
+			if err := emit(toOption(current, total)); err != nil {
+				return err
+			}
+			total = 0
+		}
+		current = value
+		total += count
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read field values: %w", err)
+	}
+	if total > 0 {
+		if err := emit(toOption(current, total)); err != nil {
+			return err
+		}
+	}
+	rows.Close()
+
+	// Count documents where the field is blank/null
+	blankQuery := fmt.Sprintf(`
+		SELECT COUNT(DISTINCT d.id)
+		FROM documents_document d
+		%s
+		AND NOT EXISTS (
+			SELECT 1 FROM documents_customfieldinstance cfi3
+			WHERE cfi3.document_id = d.id
+			AND cfi3.field_id = %s
+			AND cfi3.deleted_at IS NULL
+			AND cfi3.%s IS NOT NULL
+			AND cfi3.%s != ''
+		)
+	`, where, fieldPlaceholder, valueColumn, valueColumn)
+
+	var blankCount int
+	if err := s.db.QueryRowContext(ctx, blankQuery, args...).Scan(&blankCount); err == nil && blankCount > 0 {
+		return emit(CustomFieldValueOption{
+			ID:    "__blank__",
+			Label: "(Blank)",
+			Count: blankCount,
+		})
+	}
+
+	return nil
+}
+
+// streamValueCountsResponse writes value counts for a field as an NDJSON stream
+func (s *Service) streamValueCountsResponse(ctx context.Context, w http.ResponseWriter, fieldID int, filterRulesJSON string, includeTrashed bool) {
+	w.Header().Set("Content-Type", ndjsonContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+
+	flusher, _ := w.(http.Flusher)
+	encoder := json.NewEncoder(w)
+	written := 0
+
+	err := s.StreamValueCounts(ctx, fieldID, filterRulesJSON, includeTrashed, func(option CustomFieldValueOption) error {
+		if err := encoder.Encode(option); err != nil {
+			return err
+		}
+		written++
+		if flusher != nil && written%ndjsonFlushEvery == 0 {
+			flusher.Flush()
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("[Streaming] Field %d: stream aborted after %d options: %v", fieldID, written, err)
+		if written == 0 {
+			// Nothing has been sent yet, so a proper error status can still be returned
+			respondError(w, valueCountsErrorStatus(err), err.Error())
+		}
+		return
+	}
+
+	if flusher != nil {
+		flusher.Flush()
+	}
+}
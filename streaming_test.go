@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+// newValueCountsTestService creates a service with a text custom field (1) whose values
+// sort apart in SQL but are the same once trimmed or split
+func newValueCountsTestService(t *testing.T) *Service {
+	t.Helper()
+	s := newTestService(t, nil)
+	mustExec(t, s,
+		`INSERT INTO auth_user (id, username, is_superuser) VALUES (1, 'admin', 1)`,
+		`INSERT INTO documents_customfield (id, name, data_type) VALUES (1, 'Project', 'string')`,
+		`INSERT INTO documents_document (id, title) VALUES (1, 'a'), (2, 'b'), (3, 'spaced b'), (4, 'list'), (5, 'c'), (6, 'blank')`,
+		`INSERT INTO documents_customfieldinstance (document_id, field_id, value_text) VALUES
+			(1, 1, 'alpha'), (2, 1, 'beta'), (3, 1, ' beta'), (4, 1, 'alpha, beta, delta'), (5, 1, 'Beta')`,
+	)
+	return s
+}
+
+func TestStreamValueCountsMergesValuesTheDatabaseSortsApart(t *testing.T) {
+	s := newValueCountsTestService(t)
+
+	var options []CustomFieldValueOption
+	err := s.StreamValueCounts(context.Background(), 1, "", false, func(option CustomFieldValueOption) error {
+		options = append(options, option)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := map[string]int{}
+	var labels []string
+	for _, option := range options {
+		if _, seen := got[option.Label]; seen {
+			t.Errorf("option %q emitted twice", option.Label)
+		}
+		got[option.Label] = option.Count
+		labels = append(labels, option.Label)
+	}
+	want := map[string]int{"Beta": 1, "alpha": 2, "beta": 3, "delta": 1, "(Blank)": 1}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("counts %v, want %v", got, want)
+	}
+	if wantOrder := []string{"Beta", "alpha", "beta", "delta", "(Blank)"}; !reflect.DeepEqual(labels, wantOrder) {
+		t.Errorf("order %v, want %v", labels, wantOrder)
+	}
+}
+
+func TestStreamValueCountsCountsRepeatedListEntriesOnce(t *testing.T) {
+	s := newValueCountsTestService(t)
+	mustExec(t, s,
+		`INSERT INTO documents_document (id, title) VALUES (7, 'repeated')`,
+		`INSERT INTO documents_customfieldinstance (document_id, field_id, value_text) VALUES (7, 1, 'delta;delta : gamma,')`,
+	)
+
+	got := map[string]int{}
+	err := s.StreamValueCounts(context.Background(), 1, "", false, func(option CustomFieldValueOption) error {
+		got[option.Label] += option.Count
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got["delta"] != 2 || got["gamma"] != 1 {
+		t.Errorf("counts %v, want delta 2 and gamma 1", got)
+	}
+}
+
+func TestStreamValueCountsMatchesBufferedCounts(t *testing.T) {
+	s := newValueCountsTestService(t)
+	ctx := context.Background()
+	filterRules := `[{"rule_type": 0, "value": "a"}]`
+
+	buffered, err := s.GetValueCounts(ctx, 1, filterRules, "", "", false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]int{}
+	for _, option := range buffered {
+		want[option.Label] = option.Count
+	}
+	got := map[string]int{}
+	err = s.StreamValueCounts(ctx, 1, filterRules, false, func(option CustomFieldValueOption) error {
+		got[option.Label] += option.Count
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("streamed %v, buffered %v", got, want)
+	}
+}
+
+func TestValueCountsRejectInvalidFilterRules(t *testing.T) {
+	s := newValueCountsTestService(t)
+
+	for _, accept := range []string{"application/json", ndjsonContentType} {
+		req := httptest.NewRequest(http.MethodPost, "/api/custom-field-values/1/counts/", strings.NewReader(`{"filter_rules": ["not a rule"]}`))
+		req = mux.SetURLVars(req, map[string]string{"fieldId": "1"})
+		req.Header.Set("Accept", accept)
+		req.Header.Set("X-User-ID", "1")
+		rec := httptest.NewRecorder()
+		s.handleGetValueCounts(rec, req)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("Accept %s: status %d, want 400: %s", accept, rec.Code, rec.Body)
+			continue
+		}
+		var body map[string]interface{}
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || !strings.Contains(rec.Body.String(), "invalid filter_rules") {
+			t.Errorf("Accept %s: body %s", accept, rec.Body)
+		}
+	}
+}
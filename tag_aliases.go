@@ -0,0 +1,464 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// queryTagAliases retrieves the aliases selected by condition together with the names of
+// their tags
+func (s *Service) queryTagAliases(ctx context.Context, condition string, args ...interface{}) ([]TagAlias, error) {
+	rows, err := s.db.QueryContext(ctx, s.rebind(`
+		SELECT a.id, a.alias_tag_id, a.canonical_tag_id, alias_tag.name, canonical_tag.name, a.created, a.modified, a.created_by
+		FROM tag_aliases a
+		LEFT JOIN documents_tag alias_tag ON alias_tag.id = a.alias_tag_id
+		LEFT JOIN documents_tag canonical_tag ON canonical_tag.id = a.canonical_tag_id
+		WHERE `+condition+`
+		ORDER BY a.canonical_tag_id ASC, a.alias_tag_id ASC
+	`), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tag aliases: %w", err)
+	}
+	defer rows.Close()
+
+	aliases := []TagAlias{}
+	for rows.Next() {
+		var alias TagAlias
+		var id int
+		var aliasName, canonicalName sql.NullString
+		var created, modified dbTimestamp
+		var createdBy sql.NullInt64
+		if err := rows.Scan(&id, &alias.AliasTagID, &alias.CanonicalTagID, &aliasName, &canonicalName, &created, &modified, &createdBy); err != nil {
+			return nil, fmt.Errorf("failed to scan tag alias: %w", err)
+		}
+		alias.ID = &id
+		alias.AliasName = aliasName.String
+		alias.CanonicalName = canonicalName.String
+		alias.Created = s.formatTimestamp(created)
+		alias.Modified = s.formatTimestamp(modified)
+		if createdBy.Valid {
+			userID := int(createdBy.Int64)
+			alias.CreatedBy = &userID
+		}
+		aliases = append(aliases, alias)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read tag aliases: %w", err)
+	}
+	return aliases, nil
+}
+
+// ListTagAliases retrieves all tag aliases, or only those of one canonical tag when
+// canonicalTagID is not 0
+func (s *Service) ListTagAliases(ctx context.Context, canonicalTagID int) ([]TagAlias, error) {
+	if canonicalTagID != 0 {
+		return s.queryTagAliases(ctx, "a.canonical_tag_id = ?", canonicalTagID)
+	}
+	return s.queryTagAliases(ctx, "1 = 1")
+}
+
+// GetTagAlias retrieves a tag alias by ID
+func (s *Service) GetTagAlias(ctx context.Context, id int) (*TagAlias, error) {
+	aliases, err := s.queryTagAliases(ctx, "a.id = ?", id)
+	if err != nil {
+		return nil, err
+	}
+	if len(aliases) == 0 {
+		return nil, fmt.Errorf("tag alias not found")
+	}
+	return &aliases[0], nil
+}
+
+// checkTagAlias validates an alias before it is stored under id (0 for a new alias). Both
+// tags must exist and aliases do not chain: a canonical tag cannot be an alias itself and
+// an alias cannot be the canonical tag of other aliases.
+func (s *Service) checkTagAlias(ctx context.Context, alias TagAlias, id int) error {
+	if alias.AliasTagID <= 0 || alias.CanonicalTagID <= 0 {
+		return fmt.Errorf("alias_tag_id and canonical_tag_id are required")
+	}
+	if alias.AliasTagID == alias.CanonicalTagID {
+		return fmt.Errorf("invalid alias: tag %d cannot be an alias of itself", alias.AliasTagID)
+	}
+	if err := s.checkTagIDsExist(ctx, []int{alias.AliasTagID, alias.CanonicalTagID}); err != nil {
+		return err
+	}
+
+	var existingID int
+	err := s.db.QueryRowContext(ctx, s.rebind("SELECT id FROM tag_aliases WHERE alias_tag_id = ? AND id <> ?"), alias.AliasTagID, id).Scan(&existingID)
+	if err == nil {
+		return fmt.Errorf("an alias for tag %d already exists", alias.AliasTagID)
+	} else if err != sql.ErrNoRows {
+		return fmt.Errorf("failed to check tag alias: %w", err)
+	}
+
+	var canonicalOf int
+	err = s.db.QueryRowContext(ctx, s.rebind("SELECT canonical_tag_id FROM tag_aliases WHERE alias_tag_id = ? AND id <> ?"), alias.CanonicalTagID, id).Scan(&canonicalOf)
+	if err == nil {
+		return fmt.Errorf("invalid alias: tag %d is itself an alias of tag %d", alias.CanonicalTagID, canonicalOf)
+	} else if err != sql.ErrNoRows {
+		return fmt.Errorf("failed to check tag alias: %w", err)
+	}
+
+	var aliasCount int
+	err = s.db.QueryRowContext(ctx, s.rebind("SELECT COUNT(*) FROM tag_aliases WHERE canonical_tag_id = ? AND id <> ?"), alias.AliasTagID, id).Scan(&aliasCount)
+	if err != nil {
+		return fmt.Errorf("failed to check tag alias: %w", err)
+	}
+	if aliasCount > 0 {
+		return fmt.Errorf("invalid alias: tag %d is the canonical tag of other aliases", alias.AliasTagID)
+	}
+	return nil
+}
+
+// CreateTagAlias registers a tag as an alias of a canonical tag on behalf of a user (0 when
+// unknown)
+func (s *Service) CreateTagAlias(ctx context.Context, alias TagAlias, userID int) (*TagAlias, error) {
+	log.Printf("[TagAliases] CreateTagAlias - Tag %d -> %d, UserID: %d", alias.AliasTagID, alias.CanonicalTagID, userID)
+	if err := s.checkTagAlias(ctx, alias, 0); err != nil {
+		return nil, err
+	}
+	var createdBy *int
+	if userID > 0 {
+		createdBy = &userID
+	}
+
+	var id int64
+	if s.config.DBEngine == "postgresql" || s.config.DBEngine == "postgres" {
+		err := s.db.QueryRowContext(ctx, `
+			INSERT INTO tag_aliases (alias_tag_id, canonical_tag_id, created_by)
+			VALUES ($1, $2, $3)
+			RETURNING id
+		`, alias.AliasTagID, alias.CanonicalTagID, createdBy).Scan(&id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create tag alias: %w", err)
+		}
+	} else {
+		result, err := s.db.ExecContext(ctx, `
+			INSERT INTO tag_aliases (alias_tag_id, canonical_tag_id, created_by)
+			VALUES (?, ?, ?)
+		`, alias.AliasTagID, alias.CanonicalTagID, createdBy)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create tag alias: %w", err)
+		}
+		if id, err = result.LastInsertId(); err != nil {
+			return nil, fmt.Errorf("failed to get tag alias ID: %w", err)
+		}
+	}
+	s.invalidateTagGroupFacets()
+
+	return s.GetTagAlias(ctx, int(id))
+}
+
+// UpdateTagAlias changes the tags of an alias. Tag IDs left at 0 keep their value.
+func (s *Service) UpdateTagAlias(ctx context.Context, id int, alias TagAlias) (*TagAlias, error) {
+	log.Printf("[TagAliases] UpdateTagAlias - ID: %d", id)
+	existing, err := s.GetTagAlias(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if alias.AliasTagID == 0 {
+		alias.AliasTagID = existing.AliasTagID
+	}
+	if alias.CanonicalTagID == 0 {
+		alias.CanonicalTagID = existing.CanonicalTagID
+	}
+	if err := s.checkTagAlias(ctx, alias, id); err != nil {
+		return nil, err
+	}
+
+	_, err = s.db.ExecContext(ctx, s.rebind(`
+		UPDATE tag_aliases
+		SET alias_tag_id = ?, canonical_tag_id = ?, modified = CURRENT_TIMESTAMP
+		WHERE id = ?
+	`), alias.AliasTagID, alias.CanonicalTagID, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update tag alias: %w", err)
+	}
+	s.invalidateTagGroupFacets()
+
+	return s.GetTagAlias(ctx, id)
+}
+
+// DeleteTagAlias removes a tag alias; the tags themselves are left untouched
+func (s *Service) DeleteTagAlias(ctx context.Context, id int) error {
+	log.Printf("[TagAliases] DeleteTagAlias - ID: %d", id)
+	result, err := s.db.ExecContext(ctx, s.rebind("DELETE FROM tag_aliases WHERE id = ?"), id)
+	if err != nil {
+		return fmt.Errorf("failed to delete tag alias: %w", err)
+	}
+	if deleted, _ := result.RowsAffected(); deleted == 0 {
+		return fmt.Errorf("tag alias not found")
+	}
+	s.invalidateTagGroupFacets()
+	return nil
+}
+
+// GetFoldedTagValues returns the tag filter values with the documents of alias tags counted
+// under their canonical tag, which lists the folded tags in Aliases. A document carrying
+// several of these tags counts once. Aliases whose canonical tag was deleted from Paperless
+// keep their own option. Arguments are those of GetBuiltinFilterValues.
+func (s *Service) GetFoldedTagValues(ctx context.Context, filterRulesJSON string, includeTrashed bool, includeEmpty bool, viewerID int) ([]BuiltinFilterValueOption, error) {
+	aliasRows, err := s.db.QueryContext(ctx, "SELECT alias_tag_id, canonical_tag_id FROM tag_aliases WHERE canonical_tag_id IN (SELECT id FROM documents_tag)")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tag aliases: %w", err)
+	}
+	defer aliasRows.Close()
+	canonicalOf := make(map[string]int)
+	for aliasRows.Next() {
+		var aliasTagID, canonicalTagID int
+		if err := aliasRows.Scan(&aliasTagID, &canonicalTagID); err != nil {
+			return nil, fmt.Errorf("failed to scan tag alias: %w", err)
+		}
+		canonicalOf[strconv.Itoa(aliasTagID)] = canonicalTagID
+	}
+	if err := aliasRows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read tag aliases: %w", err)
+	}
+	if len(canonicalOf) == 0 {
+		return s.GetBuiltinFilterValues(ctx, "tag", filterRulesJSON, includeTrashed, includeEmpty, ASNBucketing{}, viewerID)
+	}
+
+	// All tags, so that canonical tags only matching through their aliases are listed
+	tagValues, err := s.GetBuiltinFilterValues(ctx, "tag", filterRulesJSON, includeTrashed, true, ASNBucketing{}, viewerID)
+	if err != nil {
+		return nil, err
+	}
+
+	docFilterWhere, docFilterArgs, err := s.buildDocumentFilterQuery(ctx, filterRulesJSON, 0, builtinFilterRuleTypes["tag"])
+	if err != nil {
+		return nil, fmt.Errorf("failed to build filter query: %w", err)
+	}
+	documentCondition := trashedCondition(includeTrashed)
+	visibility, err := s.documentVisibilityCondition(ctx, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	if visibility != "" {
+		documentCondition = fmt.Sprintf("(%s AND %s)", documentCondition, visibility)
+	}
+	filterCondition := "1 = 1"
+	args := []interface{}{}
+	if docFilterWhere != "" {
+		filterCondition = strings.Replace(docFilterWhere, "WHERE ", "", 1)
+		args = docFilterArgs
+	}
+
+	countRows, err := s.db.QueryContext(ctx, fmt.Sprintf(`
+		SELECT COALESCE(a.canonical_tag_id, dtags.tag_id), COUNT(DISTINCT d.id) as doc_count
+		FROM documents_document_tags dtags
+		INNER JOIN documents_document d ON d.id = dtags.document_id AND %s
+		LEFT JOIN tag_aliases a ON a.alias_tag_id = dtags.tag_id AND a.canonical_tag_id IN (SELECT id FROM documents_tag)
+		WHERE %s
+		GROUP BY 1
+	`, documentCondition, filterCondition), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count folded tag documents: %w", err)
+	}
+	defer countRows.Close()
+
+	counts := make(map[string]int)
+	for countRows.Next() {
+		var tagID, count int
+		if err := countRows.Scan(&tagID, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan folded tag count: %w", err)
+		}
+		counts[strconv.Itoa(tagID)] = count
+	}
+	if err := countRows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read folded tag counts: %w", err)
+	}
+
+	folded := make(map[string][]int)
+	for key, canonicalTagID := range canonicalOf {
+		aliasTagID, _ := strconv.Atoi(key)
+		folded[strconv.Itoa(canonicalTagID)] = append(folded[strconv.Itoa(canonicalTagID)], aliasTagID)
+	}
+
+	values := []BuiltinFilterValueOption{}
+	for _, option := range tagValues {
+		key := builtinOptionKey(option.ID)
+		if _, isAlias := canonicalOf[key]; isAlias {
+			continue
+		}
+		option.Count = counts[key]
+		if aliases := folded[key]; len(aliases) > 0 {
+			sort.Ints(aliases)
+			option.Aliases = aliases
+		}
+		if option.Count == 0 && !includeEmpty {
+			continue
+		}
+		values = append(values, option)
+	}
+	sort.SliceStable(values, func(a, b int) bool {
+		if values[a].Count != values[b].Count {
+			return values[a].Count > values[b].Count
+		}
+		return values[a].Label < values[b].Label
+	})
+
+	return values, nil
+}
+
+// respondTagAliasError writes the response for a failed tag alias operation, naming the
+// fields of missing tags
+func respondTagAliasError(w http.ResponseWriter, alias TagAlias, err error) {
+	var invalid *InvalidTagIDsError
+	if errors.As(err, &invalid) {
+		problems := make([]ValidationError, len(invalid.TagIDs))
+		for i, tagID := range invalid.TagIDs {
+			field := "canonical_tag_id"
+			if tagID == alias.AliasTagID {
+				field = "alias_tag_id"
+			}
+			problems[i] = ValidationError{Field: field, Message: fmt.Sprintf("tag %d does not exist", tagID)}
+		}
+		respondValidationErrors(w, invalid.Error(), problems)
+		return
+	}
+	respondError(w, tagGroupErrorStatus(err), err.Error())
+}
+
+// HTTP Handlers for tag aliases
+
+func (s *Service) handleListTagAliases(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	log.Printf("[TagAliases] GET /api/tag-aliases/ - Request from %s", r.RemoteAddr)
+
+	canonicalTagID := 0
+	if value := r.URL.Query().Get("canonical_tag_id"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			respondError(w, http.StatusBadRequest, "Invalid canonical_tag_id")
+			return
+		}
+		canonicalTagID = parsed
+	}
+
+	aliases, err := s.ListTagAliases(ctx, canonicalTagID)
+	if err != nil {
+		log.Printf("[TagAliases] Error listing aliases: %v", err)
+		respondError(w, queryErrorStatus(err), err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, TagAliasesResponse{Count: len(aliases), Results: aliases})
+}
+
+func (s *Service) handleGetTagAlias(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	idStr := mux.Vars(r)["id"]
+	log.Printf("[TagAliases] GET /api/tag-aliases/%s/ - Request from %s", idStr, r.RemoteAddr)
+
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid alias ID")
+		return
+	}
+
+	alias, err := s.GetTagAlias(ctx, id)
+	if err != nil {
+		respondError(w, tagGroupErrorStatus(err), err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, alias)
+}
+
+func (s *Service) handleCreateTagAlias(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	log.Printf("[TagAliases] POST /api/tag-aliases/ - Request from %s", r.RemoteAddr)
+
+	viewerID, err := viewerFromRequest(r)
+	if err != nil {
+		respondError(w, viewerErrorStatus(err), err.Error())
+		return
+	}
+
+	var alias TagAlias
+	if err := json.NewDecoder(r.Body).Decode(&alias); err != nil {
+		log.Printf("[TagAliases] Error decoding request body: %v", err)
+		respondError(w, http.StatusBadRequest, fmt.Sprintf("Invalid request body: %v", err))
+		return
+	}
+
+	created, err := s.CreateTagAlias(ctx, alias, viewerID)
+	if err != nil {
+		log.Printf("[TagAliases] Error creating alias: %v", err)
+		respondTagAliasError(w, alias, err)
+		return
+	}
+
+	log.Printf("[TagAliases] Successfully created alias %d: tag %d -> %d", *created.ID, created.AliasTagID, created.CanonicalTagID)
+	respondJSON(w, http.StatusCreated, created)
+}
+
+func (s *Service) handleUpdateTagAlias(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	idStr := mux.Vars(r)["id"]
+	log.Printf("[TagAliases] PUT /api/tag-aliases/%s/ - Request from %s", idStr, r.RemoteAddr)
+
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid alias ID")
+		return
+	}
+
+	var alias TagAlias
+	if err := json.NewDecoder(r.Body).Decode(&alias); err != nil {
+		log.Printf("[TagAliases] Error decoding request body: %v", err)
+		respondError(w, http.StatusBadRequest, fmt.Sprintf("Invalid request body: %v", err))
+		return
+	}
+
+	updated, err := s.UpdateTagAlias(ctx, id, alias)
+	if err != nil {
+		log.Printf("[TagAliases] Error updating alias %d: %v", id, err)
+		respondTagAliasError(w, alias, err)
+		return
+	}
+
+	log.Printf("[TagAliases] Successfully updated alias %d", id)
+	respondJSON(w, http.StatusOK, updated)
+}
+
+func (s *Service) handleDeleteTagAlias(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	idStr := mux.Vars(r)["id"]
+	log.Printf("[TagAliases] DELETE /api/tag-aliases/%s/ - Request from %s", idStr, r.RemoteAddr)
+
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid alias ID")
+		return
+	}
+
+	if err := s.DeleteTagAlias(ctx, id); err != nil {
+		log.Printf("[TagAliases] Error deleting alias %d: %v", id, err)
+		respondError(w, tagGroupErrorStatus(err), err.Error())
+		return
+	}
+
+	log.Printf("[TagAliases] Successfully deleted alias %d", id)
+	w.WriteHeader(http.StatusNoContent)
+}
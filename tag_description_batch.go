@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// TagDescriptionMapResponse holds tag descriptions keyed by tag ID
+type TagDescriptionMapResponse struct {
+	Count   int                    `json:"count"`
+	Results map[int]TagDescription `json:"results"`
+}
+
+// parseIDList parses the comma separated IDs of a query parameter such as tag_ids
+func parseIDList(value string, param string) ([]int, error) {
+	ids := []int{}
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		id, err := strconv.Atoi(part)
+		if err != nil || id <= 0 {
+			return nil, fmt.Errorf("invalid ID %q in %s", part, param)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// ListTagDescriptions returns the descriptions of the tags keyed by tag ID, or all stored
+// descriptions when tagIDs is nil (see ListEntityDescriptions)
+func (s *Service) ListTagDescriptions(ctx context.Context, tagIDs []int) (map[int]TagDescription, error) {
+	descriptions, err := s.ListEntityDescriptions(ctx, "tag", tagIDs)
+	if err != nil {
+		return nil, err
+	}
+	tagDescriptions := make(map[int]TagDescription, len(descriptions))
+	for tagID, desc := range descriptions {
+		tagDescriptions[tagID] = tagDescription(desc)
+	}
+	return tagDescriptions, nil
+}
+
+// HTTP Handler for the descriptions of several tags
+func (s *Service) handleListTagDescriptions(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	log.Printf("[TagDescriptions] GET /api/tag-descriptions/ - Request from %s", r.RemoteAddr)
+
+	var tagIDs []int
+	if r.URL.Query().Has("tag_ids") {
+		var err error
+		if tagIDs, err = parseIDList(r.URL.Query().Get("tag_ids"), "tag_ids"); err != nil {
+			respondError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	descriptions, err := s.ListTagDescriptions(ctx, tagIDs)
+	if err != nil {
+		log.Printf("[TagDescriptions] Error listing descriptions: %v", err)
+		respondError(w, queryErrorStatus(err), err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, TagDescriptionMapResponse{Count: len(descriptions), Results: descriptions})
+}
+
+// HTTP Handler for the earlier versions of a tag description
+func (s *Service) handleGetTagDescriptionHistory(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	tagIDStr := mux.Vars(r)["tagId"]
+	log.Printf("[TagDescriptions] GET /api/tag-descriptions/%s/history/ - Request from %s", tagIDStr, r.RemoteAddr)
+
+	tagID, err := strconv.Atoi(tagIDStr)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid tag ID")
+		return
+	}
+
+	revisions, err := s.ListEntityDescriptionRevisions(ctx, "tag", tagID)
+	if err != nil {
+		log.Printf("[TagDescriptions] Error listing history of tag %d: %v", tagID, err)
+		respondError(w, queryErrorStatus(err), err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, EntityDescriptionHistoryResponse{Count: len(revisions), Results: revisions})
+}
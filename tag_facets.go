@@ -0,0 +1,285 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// TagGroupFacet is a tag group with the number of matching documents carrying any of its
+// tags and the counts of its member tags. Tags outside any group are collected in a
+// group with a null ID.
+type TagGroupFacet struct {
+	ID    *int                       `json:"id"`
+	Label string                     `json:"label"`
+	Count int                        `json:"count"`
+	Tags  []BuiltinFilterValueOption `json:"tags"`
+}
+
+// ungroupedTagsLabel is the label of the group collecting tags outside any tag group
+const ungroupedTagsLabel = "(Ungrouped)"
+
+// GetGroupedTagValues returns the tag filter values rolled up by the tag groups viewerID
+// can see. A tag that belongs to several groups is listed under each of them. A viewerID
+// other than 0 only counts documents visible to that user.
+func (s *Service) GetGroupedTagValues(ctx context.Context, filterRulesJSON string, includeTrashed bool, includeEmpty bool, viewerID int) ([]TagGroupFacet, error) {
+	tagValues, err := s.GetBuiltinFilterValues(ctx, "tag", filterRulesJSON, includeTrashed, includeEmpty, ASNBucketing{}, viewerID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Group definitions
+	groupRows, err := s.db.QueryContext(ctx, fmt.Sprintf(`
+		SELECT g.id, g.name, m.tag_id
+		FROM tag_groups g
+		LEFT JOIN tag_group_memberships m ON m.tag_group_id = g.id
+		WHERE %s
+		ORDER BY g.name ASC
+	`, s.tagGroupVisibleCondition("g.", viewerID)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tag groups: %w", err)
+	}
+	defer groupRows.Close()
+
+	groups := make(map[int]*TagGroupFacet)
+	groupOrder := []int{}
+	tagGroups := make(map[string][]int)
+	for groupRows.Next() {
+		var groupID int
+		var name string
+		var tagID sql.NullInt64
+		if err := groupRows.Scan(&groupID, &name, &tagID); err != nil {
+			continue
+		}
+		if _, exists := groups[groupID]; !exists {
+			id := groupID
+			groups[groupID] = &TagGroupFacet{ID: &id, Label: name, Tags: []BuiltinFilterValueOption{}}
+			groupOrder = append(groupOrder, groupID)
+		}
+		if tagID.Valid {
+			key := fmt.Sprintf("%d", tagID.Int64)
+			tagGroups[key] = append(tagGroups[key], groupID)
+		}
+	}
+	if err := groupRows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read tag groups: %w", err)
+	}
+
+	ungrouped := &TagGroupFacet{ID: nil, Label: ungroupedTagsLabel, Tags: []BuiltinFilterValueOption{}}
+	for _, option := range tagValues {
+		memberOf := tagGroups[builtinOptionKey(option.ID)]
+		if len(memberOf) == 0 {
+			ungrouped.Tags = append(ungrouped.Tags, option)
+			continue
+		}
+		for _, groupID := range memberOf {
+			groups[groupID].Tags = append(groups[groupID].Tags, option)
+		}
+	}
+
+	// Documents per group (a document with several tags of a group counts once)
+	docFilterWhere, docFilterArgs, err := s.buildDocumentFilterQuery(ctx, filterRulesJSON, 0, FILTER_HAS_TAGS_ANY)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build filter query: %w", err)
+	}
+	documentCondition := trashedCondition(includeTrashed)
+	visibility, err := s.documentVisibilityCondition(ctx, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	if visibility != "" {
+		documentCondition = fmt.Sprintf("(%s AND %s)", documentCondition, visibility)
+	}
+	filterCondition := "1 = 1"
+	args := []interface{}{}
+	if docFilterWhere != "" {
+		filterCondition = strings.Replace(docFilterWhere, "WHERE ", "", 1)
+		args = docFilterArgs
+	}
+
+	countRows, err := s.db.QueryContext(ctx, fmt.Sprintf(`
+		SELECT m.tag_group_id, COUNT(DISTINCT d.id) as doc_count
+		FROM documents_document_tags dtags
+		INNER JOIN documents_document d ON d.id = dtags.document_id AND %s
+		LEFT JOIN tag_group_memberships m ON m.tag_id = dtags.tag_id
+		WHERE %s
+		GROUP BY m.tag_group_id
+	`, documentCondition, filterCondition), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count tag group documents: %w", err)
+	}
+	defer countRows.Close()
+
+	for countRows.Next() {
+		var groupID sql.NullInt64
+		var count int
+		if err := countRows.Scan(&groupID, &count); err != nil {
+			continue
+		}
+		if !groupID.Valid {
+			ungrouped.Count = count
+		} else if group, ok := groups[int(groupID.Int64)]; ok {
+			group.Count = count
+		}
+	}
+	if err := countRows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read tag group counts: %w", err)
+	}
+
+	result := []TagGroupFacet{}
+	for _, groupID := range groupOrder {
+		group := groups[groupID]
+		if group.Count == 0 && !includeEmpty {
+			continue
+		}
+		result = append(result, *group)
+	}
+	sort.SliceStable(result, func(a, b int) bool {
+		return result[a].Count > result[b].Count
+	})
+	if len(ungrouped.Tags) > 0 {
+		result = append(result, *ungrouped)
+	}
+
+	return result, nil
+}
+
+// TagGroupCount is the number of matching documents carrying at least one tag of a group
+type TagGroupCount struct {
+	ID            int    `json:"id"`
+	Name          string `json:"name"`
+	ParentGroupID *int   `json:"parent_group_id,omitempty"`
+	Count         int    `json:"count"`
+}
+
+// TagGroupCountsResponse represents the document counts of all tag groups
+type TagGroupCountsResponse struct {
+	Count   int             `json:"count"`
+	Results []TagGroupCount `json:"results"`
+}
+
+// GetTagGroupCounts counts, for every tag group viewerID can see, the documents matching
+// the filter rules that carry at least one of the group's tags. Every such group is listed,
+// ordered by name, also when no document matches. A viewerID other than 0 only counts
+// documents visible to that user.
+func (s *Service) GetTagGroupCounts(ctx context.Context, filterRulesJSON string, includeTrashed bool, viewerID int) ([]TagGroupCount, error) {
+	groupRows, err := s.db.QueryContext(ctx, "SELECT id, name, parent_group_id FROM tag_groups WHERE "+s.tagGroupVisibleCondition("", viewerID)+" ORDER BY name ASC")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tag groups: %w", err)
+	}
+	defer groupRows.Close()
+
+	counts := []TagGroupCount{}
+	positions := make(map[int]int)
+	for groupRows.Next() {
+		var group TagGroupCount
+		var parentID sql.NullInt64
+		if err := groupRows.Scan(&group.ID, &group.Name, &parentID); err != nil {
+			return nil, fmt.Errorf("failed to scan tag group: %w", err)
+		}
+		if parentID.Valid {
+			id := int(parentID.Int64)
+			group.ParentGroupID = &id
+		}
+		positions[group.ID] = len(counts)
+		counts = append(counts, group)
+	}
+	if err := groupRows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read tag groups: %w", err)
+	}
+	if len(counts) == 0 {
+		return counts, nil
+	}
+
+	docFilterWhere, docFilterArgs, err := s.buildDocumentFilterQuery(ctx, filterRulesJSON, 0, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build filter query: %w", err)
+	}
+	documentCondition := trashedCondition(includeTrashed)
+	visibility, err := s.documentVisibilityCondition(ctx, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	if visibility != "" {
+		documentCondition = fmt.Sprintf("(%s AND %s)", documentCondition, visibility)
+	}
+	filterCondition := "1 = 1"
+	args := []interface{}{}
+	if docFilterWhere != "" {
+		filterCondition = strings.Replace(docFilterWhere, "WHERE ", "", 1)
+		args = docFilterArgs
+	}
+
+	countRows, err := s.db.QueryContext(ctx, fmt.Sprintf(`
+		SELECT m.tag_group_id, COUNT(DISTINCT d.id) as doc_count
+		FROM tag_group_memberships m
+		INNER JOIN documents_document_tags dtags ON dtags.tag_id = m.tag_id
+		INNER JOIN documents_document d ON d.id = dtags.document_id AND %s
+		WHERE %s
+		GROUP BY m.tag_group_id
+	`, documentCondition, filterCondition), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count tag group documents: %w", err)
+	}
+	defer countRows.Close()
+
+	for countRows.Next() {
+		var groupID, count int
+		if err := countRows.Scan(&groupID, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan tag group count: %w", err)
+		}
+		if position, ok := positions[groupID]; ok {
+			counts[position].Count = count
+		}
+	}
+	if err := countRows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read tag group counts: %w", err)
+	}
+
+	return counts, nil
+}
+
+// HTTP Handler for tag group document counts
+func (s *Service) handleGetTagGroupCounts(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	log.Printf("[TagGroups] POST /api/tag-groups/counts/ - Request from %s", r.RemoteAddr)
+
+	viewerID, err := viewerFromRequest(r)
+	if err != nil {
+		respondError(w, viewerErrorStatus(err), err.Error())
+		return
+	}
+
+	// An empty body counts all documents
+	var body map[string]interface{}
+	if r.Body != nil {
+		json.NewDecoder(r.Body).Decode(&body)
+	}
+	body, err = s.applyFilterPreset(ctx, r, body)
+	if err != nil {
+		respondError(w, filterPresetErrorStatus(err), err.Error())
+		return
+	}
+	filterRulesJSON := filterRulesFromBody(body)
+	includeTrashed := wantsTrashed(r)
+	if trashed, _ := body["include_trashed"].(bool); trashed {
+		includeTrashed = true
+	}
+
+	counts, hit, err := s.getTagGroupCountsCached(ctx, filterRulesJSON, includeTrashed, viewerID, bypassCache(r))
+	if err != nil {
+		log.Printf("[TagGroups] Error counting group documents: %v", err)
+		respondError(w, queryErrorStatus(err), err.Error())
+		return
+	}
+	setCacheHeader(w, hit)
+
+	respondJSON(w, http.StatusOK, TagGroupCountsResponse{Count: len(counts), Results: counts})
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"fmt"
+)
+
+// tagGroupVisibleCondition returns the condition on the tag_groups columns (prefixed by
+// alias, e.g. "g.") selecting the groups a user can see: groups without an owner, global
+// groups and their own groups. userID 0 (no X-User-ID) sees only the first two.
+func (s *Service) tagGroupVisibleCondition(alias string, userID int) string {
+	globalTrue := alias + "is_global = 1"
+	if s.config.DBEngine == "postgresql" || s.config.DBEngine == "postgres" {
+		globalTrue = alias + "is_global = true"
+	}
+	if userID <= 0 {
+		return fmt.Sprintf("(%sowner_id IS NULL OR %s)", alias, globalTrue)
+	}
+	// userID is an integer, so it is safe to inline
+	return fmt.Sprintf("(%sowner_id IS NULL OR %s OR %sowner_id = %d)", alias, globalTrue, alias, userID)
+}
+
+// tagGroupVisible reports whether a user can see the group
+func tagGroupVisible(group *TagGroup, userID int) bool {
+	isGlobal := group.IsGlobal != nil && *group.IsGlobal
+	return group.OwnerID == nil || isGlobal || (userID > 0 && *group.OwnerID == userID)
+}
+
+// accessibleTagGroup returns a tag group the user can see. Groups of other users that are
+// not global are reported as not found, like custom views.
+func (s *Service) accessibleTagGroup(ctx context.Context, id int, userID int) (*TagGroup, error) {
+	group, err := s.GetTagGroup(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !tagGroupVisible(group, userID) {
+		return nil, fmt.Errorf("tag group with id %d not found", id)
+	}
+	return group, nil
+}
+
+// editableTagGroup returns a tag group the user can change: besides their own groups and
+// groups without an owner, everyone can change global groups
+func (s *Service) editableTagGroup(ctx context.Context, id int, userID int) (*TagGroup, error) {
+	return s.accessibleTagGroup(ctx, id, userID)
+}
+
+// deletableTagGroup returns a tag group the user can delete: only their own groups and
+// groups without an owner, global or not
+func (s *Service) deletableTagGroup(ctx context.Context, id int, userID int) (*TagGroup, error) {
+	group, err := s.accessibleTagGroup(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if group.OwnerID != nil && *group.OwnerID != userID {
+		return nil, fmt.Errorf("permission denied: tag group belongs to another user")
+	}
+	return group, nil
+}
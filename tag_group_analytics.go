@@ -0,0 +1,272 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultTagAnalyticsMonths = 12
+	maxTagAnalyticsMonths     = 120
+	defaultTagAnalyticsTop    = 10
+)
+
+// TagAnalyticsTag is the usage of a tag: its document count of all time and per month of
+// the period. Recent and Previous count the documents of the second and the first half of
+// the period, Growth is their difference.
+type TagAnalyticsTag struct {
+	ID            int    `json:"id"`
+	Name          string `json:"name"`
+	DocumentCount int    `json:"document_count"`
+	Monthly       []int  `json:"monthly"`
+	Recent        int    `json:"recent"`
+	Previous      int    `json:"previous"`
+	Growth        int    `json:"growth"`
+}
+
+// TagAnalyticsGroup is the number of documents per month of the period with any of a
+// group's own tags
+type TagAnalyticsGroup struct {
+	ID            int    `json:"id"`
+	Name          string `json:"name"`
+	ParentGroupID *int   `json:"parent_group_id,omitempty"`
+	Monthly       []int  `json:"monthly"`
+}
+
+// TagAnalyticsResponse reports tag and tag group usage over the months of a period, the
+// tags without documents and the tags that grew the most
+type TagAnalyticsResponse struct {
+	Months         []string            `json:"months"`
+	Tags           []TagAnalyticsTag   `json:"tags"`
+	Groups         []TagAnalyticsGroup `json:"groups"`
+	UnusedTags     []TagGroupTag       `json:"unused_tags"`
+	FastestGrowing []TagAnalyticsTag   `json:"fastest_growing"`
+}
+
+// tagAnalyticsMonths returns the labels ("YYYY-MM") of the months of the period ending with
+// the month of now, oldest first, and the first day of the period
+func tagAnalyticsMonths(now time.Time, months int) ([]string, time.Time) {
+	now = now.UTC()
+	start := time.Date(now.Year(), now.Month()-time.Month(months-1), 1, 0, 0, 0, 0, time.UTC)
+	labels := make([]string, months)
+	for i := range labels {
+		labels[i] = start.AddDate(0, i, 0).Format("2006-01")
+	}
+	return labels, start
+}
+
+// queryMonthlyCounts runs a query returning (id, month label, count) rows and collects the
+// counts per ID, aligned with positions. Months outside the period are ignored.
+func (s *Service) queryMonthlyCounts(ctx context.Context, positions map[string]int, query string, args ...interface{}) (map[int][]int, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[int][]int)
+	for rows.Next() {
+		var id, count int
+		var month sql.NullString
+		if err := rows.Scan(&id, &month, &count); err != nil {
+			return nil, err
+		}
+		position, ok := positions[month.String]
+		if !month.Valid || !ok {
+			continue
+		}
+		if counts[id] == nil {
+			counts[id] = make([]int, len(positions))
+		}
+		counts[id][position] += count
+	}
+	return counts, rows.Err()
+}
+
+// GetTagAnalytics counts the documents of every tag and of every tag group the user can see
+// per month of documents_document.created, over the given number of months up to the month
+// of now. Documents are counted like in the other counts: trashed ones only when
+// includeTrashed is set and, for a viewerID other than 0, only those visible to that user.
+// FastestGrowing holds up to top tags with a positive growth, largest first.
+func (s *Service) GetTagAnalytics(ctx context.Context, months int, top int, includeTrashed bool, viewerID int, now time.Time) (*TagAnalyticsResponse, error) {
+	documentCondition := trashedCondition(includeTrashed)
+	visibility, err := s.documentVisibilityCondition(ctx, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	if visibility != "" {
+		documentCondition = fmt.Sprintf("(%s AND %s)", documentCondition, visibility)
+	}
+
+	labels, start := tagAnalyticsMonths(now, months)
+	positions := make(map[string]int, len(labels))
+	for i, label := range labels {
+		positions[label] = i
+	}
+	bucket := dateBucketExpression(s.config.DBEngine, "month", "d.created")
+	startArg := start.Format("2006-01-02")
+
+	tagMonthly, err := s.queryMonthlyCounts(ctx, positions, s.rebind(fmt.Sprintf(`
+		SELECT dt.tag_id, %s, COUNT(DISTINCT d.id)
+		FROM documents_document_tags dt
+		INNER JOIN documents_document d ON d.id = dt.document_id
+		WHERE %s AND d.created >= ?
+		GROUP BY 1, 2
+	`, bucket, documentCondition)), startArg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count tag documents per month: %w", err)
+	}
+
+	groupMonthly, err := s.queryMonthlyCounts(ctx, positions, s.rebind(fmt.Sprintf(`
+		SELECT m.tag_group_id, %s, COUNT(DISTINCT d.id)
+		FROM tag_group_memberships m
+		INNER JOIN tag_groups g ON g.id = m.tag_group_id
+		INNER JOIN documents_document_tags dt ON dt.tag_id = m.tag_id
+		INNER JOIN documents_document d ON d.id = dt.document_id
+		WHERE %s AND %s AND d.created >= ?
+		GROUP BY 1, 2
+	`, bucket, s.tagGroupVisibleCondition("g.", viewerID), documentCondition)), startArg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count tag group documents per month: %w", err)
+	}
+
+	analytics := &TagAnalyticsResponse{
+		Months:         labels,
+		Tags:           []TagAnalyticsTag{},
+		Groups:         []TagAnalyticsGroup{},
+		UnusedTags:     []TagGroupTag{},
+		FastestGrowing: []TagAnalyticsTag{},
+	}
+
+	tagRows, err := s.db.QueryContext(ctx, fmt.Sprintf(`
+		SELECT t.id, t.name, t.color, COUNT(DISTINCT d.id)
+		FROM documents_tag t
+		LEFT JOIN documents_document_tags dt ON dt.tag_id = t.id
+		LEFT JOIN documents_document d ON d.id = dt.document_id AND %s
+		GROUP BY t.id, t.name, t.color
+		ORDER BY t.name ASC
+	`, documentCondition))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tags: %w", err)
+	}
+	defer tagRows.Close()
+
+	half := months / 2
+	for tagRows.Next() {
+		var tag TagGroupTag
+		var color sql.NullString
+		if err := tagRows.Scan(&tag.ID, &tag.Name, &color, &tag.DocumentCount); err != nil {
+			return nil, fmt.Errorf("failed to scan tag: %w", err)
+		}
+		if color.Valid {
+			tag.Color = &color.String
+		}
+		if tag.DocumentCount == 0 {
+			analytics.UnusedTags = append(analytics.UnusedTags, tag)
+		}
+
+		usage := TagAnalyticsTag{ID: tag.ID, Name: tag.Name, DocumentCount: tag.DocumentCount, Monthly: tagMonthly[tag.ID]}
+		if usage.Monthly == nil {
+			usage.Monthly = make([]int, months)
+		}
+		for i := 0; i < half; i++ {
+			usage.Previous += usage.Monthly[months-2*half+i]
+			usage.Recent += usage.Monthly[months-half+i]
+		}
+		usage.Growth = usage.Recent - usage.Previous
+		analytics.Tags = append(analytics.Tags, usage)
+	}
+	if err := tagRows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read tags: %w", err)
+	}
+
+	for _, tag := range analytics.Tags {
+		if tag.Growth > 0 {
+			analytics.FastestGrowing = append(analytics.FastestGrowing, tag)
+		}
+	}
+	sort.SliceStable(analytics.FastestGrowing, func(i, j int) bool {
+		a, b := analytics.FastestGrowing[i], analytics.FastestGrowing[j]
+		if a.Growth != b.Growth {
+			return a.Growth > b.Growth
+		}
+		return a.Recent > b.Recent
+	})
+	analytics.FastestGrowing = analytics.FastestGrowing[:min(top, len(analytics.FastestGrowing))]
+
+	groupRows, err := s.db.QueryContext(ctx, "SELECT id, name, parent_group_id FROM tag_groups WHERE "+s.tagGroupVisibleCondition("", viewerID)+" ORDER BY name ASC")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tag groups: %w", err)
+	}
+	defer groupRows.Close()
+	for groupRows.Next() {
+		var group TagAnalyticsGroup
+		var parentID sql.NullInt64
+		if err := groupRows.Scan(&group.ID, &group.Name, &parentID); err != nil {
+			return nil, fmt.Errorf("failed to scan tag group: %w", err)
+		}
+		if parentID.Valid {
+			id := int(parentID.Int64)
+			group.ParentGroupID = &id
+		}
+		group.Monthly = groupMonthly[group.ID]
+		if group.Monthly == nil {
+			group.Monthly = make([]int, months)
+		}
+		analytics.Groups = append(analytics.Groups, group)
+	}
+	if err := groupRows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read tag groups: %w", err)
+	}
+
+	return analytics, nil
+}
+
+// HTTP Handler for tag usage analytics
+func (s *Service) handleGetTagAnalytics(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	log.Printf("[TagGroups] GET /api/tag-groups/analytics/ - Request from %s", r.RemoteAddr)
+
+	viewerID, err := viewerFromRequest(r)
+	if err != nil {
+		respondError(w, viewerErrorStatus(err), err.Error())
+		return
+	}
+
+	months := defaultTagAnalyticsMonths
+	if value := r.URL.Query().Get("months"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 2 || parsed > maxTagAnalyticsMonths {
+			respondError(w, http.StatusBadRequest, fmt.Sprintf("Invalid months, expected 2 to %d", maxTagAnalyticsMonths))
+			return
+		}
+		months = parsed
+	}
+	top := defaultTagAnalyticsTop
+	if value := r.URL.Query().Get("top"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 {
+			respondError(w, http.StatusBadRequest, "Invalid top")
+			return
+		}
+		top = parsed
+	}
+
+	analytics, hit, err := s.getTagAnalyticsCached(ctx, months, top, wantsTrashed(r), viewerID, bypassCache(r))
+	if err != nil {
+		log.Printf("[TagGroups] Error computing tag analytics: %v", err)
+		respondError(w, queryErrorStatus(err), err.Error())
+		return
+	}
+	setCacheHeader(w, hit)
+
+	respondJSON(w, http.StatusOK, analytics)
+}
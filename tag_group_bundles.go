@@ -0,0 +1,414 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// tagGroupBundleVersion is the version of the tag group export format
+const tagGroupBundleVersion = 1
+
+// TagGroupBundle is the portable JSON form of tag groups and tag descriptions. Tags and
+// parent groups are referenced by name, since IDs differ between instances.
+type TagGroupBundle struct {
+	Version      int                    `json:"version"`
+	ExportedAt   string                 `json:"exported_at,omitempty"`
+	Groups       []TagGroupBundleGroup  `json:"groups"`
+	Descriptions []TagDescriptionBundle `json:"descriptions"`
+}
+
+// TagGroupBundleGroup is a tag group in a bundle
+type TagGroupBundleGroup struct {
+	Name        string   `json:"name"`
+	Description *string  `json:"description,omitempty"`
+	Parent      string   `json:"parent,omitempty"` // Name of the parent group
+	IsGlobal    *bool    `json:"is_global,omitempty"`
+	Tags        []string `json:"tags"` // Tag names
+}
+
+// TagDescriptionBundle is a tag description in a bundle
+type TagDescriptionBundle struct {
+	Tag         string `json:"tag"`
+	Description string `json:"description"`
+}
+
+// TagGroupImportResult reports what an import did, or would do in a dry run
+type TagGroupImportResult struct {
+	DryRun              bool     `json:"dry_run"`
+	Created             []string `json:"created"`
+	Updated             []string `json:"updated"`
+	Skipped             []string `json:"skipped"` // Names of groups that already existed
+	DescriptionsSet     int      `json:"descriptions_set"`
+	DescriptionsSkipped int      `json:"descriptions_skipped"` // Tags that already had a description
+	UnmatchedTags       []string `json:"unmatched_tags"`       // Tag names without a tag of that name here
+}
+
+// ExportTagGroups bundles the tag groups the user can see and all tag descriptions
+func (s *Service) ExportTagGroups(ctx context.Context, userID int) (*TagGroupBundle, error) {
+	groups, err := s.ListTagGroups(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	pointers := make([]*TagGroup, len(groups))
+	names := make(map[int]string, len(groups))
+	for i := range groups {
+		pointers[i] = &groups[i]
+		names[*groups[i].ID] = groups[i].Name
+	}
+	if err := s.expandTagGroupTags(ctx, pointers, false, 0); err != nil {
+		return nil, err
+	}
+
+	bundle := &TagGroupBundle{
+		Version:      tagGroupBundleVersion,
+		ExportedAt:   time.Now().UTC().Format(time.RFC3339),
+		Groups:       make([]TagGroupBundleGroup, 0, len(groups)),
+		Descriptions: []TagDescriptionBundle{},
+	}
+	for _, group := range groups {
+		entry := TagGroupBundleGroup{Name: group.Name, Description: group.Description, IsGlobal: group.IsGlobal, Tags: []string{}}
+		if group.ParentGroupID != nil {
+			entry.Parent = names[*group.ParentGroupID]
+		}
+		for _, tag := range group.Tags {
+			entry.Tags = append(entry.Tags, tag.Name)
+		}
+		bundle.Groups = append(bundle.Groups, entry)
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT t.name, d.description
+		FROM entity_descriptions d
+		INNER JOIN documents_tag t ON t.id = d.entity_id
+		WHERE d.entity_type = 'tag' AND d.description IS NOT NULL
+		ORDER BY t.name ASC
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tag descriptions: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var entry TagDescriptionBundle
+		if err := rows.Scan(&entry.Tag, &entry.Description); err != nil {
+			return nil, fmt.Errorf("failed to scan tag description: %w", err)
+		}
+		bundle.Descriptions = append(bundle.Descriptions, entry)
+	}
+	return bundle, rows.Err()
+}
+
+// tagNameResolver finds Paperless tags by name: exactly, or else case-insensitively when
+// only one tag has the name in any case
+type tagNameResolver struct {
+	exact  map[string]int
+	folded map[string][]int
+}
+
+// loadTagNameResolver reads the names of all Paperless tags
+func (s *Service) loadTagNameResolver(ctx context.Context) (*tagNameResolver, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT id, name FROM documents_tag")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tags: %w", err)
+	}
+	defer rows.Close()
+
+	resolver := &tagNameResolver{exact: make(map[string]int), folded: make(map[string][]int)}
+	for rows.Next() {
+		var id int
+		var name string
+		if err := rows.Scan(&id, &name); err != nil {
+			return nil, fmt.Errorf("failed to scan tag: %w", err)
+		}
+		resolver.exact[name] = id
+		folded := strings.ToLower(name)
+		resolver.folded[folded] = append(resolver.folded[folded], id)
+	}
+	return resolver, rows.Err()
+}
+
+func (resolver *tagNameResolver) resolve(name string) (int, bool) {
+	if id, ok := resolver.exact[name]; ok {
+		return id, true
+	}
+	if ids := resolver.folded[strings.ToLower(name)]; len(ids) == 1 {
+		return ids[0], true
+	}
+	return 0, false
+}
+
+// orderTagGroupBundle returns the groups of a bundle with every parent from the bundle
+// before its subgroups. Parents outside the bundle must exist already.
+func orderTagGroupBundle(groups []TagGroupBundleGroup, existing map[string]int) ([]TagGroupBundleGroup, error) {
+	byName := make(map[string]TagGroupBundleGroup, len(groups))
+	for i, group := range groups {
+		if strings.TrimSpace(group.Name) == "" {
+			return nil, fmt.Errorf("invalid bundle: group %d has no name", i+1)
+		}
+		if _, duplicate := byName[group.Name]; duplicate {
+			return nil, fmt.Errorf("invalid bundle: group %q is listed twice", group.Name)
+		}
+		byName[group.Name] = group
+	}
+
+	ordered := make([]TagGroupBundleGroup, 0, len(groups))
+	state := make(map[string]int) // 1 while visiting, 2 when ordered
+	var visit func(group TagGroupBundleGroup) error
+	visit = func(group TagGroupBundleGroup) error {
+		switch state[group.Name] {
+		case 1:
+			return fmt.Errorf("invalid bundle: group %q is nested in itself", group.Name)
+		case 2:
+			return nil
+		}
+		state[group.Name] = 1
+		if group.Parent != "" {
+			if parent, ok := byName[group.Parent]; ok {
+				if err := visit(parent); err != nil {
+					return err
+				}
+			} else if _, ok := existing[group.Parent]; !ok {
+				return fmt.Errorf("invalid bundle: parent %q of group %q not found", group.Parent, group.Name)
+			}
+		}
+		state[group.Name] = 2
+		ordered = append(ordered, group)
+		return nil
+	}
+	for _, group := range groups {
+		if err := visit(group); err != nil {
+			return nil, err
+		}
+	}
+	return ordered, nil
+}
+
+// ImportTagGroups creates the groups and descriptions of a bundle, resolving tags by name.
+// Groups whose name is taken are skipped, imported under a numbered name ("Name (2)") or
+// overwrite the existing group, depending on conflict; existing descriptions are only
+// replaced with overwrite. Tags without a match are left out and reported. A dry run
+// reports the same without changing anything.
+func (s *Service) ImportTagGroups(ctx context.Context, bundle TagGroupBundle, conflict string, dryRun bool, userID int, username *string) (*TagGroupImportResult, error) {
+	if bundle.Version != tagGroupBundleVersion {
+		return nil, fmt.Errorf("unsupported bundle version %d (expected %d)", bundle.Version, tagGroupBundleVersion)
+	}
+	if !customViewConflictStrategies[conflict] {
+		return nil, fmt.Errorf("invalid conflict strategy %q (expected skip, rename or overwrite)", conflict)
+	}
+
+	// Group names are unique across all users
+	existing := make(map[string]int)
+	rows, err := s.db.QueryContext(ctx, "SELECT id, name FROM tag_groups")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tag groups: %w", err)
+	}
+	for rows.Next() {
+		var id int
+		var name string
+		if err := rows.Scan(&id, &name); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan tag group: %w", err)
+		}
+		existing[name] = id
+	}
+	err = rows.Err()
+	rows.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tag groups: %w", err)
+	}
+
+	groups, err := orderTagGroupBundle(bundle.Groups, existing)
+	if err != nil {
+		return nil, err
+	}
+	tags, err := s.loadTagNameResolver(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &TagGroupImportResult{DryRun: dryRun, Created: []string{}, Updated: []string{}, Skipped: []string{}, UnmatchedTags: []string{}}
+	unmatched := make(map[string]bool)
+	resolveTags := func(names []string) []int {
+		tagIDs := []int{}
+		for _, name := range names {
+			if id, ok := tags.resolve(name); ok {
+				tagIDs = append(tagIDs, id)
+			} else {
+				unmatched[name] = true
+			}
+		}
+		return tagIDs
+	}
+
+	// groupIDs maps bundle names to the groups they ended up as; in a dry run new groups
+	// get the placeholder -1
+	groupIDs := make(map[string]int)
+	for _, group := range groups {
+		tagIDs := resolveTags(group.Tags)
+		parentID := 0
+		if group.Parent != "" {
+			if id, ok := groupIDs[group.Parent]; ok {
+				parentID = id
+			} else {
+				parentID = existing[group.Parent]
+			}
+		}
+
+		name := group.Name
+		if existingID, taken := existing[name]; taken {
+			switch conflict {
+			case "skip":
+				result.Skipped = append(result.Skipped, name)
+				groupIDs[group.Name] = existingID
+				continue
+			case "overwrite":
+				if !dryRun {
+					// Tags were resolved by name, so they exist without another check
+					updates := TagGroup{Description: group.Description, ParentGroupID: &parentID, TagIDs: tagIDs}
+					if _, err := s.UpdateTagGroup(ctx, existingID, updates, userID, false); err != nil {
+						return nil, fmt.Errorf("failed to overwrite tag group %q: %w", name, err)
+					}
+				}
+				result.Updated = append(result.Updated, name)
+				groupIDs[group.Name] = existingID
+				continue
+			case "rename":
+				for n := 2; taken; n++ {
+					name = fmt.Sprintf("%s (%d)", group.Name, n)
+					_, taken = existing[name]
+				}
+			}
+		}
+
+		id := -1
+		if !dryRun {
+			created := TagGroup{Name: name, Description: group.Description, IsGlobal: group.IsGlobal, TagIDs: tagIDs}
+			if parentID != 0 {
+				created.ParentGroupID = &parentID
+			}
+			createdGroup, err := s.CreateTagGroup(ctx, created, userID, username, false)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create tag group %q: %w", name, err)
+			}
+			id = *createdGroup.ID
+		}
+		existing[name] = id
+		groupIDs[group.Name] = id
+		result.Created = append(result.Created, name)
+	}
+
+	// Descriptions
+	described := make(map[int]bool)
+	descriptionRows, err := s.db.QueryContext(ctx, "SELECT entity_id FROM entity_descriptions WHERE entity_type = 'tag' AND description IS NOT NULL")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tag descriptions: %w", err)
+	}
+	for descriptionRows.Next() {
+		var tagID int
+		if err := descriptionRows.Scan(&tagID); err != nil {
+			descriptionRows.Close()
+			return nil, fmt.Errorf("failed to scan tag description: %w", err)
+		}
+		described[tagID] = true
+	}
+	err = descriptionRows.Err()
+	descriptionRows.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tag descriptions: %w", err)
+	}
+
+	for _, entry := range bundle.Descriptions {
+		tagID, ok := tags.resolve(entry.Tag)
+		if !ok {
+			unmatched[entry.Tag] = true
+			continue
+		}
+		if described[tagID] && conflict != "overwrite" {
+			result.DescriptionsSkipped++
+			continue
+		}
+		if !dryRun {
+			description := entry.Description
+			if _, err := s.SetTagDescription(ctx, TagDescription{TagID: tagID, Description: &description}, userID); err != nil {
+				return nil, fmt.Errorf("failed to set the description of tag %q: %w", entry.Tag, err)
+			}
+		}
+		described[tagID] = true
+		result.DescriptionsSet++
+	}
+
+	for name := range unmatched {
+		result.UnmatchedTags = append(result.UnmatchedTags, name)
+	}
+	sort.Strings(result.UnmatchedTags)
+
+	log.Printf("[TagGroups] Imported bundle (dry run: %t): %d created, %d updated, %d skipped, %d unmatched tags",
+		dryRun, len(result.Created), len(result.Updated), len(result.Skipped), len(result.UnmatchedTags))
+	return result, nil
+}
+
+// HTTP Handlers for tag group bundles
+func (s *Service) handleExportTagGroups(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	log.Printf("[TagGroups] GET /api/tag-groups/export/ - Request from %s", r.RemoteAddr)
+
+	viewerID, err := viewerFromRequest(r)
+	if err != nil {
+		respondError(w, viewerErrorStatus(err), err.Error())
+		return
+	}
+
+	bundle, err := s.ExportTagGroups(ctx, viewerID)
+	if err != nil {
+		log.Printf("[TagGroups] Error exporting groups: %v", err)
+		respondError(w, queryErrorStatus(err), err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Disposition", `attachment; filename="tag-groups.json"`)
+	respondJSON(w, http.StatusOK, bundle)
+}
+
+func (s *Service) handleImportTagGroups(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	log.Printf("[TagGroups] POST /api/tag-groups/import/ - Request from %s", r.RemoteAddr)
+
+	viewerID, err := viewerFromRequest(r)
+	if err != nil {
+		respondError(w, viewerErrorStatus(err), err.Error())
+		return
+	}
+
+	var bundle TagGroupBundle
+	if err := json.NewDecoder(r.Body).Decode(&bundle); err != nil {
+		respondError(w, http.StatusBadRequest, fmt.Sprintf("Invalid request body: %v", err))
+		return
+	}
+	conflict := r.URL.Query().Get("conflict")
+	if conflict == "" {
+		conflict = "skip"
+	}
+	dryRun := r.URL.Query().Get("dry_run") == "true" || r.URL.Query().Get("dry_run") == "1"
+
+	result, err := s.ImportTagGroups(ctx, bundle, conflict, dryRun, viewerID, getUsernameFromRequest(r))
+	if err != nil {
+		log.Printf("[TagGroups] Error importing bundle: %v", err)
+		if strings.Contains(err.Error(), "unsupported bundle version") {
+			respondError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		respondError(w, tagGroupErrorStatus(err), err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, result)
+}
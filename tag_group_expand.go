@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// wantsExpand reports whether the expand query parameter (a comma separated list) names
+// the given relation
+func wantsExpand(r *http.Request, relation string) bool {
+	for _, value := range r.URL.Query()["expand"] {
+		for _, item := range strings.Split(value, ",") {
+			if strings.TrimSpace(item) == relation {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// tagGroupTagDetails loads the name, color and document count of the tags from Paperless.
+// Documents are counted like in the other counts: trashed ones only when includeTrashed is
+// set and, for a viewerID other than 0, only those visible to that user.
+func (s *Service) tagGroupTagDetails(ctx context.Context, tagIDs []int, includeTrashed bool, viewerID int) (map[int]TagGroupTag, error) {
+	documentCondition := trashedCondition(includeTrashed)
+	visibility, err := s.documentVisibilityCondition(ctx, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	if visibility != "" {
+		documentCondition = fmt.Sprintf("(%s AND %s)", documentCondition, visibility)
+	}
+
+	tagIDs = uniqueTagIDs(tagIDs)
+	details := make(map[int]TagGroupTag, len(tagIDs))
+	for start := 0; start < len(tagIDs); start += tagGroupMembershipBatchSize {
+		placeholders, args := inPlaceholders(tagIDs[start:min(start+tagGroupMembershipBatchSize, len(tagIDs))])
+		query := s.rebind(fmt.Sprintf(`
+			SELECT t.id, t.name, t.color, COUNT(DISTINCT d.id)
+			FROM documents_tag t
+			LEFT JOIN documents_document_tags dt ON dt.tag_id = t.id
+			LEFT JOIN documents_document d ON d.id = dt.document_id AND %s
+			WHERE t.id IN (%s)
+			GROUP BY t.id, t.name, t.color
+		`, documentCondition, placeholders))
+
+		rows, err := s.db.QueryContext(ctx, query, args...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query tags: %w", err)
+		}
+		for rows.Next() {
+			var tag TagGroupTag
+			var color sql.NullString
+			if err := rows.Scan(&tag.ID, &tag.Name, &color, &tag.DocumentCount); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("failed to scan tag: %w", err)
+			}
+			if color.Valid {
+				tag.Color = &color.String
+			}
+			details[tag.ID] = tag
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read tags: %w", err)
+		}
+	}
+	return details, nil
+}
+
+// expandTagGroupTags fills in the tags of the groups, in the order of their tag_ids, with
+// one query for all groups. Tags no longer in Paperless are left out.
+func (s *Service) expandTagGroupTags(ctx context.Context, groups []*TagGroup, includeTrashed bool, viewerID int) error {
+	var tagIDs []int
+	for _, group := range groups {
+		tagIDs = append(tagIDs, group.TagIDs...)
+	}
+	details, err := s.tagGroupTagDetails(ctx, tagIDs, includeTrashed, viewerID)
+	if err != nil {
+		return err
+	}
+
+	for _, group := range groups {
+		group.Tags = []TagGroupTag{}
+		for _, tagID := range group.TagIDs {
+			if tag, ok := details[tagID]; ok {
+				group.Tags = append(group.Tags, tag)
+			}
+		}
+	}
+	return nil
+}
+
+// expandTagGroupNodes applies expandTagGroupTags to every group of a tag group tree
+func (s *Service) expandTagGroupNodes(ctx context.Context, nodes []TagGroupNode, includeTrashed bool, viewerID int) error {
+	var groups []*TagGroup
+	var collect func(nodes []TagGroupNode)
+	collect = func(nodes []TagGroupNode) {
+		for i := range nodes {
+			groups = append(groups, &nodes[i].TagGroup)
+			collect(nodes[i].Children)
+		}
+	}
+	collect(nodes)
+	return s.expandTagGroupTags(ctx, groups, includeTrashed, viewerID)
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// TagGroupFilterRulesResponse is a tag group expanded into Paperless filter rules
+type TagGroupFilterRulesResponse struct {
+	TagGroupID  int                      `json:"tag_group_id"`
+	TagIDs      []int                    `json:"tag_ids"`
+	FilterRules []map[string]interface{} `json:"filter_rules"`
+}
+
+// GetTagGroupFilterRules expands a tag group the user can see into the filter rules selecting
+// documents with any of its tags: one "has any of these tags" rule per tag, as the Paperless
+// frontend sends them. With includeSubgroups the tags of the visible subgroups are included.
+// Tags deleted from Paperless are left out, so a group without tags yields no rules.
+func (s *Service) GetTagGroupFilterRules(ctx context.Context, id int, includeSubgroups bool, userID int) (*TagGroupFilterRulesResponse, error) {
+	group, err := s.accessibleTagGroup(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	tagIDs := group.TagIDs
+	if includeSubgroups {
+		groups, err := s.ListTagGroups(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		children := make(map[int][]TagGroup)
+		for _, child := range groups {
+			if child.ParentGroupID != nil {
+				children[*child.ParentGroupID] = append(children[*child.ParentGroupID], child)
+			}
+		}
+		visited := map[int]bool{id: true}
+		pending := []int{id}
+		for len(pending) > 0 {
+			parentID := pending[0]
+			pending = pending[1:]
+			for _, child := range children[parentID] {
+				if !visited[*child.ID] {
+					visited[*child.ID] = true
+					tagIDs = append(tagIDs, child.TagIDs...)
+					pending = append(pending, *child.ID)
+				}
+			}
+		}
+	}
+
+	existing, err := s.existingTagIDs(ctx, tagIDs)
+	if err != nil {
+		return nil, err
+	}
+	response := &TagGroupFilterRulesResponse{TagGroupID: id, TagIDs: []int{}, FilterRules: []map[string]interface{}{}}
+	for _, tagID := range uniqueTagIDs(tagIDs) {
+		if !existing[tagID] {
+			continue
+		}
+		response.TagIDs = append(response.TagIDs, tagID)
+		response.FilterRules = append(response.FilterRules, map[string]interface{}{
+			"rule_type": FILTER_HAS_TAGS_ANY,
+			"value":     strconv.Itoa(tagID),
+		})
+	}
+	return response, nil
+}
+
+// HTTP Handler for the filter rules of a tag group
+func (s *Service) handleGetTagGroupFilterRules(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	idStr := mux.Vars(r)["id"]
+	log.Printf("[TagGroups] GET /api/tag-groups/%s/filter-rules/ - Request from %s", idStr, r.RemoteAddr)
+
+	viewerID, err := viewerFromRequest(r)
+	if err != nil {
+		respondError(w, viewerErrorStatus(err), err.Error())
+		return
+	}
+
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid group ID")
+		return
+	}
+	includeSubgroups := r.URL.Query().Get("include_subgroups") == "true" || r.URL.Query().Get("include_subgroups") == "1"
+
+	rules, err := s.GetTagGroupFilterRules(ctx, id, includeSubgroups, viewerID)
+	if err != nil {
+		log.Printf("[TagGroups] Error expanding group %d into filter rules: %v", id, err)
+		respondError(w, tagGroupErrorStatus(err), err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, rules)
+}
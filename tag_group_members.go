@@ -0,0 +1,414 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// tagGroupMembershipBatchSize is the number of memberships inserted per statement, which
+// keeps the placeholders of a batch below SQLite's default limit of 999
+const tagGroupMembershipBatchSize = 400
+
+// TagGroupTagsRequest is the body of the endpoints adding tags to or removing tags from a group
+type TagGroupTagsRequest struct {
+	TagIDs []int `json:"tag_ids"`
+}
+
+// TagGroupMoveRequest moves tags from one tag group to another
+type TagGroupMoveRequest struct {
+	FromGroupID int   `json:"from_group_id"`
+	ToGroupID   int   `json:"to_group_id"`
+	TagIDs      []int `json:"tag_ids"`
+}
+
+// TagGroupMoveResponse holds both groups of a move as they are afterwards
+type TagGroupMoveResponse struct {
+	From *TagGroup `json:"from"`
+	To   *TagGroup `json:"to"`
+}
+
+// uniqueTagIDs returns the tag IDs without repetitions, in their first order
+func uniqueTagIDs(tagIDs []int) []int {
+	seen := make(map[int]bool, len(tagIDs))
+	unique := make([]int, 0, len(tagIDs))
+	for _, tagID := range tagIDs {
+		if !seen[tagID] {
+			seen[tagID] = true
+			unique = append(unique, tagID)
+		}
+	}
+	return unique
+}
+
+// inPlaceholders returns the placeholders and arguments of an IN list of IDs
+func inPlaceholders(ids []int) (string, []interface{}) {
+	placeholders := make([]string, len(ids))
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		placeholders[i] = "?"
+		args[i] = id
+	}
+	return strings.Join(placeholders, ", "), args
+}
+
+// existingTagIDs returns which of the tags are in documents_tag
+func (s *Service) existingTagIDs(ctx context.Context, tagIDs []int) (map[int]bool, error) {
+	tagIDs = uniqueTagIDs(tagIDs)
+	existing := make(map[int]bool, len(tagIDs))
+	for start := 0; start < len(tagIDs); start += tagGroupMembershipBatchSize {
+		placeholders, args := inPlaceholders(tagIDs[start:min(start+tagGroupMembershipBatchSize, len(tagIDs))])
+		rows, err := s.db.QueryContext(ctx, s.rebind("SELECT id FROM documents_tag WHERE id IN ("+placeholders+")"), args...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query tags: %w", err)
+		}
+		for rows.Next() {
+			var id int
+			if err := rows.Scan(&id); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("failed to scan tag: %w", err)
+			}
+			existing[id] = true
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read tags: %w", err)
+		}
+	}
+	return existing, nil
+}
+
+// InvalidTagIDsError is returned by membership writes naming tags that are not in Paperless
+type InvalidTagIDsError struct {
+	TagIDs []int
+}
+
+func (e *InvalidTagIDsError) Error() string {
+	ids := make([]string, len(e.TagIDs))
+	for i, tagID := range e.TagIDs {
+		ids[i] = strconv.Itoa(tagID)
+	}
+	return fmt.Sprintf("invalid tag_ids: tags %s do not exist", strings.Join(ids, ", "))
+}
+
+// wantsTagValidation reports whether the tag IDs of a membership write are checked against
+// Paperless; validate_tags=false skips the check, e.g. for imports while Paperless is offline
+func wantsTagValidation(r *http.Request) bool {
+	value := r.URL.Query().Get("validate_tags")
+	return value != "false" && value != "0"
+}
+
+// checkTagIDsExist returns an InvalidTagIDsError when any of the tags is not in documents_tag
+func (s *Service) checkTagIDsExist(ctx context.Context, tagIDs []int) error {
+	tagIDs = uniqueTagIDs(tagIDs)
+	existing, err := s.existingTagIDs(ctx, tagIDs)
+	if err != nil {
+		return err
+	}
+
+	var missing []int
+	for _, tagID := range tagIDs {
+		if !existing[tagID] {
+			missing = append(missing, tagID)
+		}
+	}
+	if len(missing) > 0 {
+		return &InvalidTagIDsError{TagIDs: missing}
+	}
+	return nil
+}
+
+// checkNewTagIDsExist checks the tags of tagIDs that are not in current, so members that
+// were deleted from Paperless in the meantime do not block other changes to a group
+func (s *Service) checkNewTagIDsExist(ctx context.Context, tagIDs []int, current []int) error {
+	members := make(map[int]bool, len(current))
+	for _, tagID := range current {
+		members[tagID] = true
+	}
+	var added []int
+	for _, tagID := range tagIDs {
+		if !members[tagID] {
+			added = append(added, tagID)
+		}
+	}
+	if len(added) == 0 {
+		return nil
+	}
+	return s.checkTagIDsExist(ctx, added)
+}
+
+// insertTagGroupMemberships adds tags to a group within tx, in batches. The tags must not be
+// members yet; a tag listed twice is added once.
+func (s *Service) insertTagGroupMemberships(ctx context.Context, tx *sql.Tx, groupID int, tagIDs []int) error {
+	tagIDs = uniqueTagIDs(tagIDs)
+	for start := 0; start < len(tagIDs); start += tagGroupMembershipBatchSize {
+		batch := tagIDs[start:min(start+tagGroupMembershipBatchSize, len(tagIDs))]
+		values := make([]string, len(batch))
+		args := make([]interface{}, 0, 2*len(batch))
+		for i, tagID := range batch {
+			values[i] = "(?, ?)"
+			args = append(args, groupID, tagID)
+		}
+		query := s.rebind("INSERT INTO tag_group_memberships (tag_group_id, tag_id) VALUES " + strings.Join(values, ", "))
+		if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+			return fmt.Errorf("failed to add memberships: %w", err)
+		}
+	}
+	return nil
+}
+
+// txTagGroupMembers returns the tags of a group within tx, failing when the group does not exist
+func (s *Service) txTagGroupMembers(ctx context.Context, tx *sql.Tx, groupID int) (map[int]bool, error) {
+	var exists int
+	err := tx.QueryRowContext(ctx, s.rebind("SELECT 1 FROM tag_groups WHERE id = ?"), groupID).Scan(&exists)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("tag group with id %d not found", groupID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tag group: %w", err)
+	}
+
+	rows, err := tx.QueryContext(ctx, s.rebind("SELECT tag_id FROM tag_group_memberships WHERE tag_group_id = ?"), groupID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query memberships: %w", err)
+	}
+	defer rows.Close()
+
+	members := make(map[int]bool)
+	for rows.Next() {
+		var tagID int
+		if err := rows.Scan(&tagID); err != nil {
+			return nil, fmt.Errorf("failed to scan membership: %w", err)
+		}
+		members[tagID] = true
+	}
+	return members, rows.Err()
+}
+
+// addTagsToGroup adds the tags that are not members of the group yet, within tx
+func (s *Service) addTagsToGroup(ctx context.Context, tx *sql.Tx, groupID int, tagIDs []int) error {
+	members, err := s.txTagGroupMembers(ctx, tx, groupID)
+	if err != nil {
+		return err
+	}
+	var added []int
+	for _, tagID := range tagIDs {
+		if !members[tagID] {
+			added = append(added, tagID)
+		}
+	}
+	if err := s.insertTagGroupMemberships(ctx, tx, groupID, added); err != nil {
+		return err
+	}
+	return s.touchTagGroup(ctx, tx, groupID)
+}
+
+// removeTagsFromGroup removes the tags from the group within tx; tags that are not members
+// are ignored
+func (s *Service) removeTagsFromGroup(ctx context.Context, tx *sql.Tx, groupID int, tagIDs []int) error {
+	if _, err := s.txTagGroupMembers(ctx, tx, groupID); err != nil {
+		return err
+	}
+	tagIDs = uniqueTagIDs(tagIDs)
+	for start := 0; start < len(tagIDs); start += tagGroupMembershipBatchSize {
+		placeholders, args := inPlaceholders(tagIDs[start:min(start+tagGroupMembershipBatchSize, len(tagIDs))])
+		query := s.rebind("DELETE FROM tag_group_memberships WHERE tag_group_id = ? AND tag_id IN (" + placeholders + ")")
+		if _, err := tx.ExecContext(ctx, query, append([]interface{}{groupID}, args...)...); err != nil {
+			return fmt.Errorf("failed to remove memberships: %w", err)
+		}
+	}
+	return s.touchTagGroup(ctx, tx, groupID)
+}
+
+// touchTagGroup updates the modification time of a group whose members changed
+func (s *Service) touchTagGroup(ctx context.Context, tx *sql.Tx, groupID int) error {
+	if _, err := tx.ExecContext(ctx, s.rebind("UPDATE tag_groups SET modified = CURRENT_TIMESTAMP WHERE id = ?"), groupID); err != nil {
+		return fmt.Errorf("failed to update tag group: %w", err)
+	}
+	return nil
+}
+
+// changeTagGroupMembers checks the tags, when validateTags is set, and runs change in a
+// transaction, then clears the cached tag group counts
+func (s *Service) changeTagGroupMembers(ctx context.Context, tagIDs []int, validateTags bool, change func(tx *sql.Tx) error) error {
+	if len(tagIDs) == 0 {
+		return fmt.Errorf("tag_ids is required")
+	}
+	if validateTags {
+		if err := s.checkTagIDsExist(ctx, tagIDs); err != nil {
+			return err
+		}
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := change(tx); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit memberships: %w", err)
+	}
+	s.invalidateTagGroupFacets()
+	return nil
+}
+
+// AddTagGroupTags adds tags to a group; tags that are members already are left as they are.
+// With validateTags every tag has to exist in Paperless.
+func (s *Service) AddTagGroupTags(ctx context.Context, groupID int, tagIDs []int, userID int, validateTags bool) (*TagGroup, error) {
+	if _, err := s.editableTagGroup(ctx, groupID, userID); err != nil {
+		return nil, err
+	}
+	err := s.changeTagGroupMembers(ctx, tagIDs, validateTags, func(tx *sql.Tx) error {
+		return s.addTagsToGroup(ctx, tx, groupID, tagIDs)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.GetTagGroup(ctx, groupID)
+}
+
+// RemoveTagGroupTags removes tags from a group; tags that are not members are ignored, so
+// the tags need not exist in Paperless any more
+func (s *Service) RemoveTagGroupTags(ctx context.Context, groupID int, tagIDs []int, userID int) (*TagGroup, error) {
+	if _, err := s.editableTagGroup(ctx, groupID, userID); err != nil {
+		return nil, err
+	}
+	err := s.changeTagGroupMembers(ctx, tagIDs, false, func(tx *sql.Tx) error {
+		return s.removeTagsFromGroup(ctx, tx, groupID, tagIDs)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.GetTagGroup(ctx, groupID)
+}
+
+// MoveTagGroupTags moves tags from one group to another in one transaction. Every tag has
+// to be a member of the source group and, with validateTags, exist in Paperless.
+func (s *Service) MoveTagGroupTags(ctx context.Context, move TagGroupMoveRequest, userID int, validateTags bool) (*TagGroupMoveResponse, error) {
+	if move.FromGroupID == 0 || move.ToGroupID == 0 {
+		return nil, fmt.Errorf("from_group_id and to_group_id are required")
+	}
+	if move.FromGroupID == move.ToGroupID {
+		return nil, fmt.Errorf("invalid move: from_group_id and to_group_id are the same group")
+	}
+	for _, groupID := range []int{move.FromGroupID, move.ToGroupID} {
+		if _, err := s.editableTagGroup(ctx, groupID, userID); err != nil {
+			return nil, err
+		}
+	}
+
+	err := s.changeTagGroupMembers(ctx, move.TagIDs, validateTags, func(tx *sql.Tx) error {
+		members, err := s.txTagGroupMembers(ctx, tx, move.FromGroupID)
+		if err != nil {
+			return err
+		}
+		var outside []string
+		for _, tagID := range uniqueTagIDs(move.TagIDs) {
+			if !members[tagID] {
+				outside = append(outside, strconv.Itoa(tagID))
+			}
+		}
+		if len(outside) > 0 {
+			return fmt.Errorf("invalid tag_ids: tags %s are not in tag group %d", strings.Join(outside, ", "), move.FromGroupID)
+		}
+
+		if err := s.removeTagsFromGroup(ctx, tx, move.FromGroupID, move.TagIDs); err != nil {
+			return err
+		}
+		return s.addTagsToGroup(ctx, tx, move.ToGroupID, move.TagIDs)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	from, err := s.GetTagGroup(ctx, move.FromGroupID)
+	if err != nil {
+		return nil, err
+	}
+	to, err := s.GetTagGroup(ctx, move.ToGroupID)
+	if err != nil {
+		return nil, err
+	}
+	return &TagGroupMoveResponse{From: from, To: to}, nil
+}
+
+// HTTP Handlers for tag group members
+func (s *Service) handleChangeTagGroupTags(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	idStr := mux.Vars(r)["id"]
+	log.Printf("[TagGroups] %s /api/tag-groups/%s/tags/ - Request from %s", r.Method, idStr, r.RemoteAddr)
+
+	viewerID, err := viewerFromRequest(r)
+	if err != nil {
+		respondError(w, viewerErrorStatus(err), err.Error())
+		return
+	}
+
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid group ID")
+		return
+	}
+
+	var req TagGroupTagsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, fmt.Sprintf("Invalid request body: %v", err))
+		return
+	}
+
+	var group *TagGroup
+	if r.Method == http.MethodDelete {
+		group, err = s.RemoveTagGroupTags(ctx, id, req.TagIDs, viewerID)
+	} else {
+		group, err = s.AddTagGroupTags(ctx, id, req.TagIDs, viewerID, wantsTagValidation(r))
+	}
+	if err != nil {
+		log.Printf("[TagGroups] Error changing the tags of group %d: %v", id, err)
+		respondTagGroupError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, group)
+}
+
+func (s *Service) handleMoveTagGroupTags(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	log.Printf("[TagGroups] POST /api/tag-groups/move/ - Request from %s", r.RemoteAddr)
+
+	viewerID, err := viewerFromRequest(r)
+	if err != nil {
+		respondError(w, viewerErrorStatus(err), err.Error())
+		return
+	}
+
+	var req TagGroupMoveRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, fmt.Sprintf("Invalid request body: %v", err))
+		return
+	}
+
+	response, err := s.MoveTagGroupTags(ctx, req, viewerID, wantsTagValidation(r))
+	if err != nil {
+		log.Printf("[TagGroups] Error moving tags from group %d to %d: %v", req.FromGroupID, req.ToGroupID, err)
+		respondTagGroupError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, response)
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+)
+
+// StaleTagGroupMembership is a membership of a tag that no longer exists in Paperless
+type StaleTagGroupMembership struct {
+	TagGroupID   int    `json:"tag_group_id"`
+	TagGroupName string `json:"tag_group_name"`
+	TagID        int    `json:"tag_id"`
+}
+
+// StaleTagGroupMembershipsResponse lists stale memberships; Removed is set when they were deleted
+type StaleTagGroupMembershipsResponse struct {
+	Count   int                       `json:"count"`
+	Removed bool                      `json:"removed"`
+	Results []StaleTagGroupMembership `json:"results"`
+}
+
+// UngroupedTagsResponse lists the tags outside any tag group
+type UngroupedTagsResponse struct {
+	Count   int           `json:"count"`
+	Results []TagGroupTag `json:"results"`
+}
+
+// ListUngroupedTags returns the Paperless tags that are in none of the tag groups the user
+// can see, ordered by name, with their document counts (see tagGroupTagDetails)
+func (s *Service) ListUngroupedTags(ctx context.Context, includeTrashed bool, viewerID int) ([]TagGroupTag, error) {
+	documentCondition := trashedCondition(includeTrashed)
+	visibility, err := s.documentVisibilityCondition(ctx, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	if visibility != "" {
+		documentCondition = fmt.Sprintf("(%s AND %s)", documentCondition, visibility)
+	}
+
+	query := fmt.Sprintf(`
+		SELECT t.id, t.name, t.color, COUNT(DISTINCT d.id)
+		FROM documents_tag t
+		LEFT JOIN documents_document_tags dt ON dt.tag_id = t.id
+		LEFT JOIN documents_document d ON d.id = dt.document_id AND %s
+		WHERE NOT EXISTS (
+			SELECT 1 FROM tag_group_memberships m
+			INNER JOIN tag_groups g ON g.id = m.tag_group_id
+			WHERE m.tag_id = t.id AND %s
+		)
+		GROUP BY t.id, t.name, t.color
+		ORDER BY t.name ASC
+	`, documentCondition, s.tagGroupVisibleCondition("g.", viewerID))
+
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query ungrouped tags: %w", err)
+	}
+	defer rows.Close()
+
+	tags := []TagGroupTag{}
+	for rows.Next() {
+		var tag TagGroupTag
+		var color sql.NullString
+		if err := rows.Scan(&tag.ID, &tag.Name, &color, &tag.DocumentCount); err != nil {
+			return nil, fmt.Errorf("failed to scan tag: %w", err)
+		}
+		if color.Valid {
+			tag.Color = &color.String
+		}
+		tags = append(tags, tag)
+	}
+	return tags, rows.Err()
+}
+
+// staleMembershipCondition selects the memberships (aliased m) of tags deleted from Paperless
+const staleMembershipCondition = "NOT EXISTS (SELECT 1 FROM documents_tag t WHERE t.id = m.tag_id)"
+
+// ListStaleTagGroupMemberships returns the memberships, in all tag groups, of tags that were
+// deleted from Paperless
+func (s *Service) ListStaleTagGroupMemberships(ctx context.Context) ([]StaleTagGroupMembership, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT m.tag_group_id, g.name, m.tag_id
+		FROM tag_group_memberships m
+		INNER JOIN tag_groups g ON g.id = m.tag_group_id
+		WHERE `+staleMembershipCondition+`
+		ORDER BY m.tag_group_id, m.tag_id
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query stale memberships: %w", err)
+	}
+	defer rows.Close()
+
+	stale := []StaleTagGroupMembership{}
+	for rows.Next() {
+		var membership StaleTagGroupMembership
+		if err := rows.Scan(&membership.TagGroupID, &membership.TagGroupName, &membership.TagID); err != nil {
+			return nil, fmt.Errorf("failed to scan stale membership: %w", err)
+		}
+		stale = append(stale, membership)
+	}
+	return stale, rows.Err()
+}
+
+// RemoveStaleTagGroupMemberships deletes the memberships of tags that were deleted from
+// Paperless and returns them
+func (s *Service) RemoveStaleTagGroupMemberships(ctx context.Context) ([]StaleTagGroupMembership, error) {
+	stale, err := s.ListStaleTagGroupMemberships(ctx)
+	if err != nil || len(stale) == 0 {
+		return stale, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, membership := range stale {
+		query := s.rebind("DELETE FROM tag_group_memberships WHERE tag_group_id = ? AND tag_id = ?")
+		if _, err := tx.ExecContext(ctx, query, membership.TagGroupID, membership.TagID); err != nil {
+			return nil, fmt.Errorf("failed to delete stale membership: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit stale membership removal: %w", err)
+	}
+	s.invalidateTagGroupFacets()
+
+	log.Printf("[TagGroups] Removed %d memberships of deleted tags", len(stale))
+	return stale, nil
+}
+
+// HTTP Handlers for tags outside groups and stale memberships
+func (s *Service) handleListUngroupedTags(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	log.Printf("[TagGroups] GET /api/tag-groups/ungrouped/ - Request from %s", r.RemoteAddr)
+
+	viewerID, err := viewerFromRequest(r)
+	if err != nil {
+		respondError(w, viewerErrorStatus(err), err.Error())
+		return
+	}
+
+	tags, err := s.ListUngroupedTags(ctx, wantsTrashed(r), viewerID)
+	if err != nil {
+		log.Printf("[TagGroups] Error listing ungrouped tags: %v", err)
+		respondError(w, queryErrorStatus(err), err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, UngroupedTagsResponse{Count: len(tags), Results: tags})
+}
+
+// handleStaleTagGroupMemberships reports the memberships of deleted tags (GET) or removes
+// them (DELETE). Both cover the groups of all users, so they are limited to superusers.
+func (s *Service) handleStaleTagGroupMemberships(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	log.Printf("[TagGroups] %s /api/tag-groups/stale-memberships/ - Request from %s", r.Method, r.RemoteAddr)
+
+	userID, err := getUserIDFromRequest(r)
+	if err != nil {
+		respondError(w, viewerErrorStatus(err), err.Error())
+		return
+	}
+	superuser, err := s.isSuperuser(ctx, *userID)
+	if err != nil {
+		respondError(w, queryErrorStatus(err), err.Error())
+		return
+	}
+	if !superuser {
+		respondError(w, http.StatusForbidden, "permission denied: only superusers can maintain the memberships of all tag groups")
+		return
+	}
+
+	remove := r.Method == http.MethodDelete
+	var stale []StaleTagGroupMembership
+	if remove {
+		stale, err = s.RemoveStaleTagGroupMemberships(ctx)
+	} else {
+		stale, err = s.ListStaleTagGroupMemberships(ctx)
+	}
+	if err != nil {
+		log.Printf("[TagGroups] Error checking stale memberships: %v", err)
+		respondError(w, queryErrorStatus(err), err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, StaleTagGroupMembershipsResponse{Count: len(stale), Removed: remove, Results: stale})
+}
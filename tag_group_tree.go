@@ -0,0 +1,267 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// tagGroupParents returns the parent of every tag group, 0 for top level groups
+func (s *Service) tagGroupParents(ctx context.Context) (map[int]int, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT id, parent_group_id FROM tag_groups")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tag groups: %w", err)
+	}
+	defer rows.Close()
+
+	parents := make(map[int]int)
+	for rows.Next() {
+		var id int
+		var parentID *int
+		if err := rows.Scan(&id, &parentID); err != nil {
+			return nil, fmt.Errorf("failed to scan tag group: %w", err)
+		}
+		parents[id] = 0
+		if parentID != nil {
+			parents[id] = *parentID
+		}
+	}
+	return parents, rows.Err()
+}
+
+// checkTagGroupParent checks that parentID can become the parent of the group id (0 for a
+// new group): it has to exist, be visible to the user and must be neither the group itself
+// nor one of its subgroups
+func (s *Service) checkTagGroupParent(ctx context.Context, id int, parentID int, userID int) error {
+	parents, err := s.tagGroupParents(ctx)
+	if err != nil {
+		return err
+	}
+	if _, ok := parents[parentID]; !ok {
+		return fmt.Errorf("invalid parent: tag group %d not found", parentID)
+	}
+	if _, err := s.accessibleTagGroup(ctx, parentID, userID); err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			return fmt.Errorf("invalid parent: tag group %d not found", parentID)
+		}
+		return err
+	}
+	// Walking up from the new parent must not reach the group, or it would become its own
+	// ancestor. The step limit stops on cycles already in the table.
+	for ancestor, steps := parentID, 0; ancestor != 0 && steps <= len(parents); ancestor, steps = parents[ancestor], steps+1 {
+		if ancestor == id {
+			return fmt.Errorf("invalid parent: tag group %d is group %d or one of its subgroups", parentID, id)
+		}
+	}
+	return nil
+}
+
+// countDocumentsWithTags counts the documents matching documentCondition that have any of
+// the tags
+func (s *Service) countDocumentsWithTags(ctx context.Context, tagIDs []int, documentCondition string) (int, error) {
+	if len(tagIDs) == 0 {
+		return 0, nil
+	}
+	placeholders := make([]string, len(tagIDs))
+	args := make([]interface{}, len(tagIDs))
+	for i, tagID := range tagIDs {
+		placeholders[i] = "?"
+		args[i] = tagID
+	}
+	query := s.rebind(fmt.Sprintf(`
+		SELECT COUNT(DISTINCT dt.document_id)
+		FROM documents_document_tags dt
+		INNER JOIN documents_document d ON d.id = dt.document_id AND %s
+		WHERE dt.tag_id IN (%s)
+	`, documentCondition, strings.Join(placeholders, ", ")))
+
+	var count int
+	if err := s.db.QueryRowContext(ctx, query, args...).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count tag group documents: %w", err)
+	}
+	return count, nil
+}
+
+// GetTagGroupTree returns the tag group hierarchy: the top level groups with their
+// subgroups, or only the group rootID with its subgroups when rootID is not 0. Groups whose
+// parent no longer exists, or is not visible to the viewer, are shown at the top level. The
+// tree only holds the groups viewerID can see, and a viewerID other than 0 only counts
+// documents visible to that user.
+func (s *Service) GetTagGroupTree(ctx context.Context, rootID int, includeTrashed bool, viewerID int) ([]TagGroupNode, error) {
+	groups, err := s.ListTagGroups(ctx, viewerID)
+	if err != nil {
+		return nil, err
+	}
+
+	documentCondition := trashedCondition(includeTrashed)
+	visibility, err := s.documentVisibilityCondition(ctx, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	if visibility != "" {
+		documentCondition = fmt.Sprintf("(%s AND %s)", documentCondition, visibility)
+	}
+
+	// Groups are listed by name, so children end up sorted by name too
+	byID := make(map[int]TagGroup, len(groups))
+	for _, group := range groups {
+		byID[*group.ID] = group
+	}
+	children := make(map[int][]int)
+	for _, group := range groups {
+		parentID := 0
+		if group.ParentGroupID != nil {
+			if _, ok := byID[*group.ParentGroupID]; ok {
+				parentID = *group.ParentGroupID
+			}
+		}
+		children[parentID] = append(children[parentID], *group.ID)
+	}
+
+	// build returns the node of a group and the tags of its subtree
+	visited := make(map[int]bool)
+	var build func(id int) (TagGroupNode, []int, error)
+	build = func(id int) (TagGroupNode, []int, error) {
+		visited[id] = true
+		node := TagGroupNode{TagGroup: byID[id], Children: []TagGroupNode{}}
+		tagSet := make(map[int]bool)
+		for _, tagID := range node.TagIDs {
+			tagSet[tagID] = true
+		}
+		for _, childID := range children[id] {
+			if visited[childID] {
+				continue
+			}
+			child, childTags, err := build(childID)
+			if err != nil {
+				return node, nil, err
+			}
+			node.Children = append(node.Children, child)
+			for _, tagID := range childTags {
+				tagSet[tagID] = true
+			}
+		}
+
+		tagIDs := make([]int, 0, len(tagSet))
+		for tagID := range tagSet {
+			tagIDs = append(tagIDs, tagID)
+		}
+		count, err := s.countDocumentsWithTags(ctx, tagIDs, documentCondition)
+		if err != nil {
+			return node, nil, err
+		}
+		node.DocumentCount = count
+		return node, tagIDs, nil
+	}
+
+	roots := children[0]
+	if rootID != 0 {
+		if _, ok := byID[rootID]; !ok {
+			return nil, fmt.Errorf("tag group with id %d not found", rootID)
+		}
+		roots = []int{rootID}
+	}
+	nodes := []TagGroupNode{}
+	for _, id := range roots {
+		node, _, err := build(id)
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes, nil
+}
+
+// tagGroupErrorStatus maps tag group errors to HTTP status codes
+func tagGroupErrorStatus(err error) int {
+	switch {
+	case strings.Contains(err.Error(), "invalid"), strings.Contains(err.Error(), "required"):
+		return http.StatusBadRequest
+	case strings.Contains(err.Error(), "not found"):
+		return http.StatusNotFound
+	case strings.Contains(err.Error(), "permission denied"):
+		return http.StatusForbidden
+	case strings.Contains(err.Error(), "already exists"):
+		return http.StatusConflict
+	}
+	return queryErrorStatus(err)
+}
+
+// respondTagGroupError answers a failed tag group write: tags missing from Paperless get a
+// 422 listing them, other errors the status of tagGroupErrorStatus
+func respondTagGroupError(w http.ResponseWriter, err error) {
+	var invalid *InvalidTagIDsError
+	if errors.As(err, &invalid) {
+		problems := make([]ValidationError, len(invalid.TagIDs))
+		for i, tagID := range invalid.TagIDs {
+			problems[i] = ValidationError{Field: "tag_ids", Message: fmt.Sprintf("tag %d does not exist", tagID)}
+		}
+		respondValidationErrors(w, invalid.Error(), problems)
+		return
+	}
+	respondError(w, tagGroupErrorStatus(err), err.Error())
+}
+
+// HTTP Handlers for the tag group hierarchy
+func (s *Service) handleGetTagGroupTree(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	log.Printf("[TagGroups] GET /api/tag-groups/tree/ - Request from %s", r.RemoteAddr)
+
+	viewerID, err := viewerFromRequest(r)
+	if err != nil {
+		respondError(w, viewerErrorStatus(err), err.Error())
+		return
+	}
+
+	nodes, err := s.GetTagGroupTree(ctx, 0, wantsTrashed(r), viewerID)
+	if err == nil && wantsExpand(r, "tags") {
+		err = s.expandTagGroupNodes(ctx, nodes, wantsTrashed(r), viewerID)
+	}
+	if err != nil {
+		log.Printf("[TagGroups] Error building group tree: %v", err)
+		respondError(w, tagGroupErrorStatus(err), err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, TagGroupTreeResponse{Count: len(nodes), Results: nodes})
+}
+
+func (s *Service) handleGetTagGroupSubtree(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	idStr := mux.Vars(r)["id"]
+	log.Printf("[TagGroups] GET /api/tag-groups/%s/tree/ - Request from %s", idStr, r.RemoteAddr)
+
+	viewerID, err := viewerFromRequest(r)
+	if err != nil {
+		respondError(w, viewerErrorStatus(err), err.Error())
+		return
+	}
+
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid group ID")
+		return
+	}
+
+	nodes, err := s.GetTagGroupTree(ctx, id, wantsTrashed(r), viewerID)
+	if err == nil && wantsExpand(r, "tags") {
+		err = s.expandTagGroupNodes(ctx, nodes, wantsTrashed(r), viewerID)
+	}
+	if err != nil {
+		log.Printf("[TagGroups] Error building tree of group %d: %v", id, err)
+		respondError(w, tagGroupErrorStatus(err), err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, nodes[0])
+}
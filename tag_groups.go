@@ -1,9 +1,11 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
 	"strconv"
@@ -13,27 +15,31 @@ import (
 	"github.com/gorilla/mux"
 )
 
-// ListTagGroups retrieves all tag groups
-func (s *Service) ListTagGroups() ([]TagGroup, error) {
-	log.Printf("[TagGroups] ListTagGroups")
+// ListTagGroups retrieves the tag groups a user can see (see tagGroupVisibleCondition)
+func (s *Service) ListTagGroups(ctx context.Context, userID int) ([]TagGroup, error) {
+	log.Printf("[TagGroups] ListTagGroups - UserID: %d", userID)
 	var query string
 
 	switch s.config.DBEngine {
 	case "postgresql", "postgres":
 		query = `
-			SELECT id, name, description, created, modified
+			SELECT id, name, description, created, modified, parent_group_id, owner_id, username, is_global
 			FROM tag_groups
+			WHERE %s
 			ORDER BY name ASC
 		`
 	case "mysql", "mariadb", "sqlite", "sqlite3":
 		query = `
-			SELECT id, name, description, created, modified
+			SELECT id, name, description, created, modified, parent_group_id, owner_id, username, is_global
 			FROM tag_groups
+			WHERE %s
 			ORDER BY name ASC
 		`
 	}
 
-	rows, err := s.db.Query(query)
+	query = fmt.Sprintf(query, s.tagGroupVisibleCondition("", userID))
+
+	rows, err := s.db.QueryContext(ctx, query)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query tag groups: %w", err)
 	}
@@ -46,7 +52,7 @@ func (s *Service) ListTagGroups() ([]TagGroup, error) {
 			continue
 		}
 		// Load tag IDs for this group
-		tagIDs, err := s.getTagGroupMemberships(group.ID)
+		tagIDs, err := s.getTagGroupMemberships(ctx, group.ID)
 		if err == nil {
 			group.TagIDs = tagIDs
 		}
@@ -57,25 +63,25 @@ func (s *Service) ListTagGroups() ([]TagGroup, error) {
 }
 
 // GetTagGroup retrieves a specific tag group by ID
-func (s *Service) GetTagGroup(id int) (*TagGroup, error) {
+func (s *Service) GetTagGroup(ctx context.Context, id int) (*TagGroup, error) {
 	var query string
 
 	switch s.config.DBEngine {
 	case "postgresql", "postgres":
 		query = `
-			SELECT id, name, description, created, modified
+			SELECT id, name, description, created, modified, parent_group_id, owner_id, username, is_global
 			FROM tag_groups
 			WHERE id = $1
 		`
 	case "mysql", "mariadb", "sqlite", "sqlite3":
 		query = `
-			SELECT id, name, description, created, modified
+			SELECT id, name, description, created, modified, parent_group_id, owner_id, username, is_global
 			FROM tag_groups
 			WHERE id = ?
 		`
 	}
 
-	row := s.db.QueryRow(query, id)
+	row := s.db.QueryRowContext(ctx, query, id)
 	group, err := s.scanTagGroup(row)
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -85,7 +91,7 @@ func (s *Service) GetTagGroup(id int) (*TagGroup, error) {
 	}
 
 	// Load tag IDs for this group
-	tagIDs, err := s.getTagGroupMemberships(&id)
+	tagIDs, err := s.getTagGroupMemberships(ctx, &id)
 	if err == nil {
 		group.TagIDs = tagIDs
 	}
@@ -93,13 +99,36 @@ func (s *Service) GetTagGroup(id int) (*TagGroup, error) {
 	return &group, nil
 }
 
-// CreateTagGroup creates a new tag group
-func (s *Service) CreateTagGroup(group TagGroup) (*TagGroup, error) {
-	log.Printf("[TagGroups] CreateTagGroup - Name: %s", group.Name)
+// CreateTagGroup creates a new tag group owned by the user; userID 0 creates a group
+// without an owner, which everyone can see and change. With validateTags every tag has to
+// exist in Paperless.
+func (s *Service) CreateTagGroup(ctx context.Context, group TagGroup, userID int, username *string, validateTags bool) (*TagGroup, error) {
+	log.Printf("[TagGroups] CreateTagGroup - Name: %s, UserID: %d", group.Name, userID)
 
 	if group.Name == "" {
 		return nil, fmt.Errorf("name is required")
 	}
+	if validateTags && len(group.TagIDs) > 0 {
+		if err := s.checkTagIDsExist(ctx, group.TagIDs); err != nil {
+			return nil, err
+		}
+	}
+	if group.ParentGroupID != nil && *group.ParentGroupID == 0 {
+		group.ParentGroupID = nil
+	}
+	if group.ParentGroupID != nil {
+		if err := s.checkTagGroupParent(ctx, 0, *group.ParentGroupID, userID); err != nil {
+			return nil, err
+		}
+	}
+
+	group.OwnerID, group.Username = nil, nil
+	if userID > 0 {
+		group.OwnerID = &userID
+		group.Username = username
+	}
+	isGlobal := group.IsGlobal != nil && *group.IsGlobal
+	group.IsGlobal = &isGlobal
 
 	var query string
 	var result sql.Result
@@ -108,13 +137,13 @@ func (s *Service) CreateTagGroup(group TagGroup) (*TagGroup, error) {
 	switch s.config.DBEngine {
 	case "postgresql", "postgres":
 		query = `
-			INSERT INTO tag_groups (name, description)
-			VALUES ($1, $2)
+			INSERT INTO tag_groups (name, description, parent_group_id, owner_id, username, is_global)
+			VALUES ($1, $2, $3, $4, $5, $6)
 			RETURNING id, created, modified
 		`
 		var id int
 		var created, modified time.Time
-		err = s.db.QueryRow(query, group.Name, group.Description).Scan(&id, &created, &modified)
+		err = s.db.QueryRowContext(ctx, query, group.Name, group.Description, group.ParentGroupID, group.OwnerID, group.Username, isGlobal).Scan(&id, &created, &modified)
 		if err == nil {
 			group.ID = &id
 			createdStr := created.Format(time.RFC3339)
@@ -124,10 +153,10 @@ func (s *Service) CreateTagGroup(group TagGroup) (*TagGroup, error) {
 		}
 	case "mysql", "mariadb":
 		query = `
-			INSERT INTO tag_groups (name, description)
-			VALUES (?, ?)
+			INSERT INTO tag_groups (name, description, parent_group_id, owner_id, username, is_global)
+			VALUES (?, ?, ?, ?, ?, ?)
 		`
-		result, err = s.db.Exec(query, group.Name, group.Description)
+		result, err = s.db.ExecContext(ctx, query, group.Name, group.Description, group.ParentGroupID, group.OwnerID, group.Username, isGlobal)
 		if err == nil {
 			id, _ := result.LastInsertId()
 			idInt := int(id)
@@ -138,10 +167,10 @@ func (s *Service) CreateTagGroup(group TagGroup) (*TagGroup, error) {
 		}
 	case "sqlite", "sqlite3":
 		query = `
-			INSERT INTO tag_groups (name, description)
-			VALUES (?, ?)
+			INSERT INTO tag_groups (name, description, parent_group_id, owner_id, username, is_global)
+			VALUES (?, ?, ?, ?, ?, ?)
 		`
-		result, err = s.db.Exec(query, group.Name, group.Description)
+		result, err = s.db.ExecContext(ctx, query, group.Name, group.Description, group.ParentGroupID, group.OwnerID, group.Username, isGlobal)
 		if err == nil {
 			id, _ := result.LastInsertId()
 			idInt := int(id)
@@ -161,92 +190,191 @@ func (s *Service) CreateTagGroup(group TagGroup) (*TagGroup, error) {
 
 	// Add tag memberships if provided
 	if len(group.TagIDs) > 0 {
-		if err := s.updateTagGroupMemberships(group.ID, group.TagIDs); err != nil {
-			log.Printf("[TagGroups] Warning: Failed to add tag memberships: %v", err)
+		if err := s.updateTagGroupMemberships(ctx, group.ID, group.TagIDs); err != nil {
+			// Drop the new group so that the request can simply be retried
+			if _, deleteErr := s.db.ExecContext(ctx, s.rebind("DELETE FROM tag_groups WHERE id = ?"), *group.ID); deleteErr != nil {
+				log.Printf("[TagGroups] Failed to remove group %d after its memberships failed: %v", *group.ID, deleteErr)
+			}
+			return nil, err
 		}
 	}
 
 	return &group, nil
 }
 
-// UpdateTagGroup updates an existing tag group
-func (s *Service) UpdateTagGroup(id int, updates TagGroup) (*TagGroup, error) {
-	log.Printf("[TagGroups] UpdateTagGroup - ID: %d", id)
+// UpdateTagGroup updates an existing tag group the user can change (see editableTagGroup).
+// Only the fields set in updates are changed: an empty name or a nil description, parent,
+// is_global or tag list keeps the current value. A parent of 0 moves the group to the top
+// level. With validateTags, tags added to the group have to exist in Paperless.
+func (s *Service) UpdateTagGroup(ctx context.Context, id int, updates TagGroup, userID int, validateTags bool) (*TagGroup, error) {
+	log.Printf("[TagGroups] UpdateTagGroup - ID: %d, UserID: %d", id, userID)
 
 	// Get existing group
-	existing, err := s.GetTagGroup(id)
+	existing, err := s.editableTagGroup(ctx, id, userID)
 	if err != nil {
 		return nil, err
 	}
 
 	// Update fields
+	group := *existing
 	if updates.Name != "" {
-		existing.Name = updates.Name
+		group.Name = updates.Name
 	}
 	if updates.Description != nil {
-		existing.Description = updates.Description
+		group.Description = updates.Description
+	}
+	if updates.ParentGroupID != nil {
+		group.ParentGroupID = updates.ParentGroupID
+	}
+	if updates.IsGlobal != nil {
+		group.IsGlobal = updates.IsGlobal
+	}
+	group.TagIDs = updates.TagIDs
+
+	return s.saveTagGroup(ctx, existing, group, userID, validateTags)
+}
+
+// PatchTagGroup applies a JSON Merge Patch (RFC 7386) to a tag group the user can change:
+// omitted fields are kept and null clears a field, so "description": null removes the
+// description and "tag_ids": null or [] removes all members. validateTags is as for
+// UpdateTagGroup.
+func (s *Service) PatchTagGroup(ctx context.Context, id int, patch []byte, userID int, validateTags bool) (*TagGroup, error) {
+	log.Printf("[TagGroups] PatchTagGroup - ID: %d, UserID: %d", id, userID)
+
+	existing, err := s.editableTagGroup(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	// GetTagGroup leaves out members it fails to load, which must not read as "no members"
+	tagIDs, err := s.getTagGroupMemberships(ctx, &id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load tag group members: %w", err)
+	}
+	existing.TagIDs = tagIDs
+
+	var group TagGroup
+	if err := mergePatchJSON(existing, patch, &group); err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(group.Name) == "" {
+		return nil, fmt.Errorf("invalid tag group: name is required")
+	}
+	if group.TagIDs == nil {
+		group.TagIDs = []int{}
+	}
+
+	return s.saveTagGroup(ctx, existing, group, userID, validateTags)
+}
+
+// saveTagGroup stores the new state of a tag group. Its ID, owner and timestamps are those of
+// existing, and its members are only replaced when group.TagIDs is not nil. With validateTags
+// the tags that become members have to exist in Paperless.
+func (s *Service) saveTagGroup(ctx context.Context, existing *TagGroup, group TagGroup, userID int, validateTags bool) (*TagGroup, error) {
+	id := *existing.ID
+	group.ID, group.OwnerID, group.Username, group.Created, group.Tags = existing.ID, existing.OwnerID, existing.Username, existing.Created, nil
+
+	// 0 moves the group to the top level
+	if group.ParentGroupID != nil && *group.ParentGroupID == 0 {
+		group.ParentGroupID = nil
+	}
+	if group.ParentGroupID != nil && (existing.ParentGroupID == nil || *existing.ParentGroupID != *group.ParentGroupID) {
+		if err := s.checkTagGroupParent(ctx, id, *group.ParentGroupID, userID); err != nil {
+			return nil, err
+		}
+	}
+	wasGlobal := existing.IsGlobal != nil && *existing.IsGlobal
+	isGlobal := group.IsGlobal != nil && *group.IsGlobal
+	if isGlobal != wasGlobal && existing.OwnerID != nil && *existing.OwnerID != userID {
+		// Only the owner shares a group or takes it back
+		return nil, fmt.Errorf("permission denied: only the owner can change is_global")
+	}
+	group.IsGlobal = &isGlobal
+	if validateTags && group.TagIDs != nil {
+		if err := s.checkNewTagIDsExist(ctx, group.TagIDs, existing.TagIDs); err != nil {
+			return nil, err
+		}
 	}
 
 	var query string
+	var err error
 	switch s.config.DBEngine {
 	case "postgresql", "postgres":
 		query = `
 			UPDATE tag_groups
-			SET name = $1, description = $2, modified = CURRENT_TIMESTAMP
-			WHERE id = $3
+			SET name = $1, description = $2, parent_group_id = $3, is_global = $4, modified = CURRENT_TIMESTAMP
+			WHERE id = $5
 			RETURNING modified
 		`
 		var modified time.Time
-		err = s.db.QueryRow(query, existing.Name, existing.Description, id).Scan(&modified)
+		err = s.db.QueryRowContext(ctx, query, group.Name, group.Description, group.ParentGroupID, isGlobal, id).Scan(&modified)
 		if err == nil {
 			modifiedStr := modified.Format(time.RFC3339)
-			existing.Modified = &modifiedStr
+			group.Modified = &modifiedStr
 		}
 	case "mysql", "mariadb":
 		query = `
 			UPDATE tag_groups
-			SET name = ?, description = ?, modified = CURRENT_TIMESTAMP
+			SET name = ?, description = ?, parent_group_id = ?, is_global = ?, modified = CURRENT_TIMESTAMP
 			WHERE id = ?
 		`
-		_, err = s.db.Exec(query, existing.Name, existing.Description, id)
+		_, err = s.db.ExecContext(ctx, query, group.Name, group.Description, group.ParentGroupID, isGlobal, id)
 		if err == nil {
 			now := time.Now().Format(time.RFC3339)
-			existing.Modified = &now
+			group.Modified = &now
 		}
 	case "sqlite", "sqlite3":
 		query = `
 			UPDATE tag_groups
-			SET name = ?, description = ?, modified = CURRENT_TIMESTAMP
+			SET name = ?, description = ?, parent_group_id = ?, is_global = ?, modified = CURRENT_TIMESTAMP
 			WHERE id = ?
 		`
-		_, err = s.db.Exec(query, existing.Name, existing.Description, id)
+		_, err = s.db.ExecContext(ctx, query, group.Name, group.Description, group.ParentGroupID, isGlobal, id)
 		if err == nil {
 			now := time.Now().Format(time.RFC3339)
-			existing.Modified = &now
+			group.Modified = &now
 		}
 	}
 
 	if err != nil {
 		if strings.Contains(err.Error(), "UNIQUE constraint") || strings.Contains(err.Error(), "duplicate key") {
-			return nil, fmt.Errorf("tag group with name '%s' already exists", existing.Name)
+			return nil, fmt.Errorf("tag group with name '%s' already exists", group.Name)
 		}
 		return nil, fmt.Errorf("failed to update tag group: %w", err)
 	}
+	s.invalidateTagGroupFacets()
 
 	// Update tag memberships if provided
-	if updates.TagIDs != nil {
-		if err := s.updateTagGroupMemberships(&id, updates.TagIDs); err != nil {
-			log.Printf("[TagGroups] Warning: Failed to update tag memberships: %v", err)
+	if group.TagIDs != nil {
+		if err := s.updateTagGroupMemberships(ctx, &id, group.TagIDs); err != nil {
+			return nil, err
 		}
-		existing.TagIDs = updates.TagIDs
+	} else {
+		group.TagIDs = existing.TagIDs
 	}
 
-	return existing, nil
+	return &group, nil
 }
 
-// DeleteTagGroup deletes a tag group
-func (s *Service) DeleteTagGroup(id int) error {
-	log.Printf("[TagGroups] DeleteTagGroup - ID: %d")
+// DeleteTagGroup deletes a tag group the user can delete (see deletableTagGroup)
+func (s *Service) DeleteTagGroup(ctx context.Context, id int, userID int) error {
+	log.Printf("[TagGroups] DeleteTagGroup - ID: %d, UserID: %d", id, userID)
+
+	if _, err := s.deletableTagGroup(ctx, id, userID); err != nil {
+		return err
+	}
+
+	// Subgroups move up to the parent of the deleted group
+	var parentGroupID sql.NullInt64
+	err := s.db.QueryRowContext(ctx, s.rebind("SELECT parent_group_id FROM tag_groups WHERE id = ?"), id).Scan(&parentGroupID)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("tag group with id %d not found", id)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to query tag group: %w", err)
+	}
+	if _, err := s.db.ExecContext(ctx, s.rebind("UPDATE tag_groups SET parent_group_id = ?, modified = CURRENT_TIMESTAMP WHERE parent_group_id = ?"), parentGroupID, id); err != nil {
+		return fmt.Errorf("failed to move subgroups of tag group %d: %w", id, err)
+	}
 
 	var query string
 	switch s.config.DBEngine {
@@ -256,7 +384,7 @@ func (s *Service) DeleteTagGroup(id int) error {
 		query = `DELETE FROM tag_groups WHERE id = ?`
 	}
 
-	result, err := s.db.Exec(query, id)
+	result, err := s.db.ExecContext(ctx, query, id)
 	if err != nil {
 		return fmt.Errorf("failed to delete tag group: %w", err)
 	}
@@ -265,12 +393,13 @@ func (s *Service) DeleteTagGroup(id int) error {
 	if rowsAffected == 0 {
 		return fmt.Errorf("tag group with id %d not found", id)
 	}
+	s.invalidateTagGroupFacets()
 
 	return nil
 }
 
 // getTagGroupMemberships retrieves tag IDs for a tag group
-func (s *Service) getTagGroupMemberships(groupID *int) ([]int, error) {
+func (s *Service) getTagGroupMemberships(ctx context.Context, groupID *int) ([]int, error) {
 	if groupID == nil {
 		return []int{}, nil
 	}
@@ -283,7 +412,7 @@ func (s *Service) getTagGroupMemberships(groupID *int) ([]int, error) {
 		query = `SELECT tag_id FROM tag_group_memberships WHERE tag_group_id = ? ORDER BY tag_id ASC`
 	}
 
-	rows, err := s.db.Query(query, *groupID)
+	rows, err := s.db.QueryContext(ctx, query, *groupID)
 	if err != nil {
 		return nil, err
 	}
@@ -300,45 +429,33 @@ func (s *Service) getTagGroupMemberships(groupID *int) ([]int, error) {
 	return tagIDs, nil
 }
 
-// updateTagGroupMemberships updates the tag memberships for a group
-func (s *Service) updateTagGroupMemberships(groupID *int, tagIDs []int) error {
+// updateTagGroupMemberships replaces the tag memberships of a group in one transaction, so
+// that a failure keeps the previous memberships
+func (s *Service) updateTagGroupMemberships(ctx context.Context, groupID *int, tagIDs []int) error {
 	if groupID == nil {
 		return fmt.Errorf("group ID is required")
 	}
 
-	// Delete existing memberships
-	var deleteQuery string
-	switch s.config.DBEngine {
-	case "postgresql", "postgres":
-		deleteQuery = `DELETE FROM tag_group_memberships WHERE tag_group_id = $1`
-	case "mysql", "mariadb", "sqlite", "sqlite3":
-		deleteQuery = `DELETE FROM tag_group_memberships WHERE tag_group_id = ?`
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
+	defer tx.Rollback()
 
-	_, err := s.db.Exec(deleteQuery, *groupID)
-	if err != nil {
+	// Delete existing memberships
+	if _, err := tx.ExecContext(ctx, s.rebind(`DELETE FROM tag_group_memberships WHERE tag_group_id = ?`), *groupID); err != nil {
 		return fmt.Errorf("failed to delete existing memberships: %w", err)
 	}
 
 	// Insert new memberships
-	if len(tagIDs) == 0 {
-		return nil
+	if err := s.insertTagGroupMemberships(ctx, tx, *groupID, tagIDs); err != nil {
+		return err
 	}
 
-	var insertQuery string
-	switch s.config.DBEngine {
-	case "postgresql", "postgres":
-		insertQuery = `INSERT INTO tag_group_memberships (tag_group_id, tag_id) VALUES ($1, $2)`
-	case "mysql", "mariadb", "sqlite", "sqlite3":
-		insertQuery = `INSERT INTO tag_group_memberships (tag_group_id, tag_id) VALUES (?, ?)`
-	}
-
-	for _, tagID := range tagIDs {
-		_, err := s.db.Exec(insertQuery, *groupID, tagID)
-		if err != nil {
-			log.Printf("[TagGroups] Warning: Failed to add membership for tag %d: %v", tagID, err)
-		}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit memberships: %w", err)
 	}
+	s.invalidateTagGroupFacets()
 
 	return nil
 }
@@ -347,16 +464,19 @@ func (s *Service) updateTagGroupMemberships(groupID *int, tagIDs []int) error {
 func (s *Service) scanTagGroup(scanner interface{}) (TagGroup, error) {
 	var group TagGroup
 	var id sql.NullInt64
-	var description, created, modified sql.NullString
+	var description, username sql.NullString
+	var created, modified dbTimestamp
+	var parentGroupID, ownerID sql.NullInt64
+	var isGlobal sql.NullBool
 
 	switch sc := scanner.(type) {
 	case *sql.Row:
-		err := sc.Scan(&id, &group.Name, &description, &created, &modified)
+		err := sc.Scan(&id, &group.Name, &description, &created, &modified, &parentGroupID, &ownerID, &username, &isGlobal)
 		if err != nil {
 			return group, err
 		}
 	case *sql.Rows:
-		err := sc.Scan(&id, &group.Name, &description, &created, &modified)
+		err := sc.Scan(&id, &group.Name, &description, &created, &modified, &parentGroupID, &ownerID, &username, &isGlobal)
 		if err != nil {
 			return group, err
 		}
@@ -371,11 +491,21 @@ func (s *Service) scanTagGroup(scanner interface{}) (TagGroup, error) {
 	if description.Valid {
 		group.Description = &description.String
 	}
-	if created.Valid {
-		group.Created = &created.String
+	group.Created = s.formatTimestamp(created)
+	group.Modified = s.formatTimestamp(modified)
+	if parentGroupID.Valid {
+		parentID := int(parentGroupID.Int64)
+		group.ParentGroupID = &parentID
+	}
+	if ownerID.Valid {
+		owner := int(ownerID.Int64)
+		group.OwnerID = &owner
 	}
-	if modified.Valid {
-		group.Modified = &modified.String
+	if username.Valid {
+		group.Username = &username.String
+	}
+	if isGlobal.Valid {
+		group.IsGlobal = &isGlobal.Bool
 	}
 
 	return group, nil
@@ -384,9 +514,18 @@ func (s *Service) scanTagGroup(scanner interface{}) (TagGroup, error) {
 // HTTP Handlers
 
 func (s *Service) handleListTagGroups(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
 	log.Printf("[TagGroups] GET /api/tag-groups/ - Request from %s", r.RemoteAddr)
 
-	groups, err := s.ListTagGroups()
+	viewerID, err := viewerFromRequest(r)
+	if err != nil {
+		respondError(w, viewerErrorStatus(err), err.Error())
+		return
+	}
+
+	groups, err := s.ListTagGroups(ctx, viewerID)
 	if err != nil {
 		log.Printf("[TagGroups] Error listing groups: %v", err)
 		respondError(w, http.StatusInternalServerError, err.Error())
@@ -394,6 +533,17 @@ func (s *Service) handleListTagGroups(w http.ResponseWriter, r *http.Request) {
 	}
 
 	log.Printf("[TagGroups] Found %d groups", len(groups))
+	if wantsExpand(r, "tags") {
+		expanded := make([]*TagGroup, len(groups))
+		for i := range groups {
+			expanded[i] = &groups[i]
+		}
+		if err := s.expandTagGroupTags(ctx, expanded, wantsTrashed(r), viewerID); err != nil {
+			log.Printf("[TagGroups] Error expanding group tags: %v", err)
+			respondError(w, queryErrorStatus(err), err.Error())
+			return
+		}
+	}
 	response := TagGroupListResponse{
 		Count:   len(groups),
 		Results: groups,
@@ -403,10 +553,19 @@ func (s *Service) handleListTagGroups(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Service) handleGetTagGroup(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
 	vars := mux.Vars(r)
 	idStr := vars["id"]
 	log.Printf("[TagGroups] GET /api/tag-groups/%s/ - Request from %s", idStr, r.RemoteAddr)
 
+	viewerID, err := viewerFromRequest(r)
+	if err != nil {
+		respondError(w, viewerErrorStatus(err), err.Error())
+		return
+	}
+
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
 		log.Printf("[TagGroups] Invalid group ID: %s", idStr)
@@ -414,20 +573,37 @@ func (s *Service) handleGetTagGroup(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	group, err := s.GetTagGroup(id)
+	group, err := s.accessibleTagGroup(ctx, id, viewerID)
 	if err != nil {
 		log.Printf("[TagGroups] Error getting group %d: %v", id, err)
-		respondError(w, http.StatusNotFound, err.Error())
+		respondError(w, tagGroupErrorStatus(err), err.Error())
 		return
 	}
 
+	if wantsExpand(r, "tags") {
+		if err := s.expandTagGroupTags(ctx, []*TagGroup{group}, wantsTrashed(r), viewerID); err != nil {
+			log.Printf("[TagGroups] Error expanding tags of group %d: %v", id, err)
+			respondError(w, queryErrorStatus(err), err.Error())
+			return
+		}
+	}
+
 	log.Printf("[TagGroups] Successfully retrieved group %d: %s", id, group.Name)
 	respondJSON(w, http.StatusOK, group)
 }
 
 func (s *Service) handleCreateTagGroup(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
 	log.Printf("[TagGroups] POST /api/tag-groups/ - Request from %s", r.RemoteAddr)
 
+	viewerID, err := viewerFromRequest(r)
+	if err != nil {
+		respondError(w, viewerErrorStatus(err), err.Error())
+		return
+	}
+
 	var group TagGroup
 	if err := json.NewDecoder(r.Body).Decode(&group); err != nil {
 		log.Printf("[TagGroups] Error decoding request body: %v", err)
@@ -443,10 +619,10 @@ func (s *Service) handleCreateTagGroup(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	created, err := s.CreateTagGroup(group)
+	created, err := s.CreateTagGroup(ctx, group, viewerID, getUsernameFromRequest(r), wantsTagValidation(r))
 	if err != nil {
 		log.Printf("[TagGroups] Error creating group: %v", err)
-		respondError(w, http.StatusInternalServerError, err.Error())
+		respondTagGroupError(w, err)
 		return
 	}
 
@@ -455,11 +631,20 @@ func (s *Service) handleCreateTagGroup(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Service) handleUpdateTagGroup(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
 	vars := mux.Vars(r)
 	idStr := vars["id"]
 	method := r.Method
 	log.Printf("[TagGroups] %s /api/tag-groups/%s/ - Request from %s", method, idStr, r.RemoteAddr)
 
+	viewerID, err := viewerFromRequest(r)
+	if err != nil {
+		respondError(w, viewerErrorStatus(err), err.Error())
+		return
+	}
+
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
 		log.Printf("[TagGroups] Invalid group ID: %s", idStr)
@@ -467,19 +652,31 @@ func (s *Service) handleUpdateTagGroup(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var updates TagGroup
-	if err := json.NewDecoder(r.Body).Decode(&updates); err != nil {
-		log.Printf("[TagGroups] Error decoding request body for group %d: %v", id, err)
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
 		respondError(w, http.StatusBadRequest, fmt.Sprintf("Invalid request body: %v", err))
 		return
 	}
 
 	log.Printf("[TagGroups] Updating group ID: %d", id)
 
-	updated, err := s.UpdateTagGroup(id, updates)
+	// PUT changes the fields it is given; PATCH is a JSON Merge Patch (RFC 7386) of the
+	// current group, where null clears a field
+	var updated *TagGroup
+	if method == http.MethodPatch {
+		updated, err = s.PatchTagGroup(ctx, id, body, viewerID, wantsTagValidation(r))
+	} else {
+		var updates TagGroup
+		if err := json.Unmarshal(body, &updates); err != nil {
+			log.Printf("[TagGroups] Error decoding request body for group %d: %v", id, err)
+			respondError(w, http.StatusBadRequest, fmt.Sprintf("Invalid request body: %v", err))
+			return
+		}
+		updated, err = s.UpdateTagGroup(ctx, id, updates, viewerID, wantsTagValidation(r))
+	}
 	if err != nil {
 		log.Printf("[TagGroups] Error updating group %d: %v", id, err)
-		respondError(w, http.StatusInternalServerError, err.Error())
+		respondTagGroupError(w, err)
 		return
 	}
 
@@ -488,10 +685,19 @@ func (s *Service) handleUpdateTagGroup(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Service) handleDeleteTagGroup(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
 	vars := mux.Vars(r)
 	idStr := vars["id"]
 	log.Printf("[TagGroups] DELETE /api/tag-groups/%s/ - Request from %s", idStr, r.RemoteAddr)
 
+	viewerID, err := viewerFromRequest(r)
+	if err != nil {
+		respondError(w, viewerErrorStatus(err), err.Error())
+		return
+	}
+
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
 		log.Printf("[TagGroups] Invalid group ID: %s", idStr)
@@ -501,9 +707,9 @@ func (s *Service) handleDeleteTagGroup(w http.ResponseWriter, r *http.Request) {
 
 	log.Printf("[TagGroups] Deleting group ID: %d", id)
 
-	if err := s.DeleteTagGroup(id); err != nil {
+	if err := s.DeleteTagGroup(ctx, id, viewerID); err != nil {
 		log.Printf("[TagGroups] Error deleting group %d: %v", id, err)
-		respondError(w, http.StatusInternalServerError, err.Error())
+		respondError(w, tagGroupErrorStatus(err), err.Error())
 		return
 	}
 
@@ -513,204 +719,49 @@ func (s *Service) handleDeleteTagGroup(w http.ResponseWriter, r *http.Request) {
 
 // Tag Description Functions
 
-// GetTagDescription retrieves a description for a tag
-func (s *Service) GetTagDescription(tagID int) (*TagDescription, error) {
-	var query string
-
-	switch s.config.DBEngine {
-	case "postgresql", "postgres":
-		query = `
-			SELECT id, tag_id, description, created, modified
-			FROM tag_descriptions
-			WHERE tag_id = $1
-		`
-	case "mysql", "mariadb", "sqlite", "sqlite3":
-		query = `
-			SELECT id, tag_id, description, created, modified
-			FROM tag_descriptions
-			WHERE tag_id = ?
-		`
+// tagDescription converts the description of a tag to a TagDescription
+func tagDescription(desc EntityDescription) TagDescription {
+	return TagDescription{
+		ID:          desc.ID,
+		TagID:       desc.EntityID,
+		Description: desc.Description,
+		Created:     desc.Created,
+		Modified:    desc.Modified,
+		ModifiedBy:  desc.ModifiedBy,
 	}
+}
 
-	row := s.db.QueryRow(query, tagID)
-	desc, err := s.scanTagDescription(row)
+// GetTagDescription retrieves a description for a tag
+func (s *Service) GetTagDescription(ctx context.Context, tagID int) (*TagDescription, error) {
+	desc, err := s.GetEntityDescription(ctx, "tag", tagID)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			// Return empty description if not found
-			return &TagDescription{TagID: tagID}, nil
-		}
 		return nil, err
 	}
-
-	return &desc, nil
+	tagDesc := tagDescription(*desc)
+	return &tagDesc, nil
 }
 
-// SetTagDescription creates or updates a description for a tag
-func (s *Service) SetTagDescription(desc TagDescription) (*TagDescription, error) {
-	log.Printf("[TagDescriptions] SetTagDescription - TagID: %d", desc.TagID)
-
-	// Check if description exists
-	existing, err := s.GetTagDescription(desc.TagID)
-	if err != nil && err != sql.ErrNoRows {
-		return nil, fmt.Errorf("failed to check existing description: %w", err)
-	}
-
-	var query string
-	var result sql.Result
-
-	if existing != nil && existing.ID != nil {
-		// Update existing
-		switch s.config.DBEngine {
-		case "postgresql", "postgres":
-			query = `
-				UPDATE tag_descriptions
-				SET description = $1, modified = CURRENT_TIMESTAMP
-				WHERE tag_id = $2
-				RETURNING modified
-			`
-			var modified time.Time
-			err = s.db.QueryRow(query, desc.Description, desc.TagID).Scan(&modified)
-			if err == nil {
-				modifiedStr := modified.Format(time.RFC3339)
-				desc.Modified = &modifiedStr
-				desc.ID = existing.ID
-				desc.Created = existing.Created
-			}
-		case "mysql", "mariadb":
-			query = `
-				UPDATE tag_descriptions
-				SET description = ?, modified = CURRENT_TIMESTAMP
-				WHERE tag_id = ?
-			`
-			result, err = s.db.Exec(query, desc.Description, desc.TagID)
-			if err == nil {
-				desc.ID = existing.ID
-				desc.Created = existing.Created
-				now := time.Now().Format(time.RFC3339)
-				desc.Modified = &now
-			}
-		case "sqlite", "sqlite3":
-			query = `
-				UPDATE tag_descriptions
-				SET description = ?, modified = CURRENT_TIMESTAMP
-				WHERE tag_id = ?
-			`
-			result, err = s.db.Exec(query, desc.Description, desc.TagID)
-			if err == nil {
-				desc.ID = existing.ID
-				desc.Created = existing.Created
-				now := time.Now().Format(time.RFC3339)
-				desc.Modified = &now
-			}
-		}
-	} else {
-		// Create new
-		switch s.config.DBEngine {
-		case "postgresql", "postgres":
-			query = `
-				INSERT INTO tag_descriptions (tag_id, description)
-				VALUES ($1, $2)
-				RETURNING id, created, modified
-			`
-			var id int
-			var created, modified time.Time
-			err = s.db.QueryRow(query, desc.TagID, desc.Description).Scan(&id, &created, &modified)
-			if err == nil {
-				desc.ID = &id
-				createdStr := created.Format(time.RFC3339)
-				modifiedStr := modified.Format(time.RFC3339)
-				desc.Created = &createdStr
-				desc.Modified = &modifiedStr
-			}
-		case "mysql", "mariadb":
-			query = `
-				INSERT INTO tag_descriptions (tag_id, description)
-				VALUES (?, ?)
-			`
-			result, err = s.db.Exec(query, desc.TagID, desc.Description)
-			if err == nil {
-				id, _ := result.LastInsertId()
-				idInt := int(id)
-				desc.ID = &idInt
-				now := time.Now().Format(time.RFC3339)
-				desc.Created = &now
-				desc.Modified = &now
-			}
-		case "sqlite", "sqlite3":
-			query = `
-				INSERT INTO tag_descriptions (tag_id, description)
-				VALUES (?, ?)
-			`
-			result, err = s.db.Exec(query, desc.TagID, desc.Description)
-			if err == nil {
-				id, _ := result.LastInsertId()
-				idInt := int(id)
-				desc.ID = &idInt
-				now := time.Now().Format(time.RFC3339)
-				desc.Created = &now
-				desc.Modified = &now
-			}
-		}
-	}
-
+// SetTagDescription creates or updates a description for a tag on behalf of a user
+func (s *Service) SetTagDescription(ctx context.Context, desc TagDescription, userID int) (*TagDescription, error) {
+	saved, err := s.SetEntityDescription(ctx, EntityDescription{EntityType: "tag", EntityID: desc.TagID, Description: desc.Description}, userID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to save tag description: %w", err)
+		return nil, err
 	}
-
-	return &desc, nil
+	tagDesc := tagDescription(*saved)
+	return &tagDesc, nil
 }
 
 // DeleteTagDescription deletes a description for a tag
-func (s *Service) DeleteTagDescription(tagID int) error {
-	log.Printf("[TagDescriptions] DeleteTagDescription - TagID: %d")
-
-	var query string
-	switch s.config.DBEngine {
-	case "postgresql", "postgres":
-		query = `DELETE FROM tag_descriptions WHERE tag_id = $1`
-	case "mysql", "mariadb", "sqlite", "sqlite3":
-		query = `DELETE FROM tag_descriptions WHERE tag_id = ?`
-	}
-
-	_, err := s.db.Exec(query, tagID)
-	if err != nil {
-		return fmt.Errorf("failed to delete tag description: %w", err)
-	}
-
-	return nil
-}
-
-// scanTagDescription scans a TagDescription from a database row
-func (s *Service) scanTagDescription(row *sql.Row) (TagDescription, error) {
-	var desc TagDescription
-	var id sql.NullInt64
-	var description, created, modified sql.NullString
-
-	err := row.Scan(&id, &desc.TagID, &description, &created, &modified)
-	if err != nil {
-		return desc, err
-	}
-
-	if id.Valid {
-		idInt := int(id.Int64)
-		desc.ID = &idInt
-	}
-	if description.Valid {
-		desc.Description = &description.String
-	}
-	if created.Valid {
-		desc.Created = &created.String
-	}
-	if modified.Valid {
-		desc.Modified = &modified.String
-	}
-
-	return desc, nil
+func (s *Service) DeleteTagDescription(ctx context.Context, tagID int) error {
+	return s.DeleteEntityDescription(ctx, "tag", tagID)
 }
 
 // HTTP Handlers for Tag Descriptions
 
 func (s *Service) handleGetTagDescription(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
 	vars := mux.Vars(r)
 	tagIDStr := vars["tagId"]
 	log.Printf("[TagDescriptions] GET /api/tag-descriptions/%s/ - Request from %s", tagIDStr, r.RemoteAddr)
@@ -722,7 +773,7 @@ func (s *Service) handleGetTagDescription(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	desc, err := s.GetTagDescription(tagID)
+	desc, err := s.GetTagDescription(ctx, tagID)
 	if err != nil {
 		log.Printf("[TagDescriptions] Error getting description for tag %d: %v", tagID, err)
 		respondError(w, http.StatusInternalServerError, err.Error())
@@ -733,10 +784,19 @@ func (s *Service) handleGetTagDescription(w http.ResponseWriter, r *http.Request
 }
 
 func (s *Service) handleSetTagDescription(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
 	vars := mux.Vars(r)
 	tagIDStr := vars["tagId"]
 	log.Printf("[TagDescriptions] PUT /api/tag-descriptions/%s/ - Request from %s", tagIDStr, r.RemoteAddr)
 
+	viewerID, err := viewerFromRequest(r)
+	if err != nil {
+		respondError(w, viewerErrorStatus(err), err.Error())
+		return
+	}
+
 	tagID, err := strconv.Atoi(tagIDStr)
 	if err != nil {
 		log.Printf("[TagDescriptions] Invalid tag ID: %s", tagIDStr)
@@ -752,7 +812,7 @@ func (s *Service) handleSetTagDescription(w http.ResponseWriter, r *http.Request
 	}
 
 	desc.TagID = tagID
-	saved, err := s.SetTagDescription(desc)
+	saved, err := s.SetTagDescription(ctx, desc, viewerID)
 	if err != nil {
 		log.Printf("[TagDescriptions] Error saving description for tag %d: %v", tagID, err)
 		respondError(w, http.StatusInternalServerError, err.Error())
@@ -764,6 +824,9 @@ func (s *Service) handleSetTagDescription(w http.ResponseWriter, r *http.Request
 }
 
 func (s *Service) handleDeleteTagDescription(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
 	vars := mux.Vars(r)
 	tagIDStr := vars["tagId"]
 	log.Printf("[TagDescriptions] DELETE /api/tag-descriptions/%s/ - Request from %s", tagIDStr, r.RemoteAddr)
@@ -775,7 +838,7 @@ func (s *Service) handleDeleteTagDescription(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	if err := s.DeleteTagDescription(tagID); err != nil {
+	if err := s.DeleteTagDescription(ctx, tagID); err != nil {
 		log.Printf("[TagDescriptions] Error deleting description for tag %d: %v", tagID, err)
 		respondError(w, http.StatusInternalServerError, err.Error())
 		return
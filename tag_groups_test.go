@@ -0,0 +1,377 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+// tagGroupAPI calls the tag group API of a service through its routes
+type tagGroupAPI struct {
+	t      *testing.T
+	s      *Service
+	router *mux.Router
+}
+
+func newTagGroupAPI(t *testing.T) *tagGroupAPI {
+	t.Helper()
+	s := newTestService(t, nil)
+	mustExec(t, s,
+		`INSERT INTO auth_user (id, username, is_superuser) VALUES (1, 'admin', 1), (2, 'alice', 0), (3, 'bob', 0)`,
+		`INSERT INTO documents_tag (id, name, color) VALUES (3, 'Tax', '#ff0000'), (7, 'Bank', '#00ff00'), (12, 'Invoice', '#0000ff'), (20, 'Unused', NULL)`,
+		`INSERT INTO documents_document (id, title, created, owner_id) VALUES
+			(1, 'tax return', '2024-01-10', NULL), (2, 'statement', '2024-02-10', 2), (3, 'invoice', '2024-02-11', 3)`,
+		`INSERT INTO documents_document_tags (document_id, tag_id) VALUES (1, 3), (2, 7), (3, 12), (1, 7)`,
+	)
+	router := mux.NewRouter()
+	registerTagGroupRoutes(router, s)
+	return &tagGroupAPI{t: t, s: s, router: router}
+}
+
+// call sends a request as userID and decodes the JSON response into out when it is not nil
+func (a *tagGroupAPI) call(method string, path string, userID string, body interface{}, out interface{}) int {
+	a.t.Helper()
+	var data []byte
+	if body != nil {
+		var err error
+		if data, err = json.Marshal(body); err != nil {
+			a.t.Fatal(err)
+		}
+	}
+	req := httptest.NewRequest(method, path, bytes.NewReader(data))
+	req.Header.Set("Content-Type", "application/json")
+	if userID != "" {
+		req.Header.Set("X-User-ID", userID)
+	}
+	rec := httptest.NewRecorder()
+	a.router.ServeHTTP(rec, req)
+	if out != nil && rec.Code < 300 {
+		if err := json.Unmarshal(rec.Body.Bytes(), out); err != nil {
+			a.t.Fatalf("%s %s: failed to decode %s: %v", method, path, rec.Body, err)
+		}
+	}
+	return rec.Code
+}
+
+// expect sends a request and fails the test unless it answers with status
+func (a *tagGroupAPI) expect(status int, method string, path string, userID string, body interface{}, out interface{}) {
+	a.t.Helper()
+	if got := a.call(method, path, userID, body, out); got != status {
+		a.t.Fatalf("%s %s as %q: status %d, want %d", method, path, userID, got, status)
+	}
+}
+
+func (a *tagGroupAPI) create(userID string, group map[string]interface{}) TagGroup {
+	a.t.Helper()
+	var created TagGroup
+	a.expect(http.StatusCreated, http.MethodPost, "/api/tag-groups/", userID, group, &created)
+	if created.ID == nil {
+		a.t.Fatalf("created group %v has no ID", group)
+	}
+	return created
+}
+
+func groupPath(id *int, suffix string) string {
+	return "/api/tag-groups/" + strconv.Itoa(*id) + "/" + suffix
+}
+
+func TestTagGroupCRUD(t *testing.T) {
+	api := newTagGroupAPI(t)
+
+	finance := api.create("2", map[string]interface{}{"name": "Finance", "description": "Money matters", "tag_ids": []int{3, 7}, "is_global": true})
+	if finance.OwnerID == nil || *finance.OwnerID != 2 || !reflect.DeepEqual(finance.TagIDs, []int{3, 7}) {
+		t.Fatalf("created %+v", finance)
+	}
+	api.expect(http.StatusBadRequest, http.MethodPost, "/api/tag-groups/", "2", map[string]interface{}{"name": ""}, nil)
+	api.expect(http.StatusConflict, http.MethodPost, "/api/tag-groups/", "3", map[string]interface{}{"name": "Finance"}, nil)
+	api.expect(http.StatusUnprocessableEntity, http.MethodPost, "/api/tag-groups/", "2", map[string]interface{}{"name": "Ghosts", "tag_ids": []int{98}}, nil)
+
+	var got TagGroup
+	api.expect(http.StatusOK, http.MethodGet, groupPath(finance.ID, ""), "3", nil, &got)
+	if got.Name != "Finance" || *got.Description != "Money matters" {
+		t.Errorf("got %+v", got)
+	}
+
+	// PUT changes the given fields and replaces the members
+	var updated TagGroup
+	api.expect(http.StatusOK, http.MethodPut, groupPath(finance.ID, ""), "2", map[string]interface{}{"name": "Money", "tag_ids": []int{3}}, &updated)
+	if updated.Name != "Money" || !reflect.DeepEqual(updated.TagIDs, []int{3}) || *updated.Description != "Money matters" {
+		t.Errorf("after PUT %+v", updated)
+	}
+
+	// PATCH is a merge patch: null clears a field
+	var patched TagGroup
+	api.expect(http.StatusOK, http.MethodPatch, groupPath(finance.ID, ""), "3", map[string]interface{}{"description": nil}, &patched)
+	if patched.Description != nil || patched.Name != "Money" {
+		t.Errorf("after PATCH %+v", patched)
+	}
+	api.expect(http.StatusForbidden, http.MethodPatch, groupPath(finance.ID, ""), "3", map[string]interface{}{"is_global": false}, nil)
+
+	var list TagGroupListResponse
+	api.expect(http.StatusOK, http.MethodGet, "/api/tag-groups/?expand=tags", "3", nil, &list)
+	if list.Count != 1 || len(list.Results[0].Tags) != 1 || list.Results[0].Tags[0].Name != "Tax" {
+		t.Errorf("list %+v", list)
+	}
+
+	// Only the owner deletes a group
+	api.expect(http.StatusForbidden, http.MethodDelete, groupPath(finance.ID, ""), "3", nil, nil)
+	api.expect(http.StatusNoContent, http.MethodDelete, groupPath(finance.ID, ""), "2", nil, nil)
+	api.expect(http.StatusNotFound, http.MethodGet, groupPath(finance.ID, ""), "2", nil, nil)
+}
+
+func TestTagGroupOwnership(t *testing.T) {
+	api := newTagGroupAPI(t)
+
+	private := api.create("2", map[string]interface{}{"name": "Alice only"})
+	api.create("3", map[string]interface{}{"name": "Shared", "is_global": true})
+
+	api.expect(http.StatusNotFound, http.MethodGet, groupPath(private.ID, ""), "3", nil, nil)
+	api.expect(http.StatusUnauthorized, http.MethodGet, "/api/tag-groups/", "", nil, nil)
+
+	var list TagGroupListResponse
+	api.expect(http.StatusOK, http.MethodGet, "/api/tag-groups/", "3", nil, &list)
+	if list.Count != 1 || list.Results[0].Name != "Shared" {
+		t.Errorf("bob sees %+v", list.Results)
+	}
+	api.expect(http.StatusOK, http.MethodGet, "/api/tag-groups/", "2", nil, &list)
+	if list.Count != 2 {
+		t.Errorf("alice sees %+v", list.Results)
+	}
+}
+
+func TestTagGroupTreeAndMembers(t *testing.T) {
+	api := newTagGroupAPI(t)
+
+	finance := api.create("2", map[string]interface{}{"name": "Finance", "tag_ids": []int{3}})
+	invoices := api.create("2", map[string]interface{}{"name": "Invoices", "tag_ids": []int{7}, "parent_group_id": *finance.ID})
+
+	// A group cannot become its own descendant
+	api.expect(http.StatusBadRequest, http.MethodPatch, groupPath(finance.ID, ""), "2", map[string]interface{}{"parent_group_id": *invoices.ID}, nil)
+
+	var tree TagGroupTreeResponse
+	api.expect(http.StatusOK, http.MethodGet, "/api/tag-groups/tree/", "2", nil, &tree)
+	if tree.Count != 1 || len(tree.Results[0].Children) != 1 || tree.Results[0].Children[0].Name != "Invoices" {
+		t.Fatalf("tree %+v", tree)
+	}
+	// Documents 1 and 2 carry a tag of Finance or of its subgroup
+	if tree.Results[0].DocumentCount != 2 {
+		t.Errorf("Finance counts %d documents, want 2", tree.Results[0].DocumentCount)
+	}
+	var subtree TagGroupNode
+	api.expect(http.StatusOK, http.MethodGet, groupPath(invoices.ID, "tree/"), "2", nil, &subtree)
+	if subtree.Name != "Invoices" || subtree.DocumentCount != 2 {
+		t.Errorf("subtree %+v", subtree)
+	}
+
+	var group TagGroup
+	api.expect(http.StatusOK, http.MethodPost, groupPath(invoices.ID, "tags/"), "2", TagGroupTagsRequest{TagIDs: []int{12, 7}}, &group)
+	if !reflect.DeepEqual(group.TagIDs, []int{7, 12}) {
+		t.Errorf("after adding tags %v", group.TagIDs)
+	}
+	api.expect(http.StatusOK, http.MethodDelete, groupPath(invoices.ID, "tags/"), "2", TagGroupTagsRequest{TagIDs: []int{7}}, &group)
+	if !reflect.DeepEqual(group.TagIDs, []int{12}) {
+		t.Errorf("after removing tags %v", group.TagIDs)
+	}
+
+	var moved TagGroupMoveResponse
+	api.expect(http.StatusOK, http.MethodPost, "/api/tag-groups/move/", "2",
+		TagGroupMoveRequest{FromGroupID: *invoices.ID, ToGroupID: *finance.ID, TagIDs: []int{12}}, &moved)
+	if len(moved.From.TagIDs) != 0 || !reflect.DeepEqual(moved.To.TagIDs, []int{3, 12}) {
+		t.Errorf("after move from %v to %v", moved.From.TagIDs, moved.To.TagIDs)
+	}
+	api.expect(http.StatusBadRequest, http.MethodPost, "/api/tag-groups/move/", "2",
+		TagGroupMoveRequest{FromGroupID: *invoices.ID, ToGroupID: *finance.ID, TagIDs: []int{3}}, nil)
+
+	var rules struct {
+		TagIDs      []int                    `json:"tag_ids"`
+		FilterRules []map[string]interface{} `json:"filter_rules"`
+	}
+	api.expect(http.StatusOK, http.MethodGet, groupPath(finance.ID, "filter-rules/"), "2", nil, &rules)
+	if !reflect.DeepEqual(rules.TagIDs, []int{3, 12}) || len(rules.FilterRules) != 2 {
+		t.Errorf("filter rules %+v", rules)
+	}
+
+	var counts TagGroupCountsResponse
+	api.expect(http.StatusOK, http.MethodPost, "/api/tag-groups/counts/", "2", map[string]interface{}{}, &counts)
+	// Counts only include the group's own tags and documents the user can see: document 3
+	// belongs to bob
+	want := map[string]int{"Finance": 1, "Invoices": 0}
+	got := map[string]int{}
+	for _, count := range counts.Results {
+		got[count.Name] = count.Count
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("counts %v, want %v", got, want)
+	}
+}
+
+func TestTagGroupUngroupedAndStaleMemberships(t *testing.T) {
+	api := newTagGroupAPI(t)
+	finance := api.create("2", map[string]interface{}{"name": "Finance", "tag_ids": []int{3, 7}})
+	mustExec(t, api.s, `DELETE FROM documents_tag WHERE id = 7`)
+
+	var ungrouped struct {
+		Count   int           `json:"count"`
+		Results []TagGroupTag `json:"results"`
+	}
+	api.expect(http.StatusOK, http.MethodGet, "/api/tag-groups/ungrouped/", "2", nil, &ungrouped)
+	if ungrouped.Count != 2 || ungrouped.Results[0].Name != "Invoice" || ungrouped.Results[1].Name != "Unused" {
+		t.Errorf("ungrouped %+v", ungrouped)
+	}
+
+	api.expect(http.StatusForbidden, http.MethodGet, "/api/tag-groups/stale-memberships/", "2", nil, nil)
+	var stale struct {
+		Count   int  `json:"count"`
+		Removed bool `json:"removed"`
+		Results []struct {
+			TagGroupID int `json:"tag_group_id"`
+			TagID      int `json:"tag_id"`
+		} `json:"results"`
+	}
+	api.expect(http.StatusOK, http.MethodGet, "/api/tag-groups/stale-memberships/", "1", nil, &stale)
+	if stale.Count != 1 || stale.Removed || stale.Results[0].TagID != 7 || stale.Results[0].TagGroupID != *finance.ID {
+		t.Errorf("stale memberships %+v", stale)
+	}
+	api.expect(http.StatusOK, http.MethodDelete, "/api/tag-groups/stale-memberships/", "1", nil, &stale)
+	if !stale.Removed {
+		t.Errorf("stale memberships not removed: %+v", stale)
+	}
+	var group TagGroup
+	api.expect(http.StatusOK, http.MethodGet, groupPath(finance.ID, ""), "2", nil, &group)
+	if !reflect.DeepEqual(group.TagIDs, []int{3}) {
+		t.Errorf("members after cleanup %v", group.TagIDs)
+	}
+}
+
+func TestTagGroupAnalytics(t *testing.T) {
+	api := newTagGroupAPI(t)
+	api.create("2", map[string]interface{}{"name": "Finance", "tag_ids": []int{3, 7}, "is_global": true})
+
+	var analytics struct {
+		Months []string `json:"months"`
+		Tags   []struct {
+			ID            int `json:"id"`
+			DocumentCount int `json:"document_count"`
+		} `json:"tags"`
+		Groups []struct {
+			Name string `json:"name"`
+		} `json:"groups"`
+		UnusedTags []struct {
+			ID int `json:"id"`
+		} `json:"unused_tags"`
+	}
+	api.expect(http.StatusOK, http.MethodGet, "/api/tag-groups/analytics/?months=6", "1", nil, &analytics)
+	if len(analytics.Months) != 6 || len(analytics.Groups) != 1 || analytics.Groups[0].Name != "Finance" {
+		t.Errorf("analytics %+v", analytics)
+	}
+	counts := map[int]int{}
+	for _, tag := range analytics.Tags {
+		counts[tag.ID] = tag.DocumentCount
+	}
+	if want := map[int]int{3: 1, 7: 2, 12: 1, 20: 0}; !reflect.DeepEqual(counts, want) {
+		t.Errorf("tag counts %v, want %v", counts, want)
+	}
+	if len(analytics.UnusedTags) != 1 || analytics.UnusedTags[0].ID != 20 {
+		t.Errorf("unused tags %+v", analytics.UnusedTags)
+	}
+	api.expect(http.StatusBadRequest, http.MethodGet, "/api/tag-groups/analytics/?months=1", "1", nil, nil)
+}
+
+func TestTagGroupExportImport(t *testing.T) {
+	api := newTagGroupAPI(t)
+	api.create("2", map[string]interface{}{"name": "Finance", "tag_ids": []int{3, 7}, "is_global": true})
+	api.expect(http.StatusOK, http.MethodPut, "/api/tag-descriptions/3/", "2", map[string]string{"description": "Anything for the tax return"}, nil)
+
+	var bundle TagGroupBundle
+	api.expect(http.StatusOK, http.MethodGet, "/api/tag-groups/export/", "2", nil, &bundle)
+	if bundle.Version != 1 || len(bundle.Groups) != 1 || !reflect.DeepEqual(bundle.Groups[0].Tags, []string{"Tax", "Bank"}) {
+		t.Fatalf("bundle %+v", bundle)
+	}
+	if len(bundle.Descriptions) != 1 || bundle.Descriptions[0].Tag != "Tax" {
+		t.Errorf("bundle descriptions %+v", bundle.Descriptions)
+	}
+
+	bundle.Groups = append(bundle.Groups, TagGroupBundleGroup{Name: "Invoices", Parent: "Finance", Tags: []string{"invoice", "Missing"}})
+	var result struct {
+		DryRun        bool     `json:"dry_run"`
+		Created       []string `json:"created"`
+		Skipped       []string `json:"skipped"`
+		UnmatchedTags []string `json:"unmatched_tags"`
+	}
+	api.expect(http.StatusOK, http.MethodPost, "/api/tag-groups/import/?dry_run=true", "3", bundle, &result)
+	if !result.DryRun || !reflect.DeepEqual(result.Created, []string{"Invoices"}) || !reflect.DeepEqual(result.Skipped, []string{"Finance"}) ||
+		!reflect.DeepEqual(result.UnmatchedTags, []string{"Missing"}) {
+		t.Errorf("dry run %+v", result)
+	}
+	var list TagGroupListResponse
+	api.expect(http.StatusOK, http.MethodGet, "/api/tag-groups/", "3", nil, &list)
+	if list.Count != 1 {
+		t.Fatalf("dry run created groups: %+v", list.Results)
+	}
+
+	api.expect(http.StatusOK, http.MethodPost, "/api/tag-groups/import/", "3", bundle, &result)
+	api.expect(http.StatusOK, http.MethodGet, "/api/tag-groups/", "3", nil, &list)
+	if list.Count != 2 {
+		t.Fatalf("after import %+v", list.Results)
+	}
+	for _, group := range list.Results {
+		if group.Name == "Invoices" && (!reflect.DeepEqual(group.TagIDs, []int{12}) || group.ParentGroupID == nil) {
+			t.Errorf("imported %+v", group)
+		}
+	}
+}
+
+func TestTagDescriptions(t *testing.T) {
+	api := newTagGroupAPI(t)
+
+	// Tags without a description get an empty one
+	var desc TagDescription
+	api.expect(http.StatusOK, http.MethodGet, "/api/tag-descriptions/3/", "2", nil, &desc)
+	if desc.TagID != 3 || desc.Description != nil {
+		t.Errorf("before set %+v", desc)
+	}
+	api.expect(http.StatusOK, http.MethodPut, "/api/tag-descriptions/3/", "2", map[string]string{"description": "Taxes"}, &desc)
+	if desc.TagID != 3 || *desc.Description != "Taxes" || desc.ModifiedBy == nil || *desc.ModifiedBy != 2 {
+		t.Errorf("set %+v", desc)
+	}
+	api.expect(http.StatusOK, http.MethodPut, "/api/tag-descriptions/3/", "3", map[string]string{"description": "Tax documents"}, &desc)
+	api.expect(http.StatusOK, http.MethodGet, "/api/tag-descriptions/3/", "2", nil, &desc)
+	if *desc.Description != "Tax documents" {
+		t.Errorf("got %+v", desc)
+	}
+
+	var many struct {
+		Count   int                       `json:"count"`
+		Results map[string]TagDescription `json:"results"`
+	}
+	api.expect(http.StatusOK, http.MethodGet, "/api/tag-descriptions/?tag_ids=3,7", "2", nil, &many)
+	if many.Count != 2 || many.Results["3"].Description == nil || many.Results["7"].Description != nil {
+		t.Errorf("descriptions %+v", many)
+	}
+
+	// The replaced version is kept as a revision, with its author
+	var history struct {
+		Count   int `json:"count"`
+		Results []struct {
+			Description *string `json:"description"`
+			ModifiedBy  *int    `json:"modified_by"`
+		} `json:"results"`
+	}
+	api.expect(http.StatusOK, http.MethodGet, "/api/tag-descriptions/3/history/", "2", nil, &history)
+	if history.Count != 1 || *history.Results[0].Description != "Taxes" || *history.Results[0].ModifiedBy != 2 {
+		t.Errorf("history %+v", history)
+	}
+
+	api.expect(http.StatusNoContent, http.MethodDelete, "/api/tag-descriptions/3/", "2", nil, nil)
+	desc = TagDescription{}
+	api.expect(http.StatusOK, http.MethodGet, "/api/tag-descriptions/3/", "2", nil, &desc)
+	if desc.Description != nil {
+		t.Errorf("after delete %+v", desc)
+	}
+}
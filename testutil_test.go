@@ -0,0 +1,79 @@
+package main
+
+import (
+	"testing"
+)
+
+// testSchema is the part of the Paperless schema the tests need, in its SQLite form
+var testSchema = []string{
+	`CREATE TABLE documents_document (id INTEGER PRIMARY KEY, title TEXT, content TEXT, modified datetime,
+		created datetime, added datetime, deleted_at datetime, owner_id INTEGER, correspondent_id INTEGER,
+		document_type_id INTEGER, storage_path_id INTEGER, archive_serial_number INTEGER, page_count INTEGER,
+		mime_type TEXT)`,
+	`CREATE TABLE documents_customfield (id INTEGER PRIMARY KEY, name TEXT, data_type TEXT, extra_data TEXT)`,
+	`CREATE TABLE documents_customfieldinstance (id INTEGER PRIMARY KEY, document_id INTEGER, field_id INTEGER,
+		deleted_at datetime, value_text TEXT, value_url TEXT, value_date TEXT, value_bool TEXT, value_int TEXT,
+		value_float TEXT, value_monetary TEXT, value_document_ids TEXT, value_select TEXT, value_long_text TEXT)`,
+	`CREATE TABLE documents_tag (id INTEGER PRIMARY KEY, name TEXT, color TEXT, is_inbox_tag BOOLEAN, owner_id INTEGER)`,
+	`CREATE TABLE documents_document_tags (id INTEGER PRIMARY KEY, document_id INTEGER, tag_id INTEGER)`,
+	`CREATE TABLE documents_storagepath (id INTEGER PRIMARY KEY, name TEXT, path TEXT, owner_id INTEGER)`,
+	`CREATE TABLE documents_correspondent (id INTEGER PRIMARY KEY, name TEXT, match TEXT, matching_algorithm INTEGER,
+		is_insensitive BOOLEAN, owner_id INTEGER)`,
+	`CREATE TABLE documents_documenttype (id INTEGER PRIMARY KEY, name TEXT, owner_id INTEGER)`,
+	`CREATE TABLE auth_user (id INTEGER PRIMARY KEY, username TEXT, first_name TEXT, last_name TEXT, email TEXT, is_superuser BOOLEAN)`,
+	`CREATE TABLE auth_user_groups (id INTEGER PRIMARY KEY, user_id INTEGER, group_id INTEGER)`,
+	`CREATE TABLE auth_permission (id INTEGER PRIMARY KEY, codename TEXT, content_type_id INTEGER)`,
+	`CREATE TABLE auth_user_user_permissions (id INTEGER PRIMARY KEY, user_id INTEGER, permission_id INTEGER)`,
+	`CREATE TABLE auth_group_permissions (id INTEGER PRIMARY KEY, group_id INTEGER, permission_id INTEGER)`,
+	`CREATE TABLE guardian_userobjectpermission (id INTEGER PRIMARY KEY, user_id INTEGER, permission_id INTEGER,
+		content_type_id INTEGER, object_pk TEXT)`,
+	`CREATE TABLE guardian_groupobjectpermission (id INTEGER PRIMARY KEY, group_id INTEGER, permission_id INTEGER,
+		content_type_id INTEGER, object_pk TEXT)`,
+	`CREATE TABLE django_content_type (id INTEGER PRIMARY KEY, app_label TEXT, model TEXT)`,
+	`INSERT INTO django_content_type (id, app_label, model) VALUES (1, 'documents', 'document')`,
+	`INSERT INTO auth_permission (id, codename, content_type_id) VALUES (1, 'view_document', 1), (2, 'change_document', 1)`,
+}
+
+// newTestService creates a service on a fresh SQLite database with the Paperless tables
+// of testSchema; mutate adjusts the configuration before the service starts
+func newTestService(t *testing.T, mutate func(*Config)) *Service {
+	t.Helper()
+	config := loadConfig()
+	config.DBEngine = "sqlite"
+	config.DBPath = t.TempDir() + "/paperless.db"
+	config.IndexAdvisor = "off"
+	if mutate != nil {
+		mutate(config)
+	}
+
+	db, err := connectDB(config)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	for _, statement := range testSchema {
+		if _, err := db.Exec(statement); err != nil {
+			t.Fatalf("failed to create schema: %v", err)
+		}
+	}
+	db.Close()
+
+	service, err := NewService(config)
+	if err != nil {
+		t.Fatalf("failed to create service: %v", err)
+	}
+	t.Cleanup(func() {
+		service.statements.Close()
+		service.db.Close()
+	})
+	return service
+}
+
+// mustExec runs statements against the service database
+func mustExec(t *testing.T, s *Service, statements ...string) {
+	t.Helper()
+	for _, statement := range statements {
+		if _, err := s.db.Exec(statement); err != nil {
+			t.Fatalf("failed to run %q: %v", statement, err)
+		}
+	}
+}
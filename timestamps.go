@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// dbTimestampLayouts are the text forms of timestamps returned by the drivers, tried in order.
+// Values without a zone are taken as UTC.
+var dbTimestampLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999-07",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999",
+}
+
+// dbTimestamp scans a timestamp column whichever way the driver returns it: as a time.Time
+// (PostgreSQL, MySQL with parseTime, SQLite DATETIME columns) or as text
+type dbTimestamp struct {
+	Time  time.Time
+	Raw   string // The value as it scans into a string, kept for LEGACY_TIMESTAMPS
+	Valid bool
+}
+
+// Scan implements sql.Scanner
+func (t *dbTimestamp) Scan(value interface{}) error {
+	*t = dbTimestamp{}
+	switch typed := value.(type) {
+	case nil:
+		return nil
+	case time.Time:
+		t.Time, t.Raw, t.Valid = typed, typed.Format(time.RFC3339Nano), true
+	case []byte:
+		t.parse(string(typed))
+	case string:
+		t.parse(typed)
+	default:
+		return fmt.Errorf("unsupported timestamp type %T", value)
+	}
+	return nil
+}
+
+// parse reads a text timestamp. Text that matches no layout keeps only Raw.
+func (t *dbTimestamp) parse(text string) {
+	t.Raw, t.Valid = text, true
+	for _, layout := range dbTimestampLayouts {
+		if parsed, err := time.Parse(layout, strings.TrimSpace(text)); err == nil {
+			t.Time = parsed
+			return
+		}
+	}
+}
+
+// formatTimestamp formats a scanned timestamp for responses: RFC3339 in UTC, or as the
+// driver returned it when LEGACY_TIMESTAMPS is set
+func (s *Service) formatTimestamp(t dbTimestamp) *string {
+	if !t.Valid {
+		return nil
+	}
+	formatted := t.Raw
+	if !s.config.LegacyTimestamps && !t.Time.IsZero() {
+		formatted = t.Time.UTC().Format(time.RFC3339)
+	}
+	return &formatted
+}
@@ -1,10 +1,11 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
-	"strconv"
 	"strings"
 )
 
@@ -23,20 +24,26 @@ func respondError(w http.ResponseWriter, status int, message string) {
 	})
 }
 
-// getUserIDFromRequest extracts user ID from request headers
-// In production, this should validate JWT tokens or session cookies
+// queryErrorStatus maps a failed query to an HTTP status: queries stopped by the
+// request timeout yield 504, invalid filter rules 400, everything else 500
+func queryErrorStatus(err error) int {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return http.StatusGatewayTimeout
+	}
+	if errors.Is(err, errInvalidFilterRules) {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+// getUserIDFromRequest returns the user of a request from the X-User-ID header, like
+// viewerFromRequest; requests without a valid header are rejected, never served as a
+// default user
 func getUserIDFromRequest(r *http.Request) (*int, error) {
-	// For now, extract from X-User-ID header if present
-	// In production, implement proper authentication
-	userIDStr := r.Header.Get("X-User-ID")
-	if userIDStr != "" {
-		userID, err := strconv.Atoi(userIDStr)
-		if err == nil {
-			return &userID, nil
-		}
+	userID, err := viewerFromRequest(r)
+	if err != nil {
+		return nil, err
 	}
-	// Default to user ID 1 for development (you may want to return error in production)
-	userID := 1
 	return &userID, nil
 }
 
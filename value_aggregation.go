@@ -0,0 +1,223 @@
+package main
+
+import (
+	"encoding/binary"
+	"log"
+
+	"github.com/RoaringBitmap/roaring"
+	"github.com/axiomhq/hyperloglog"
+)
+
+// Value lists and counts are aggregated by collecting the distinct documents of every value
+// in Roaring bitmaps. With VALUE_COUNT_MODE=approximate a set that outgrows a HyperLogLog
+// sketch is replaced by one, which bounds the memory of every value at the cost of a ~1.6%
+// count error. The values and their documents are bounded by AGGREGATION_MEMORY_MB: values
+// first seen after the budget is used up, and values whose documents outgrow it, are counted
+// together as "(Other values)" instead of failing the request. Nothing is spilled to disk,
+// so the values beyond the budget are not listed on their own.
+
+const (
+	valueEntryBytes = 96 // Map entry, string and set headers of one value
+)
+
+const (
+	sketchPrecision  = 12                   // log2 of the registers of a sketch
+	sketchRegisters  = 1 << sketchPrecision // Registers, one byte each once the sketch is dense
+	sketchThreshold  = sketchRegisters / 2  // Exact documents of a value before it is sketched
+	approximateCount = "approximate"        // VALUE_COUNT_MODE of sketched counts
+)
+
+// otherValuesID is the option counting the documents of the values beyond the memory budget
+const (
+	otherValuesID    = "__other__"
+	otherValuesLabel = "(Other values)"
+)
+
+// newDocumentSketch returns an empty HyperLogLog sketch of document IDs
+func newDocumentSketch() *hyperloglog.Sketch {
+	sketch, err := hyperloglog.NewSketch(sketchPrecision, true)
+	if err != nil {
+		panic(err) // The precision is a valid constant
+	}
+	return sketch
+}
+
+// sketchDocument adds a document ID to a sketch
+func sketchDocument(sketch *hyperloglog.Sketch, documentID uint32) {
+	var key [4]byte
+	binary.LittleEndian.PutUint32(key[:], documentID)
+	sketch.Insert(key[:])
+}
+
+// documentSet is the set of documents of one value: exact, or a sketch once it was sketched
+type documentSet struct {
+	exact  *roaring.Bitmap
+	sketch *hyperloglog.Sketch
+}
+
+// newDocumentSet returns an empty exact set
+func newDocumentSet() *documentSet {
+	return &documentSet{exact: roaring.New()}
+}
+
+// toSketch replaces the exact documents of the set by a sketch
+func (d *documentSet) toSketch() {
+	d.sketch = newDocumentSketch()
+	d.exact.Iterate(func(documentID uint32) bool {
+		sketchDocument(d.sketch, documentID)
+		return true
+	})
+	d.exact = nil
+}
+
+// add inserts a document ID and returns by how many bytes the set grew; approximate sets
+// are sketched once their exact form outgrows sketchThreshold documents
+func (d *documentSet) add(documentID int, approximate bool) int {
+	if d.sketch != nil {
+		sketchDocument(d.sketch, uint32(documentID))
+		return 0
+	}
+	before := d.size()
+	if !d.exact.CheckedAdd(uint32(documentID)) {
+		return 0
+	}
+	if approximate && d.exact.GetCardinality() > sketchThreshold {
+		d.toSketch()
+	}
+	return d.size() - before
+}
+
+// merge adds the documents of another set and returns by how many bytes the set grew; a
+// sketched set sketches this one
+func (d *documentSet) merge(from *documentSet, approximate bool) int {
+	before := d.size()
+	if from.sketch == nil {
+		if d.sketch != nil {
+			from.exact.Iterate(func(documentID uint32) bool {
+				sketchDocument(d.sketch, documentID)
+				return true
+			})
+			return 0
+		}
+		d.exact.Or(from.exact)
+		if approximate && d.exact.GetCardinality() > sketchThreshold {
+			d.toSketch()
+		}
+		return d.size() - before
+	}
+	if d.sketch == nil {
+		d.toSketch()
+	}
+	if err := d.sketch.Merge(from.sketch); err != nil {
+		log.Printf("[Aggregation] Failed to merge sketches: %v", err)
+	}
+	return d.size() - before
+}
+
+// size returns the approximate memory use of the set in bytes; sketches are charged their
+// dense size
+func (d *documentSet) size() int {
+	if d.sketch != nil {
+		return sketchRegisters
+	}
+	return int(d.exact.GetSizeInBytes())
+}
+
+func (d *documentSet) count() int {
+	if d.sketch != nil {
+		return int(d.sketch.Estimate())
+	}
+	return int(d.exact.GetCardinality())
+}
+
+// valueAggregator counts the distinct documents of the values of a custom field within the
+// aggregation memory budget
+type valueAggregator struct {
+	approximate bool
+	budget      int // Bytes, 0 for no limit
+	used        int
+	full        bool // Whether the budget was used up; new values are then other values
+	values      map[string]*documentSet
+	other       *documentSet // Documents of the values beyond the budget
+	dropped     int          // Documents of values counted as other values
+}
+
+// newValueAggregator creates an aggregator with the configured count mode and memory budget
+func (s *Service) newValueAggregator() *valueAggregator {
+	return &valueAggregator{
+		approximate: s.config.ValueCountMode == approximateCount,
+		budget:      s.config.AggregationMemoryMB << 20,
+		values:      make(map[string]*documentSet),
+	}
+}
+
+// add records that a document has a value. Every insert is charged to the budget: values
+// first seen once it is used up are counted as other values, and so is a value whose
+// documents grow beyond it, together with the documents it already had.
+func (a *valueAggregator) add(value string, documentID int) {
+	set, ok := a.values[value]
+	if !ok {
+		cost := valueEntryBytes + len(value)
+		if a.budget > 0 && (a.full || a.used+cost > a.budget) {
+			a.full = true
+			a.used += a.otherValues().add(documentID, a.approximate)
+			a.dropped++
+			return
+		}
+		set = newDocumentSet()
+		a.values[value] = set
+		a.used += cost + set.size()
+	}
+	a.used += set.add(documentID, a.approximate)
+	if a.budget > 0 && a.used > a.budget {
+		// No new values are listed from now on, so the value does not come back with
+		// only its later documents
+		a.full = true
+		delete(a.values, value)
+		a.used -= valueEntryBytes + len(value) + set.size()
+		a.used += a.otherValues().merge(set, a.approximate)
+		a.dropped += set.count()
+	}
+}
+
+// otherValues returns the documents of the values beyond the budget
+func (a *valueAggregator) otherValues() *documentSet {
+	if a.other == nil {
+		a.other = newDocumentSet()
+	}
+	return a.other
+}
+
+// options converts the aggregated values to options; SELECT option IDs are labeled with
+// selectOptionMap, other values use the value as label
+func (a *valueAggregator) options(fieldID int, dataType string, selectOptionMap map[string]string) []CustomFieldValueOption {
+	values := make([]CustomFieldValueOption, 0, len(a.values)+1)
+	for value, documents := range a.values {
+		option := CustomFieldValueOption{
+			ID:          generateID(value),
+			Label:       value,
+			Count:       documents.count(), // Count of unique documents containing this value
+			Approximate: documents.sketch != nil,
+		}
+		if dataType == "select" {
+			// For SELECT fields the value is the option ID
+			option.ID = value
+			if label, exists := selectOptionMap[value]; exists {
+				option.Label = label
+			}
+		}
+		values = append(values, option)
+	}
+
+	if a.other != nil {
+		log.Printf("[Aggregation] Field %d: memory budget of %d MB reached after %d values, %d documents of values counted as %s",
+			fieldID, a.budget>>20, len(a.values), a.dropped, otherValuesLabel)
+		values = append(values, CustomFieldValueOption{
+			ID:          otherValuesID,
+			Label:       otherValuesLabel,
+			Count:       a.other.count(),
+			Approximate: a.other.sketch != nil,
+		})
+	}
+	return values
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// randomDocumentIDs returns n document IDs below max with duplicates, from a fixed seed
+func randomDocumentIDs(seed int64, n int, max int) []int {
+	random := rand.New(rand.NewSource(seed))
+	ids := make([]int, n)
+	for i := range ids {
+		ids[i] = random.Intn(max)
+	}
+	return ids
+}
+
+// distinctSorted returns the distinct IDs in ascending order
+func distinctSorted(ids []int) []int {
+	seen := map[int]bool{}
+	distinct := []int{}
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			distinct = append(distinct, id)
+		}
+	}
+	sort.Ints(distinct)
+	return distinct
+}
+
+func TestDocumentSetMatchesExactSet(t *testing.T) {
+	tests := []struct {
+		name string
+		ids  []int
+	}{
+		{"empty", nil},
+		{"sparse over containers", randomDocumentIDs(1, 3000, 1<<24)},
+		// Enough IDs in the first containers to switch them from arrays to bitmaps
+		{"dense", randomDocumentIDs(2, 30000, 3<<16)},
+	}
+	for _, tt := range tests {
+		set := newDocumentSet()
+		grown := 0
+		for _, id := range tt.ids {
+			grown += set.add(id, false)
+		}
+		want := distinctSorted(tt.ids)
+		got := []int{}
+		set.exact.Iterate(func(documentID uint32) bool {
+			got = append(got, int(documentID))
+			return true
+		})
+
+		if !reflect.DeepEqual(got, want) || set.count() != len(want) {
+			t.Errorf("%s: set holds %d IDs (count %d), want %d", tt.name, len(got), set.count(), len(want))
+		}
+		if empty := newDocumentSet().size(); set.size() != empty+grown {
+			t.Errorf("%s: size %d, grown %d from %d", tt.name, set.size(), grown, empty)
+		}
+
+		// Merging into a set holding another ID gives the union
+		merged := newDocumentSet()
+		merged.add(1, false)
+		merged.merge(set, false)
+		if union := len(distinctSorted(append(tt.ids, 1))); merged.count() != union {
+			t.Errorf("%s: merged count %d, want %d", tt.name, merged.count(), union)
+		}
+	}
+}
+
+func TestDocumentSetSketchError(t *testing.T) {
+	for _, n := range []int{5000, 20000, 100000, 1000000} {
+		set := newDocumentSet()
+		for id := 1; id <= n; id++ {
+			set.add(id, true)
+			set.add(id, true) // Duplicates do not count
+		}
+		if set.sketch == nil {
+			t.Fatalf("%d documents: set not sketched", n)
+		}
+		// About three standard errors of 1.04/sqrt(registers)
+		if err := math.Abs(float64(set.count()-n)) / float64(n); err > 0.05 {
+			t.Errorf("%d documents: estimated %d (%.1f%% error)", n, set.count(), 100*err)
+		}
+	}
+}
+
+func TestValueAggregatorCountsExactly(t *testing.T) {
+	a := &valueAggregator{values: map[string]*documentSet{}}
+	want := map[string]map[int]bool{}
+	random := rand.New(rand.NewSource(3))
+	for i := 0; i < 50000; i++ {
+		value := fmt.Sprintf("value %d", random.Intn(40))
+		documentID := random.Intn(100000)
+		a.add(value, documentID)
+		if want[value] == nil {
+			want[value] = map[int]bool{}
+		}
+		want[value][documentID] = true
+	}
+
+	options := a.options(1, "string", nil)
+	if len(options) != len(want) {
+		t.Fatalf("%d options, want %d", len(options), len(want))
+	}
+	for _, option := range options {
+		if option.Count != len(want[option.Label]) || option.Approximate {
+			t.Errorf("%s: count %d (approximate %v), want %d", option.Label, option.Count, option.Approximate, len(want[option.Label]))
+		}
+	}
+}
+
+func TestValueAggregatorSketchesLargeValues(t *testing.T) {
+	a := &valueAggregator{approximate: true, values: map[string]*documentSet{}}
+	for id := 0; id < 50000; id++ {
+		a.add("large", id)
+		if id%10 == 0 {
+			a.add("small", id)
+		}
+	}
+	a.add("single", 7)
+
+	counts := map[string]CustomFieldValueOption{}
+	for _, option := range a.options(1, "string", nil) {
+		counts[option.Label] = option
+	}
+	if large := counts["large"]; !large.Approximate || math.Abs(float64(large.Count-50000))/50000 > 0.05 {
+		t.Errorf("large value: %+v, want about 50000 approximate", large)
+	}
+	if small := counts["small"]; !small.Approximate || math.Abs(float64(small.Count-5000))/5000 > 0.05 {
+		t.Errorf("small value: %+v, want about 5000 approximate", small)
+	}
+	if single := counts["single"]; single.Approximate || single.Count != 1 {
+		t.Errorf("single value: %+v, want exactly 1", single)
+	}
+	// Sketched values stop growing
+	if a.used > 3*(valueEntryBytes+sketchRegisters) {
+		t.Errorf("used %d bytes for three values", a.used)
+	}
+}
+
+func TestValueAggregatorChargesEveryInsert(t *testing.T) {
+	const budget = 4 << 10
+	a := &valueAggregator{budget: budget, values: map[string]*documentSet{}}
+	documents := map[int]bool{}
+	for id := 0; id < 20000; id++ {
+		// One value growing beyond the budget and a few small ones
+		a.add("large", id)
+		documents[id] = true
+		if id%1000 == 0 {
+			a.add(fmt.Sprintf("small %d", id), id)
+		}
+		// Growing a container may take one 8 KB bitmap container past the budget
+		if a.used > budget+8192 {
+			t.Fatalf("used %d bytes after document %d, budget %d", a.used, id, budget)
+		}
+	}
+
+	counts := map[string]int{}
+	for _, option := range a.options(1, "string", nil) {
+		counts[option.ID] = option.Count
+	}
+	if _, listed := counts[generateID("large")]; listed {
+		t.Error("value beyond the budget is still listed")
+	}
+	if counts[otherValuesID] != len(documents) {
+		t.Errorf("other values count %d documents, want %d", counts[otherValuesID], len(documents))
+	}
+	if a.dropped < len(documents) {
+		t.Errorf("dropped %d documents, want at least %d", a.dropped, len(documents))
+	}
+}
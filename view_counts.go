@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// viewCount is a cached document count of a view
+type viewCount struct {
+	Count     int       `json:"count"`
+	CountedAt time.Time `json:"counted_at"`
+}
+
+// addViewDocumentCounts sets the document count of each view, counting the documents of its
+// filter rules the viewer can see. Counts are cached with the builtin filter values, so they
+// expire and are invalidated on document changes the same way; CountedAt tells how fresh a
+// count is.
+func (s *Service) addViewDocumentCounts(ctx context.Context, views []CustomView, viewerID int, bypass bool) error {
+	for i := range views {
+		view := &views[i]
+		rulesJSON, err := json.Marshal(view.FilterRules)
+		if err != nil {
+			return fmt.Errorf("failed to encode filter rules: %w", err)
+		}
+		key := fmt.Sprintf("view_count|%d|%s", viewerID, rulesJSON)
+
+		var cached viewCount
+		if bypass || !s.builtinCache.Get(key, &cached) {
+			where, args, err := s.viewDocumentCondition(ctx, view, viewerID)
+			if err != nil {
+				return err
+			}
+			count, err := s.countViewDocuments(ctx, where, args)
+			if err != nil {
+				return err
+			}
+			cached = viewCount{Count: count, CountedAt: time.Now()}
+			s.builtinCache.Set(key, cached)
+		}
+
+		countedAt := cached.CountedAt.UTC().Format(time.RFC3339)
+		count := cached.Count
+		view.DocumentCount = &count
+		view.CountedAt = &countedAt
+	}
+	return nil
+}
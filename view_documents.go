@@ -0,0 +1,366 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// viewDocumentColumns are the builtin view columns returned by the view documents endpoint,
+// with their expressions on the documents table. Tags and custom fields are read separately.
+var viewDocumentColumns = map[string]string{
+	"title":                 "d.title",
+	"created":               "d.created",
+	"added":                 "d.added",
+	"modified":              "d.modified",
+	"correspondent":         "d.correspondent_id",
+	"document_type":         "d.document_type_id",
+	"storage_path":          "d.storage_path_id",
+	"owner":                 "d.owner_id",
+	"asn":                   "d.archive_serial_number",
+	"archive_serial_number": "d.archive_serial_number",
+	"page_count":            "d.page_count",
+	"mime_type":             "d.mime_type",
+	"filename":              "d.filename",
+	"original_filename":     "d.original_filename",
+	"checksum":              "d.checksum",
+}
+
+// viewDocumentOrdering is the SQL of a view sort field and the join it needs, if any
+type viewDocumentOrdering struct {
+	expression string
+	join       string
+}
+
+// viewDocumentOrderings maps the Paperless sort fields stored with views to SQL
+var viewDocumentOrderings = map[string]viewDocumentOrdering{
+	"title":                 {expression: "LOWER(d.title)"},
+	"created":               {expression: "d.created"},
+	"added":                 {expression: "d.added"},
+	"modified":              {expression: "d.modified"},
+	"archive_serial_number": {expression: "d.archive_serial_number"},
+	"page_count":            {expression: "d.page_count"},
+	"mime_type":             {expression: "d.mime_type"},
+	"correspondent__name":   {expression: "LOWER(c.name)", join: "LEFT JOIN documents_correspondent c ON c.id = d.correspondent_id"},
+	"document_type__name":   {expression: "LOWER(dt.name)", join: "LEFT JOIN documents_documenttype dt ON dt.id = d.document_type_id"},
+	"storage_path__name":    {expression: "LOWER(sp.name)", join: "LEFT JOIN documents_storagepath sp ON sp.id = d.storage_path_id"},
+	"owner":                 {expression: "LOWER(u.username)", join: "LEFT JOIN auth_user u ON u.id = d.owner_id"},
+}
+
+// ViewDocumentsResponse is a page of the documents matching a view
+type ViewDocumentsResponse struct {
+	Count    int                      `json:"count"`
+	Next     *string                  `json:"next,omitempty"`
+	Previous *string                  `json:"previous,omitempty"`
+	Columns  []string                 `json:"columns"` // Keys of the column values in each result
+	Results  []map[string]interface{} `json:"results"`
+}
+
+// viewDocumentValue turns a scanned column value into its JSON form
+func viewDocumentValue(column string, raw interface{}) interface{} {
+	switch v := raw.(type) {
+	case []byte:
+		return string(v)
+	case time.Time:
+		if column == "created" {
+			return v.Format("2006-01-02")
+		}
+		return v.UTC().Format(time.RFC3339)
+	}
+	return raw
+}
+
+// viewColumns splits the visible columns of a view, in column order, into builtin columns
+// with an SQL expression, whether tags are shown and custom field IDs
+func viewColumns(view *CustomView) (builtin []string, tags bool, fieldIDs []int) {
+	for _, entry := range view.ColumnOrder {
+		var key string
+		switch typed := entry.(type) {
+		case string:
+			key = typed
+		case float64:
+			key = strconv.Itoa(int(typed))
+		default:
+			continue
+		}
+		if visible, ok := view.ColumnVisibility[key]; ok && !visible {
+			continue
+		}
+
+		if fieldID, err := strconv.Atoi(strings.TrimPrefix(key, "custom_field_")); err == nil {
+			fieldIDs = append(fieldIDs, fieldID)
+		} else if key == "tags" {
+			tags = true
+		} else if _, ok := viewDocumentColumns[key]; ok {
+			builtin = append(builtin, key)
+		}
+	}
+	return builtin, tags, fieldIDs
+}
+
+// viewDocumentCondition returns the condition on d selecting the documents of a view:
+// those matching its filter rules, not in the trash and visible to the viewer
+func (s *Service) viewDocumentCondition(ctx context.Context, view *CustomView, viewerID int) (string, []interface{}, error) {
+	filterRulesJSON := ""
+	if len(view.FilterRules) > 0 {
+		data, err := json.Marshal(view.FilterRules)
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to encode filter rules: %w", err)
+		}
+		filterRulesJSON = string(data)
+	}
+	docFilterWhere, args, err := s.buildDocumentFilterQuery(ctx, filterRulesJSON, 0, 0)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to build filter query: %w", err)
+	}
+
+	conditions := []string{"d.deleted_at IS NULL"}
+	if docFilterWhere != "" {
+		conditions = append(conditions, "("+strings.Replace(docFilterWhere, "WHERE ", "", 1)+")")
+	}
+	visibility, err := s.documentVisibilityCondition(ctx, viewerID)
+	if err != nil {
+		return "", nil, err
+	}
+	if visibility != "" {
+		conditions = append(conditions, visibility)
+	}
+	return strings.Join(conditions, " AND "), args, nil
+}
+
+// countViewDocuments counts the documents of a view
+func (s *Service) countViewDocuments(ctx context.Context, where string, args []interface{}) (int, error) {
+	var count int
+	if err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM documents_document d WHERE "+where, args...).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count view documents: %w", err)
+	}
+	return count, nil
+}
+
+// GetViewDocuments runs a view: it returns one page of the documents matching the view's
+// filter rules in the view's sort order, with the values of its visible columns. Documents
+// in the trash and documents the viewer cannot see are left out.
+func (s *Service) GetViewDocuments(ctx context.Context, view *CustomView, viewerID int, p Pagination) (*ViewDocumentsResponse, error) {
+	where, args, err := s.viewDocumentCondition(ctx, view, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	count, err := s.countViewDocuments(ctx, where, args)
+	if err != nil {
+		return nil, err
+	}
+
+	// Paperless sorts by created date, newest first, unless the view says otherwise
+	sortField := "created"
+	if view.SortField != nil && *view.SortField != "" {
+		sortField = *view.SortField
+	}
+	direction := "DESC"
+	if view.SortReverse != nil && !*view.SortReverse {
+		direction = "ASC"
+	}
+	ordering, ok := viewDocumentOrderings[sortField]
+	if !ok {
+		ordering = viewDocumentOrderings["created"]
+	}
+
+	builtin, withTags, fieldIDs := viewColumns(view)
+	selectColumns := []string{"d.id"}
+	for _, column := range builtin {
+		selectColumns = append(selectColumns, viewDocumentColumns[column])
+	}
+
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM documents_document d
+		%s
+		WHERE %s
+		ORDER BY %s %s, d.id %s
+		%s
+	`, strings.Join(selectColumns, ", "), ordering.join, where, ordering.expression, direction, direction, p.limitClause())
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query view documents: %w", err)
+	}
+	defer rows.Close()
+
+	response := &ViewDocumentsResponse{Count: count, Columns: builtin, Results: []map[string]interface{}{}}
+	documents := make(map[int]map[string]interface{})
+	ids := []string{}
+	for rows.Next() {
+		var id int
+		values := make([]interface{}, len(builtin))
+		dest := []interface{}{&id}
+		for i := range values {
+			dest = append(dest, &values[i])
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, fmt.Errorf("failed to scan view document: %w", err)
+		}
+
+		document := map[string]interface{}{"id": id}
+		for i, column := range builtin {
+			document[column] = viewDocumentValue(column, values[i])
+		}
+		response.Results = append(response.Results, document)
+		documents[id] = document
+		ids = append(ids, strconv.Itoa(id))
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read view documents: %w", err)
+	}
+
+	if withTags {
+		response.Columns = append(response.Columns, "tags")
+		for _, document := range documents {
+			document["tags"] = []int{}
+		}
+		if err := s.loadViewDocumentTags(ctx, ids, documents); err != nil {
+			return nil, err
+		}
+	}
+	for _, fieldID := range fieldIDs {
+		key := fmt.Sprintf("custom_field_%d", fieldID)
+		response.Columns = append(response.Columns, key)
+		for _, document := range documents {
+			document[key] = nil
+		}
+		if err := s.loadViewDocumentField(ctx, fieldID, key, ids, documents); err != nil {
+			return nil, err
+		}
+	}
+
+	return response, nil
+}
+
+// loadViewDocumentTags adds the tag IDs of the documents of a page
+func (s *Service) loadViewDocumentTags(ctx context.Context, ids []string, documents map[int]map[string]interface{}) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	// The IDs are integers, so they are safe to inline
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf(
+		"SELECT document_id, tag_id FROM documents_document_tags WHERE document_id IN (%s) ORDER BY tag_id", strings.Join(ids, ", ")))
+	if err != nil {
+		return fmt.Errorf("failed to query document tags: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var documentID, tagID int
+		if err := rows.Scan(&documentID, &tagID); err != nil {
+			return fmt.Errorf("failed to scan document tag: %w", err)
+		}
+		if document, ok := documents[documentID]; ok {
+			document["tags"] = append(document["tags"].([]int), tagID)
+		}
+	}
+	return rows.Err()
+}
+
+// loadViewDocumentField adds the values of a custom field to the documents of a page
+func (s *Service) loadViewDocumentField(ctx context.Context, fieldID int, key string, ids []string, documents map[int]map[string]interface{}) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	var dataType string
+	if err := s.db.QueryRowContext(ctx, s.rebind("SELECT data_type FROM documents_customfield WHERE id = ?"), fieldID).Scan(&dataType); err != nil {
+		// Columns of deleted custom fields stay empty
+		return nil
+	}
+	valueColumn := getValueColumnName(dataType)
+
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf(`
+		SELECT document_id, %s
+		FROM documents_customfieldinstance
+		WHERE field_id = %d AND deleted_at IS NULL AND document_id IN (%s)
+	`, valueColumn, fieldID, strings.Join(ids, ", ")))
+	if err != nil {
+		return fmt.Errorf("failed to query custom field %d values: %w", fieldID, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var documentID int
+		var value interface{}
+		if err := rows.Scan(&documentID, &value); err != nil {
+			return fmt.Errorf("failed to scan custom field %d value: %w", fieldID, err)
+		}
+		document, ok := documents[documentID]
+		if !ok {
+			continue
+		}
+		value = viewDocumentValue(key, value)
+		if number, isNumber := value.(int64); isNumber && dataType == "boolean" {
+			value = number != 0
+		}
+		if date, isDate := value.(string); isDate && dataType == "date" && len(date) >= 10 {
+			value = date[:10]
+		}
+		document[key] = value
+	}
+	return rows.Err()
+}
+
+// HTTP Handler for the documents of a view
+func (s *Service) handleGetViewDocuments(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	idStr := mux.Vars(r)["id"]
+	log.Printf("[CustomViews] GET /api/custom_views/%s/documents/ - Request from %s", idStr, r.RemoteAddr)
+
+	viewerID, err := viewerFromRequest(r)
+	if err != nil {
+		respondError(w, viewerErrorStatus(err), err.Error())
+		return
+	}
+
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid view ID")
+		return
+	}
+	pagination, err := parsePagination(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	// Documents are always paginated
+	if pagination.PageSize == 0 {
+		pagination.PageSize = defaultPageSize
+	}
+	userID, err := getUserIDFromRequest(r)
+	if err != nil {
+		respondError(w, viewerErrorStatus(err), err.Error())
+		return
+	}
+
+	view, err := s.accessibleCustomView(ctx, id, *userID)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			respondError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		respondError(w, queryErrorStatus(err), err.Error())
+		return
+	}
+
+	response, err := s.GetViewDocuments(ctx, view, viewerID, pagination)
+	if err != nil {
+		log.Printf("[CustomViews] Error running view %d: %v", id, err)
+		respondError(w, queryErrorStatus(err), err.Error())
+		return
+	}
+	response.Next, response.Previous = pageLinks(r, pagination, response.Count)
+
+	respondJSON(w, http.StatusOK, response)
+}
@@ -0,0 +1,374 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// customFieldKeyID returns the custom field ID of a column or filter key given as "12"
+// or "custom_field_12"
+func customFieldKeyID(key string) (int, bool) {
+	id, err := strconv.Atoi(strings.TrimPrefix(key, "custom_field_"))
+	return id, err == nil
+}
+
+// customFieldQueryIDs returns the custom field IDs of a custom field query: the
+// [fieldId, "operator", value] atoms nested in ["AND" | "OR", [queries]] and ["NOT", query]
+func customFieldQueryIDs(query interface{}) []int {
+	parts, ok := query.([]interface{})
+	if !ok || len(parts) == 0 {
+		return nil
+	}
+	if operator, ok := parts[0].(string); ok && len(parts) > 1 {
+		var ids []int
+		switch operator {
+		case "AND", "OR":
+			subQueries, _ := parts[1].([]interface{})
+			for _, subQuery := range subQueries {
+				ids = append(ids, customFieldQueryIDs(subQuery)...)
+			}
+		case "NOT":
+			ids = customFieldQueryIDs(parts[1])
+		}
+		return ids
+	}
+	if id, ok := parts[0].(float64); ok && len(parts) >= 3 {
+		return []int{int(id)}
+	}
+	return nil
+}
+
+// filterRuleFieldIDs returns the custom field IDs a filter rule refers to
+func filterRuleFieldIDs(rule map[string]interface{}) []int {
+	ruleType, _ := rule["rule_type"].(float64)
+	switch int(ruleType) {
+	case FILTER_HAS_CUSTOM_FIELDS_ALL, FILTER_HAS_CUSTOM_FIELDS_ANY, FILTER_DOES_NOT_HAVE_CUSTOM_FIELDS:
+		switch value := rule["value"].(type) {
+		case float64:
+			return []int{int(value)}
+		case string:
+			if id, err := strconv.Atoi(strings.TrimSpace(value)); err == nil {
+				return []int{id}
+			}
+		}
+	case FILTER_CUSTOM_FIELDS_QUERY:
+		value, _ := rule["value"].(string)
+		var query interface{}
+		if err := json.Unmarshal([]byte(value), &query); err == nil {
+			return customFieldQueryIDs(query)
+		}
+	}
+	return nil
+}
+
+// danglingFieldRefs lists the references of a view to custom fields missing from fieldIDs
+func danglingFieldRefs(view *CustomView, fieldIDs map[int]bool) []DanglingFieldRef {
+	var refs []DanglingFieldRef
+	check := func(field string, id int) {
+		if !fieldIDs[id] {
+			refs = append(refs, DanglingFieldRef{Field: field, FieldID: id})
+		}
+	}
+
+	for i, entry := range view.ColumnOrder {
+		field := fmt.Sprintf("column_order[%d]", i)
+		switch typed := entry.(type) {
+		case string:
+			if id, ok := customFieldKeyID(typed); ok {
+				check(field, id)
+			}
+		case float64:
+			check(field, int(typed))
+		}
+	}
+
+	keyed := []struct {
+		name string
+		keys []string
+	}{
+		{"column_sizing", columnMapKeys(view.ColumnSizing)},
+		{"column_visibility", columnMapKeys(view.ColumnVisibility)},
+		{"column_display_types", columnMapKeys(view.ColumnDisplayTypes)},
+		{"column_styles", columnMapKeys(view.ColumnStyles)},
+		{"column_spanning", columnMapKeys(view.ColumnSpanning)},
+		{"filter_visibility", columnMapKeys(view.FilterVisibility)},
+		{"filter_types", columnMapKeys(view.FilterTypes)},
+		{"edit_mode_settings", columnMapKeys(view.EditModeSettings)},
+	}
+	for _, m := range keyed {
+		for _, key := range m.keys {
+			if id, ok := customFieldKeyID(key); ok {
+				check(m.name+"."+key, id)
+			}
+		}
+	}
+
+	for i, rule := range view.FilterRules {
+		for _, id := range filterRuleFieldIDs(rule) {
+			check(fmt.Sprintf("filter_rules[%d]", i), id)
+		}
+	}
+
+	if board := view.BoardSettings; board != nil {
+		if id, ok := customFieldKeyID(board.GroupBy); ok {
+			check("board_settings.group_by", id)
+		}
+		for i, column := range board.CardFields {
+			if id, ok := customFieldKeyID(column); ok {
+				check(fmt.Sprintf("board_settings.card_fields[%d]", i), id)
+			}
+		}
+	}
+	return refs
+}
+
+// danglingFieldIDs returns the distinct field IDs of refs in ascending order
+func danglingFieldIDs(refs []DanglingFieldRef) []int {
+	seen := make(map[int]bool)
+	var ids []int
+	for _, ref := range refs {
+		if !seen[ref.FieldID] {
+			seen[ref.FieldID] = true
+			ids = append(ids, ref.FieldID)
+		}
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+// withoutFieldKeys returns a copy of a map keyed by column without the keys of the given
+// custom fields
+func withoutFieldKeys[T any](values map[string]T, fieldIDs map[int]bool) map[string]T {
+	if values == nil {
+		return nil
+	}
+	kept := make(map[string]T, len(values))
+	for key, value := range values {
+		if id, ok := customFieldKeyID(key); ok && fieldIDs[id] {
+			continue
+		}
+		kept[key] = value
+	}
+	return kept
+}
+
+// pruneFieldRefs returns a copy of view without its references to the given custom
+// fields. Filter rules on a removed field are dropped as a whole, so a custom field query
+// mentioning it stops filtering at all, and so are board settings grouped by one.
+func pruneFieldRefs(view CustomView, fieldIDs map[int]bool) CustomView {
+	columnOrder := make([]interface{}, 0, len(view.ColumnOrder))
+	for _, entry := range view.ColumnOrder {
+		switch typed := entry.(type) {
+		case string:
+			if id, ok := customFieldKeyID(typed); ok && fieldIDs[id] {
+				continue
+			}
+		case float64:
+			if fieldIDs[int(typed)] {
+				continue
+			}
+		}
+		columnOrder = append(columnOrder, entry)
+	}
+	view.ColumnOrder = columnOrder
+
+	view.ColumnSizing = withoutFieldKeys(view.ColumnSizing, fieldIDs)
+	view.ColumnVisibility = withoutFieldKeys(view.ColumnVisibility, fieldIDs)
+	view.ColumnDisplayTypes = withoutFieldKeys(view.ColumnDisplayTypes, fieldIDs)
+	view.ColumnStyles = withoutFieldKeys(view.ColumnStyles, fieldIDs)
+	view.ColumnSpanning = withoutFieldKeys(view.ColumnSpanning, fieldIDs)
+	view.FilterVisibility = withoutFieldKeys(view.FilterVisibility, fieldIDs)
+	view.FilterTypes = withoutFieldKeys(view.FilterTypes, fieldIDs)
+	view.EditModeSettings = withoutFieldKeys(view.EditModeSettings, fieldIDs)
+
+	var filterRules []map[string]interface{}
+rules:
+	for _, rule := range view.FilterRules {
+		for _, id := range filterRuleFieldIDs(rule) {
+			if fieldIDs[id] {
+				continue rules
+			}
+		}
+		filterRules = append(filterRules, rule)
+	}
+	view.FilterRules = filterRules
+
+	if view.BoardSettings != nil {
+		board := *view.BoardSettings
+		if id, ok := customFieldKeyID(board.GroupBy); ok && fieldIDs[id] {
+			view.BoardSettings = nil
+			return view
+		}
+		var cardFields []string
+		for _, column := range board.CardFields {
+			if id, ok := customFieldKeyID(column); ok && fieldIDs[id] {
+				continue
+			}
+			cardFields = append(cardFields, column)
+		}
+		board.CardFields = cardFields
+		view.BoardSettings = &board
+	}
+	return view
+}
+
+// flagDanglingFieldRefs sets DanglingFieldIDs on the views referring to custom fields that
+// no longer exist
+func (s *Service) flagDanglingFieldRefs(ctx context.Context, views []CustomView) error {
+	if len(views) == 0 {
+		return nil
+	}
+	fieldIDs, err := s.customFieldIDs(ctx)
+	if err != nil {
+		return err
+	}
+	for i := range views {
+		views[i].DanglingFieldIDs = danglingFieldIDs(danglingFieldRefs(&views[i], fieldIDs))
+	}
+	return nil
+}
+
+// CheckCustomView validates a stored view and lists its references to deleted custom fields
+func (s *Service) CheckCustomView(ctx context.Context, view *CustomView) (*CustomViewCheck, error) {
+	fieldIDs, err := s.customFieldIDs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	v := &customViewValidator{fieldIDs: fieldIDs}
+	v.validate(*view)
+
+	check := &CustomViewCheck{
+		ViewID:       *view.ID,
+		Problems:     v.problems,
+		DanglingRefs: danglingFieldRefs(view, fieldIDs),
+	}
+	if check.Problems == nil {
+		check.Problems = []ValidationError{}
+	}
+	if check.DanglingRefs == nil {
+		check.DanglingRefs = []DanglingFieldRef{}
+	}
+	check.Valid = len(check.Problems) == 0
+	return check, nil
+}
+
+// checkViewFieldRefs looks for views referring to deleted custom fields, and prunes those
+// references when VIEW_FIELD_PRUNE is set
+func (s *Service) checkViewFieldRefs(ctx context.Context) {
+	fieldIDs, err := s.customFieldIDs(ctx)
+	if err != nil {
+		log.Printf("[CustomViews] Field reference check failed: %v", err)
+		return
+	}
+
+	rows, err := s.db.QueryContext(ctx, "SELECT id FROM custom_views WHERE deleted_at IS NULL")
+	if err != nil {
+		log.Printf("[CustomViews] Field reference check failed to query views: %v", err)
+		return
+	}
+	var viewIDs []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err == nil {
+			viewIDs = append(viewIDs, id)
+		}
+	}
+	rows.Close()
+
+	for _, id := range viewIDs {
+		view, err := s.GetCustomView(ctx, id)
+		if err != nil {
+			continue
+		}
+		dangling := danglingFieldIDs(danglingFieldRefs(view, fieldIDs))
+		if len(dangling) == 0 {
+			continue
+		}
+		if !s.config.ViewFieldPrune {
+			log.Printf("[CustomViews] View %d refers to deleted custom fields %v", id, dangling)
+			continue
+		}
+
+		prune := make(map[int]bool, len(dangling))
+		for _, fieldID := range dangling {
+			prune[fieldID] = true
+		}
+		ownerID := 0
+		if view.OwnerID != nil {
+			ownerID = *view.OwnerID
+		}
+		// Only prune the version checked, so a concurrent edit is not overwritten
+		_, err = s.UpdateCustomViewIfMatch(ctx, id, ownerID, customViewETag(view), func(current *CustomView) (CustomView, error) {
+			return pruneFieldRefs(*current, prune), nil
+		})
+		if err != nil {
+			log.Printf("[CustomViews] Failed to prune deleted custom fields %v from view %d: %v", dangling, id, err)
+			continue
+		}
+		log.Printf("[CustomViews] Pruned deleted custom fields %v from view %d", dangling, id)
+	}
+}
+
+// runViewFieldCheck periodically checks the custom field references of views
+func (s *Service) runViewFieldCheck(ctx context.Context) {
+	log.Printf("[CustomViews] Field reference check started - Interval: %s, Prune: %v", s.config.ViewFieldCheckInterval, s.config.ViewFieldPrune)
+	s.checkViewFieldRefs(ctx)
+
+	ticker := time.NewTicker(s.config.ViewFieldCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Printf("[CustomViews] Field reference check stopped")
+			return
+		case <-ticker.C:
+			s.checkViewFieldRefs(ctx)
+		}
+	}
+}
+
+// HTTP Handlers for view consistency checks
+func (s *Service) handleCheckCustomView(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	idStr := mux.Vars(r)["id"]
+	log.Printf("[CustomViews] GET /api/custom_views/%s/validate/ - Request from %s", idStr, r.RemoteAddr)
+
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid view ID")
+		return
+	}
+	userID, err := getUserIDFromRequest(r)
+	if err != nil {
+		respondError(w, viewerErrorStatus(err), err.Error())
+		return
+	}
+
+	view, err := s.accessibleCustomView(ctx, id, *userID)
+	if err != nil {
+		respondError(w, viewPreferencesErrorStatus(err), err.Error())
+		return
+	}
+
+	check, err := s.CheckCustomView(ctx, view)
+	if err != nil {
+		log.Printf("[CustomViews] Error checking view %d: %v", id, err)
+		respondError(w, queryErrorStatus(err), err.Error())
+		return
+	}
+
+	log.Printf("[CustomViews] Checked view %d - %d problem(s), %d dangling field reference(s)", id, len(check.Problems), len(check.DanglingRefs))
+	respondJSON(w, http.StatusOK, check)
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// localeTagPattern matches BCP 47 style locale tags such as "de", "pt-BR" or "zh-Hant-TW"
+var localeTagPattern = regexp.MustCompile(`^[A-Za-z]{2,3}(-[A-Za-z0-9]{2,8})*$`)
+
+// translations checks the locales and the names of a view's translations
+func (v *customViewValidator) translations(translations map[string]ViewTranslation) {
+	for _, locale := range columnMapKeys(translations) {
+		field := "translations." + locale
+		if !localeTagPattern.MatchString(locale) {
+			v.add(field, "%q is not a locale tag such as \"de\" or \"pt-BR\"", locale)
+		}
+		if len(translations[locale].Name) > maxCustomViewNameLength {
+			v.add(field+".name", "name must be at most %d characters", maxCustomViewNameLength)
+		}
+	}
+}
+
+// requestedLocales returns the locales a request asks for, preferred first: the locale query
+// parameter, or else the Accept-Language header ordered by quality
+func requestedLocales(r *http.Request) []string {
+	if locale := strings.TrimSpace(r.URL.Query().Get("locale")); locale != "" {
+		return []string{locale}
+	}
+
+	type weighted struct {
+		locale  string
+		quality float64
+	}
+	var candidates []weighted
+	for _, part := range strings.Split(r.Header.Get("Accept-Language"), ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		locale := strings.TrimSpace(fields[0])
+		if locale == "" || locale == "*" {
+			continue
+		}
+		quality := 1.0
+		for _, param := range fields[1:] {
+			if value, ok := strings.CutPrefix(strings.TrimSpace(param), "q="); ok {
+				if q, err := strconv.ParseFloat(value, 64); err == nil {
+					quality = q
+				}
+			}
+		}
+		if quality > 0 {
+			candidates = append(candidates, weighted{locale, quality})
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].quality > candidates[j].quality })
+
+	locales := make([]string, len(candidates))
+	for i, candidate := range candidates {
+		locales[i] = candidate.locale
+	}
+	return locales
+}
+
+// matchTranslation picks the translation for the first requested locale that has one. A
+// locale matches its exact tag, then its less specific tags ("de-CH" falls back to "de"),
+// then any tag of the same language ("de" matches "de-DE"). Tags compare case-insensitively.
+func matchTranslation(translations map[string]ViewTranslation, locales []string) (string, bool) {
+	if len(translations) == 0 {
+		return "", false
+	}
+	byTag := make(map[string]string, len(translations))
+	for _, key := range columnMapKeys(translations) {
+		byTag[strings.ToLower(key)] = key
+	}
+
+	for _, locale := range locales {
+		tag := strings.ToLower(strings.ReplaceAll(locale, "_", "-"))
+		for candidate := tag; candidate != ""; {
+			if key, ok := byTag[candidate]; ok {
+				return key, true
+			}
+			cut := strings.LastIndex(candidate, "-")
+			if cut < 0 {
+				break
+			}
+			candidate = candidate[:cut]
+		}
+		language, _, _ := strings.Cut(tag, "-")
+		for _, key := range columnMapKeys(byTag) {
+			if strings.HasPrefix(key, language+"-") {
+				return byTag[key], true
+			}
+		}
+	}
+	return "", false
+}
+
+// localizedCustomView returns a copy of view with display_name and display_description in
+// the locale the request asks for. The view itself is left unchanged, so name and
+// description always hold the untranslated values clients edit.
+func localizedCustomView(r *http.Request, view *CustomView) *CustomView {
+	locale, ok := matchTranslation(view.Translations, requestedLocales(r))
+	if !ok {
+		return view
+	}
+	localized := *view
+	translation := view.Translations[locale]
+	localized.Locale = &locale
+	if translation.Name != "" {
+		localized.DisplayName = &translation.Name
+	}
+	localized.DisplayDescription = translation.Description
+	return &localized
+}
+
+// localizeCustomViews applies localizedCustomView to a list of views in place
+func localizeCustomViews(r *http.Request, views []CustomView) {
+	locales := requestedLocales(r)
+	if len(locales) == 0 {
+		return
+	}
+	for i := range views {
+		views[i] = *localizedCustomView(r, &views[i])
+	}
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+)
+
+// ViewQuotaExceededError is returned when a user who already has MAX_VIEWS_PER_USER views
+// creates or restores another one
+type ViewQuotaExceededError struct {
+	Limit int
+	Count int
+}
+
+func (e *ViewQuotaExceededError) Error() string {
+	return fmt.Sprintf("view quota exceeded: user has %d views and at most %d are allowed; delete a view first", e.Count, e.Limit)
+}
+
+// ViewQuotaExceededResponse is the 409 response of a creation beyond the quota
+type ViewQuotaExceededResponse struct {
+	Error   string `json:"error"`
+	Message string `json:"message"`
+	Limit   int    `json:"limit"`
+	Count   int    `json:"count"`
+}
+
+// checkViewQuota fails with a ViewQuotaExceededError when the user cannot have another
+// view. Views in the trash do not count. The check is not atomic with the creation, so
+// concurrent requests can exceed the quota slightly.
+func (s *Service) checkViewQuota(ctx context.Context, userID int) error {
+	if s.config.MaxViewsPerUser <= 0 {
+		return nil
+	}
+	var count int
+	err := s.db.QueryRowContext(ctx, s.rebind("SELECT COUNT(*) FROM custom_views WHERE owner_id = ? AND deleted_at IS NULL"), userID).Scan(&count)
+	if err != nil {
+		return fmt.Errorf("failed to count custom views: %w", err)
+	}
+	if count >= s.config.MaxViewsPerUser {
+		log.Printf("[CustomViews] User %d reached the view quota (%d views)", userID, count)
+		return &ViewQuotaExceededError{Limit: s.config.MaxViewsPerUser, Count: count}
+	}
+	return nil
+}
+
+// respondViewQuotaExceeded sends the 409 response when err is a ViewQuotaExceededError and
+// reports whether it did
+func respondViewQuotaExceeded(w http.ResponseWriter, err error) bool {
+	var exceeded *ViewQuotaExceededError
+	if !errors.As(err, &exceeded) {
+		return false
+	}
+	respondJSON(w, http.StatusConflict, ViewQuotaExceededResponse{
+		Error:   http.StatusText(http.StatusConflict),
+		Message: exceeded.Error(),
+		Limit:   exceeded.Limit,
+		Count:   exceeded.Count,
+	})
+	return true
+}
+
+// ListViewUsage returns the number of views of every user who owns any
+func (s *Service) ListViewUsage(ctx context.Context) ([]ViewUsage, error) {
+	globalTrue := "is_global = 1"
+	if s.config.DBEngine == "postgresql" || s.config.DBEngine == "postgres" {
+		globalTrue = "is_global = true"
+	}
+	query := fmt.Sprintf(`
+		SELECT owner_id, MAX(username),
+			SUM(CASE WHEN deleted_at IS NULL THEN 1 ELSE 0 END),
+			SUM(CASE WHEN deleted_at IS NULL AND %s THEN 1 ELSE 0 END),
+			SUM(CASE WHEN deleted_at IS NOT NULL THEN 1 ELSE 0 END)
+		FROM custom_views
+		GROUP BY owner_id
+		ORDER BY owner_id
+	`, globalTrue)
+
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query view usage: %w", err)
+	}
+	defer rows.Close()
+
+	usage := []ViewUsage{}
+	for rows.Next() {
+		var entry ViewUsage
+		var ownerID sql.NullInt64
+		var username sql.NullString
+		if err := rows.Scan(&ownerID, &username, &entry.Views, &entry.GlobalViews, &entry.TrashedViews); err != nil {
+			return nil, fmt.Errorf("failed to scan view usage: %w", err)
+		}
+		if ownerID.Valid {
+			id := int(ownerID.Int64)
+			entry.OwnerID = &id
+		}
+		if username.Valid {
+			entry.Username = &username.String
+		}
+		usage = append(usage, entry)
+	}
+	return usage, rows.Err()
+}
+
+// HTTP Handlers for view quotas
+func (s *Service) handleGetViewUsage(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	log.Printf("[CustomViews] GET /api/custom_views/usage/ - Request from %s", r.RemoteAddr)
+
+	userID, err := getUserIDFromRequest(r)
+	if err != nil {
+		respondError(w, viewerErrorStatus(err), err.Error())
+		return
+	}
+	superuser, err := s.isSuperuser(ctx, *userID)
+	if err != nil {
+		respondError(w, queryErrorStatus(err), err.Error())
+		return
+	}
+	if !superuser {
+		respondError(w, http.StatusForbidden, "permission denied: only superusers can see the view usage of all users")
+		return
+	}
+
+	usage, err := s.ListViewUsage(ctx)
+	if err != nil {
+		log.Printf("[CustomViews] Error listing view usage: %v", err)
+		respondError(w, queryErrorStatus(err), err.Error())
+		return
+	}
+
+	response := ViewUsageResponse{Count: len(usage), Results: usage}
+	if s.config.MaxViewsPerUser > 0 {
+		response.Limit = &s.config.MaxViewsPerUser
+	}
+	respondJSON(w, http.StatusOK, response)
+}
@@ -0,0 +1,688 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Notification rule conditions
+const (
+	RuleCountAbove   = "count_above"   // More than threshold documents match
+	RuleCountBelow   = "count_below"   // Fewer than threshold documents match
+	RuleNewDocuments = "new_documents" // A document matched that did not at the previous check
+)
+
+// Notification rule channels
+const (
+	RuleChannelWebhook = "webhook"
+	RuleChannelEmail   = "email"
+)
+
+// EventViewRuleTriggered is the event of rule notifications sent by webhook
+const EventViewRuleTriggered = "view.rule_triggered"
+
+// viewRuleColumns are the columns read by scanViewRule
+const viewRuleColumns = "id, view_id, condition_type, threshold, channel, target, secret, is_active, last_checked, last_triggered, owner_id, username, created, modified"
+
+// ViewRuleNotification is the JSON body posted by webhook rules
+type ViewRuleNotification struct {
+	ID             string `json:"id"` // Delivery ID, the same for all attempts
+	Event          string `json:"event"`
+	Timestamp      string `json:"timestamp"`
+	RuleID         int    `json:"rule_id"`
+	ViewID         int    `json:"view_id"`
+	ViewName       string `json:"view_name"`
+	Condition      string `json:"condition"`
+	Threshold      *int   `json:"threshold,omitempty"`
+	DocumentCount  int    `json:"document_count"`
+	NewDocumentIDs []int  `json:"new_document_ids,omitempty"`
+}
+
+// scanViewRule scans a ViewNotificationRule from a database row or rows
+func (s *Service) scanViewRule(scanner interface{ Scan(...interface{}) error }) (ViewNotificationRule, error) {
+	var rule ViewNotificationRule
+	var id int
+	var threshold, ownerID sql.NullInt64
+	var secret, username sql.NullString
+	var lastChecked, lastTriggered, created, modified dbTimestamp
+	var isActive sql.NullBool
+
+	if err := scanner.Scan(&id, &rule.ViewID, &rule.Condition, &threshold, &rule.Channel, &rule.Target, &secret,
+		&isActive, &lastChecked, &lastTriggered, &ownerID, &username, &created, &modified); err != nil {
+		return rule, err
+	}
+
+	rule.ID = &id
+	if threshold.Valid {
+		value := int(threshold.Int64)
+		rule.Threshold = &value
+	}
+	if secret.Valid && secret.String != "" {
+		rule.Secret = &secret.String
+		rule.HasSecret = true
+	}
+	if isActive.Valid {
+		rule.IsActive = &isActive.Bool
+	}
+	rule.LastCheckedAt = s.formatTimestamp(lastChecked)
+	rule.LastTriggeredAt = s.formatTimestamp(lastTriggered)
+	if ownerID.Valid {
+		owner := int(ownerID.Int64)
+		rule.OwnerID = &owner
+	}
+	if username.Valid {
+		rule.Username = &username.String
+	}
+	rule.Created = s.formatTimestamp(created)
+	rule.Modified = s.formatTimestamp(modified)
+
+	return rule, nil
+}
+
+// validateViewRule checks the condition and the channel of a rule
+func (s *Service) validateViewRule(rule ViewNotificationRule) error {
+	switch rule.Condition {
+	case RuleCountAbove, RuleCountBelow:
+		if rule.Threshold == nil || *rule.Threshold < 0 {
+			return fmt.Errorf("invalid rule: %s needs a threshold of 0 or more", rule.Condition)
+		}
+	case RuleNewDocuments:
+	default:
+		return fmt.Errorf("invalid rule: condition must be count_above, count_below or new_documents")
+	}
+
+	switch rule.Channel {
+	case RuleChannelWebhook:
+		if err := validateWebhook(Webhook{URL: rule.Target}); err != nil {
+			return fmt.Errorf("invalid rule: target must be an absolute http or https URL")
+		}
+	case RuleChannelEmail:
+		if _, err := mail.ParseAddress(rule.Target); err != nil {
+			return fmt.Errorf("invalid rule: target must be an email address")
+		}
+		if s.config.SMTPHost == "" {
+			return fmt.Errorf("invalid rule: email notifications need SMTP_HOST to be configured")
+		}
+	default:
+		return fmt.Errorf("invalid rule: channel must be webhook or email")
+	}
+	return nil
+}
+
+// ListViewRules retrieves the user's notification rules of a view
+func (s *Service) ListViewRules(ctx context.Context, viewID int, userID int) ([]ViewNotificationRule, error) {
+	rows, err := s.db.QueryContext(ctx, s.rebind("SELECT "+viewRuleColumns+" FROM view_notification_rules WHERE view_id = ? AND owner_id = ? ORDER BY id"), viewID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query notification rules: %w", err)
+	}
+	defer rows.Close()
+
+	rules := []ViewNotificationRule{}
+	for rows.Next() {
+		rule, err := s.scanViewRule(rows)
+		if err != nil {
+			continue
+		}
+		rules = append(rules, rule)
+	}
+
+	return rules, nil
+}
+
+// GetViewRule retrieves a notification rule of the user; other users' rules are not found
+func (s *Service) GetViewRule(ctx context.Context, viewID int, id int, userID int) (*ViewNotificationRule, error) {
+	rule, err := s.scanViewRule(s.db.QueryRowContext(ctx, s.rebind("SELECT "+viewRuleColumns+" FROM view_notification_rules WHERE id = ? AND view_id = ?"), id, viewID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("notification rule with id %d not found", id)
+		}
+		return nil, fmt.Errorf("failed to query notification rule: %w", err)
+	}
+	if rule.OwnerID != nil && *rule.OwnerID != userID {
+		return nil, fmt.Errorf("notification rule with id %d not found", id)
+	}
+	return &rule, nil
+}
+
+// CreateViewRule attaches a notification rule of the user to a view
+func (s *Service) CreateViewRule(ctx context.Context, viewID int, rule ViewNotificationRule, userID int, username string) (*ViewNotificationRule, error) {
+	log.Printf("[ViewRules] CreateViewRule - ViewID: %d, Condition: %s, Channel: %s, UserID: %d", viewID, rule.Condition, rule.Channel, userID)
+	if rule.Condition == RuleNewDocuments {
+		rule.Threshold = nil
+	}
+	if err := s.validateViewRule(rule); err != nil {
+		return nil, err
+	}
+	isActive := rule.IsActive == nil || *rule.IsActive
+
+	var newID int
+	if s.config.DBEngine == "postgresql" || s.config.DBEngine == "postgres" {
+		err := s.db.QueryRowContext(ctx, `
+			INSERT INTO view_notification_rules (view_id, condition_type, threshold, channel, target, secret, is_active, owner_id, username)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+			RETURNING id
+		`, viewID, rule.Condition, rule.Threshold, rule.Channel, rule.Target, rule.Secret, isActive, userID, username).Scan(&newID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create notification rule: %w", err)
+		}
+	} else {
+		result, err := s.db.ExecContext(ctx, `
+			INSERT INTO view_notification_rules (view_id, condition_type, threshold, channel, target, secret, is_active, owner_id, username)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`, viewID, rule.Condition, rule.Threshold, rule.Channel, rule.Target, rule.Secret, isActive, userID, username)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create notification rule: %w", err)
+		}
+		lastID, err := result.LastInsertId()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get last insert ID: %w", err)
+		}
+		newID = int(lastID)
+	}
+
+	return s.GetViewRule(ctx, viewID, newID, userID)
+}
+
+// UpdateViewRule updates the provided fields of a rule of the user. Changing the condition
+// or the threshold starts the rule over, as if it had just been created. An empty secret
+// removes the signature.
+func (s *Service) UpdateViewRule(ctx context.Context, viewID int, id int, updates ViewNotificationRule, userID int) (*ViewNotificationRule, error) {
+	log.Printf("[ViewRules] UpdateViewRule - ID: %d, UserID: %d", id, userID)
+	existing, err := s.GetViewRule(ctx, viewID, id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	merged := *existing
+	if updates.Condition != "" {
+		merged.Condition = updates.Condition
+	}
+	if updates.Threshold != nil {
+		merged.Threshold = updates.Threshold
+	}
+	if merged.Condition == RuleNewDocuments {
+		merged.Threshold = nil
+	}
+	if updates.Channel != "" {
+		merged.Channel = updates.Channel
+	}
+	if updates.Target != "" {
+		merged.Target = updates.Target
+	}
+	if err := s.validateViewRule(merged); err != nil {
+		return nil, err
+	}
+
+	usePostgres := s.config.DBEngine == "postgresql" || s.config.DBEngine == "postgres"
+	setParts := []string{}
+	args := []interface{}{}
+	addSet := func(column string, value interface{}) {
+		args = append(args, value)
+		if usePostgres {
+			setParts = append(setParts, fmt.Sprintf("%s = $%d", column, len(args)))
+		} else {
+			setParts = append(setParts, column+" = ?")
+		}
+	}
+
+	if updates.Condition != "" || updates.Threshold != nil {
+		addSet("condition_type", merged.Condition)
+		addSet("threshold", merged.Threshold)
+		addSet("condition_met", false)
+		setParts = append(setParts, "last_document_ids = NULL")
+	}
+	if updates.Channel != "" {
+		addSet("channel", updates.Channel)
+	}
+	if updates.Target != "" {
+		addSet("target", updates.Target)
+	}
+	if updates.Secret != nil {
+		addSet("secret", *updates.Secret)
+	}
+	if updates.IsActive != nil {
+		addSet("is_active", *updates.IsActive)
+	}
+	setParts = append(setParts, "modified = CURRENT_TIMESTAMP")
+
+	args = append(args, id)
+	updateQuery := fmt.Sprintf("UPDATE view_notification_rules SET %s WHERE id = ?", strings.Join(setParts, ", "))
+	if usePostgres {
+		updateQuery = fmt.Sprintf("UPDATE view_notification_rules SET %s WHERE id = $%d", strings.Join(setParts, ", "), len(args))
+	}
+
+	if _, err := s.db.ExecContext(ctx, updateQuery, args...); err != nil {
+		return nil, fmt.Errorf("failed to update notification rule: %w", err)
+	}
+
+	return s.GetViewRule(ctx, viewID, id, userID)
+}
+
+// DeleteViewRule deletes a rule of the user
+func (s *Service) DeleteViewRule(ctx context.Context, viewID int, id int, userID int) error {
+	log.Printf("[ViewRules] DeleteViewRule - ID: %d, UserID: %d", id, userID)
+	if _, err := s.GetViewRule(ctx, viewID, id, userID); err != nil {
+		return err
+	}
+	if _, err := s.db.ExecContext(ctx, s.rebind("DELETE FROM view_notification_rules WHERE id = ?"), id); err != nil {
+		return fmt.Errorf("failed to delete notification rule: %w", err)
+	}
+	return nil
+}
+
+// evaluateViewRule checks one rule against the current documents of its view, stores the
+// outcome and sends a notification when the rule triggers. Count rules trigger when their
+// condition becomes true, not on every check while it stays true; new_documents rules
+// compare with the documents of the previous check (the first check only records them).
+func (s *Service) evaluateViewRule(ctx context.Context, rule ViewNotificationRule, conditionMet bool, lastDocumentIDs sql.NullString) error {
+	ownerID := 0
+	if rule.OwnerID != nil {
+		ownerID = *rule.OwnerID
+	}
+	view, err := s.accessibleCustomView(ctx, rule.ViewID, ownerID)
+	if err != nil {
+		return err
+	}
+	where, args, err := s.viewDocumentCondition(ctx, view, ownerID)
+	if err != nil {
+		return err
+	}
+
+	notification := ViewRuleNotification{
+		RuleID:    *rule.ID,
+		ViewID:    rule.ViewID,
+		ViewName:  view.Name,
+		Condition: rule.Condition,
+		Threshold: rule.Threshold,
+	}
+	triggered := false
+	var documentIDsJSON interface{}
+
+	switch rule.Condition {
+	case RuleCountAbove, RuleCountBelow:
+		count, err := s.countViewDocuments(ctx, where, args)
+		if err != nil {
+			return err
+		}
+		met := count > *rule.Threshold
+		if rule.Condition == RuleCountBelow {
+			met = count < *rule.Threshold
+		}
+		triggered = met && !conditionMet
+		conditionMet = met
+		notification.DocumentCount = count
+	case RuleNewDocuments:
+		rows, err := s.db.QueryContext(ctx, "SELECT d.id FROM documents_document d WHERE "+where+" ORDER BY d.id", args...)
+		if err != nil {
+			return fmt.Errorf("failed to query view documents: %w", err)
+		}
+		documentIDs := []int{}
+		for rows.Next() {
+			var id int
+			if err := rows.Scan(&id); err == nil {
+				documentIDs = append(documentIDs, id)
+			}
+		}
+		rows.Close()
+
+		if lastDocumentIDs.Valid {
+			var previous []int
+			json.Unmarshal([]byte(lastDocumentIDs.String), &previous)
+			known := make(map[int]bool, len(previous))
+			for _, id := range previous {
+				known[id] = true
+			}
+			for _, id := range documentIDs {
+				if !known[id] {
+					notification.NewDocumentIDs = append(notification.NewDocumentIDs, id)
+				}
+			}
+			triggered = len(notification.NewDocumentIDs) > 0
+		}
+		encoded, _ := json.Marshal(documentIDs)
+		documentIDsJSON = string(encoded)
+		notification.DocumentCount = len(documentIDs)
+	}
+
+	stateQuery := "UPDATE view_notification_rules SET condition_met = ?, last_document_ids = ?, last_checked = CURRENT_TIMESTAMP"
+	if s.config.DBEngine == "postgresql" || s.config.DBEngine == "postgres" {
+		stateQuery = "UPDATE view_notification_rules SET condition_met = ?, last_document_ids = ?::jsonb, last_checked = CURRENT_TIMESTAMP"
+	}
+	if triggered {
+		stateQuery += ", last_triggered = CURRENT_TIMESTAMP"
+	}
+	if _, err := s.db.ExecContext(ctx, s.rebind(stateQuery+" WHERE id = ?"), conditionMet, documentIDsJSON, *rule.ID); err != nil {
+		return fmt.Errorf("failed to store rule state: %w", err)
+	}
+
+	if triggered {
+		log.Printf("[ViewRules] Rule %d of view %d triggered (%s, %d documents)", *rule.ID, rule.ViewID, rule.Condition, notification.DocumentCount)
+		go s.sendViewRuleNotification(rule, notification)
+	}
+	return nil
+}
+
+// runDueViewRules evaluates every active rule of a view that is not in the trash
+func (s *Service) runDueViewRules(ctx context.Context) {
+	rows, err := s.db.QueryContext(ctx, s.rebind(`SELECT r.id, r.view_id, r.condition_type, r.threshold, r.channel, r.target,
+			r.secret, r.is_active, r.last_checked, r.last_triggered, r.owner_id, r.username, r.created, r.modified,
+			r.condition_met, r.last_document_ids
+		FROM view_notification_rules r
+		JOIN custom_views cv ON cv.id = r.view_id
+		WHERE cv.deleted_at IS NULL AND r.is_active = ?`), true)
+	if err != nil {
+		log.Printf("[ViewRules] Failed to query notification rules: %v", err)
+		return
+	}
+	type ruleState struct {
+		rule            ViewNotificationRule
+		conditionMet    bool
+		lastDocumentIDs sql.NullString
+	}
+	var states []ruleState
+	for rows.Next() {
+		var state ruleState
+		var conditionMet sql.NullBool
+		rule, err := s.scanViewRule(scannerFunc(func(dest ...interface{}) error {
+			return rows.Scan(append(dest, &conditionMet, &state.lastDocumentIDs)...)
+		}))
+		if err != nil {
+			log.Printf("[ViewRules] Failed to scan notification rule: %v", err)
+			continue
+		}
+		state.rule = rule
+		state.conditionMet = conditionMet.Valid && conditionMet.Bool
+		states = append(states, state)
+	}
+	rows.Close()
+
+	for _, state := range states {
+		if err := s.evaluateViewRule(ctx, state.rule, state.conditionMet, state.lastDocumentIDs); err != nil {
+			log.Printf("[ViewRules] Evaluating rule %d of view %d failed: %v", *state.rule.ID, state.rule.ViewID, err)
+		}
+	}
+}
+
+// scannerFunc adapts a function to the Scan method expected by the scan helpers
+type scannerFunc func(dest ...interface{}) error
+
+func (f scannerFunc) Scan(dest ...interface{}) error { return f(dest...) }
+
+// runViewNotificationRules periodically evaluates the notification rules
+func (s *Service) runViewNotificationRules(ctx context.Context) {
+	log.Printf("[ViewRules] Rule worker started - Interval: %s", s.config.ViewRuleInterval)
+	s.runDueViewRules(ctx)
+
+	ticker := time.NewTicker(s.config.ViewRuleInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Printf("[ViewRules] Rule worker stopped")
+			return
+		case <-ticker.C:
+			s.runDueViewRules(ctx)
+		}
+	}
+}
+
+// sendViewRuleNotification delivers a triggered rule through its channel
+func (s *Service) sendViewRuleNotification(rule ViewNotificationRule, notification ViewRuleNotification) {
+	deliveryID := make([]byte, 8)
+	rand.Read(deliveryID)
+	notification.ID = hex.EncodeToString(deliveryID)
+	notification.Event = EventViewRuleTriggered
+	notification.Timestamp = time.Now().UTC().Format(time.RFC3339)
+
+	switch rule.Channel {
+	case RuleChannelWebhook:
+		payload, err := json.Marshal(notification)
+		if err != nil {
+			log.Printf("[ViewRules] Failed to encode notification of rule %d: %v", *rule.ID, err)
+			return
+		}
+		s.postWebhookWithRetry(s.lifecycle, fmt.Sprintf("rule %d", *rule.ID), Webhook{URL: rule.Target, Secret: rule.Secret}, EventViewRuleTriggered, payload)
+	case RuleChannelEmail:
+		if err := s.sendViewRuleEmail(rule.Target, notification); err != nil {
+			log.Printf("[ViewRules] Emailing rule %d to %s failed: %v", *rule.ID, rule.Target, err)
+			return
+		}
+		log.Printf("[ViewRules] Emailed rule %d to %s", *rule.ID, rule.Target)
+	}
+}
+
+// viewRuleSummary describes a triggered rule in one sentence
+func viewRuleSummary(notification ViewRuleNotification) string {
+	switch notification.Condition {
+	case RuleCountAbove:
+		return fmt.Sprintf("%d documents match the view \"%s\", more than %d.", notification.DocumentCount, notification.ViewName, *notification.Threshold)
+	case RuleCountBelow:
+		return fmt.Sprintf("%d documents match the view \"%s\", fewer than %d.", notification.DocumentCount, notification.ViewName, *notification.Threshold)
+	}
+	return fmt.Sprintf("%d new documents match the view \"%s\".", len(notification.NewDocumentIDs), notification.ViewName)
+}
+
+// sendViewRuleEmail emails a triggered rule through the configured SMTP server
+func (s *Service) sendViewRuleEmail(to string, notification ViewRuleNotification) error {
+	var body strings.Builder
+	body.WriteString(viewRuleSummary(notification) + "\r\n")
+	if len(notification.NewDocumentIDs) > 0 {
+		ids := make([]string, len(notification.NewDocumentIDs))
+		for i, id := range notification.NewDocumentIDs {
+			ids[i] = strconv.Itoa(id)
+		}
+		body.WriteString("\r\nNew document IDs: " + strings.Join(ids, ", ") + "\r\n")
+	}
+
+	return s.sendEmail(to, "Paperless view \""+notification.ViewName+"\"", body.String())
+}
+
+// sendEmail sends a plain text email through the configured SMTP server
+func (s *Service) sendEmail(to string, subject string, body string) error {
+	return s.sendMail(to, subject, "text/plain; charset=utf-8", body)
+}
+
+// sendMail sends a message with a body of the given content type through the configured
+// SMTP server
+func (s *Service) sendMail(to string, subject string, contentType string, body string) error {
+	from := s.config.SMTPFrom
+	if from == "" {
+		from = s.config.SMTPUsername
+	}
+
+	message := "From: " + from + "\r\n" +
+		"To: " + to + "\r\n" +
+		"Subject: " + subject + "\r\n" +
+		"Date: " + time.Now().Format(time.RFC1123Z) + "\r\n" +
+		"MIME-Version: 1.0\r\n" +
+		"Content-Type: " + contentType + "\r\n" +
+		"\r\n" + body
+
+	var auth smtp.Auth
+	if s.config.SMTPUsername != "" {
+		auth = smtp.PlainAuth("", s.config.SMTPUsername, s.config.SMTPPassword, s.config.SMTPHost)
+	}
+	return smtp.SendMail(net.JoinHostPort(s.config.SMTPHost, s.config.SMTPPort), auth, from, []string{to}, []byte(message))
+}
+
+// viewRuleErrorStatus maps notification rule errors to HTTP status codes
+func viewRuleErrorStatus(err error) int {
+	switch {
+	case strings.Contains(err.Error(), "not found"):
+		return http.StatusNotFound
+	case strings.HasPrefix(err.Error(), "invalid rule"):
+		return http.StatusBadRequest
+	}
+	return queryErrorStatus(err)
+}
+
+// respondViewRule sends a rule without its secret
+func respondViewRule(w http.ResponseWriter, status int, rule *ViewNotificationRule) {
+	rule.Secret = nil
+	respondJSON(w, status, rule)
+}
+
+// viewRuleRequest reads the view ID and the user of a rule request and checks that the user
+// can see the view. It answers the request itself when it returns false.
+func (s *Service) viewRuleRequest(ctx context.Context, w http.ResponseWriter, r *http.Request) (int, int, bool) {
+	viewID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid view ID")
+		return 0, 0, false
+	}
+	userID, err := getUserIDFromRequest(r)
+	if err != nil {
+		respondError(w, viewerErrorStatus(err), err.Error())
+		return 0, 0, false
+	}
+	if _, err := s.accessibleCustomView(ctx, viewID, *userID); err != nil {
+		respondError(w, viewRuleErrorStatus(err), err.Error())
+		return 0, 0, false
+	}
+	return viewID, *userID, true
+}
+
+// HTTP Handlers for view notification rules
+func (s *Service) handleListViewRules(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	log.Printf("[ViewRules] GET /api/custom_views/%s/rules/ - Request from %s", mux.Vars(r)["id"], r.RemoteAddr)
+	viewID, userID, ok := s.viewRuleRequest(ctx, w, r)
+	if !ok {
+		return
+	}
+
+	rules, err := s.ListViewRules(ctx, viewID, userID)
+	if err != nil {
+		respondError(w, queryErrorStatus(err), err.Error())
+		return
+	}
+	for i := range rules {
+		rules[i].Secret = nil
+	}
+
+	respondJSON(w, http.StatusOK, ViewNotificationRuleListResponse{Count: len(rules), Results: rules})
+}
+
+func (s *Service) handleGetViewRule(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	vars := mux.Vars(r)
+	log.Printf("[ViewRules] GET /api/custom_views/%s/rules/%s/ - Request from %s", vars["id"], vars["rule"], r.RemoteAddr)
+	ruleID, err := strconv.Atoi(vars["rule"])
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid rule ID")
+		return
+	}
+	viewID, userID, ok := s.viewRuleRequest(ctx, w, r)
+	if !ok {
+		return
+	}
+
+	rule, err := s.GetViewRule(ctx, viewID, ruleID, userID)
+	if err != nil {
+		respondError(w, viewRuleErrorStatus(err), err.Error())
+		return
+	}
+
+	respondViewRule(w, http.StatusOK, rule)
+}
+
+func (s *Service) handleCreateViewRule(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	log.Printf("[ViewRules] POST /api/custom_views/%s/rules/ - Request from %s", mux.Vars(r)["id"], r.RemoteAddr)
+	var rule ViewNotificationRule
+	if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+		respondError(w, http.StatusBadRequest, fmt.Sprintf("Invalid request body: %v", err))
+		return
+	}
+	viewID, userID, ok := s.viewRuleRequest(ctx, w, r)
+	if !ok {
+		return
+	}
+	username := getUsernameFromRequest(r)
+
+	created, err := s.CreateViewRule(ctx, viewID, rule, userID, *username)
+	if err != nil {
+		log.Printf("[ViewRules] Error creating rule for view %d: %v", viewID, err)
+		respondError(w, viewRuleErrorStatus(err), err.Error())
+		return
+	}
+
+	respondViewRule(w, http.StatusCreated, created)
+}
+
+func (s *Service) handleUpdateViewRule(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	vars := mux.Vars(r)
+	log.Printf("[ViewRules] %s /api/custom_views/%s/rules/%s/ - Request from %s", r.Method, vars["id"], vars["rule"], r.RemoteAddr)
+	ruleID, err := strconv.Atoi(vars["rule"])
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid rule ID")
+		return
+	}
+	var updates ViewNotificationRule
+	if err := json.NewDecoder(r.Body).Decode(&updates); err != nil {
+		respondError(w, http.StatusBadRequest, fmt.Sprintf("Invalid request body: %v", err))
+		return
+	}
+	viewID, userID, ok := s.viewRuleRequest(ctx, w, r)
+	if !ok {
+		return
+	}
+
+	updated, err := s.UpdateViewRule(ctx, viewID, ruleID, updates, userID)
+	if err != nil {
+		log.Printf("[ViewRules] Error updating rule %d: %v", ruleID, err)
+		respondError(w, viewRuleErrorStatus(err), err.Error())
+		return
+	}
+
+	respondViewRule(w, http.StatusOK, updated)
+}
+
+func (s *Service) handleDeleteViewRule(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	vars := mux.Vars(r)
+	log.Printf("[ViewRules] DELETE /api/custom_views/%s/rules/%s/ - Request from %s", vars["id"], vars["rule"], r.RemoteAddr)
+	ruleID, err := strconv.Atoi(vars["rule"])
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid rule ID")
+		return
+	}
+	viewID, userID, ok := s.viewRuleRequest(ctx, w, r)
+	if !ok {
+		return
+	}
+
+	if err := s.DeleteViewRule(ctx, viewID, ruleID, userID); err != nil {
+		log.Printf("[ViewRules] Error deleting rule %d: %v", ruleID, err)
+		respondError(w, viewRuleErrorStatus(err), err.Error())
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,563 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Snapshot triggers
+const (
+	SnapshotTriggerSchedule = "schedule"
+	SnapshotTriggerManual   = "manual"
+)
+
+// snapshotTimeLayout is how schedule times are written, in UTC, so that they compare the
+// same way on every engine
+const snapshotTimeLayout = "2006-01-02 15:04:05"
+
+// snapshotIntervalStep returns the function advancing a schedule by one interval:
+// "daily", "weekly" and "monthly" follow the calendar, anything else is a duration of at
+// least a minute
+func snapshotIntervalStep(interval string) (func(time.Time) time.Time, error) {
+	switch interval {
+	case "daily":
+		return func(t time.Time) time.Time { return t.AddDate(0, 0, 1) }, nil
+	case "weekly":
+		return func(t time.Time) time.Time { return t.AddDate(0, 0, 7) }, nil
+	case "monthly":
+		return func(t time.Time) time.Time { return t.AddDate(0, 1, 0) }, nil
+	}
+	duration, err := time.ParseDuration(interval)
+	if err != nil || duration < time.Minute {
+		return nil, fmt.Errorf("invalid interval: use daily, weekly, monthly or a duration of at least 1m")
+	}
+	return func(t time.Time) time.Time { return t.Add(duration) }, nil
+}
+
+// parseSnapshotTime reads a next_run given as RFC 3339 or as a date (midnight UTC)
+func parseSnapshotTime(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.UTC(), nil
+	}
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid next_run: use RFC 3339 or YYYY-MM-DD")
+}
+
+// ownedCustomView returns a view the user may manage snapshots of
+func (s *Service) ownedCustomView(ctx context.Context, id int, userID int) (*CustomView, error) {
+	view, err := s.accessibleCustomView(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if view.OwnerID != nil && *view.OwnerID != userID {
+		return nil, fmt.Errorf("permission denied: view belongs to another user")
+	}
+	return view, nil
+}
+
+// CaptureViewSnapshot stores the IDs of the documents currently matching a view. The
+// documents are those the view's owner can see.
+func (s *Service) CaptureViewSnapshot(ctx context.Context, view *CustomView, trigger string) (*ViewSnapshot, error) {
+	viewerID := 0
+	if view.OwnerID != nil {
+		viewerID = *view.OwnerID
+	}
+	where, args, err := s.viewDocumentCondition(ctx, view, viewerID)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.QueryContext(ctx, "SELECT d.id FROM documents_document d WHERE "+where+" ORDER BY d.id", args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query view documents: %w", err)
+	}
+	documentIDs := []int{}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan view document: %w", err)
+		}
+		documentIDs = append(documentIDs, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read view documents: %w", err)
+	}
+	documentIDsJSON, _ := json.Marshal(documentIDs)
+
+	var snapshotID int
+	if s.config.DBEngine == "postgresql" || s.config.DBEngine == "postgres" {
+		err := s.db.QueryRowContext(ctx, `
+			INSERT INTO view_snapshots (view_id, document_ids, document_count, trigger_type)
+			VALUES ($1, $2::jsonb, $3, $4)
+			RETURNING id
+		`, *view.ID, string(documentIDsJSON), len(documentIDs), trigger).Scan(&snapshotID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to store view snapshot: %w", err)
+		}
+	} else {
+		result, err := s.db.ExecContext(ctx, `
+			INSERT INTO view_snapshots (view_id, document_ids, document_count, trigger_type)
+			VALUES (?, ?, ?, ?)
+		`, *view.ID, string(documentIDsJSON), len(documentIDs), trigger)
+		if err != nil {
+			return nil, fmt.Errorf("failed to store view snapshot: %w", err)
+		}
+		lastID, err := result.LastInsertId()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get last insert ID: %w", err)
+		}
+		snapshotID = int(lastID)
+	}
+
+	if s.config.ViewSnapshotLimit > 0 {
+		// The inner query is wrapped so MySQL accepts it in a DELETE on the same table
+		pruneQuery := `DELETE FROM view_snapshots WHERE view_id = ? AND id NOT IN (
+			SELECT id FROM (SELECT id FROM view_snapshots WHERE view_id = ? ORDER BY id DESC LIMIT ?) newest
+		)`
+		if _, err := s.db.ExecContext(ctx, s.rebind(pruneQuery), *view.ID, *view.ID, s.config.ViewSnapshotLimit); err != nil {
+			return nil, fmt.Errorf("failed to prune view snapshots: %w", err)
+		}
+	}
+
+	log.Printf("[ViewSnapshots] Captured snapshot %d of view %d: %d documents (%s)", snapshotID, *view.ID, len(documentIDs), trigger)
+	return s.GetViewSnapshot(ctx, *view.ID, snapshotID)
+}
+
+// ListViewSnapshots retrieves the snapshots of a view, latest first, without document IDs
+func (s *Service) ListViewSnapshots(ctx context.Context, viewID int) ([]ViewSnapshot, error) {
+	rows, err := s.db.QueryContext(ctx, s.rebind(`SELECT id, view_id, document_count, trigger_type, created
+		FROM view_snapshots WHERE view_id = ? ORDER BY id DESC`), viewID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query view snapshots: %w", err)
+	}
+	defer rows.Close()
+
+	snapshots := []ViewSnapshot{}
+	for rows.Next() {
+		var snapshot ViewSnapshot
+		var created dbTimestamp
+		if err := rows.Scan(&snapshot.ID, &snapshot.ViewID, &snapshot.DocumentCount, &snapshot.Trigger, &created); err != nil {
+			continue
+		}
+		snapshot.Created = s.formatTimestamp(created)
+		snapshots = append(snapshots, snapshot)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read view snapshots: %w", err)
+	}
+
+	return snapshots, nil
+}
+
+// GetViewSnapshot retrieves a snapshot of a view with its document IDs
+func (s *Service) GetViewSnapshot(ctx context.Context, viewID int, snapshotID int) (*ViewSnapshot, error) {
+	var snapshot ViewSnapshot
+	var documentIDsJSON string
+	var created dbTimestamp
+	err := s.db.QueryRowContext(ctx, s.rebind(`SELECT id, view_id, document_ids, document_count, trigger_type, created
+		FROM view_snapshots WHERE id = ? AND view_id = ?`), snapshotID, viewID).Scan(
+		&snapshot.ID, &snapshot.ViewID, &documentIDsJSON, &snapshot.DocumentCount, &snapshot.Trigger, &created)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("snapshot %d of custom view %d not found", snapshotID, viewID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query view snapshot: %w", err)
+	}
+
+	snapshot.DocumentIDs = []int{}
+	json.Unmarshal([]byte(documentIDsJSON), &snapshot.DocumentIDs)
+	snapshot.Created = s.formatTimestamp(created)
+	return &snapshot, nil
+}
+
+// DiffViewSnapshots compares two snapshots of a view: documents in to but not in from were
+// added, documents in from but not in to were removed
+func (s *Service) DiffViewSnapshots(ctx context.Context, viewID int, fromID int, toID int) (*ViewSnapshotDiff, error) {
+	from, err := s.GetViewSnapshot(ctx, viewID, fromID)
+	if err != nil {
+		return nil, err
+	}
+	to, err := s.GetViewSnapshot(ctx, viewID, toID)
+	if err != nil {
+		return nil, err
+	}
+
+	inFrom := make(map[int]bool, len(from.DocumentIDs))
+	for _, id := range from.DocumentIDs {
+		inFrom[id] = true
+	}
+	inTo := make(map[int]bool, len(to.DocumentIDs))
+	for _, id := range to.DocumentIDs {
+		inTo[id] = true
+	}
+
+	diff := &ViewSnapshotDiff{Added: []int{}, Removed: []int{}}
+	for _, id := range to.DocumentIDs {
+		if !inFrom[id] {
+			diff.Added = append(diff.Added, id)
+		}
+	}
+	for _, id := range from.DocumentIDs {
+		if !inTo[id] {
+			diff.Removed = append(diff.Removed, id)
+		}
+	}
+	sort.Ints(diff.Added)
+	sort.Ints(diff.Removed)
+
+	from.DocumentIDs, to.DocumentIDs = nil, nil
+	diff.From, diff.To = *from, *to
+	return diff, nil
+}
+
+// GetViewSnapshotSchedule retrieves the snapshot schedule of a view
+func (s *Service) GetViewSnapshotSchedule(ctx context.Context, viewID int) (*ViewSnapshotSchedule, error) {
+	schedule := ViewSnapshotSchedule{ViewID: viewID}
+	var nextRun, lastRun sql.NullTime
+	err := s.db.QueryRowContext(ctx, s.rebind("SELECT schedule_interval, next_run, last_run FROM view_snapshot_schedules WHERE view_id = ?"), viewID).
+		Scan(&schedule.Interval, &nextRun, &lastRun)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("snapshot schedule of custom view %d not found", viewID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query snapshot schedule: %w", err)
+	}
+	if nextRun.Valid {
+		formatted := nextRun.Time.UTC().Format(time.RFC3339)
+		schedule.NextRun = &formatted
+	}
+	if lastRun.Valid {
+		formatted := lastRun.Time.UTC().Format(time.RFC3339)
+		schedule.LastRun = &formatted
+	}
+	return &schedule, nil
+}
+
+// SetViewSnapshotSchedule creates or replaces the snapshot schedule of a view. The first
+// snapshot is taken at next_run, or one interval from now when it is not given.
+func (s *Service) SetViewSnapshotSchedule(ctx context.Context, viewID int, schedule ViewSnapshotSchedule, userID int) (*ViewSnapshotSchedule, error) {
+	log.Printf("[ViewSnapshots] SetViewSnapshotSchedule - ViewID: %d, Interval: %s, UserID: %d", viewID, schedule.Interval, userID)
+	step, err := snapshotIntervalStep(schedule.Interval)
+	if err != nil {
+		return nil, err
+	}
+	nextRun := step(time.Now().UTC())
+	if schedule.NextRun != nil && *schedule.NextRun != "" {
+		if nextRun, err = parseSnapshotTime(*schedule.NextRun); err != nil {
+			return nil, err
+		}
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, s.rebind("DELETE FROM view_snapshot_schedules WHERE view_id = ?"), viewID); err != nil {
+		return nil, fmt.Errorf("failed to replace snapshot schedule: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, s.rebind("INSERT INTO view_snapshot_schedules (view_id, schedule_interval, next_run, user_id) VALUES (?, ?, ?, ?)"),
+		viewID, schedule.Interval, nextRun.Format(snapshotTimeLayout), userID); err != nil {
+		return nil, fmt.Errorf("failed to store snapshot schedule: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit snapshot schedule: %w", err)
+	}
+
+	return s.GetViewSnapshotSchedule(ctx, viewID)
+}
+
+// DeleteViewSnapshotSchedule stops the scheduled snapshots of a view; existing snapshots are kept
+func (s *Service) DeleteViewSnapshotSchedule(ctx context.Context, viewID int) error {
+	result, err := s.db.ExecContext(ctx, s.rebind("DELETE FROM view_snapshot_schedules WHERE view_id = ?"), viewID)
+	if err != nil {
+		return fmt.Errorf("failed to delete snapshot schedule: %w", err)
+	}
+	if deleted, _ := result.RowsAffected(); deleted == 0 {
+		return fmt.Errorf("snapshot schedule of custom view %d not found", viewID)
+	}
+	return nil
+}
+
+// runDueViewSnapshots takes the snapshots whose schedule is due and moves each schedule
+// past now. Views in the trash are skipped until they are restored.
+func (s *Service) runDueViewSnapshots(ctx context.Context) {
+	now := time.Now().UTC()
+	rows, err := s.db.QueryContext(ctx, s.rebind(`SELECT sch.view_id, sch.schedule_interval, sch.next_run
+		FROM view_snapshot_schedules sch
+		JOIN custom_views cv ON cv.id = sch.view_id
+		WHERE cv.deleted_at IS NULL AND sch.next_run <= ?`), now.Format(snapshotTimeLayout))
+	if err != nil {
+		log.Printf("[ViewSnapshots] Failed to query due schedules: %v", err)
+		return
+	}
+	type dueSchedule struct {
+		viewID   int
+		interval string
+		nextRun  time.Time
+	}
+	var due []dueSchedule
+	for rows.Next() {
+		var schedule dueSchedule
+		if err := rows.Scan(&schedule.viewID, &schedule.interval, &schedule.nextRun); err != nil {
+			log.Printf("[ViewSnapshots] Failed to scan schedule: %v", err)
+			continue
+		}
+		due = append(due, schedule)
+	}
+	rows.Close()
+
+	for _, schedule := range due {
+		view, err := s.GetCustomView(ctx, schedule.viewID)
+		if err != nil {
+			log.Printf("[ViewSnapshots] Skipping schedule of view %d: %v", schedule.viewID, err)
+			continue
+		}
+		if _, err := s.CaptureViewSnapshot(ctx, view, SnapshotTriggerSchedule); err != nil {
+			log.Printf("[ViewSnapshots] Scheduled snapshot of view %d failed: %v", schedule.viewID, err)
+			continue
+		}
+
+		// Runs missed while the service was down are not caught up
+		step, err := snapshotIntervalStep(schedule.interval)
+		if err != nil {
+			log.Printf("[ViewSnapshots] Invalid interval of view %d: %v", schedule.viewID, err)
+			continue
+		}
+		next := schedule.nextRun.UTC()
+		for !next.After(now) {
+			next = step(next)
+		}
+		if _, err := s.db.ExecContext(ctx, s.rebind("UPDATE view_snapshot_schedules SET next_run = ?, last_run = ? WHERE view_id = ?"),
+			next.Format(snapshotTimeLayout), now.Format(snapshotTimeLayout), schedule.viewID); err != nil {
+			log.Printf("[ViewSnapshots] Failed to advance schedule of view %d: %v", schedule.viewID, err)
+		}
+	}
+}
+
+// runViewSnapshotScheduler periodically takes the due scheduled snapshots
+func (s *Service) runViewSnapshotScheduler(ctx context.Context) {
+	log.Printf("[ViewSnapshots] Scheduler started - Check interval: %s", s.config.ViewSnapshotCheckInterval)
+	s.runDueViewSnapshots(ctx)
+
+	ticker := time.NewTicker(s.config.ViewSnapshotCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Printf("[ViewSnapshots] Scheduler stopped")
+			return
+		case <-ticker.C:
+			s.runDueViewSnapshots(ctx)
+		}
+	}
+}
+
+// viewSnapshotErrorStatus maps snapshot errors to HTTP status codes
+func viewSnapshotErrorStatus(err error) int {
+	switch {
+	case strings.Contains(err.Error(), "permission denied"):
+		return http.StatusForbidden
+	case strings.Contains(err.Error(), "not found"):
+		return http.StatusNotFound
+	case strings.HasPrefix(err.Error(), "invalid"):
+		return http.StatusBadRequest
+	}
+	return queryErrorStatus(err)
+}
+
+// snapshotRequest reads the view ID and the user of a snapshot request and checks that the
+// user can see the view (or manage it, when manage is set). It answers the request itself
+// when it returns false.
+func (s *Service) snapshotRequest(ctx context.Context, w http.ResponseWriter, r *http.Request, manage bool) (*CustomView, int, bool) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid view ID")
+		return nil, 0, false
+	}
+	userID, err := getUserIDFromRequest(r)
+	if err != nil {
+		respondError(w, viewerErrorStatus(err), err.Error())
+		return nil, 0, false
+	}
+
+	var view *CustomView
+	if manage {
+		view, err = s.ownedCustomView(ctx, id, *userID)
+	} else {
+		view, err = s.accessibleCustomView(ctx, id, *userID)
+	}
+	if err != nil {
+		respondError(w, viewSnapshotErrorStatus(err), err.Error())
+		return nil, 0, false
+	}
+	return view, *userID, true
+}
+
+// HTTP Handlers for view snapshots
+func (s *Service) handleListViewSnapshots(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	log.Printf("[ViewSnapshots] GET /api/custom_views/%s/snapshots/ - Request from %s", mux.Vars(r)["id"], r.RemoteAddr)
+	view, _, ok := s.snapshotRequest(ctx, w, r, false)
+	if !ok {
+		return
+	}
+
+	snapshots, err := s.ListViewSnapshots(ctx, *view.ID)
+	if err != nil {
+		respondError(w, queryErrorStatus(err), err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, ViewSnapshotListResponse{Count: len(snapshots), Results: snapshots})
+}
+
+func (s *Service) handleCreateViewSnapshot(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	log.Printf("[ViewSnapshots] POST /api/custom_views/%s/snapshots/ - Request from %s", mux.Vars(r)["id"], r.RemoteAddr)
+	view, _, ok := s.snapshotRequest(ctx, w, r, true)
+	if !ok {
+		return
+	}
+
+	snapshot, err := s.CaptureViewSnapshot(ctx, view, SnapshotTriggerManual)
+	if err != nil {
+		log.Printf("[ViewSnapshots] Error capturing snapshot of view %d: %v", *view.ID, err)
+		respondError(w, queryErrorStatus(err), err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, snapshot)
+}
+
+func (s *Service) handleGetViewSnapshot(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	vars := mux.Vars(r)
+	log.Printf("[ViewSnapshots] GET /api/custom_views/%s/snapshots/%s/ - Request from %s", vars["id"], vars["snapshot"], r.RemoteAddr)
+	snapshotID, err := strconv.Atoi(vars["snapshot"])
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid snapshot ID")
+		return
+	}
+	view, _, ok := s.snapshotRequest(ctx, w, r, false)
+	if !ok {
+		return
+	}
+
+	snapshot, err := s.GetViewSnapshot(ctx, *view.ID, snapshotID)
+	if err != nil {
+		respondError(w, viewSnapshotErrorStatus(err), err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, snapshot)
+}
+
+func (s *Service) handleDiffViewSnapshots(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	log.Printf("[ViewSnapshots] GET /api/custom_views/%s/snapshots/diff/ - Request from %s", mux.Vars(r)["id"], r.RemoteAddr)
+	fromID, fromErr := strconv.Atoi(r.URL.Query().Get("from"))
+	toID, toErr := strconv.Atoi(r.URL.Query().Get("to"))
+	if fromErr != nil || toErr != nil {
+		respondError(w, http.StatusBadRequest, "from and to must be snapshot IDs")
+		return
+	}
+	view, _, ok := s.snapshotRequest(ctx, w, r, false)
+	if !ok {
+		return
+	}
+
+	diff, err := s.DiffViewSnapshots(ctx, *view.ID, fromID, toID)
+	if err != nil {
+		respondError(w, viewSnapshotErrorStatus(err), err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, diff)
+}
+
+func (s *Service) handleGetViewSnapshotSchedule(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	log.Printf("[ViewSnapshots] GET /api/custom_views/%s/snapshots/schedule/ - Request from %s", mux.Vars(r)["id"], r.RemoteAddr)
+	view, _, ok := s.snapshotRequest(ctx, w, r, false)
+	if !ok {
+		return
+	}
+
+	schedule, err := s.GetViewSnapshotSchedule(ctx, *view.ID)
+	if err != nil {
+		respondError(w, viewSnapshotErrorStatus(err), err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, schedule)
+}
+
+func (s *Service) handleSetViewSnapshotSchedule(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	log.Printf("[ViewSnapshots] PUT /api/custom_views/%s/snapshots/schedule/ - Request from %s", mux.Vars(r)["id"], r.RemoteAddr)
+	var schedule ViewSnapshotSchedule
+	if err := json.NewDecoder(r.Body).Decode(&schedule); err != nil {
+		respondError(w, http.StatusBadRequest, fmt.Sprintf("Invalid request body: %v", err))
+		return
+	}
+	view, userID, ok := s.snapshotRequest(ctx, w, r, true)
+	if !ok {
+		return
+	}
+
+	saved, err := s.SetViewSnapshotSchedule(ctx, *view.ID, schedule, userID)
+	if err != nil {
+		log.Printf("[ViewSnapshots] Error scheduling snapshots of view %d: %v", *view.ID, err)
+		respondError(w, viewSnapshotErrorStatus(err), err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, saved)
+}
+
+func (s *Service) handleDeleteViewSnapshotSchedule(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	log.Printf("[ViewSnapshots] DELETE /api/custom_views/%s/snapshots/schedule/ - Request from %s", mux.Vars(r)["id"], r.RemoteAddr)
+	view, _, ok := s.snapshotRequest(ctx, w, r, true)
+	if !ok {
+		return
+	}
+
+	if err := s.DeleteViewSnapshotSchedule(ctx, *view.ID); err != nil {
+		respondError(w, viewSnapshotErrorStatus(err), err.Error())
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
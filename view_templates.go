@@ -0,0 +1,338 @@
+package main
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed view_templates/*.json
+var viewTemplatesFS embed.FS
+
+// ViewTemplateParameter is a value asked for when a view is created from a template.
+// Types are "integer", "integer_list", "string" and "year" (an integer defaulting to last year).
+type ViewTemplateParameter struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	Required    bool   `json:"required,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// ViewTemplate is a predefined custom view. Strings of the view may refer to parameters as
+// "{{name}}"; a filter rule whose value is just a list parameter becomes one rule per element.
+type ViewTemplate struct {
+	ID          string                  `json:"id"`
+	Name        string                  `json:"name"`
+	Description string                  `json:"description,omitempty"`
+	Parameters  []ViewTemplateParameter `json:"parameters"`
+	View        map[string]interface{}  `json:"view,omitempty"`
+}
+
+// ViewFromTemplateRequest is the request body for creating a view from a template
+type ViewFromTemplateRequest struct {
+	Template   string                 `json:"template"`
+	Parameters map[string]interface{} `json:"parameters"`
+	Name       string                 `json:"name,omitempty"` // Overrides the template's view name
+	IsGlobal   *bool                  `json:"is_global,omitempty"`
+}
+
+// loadViewTemplates reads the embedded templates, ordered by name
+func loadViewTemplates() ([]ViewTemplate, error) {
+	files, err := viewTemplatesFS.ReadDir("view_templates")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read view templates: %w", err)
+	}
+	templates := make([]ViewTemplate, 0, len(files))
+	for _, file := range files {
+		data, err := viewTemplatesFS.ReadFile(path.Join("view_templates", file.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read view template %s: %w", file.Name(), err)
+		}
+		var template ViewTemplate
+		if err := json.Unmarshal(data, &template); err != nil {
+			return nil, fmt.Errorf("failed to parse view template %s: %w", file.Name(), err)
+		}
+		templates = append(templates, template)
+	}
+	sort.Slice(templates, func(i, j int) bool { return templates[i].Name < templates[j].Name })
+	return templates, nil
+}
+
+// findViewTemplate returns the embedded template with the given ID
+func findViewTemplate(id string) (*ViewTemplate, error) {
+	templates, err := loadViewTemplates()
+	if err != nil {
+		return nil, err
+	}
+	for i := range templates {
+		if templates[i].ID == id {
+			return &templates[i], nil
+		}
+	}
+	return nil, fmt.Errorf("view template %q not found", id)
+}
+
+// templateInteger reads an integer parameter value given as a JSON number or a string
+func templateInteger(value interface{}) (int, bool) {
+	switch v := value.(type) {
+	case float64:
+		if v == float64(int(v)) {
+			return int(v), true
+		}
+	case string:
+		if n, err := strconv.Atoi(strings.TrimSpace(v)); err == nil {
+			return n, true
+		}
+	}
+	return 0, false
+}
+
+// resolveParameters checks the given parameter values against the template and returns
+// them as strings (lists as string slices), with defaults filled in
+func (t *ViewTemplate) resolveParameters(values map[string]interface{}) (map[string]string, map[string][]string, []ValidationError) {
+	scalars := make(map[string]string)
+	lists := make(map[string][]string)
+	var problems []ValidationError
+	invalid := func(name string, format string, args ...interface{}) {
+		problems = append(problems, ValidationError{Field: "parameters." + name, Message: fmt.Sprintf(format, args...)})
+	}
+
+	known := make(map[string]bool, len(t.Parameters))
+	for _, parameter := range t.Parameters {
+		known[parameter.Name] = true
+		value, given := values[parameter.Name]
+		if !given || value == nil {
+			switch {
+			case parameter.Type == "year":
+				scalars[parameter.Name] = strconv.Itoa(time.Now().Year() - 1)
+			case parameter.Required:
+				invalid(parameter.Name, "%s is required", parameter.Name)
+			case parameter.Type == "integer_list":
+				lists[parameter.Name] = nil
+			default:
+				scalars[parameter.Name] = ""
+			}
+			continue
+		}
+
+		switch parameter.Type {
+		case "integer":
+			n, ok := templateInteger(value)
+			if !ok {
+				invalid(parameter.Name, "%s must be an integer", parameter.Name)
+				continue
+			}
+			scalars[parameter.Name] = strconv.Itoa(n)
+		case "year":
+			n, ok := templateInteger(value)
+			if !ok || n < 1900 || n > 9999 {
+				invalid(parameter.Name, "%s must be a year", parameter.Name)
+				continue
+			}
+			scalars[parameter.Name] = strconv.Itoa(n)
+		case "integer_list":
+			items, ok := value.([]interface{})
+			if !ok {
+				invalid(parameter.Name, "%s must be a list of integers", parameter.Name)
+				continue
+			}
+			for _, item := range items {
+				n, ok := templateInteger(item)
+				if !ok {
+					invalid(parameter.Name, "%s must be a list of integers", parameter.Name)
+					break
+				}
+				lists[parameter.Name] = append(lists[parameter.Name], strconv.Itoa(n))
+			}
+		default:
+			text, ok := value.(string)
+			if !ok {
+				invalid(parameter.Name, "%s must be a string", parameter.Name)
+				continue
+			}
+			scalars[parameter.Name] = text
+		}
+	}
+
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if !known[name] {
+			invalid(name, "unknown parameter %s", name)
+		}
+	}
+	return scalars, lists, problems
+}
+
+// substituteTemplateValue replaces the "{{name}}" placeholders in the strings of a decoded
+// JSON value
+func substituteTemplateValue(value interface{}, scalars map[string]string) interface{} {
+	switch v := value.(type) {
+	case string:
+		for name, replacement := range scalars {
+			v = strings.ReplaceAll(v, "{{"+name+"}}", replacement)
+		}
+		return v
+	case []interface{}:
+		result := make([]interface{}, len(v))
+		for i, item := range v {
+			result[i] = substituteTemplateValue(item, scalars)
+		}
+		return result
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			result[key] = substituteTemplateValue(item, scalars)
+		}
+		return result
+	}
+	return value
+}
+
+// instantiate builds the view of a template from resolved parameters
+func (t *ViewTemplate) instantiate(scalars map[string]string, lists map[string][]string) (CustomView, error) {
+	view := make(map[string]interface{}, len(t.View))
+	for key, value := range t.View {
+		view[key] = value
+	}
+
+	// Rules whose value is a list parameter are repeated for each element of the list
+	if rules, ok := view["filter_rules"].([]interface{}); ok {
+		expanded := []interface{}{}
+		for _, rule := range rules {
+			ruleObject, ok := rule.(map[string]interface{})
+			value, _ := ruleObject["value"].(string)
+			name := strings.TrimSuffix(strings.TrimPrefix(value, "{{"), "}}")
+			list, isList := lists[name]
+			if !ok || !isList || value != "{{"+name+"}}" {
+				expanded = append(expanded, rule)
+				continue
+			}
+			for _, item := range list {
+				copied := make(map[string]interface{}, len(ruleObject))
+				for key, field := range ruleObject {
+					copied[key] = field
+				}
+				copied["value"] = item
+				expanded = append(expanded, copied)
+			}
+		}
+		view["filter_rules"] = expanded
+	}
+
+	data, err := json.Marshal(substituteTemplateValue(view, scalars))
+	if err != nil {
+		return CustomView{}, fmt.Errorf("failed to encode view template %s: %w", t.ID, err)
+	}
+	var result CustomView
+	if err := json.Unmarshal(data, &result); err != nil {
+		return CustomView{}, fmt.Errorf("failed to decode view template %s: %w", t.ID, err)
+	}
+	return result, nil
+}
+
+// CreateViewFromTemplate creates a custom view for the user from an embedded template
+func (s *Service) CreateViewFromTemplate(ctx context.Context, req ViewFromTemplateRequest, userID int, username string) (*CustomView, error) {
+	template, err := findViewTemplate(req.Template)
+	if err != nil {
+		return nil, err
+	}
+
+	scalars, lists, problems := template.resolveParameters(req.Parameters)
+	if len(problems) > 0 {
+		return nil, &ValidationFailedError{Problems: problems}
+	}
+	view, err := template.instantiate(scalars, lists)
+	if err != nil {
+		return nil, err
+	}
+	if req.Name != "" {
+		view.Name = req.Name
+	}
+	view.IsGlobal = req.IsGlobal
+
+	problems, err = s.ValidateCustomView(ctx, view)
+	if err != nil {
+		return nil, err
+	}
+	if len(problems) > 0 {
+		return nil, &ValidationFailedError{Problems: problems}
+	}
+
+	log.Printf("[CustomViews] Creating view from template %s", template.ID)
+	return s.CreateCustomView(ctx, view, userID, username)
+}
+
+// HTTP Handlers for view templates
+func (s *Service) handleListViewTemplates(w http.ResponseWriter, r *http.Request) {
+	log.Printf("[CustomViews] GET /api/custom_views/templates/ - Request from %s", r.RemoteAddr)
+
+	templates, err := loadViewTemplates()
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	// The view definitions are an implementation detail of each template
+	for i := range templates {
+		templates[i].View = nil
+	}
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{"count": len(templates), "results": templates})
+}
+
+func (s *Service) handleCreateViewFromTemplate(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	log.Printf("[CustomViews] POST /api/custom_views/from-template/ - Request from %s", r.RemoteAddr)
+
+	var req ViewFromTemplateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, fmt.Sprintf("Invalid request body: %v", err))
+		return
+	}
+	if req.Template == "" {
+		respondError(w, http.StatusBadRequest, "template is required")
+		return
+	}
+
+	userID, err := getUserIDFromRequest(r)
+	if err != nil {
+		respondError(w, viewerErrorStatus(err), err.Error())
+		return
+	}
+	username := getUsernameFromRequest(r)
+
+	view, err := s.CreateViewFromTemplate(ctx, req, *userID, *username)
+	if err != nil {
+		log.Printf("[CustomViews] Error creating view from template %s: %v", req.Template, err)
+		var invalid *ValidationFailedError
+		if errors.As(err, &invalid) {
+			respondValidationErrors(w, "Invalid template parameters", invalid.Problems)
+			return
+		}
+		if respondViewQuotaExceeded(w, err) {
+			return
+		}
+		if strings.Contains(err.Error(), "not found") {
+			respondError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		respondError(w, queryErrorStatus(err), err.Error())
+		return
+	}
+
+	respondCustomView(w, r, http.StatusCreated, view)
+}
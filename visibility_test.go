@@ -0,0 +1,107 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+func TestViewerFromRequest(t *testing.T) {
+	tests := []struct {
+		header string
+		want   int
+		status int
+	}{
+		{"7", 7, 0},
+		{"", 0, http.StatusUnauthorized},
+		{"abc", 0, http.StatusBadRequest},
+		{"0", 0, http.StatusBadRequest},
+		{"-3", 0, http.StatusBadRequest},
+		{"7; DROP TABLE", 0, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if tt.header != "" {
+			req.Header.Set("X-User-ID", tt.header)
+		}
+		got, err := viewerFromRequest(req)
+		if tt.status == 0 {
+			if err != nil || got != tt.want {
+				t.Errorf("header %q: got %d, %v, want %d", tt.header, got, err, tt.want)
+			}
+			continue
+		}
+		if err == nil {
+			t.Errorf("header %q: got viewer %d, want an error", tt.header, got)
+		} else if status := viewerErrorStatus(err); status != tt.status {
+			t.Errorf("header %q: status %d, want %d", tt.header, status, tt.status)
+		}
+	}
+}
+
+func TestScopedHandlersRejectRequestsWithoutViewer(t *testing.T) {
+	s := newTestService(t, nil)
+	mustExec(t, s,
+		`INSERT INTO auth_user (id, username, is_superuser) VALUES (1, 'admin', 1), (2, 'user', 0)`,
+		`INSERT INTO documents_document (id, title, owner_id, correspondent_id) VALUES (1, 'shared', NULL, 5), (2, 'private', 1, 6)`,
+	)
+
+	get := func(userHeader string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/builtin-filter-values/correspondent/", nil)
+		req = mux.SetURLVars(req, map[string]string{"filterType": "correspondent"})
+		if userHeader != "" {
+			req.Header.Set("X-User-ID", userHeader)
+		}
+		rec := httptest.NewRecorder()
+		s.handleGetBuiltinFilterValues(rec, req)
+		return rec
+	}
+
+	if rec := get(""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("without X-User-ID: status %d, want 401", rec.Code)
+	}
+	if rec := get("nobody"); rec.Code != http.StatusBadRequest {
+		t.Errorf("with a malformed X-User-ID: status %d, want 400", rec.Code)
+	}
+	if rec := get("2"); rec.Code != http.StatusOK {
+		t.Errorf("with X-User-ID: status %d, want 200: %s", rec.Code, rec.Body)
+	}
+}
+
+func TestUserHandlersHaveNoDefaultUser(t *testing.T) {
+	s := newTestService(t, nil)
+	mustExec(t, s, `INSERT INTO auth_user (id, username, is_superuser) VALUES (1, 'admin', 1)`)
+
+	handlers := map[string]http.HandlerFunc{
+		"webhooks":          s.handleListWebhooks,
+		"column presets":    s.handleListColumnPresets,
+		"filter presets":    s.handleListFilterPresets,
+		"preferences":       s.handleListUserPreferences,
+		"saved searches":    s.handleListSavedSearches,
+		"view usage":        s.handleGetViewUsage,
+		"view rules":        s.handleListViewRules,
+		"view snapshots":    s.handleListViewSnapshots,
+		"ungrouped tags":    s.handleListUngroupedTags,
+		"activity feed":     s.handleGetActivityFeed,
+		"custom views":      s.handleListCustomViews,
+		"own custom views":  s.handleListMyCustomViews,
+		"share links":       s.handleListShareLinks,
+		"scheduled reports": s.handleListScheduledReports,
+	}
+	for name, handler := range handlers {
+		for header, want := range map[string]int{"": http.StatusUnauthorized, "admin": http.StatusBadRequest} {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req = mux.SetURLVars(req, map[string]string{"id": "1"})
+			if header != "" {
+				req.Header.Set("X-User-ID", header)
+			}
+			rec := httptest.NewRecorder()
+			handler(rec, req)
+			if rec.Code != want {
+				t.Errorf("%s with X-User-ID %q: status %d, want %d", name, header, rec.Code, want)
+			}
+		}
+	}
+}
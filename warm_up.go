@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// warmUpConcurrency bounds the fields warmed at the same time. It is separate from the
+// query pool: the queries of a field take pool slots themselves, so a field holding a
+// slot while waiting for more could starve the pool.
+const warmUpConcurrency = 4
+
+// viewFieldIDs returns the custom fields a view refers to in its columns, filters and board
+func viewFieldIDs(view *CustomView) []int {
+	// Against an empty set of existing fields every reference is reported
+	return danglingFieldIDs(danglingFieldRefs(view, nil))
+}
+
+// globalViewFieldIDs returns the distinct custom fields referenced by global views
+func (s *Service) globalViewFieldIDs(ctx context.Context) ([]int, int, error) {
+	globalTrue := "is_global = 1"
+	if s.config.DBEngine == "postgresql" || s.config.DBEngine == "postgres" {
+		globalTrue = "is_global = true"
+	}
+	rows, err := s.db.QueryContext(ctx, "SELECT id FROM custom_views WHERE deleted_at IS NULL AND "+globalTrue)
+	if err != nil {
+		return nil, 0, err
+	}
+	var viewIDs []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err == nil {
+			viewIDs = append(viewIDs, id)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	seen := make(map[int]bool)
+	fieldIDs := []int{}
+	for _, id := range viewIDs {
+		view, err := s.GetCustomView(ctx, id)
+		if err != nil {
+			continue
+		}
+		for _, fieldID := range viewFieldIDs(view) {
+			if !seen[fieldID] {
+				seen[fieldID] = true
+				fieldIDs = append(fieldIDs, fieldID)
+			}
+		}
+	}
+	return fieldIDs, len(viewIDs), nil
+}
+
+// warmUpField loads the metadata (with the select option labels), the value list and the
+// unfiltered facet counts of a field into the caches, as requested by a client without
+// sort parameters. Values and counts served from a summary need no warm-up.
+func (s *Service) warmUpField(ctx context.Context, fieldID int) error {
+	if s.config.QueryTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.config.QueryTimeout)
+		defer cancel()
+	}
+
+	if _, err := s.getFieldMeta(ctx, fieldID); err != nil {
+		return err
+	}
+	if _, ok := s.getFieldValueSummary(ctx, fieldID); !ok {
+		if _, _, err := s.getFieldValuesCached(ctx, fieldID, "", "", false, false, false); err != nil {
+			return err
+		}
+	}
+	if _, ok := s.getFacetSummary(ctx, fieldID); !ok {
+		if _, _, err := s.getValueCountsCached(ctx, fieldID, "", "count", "desc", false, false, false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// warmUp preloads the caches for the custom fields of global views after startup, so the
+// first requests after a deploy do not pay for cold caches. At most warmUpConcurrency
+// fields are warmed in parallel.
+func (s *Service) warmUp(ctx context.Context) {
+	started := time.Now()
+	fieldIDs, viewCount, err := s.globalViewFieldIDs(ctx)
+	if err != nil {
+		log.Printf("[WarmUp] Failed to load global views: %v", err)
+		return
+	}
+	log.Printf("[WarmUp] Warming %d custom fields of %d global views", len(fieldIDs), viewCount)
+
+	var failed atomic.Int32
+	var group errgroup.Group
+	group.SetLimit(warmUpConcurrency)
+	for _, fieldID := range fieldIDs {
+		fieldID := fieldID
+		group.Go(func() error {
+			if err := s.warmUpField(ctx, fieldID); err != nil {
+				// A deleted field must not stop the warm-up of the others
+				log.Printf("[WarmUp] Failed to warm field %d: %v", fieldID, err)
+				failed.Add(1)
+			}
+			return nil
+		})
+	}
+	group.Wait()
+	if err := ctx.Err(); err != nil {
+		log.Printf("[WarmUp] Stopped: %v", err)
+		return
+	}
+	log.Printf("[WarmUp] Done in %v (%d failed)", time.Since(started).Round(time.Millisecond), failed.Load())
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWarmUpWithMoreFieldsThanQuerySlots(t *testing.T) {
+	s := newTestService(t, func(config *Config) {
+		config.QueryConcurrency = 2
+	})
+
+	const fields = 10
+	columns := []string{}
+	for id := 1; id <= fields; id++ {
+		columns = append(columns, fmt.Sprint(id))
+		mustExec(t, s,
+			fmt.Sprintf(`INSERT INTO documents_customfield VALUES (%d, 'Field %d', 'string', NULL)`, id, id),
+			fmt.Sprintf(`INSERT INTO documents_document (id, title) VALUES (%d, 'Document %d')`, id, id),
+			fmt.Sprintf(`INSERT INTO documents_customfieldinstance (document_id, field_id, value_text) VALUES (%d, %d, 'value')`, id, id),
+		)
+	}
+	mustExec(t, s, fmt.Sprintf(`INSERT INTO custom_views (name, column_order, is_global) VALUES ('Global', '[%s]', 1)`,
+		strings.Join(columns, ", ")))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	done := make(chan struct{})
+	go func() {
+		s.warmUp(ctx)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		t.Fatal("warm-up did not finish; the query pool is starved")
+	}
+
+	for id := 1; id <= fields; id++ {
+		if _, hit, err := s.getValueCountsCached(ctx, id, "", "count", "desc", false, false, false); err != nil || !hit {
+			t.Errorf("field %d: counts not warmed (hit %v, err %v)", id, hit, err)
+		}
+		if _, hit, err := s.getFieldValuesCached(ctx, id, "", "", false, false, false); err != nil || !hit {
+			t.Errorf("field %d: values not warmed (hit %v, err %v)", id, hit, err)
+		}
+	}
+}
+
+func TestWarmUpSkipsPersonalViews(t *testing.T) {
+	s := newTestService(t, nil)
+	mustExec(t, s,
+		`INSERT INTO documents_customfield VALUES (1, 'Global', 'string', NULL), (2, 'Personal', 'string', NULL)`,
+		`INSERT INTO custom_views (name, column_order, is_global) VALUES ('Global', '[1, "title"]', 1), ('Mine', '[2]', 0)`,
+	)
+
+	fieldIDs, viewCount, err := s.globalViewFieldIDs(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if viewCount != 1 || len(fieldIDs) != 1 || fieldIDs[0] != 1 {
+		t.Errorf("got fields %v of %d views, want [1] of 1 view", fieldIDs, viewCount)
+	}
+}
@@ -0,0 +1,655 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/gorilla/mux"
+	"golang.org/x/sync/semaphore"
+)
+
+// Custom view events webhooks can subscribe to
+const (
+	EventViewCreated  = "view.created"
+	EventViewUpdated  = "view.updated"
+	EventViewDeleted  = "view.deleted"
+	EventViewRestored = "view.restored"
+)
+
+// webhookEvents are the events webhooks can subscribe to
+var webhookEvents = map[string]bool{
+	EventViewCreated:  true,
+	EventViewUpdated:  true,
+	EventViewDeleted:  true,
+	EventViewRestored: true,
+}
+
+// webhookColumns are the columns read by scanWebhook
+const webhookColumns = "id, url, secret, events, is_active, owner_id, username, created, modified"
+
+// WebhookPayload is the JSON body posted to webhooks
+type WebhookPayload struct {
+	ID        string      `json:"id"` // Delivery ID, the same for all attempts
+	Event     string      `json:"event"`
+	Timestamp string      `json:"timestamp"`
+	View      *CustomView `json:"view"`
+}
+
+// scanWebhook scans a Webhook from a database row or rows
+func (s *Service) scanWebhook(scanner interface{ Scan(...interface{}) error }) (Webhook, error) {
+	var webhook Webhook
+	var id int
+	var ownerID sql.NullInt64
+	var secret, eventsJSON, username sql.NullString
+	var created, modified dbTimestamp
+	var isActive sql.NullBool
+
+	if err := scanner.Scan(&id, &webhook.URL, &secret, &eventsJSON, &isActive, &ownerID, &username, &created, &modified); err != nil {
+		return webhook, err
+	}
+
+	webhook.ID = &id
+	if secret.Valid && secret.String != "" {
+		webhook.signingSecret = secret.String
+		webhook.HasSecret = true
+	}
+	webhook.Events = []string{}
+	if eventsJSON.Valid {
+		json.Unmarshal([]byte(eventsJSON.String), &webhook.Events)
+	}
+	if isActive.Valid {
+		webhook.IsActive = &isActive.Bool
+	}
+	if ownerID.Valid {
+		owner := int(ownerID.Int64)
+		webhook.OwnerID = &owner
+	}
+	if username.Valid {
+		webhook.Username = &username.String
+	}
+	webhook.Created = s.formatTimestamp(created)
+	webhook.Modified = s.formatTimestamp(modified)
+
+	return webhook, nil
+}
+
+// validateWebhook checks the URL and the events of a webhook
+func validateWebhook(webhook Webhook) error {
+	parsed, err := url.Parse(webhook.URL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("invalid webhook: url must be an absolute http or https URL")
+	}
+	for _, event := range webhook.Events {
+		if !webhookEvents[event] {
+			return fmt.Errorf("invalid webhook: unknown event %q", event)
+		}
+	}
+	return nil
+}
+
+// ListWebhooks retrieves the webhooks of a user
+func (s *Service) ListWebhooks(ctx context.Context, userID int) ([]Webhook, error) {
+	rows, err := s.db.QueryContext(ctx, s.rebind("SELECT "+webhookColumns+" FROM webhooks WHERE owner_id = ? ORDER BY id"), userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query webhooks: %w", err)
+	}
+	defer rows.Close()
+
+	webhooks := []Webhook{}
+	for rows.Next() {
+		webhook, err := s.scanWebhook(rows)
+		if err != nil {
+			continue
+		}
+		webhooks = append(webhooks, webhook)
+	}
+
+	return webhooks, nil
+}
+
+// GetWebhook retrieves a webhook of the user; webhooks of other users are not found
+func (s *Service) GetWebhook(ctx context.Context, id int, userID int) (*Webhook, error) {
+	webhook, err := s.scanWebhook(s.db.QueryRowContext(ctx, s.rebind("SELECT "+webhookColumns+" FROM webhooks WHERE id = ?"), id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("webhook with id %d not found", id)
+		}
+		return nil, fmt.Errorf("failed to query webhook: %w", err)
+	}
+	if webhook.OwnerID != nil && *webhook.OwnerID != userID {
+		return nil, fmt.Errorf("webhook with id %d not found", id)
+	}
+	return &webhook, nil
+}
+
+// CreateWebhook creates a webhook for the user
+func (s *Service) CreateWebhook(ctx context.Context, webhook Webhook, userID int, username string) (*Webhook, error) {
+	log.Printf("[Webhooks] CreateWebhook - URL: %s, UserID: %d", webhook.URL, userID)
+	if webhook.Events == nil {
+		webhook.Events = []string{}
+	}
+	if err := validateWebhook(webhook); err != nil {
+		return nil, err
+	}
+	eventsJSON, _ := json.Marshal(webhook.Events)
+	isActive := webhook.IsActive == nil || *webhook.IsActive
+
+	var newID int
+	if s.config.DBEngine == "postgresql" || s.config.DBEngine == "postgres" {
+		err := s.db.QueryRowContext(ctx, `
+			INSERT INTO webhooks (url, secret, events, is_active, owner_id, username)
+			VALUES ($1, $2, $3::jsonb, $4, $5, $6)
+			RETURNING id
+		`, webhook.URL, webhook.Secret, string(eventsJSON), isActive, userID, username).Scan(&newID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create webhook: %w", err)
+		}
+	} else {
+		result, err := s.db.ExecContext(ctx, `
+			INSERT INTO webhooks (url, secret, events, is_active, owner_id, username)
+			VALUES (?, ?, ?, ?, ?, ?)
+		`, webhook.URL, webhook.Secret, string(eventsJSON), isActive, userID, username)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create webhook: %w", err)
+		}
+		lastID, err := result.LastInsertId()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get last insert ID: %w", err)
+		}
+		newID = int(lastID)
+	}
+
+	return s.GetWebhook(ctx, newID, userID)
+}
+
+// UpdateWebhook updates the provided fields of a webhook of the user. An empty secret
+// removes the signature.
+func (s *Service) UpdateWebhook(ctx context.Context, id int, updates Webhook, userID int) (*Webhook, error) {
+	log.Printf("[Webhooks] UpdateWebhook - ID: %d, UserID: %d", id, userID)
+	existing, err := s.GetWebhook(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	merged := *existing
+	if updates.URL != "" {
+		merged.URL = updates.URL
+	}
+	if updates.Events != nil {
+		merged.Events = updates.Events
+	}
+	if err := validateWebhook(merged); err != nil {
+		return nil, err
+	}
+
+	usePostgres := s.config.DBEngine == "postgresql" || s.config.DBEngine == "postgres"
+	setParts := []string{}
+	args := []interface{}{}
+	addSet := func(column string, value interface{}, cast string) {
+		args = append(args, value)
+		if usePostgres {
+			setParts = append(setParts, fmt.Sprintf("%s = $%d%s", column, len(args), cast))
+		} else {
+			setParts = append(setParts, column+" = ?")
+		}
+	}
+
+	if updates.URL != "" {
+		addSet("url", updates.URL, "")
+	}
+	if updates.Secret != nil {
+		addSet("secret", *updates.Secret, "")
+	}
+	if updates.Events != nil {
+		eventsJSON, _ := json.Marshal(updates.Events)
+		addSet("events", string(eventsJSON), "::jsonb")
+	}
+	if updates.IsActive != nil {
+		addSet("is_active", *updates.IsActive, "")
+	}
+	setParts = append(setParts, "modified = CURRENT_TIMESTAMP")
+
+	args = append(args, id)
+	updateQuery := fmt.Sprintf("UPDATE webhooks SET %s WHERE id = ?", strings.Join(setParts, ", "))
+	if usePostgres {
+		updateQuery = fmt.Sprintf("UPDATE webhooks SET %s WHERE id = $%d", strings.Join(setParts, ", "), len(args))
+	}
+
+	if _, err := s.db.ExecContext(ctx, updateQuery, args...); err != nil {
+		return nil, fmt.Errorf("failed to update webhook: %w", err)
+	}
+
+	return s.GetWebhook(ctx, id, userID)
+}
+
+// DeleteWebhook deletes a webhook of the user
+func (s *Service) DeleteWebhook(ctx context.Context, id int, userID int) error {
+	log.Printf("[Webhooks] DeleteWebhook - ID: %d, UserID: %d", id, userID)
+	if _, err := s.GetWebhook(ctx, id, userID); err != nil {
+		return err
+	}
+	if _, err := s.db.ExecContext(ctx, s.rebind("DELETE FROM webhooks WHERE id = ?"), id); err != nil {
+		return fmt.Errorf("failed to delete webhook: %w", err)
+	}
+	return nil
+}
+
+// viewEventQueueSize bounds the view events waiting for runWebhookDispatcher; events
+// emitted while it is full are dropped
+const viewEventQueueSize = 256
+
+// viewEvent is a view change queued for the activity feed and the webhooks
+type viewEvent struct {
+	event string
+	view  CustomView
+}
+
+// emitViewEvent queues a view change for runWebhookDispatcher, which records it in the
+// activity feed and notifies the active webhooks subscribed to event about it. It never
+// blocks or fails the change itself.
+func (s *Service) emitViewEvent(event string, view *CustomView) {
+	if view == nil {
+		return
+	}
+	select {
+	case s.viewEvents <- viewEvent{event: event, view: *view}:
+	default:
+		log.Printf("[Webhooks] Event queue full, dropping %s of view %q", event, view.Name)
+	}
+}
+
+// runWebhookDispatcher handles the queued view events until ctx is cancelled. At most
+// WEBHOOK_CONCURRENCY deliveries run at a time; further events wait in the queue meanwhile.
+func (s *Service) runWebhookDispatcher(ctx context.Context) {
+	deliveries := semaphore.NewWeighted(int64(max(s.config.WebhookConcurrency, 1)))
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case queued := <-s.viewEvents:
+			s.dispatchViewEvent(ctx, deliveries, queued.event, &queued.view)
+		}
+	}
+}
+
+// dispatchViewEvent records a view event in the activity feed and starts its deliveries.
+// Only users who can see the view are told: its owner, and everyone for global views.
+func (s *Service) dispatchViewEvent(ctx context.Context, deliveries *semaphore.Weighted, event string, view *CustomView) {
+	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	isGlobal := view.IsGlobal != nil && *view.IsGlobal
+	audience := view.OwnerID
+	if isGlobal {
+		audience = nil
+	}
+	data := map[string]interface{}{}
+	if view.ID != nil {
+		data["view_id"] = *view.ID
+	}
+	if _, err := s.recordActivity(queryCtx, event, nil, audience, view.Name, data); err != nil {
+		log.Printf("[Webhooks] Failed to record %s in the activity feed: %v", event, err)
+	}
+
+	rows, err := s.db.QueryContext(queryCtx, "SELECT "+webhookColumns+" FROM webhooks")
+	if err != nil {
+		log.Printf("[Webhooks] Failed to load webhooks for %s: %v", event, err)
+		return
+	}
+	var webhooks []Webhook
+	for rows.Next() {
+		webhook, err := s.scanWebhook(rows)
+		if err != nil {
+			continue
+		}
+		webhooks = append(webhooks, webhook)
+	}
+	rows.Close()
+
+	for _, webhook := range webhooks {
+		if webhook.IsActive != nil && !*webhook.IsActive {
+			continue
+		}
+		if !isGlobal && (webhook.OwnerID == nil || view.OwnerID == nil || *webhook.OwnerID != *view.OwnerID) {
+			continue
+		}
+		if !webhookSubscribes(webhook, event) {
+			continue
+		}
+		if err := deliveries.Acquire(ctx, 1); err != nil {
+			return
+		}
+		go func(webhook Webhook) {
+			defer deliveries.Release(1)
+			s.deliverWebhook(ctx, webhook, event, view)
+		}(webhook)
+	}
+}
+
+// webhookSubscribes reports whether a webhook wants an event; no events means all
+func webhookSubscribes(webhook Webhook, event string) bool {
+	if len(webhook.Events) == 0 {
+		return true
+	}
+	for _, subscribed := range webhook.Events {
+		if subscribed == event {
+			return true
+		}
+	}
+	return false
+}
+
+// signWebhookPayload returns the hex encoded HMAC-SHA256 of a payload
+func signWebhookPayload(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// signingKey returns the secret payloads are signed with: that of a stored webhook, or the
+// one of a receiver built for a delivery (reminders, saved searches, rules)
+func (webhook Webhook) signingKey() string {
+	if webhook.signingSecret != "" {
+		return webhook.signingSecret
+	}
+	if webhook.Secret != nil {
+		return *webhook.Secret
+	}
+	return ""
+}
+
+// newWebhookClient returns the client delivering webhooks. Unless allowPrivate is set it
+// only connects to public addresses: the address of every connection, redirects included,
+// is checked after the host was resolved, so neither the URL nor its DNS records can point
+// deliveries at the service itself or the network it runs in. Deliveries do not go through
+// a proxy, which would hide the address.
+func newWebhookClient(timeout time.Duration, allowPrivate bool) *http.Client {
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	if !allowPrivate {
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !publicAddress(ip) {
+				return fmt.Errorf("webhook address %s is not public (set WEBHOOK_ALLOW_PRIVATE to allow it)", host)
+			}
+			return nil
+		}
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
+
+// nonPublicNetworks are the networks beyond those of the net.IP predicates that webhooks
+// must not reach: "this network" and carrier-grade NAT
+var nonPublicNetworks = []*net.IPNet{
+	{IP: net.IPv4(0, 0, 0, 0), Mask: net.CIDRMask(8, 32)},
+	{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)},
+}
+
+// IPv6 prefixes embedding an IPv4 address: NAT64 (in the last 4 bytes) and 6to4 (in bytes 2-5)
+var (
+	nat64Network     = &net.IPNet{IP: net.ParseIP("64:ff9b::"), Mask: net.CIDRMask(96, 128)}
+	sixToFourNetwork = &net.IPNet{IP: net.ParseIP("2002::"), Mask: net.CIDRMask(16, 128)}
+)
+
+// publicAddress reports whether an IP address is neither loopback, private, link-local,
+// multicast nor unspecified, including the IPv4 address embedded by NAT64 and 6to4
+func publicAddress(ip net.IP) bool {
+	if ip.To4() == nil && len(ip) == net.IPv6len {
+		switch {
+		case nat64Network.Contains(ip):
+			return publicAddress(ip[12:16])
+		case sixToFourNetwork.Contains(ip):
+			return publicAddress(ip[2:6])
+		}
+	}
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() {
+		return false
+	}
+	for _, network := range nonPublicNetworks {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// deliverWebhook posts a view event to a webhook
+func (s *Service) deliverWebhook(ctx context.Context, webhook Webhook, event string, view *CustomView) {
+	deliveryID := make([]byte, 8)
+	rand.Read(deliveryID)
+	payload, err := json.Marshal(WebhookPayload{
+		ID:        hex.EncodeToString(deliveryID),
+		Event:     event,
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+		View:      view,
+	})
+	if err != nil {
+		log.Printf("[Webhooks] Failed to encode %s payload: %v", event, err)
+		return
+	}
+	s.postWebhookWithRetry(ctx, fmt.Sprintf("webhook %d", *webhook.ID), webhook, event, payload)
+}
+
+// postWebhookWithRetry posts a payload, retrying with exponential backoff until a 2xx
+// response, WebhookMaxAttempts attempts or the cancellation of ctx. label names the
+// receiver in the logs.
+func (s *Service) postWebhookWithRetry(ctx context.Context, label string, webhook Webhook, event string, payload []byte) error {
+	backoff := s.config.WebhookRetryBackoff
+	var err error
+	for attempt := 1; attempt <= s.config.WebhookMaxAttempts; attempt++ {
+		if err = s.postWebhook(ctx, webhook, event, payload); err == nil {
+			log.Printf("[Webhooks] Delivered %s to %s (attempt %d)", event, label, attempt)
+			return nil
+		}
+		log.Printf("[Webhooks] Delivery of %s to %s failed (attempt %d/%d): %v", event, label, attempt, s.config.WebhookMaxAttempts, err)
+		if attempt < s.config.WebhookMaxAttempts {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(backoff):
+			}
+			backoff *= 2
+		}
+	}
+	return err
+}
+
+// postWebhook makes a single delivery attempt
+func (s *Service) postWebhook(ctx context.Context, webhook Webhook, event string, payload []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Webhook-Event", event)
+	if secret := webhook.signingKey(); secret != "" {
+		req.Header.Set("X-Webhook-Signature", "sha256="+signWebhookPayload(secret, payload))
+	}
+
+	resp, err := s.webhookClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// webhookErrorStatus maps webhook errors to HTTP statuses
+func webhookErrorStatus(err error) int {
+	switch {
+	case strings.Contains(err.Error(), "not found"):
+		return http.StatusNotFound
+	case strings.Contains(err.Error(), "invalid webhook"):
+		return http.StatusBadRequest
+	}
+	return queryErrorStatus(err)
+}
+
+// respondWebhook sends a webhook without its secret
+func respondWebhook(w http.ResponseWriter, status int, webhook *Webhook) {
+	webhook.Secret = nil
+	respondJSON(w, status, webhook)
+}
+
+// HTTP Handlers for webhooks
+func (s *Service) handleListWebhooks(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	log.Printf("[Webhooks] GET /api/webhooks/ - Request from %s", r.RemoteAddr)
+
+	userID, err := getUserIDFromRequest(r)
+	if err != nil {
+		respondError(w, viewerErrorStatus(err), err.Error())
+		return
+	}
+
+	webhooks, err := s.ListWebhooks(ctx, *userID)
+	if err != nil {
+		respondError(w, queryErrorStatus(err), err.Error())
+		return
+	}
+	for i := range webhooks {
+		webhooks[i].Secret = nil
+	}
+
+	respondJSON(w, http.StatusOK, WebhookListResponse{Count: len(webhooks), Results: webhooks})
+}
+
+func (s *Service) handleGetWebhook(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid webhook ID")
+		return
+	}
+	log.Printf("[Webhooks] GET /api/webhooks/%d/ - Request from %s", id, r.RemoteAddr)
+
+	userID, err := getUserIDFromRequest(r)
+	if err != nil {
+		respondError(w, viewerErrorStatus(err), err.Error())
+		return
+	}
+
+	webhook, err := s.GetWebhook(ctx, id, *userID)
+	if err != nil {
+		respondError(w, webhookErrorStatus(err), err.Error())
+		return
+	}
+
+	respondWebhook(w, http.StatusOK, webhook)
+}
+
+func (s *Service) handleCreateWebhook(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	log.Printf("[Webhooks] POST /api/webhooks/ - Request from %s", r.RemoteAddr)
+
+	var webhook Webhook
+	if err := json.NewDecoder(r.Body).Decode(&webhook); err != nil {
+		respondError(w, http.StatusBadRequest, fmt.Sprintf("Invalid request body: %v", err))
+		return
+	}
+
+	userID, err := getUserIDFromRequest(r)
+	if err != nil {
+		respondError(w, viewerErrorStatus(err), err.Error())
+		return
+	}
+	username := getUsernameFromRequest(r)
+
+	created, err := s.CreateWebhook(ctx, webhook, *userID, *username)
+	if err != nil {
+		log.Printf("[Webhooks] Error creating webhook: %v", err)
+		respondError(w, webhookErrorStatus(err), err.Error())
+		return
+	}
+
+	respondWebhook(w, http.StatusCreated, created)
+}
+
+func (s *Service) handleUpdateWebhook(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid webhook ID")
+		return
+	}
+	log.Printf("[Webhooks] %s /api/webhooks/%d/ - Request from %s", r.Method, id, r.RemoteAddr)
+
+	var updates Webhook
+	if err := json.NewDecoder(r.Body).Decode(&updates); err != nil {
+		respondError(w, http.StatusBadRequest, fmt.Sprintf("Invalid request body: %v", err))
+		return
+	}
+
+	userID, err := getUserIDFromRequest(r)
+	if err != nil {
+		respondError(w, viewerErrorStatus(err), err.Error())
+		return
+	}
+
+	updated, err := s.UpdateWebhook(ctx, id, updates, *userID)
+	if err != nil {
+		log.Printf("[Webhooks] Error updating webhook %d: %v", id, err)
+		respondError(w, webhookErrorStatus(err), err.Error())
+		return
+	}
+
+	respondWebhook(w, http.StatusOK, updated)
+}
+
+func (s *Service) handleDeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid webhook ID")
+		return
+	}
+	log.Printf("[Webhooks] DELETE /api/webhooks/%d/ - Request from %s", id, r.RemoteAddr)
+
+	userID, err := getUserIDFromRequest(r)
+	if err != nil {
+		respondError(w, viewerErrorStatus(err), err.Error())
+		return
+	}
+
+	if err := s.DeleteWebhook(ctx, id, *userID); err != nil {
+		log.Printf("[Webhooks] Error deleting webhook %d: %v", id, err)
+		respondError(w, webhookErrorStatus(err), err.Error())
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
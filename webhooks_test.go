@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+func TestPublicAddress(t *testing.T) {
+	tests := map[string]bool{
+		"93.184.216.34":          true,
+		"2606:2800:220:1::1":     true,
+		"127.0.0.1":              false,
+		"::1":                    false,
+		"10.1.2.3":               false,
+		"172.16.0.1":             false,
+		"192.168.1.10":           false,
+		"169.254.169.254":        false, // Cloud metadata
+		"fe80::1":                false,
+		"fd00::1":                false,
+		"0.0.0.0":                false,
+		"::":                     false,
+		"100.64.1.1":             false,
+		"224.0.0.1":              false,
+		"::ffff:127.0.0.1":       false,
+		"::ffff:93.184.216.34":   true,
+		"ff02::1":                false,
+		"2001:db8::1":            true, // Documentation, but not a local network
+		"192.0.2.1":              true,
+		"100.128.0.1":            true,
+		"172.32.0.1":             true,
+		"11.0.0.1":               true,
+		"2002:7f00:1::1":         false, // 6to4 of 127.0.0.1
+		"2002:5db8:d822::1":      true,
+		"64:ff9b::7f00:1":        false, // NAT64 of 127.0.0.1
+		"64:ff9b::5db8:d822":     true,
+		"::ffff:169.254.169.254": false,
+	}
+	for address, want := range tests {
+		if got := publicAddress(net.ParseIP(address)); got != want {
+			t.Errorf("%s: public %v, want %v", address, got, want)
+		}
+	}
+}
+
+func TestWebhookClientRefusesPrivateAddresses(t *testing.T) {
+	var received atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received.Add(1)
+	}))
+	defer server.Close()
+	_, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+
+	client := newWebhookClient(time.Second, false)
+	for _, target := range []string{server.URL, "http://localhost:" + port + "/"} {
+		resp, err := client.Post(target, "application/json", strings.NewReader("{}"))
+		if err == nil {
+			resp.Body.Close()
+			t.Errorf("%s: delivered to a loopback address", target)
+		} else if !strings.Contains(err.Error(), "not public") {
+			t.Errorf("%s: got %v, want a refused address", target, err)
+		}
+	}
+
+	if received.Load() != 0 {
+		t.Fatalf("server received %d deliveries", received.Load())
+	}
+
+	allowing := newWebhookClient(time.Second, true)
+	resp, err := allowing.Post(server.URL, "application/json", strings.NewReader("{}"))
+	if err != nil {
+		t.Fatalf("WEBHOOK_ALLOW_PRIVATE: %v", err)
+	}
+	resp.Body.Close()
+	if received.Load() != 1 {
+		t.Errorf("server received %d deliveries, want 1", received.Load())
+	}
+}
+
+func TestWebhookSecretIsNeverReturned(t *testing.T) {
+	s := newTestService(t, func(config *Config) { config.WebhookAllowPrivate = true })
+
+	var signature string
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		signature = r.Header.Get("X-Webhook-Signature")
+	}))
+	defer receiver.Close()
+
+	request := func(handler http.HandlerFunc, method, target, body string, vars map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("X-User-ID", "1")
+		if vars != nil {
+			req = mux.SetURLVars(req, vars)
+		}
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+		return rec
+	}
+
+	created := request(s.handleCreateWebhook, http.MethodPost, "/api/webhooks/", `{"url": "`+receiver.URL+`", "secret": "s3cret"}`, nil)
+	if created.Code != http.StatusCreated {
+		t.Fatalf("create: status %d: %s", created.Code, created.Body)
+	}
+	var webhook Webhook
+	json.Unmarshal(created.Body.Bytes(), &webhook)
+	id := map[string]string{"id": "1"}
+
+	responses := map[string]*httptest.ResponseRecorder{
+		"create": created,
+		"get":    request(s.handleGetWebhook, http.MethodGet, "/api/webhooks/1/", "", id),
+		"list":   request(s.handleListWebhooks, http.MethodGet, "/api/webhooks/", "", nil),
+		"update": request(s.handleUpdateWebhook, http.MethodPatch, "/api/webhooks/1/", `{"events": ["view.created"]}`, id),
+	}
+	for name, rec := range responses {
+		if strings.Contains(rec.Body.String(), "s3cret") || !strings.Contains(rec.Body.String(), `"has_secret":true`) {
+			t.Errorf("%s response: %s", name, rec.Body)
+		}
+	}
+
+	// The stored secret still signs the deliveries
+	stored, err := s.GetWebhook(context.Background(), *webhook.ID, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Created == nil || !strings.HasSuffix(*stored.Created, "Z") {
+		t.Errorf("created %v, want an RFC3339 UTC timestamp", stored.Created)
+	} else if _, err := time.Parse(time.RFC3339, *stored.Created); err != nil {
+		t.Errorf("created: %v", err)
+	}
+	payload := []byte(`{"event": "view.created"}`)
+	if err := s.postWebhook(context.Background(), *stored, EventViewCreated, payload); err != nil {
+		t.Fatal(err)
+	}
+	if want := "sha256=" + signWebhookPayload("s3cret", payload); signature != want {
+		t.Errorf("signature %q, want %q", signature, want)
+	}
+}
+
+func TestWebhookDispatcherBoundsDeliveries(t *testing.T) {
+	s := newTestService(t, func(config *Config) {
+		config.WebhookAllowPrivate = true
+		config.WebhookConcurrency = 1
+	})
+
+	var inFlight, maxInFlight, delivered atomic.Int32
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		current := inFlight.Add(1)
+		for {
+			seen := maxInFlight.Load()
+			if current <= seen || maxInFlight.CompareAndSwap(seen, current) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		inFlight.Add(-1)
+		delivered.Add(1)
+	}))
+	defer receiver.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	for i := 0; i < 3; i++ {
+		if _, err := s.CreateWebhook(ctx, Webhook{URL: receiver.URL}, 1, "admin"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	go s.runWebhookDispatcher(ctx)
+
+	id, owner := 7, 1
+	s.emitViewEvent(EventViewCreated, &CustomView{ID: &id, Name: "Inbox", OwnerID: &owner})
+
+	deadline := time.Now().Add(5 * time.Second)
+	for delivered.Load() < 3 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if delivered.Load() != 3 {
+		t.Fatalf("delivered %d events, want 3", delivered.Load())
+	}
+	if maxInFlight.Load() != 1 {
+		t.Errorf("%d deliveries ran at the same time, want 1", maxInFlight.Load())
+	}
+}
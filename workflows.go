@@ -0,0 +1,757 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	// maxWorkflowStatuses caps the statuses of a workflow
+	maxWorkflowStatuses = 50
+	// maxWorkflowAssignDocuments caps the documents of one status assignment
+	maxWorkflowAssignDocuments = 500
+)
+
+// noWorkflowStatusLabel is the label of the counts option for documents without a status
+const noWorkflowStatusLabel = "(No status)"
+
+// workflowStatusKeyPattern is the allowed form of workflow status keys
+var workflowStatusKeyPattern = regexp.MustCompile(`^[a-z0-9_-]{1,50}$`)
+
+// WorkflowAssignRequest sets the status of documents in a workflow; a null status removes
+// them from the workflow
+type WorkflowAssignRequest struct {
+	DocumentIDs []int   `json:"document_ids"`
+	Status      *string `json:"status"`
+}
+
+// WorkflowAssignResponse reports the documents whose status was set or removed
+type WorkflowAssignResponse struct {
+	WorkflowID int     `json:"workflow_id"`
+	Status     *string `json:"status"`
+	Updated    int     `json:"updated"`
+}
+
+// queryWorkflows retrieves the workflows selected by condition, ordered by name
+func (s *Service) queryWorkflows(ctx context.Context, condition string, args ...interface{}) ([]Workflow, error) {
+	rows, err := s.db.QueryContext(ctx, s.rebind(`
+		SELECT id, name, description, statuses, owner_id, created, modified
+		FROM workflows
+		WHERE `+condition+`
+		ORDER BY name ASC, id ASC
+	`), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query workflows: %w", err)
+	}
+	defer rows.Close()
+
+	workflows := []Workflow{}
+	for rows.Next() {
+		var workflow Workflow
+		var id int
+		var description, statusesJSON sql.NullString
+		var ownerID sql.NullInt64
+		var created, modified dbTimestamp
+		if err := rows.Scan(&id, &workflow.Name, &description, &statusesJSON, &ownerID, &created, &modified); err != nil {
+			return nil, fmt.Errorf("failed to scan workflow: %w", err)
+		}
+		workflow.ID = &id
+		if description.Valid {
+			workflow.Description = &description.String
+		}
+		workflow.Statuses = []WorkflowStatus{}
+		if statusesJSON.Valid {
+			json.Unmarshal([]byte(statusesJSON.String), &workflow.Statuses)
+		}
+		if ownerID.Valid {
+			owner := int(ownerID.Int64)
+			workflow.OwnerID = &owner
+		}
+		workflow.Created = s.formatTimestamp(created)
+		workflow.Modified = s.formatTimestamp(modified)
+		workflows = append(workflows, workflow)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read workflows: %w", err)
+	}
+	return workflows, nil
+}
+
+// ListWorkflows retrieves all workflows
+func (s *Service) ListWorkflows(ctx context.Context) ([]Workflow, error) {
+	return s.queryWorkflows(ctx, "1 = 1")
+}
+
+// GetWorkflow retrieves a workflow
+func (s *Service) GetWorkflow(ctx context.Context, id int) (*Workflow, error) {
+	workflows, err := s.queryWorkflows(ctx, "id = ?", id)
+	if err != nil {
+		return nil, err
+	}
+	if len(workflows) == 0 {
+		return nil, fmt.Errorf("workflow with id %d not found", id)
+	}
+	return &workflows[0], nil
+}
+
+// checkWorkflow normalizes and validates a workflow before it is stored. Statuses without
+// a label are labelled with their key.
+func checkWorkflow(workflow *Workflow) error {
+	workflow.Name = strings.TrimSpace(workflow.Name)
+	if workflow.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if len(workflow.Statuses) == 0 {
+		return fmt.Errorf("statuses are required")
+	}
+	if len(workflow.Statuses) > maxWorkflowStatuses {
+		return fmt.Errorf("invalid statuses: at most %d statuses", maxWorkflowStatuses)
+	}
+
+	keys := make(map[string]bool, len(workflow.Statuses))
+	for i := range workflow.Statuses {
+		status := &workflow.Statuses[i]
+		status.Key = strings.TrimSpace(status.Key)
+		if !workflowStatusKeyPattern.MatchString(status.Key) {
+			return fmt.Errorf("invalid status key %q: use 1 to 50 lowercase letters, digits, _ or -", status.Key)
+		}
+		if keys[status.Key] {
+			return fmt.Errorf("invalid status key %q: duplicate key", status.Key)
+		}
+		keys[status.Key] = true
+		status.Label = strings.TrimSpace(status.Label)
+		if status.Label == "" {
+			status.Label = status.Key
+		}
+	}
+	return nil
+}
+
+// checkWorkflowOwner allows changes to a workflow only by its owner and superusers
+func (s *Service) checkWorkflowOwner(ctx context.Context, workflow *Workflow, userID int) error {
+	if workflow.OwnerID != nil && *workflow.OwnerID == userID {
+		return nil
+	}
+	superuser, err := s.isSuperuser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !superuser {
+		return fmt.Errorf("permission denied: only the owner can change workflow %d", *workflow.ID)
+	}
+	return nil
+}
+
+// CreateWorkflow creates a new workflow owned by the user
+func (s *Service) CreateWorkflow(ctx context.Context, workflow Workflow, userID int) (*Workflow, error) {
+	log.Printf("[Workflows] CreateWorkflow - Name: %s, UserID: %d", workflow.Name, userID)
+	if err := checkWorkflow(&workflow); err != nil {
+		return nil, err
+	}
+	statusesJSON, err := json.Marshal(workflow.Statuses)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode statuses: %w", err)
+	}
+
+	var id int64
+	if s.config.DBEngine == "postgresql" || s.config.DBEngine == "postgres" {
+		err := s.db.QueryRowContext(ctx, `
+			INSERT INTO workflows (name, description, statuses, owner_id)
+			VALUES ($1, $2, $3::jsonb, $4)
+			RETURNING id
+		`, workflow.Name, workflow.Description, string(statusesJSON), userID).Scan(&id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create workflow: %w", err)
+		}
+	} else {
+		result, err := s.db.ExecContext(ctx, `
+			INSERT INTO workflows (name, description, statuses, owner_id)
+			VALUES (?, ?, ?, ?)
+		`, workflow.Name, workflow.Description, string(statusesJSON), userID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create workflow: %w", err)
+		}
+		if id, err = result.LastInsertId(); err != nil {
+			return nil, fmt.Errorf("failed to get workflow ID: %w", err)
+		}
+	}
+
+	return s.GetWorkflow(ctx, int(id))
+}
+
+// UpdateWorkflow updates the provided fields of a workflow. Statuses replace the previous
+// ones; a status still assigned to documents cannot be removed.
+func (s *Service) UpdateWorkflow(ctx context.Context, id int, updates Workflow, userID int) (*Workflow, error) {
+	log.Printf("[Workflows] UpdateWorkflow - ID: %d, UserID: %d", id, userID)
+	existing, err := s.GetWorkflow(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkWorkflowOwner(ctx, existing, userID); err != nil {
+		return nil, err
+	}
+
+	merged := *existing
+	if updates.Name != "" {
+		merged.Name = updates.Name
+	}
+	if updates.Description != nil {
+		merged.Description = updates.Description
+	}
+	if updates.Statuses != nil {
+		merged.Statuses = updates.Statuses
+	}
+	if err := checkWorkflow(&merged); err != nil {
+		return nil, err
+	}
+
+	if updates.Statuses != nil {
+		kept := make(map[string]bool, len(merged.Statuses))
+		for _, status := range merged.Statuses {
+			kept[status.Key] = true
+		}
+		counts, err := s.workflowStatusUsage(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		for _, status := range existing.Statuses {
+			if !kept[status.Key] && counts[status.Key] > 0 {
+				return nil, fmt.Errorf("status %q is still in use (%d documents)", status.Key, counts[status.Key])
+			}
+		}
+	}
+	statusesJSON, err := json.Marshal(merged.Statuses)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode statuses: %w", err)
+	}
+
+	query := "UPDATE workflows SET name = ?, description = ?, statuses = ?, modified = CURRENT_TIMESTAMP WHERE id = ?"
+	if s.config.DBEngine == "postgresql" || s.config.DBEngine == "postgres" {
+		query = "UPDATE workflows SET name = $1, description = $2, statuses = $3::jsonb, modified = CURRENT_TIMESTAMP WHERE id = $4"
+	}
+	if _, err := s.db.ExecContext(ctx, query, merged.Name, merged.Description, string(statusesJSON), id); err != nil {
+		return nil, fmt.Errorf("failed to update workflow: %w", err)
+	}
+
+	return s.GetWorkflow(ctx, id)
+}
+
+// workflowStatusUsage counts the documents per status of a workflow, trashed ones included
+func (s *Service) workflowStatusUsage(ctx context.Context, id int) (map[string]int, error) {
+	rows, err := s.db.QueryContext(ctx, s.rebind("SELECT status, COUNT(*) FROM document_workflow_status WHERE workflow_id = ? GROUP BY status"), id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query workflow statuses: %w", err)
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int)
+	for rows.Next() {
+		var status string
+		var count int
+		if err := rows.Scan(&status, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan workflow status: %w", err)
+		}
+		counts[status] = count
+	}
+	return counts, rows.Err()
+}
+
+// DeleteWorkflow deletes a workflow with the statuses assigned to documents
+func (s *Service) DeleteWorkflow(ctx context.Context, id int, userID int) error {
+	log.Printf("[Workflows] DeleteWorkflow - ID: %d, UserID: %d", id, userID)
+	existing, err := s.GetWorkflow(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := s.checkWorkflowOwner(ctx, existing, userID); err != nil {
+		return err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, s.rebind("DELETE FROM document_workflow_status WHERE workflow_id = ?"), id); err != nil {
+		return fmt.Errorf("failed to delete workflow statuses: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, s.rebind("DELETE FROM workflows WHERE id = ?"), id); err != nil {
+		return fmt.Errorf("failed to delete workflow: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// AssignWorkflowStatus sets the status of documents the viewer can see in a workflow, or
+// removes them from the workflow when status is nil. It returns the number of documents
+// changed.
+func (s *Service) AssignWorkflowStatus(ctx context.Context, id int, documentIDs []int, status *string, userID int, username string, viewerID int) (int, error) {
+	log.Printf("[Workflows] AssignWorkflowStatus - ID: %d, Documents: %d, UserID: %d", id, len(documentIDs), userID)
+	if len(documentIDs) == 0 {
+		return 0, fmt.Errorf("document_ids are required")
+	}
+	if len(documentIDs) > maxWorkflowAssignDocuments {
+		return 0, fmt.Errorf("invalid document_ids: at most %d documents", maxWorkflowAssignDocuments)
+	}
+	workflow, err := s.GetWorkflow(ctx, id)
+	if err != nil {
+		return 0, err
+	}
+	if status != nil {
+		known := false
+		for _, defined := range workflow.Statuses {
+			known = known || defined.Key == *status
+		}
+		if !known {
+			return 0, fmt.Errorf("invalid status %q: not a status of workflow %d", *status, id)
+		}
+	}
+	documentIDs = uniqueTagIDs(documentIDs)
+	if err := s.checkDocumentsVisible(ctx, documentIDs, false, viewerID); err != nil {
+		return 0, err
+	}
+	placeholders, idArgs := inPlaceholders(documentIDs)
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if status == nil {
+		result, err := tx.ExecContext(ctx, s.rebind(fmt.Sprintf("DELETE FROM document_workflow_status WHERE workflow_id = ? AND document_id IN (%s)", placeholders)),
+			append([]interface{}{id}, idArgs...)...)
+		if err != nil {
+			return 0, fmt.Errorf("failed to remove workflow statuses: %w", err)
+		}
+		if err := tx.Commit(); err != nil {
+			return 0, fmt.Errorf("failed to commit transaction: %w", err)
+		}
+		removed, _ := result.RowsAffected()
+		return int(removed), nil
+	}
+
+	rows, err := tx.QueryContext(ctx, s.rebind(fmt.Sprintf("SELECT document_id FROM document_workflow_status WHERE workflow_id = ? AND document_id IN (%s)", placeholders)),
+		append([]interface{}{id}, idArgs...)...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query workflow statuses: %w", err)
+	}
+	assigned := make(map[int]bool)
+	for rows.Next() {
+		var documentID int
+		if err := rows.Scan(&documentID); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan workflow status: %w", err)
+		}
+		assigned[documentID] = true
+	}
+	rows.Close()
+
+	update := s.rebind("UPDATE document_workflow_status SET status = ?, updated_by = ?, username = ?, modified = CURRENT_TIMESTAMP WHERE workflow_id = ? AND document_id = ?")
+	insert := s.rebind("INSERT INTO document_workflow_status (workflow_id, document_id, status, updated_by, username) VALUES (?, ?, ?, ?, ?)")
+	for _, documentID := range documentIDs {
+		if assigned[documentID] {
+			_, err = tx.ExecContext(ctx, update, *status, userID, username, id, documentID)
+		} else {
+			_, err = tx.ExecContext(ctx, insert, id, documentID, *status, userID, username)
+		}
+		if err != nil {
+			return 0, fmt.Errorf("failed to set workflow status of document %d: %w", documentID, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return len(documentIDs), nil
+}
+
+// GetDocumentWorkflowStatuses retrieves the statuses of a document the viewer can see in
+// all workflows it takes part in
+func (s *Service) GetDocumentWorkflowStatuses(ctx context.Context, documentID int, includeTrashed bool, viewerID int) ([]DocumentWorkflowStatus, error) {
+	if err := s.checkDocumentsVisible(ctx, []int{documentID}, includeTrashed, viewerID); err != nil {
+		return nil, err
+	}
+	rows, err := s.db.QueryContext(ctx, s.rebind(`
+		SELECT ws.workflow_id, w.name, w.statuses, ws.status, ws.updated_by, ws.username, ws.modified
+		FROM document_workflow_status ws
+		INNER JOIN workflows w ON w.id = ws.workflow_id
+		WHERE ws.document_id = ?
+		ORDER BY w.name ASC, w.id ASC
+	`), documentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query document workflow statuses: %w", err)
+	}
+	defer rows.Close()
+
+	statuses := []DocumentWorkflowStatus{}
+	for rows.Next() {
+		var entry DocumentWorkflowStatus
+		var statusesJSON, username sql.NullString
+		var updatedBy sql.NullInt64
+		var modified dbTimestamp
+		if err := rows.Scan(&entry.WorkflowID, &entry.WorkflowName, &statusesJSON, &entry.Status, &updatedBy, &username, &modified); err != nil {
+			return nil, fmt.Errorf("failed to scan document workflow status: %w", err)
+		}
+		entry.StatusLabel = entry.Status
+		var defined []WorkflowStatus
+		json.Unmarshal([]byte(statusesJSON.String), &defined)
+		for _, status := range defined {
+			if status.Key == entry.Status {
+				entry.StatusLabel = status.Label
+			}
+		}
+		if updatedBy.Valid {
+			user := int(updatedBy.Int64)
+			entry.UpdatedBy = &user
+		}
+		if username.Valid {
+			entry.Username = &username.String
+		}
+		entry.Modified = s.formatTimestamp(modified)
+		statuses = append(statuses, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read document workflow statuses: %w", err)
+	}
+	return statuses, nil
+}
+
+// GetWorkflowCounts counts the documents matching the filter rules per status of a
+// workflow, in the order of the statuses, followed by the documents without a status under
+// a null ID. Documents are counted like the builtin filter values: trashed ones only with
+// includeTrashed, and only those visible to viewerID (0 = all). With includeEmpty, statuses
+// without documents are listed with a zero count.
+func (s *Service) GetWorkflowCounts(ctx context.Context, id int, filterRulesJSON string, includeTrashed bool, includeEmpty bool, viewerID int) ([]BuiltinFilterValueOption, error) {
+	workflow, err := s.GetWorkflow(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	docFilterWhere, args, err := s.buildDocumentFilterQuery(ctx, filterRulesJSON, 0, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build filter query: %w", err)
+	}
+	conditions := []string{trashedCondition(includeTrashed)}
+	if docFilterWhere != "" {
+		conditions = append(conditions, "("+strings.Replace(docFilterWhere, "WHERE ", "", 1)+")")
+	}
+	visibility, err := s.documentVisibilityCondition(ctx, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	if visibility != "" {
+		conditions = append(conditions, visibility)
+	}
+
+	// The workflow ID is an integer, so it is safe to inline
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf(`
+		SELECT ws.status, COUNT(DISTINCT d.id) as doc_count
+		FROM documents_document d
+		LEFT JOIN document_workflow_status ws ON ws.document_id = d.id AND ws.workflow_id = %d
+		WHERE %s
+		GROUP BY ws.status
+	`, id, strings.Join(conditions, " AND ")), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query workflow counts: %w", err)
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int)
+	unassigned := 0
+	for rows.Next() {
+		var status sql.NullString
+		var count int
+		if err := rows.Scan(&status, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan workflow count: %w", err)
+		}
+		if status.Valid {
+			counts[status.String] = count
+		} else {
+			unassigned = count
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read workflow counts: %w", err)
+	}
+
+	values := []BuiltinFilterValueOption{}
+	for _, status := range workflow.Statuses {
+		if counts[status.Key] == 0 && !includeEmpty {
+			continue
+		}
+		option := BuiltinFilterValueOption{ID: status.Key, Label: status.Label, Count: counts[status.Key]}
+		if status.Color != nil {
+			option.Color = *status.Color
+		}
+		values = append(values, option)
+	}
+	if unassigned > 0 || includeEmpty {
+		values = append(values, BuiltinFilterValueOption{ID: nil, Label: noWorkflowStatusLabel, Count: unassigned})
+	}
+	return values, nil
+}
+
+// workflowErrorStatus maps workflow errors to HTTP status codes
+func workflowErrorStatus(err error) int {
+	switch {
+	case strings.HasPrefix(err.Error(), "invalid"), strings.Contains(err.Error(), "required"):
+		return http.StatusBadRequest
+	case strings.HasPrefix(err.Error(), "permission denied"):
+		return http.StatusForbidden
+	case strings.Contains(err.Error(), "not found"):
+		return http.StatusNotFound
+	case strings.Contains(err.Error(), "still in use"):
+		return http.StatusConflict
+	}
+	return queryErrorStatus(err)
+}
+
+// HTTP Handlers for workflows
+func (s *Service) handleListWorkflows(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	log.Printf("[Workflows] GET /api/workflows/ - Request from %s", r.RemoteAddr)
+
+	workflows, err := s.ListWorkflows(ctx)
+	if err != nil {
+		log.Printf("[Workflows] Error listing workflows: %v", err)
+		respondError(w, queryErrorStatus(err), err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, WorkflowListResponse{Count: len(workflows), Results: workflows})
+}
+
+func (s *Service) handleGetWorkflow(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	idStr := mux.Vars(r)["id"]
+	log.Printf("[Workflows] GET /api/workflows/%s/ - Request from %s", idStr, r.RemoteAddr)
+
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid workflow ID")
+		return
+	}
+
+	workflow, err := s.GetWorkflow(ctx, id)
+	if err != nil {
+		respondError(w, workflowErrorStatus(err), err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, workflow)
+}
+
+func (s *Service) handleCreateWorkflow(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	log.Printf("[Workflows] POST /api/workflows/ - Request from %s", r.RemoteAddr)
+
+	var workflow Workflow
+	if err := json.NewDecoder(r.Body).Decode(&workflow); err != nil {
+		respondError(w, http.StatusBadRequest, fmt.Sprintf("Invalid request body: %v", err))
+		return
+	}
+	userID, err := getUserIDFromRequest(r)
+	if err != nil {
+		respondError(w, viewerErrorStatus(err), err.Error())
+		return
+	}
+
+	created, err := s.CreateWorkflow(ctx, workflow, *userID)
+	if err != nil {
+		log.Printf("[Workflows] Error creating workflow: %v", err)
+		respondError(w, workflowErrorStatus(err), err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, created)
+}
+
+func (s *Service) handleUpdateWorkflow(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	idStr := mux.Vars(r)["id"]
+	log.Printf("[Workflows] %s /api/workflows/%s/ - Request from %s", r.Method, idStr, r.RemoteAddr)
+
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid workflow ID")
+		return
+	}
+	var updates Workflow
+	if err := json.NewDecoder(r.Body).Decode(&updates); err != nil {
+		respondError(w, http.StatusBadRequest, fmt.Sprintf("Invalid request body: %v", err))
+		return
+	}
+	userID, err := getUserIDFromRequest(r)
+	if err != nil {
+		respondError(w, viewerErrorStatus(err), err.Error())
+		return
+	}
+
+	updated, err := s.UpdateWorkflow(ctx, id, updates, *userID)
+	if err != nil {
+		log.Printf("[Workflows] Error updating workflow %d: %v", id, err)
+		respondError(w, workflowErrorStatus(err), err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, updated)
+}
+
+func (s *Service) handleDeleteWorkflow(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	idStr := mux.Vars(r)["id"]
+	log.Printf("[Workflows] DELETE /api/workflows/%s/ - Request from %s", idStr, r.RemoteAddr)
+
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid workflow ID")
+		return
+	}
+	userID, err := getUserIDFromRequest(r)
+	if err != nil {
+		respondError(w, viewerErrorStatus(err), err.Error())
+		return
+	}
+
+	if err := s.DeleteWorkflow(ctx, id, *userID); err != nil {
+		log.Printf("[Workflows] Error deleting workflow %d: %v", id, err)
+		respondError(w, workflowErrorStatus(err), err.Error())
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Service) handleAssignWorkflowStatus(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	idStr := mux.Vars(r)["id"]
+	log.Printf("[Workflows] POST /api/workflows/%s/assign/ - Request from %s", idStr, r.RemoteAddr)
+
+	viewerID, err := viewerFromRequest(r)
+	if err != nil {
+		respondError(w, viewerErrorStatus(err), err.Error())
+		return
+	}
+
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid workflow ID")
+		return
+	}
+	var req WorkflowAssignRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, fmt.Sprintf("Invalid request body: %v", err))
+		return
+	}
+	userID, err := getUserIDFromRequest(r)
+	if err != nil {
+		respondError(w, viewerErrorStatus(err), err.Error())
+		return
+	}
+
+	updated, err := s.AssignWorkflowStatus(ctx, id, req.DocumentIDs, req.Status, *userID, *getUsernameFromRequest(r), viewerID)
+	if err != nil {
+		log.Printf("[Workflows] Error assigning status in workflow %d: %v", id, err)
+		respondError(w, workflowErrorStatus(err), err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, WorkflowAssignResponse{WorkflowID: id, Status: req.Status, Updated: updated})
+}
+
+func (s *Service) handleGetWorkflowCounts(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	idStr := mux.Vars(r)["id"]
+	log.Printf("[Workflows] %s /api/workflows/%s/counts/ - Request from %s", r.Method, idStr, r.RemoteAddr)
+
+	viewerID, err := viewerFromRequest(r)
+	if err != nil {
+		respondError(w, viewerErrorStatus(err), err.Error())
+		return
+	}
+
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid workflow ID")
+		return
+	}
+
+	// Filter rules come from the request body (POST) or query parameters (GET)
+	var body map[string]interface{}
+	if r.Method == http.MethodGet {
+		body, err = builtinFilterBodyFromQuery(r)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	} else if r.Body != nil {
+		json.NewDecoder(r.Body).Decode(&body)
+	}
+	body, err = s.applyFilterPreset(ctx, r, body)
+	if err != nil {
+		respondError(w, filterPresetErrorStatus(err), err.Error())
+		return
+	}
+
+	values, err := s.GetWorkflowCounts(ctx, id, filterRulesFromBody(body), wantsTrashed(r), wantsEmpty(r), viewerID)
+	if err != nil {
+		log.Printf("[Workflows] Error counting documents of workflow %d: %v", id, err)
+		respondError(w, workflowErrorStatus(err), err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, values)
+}
+
+func (s *Service) handleGetDocumentWorkflowStatuses(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := s.requestContext(r)
+	defer cancel()
+
+	idStr := mux.Vars(r)["id"]
+	log.Printf("[Workflows] GET /api/documents/%s/workflows/ - Request from %s", idStr, r.RemoteAddr)
+
+	viewerID, err := viewerFromRequest(r)
+	if err != nil {
+		respondError(w, viewerErrorStatus(err), err.Error())
+		return
+	}
+
+	documentID, err := strconv.Atoi(idStr)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid document ID")
+		return
+	}
+
+	statuses, err := s.GetDocumentWorkflowStatuses(ctx, documentID, wantsTrashed(r), viewerID)
+	if err != nil {
+		log.Printf("[Workflows] Error getting workflow statuses of document %d: %v", documentID, err)
+		respondError(w, workflowErrorStatus(err), err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, statuses)
+}